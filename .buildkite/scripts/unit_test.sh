@@ -9,4 +9,12 @@ add_bin_path
 with_go
 
 echo "Starting the unit tests..."
-make test-unit junit-report
+make test-unit
+
+# the BoringCrypto module of the FIPS builds is only available on linux
+if [[ "$(uname)" == "Linux" ]]; then
+    echo "Starting the FIPS unit tests..."
+    make test-unit-fips
+fi
+
+make junit-report
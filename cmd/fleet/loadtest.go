@@ -0,0 +1,160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+	"github.com/elastic/fleet-server/v7/internal/pkg/loadtest"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signal"
+)
+
+func newLoadTestCommand(bi build.Info) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Simulate agents against a fleet-server",
+		Long: "Simulate enrolled agents against a fleet-server and report the latencies and the errors of the requests: " +
+			"each agent enrolls with the enrollment token, then checks in with long polls, acknowledges the actions it " +
+			"receives and downloads the artifacts of its policy. The agents are left enrolled at the end of the test, " +
+			"they should be unenrolled from Fleet.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          getLoadTestCommand(),
+	}
+	cmd.Flags().String("url", "", "URL of the fleet-server, like https://fleet-server:8220")
+	cmd.Flags().String("enrollment-token", "", "Enrollment token of the agents")
+	cmd.Flags().Int("agents", 100, "Number of simulated agents")
+	cmd.Flags().Duration("duration", 10*time.Minute, "Duration of the test, 0 runs until interrupted")
+	cmd.Flags().Duration("ramp-up", time.Minute, "Period the agents are started over")
+	cmd.Flags().Duration("poll-timeout", 5*time.Minute, "Poll timeout of the checkins, the server holds them for the poll timeout minus 2m, 1m at least")
+	cmd.Flags().Duration("request-timeout", 30*time.Second, "Timeout of the requests other than the checkins")
+	cmd.Flags().String("agent-version", bi.Version, "Version of the simulated agents")
+	cmd.Flags().StringSlice("tags", nil, "Tags of the simulated agents")
+	cmd.Flags().Bool("artifacts", true, "Download the artifacts of the policies")
+	cmd.Flags().String("ca", "", "Path of the CA certificates of the fleet-server")
+	cmd.Flags().Bool("insecure", false, "Skip the verification of the certificate of the fleet-server")
+	cmd.Flags().Duration("progress", 10*time.Second, "Interval of the progress written to stderr, 0 disables it")
+	cmd.Flags().String(kOutputFormat, "text", "Format of the report, text or json")
+	return cmd
+}
+
+func getLoadTestCommand() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, format, err := loadTestConfig(cmd)
+		if err != nil {
+			return err
+		}
+		cfg.Progress = cmd.ErrOrStderr()
+
+		ctx := signal.HandleInterrupt(cmd.Context(), nil)
+		report, err := loadtest.Run(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if err := writeLoadTestReport(cmd.OutOrStdout(), format, report); err != nil {
+			return err
+		}
+		if report.Enrolled == 0 {
+			return errors.New("no agent enrolled")
+		}
+		return nil
+	}
+}
+
+// loadTestConfig returns the configuration of the test and the format of the report from the flags.
+func loadTestConfig(cmd *cobra.Command) (loadtest.Config, string, error) {
+	var cfg loadtest.Config
+	flags := cmd.Flags()
+	var err error
+	if cfg.URL, err = flags.GetString("url"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.EnrollmentToken, err = flags.GetString("enrollment-token"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.Agents, err = flags.GetInt("agents"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.Duration, err = flags.GetDuration("duration"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.RampUp, err = flags.GetDuration("ramp-up"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.PollTimeout, err = flags.GetDuration("poll-timeout"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.RequestTimeout, err = flags.GetDuration("request-timeout"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.AgentVersion, err = flags.GetString("agent-version"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.Tags, err = flags.GetStringSlice("tags"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.Artifacts, err = flags.GetBool("artifacts"); err != nil {
+		return cfg, "", err
+	}
+	if cfg.ProgressInterval, err = flags.GetDuration("progress"); err != nil {
+		return cfg, "", err
+	}
+	ca, err := flags.GetString("ca")
+	if err != nil {
+		return cfg, "", err
+	}
+	insecure, err := flags.GetBool("insecure")
+	if err != nil {
+		return cfg, "", err
+	}
+	if cfg.TLS, err = loadTestTLS(ca, insecure); err != nil {
+		return cfg, "", err
+	}
+	format, err := flags.GetString(kOutputFormat)
+	if err != nil {
+		return cfg, "", err
+	}
+	if format != "text" && format != "json" {
+		return cfg, "", fmt.Errorf("unknown output format %q, text or json", format)
+	}
+	return cfg, format, nil
+}
+
+func loadTestTLS(caPath string, insecure bool) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure} //nolint:gosec // skipping the verification is requested with --insecure
+	if caPath != "" {
+		ca, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("the CA %s has no certificate", caPath)
+		}
+		cfg.RootCAs = pool
+	}
+	return fips.Restrict(cfg), nil
+}
+
+func writeLoadTestReport(w io.Writer, format string, report *loadtest.Report) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.WriteText(w)
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+)
+
+func TestLoadTestConfig(t *testing.T) {
+	parse := func(args ...string) error {
+		cmd := newLoadTestCommand(build.Info{Version: "8.15.0"})
+		require.NoError(t, cmd.ParseFlags(args))
+		_, _, err := loadTestConfig(cmd)
+		return err
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cmd := newLoadTestCommand(build.Info{Version: "8.15.0"})
+		require.NoError(t, cmd.ParseFlags([]string{"--url", "https://localhost:8220", "--enrollment-token", "token", "--tags", "a,b"}))
+		cfg, format, err := loadTestConfig(cmd)
+		require.NoError(t, err)
+		assert.Equal(t, "text", format)
+		assert.Equal(t, "https://localhost:8220", cfg.URL)
+		assert.Equal(t, 100, cfg.Agents)
+		assert.Equal(t, 5*time.Minute, cfg.PollTimeout)
+		assert.Equal(t, "8.15.0", cfg.AgentVersion)
+		assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+		assert.True(t, cfg.Artifacts)
+		assert.False(t, cfg.TLS.InsecureSkipVerify)
+	})
+	t.Run("unknown output format", func(t *testing.T) {
+		assert.EqualError(t, parse("--output", "yaml"), `unknown output format "yaml", text or json`)
+	})
+	t.Run("missing CA", func(t *testing.T) {
+		assert.ErrorContains(t, parse("--ca", filepath.Join(t.TempDir(), "ca.crt")), "unable to read the CA")
+	})
+	t.Run("CA without certificate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.crt")
+		require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+		assert.EqualError(t, parse("--ca", path), "the CA "+path+" has no certificate")
+	})
+}
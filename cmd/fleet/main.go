@@ -12,6 +12,7 @@ import (
 
 	"go.elastic.co/apm/v2"
 
+	"github.com/elastic/go-ucfg"
 	"github.com/elastic/go-ucfg/yaml"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/build"
@@ -34,9 +35,9 @@ func init() {
 	apm.DefaultTracer().Close()
 }
 
-func installSignalHandler() context.Context {
+func installSignalHandler(drain func(reason string) bool) context.Context {
 	rootCtx := context.Background()
-	return signal.HandleInterrupt(rootCtx)
+	return signal.HandleInterrupt(rootCtx, drain)
 }
 
 func initLogger(cfg *config.Config, version, commit string) (*logger.Logger, error) {
@@ -84,7 +85,7 @@ func getRunCommand(bi build.Info) func(cmd *cobra.Command, args []string) error
 				return err
 			}
 
-			if err := srv.Run(installSignalHandler()); err != nil && !errors.Is(err, context.Canceled) {
+			if err := srv.Run(installSignalHandler(srv.Drain)); err != nil && !errors.Is(err, context.Canceled) {
 				log.Error().Err(err).Msg("Exiting")
 				l.Sync()
 				return err
@@ -94,11 +95,7 @@ func getRunCommand(bi build.Info) func(cmd *cobra.Command, args []string) error
 			if err != nil {
 				return err
 			}
-			cfgData, err := yaml.NewConfigWithFile(cfgPath, config.DefaultOptions...)
-			if err != nil {
-				return err
-			}
-			err = cfgData.Merge(cliCfg, config.DefaultOptions...)
+			cfgData, err := readConfigFile(cfgPath, cliCfg)
 			if err != nil {
 				return err
 			}
@@ -122,7 +119,7 @@ func getRunCommand(bi build.Info) func(cmd *cobra.Command, args []string) error
 				return err
 			}
 
-			if err := srv.Run(installSignalHandler(), cfg); err != nil && !errors.Is(err, context.Canceled) {
+			if err := srv.Run(installSignalHandler(srv.Drain), cfg); err != nil && !errors.Is(err, context.Canceled) {
 				log.Error().Err(err).Msg("Exiting")
 				l.Sync()
 				return err
@@ -134,6 +131,18 @@ func getRunCommand(bi build.Info) func(cmd *cobra.Command, args []string) error
 	}
 }
 
+// readConfigFile returns the settings of the configuration file with the overrides of the command line.
+func readConfigFile(cfgPath string, cliCfg *ucfg.Config) (*ucfg.Config, error) {
+	cfgData, err := yaml.NewConfigWithFile(cfgPath, config.DefaultOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfgData.Merge(cliCfg, config.DefaultOptions...); err != nil {
+		return nil, err
+	}
+	return cfgData, nil
+}
+
 func NewCommand(bi build.Info) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   build.ServiceName,
@@ -143,5 +152,7 @@ func NewCommand(bi build.Info) *cobra.Command {
 	cmd.Flags().StringP("config", "c", "fleet-server.yml", "Configuration for Fleet Server")
 	cmd.Flags().Bool(kAgentMode, false, "Running under execution of the Elastic Agent")
 	cmd.Flags().VarP(config.NewFlag(), "E", "E", "Overwrite configuration value")
+	cmd.AddCommand(newTestConfigCommand(bi))
+	cmd.AddCommand(newLoadTestCommand(bi))
 	return cmd
 }
@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/go-ucfg"
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+)
+
+const (
+	kCheckElasticsearch = "elasticsearch"
+	kOutputFormat       = "output"
+
+	esCheckTimeout = 30 * time.Second
+)
+
+// configReport is the result of the check of a configuration written by the test-config command.
+type configReport struct {
+	Valid    bool             `json:"valid"`
+	Problems []config.Problem `json:"problems"`
+}
+
+func newTestConfigCommand(bi build.Info) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test-config",
+		Short: "Check the configuration and exit",
+		Long: "Check the configuration and exit with an error when it is not valid: the settings are parsed and validated, " +
+			"the TLS material is loaded, the limits and timeouts are checked for consistency, the deprecated settings are " +
+			"reported and the connection to Elasticsearch is tested when requested.",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          getTestConfigCommand(bi),
+	}
+	cmd.Flags().StringP("config", "c", "fleet-server.yml", "Configuration for Fleet Server")
+	cmd.Flags().VarP(config.NewFlag(), "E", "E", "Overwrite configuration value")
+	cmd.Flags().Bool(kCheckElasticsearch, false, "Test the connection to Elasticsearch")
+	cmd.Flags().String(kOutputFormat, "text", "Format of the report, text or json")
+	return cmd
+}
+
+func getTestConfigCommand(bi build.Info) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfgObject := cmd.Flags().Lookup("E").Value.(*config.Flag) //nolint:errcheck // we know the flag exists
+		cfgPath, err := cmd.Flags().GetString("config")
+		if err != nil {
+			return err
+		}
+		checkES, err := cmd.Flags().GetBool(kCheckElasticsearch)
+		if err != nil {
+			return err
+		}
+		format, err := cmd.Flags().GetString(kOutputFormat)
+		if err != nil {
+			return err
+		}
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown output format %q, text or json", format)
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), esCheckTimeout)
+		defer cancel()
+		report := testConfig(ctx, cfgPath, cfgObject.Config(), checkES, bi)
+		if err := writeReport(cmd.OutOrStdout(), format, report); err != nil {
+			return err
+		}
+		if !report.Valid {
+			return fmt.Errorf("configuration %s is not valid", cfgPath)
+		}
+		return nil
+	}
+}
+
+// testConfig checks the configuration file with the overrides of the command line, a problem that stops the
+// check, like a file that can not be parsed, is the last problem of the report.
+func testConfig(ctx context.Context, cfgPath string, cliCfg *ucfg.Config, checkES bool, bi build.Info) configReport {
+	report := configReport{Problems: []config.Problem{}}
+	fail := func(setting string, err error) configReport {
+		report.Problems = append(report.Problems, config.Problem{Setting: setting, Severity: config.SeverityError, Message: err.Error()})
+		return report
+	}
+
+	cfgData, err := readConfigFile(cfgPath, cliCfg)
+	if err != nil {
+		return fail("", err)
+	}
+	report.Problems = append(report.Problems, config.DeprecatedOptions(cfgData)...)
+	cfg, err := config.FromConfig(cfgData)
+	if err != nil {
+		return fail("", err)
+	}
+	if err := cfg.LoadServerLimits(); err != nil {
+		return fail("inputs", err)
+	}
+	report.Problems = append(report.Problems, cfg.Check()...)
+
+	if checkES {
+		if err := pingElasticsearch(ctx, cfg, bi); err != nil {
+			report.Problems = append(report.Problems, config.Problem{Setting: "output.elasticsearch", Severity: config.SeverityError, Message: err.Error()})
+		}
+	}
+
+	report.Valid = true
+	for _, p := range report.Problems {
+		if p.Severity == config.SeverityError {
+			report.Valid = false
+		}
+	}
+	return report
+}
+
+// pingElasticsearch requests the cluster info with the credentials of the output.
+func pingElasticsearch(ctx context.Context, cfg *config.Config, bi build.Info) error {
+	cli, err := es.NewClient(ctx, cfg, false, es.WithUserAgent(build.ServiceName, bi))
+	if err != nil {
+		return fmt.Errorf("unable to create the Elasticsearch client: %w", err)
+	}
+	res, err := cli.Info(cli.Info.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("unable to connect to Elasticsearch: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("unable to connect to Elasticsearch: %s", res.Status())
+	}
+	return nil
+}
+
+func writeReport(w io.Writer, format string, report configReport) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	for _, p := range report.Problems {
+		if _, err := fmt.Fprintln(w, p); err != nil {
+			return err
+		}
+	}
+	if report.Valid {
+		_, err := fmt.Fprintln(w, "Config OK")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const testConfigFile = `
+output:
+  elasticsearch:
+    hosts: ["localhost:9200"]
+    service_token: "test-token"
+inputs:
+  - type: fleet-server
+    server:
+      limits:
+        max_connections: 10000
+        ack_limit:
+          max: 20000
+`
+
+func TestTestConfigCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleet-server.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+
+	run := func(args ...string) (configReport, error) {
+		var out bytes.Buffer
+		cmd := NewCommand(build.Info{})
+		cmd.SetOut(&out)
+		cmd.SetArgs(append([]string{"test-config", "-c", path, "--output", "json"}, args...))
+		err := cmd.Execute()
+		var report configReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		return report, err
+	}
+
+	t.Run("warnings", func(t *testing.T) {
+		report, err := run()
+		require.NoError(t, err)
+		assert.True(t, report.Valid)
+		assert.Equal(t, []config.Problem{{
+			Setting:  "inputs.0.server.limits.max_connections",
+			Severity: config.SeverityWarning,
+			Message:  "max_connections has been deprecated and will be removed in a future release. Please configure server limits using max_agents instead.",
+		}, {
+			Setting:  "inputs.0.server.limits.ack_limit",
+			Severity: config.SeverityWarning,
+			Message:  "max 20000 is more than the 10000 connections of the server",
+		}}, report.Problems)
+	})
+
+	t.Run("invalid setting", func(t *testing.T) {
+		report, err := run("-E", "inputs.0.server.reuse_port.sockets=-1")
+		require.Error(t, err)
+		assert.False(t, report.Valid)
+		require.Len(t, report.Problems, 1)
+		assert.Equal(t, config.SeverityError, report.Problems[0].Severity)
+		assert.Contains(t, report.Problems[0].Message, "reuse_port sockets")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		report, err := run("-c", filepath.Join(t.TempDir(), "missing.yml"))
+		require.Error(t, err)
+		assert.False(t, report.Valid)
+	})
+}
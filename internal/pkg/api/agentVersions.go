@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+var ErrAgentVersionNotAllowed = errors.New("agent version is not allowed")
+
+// agentVersionRules are the parsed rules of config.AgentVersions.
+type agentVersionRules struct {
+	min      *version.Version
+	blocked  []version.Constraints
+	policies map[string]version.Constraints
+	warnOnly bool
+}
+
+// newAgentVersionRules parses the rules of the configuration, it returns nil when no rule is set.
+func newAgentVersionRules(cfg *config.AgentVersions) (*agentVersionRules, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	rules := &agentVersionRules{
+		policies: make(map[string]version.Constraints, len(cfg.Policies)),
+		warnOnly: cfg.WarnOnly,
+	}
+	if cfg.MinVersion != "" {
+		v, err := version.NewVersion(cfg.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		rules.min = v
+	}
+	for _, blocked := range cfg.Blocked {
+		c, err := version.NewConstraint(blocked)
+		if err != nil {
+			return nil, err
+		}
+		rules.blocked = append(rules.blocked, c)
+	}
+	for _, p := range cfg.Policies {
+		c, err := version.NewConstraint(p.Allowed)
+		if err != nil {
+			return nil, err
+		}
+		rules.policies[p.PolicyID] = c
+	}
+	return rules, nil
+}
+
+// check returns ErrAgentVersionNotAllowed with the rule refusing the version of an agent of the policy. The agents
+// are only logged in the warn only mode.
+func (r *agentVersionRules) check(zlog zerolog.Logger, ver, policyID string) error {
+	if r == nil {
+		return nil
+	}
+	err := r.reason(ver, policyID)
+	if err == nil {
+		return nil
+	}
+	if r.warnOnly {
+		zlog.Warn().Err(err).Str("agentVersion", ver).Str(logger.PolicyID, policyID).Msg("agent version would be refused")
+		return nil
+	}
+	zlog.Info().Err(err).Str("agentVersion", ver).Str(logger.PolicyID, policyID).Msg("agent version refused")
+	return err
+}
+
+func (r *agentVersionRules) reason(ver, policyID string) error {
+	v, err := version.NewVersion(ver)
+	if err != nil {
+		return fmt.Errorf("%w: invalid version %s", ErrAgentVersionNotAllowed, ver)
+	}
+	if r.min != nil && v.LessThan(r.min) {
+		return fmt.Errorf("%w: version %s is below the minimum version %s", ErrAgentVersionNotAllowed, ver, r.min)
+	}
+	for _, c := range r.blocked {
+		if c.Check(v) {
+			return fmt.Errorf("%w: version %s is blocked by %q", ErrAgentVersionNotAllowed, ver, c)
+		}
+	}
+	if c, ok := r.policies[policyID]; ok && !c.Check(v) {
+		return fmt.Errorf("%w: version %s does not match the versions %q allowed by policy %s", ErrAgentVersionNotAllowed, ver, c, policyID)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestAgentVersionRules(t *testing.T) {
+	cfg := &config.AgentVersions{
+		MinVersion: "8.10.0",
+		Blocked:    []string{"8.13.1", ">= 8.14.0, < 8.14.2"},
+		Policies:   []config.PolicyAgentVersions{{PolicyID: "p1", Allowed: ">= 8.12"}},
+	}
+	tests := []struct {
+		name     string
+		ver      string
+		policyID string
+		err      string
+	}{
+		{name: "allowed", ver: "8.11.0", policyID: "p2"},
+		{name: "below minimum", ver: "8.9.3", policyID: "p2", err: "agent version is not allowed: version 8.9.3 is below the minimum version 8.10.0"},
+		{name: "blocked version", ver: "8.13.1", policyID: "p2", err: "agent version is not allowed: version 8.13.1 is blocked by \"8.13.1\""},
+		{name: "blocked range", ver: "8.14.1", policyID: "p2", err: "agent version is not allowed: version 8.14.1 is blocked by \">= 8.14.0, < 8.14.2\""},
+		{name: "after blocked range", ver: "8.14.2", policyID: "p2"},
+		{name: "allowed by policy", ver: "8.12.0", policyID: "p1"},
+		{name: "refused by policy", ver: "8.11.0", policyID: "p1", err: "agent version is not allowed: version 8.11.0 does not match the versions \">= 8.12\" allowed by policy p1"},
+	}
+	rules, err := newAgentVersionRules(cfg)
+	require.NoError(t, err)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := rules.check(zerolog.Nop(), tc.ver, tc.policyID)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrAgentVersionNotAllowed)
+			assert.EqualError(t, err, tc.err)
+
+			resp := NewHTTPErrResp(err)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+			assert.Equal(t, "AgentVersionNotAllowed", resp.Error)
+			assert.Equal(t, tc.err, resp.Message)
+		})
+	}
+
+	t.Run("warn only", func(t *testing.T) {
+		cfg := *cfg
+		cfg.WarnOnly = true
+		rules, err := newAgentVersionRules(&cfg)
+		require.NoError(t, err)
+		assert.NoError(t, rules.check(zerolog.Nop(), "8.9.3", "p2"))
+	})
+
+	t.Run("no rules", func(t *testing.T) {
+		rules, err := newAgentVersionRules(&config.AgentVersions{WarnOnly: true})
+		require.NoError(t, err)
+		assert.Nil(t, rules)
+		assert.NoError(t, rules.check(zerolog.Nop(), "7.13.0", "p1"))
+	})
+}
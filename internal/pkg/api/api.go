@@ -18,17 +18,26 @@ import (
 // FIXME: Cleanup needed for: metrics endpoint (actually a separate listener?), endpoint auth
 // FIXME: Should we use strict handler
 type apiServer struct {
-	ct     *CheckinT
-	et     *EnrollerT
-	at     *ArtifactT
-	ack    *AckT
-	st     *StatusT
-	sm     policy.SelfMonitor
-	bi     build.Info
-	ut     *UploadT
-	ft     *FileDeliveryT
-	pt     *PGPRetrieverT
-	bulker bulk.Bulk
+	ct      *CheckinT
+	et      *EnrollerT
+	at      *ArtifactT
+	ack     *AckT
+	st      *StatusT
+	sm      policy.SelfMonitor
+	bi      build.Info
+	ut      *UploadT
+	ft      *FileDeliveryT
+	pt      *PGPRetrieverT
+	pv      *PolicyValidatorT
+	rt      *RevokerT
+	prof    *ProfilerT
+	diag    *DiagnosticsT
+	drain   *DrainT
+	ast     *AgentSearchT
+	art     *AgentReassignT
+	pl      *PolicyLeadersT
+	promote *PromoteT
+	bulker  bulk.Bulk
 }
 
 // ensure api implements the ServerInterface
@@ -80,6 +89,33 @@ func (a *apiServer) AgentCheckin(w http.ResponseWriter, r *http.Request, id stri
 	}
 }
 
+func (a *apiServer) AgentRevoke(w http.ResponseWriter, r *http.Request, id string, params AgentRevokeParams) {
+	zlog := hlog.FromRequest(r).With().Str(LogAgentID, id).Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.rt.handleRevoke(zlog, w, r, id); err != nil {
+		cntRevoke.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) AgentReassign(w http.ResponseWriter, r *http.Request, params AgentReassignParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.art.handleReassign(zlog, w, r); err != nil {
+		cntAgentReassign.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) AgentSearch(w http.ResponseWriter, r *http.Request, params AgentSearchParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.ast.handleSearch(zlog, w, r); err != nil {
+		cntAgentSearch.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
 func (a *apiServer) Artifact(w http.ResponseWriter, r *http.Request, id string, sha2 string, params ArtifactParams) {
 	zlog := hlog.FromRequest(r).With().
 		Str(LogAgentID, id).
@@ -105,6 +141,15 @@ func (a *apiServer) UploadBegin(w http.ResponseWriter, r *http.Request, params U
 	}
 }
 
+func (a *apiServer) UploadStatus(w http.ResponseWriter, r *http.Request, id string, params UploadStatusParams) {
+	zlog := hlog.FromRequest(r).With().Str(LogAgentID, id).Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.ut.handleUploadStatus(zlog, w, r, id); err != nil {
+		cntUploadStatus.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
 func (a *apiServer) UploadComplete(w http.ResponseWriter, r *http.Request, id string, params UploadCompleteParams) {
 	zlog := hlog.FromRequest(r).With().Str(LogAgentID, id).Logger()
 	w.Header().Set("Content-Type", "application/json")
@@ -115,6 +160,15 @@ func (a *apiServer) UploadComplete(w http.ResponseWriter, r *http.Request, id st
 	}
 }
 
+func (a *apiServer) UploadContents(w http.ResponseWriter, r *http.Request, id string, params UploadContentsParams) {
+	zlog := hlog.FromRequest(r).With().Str("uploadID", id).Logger()
+	if err := a.ut.handleUploadContents(zlog, w, r, id); err != nil {
+		cntUploadStatus.IncError(err)
+		w.Header().Set("Content-Type", "application/json")
+		ErrorResp(w, r, err)
+	}
+}
+
 func (a *apiServer) UploadChunk(w http.ResponseWriter, r *http.Request, id string, chunkNum int, params UploadChunkParams) {
 	zlog := hlog.FromRequest(r).With().Str(LogAgentID, id).Logger()
 	w.Header().Set("Content-Type", "application/json")
@@ -148,12 +202,67 @@ func (a *apiServer) GetPGPKey(w http.ResponseWriter, r *http.Request, major, min
 	}
 }
 
+func (a *apiServer) PolicyValidate(w http.ResponseWriter, r *http.Request, params PolicyValidateParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.pv.handleValidate(zlog, w, r); err != nil {
+		cntPolicyValidate.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) UpdateProfiler(w http.ResponseWriter, r *http.Request, params UpdateProfilerParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.prof.handleUpdate(zlog, w, r); err != nil {
+		cntProfiler.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) GetDiagnostics(w http.ResponseWriter, r *http.Request, params GetDiagnosticsParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	if err := a.diag.handleDiagnostics(zlog, w, r, a.bi); err != nil {
+		cntDiagnostics.IncError(err)
+		w.Header().Set("Content-Type", "application/json")
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) GetPolicyLeaders(w http.ResponseWriter, r *http.Request, params GetPolicyLeadersParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	w.Header().Set("Content-Type", "application/json")
+	if err := a.pl.handlePolicyLeaders(zlog, w, r); err != nil {
+		cntPolicyLeaders.IncError(err)
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) Drain(w http.ResponseWriter, r *http.Request, params DrainParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	if err := a.drain.handleDrain(zlog, w, r); err != nil {
+		cntDrain.IncError(err)
+		w.Header().Set("Content-Type", "application/json")
+		ErrorResp(w, r, err)
+	}
+}
+
+func (a *apiServer) Promote(w http.ResponseWriter, r *http.Request, params PromoteParams) {
+	zlog := hlog.FromRequest(r).With().Logger()
+	if err := a.promote.handlePromote(zlog, w, r); err != nil {
+		cntPromote.IncError(err)
+		w.Header().Set("Content-Type", "application/json")
+		ErrorResp(w, r, err)
+	}
+}
+
 func (a *apiServer) Status(w http.ResponseWriter, r *http.Request, params StatusParams) {
 	zlog := hlog.FromRequest(r).With().
 		Str("mod", kStatusMod).
 		Logger()
 	w.Header().Set("Content-Type", "application/json")
-	err := a.st.handleStatus(zlog, a.sm, a.bi, r, w)
+	verbose := params.Verbose != nil && *params.Verbose
+	err := a.st.handleStatus(zlog, a.sm, a.bi, r, w, verbose)
 	if err != nil {
 		cntStatus.IncError(err)
 		ErrorResp(w, r, err)
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// auditActions are the actions of the audit events of the operations that are audited, the mutating requests of the
+// agents and the admin endpoints.
+var auditActions = map[string]audit.Action{
+	"enroll":         audit.ActionAgentEnroll,
+	"acks":           audit.ActionAgentAck,
+	"revoke":         audit.ActionAgentRevoke,
+	"agentReassign":  audit.ActionAgentReassign,
+	"agentSearch":    audit.ActionAgentSearch,
+	"uploadBegin":    audit.ActionUploadStart,
+	"uploadComplete": audit.ActionUploadFinish,
+	"uploadContents": audit.ActionUploadRead,
+	"policyLeaders":  audit.ActionPolicyLeaders,
+	"profiler":       audit.ActionProfilerUpdate,
+	"diagnostics":    audit.ActionDiagnostics,
+	"drain":          audit.ActionServerDrain,
+	"promote":        audit.ActionServerPromote,
+}
+
+// auditRequests records an audit event for each request of an audited operation once it is handled, the
+// authentication adds the agent, the policy and the API key of the request to the event.
+func auditRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action, ok := auditActions[requestOperation(r)]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		e := &audit.Event{Action: action, RequestID: r.Header.Get(logger.HeaderRequestID)}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			e.ClientIP = host
+		}
+
+		rc := logger.NewResponseCounter(w)
+		next.ServeHTTP(rc, r.WithContext(audit.WithEvent(r.Context(), e)))
+
+		e.StatusCode = rc.StatusCode()
+		if e.StatusCode == 0 {
+			e.StatusCode = http.StatusOK
+		}
+		e.Outcome = audit.OutcomeSuccess
+		if e.StatusCode >= http.StatusBadRequest {
+			e.Outcome = audit.OutcomeFailure
+		}
+		audit.Record(r.Context(), *e, nil)
+	})
+}
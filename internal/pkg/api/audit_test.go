@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+func TestAuditRequests(t *testing.T) {
+	cfg := config.Audit{Enabled: true}
+	cfg.InitDefaults()
+	cfg.Key = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	cfg.Files.Path = t.TempDir()
+	l, err := audit.Open(context.Background(), cfg)
+	require.NoError(t, err)
+	audit.SetLog(l)
+	t.Cleanup(func() {
+		audit.SetLog(nil)
+		_ = l.Close()
+	})
+
+	handler := auditRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := audit.FromContext(r.Context())
+		e.AgentID, e.PolicyID = "agent", "policy"
+		if r.URL.Path == "/api/fleet/agents/agent/acks" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, path := range []string{"/api/fleet/agents/enroll", "/api/fleet/agents/agent/acks", "/api/status"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(logger.HeaderRequestID, "req-"+path)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.Files.Path, "*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2, "the status requests are not audited")
+
+	var enroll, ack map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &enroll))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &ack))
+	assert.Equal(t, "agent-enroll", enroll["event.action"])
+	assert.Equal(t, "success", enroll["event.outcome"])
+	assert.Equal(t, "agent", enroll["fleet.agent.id"])
+	assert.Equal(t, "policy", enroll["fleet.policy.id"])
+	assert.Equal(t, "req-/api/fleet/agents/enroll", enroll["http.request.id"])
+	assert.Equal(t, "192.0.2.1", enroll["client.ip"])
+	assert.EqualValues(t, http.StatusOK, enroll["http.response.status_code"])
+
+	assert.Equal(t, "agent-ack", ack["event.action"])
+	assert.Equal(t, "failure", ack["event.outcome"])
+	assert.EqualValues(t, http.StatusNotFound, ack["http.response.status_code"])
+}
@@ -6,13 +6,18 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 
 	"github.com/rs/zerolog/hlog"
@@ -24,6 +29,9 @@ var (
 	ErrAgentCorrupted   = errors.New("agent record corrupted")
 	ErrAgentInactive    = errors.New("agent inactive")
 	ErrAgentIdentity    = errors.New("agent header contains wrong identifier")
+
+	ErrServiceTokenAuthDisabled = errors.New("service token authentication is not enabled")
+	ErrServiceTokenNotAllowed   = errors.New("service token is not allowed")
 )
 
 // authAPIKey authenticates the provided API key, it checks that the key exists and is enabled.
@@ -38,6 +46,7 @@ func authAPIKey(r *http.Request, bulker bulk.Bulk, c cache.Cache) (*apikey.APIKe
 	if err != nil {
 		return nil, err
 	}
+	audit.FromContext(r.Context()).APIKeyID = key.ID
 
 	if c.ValidAPIKey(*key) {
 		span.Context.SetLabel("api_key_cache_hit", true)
@@ -85,14 +94,120 @@ func authAPIKey(r *http.Request, bulker bulk.Bulk, c cache.Cache) (*apikey.APIKe
 	return key, err
 }
 
+// authServiceToken authenticates an Elasticsearch service token, it returns the identity of the token,
+// "<service account>/<token name>". Validated tokens are cached for the API key TTL.
+func authServiceToken(ctx context.Context, bulker bulk.Bulk, c cache.Cache, token string) (string, error) {
+	if identity, ok := c.GetServiceToken(token); ok {
+		return identity, nil
+	}
+	info, err := bulker.ServiceTokenAuth(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	identity := info.ServiceTokenIdentity()
+	c.SetServiceToken(token, identity)
+	return identity, nil
+}
+
+// authAgentServiceToken ensures that the service token of the request is the one the agent enrolled with and that it
+// is still allowed by the configuration. The agent id is required as service tokens are shared by agents.
+func authAgentServiceToken(r *http.Request, id *string, token string, cfg *config.Server, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+	ctx := r.Context()
+	start := time.Now()
+	if cfg == nil || !cfg.ServiceTokenAuth.Enabled {
+		return nil, ErrServiceTokenAuthDisabled
+	}
+	if id == nil {
+		return nil, ErrAgentIdentity
+	}
+	zlog := hlog.FromRequest(r).With().Str(LogAgentID, *id).Logger()
+
+	identity, err := authServiceToken(ctx, bulker, c, token)
+	if err != nil {
+		zlog.Info().
+			Err(err).
+			Int64(ECSEventDuration, time.Since(start).Nanoseconds()).
+			Msg("Service token fail authentication")
+		return nil, err
+	}
+	zlog = zlog.With().Str(LogServiceToken, identity).Logger()
+	if !cfg.ServiceTokenAuth.Allowed(identity) {
+		zlog.Warn().
+			Err(ErrServiceTokenNotAllowed).
+			Msg("service token is not allowed")
+		return nil, ErrServiceTokenNotAllowed
+	}
+
+	agent, err := getAgent(ctx, bulker, c, *id)
+	if errors.Is(err, dl.ErrNotFound) {
+		return nil, ErrAgentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("GetAgent: %w", err)
+	}
+	e := audit.FromContext(ctx)
+	e.AgentID, e.PolicyID = agent.Id, agent.PolicyID
+
+	tx := apm.TransactionFromContext(ctx)
+	if tx != nil {
+		tx.Context.SetLabel("agent_id", agent.Id)
+	}
+
+	if agent.Agent == nil {
+		zlog.Warn().
+			Err(ErrAgentCorrupted).
+			Msg("agent record does not contain required metadata section")
+		return nil, ErrAgentCorrupted
+	}
+
+	// the service token must be the one the agent enrolled with, an agent enrolled with an access API key
+	// can not switch to a service token.
+	if agent.AccessServiceToken == "" || agent.AccessServiceToken != identity {
+		zlog.Warn().
+			Err(ErrServiceTokenNotAllowed).
+			Str("agent.AccessServiceToken", agent.AccessServiceToken).
+			Msg("agent service token mismatch agent record")
+		return nil, ErrServiceTokenNotAllowed
+	}
+
+	if err := verifyClientCertificate(r, &agent); err != nil {
+		zlog.Warn().
+			Err(err).
+			Msg("client certificate mismatch agent record")
+		return nil, err
+	}
+
+	if err := verifyRequestSignature(r, agent.Id); err != nil {
+		zlog.Warn().
+			Err(err).
+			Msg("request signature verification failed")
+		return nil, err
+	}
+
+	if !agent.Active {
+		zlog.Info().
+			Err(ErrAgentInactive).
+			Msg("agent record inactive")
+		return &agent, ErrAgentInactive
+	}
+
+	zlog.Debug().
+		Int64(ECSEventDuration, time.Since(start).Nanoseconds()).
+		Msg("Service token authenticated")
+	return &agent, nil
+}
+
 // authAgent ensures that the requested API-Key is associated with the correct agent.
 // If all succeeds, it returns the agent associated with id.
-func authAgent(r *http.Request, id *string, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+func authAgent(r *http.Request, id *string, cfg *config.Server, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 	span, ctx := apm.StartSpan(r.Context(), "authAgent", "auth")
 	defer span.End()
 	r = r.WithContext(ctx)
 	start := time.Now()
 
+	if token, ok := apikey.ExtractServiceToken(r); ok {
+		return authAgentServiceToken(r, id, token, cfg, bulker, c)
+	}
+
 	// authenticate
 	key, err := authAPIKey(r, bulker, c)
 	if err != nil {
@@ -119,13 +234,15 @@ func authAgent(r *http.Request, id *string, bulker bulk.Bulk, c cache.Cache) (*m
 	var agent *model.Agent
 	// If we have the agentID retrieve the agent document with a get (more performant) instead of triggering a search
 	if id != nil {
-		agent, err = getAgentAndVerifyAPIKeyID(ctx, bulker, *id, key.ID)
+		agent, err = getAgentAndVerifyAPIKeyID(ctx, bulker, c, *id, key.ID)
 	} else {
-		agent, err = findAgentByAPIKeyID(ctx, bulker, key.ID)
+		agent, err = findAgentByAPIKeyID(ctx, bulker, c, key.ID)
 	}
 	if err != nil {
 		return nil, err
 	}
+	e := audit.FromContext(ctx)
+	e.AgentID, e.PolicyID = agent.Id, agent.PolicyID
 
 	tx := apm.TransactionFromContext(ctx)
 	if tx != nil {
@@ -166,6 +283,23 @@ func authAgent(r *http.Request, id *string, bulker bulk.Bulk, c cache.Cache) (*m
 		return nil, ErrAgentIdentity
 	}
 
+	// validate that the client certificate is the one the agent enrolled with, so the access ApiKey alone does
+	// not authenticate the agent
+	if err := verifyClientCertificate(r, agent); err != nil {
+		zlog.Warn().
+			Err(err).
+			Msg("client certificate mismatch agent record")
+		return nil, err
+	}
+
+	// validate the signature of the request with the signing key of the agent
+	if err := verifyRequestSignature(r, agent.Id); err != nil {
+		zlog.Warn().
+			Err(err).
+			Msg("request signature verification failed")
+		return nil, err
+	}
+
 	// validate active, an api key can be valid for an inactive agent record
 	// if it is in our cache and has not timed out.
 	if !agent.Active {
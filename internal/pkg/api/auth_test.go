@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testcache "github.com/elastic/fleet-server/v7/internal/pkg/testing/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/certs"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestAuthAgentServiceToken(t *testing.T) {
+	serviceInfo := &apikey.SecurityInfo{
+		UserName: "elastic/fleet-server",
+		Token:    &apikey.TokenInfo{Name: "agents", Type: "_service_account_index"},
+	}
+	agentDoc := func(t *testing.T, agent model.Agent) *bulk.MgetResponseItem {
+		b, err := json.Marshal(agent)
+		require.NoError(t, err)
+		return &bulk.MgetResponseItem{Found: true, Source: b}
+	}
+	agentID := "agent-id"
+
+	cert := certs.GenCert(t, certs.GenCA(t))
+	clientCert := clientCertificateIdentity(config.ClientCertificateIdentitySubject, cert.Leaf)
+
+	enabled := &config.Server{ServiceTokenAuth: config.ServiceTokenAuth{Enabled: true}}
+
+	tests := []struct {
+		name  string
+		id    *string
+		cfg   *config.Server
+		agent model.Agent
+		tls   *tls.ConnectionState
+		err   error
+	}{{
+		name:  "agent bound to the token",
+		id:    &agentID,
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/agents", Agent: &model.AgentMetadata{ID: agentID}},
+	}, {
+		name: "agent id is required",
+		err:  ErrAgentIdentity,
+	}, {
+		name:  "agent bound to another token",
+		id:    &agentID,
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/other", Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrServiceTokenNotAllowed,
+	}, {
+		name:  "agent enrolled with an access API key",
+		id:    &agentID,
+		agent: model.Agent{Active: true, AccessAPIKeyID: "key-id", Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrServiceTokenNotAllowed,
+	}, {
+		name:  "inactive agent",
+		id:    &agentID,
+		agent: model.Agent{AccessServiceToken: "elastic/fleet-server/agents", Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrAgentInactive,
+	}, {
+		name:  "agent bound to its client certificate",
+		id:    &agentID,
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/agents", ClientCertificate: clientCert, Agent: &model.AgentMetadata{ID: agentID}},
+		tls:   &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert.Leaf}}},
+	}, {
+		name:  "agent bound to a client certificate without a certificate",
+		id:    &agentID,
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/agents", ClientCertificate: clientCert, Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrClientCertificateRequired,
+	}, {
+		name:  "service token authentication disabled after the enrollment",
+		id:    &agentID,
+		cfg:   &config.Server{},
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/agents", Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrServiceTokenAuthDisabled,
+	}, {
+		name:  "principal removed after the enrollment",
+		id:    &agentID,
+		cfg:   &config.Server{ServiceTokenAuth: config.ServiceTokenAuth{Enabled: true, Principals: []string{"elastic/other"}}},
+		agent: model.Agent{Active: true, AccessServiceToken: "elastic/fleet-server/agents", Agent: &model.AgentMetadata{ID: agentID}},
+		err:   ErrServiceTokenNotAllowed,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.cfg
+			if cfg == nil {
+				cfg = enabled
+			}
+			c := testcache.NewMockCache()
+			c.On("GetServiceToken", "token").Return("", false).Once()
+			c.On("SetServiceToken", "token", "elastic/fleet-server/agents").Return().Maybe()
+			c.On("NotFound", cache.NotFoundAgent, agentID).Return(false).Maybe()
+			bulker := ftesting.NewMockBulk()
+			bulker.On("ServiceTokenAuth", mock.Anything, "token").Return(serviceInfo, nil).Once()
+			bulker.On("ReadRaw", mock.Anything, dl.FleetAgents, agentID, mock.Anything).Return(agentDoc(t, tc.agent), nil).Maybe()
+
+			r := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent-id/checkin", nil)
+			r.Header.Set("Authorization", "Bearer token")
+			r.TLS = tc.tls
+			r = r.WithContext(testlog.SetLogger(t).WithContext(r.Context()))
+
+			agent, err := authAgent(r, tc.id, cfg, bulker, c)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, agentID, agent.Id)
+
+			// the validated token is cached
+			c.On("GetServiceToken", "token").Return("elastic/fleet-server/agents", true).Once()
+			_, err = authAgent(r, tc.id, cfg, bulker, c)
+			require.NoError(t, err)
+			bulker.AssertNumberOfCalls(t, "ServiceTokenAuth", 1)
+			c.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const ocspMaxCacheSize = 10000
+
+var (
+	ErrClientCertificateRequired = errors.New("client certificate required")
+	ErrClientCertificateMismatch = errors.New("client certificate does not match the agent")
+
+	errCertificateRevoked = errors.New("client certificate revoked")
+)
+
+// requestCertificate returns the verified client certificate of the request, nil when there is none.
+func requestCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// clientCertificateIdentity returns the identity of cert an agent is bound to, prefixed by its kind so the agents
+// keep the kind of identity they enrolled with.
+func clientCertificateIdentity(kind string, cert *x509.Certificate) string {
+	if kind == config.ClientCertificateIdentityPublicKey {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		return kind + ":" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	return config.ClientCertificateIdentitySubject + ":" + cert.Subject.String()
+}
+
+// verifyClientCertificate ensures the request has the client certificate the agent is bound to.
+// The agents enrolled without a client certificate are not bound.
+func verifyClientCertificate(r *http.Request, agent *model.Agent) error {
+	if agent.ClientCertificate == "" {
+		return nil
+	}
+	cert := requestCertificate(r)
+	if cert == nil {
+		return ErrClientCertificateRequired
+	}
+	kind, _, _ := strings.Cut(agent.ClientCertificate, ":")
+	if clientCertificateIdentity(kind, cert) != agent.ClientCertificate {
+		return ErrClientCertificateMismatch
+	}
+	return nil
+}
+
+// revocationChecker rejects the handshakes of the client certificates revoked by the CRLs or by the OCSP
+// responders of their issuers. Only the certificates of the clients are checked, not their intermediates.
+type revocationChecker struct {
+	crls []revocationList
+	ocsp *ocspChecker
+}
+
+type revocationList struct {
+	crl     *x509.RevocationList
+	serials map[string]struct{}
+}
+
+// newRevocationChecker returns the checker of the configuration, the OCSP responses of ocsp are kept across
+// reloads. It returns nil when the certificates are not checked for revocation.
+func newRevocationChecker(cfg config.ClientCertificates, ocsp *ocspChecker) (*revocationChecker, error) {
+	if !cfg.Revocation() {
+		return nil, nil
+	}
+	c := &revocationChecker{}
+	if cfg.OCSP.Enabled {
+		c.ocsp = ocsp
+	}
+	for _, path := range cfg.CRLs {
+		crls, err := loadCRLs(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load certificate revocation list %s: %w", path, err)
+		}
+		c.crls = append(c.crls, crls...)
+	}
+	return c, nil
+}
+
+// loadCRLs reads the CRLs of a PEM or DER file.
+func loadCRLs(path string) ([]revocationList, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ders [][]byte
+	for rest := b; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "X509 CRL" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{b}
+	}
+
+	lists := make([]revocationList, 0, len(ders))
+	for _, der := range ders {
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, err
+		}
+		serials := make(map[string]struct{}, len(crl.RevokedCertificateEntries))
+		for _, entry := range crl.RevokedCertificateEntries {
+			serials[entry.SerialNumber.String()] = struct{}{}
+		}
+		lists = append(lists, revocationList{crl: crl, serials: serials})
+	}
+	return lists, nil
+}
+
+// verifyConnection is the VerifyConnection callback of the TLS configuration.
+func (c *revocationChecker) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) < 2 {
+		// no client certificate, or a self-signed one without an issuer to revoke it
+		return nil
+	}
+	leaf, issuer := cs.VerifiedChains[0][0], cs.VerifiedChains[0][1]
+	for _, l := range c.crls {
+		if !bytes.Equal(l.crl.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+		if _, ok := l.serials[leaf.SerialNumber.String()]; !ok {
+			continue
+		}
+		// the lists of the other issuers with the same subject do not revoke the certificate
+		if l.crl.CheckSignatureFrom(issuer) == nil {
+			return errCertificateRevoked
+		}
+	}
+	if c.ocsp != nil {
+		return c.ocsp.check(leaf, issuer)
+	}
+	return nil
+}
+
+// ocspChecker checks the status of the client certificates with the OCSP responders of their issuers.
+type ocspChecker struct {
+	cfg    config.OCSP
+	client *http.Client
+
+	mut   sync.Mutex
+	cache map[string]ocspStatus
+}
+
+type ocspStatus struct {
+	revoked    bool
+	nextUpdate time.Time
+}
+
+func newOCSPChecker(cfg config.OCSP) *ocspChecker {
+	return &ocspChecker{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cache:  make(map[string]ocspStatus),
+	}
+}
+
+// check returns an error when the certificate is revoked, or when its status can not be retrieved and soft fail
+// is not enabled. The certificates without an OCSP responder are not checked.
+func (o *ocspChecker) check(leaf, issuer *x509.Certificate) error {
+	if len(leaf.OCSPServer) == 0 {
+		return nil
+	}
+	key := string(issuer.RawSubjectPublicKeyInfo) + leaf.SerialNumber.String()
+	now := time.Now()
+	o.mut.Lock()
+	status, ok := o.cache[key]
+	o.mut.Unlock()
+	if !ok || now.After(status.nextUpdate) {
+		var err error
+		status, err = o.fetch(leaf, issuer)
+		if err != nil {
+			if o.cfg.SoftFail {
+				return nil
+			}
+			return fmt.Errorf("unable to check the client certificate status: %w", err)
+		}
+		o.store(key, status, now)
+	}
+	if status.revoked {
+		return errCertificateRevoked
+	}
+	return nil
+}
+
+func (o *ocspChecker) fetch(leaf, issuer *x509.Certificate) (ocspStatus, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return ocspStatus{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), o.cfg.Timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(req))
+	if err != nil {
+		return ocspStatus{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return ocspStatus{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ocspStatus{}, fmt.Errorf("ocsp responder status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return ocspStatus{}, err
+	}
+	parsed, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return ocspStatus{}, err
+	}
+	switch parsed.Status {
+	case ocsp.Good:
+		return ocspStatus{nextUpdate: parsed.NextUpdate}, nil
+	case ocsp.Revoked:
+		return ocspStatus{revoked: true, nextUpdate: parsed.NextUpdate}, nil
+	}
+	return ocspStatus{}, errors.New("ocsp responder does not know the certificate")
+}
+
+// store caches the status until its next update, the responses without a next update are not cached.
+func (o *ocspChecker) store(key string, status ocspStatus, now time.Time) {
+	if !status.nextUpdate.After(now) {
+		return
+	}
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	if len(o.cache) >= ocspMaxCacheSize {
+		for k, s := range o.cache {
+			if now.After(s.nextUpdate) {
+				delete(o.cache, k)
+			}
+		}
+		if len(o.cache) >= ocspMaxCacheSize {
+			return
+		}
+	}
+	o.cache[key] = status
+}
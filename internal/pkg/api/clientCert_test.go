@@ -0,0 +1,210 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/certs"
+)
+
+// genClientCert returns a client certificate signed by ca with the serial number and the OCSP responder.
+func genClientCert(t *testing.T, ca tls.Certificate, serial int64, cn string, ocspServer string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if ocspServer != "" {
+		tmpl.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Leaf, &key.PublicKey, ca.PrivateKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestVerifyClientCertificate(t *testing.T) {
+	ca := certs.GenCA(t)
+	cert := genClientCert(t, ca, 10, "agent-1", "")
+	renewed := genClientCert(t, ca, 11, "agent-1", "")
+	other := genClientCert(t, ca, 12, "agent-2", "")
+	request := func(cert *x509.Certificate) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent-id/checkin", nil)
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.Leaf}}}
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		identity string
+		cert     *x509.Certificate
+		err      error
+	}{
+		{name: "unbound agent", cert: nil},
+		{name: "subject", identity: config.ClientCertificateIdentitySubject, cert: cert},
+		{name: "subject of a renewed certificate", identity: config.ClientCertificateIdentitySubject, cert: renewed},
+		{name: "other subject", identity: config.ClientCertificateIdentitySubject, cert: other, err: ErrClientCertificateMismatch},
+		{name: "public key", identity: config.ClientCertificateIdentityPublicKey, cert: cert},
+		{name: "public key of a renewed certificate", identity: config.ClientCertificateIdentityPublicKey, cert: renewed, err: ErrClientCertificateMismatch},
+		{name: "no certificate", identity: config.ClientCertificateIdentitySubject, err: ErrClientCertificateRequired},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			agent := &model.Agent{}
+			if tc.identity != "" {
+				agent.ClientCertificate = clientCertificateIdentity(tc.identity, cert)
+			}
+			err := verifyClientCertificate(request(tc.cert), agent)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	et := &EnrollerT{cfg: &config.Server{ClientCertificates: config.ClientCertificates{Enabled: true, Identity: config.ClientCertificateIdentitySubject}}}
+	_, err := et.clientCertificate(request(nil))
+	assert.ErrorIs(t, err, ErrClientCertificateRequired)
+	identity, err := et.clientCertificate(request(cert))
+	require.NoError(t, err)
+	assert.Equal(t, "subject:CN=agent-1", identity)
+}
+
+func TestRevocationCheckerCRL(t *testing.T) {
+	ca := certs.GenCA(t)
+	otherCA := certs.GenCA(t)
+	revoked := genClientCert(t, ca, 10, "revoked", "")
+	valid := genClientCert(t, ca, 11, "valid", "")
+	sameSerial := genClientCert(t, otherCA, 10, "other", "")
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now(),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()}},
+	}, ca.Leaf, ca.PrivateKey.(crypto.Signer))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ca.crl")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0o600))
+
+	checker, err := newRevocationChecker(config.ClientCertificates{CRLs: []string{path}}, nil)
+	require.NoError(t, err)
+	verify := func(cert, issuer *x509.Certificate) error {
+		return checker.verifyConnection(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, issuer}}})
+	}
+	assert.ErrorIs(t, verify(revoked, ca.Leaf), errCertificateRevoked)
+	assert.NoError(t, verify(valid, ca.Leaf))
+	assert.NoError(t, verify(sameSerial, otherCA.Leaf), "the list of an issuer does not revoke the certificates of the other issuers")
+	assert.NoError(t, checker.verifyConnection(tls.ConnectionState{}), "no client certificate")
+
+	// DER lists are read as well
+	require.NoError(t, os.WriteFile(path, der, 0o600))
+	checker, err = newRevocationChecker(config.ClientCertificates{CRLs: []string{path}}, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, verify(revoked, ca.Leaf), errCertificateRevoked)
+
+	require.NoError(t, os.WriteFile(path, []byte("not a list"), 0o600))
+	_, err = newRevocationChecker(config.ClientCertificates{CRLs: []string{path}}, nil)
+	assert.Error(t, err)
+}
+
+func TestRevocationCheckerOCSP(t *testing.T) {
+	ca := certs.GenCA(t)
+	var requests atomic.Int32
+	var unavailable atomic.Bool
+	const revokedSerial = 10
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if unavailable.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+		status := ocsp.Good
+		if req.SerialNumber.Int64() == revokedSerial {
+			status = ocsp.Revoked
+		}
+		resp, err := ocsp.CreateResponse(ca.Leaf, ca.Leaf, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now(),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now(),
+		}, ca.PrivateKey.(crypto.Signer))
+		require.NoError(t, err)
+		_, _ = w.Write(resp)
+	}))
+	defer responder.Close()
+
+	revoked := genClientCert(t, ca, revokedSerial, "revoked", responder.URL)
+	valid := genClientCert(t, ca, 11, "valid", responder.URL)
+	noResponder := genClientCert(t, ca, 12, "no responder", "")
+
+	cfg := config.ClientCertificates{OCSP: config.OCSP{Enabled: true, Timeout: time.Second}}
+	checker, err := newRevocationChecker(cfg, newOCSPChecker(cfg.OCSP))
+	require.NoError(t, err)
+	verify := func(cert *x509.Certificate) error {
+		return checker.verifyConnection(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca.Leaf}}})
+	}
+	assert.ErrorIs(t, verify(revoked), errCertificateRevoked)
+	assert.NoError(t, verify(valid))
+	assert.NoError(t, verify(noResponder))
+	assert.Equal(t, int32(2), requests.Load())
+
+	t.Run("cached responses", func(t *testing.T) {
+		assert.ErrorIs(t, verify(revoked), errCertificateRevoked)
+		assert.NoError(t, verify(valid))
+		assert.Equal(t, int32(2), requests.Load())
+	})
+
+	t.Run("unavailable responder", func(t *testing.T) {
+		unavailable.Store(true)
+		uncached := genClientCert(t, ca, 13, "uncached", responder.URL)
+		assert.ErrorContains(t, verify(uncached), "unable to check the client certificate status")
+
+		cfg.OCSP.SoftFail = true
+		softFail, err := newRevocationChecker(cfg, newOCSPChecker(cfg.OCSP))
+		require.NoError(t, err)
+		assert.NoError(t, softFail.verifyConnection(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{uncached, ca.Leaf}}}))
+	})
+}
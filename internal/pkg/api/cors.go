@@ -0,0 +1,124 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// cors adds the CORS headers to the responses of the routes configured for the cross-origin requests of the allowed
+// origins, and answers their preflight requests. The responses to the other origins have no CORS header so the
+// browsers do not expose them.
+type cors struct {
+	cfg       *config.CORS
+	anyOrigin bool
+	origins   map[string]struct{}
+	methods   map[string]struct{}
+	headers   map[string]struct{}
+	routes    map[string]struct{}
+}
+
+func newCORS(cfg *config.CORS) *cors {
+	c := &cors{
+		cfg:     cfg,
+		origins: make(map[string]struct{}, len(cfg.AllowedOrigins)),
+		methods: make(map[string]struct{}, len(cfg.AllowedMethods)),
+		headers: make(map[string]struct{}, len(cfg.AllowedHeaders)),
+		routes:  make(map[string]struct{}, len(cfg.Routes)),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == config.CORSAnyOrigin {
+			c.anyOrigin = true
+		}
+		c.origins[strings.TrimSuffix(origin, "/")] = struct{}{}
+	}
+	for _, method := range cfg.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = struct{}{}
+	}
+	for _, header := range cfg.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	for _, route := range cfg.Routes {
+		c.routes[route] = struct{}{}
+	}
+	return c
+}
+
+func (c *cors) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := c.routes[requestOperation(r)]; !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := c.allowsOrigin(origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			// the preflight requests are not authenticated, they are answered before the routes
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if allowed && c.allowsPreflight(r) {
+				c.writeOrigin(w, origin)
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.cfg.AllowedMethods, ", "))
+				if len(c.cfg.AllowedHeaders) > 0 {
+					w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.cfg.AllowedHeaders, ", "))
+				}
+				if c.cfg.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if allowed {
+			c.writeOrigin(w, origin)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *cors) allowsOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	_, ok := c.origins[origin]
+	return ok
+}
+
+// allowsPreflight returns true when the method and all the headers of the preflight request are allowed.
+func (c *cors) allowsPreflight(r *http.Request) bool {
+	if _, ok := c.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))]; !ok {
+		return false
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if _, ok := c.headers[http.CanonicalHeaderKey(header)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *cors) writeOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", config.CORSAnyOrigin)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := &config.CORS{Enabled: true, AllowedOrigins: []string{"https://dashboard.example.com"}, AllowCredentials: true}
+	cfg.InitDefaults()
+	handler := newCORS(cfg).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		headers     map[string]string
+		status      int
+		allowOrigin string
+		allowHeader string
+	}{
+		{
+			name:        "allowed origin",
+			method:      http.MethodGet,
+			path:        "/api/status",
+			headers:     map[string]string{"Origin": "https://dashboard.example.com"},
+			status:      http.StatusTeapot,
+			allowOrigin: "https://dashboard.example.com",
+		},
+		{
+			name:    "origin not allowed",
+			method:  http.MethodGet,
+			path:    "/api/status",
+			headers: map[string]string{"Origin": "https://evil.example.com"},
+			status:  http.StatusTeapot,
+		},
+		{
+			name:    "route not allowed",
+			method:  http.MethodPost,
+			path:    "/api/fleet/agents/enroll",
+			headers: map[string]string{"Origin": "https://dashboard.example.com"},
+			status:  http.StatusTeapot,
+		},
+		{
+			name:   "same origin",
+			method: http.MethodGet,
+			path:   "/api/status",
+			status: http.StatusTeapot,
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			path:   "/api/status",
+			headers: map[string]string{
+				"Origin":                         "https://dashboard.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "authorization, elastic-api-version",
+			},
+			status:      http.StatusNoContent,
+			allowOrigin: "https://dashboard.example.com",
+			allowHeader: "Authorization, Content-Type, Elastic-Api-Version",
+		},
+		{
+			name:   "preflight of a method not allowed",
+			method: http.MethodOptions,
+			path:   "/api/status",
+			headers: map[string]string{
+				"Origin":                        "https://dashboard.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "preflight of a header not allowed",
+			method: http.MethodOptions,
+			path:   "/api/status",
+			headers: map[string]string{
+				"Origin":                         "https://dashboard.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			status: http.StatusNoContent,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, tc.allowOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tc.allowHeader, w.Header().Get("Access-Control-Allow-Headers"))
+			if tc.allowOrigin != "" {
+				assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := &config.CORS{Enabled: true, AllowedOrigins: []string{config.CORSAnyOrigin}}
+	cfg.InitDefaults()
+	handler := newCORS(cfg).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/status", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
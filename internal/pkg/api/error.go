@@ -22,6 +22,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/file/uploader"
 	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
 	"go.elastic.co/apm/v2"
 
 	"github.com/rs/zerolog"
@@ -40,6 +41,7 @@ const (
 	LogAgentID        = logger.AgentID
 	LogEnrollAPIKeyID = logger.EnrollAPIKeyID
 	LogAccessAPIKeyID = logger.AccessAPIKeyID
+	LogServiceToken   = logger.AccessServiceToken
 )
 
 // BadRequestErr is used for request validation errors. These can be json
@@ -117,6 +119,15 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			ErrAgentVersionNotAllowed,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"AgentVersionNotAllowed",
+				"",
+				zerolog.InfoLevel,
+			},
+		},
 		{
 			dl.ErrNotFound,
 			HTTPErrResp{
@@ -171,6 +182,15 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			es.ErrTimeout,
+			HTTPErrResp{
+				http.StatusServiceUnavailable,
+				"ElasticsearchTimeout",
+				"elasticsearch query timed out",
+				zerolog.WarnLevel,
+			},
+		},
 		{
 			ErrUpdatingInactiveAgent,
 			HTTPErrResp{
@@ -189,6 +209,123 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			ErrPolicyValidateForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrPolicyValidateForbidden",
+				"API key is not allowed to read policies",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrProfilerForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrProfilerForbidden",
+				"API key is not allowed to change the profiler settings",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrDiagnosticsForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrDiagnosticsForbidden",
+				"API key is not allowed to collect diagnostics",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrDrainForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrDrainForbidden",
+				"API key is not allowed to drain the server",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrNamespaceForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrNamespaceForbidden",
+				"API key is not allowed in the namespace",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrAgentSearchForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrAgentSearchForbidden",
+				"API key is not allowed to search agents",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrAgentReassignForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrAgentReassignForbidden",
+				"API key is not allowed to reassign agents",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrPolicyLeadersForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrPolicyLeadersForbidden",
+				"API key is not allowed to read the policy leaders",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrPromoteForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrPromoteForbidden",
+				"API key is not allowed to promote the server",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrStandby,
+			HTTPErrResp{
+				http.StatusServiceUnavailable,
+				"ErrStandby",
+				"server is in standby",
+				zerolog.DebugLevel,
+			},
+		},
+		{
+			ErrAddressDenied,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrAddressDenied",
+				"client address is not allowed",
+				zerolog.DebugLevel,
+			},
+		},
+		{
+			ErrRouteNotServed,
+			HTTPErrResp{
+				http.StatusNotFound,
+				"ErrRouteNotServed",
+				"route is not served by this listener",
+				zerolog.DebugLevel,
+			},
+		},
+		{
+			ErrPolicyDataRequired,
+			HTTPErrResp{
+				http.StatusBadRequest,
+				"ErrPolicyDataRequired",
+				"policy data is required",
+				zerolog.InfoLevel,
+			},
+		},
 		{
 			ErrAgentIdentity,
 			HTTPErrResp{
@@ -225,6 +362,87 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			ErrAgentRevokeForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrAgentRevokeForbidden",
+				"API key is not allowed to revoke agents",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrServiceTokenAuthDisabled,
+			HTTPErrResp{
+				http.StatusBadRequest,
+				"ErrServiceTokenAuthDisabled",
+				"service token authentication is not enabled",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrServiceTokenNotAllowed,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrServiceTokenNotAllowed",
+				"service token is not allowed",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrClientCertificateRequired,
+			HTTPErrResp{
+				http.StatusUnauthorized,
+				"ErrClientCertificateRequired",
+				"client certificate required",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrClientCertificateMismatch,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrClientCertificateMismatch",
+				"client certificate does not match the agent",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			signing.ErrSignatureMissing,
+			HTTPErrResp{
+				http.StatusUnauthorized,
+				"ErrSignatureMissing",
+				"request signature missing",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			signing.ErrSignatureInvalid,
+			HTTPErrResp{
+				http.StatusUnauthorized,
+				"ErrSignatureInvalid",
+				"request signature invalid",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			signing.ErrSignatureExpired,
+			HTTPErrResp{
+				http.StatusUnauthorized,
+				"ErrSignatureExpired",
+				"request signature timestamp outside of the allowed skew",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			signing.ErrSignatureReplay,
+			HTTPErrResp{
+				http.StatusUnauthorized,
+				"ErrSignatureReplay",
+				"request signature nonce already used",
+				zerolog.InfoLevel,
+			},
+		},
 		{
 			ErrFileInfoBodyRequired,
 			HTTPErrResp{
@@ -349,7 +567,7 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 			HTTPErrResp{
 				http.StatusBadRequest,
 				"ErrHashMismatch",
-				"hash does not match",
+				"hash does not match, chunk may be retried",
 				zerolog.InfoLevel,
 			},
 		},
@@ -380,6 +598,60 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			uploader.ErrQuotaConcurrentUploads,
+			HTTPErrResp{
+				http.StatusTooManyRequests,
+				"ErrQuotaConcurrentUploads",
+				"too many uploads in progress for this agent",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			uploader.ErrQuotaUploadBytes,
+			HTTPErrResp{
+				http.StatusTooManyRequests,
+				"ErrQuotaUploadBytes",
+				"this upload exceeds the daily upload quota of the agent",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			uploader.ErrChunkExists,
+			HTTPErrResp{
+				http.StatusConflict,
+				"ErrChunkExists",
+				"chunk has already been received",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			uploader.ErrChunkInProgress,
+			HTTPErrResp{
+				http.StatusConflict,
+				"ErrChunkInProgress",
+				"chunk is already being uploaded",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			uploader.ErrTooManyParallelChunks,
+			HTTPErrResp{
+				http.StatusTooManyRequests,
+				"ErrTooManyParallelChunks",
+				"too many chunks of this upload are being uploaded at once",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			uploader.ErrInvalidChunkHash,
+			HTTPErrResp{
+				http.StatusBadRequest,
+				"ErrInvalidChunkHash",
+				"chunk hash must be a hex encoded SHA256",
+				zerolog.InfoLevel,
+			},
+		},
 		{
 			uploader.ErrFailValidation,
 			HTTPErrResp{
@@ -389,6 +661,24 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			uploader.ErrUploadNotDone,
+			HTTPErrResp{
+				http.StatusConflict,
+				"ErrUploadNotDone",
+				"upload has not been verified",
+				zerolog.InfoLevel,
+			},
+		},
+		{
+			ErrUploadContentsForbidden,
+			HTTPErrResp{
+				http.StatusForbidden,
+				"ErrUploadContentsForbidden",
+				"API key is not allowed to read the upload contents",
+				zerolog.InfoLevel,
+			},
+		},
 		{
 			uploader.ErrStatusNoUploads,
 			HTTPErrResp{
@@ -481,6 +771,15 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 				zerolog.InfoLevel,
 			},
 		},
+		{
+			ErrPolicyTooLarge,
+			HTTPErrResp{
+				http.StatusInternalServerError,
+				"ErrPolicyTooLarge",
+				"rendered policy exceeds the maximum policy size",
+				zerolog.ErrorLevel,
+			},
+		},
 	}
 
 	for _, e := range errTable {
@@ -498,6 +797,17 @@ func NewHTTPErrResp(err error) HTTPErrResp {
 		}
 	}
 
+	// the body exceeds the max_body_byte_size of the limit of the route
+	var mbErr *http.MaxBytesError
+	if errors.As(err, &mbErr) {
+		return HTTPErrResp{
+			http.StatusRequestEntityTooLarge,
+			"RequestEntityTooLarge",
+			fmt.Sprintf("request body exceeds %d bytes", mbErr.Limit),
+			zerolog.InfoLevel,
+		}
+	}
+
 	var drErr *BadRequestErr
 	if errors.As(err, &drErr) {
 		return HTTPErrResp{
@@ -588,6 +898,10 @@ func ErrorResp(w http.ResponseWriter, r *http.Request, err error) {
 		apm.CaptureError(r.Context(), err).Send()
 	}
 
+	var raErr *limit.RetryAfterError
+	if errors.As(err, &raErr) {
+		w.Header().Set("Retry-After", limit.RetryAfterSeconds(raErr.After))
+	}
 	if rerr := resp.Write(w); rerr != nil {
 		zlog.Error().Err(rerr).Msg("fail writing error response")
 	}
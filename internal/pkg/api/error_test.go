@@ -119,6 +119,13 @@ func Test_ErrResp_Status(t *testing.T) {
 			nextErr: fmt.Errorf("testError"),
 		},
 		status: 400,
+	}, {
+		name: "body too large",
+		err: &BadRequestErr{
+			msg:     "testMessage",
+			nextErr: &http.MaxBytesError{Limit: 1024},
+		},
+		status: 413,
 	}}
 
 	for _, tc := range tests {
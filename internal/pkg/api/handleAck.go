@@ -18,15 +18,18 @@ import (
 	"github.com/miolini/datacounter"
 	"github.com/rs/zerolog"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
 	"github.com/elastic/fleet-server/v7/internal/pkg/smap"
+	"github.com/elastic/fleet-server/v7/internal/pkg/spool"
 	"go.elastic.co/apm/module/apmhttp/v2"
 	"go.elastic.co/apm/v2"
 )
@@ -81,21 +84,36 @@ type AckT struct {
 	cfg   *config.Server
 	bulk  bulk.Bulk
 	cache cache.Cache
+	pm    policy.Monitor
+	inv   *invalidator.Invalidator
+	spool *spool.Spool
+
+	policyLimits policyLimiter
 }
 
-func NewAckT(cfg *config.Server, bulker bulk.Bulk, cache cache.Cache) *AckT {
+func NewAckT(cfg *config.Server, bulker bulk.Bulk, cache cache.Cache, pm policy.Monitor, inv *invalidator.Invalidator, sp *spool.Spool) *AckT {
 	return &AckT{
 		cfg:   cfg,
 		bulk:  bulker,
 		cache: cache,
+		pm:    pm,
+		inv:   inv,
+		spool: sp,
+
+		policyLimits: newPolicyLimiter(&cfg.Limits, func(p *config.PolicyLimits) *config.Limit { return p.AckLimit }),
 	}
 }
 
 func (ack *AckT) handleAcks(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, id string) error {
-	agent, err := authAgent(r, &id, ack.bulk, ack.cache)
+	agent, err := authAgent(r, &id, ack.cfg, ack.bulk, ack.cache)
 	if err != nil {
 		return err
 	}
+	release, err := ack.policyLimits.acquire(w, r, agent.PolicyID)
+	if err != nil {
+		return err
+	}
+	defer release()
 	zlog = zlog.With().Str(LogAccessAPIKeyID, agent.AccessAPIKeyID).Logger()
 	ctx := zlog.WithContext(r.Context())
 	r = r.WithContext(ctx)
@@ -144,13 +162,8 @@ func (ack *AckT) validateRequest(zlog zerolog.Logger, w http.ResponseWriter, r *
 	span, _ := apm.StartSpan(r.Context(), "validateRequest", "validate")
 	defer span.End()
 
-	body := r.Body
-
-	// Limit the size of the body to prevent malicious agent from exhausting RAM in server
-	if ack.cfg.Limits.AckLimit.MaxBody > 0 {
-		body = http.MaxBytesReader(w, body, ack.cfg.Limits.AckLimit.MaxBody)
-	}
-	readCounter := datacounter.NewReaderCounter(body)
+	// the size of the body is limited by the limiter of the route
+	readCounter := datacounter.NewReaderCounter(r.Body)
 
 	var req AckRequest
 	dec := json.NewDecoder(readCounter)
@@ -299,6 +312,14 @@ func (ack *AckT) handleAckEvents(ctx context.Context, zlog zerolog.Logger, agent
 		}
 		vSpan.End()
 
+		// an action of another namespace is not visible to the agent
+		if !dl.InNamespaces(action.Namespaces, agent.Namespaces) {
+			log.Warn().Strs("action.namespaces", action.Namespaces).Msg("action not in the namespaces of the agent")
+			setResult(n, http.StatusNotFound)
+			span.End()
+			continue
+		}
+
 		if err := ack.handleActionResult(ctx, zlog, agent, action, ev); err != nil {
 			setError(n, err)
 		} else {
@@ -364,7 +385,7 @@ func (ack *AckT) handleActionResult(ctx context.Context, zlog zerolog.Logger, ag
 	acr := eventToActionResult(agent.Id, action.Type, action.Namespaces, ev)
 
 	// Save action result document
-	if err := dl.CreateActionResult(ctx, ack.bulk, acr); err != nil {
+	if err := dl.CreateActionResult(ctx, ack.bulk, ack.spool, acr); err != nil {
 		zlog.Error().Err(err).Msg("create action result")
 		return err
 	}
@@ -391,6 +412,19 @@ func (ack *AckT) handlePolicyChange(ctx context.Context, zlog zerolog.Logger, ag
 	currRev := agent.PolicyRevisionIdx
 	currCoord := agent.PolicyCoordinatorIdx
 	vSpan, _ := apm.StartSpan(ctx, "checkPolicyActions", "validate")
+	// A rolled back staged rollout redelivers an older revision; accept its ack so it is not sent to the agent again.
+	if ack.pm != nil {
+		if rbRev, ok := ack.pm.RolledBackRevision(agent.PolicyID); ok && currRev > rbRev {
+			for _, a := range actionIds {
+				rev, ok := policy.RevisionFromString(a)
+				if ok && rev.PolicyID == agent.PolicyID && rev.RevisionIdx == rbRev {
+					found = true
+					currRev = rev.RevisionIdx
+					currCoord = rev.CoordinatorIdx
+				}
+			}
+		}
+	}
 	for _, a := range actionIds {
 		rev, ok := policy.RevisionFromString(a)
 
@@ -485,7 +519,15 @@ func (ack *AckT) updateAPIKey(ctx context.Context,
 					Str(LogAPIKeyID, apiKeyID).
 					Msg("Failed to cleanup roles")
 			} else if removedRolesCount > 0 {
-				if err := bulk.APIKeyUpdate(ctx, apiKeyID, permissionHash, clean); err != nil {
+				err := bulk.APIKeyUpdate(ctx, apiKeyID, permissionHash, clean)
+				audit.APIKey(zlog, audit.APIKeyEvent{
+					Action:  audit.ActionAPIKeyUpdate,
+					Reason:  audit.ReasonRolesCleanup,
+					AgentID: agentID,
+					Output:  outputName,
+					IDs:     []string{apiKeyID},
+				}, err)
+				if err != nil {
 					zlog.Error().Err(err).RawJSON("roles", clean).Str(LogAPIKeyID, apiKeyID).Str(logger.PolicyOutputName, outputName).Msg("Failed to update API Key")
 				} else {
 					zlog.Debug().
@@ -498,7 +540,7 @@ func (ack *AckT) updateAPIKey(ctx context.Context,
 				}
 			}
 		}
-		ack.invalidateAPIKeys(ctx, zlog, toRetireAPIKeyIDs, apiKeyID)
+		ack.invalidateAPIKeys(ctx, zlog, agentID, audit.ReasonRetired, toRetireAPIKeyIDs, apiKeyID)
 	}
 
 	return nil
@@ -518,14 +560,7 @@ func (ack *AckT) updateAgentDoc(ctx context.Context,
 		currCoord,
 	)
 
-	err := ack.bulk.Update(
-		ctx,
-		dl.FleetAgents,
-		agentID,
-		body,
-		bulk.WithRefresh(),
-		bulk.WithRetryOnConflict(3),
-	)
+	err := ack.updateAgent(ctx, agentID, body)
 
 	zlog.Err(err).
 		Str(LogPolicyID, policyID).
@@ -539,6 +574,15 @@ func (ack *AckT) updateAgentDoc(ctx context.Context,
 	return nil
 }
 
+// updateAgent updates the agent document with the ack, the update is held in the spool when elasticsearch is
+// unavailable.
+func (ack *AckT) updateAgent(ctx context.Context, agentID string, body []byte) error {
+	op := spool.Op{Action: spool.ActionUpdate, Index: dl.FleetAgents, ID: agentID, Body: body, Refresh: true}
+	return ack.spool.Write([]spool.Op{op}, func() error {
+		return ack.bulk.Update(ctx, dl.FleetAgents, agentID, body, bulk.WithRefresh(), bulk.WithRetryOnConflict(3))
+	})
+}
+
 func cleanRoles(roles json.RawMessage) (json.RawMessage, int, error) {
 	rr := smap.Map{}
 	if err := json.Unmarshal(roles, &rr); err != nil {
@@ -567,8 +611,8 @@ func cleanRoles(roles json.RawMessage) (json.RawMessage, int, error) {
 	return r, len(keys), nil
 }
 
-func (ack *AckT) invalidateAPIKeys(ctx context.Context, zlog zerolog.Logger, toRetireAPIKeyIDs []model.ToRetireAPIKeyIdsItems, skip string) {
-	invalidateAPIKeys(ctx, zlog, ack.bulk, toRetireAPIKeyIDs, skip)
+func (ack *AckT) invalidateAPIKeys(ctx context.Context, zlog zerolog.Logger, agentID, reason string, toRetireAPIKeyIDs []model.ToRetireAPIKeyIdsItems, skip string) {
+	invalidateAPIKeys(ctx, zlog, ack.bulk, ack.inv, agentID, reason, toRetireAPIKeyIDs, skip)
 }
 
 func (ack *AckT) handleUnenroll(ctx context.Context, zlog zerolog.Logger, agent *model.Agent) error {
@@ -577,22 +621,12 @@ func (ack *AckT) handleUnenroll(ctx context.Context, zlog zerolog.Logger, agent
 
 	apiKeys := agent.APIKeyIDs()
 	zlog.Info().Any("fleet.policy.apiKeyIDsToRetire", apiKeys).Msg("handleUnenroll invalidate API keys")
-	ack.invalidateAPIKeys(ctx, zlog, apiKeys, "")
+	ack.invalidateAPIKeys(ctx, zlog, agent.Id, audit.ReasonUnenroll, apiKeys, "")
 
-	now := time.Now().UTC().Format(time.RFC3339)
-	doc := bulk.UpdateFields{
-		dl.FieldActive:       false,
-		dl.FieldUnenrolledAt: now,
-		dl.FieldUpdatedAt:    now,
-	}
-
-	body, err := doc.Marshal()
+	err := dl.TombstoneAgent(ctx, ack.bulk, agent, "")
+	audit.Record(ctx, audit.Event{Action: audit.ActionAgentUnenroll, AgentID: agent.Id, PolicyID: agent.PolicyID}, err)
 	if err != nil {
-		return fmt.Errorf("handleUnenroll marshal: %w", err)
-	}
-
-	if err = ack.bulk.Update(ctx, dl.FleetAgents, agent.Id, body, bulk.WithRefresh(), bulk.WithRetryOnConflict(3)); err != nil {
-		return fmt.Errorf("handleUnenroll update: %w", err)
+		return fmt.Errorf("handleUnenroll: %w", err)
 	}
 
 	zlog.Info().Msg("ack unenroll")
@@ -635,7 +669,7 @@ func (ack *AckT) handleUpgrade(ctx context.Context, zlog zerolog.Logger, agent *
 		return fmt.Errorf("handleUpgrade marshal: %w", err)
 	}
 
-	if err = ack.bulk.Update(ctx, dl.FleetAgents, agent.Id, body, bulk.WithRefresh(), bulk.WithRetryOnConflict(3)); err != nil {
+	if err = ack.updateAgent(ctx, agent.Id, body); err != nil {
 		return fmt.Errorf("handleUpgrade update: %w", err)
 	}
 
@@ -696,17 +730,40 @@ func makeUpdatePolicyBody(policyID string, newRev, coordIdx int64) []byte {
 	return buf.Bytes()
 }
 
-func invalidateAPIKeys(ctx context.Context, zlog zerolog.Logger, bulk bulk.Bulk, toRetireAPIKeyIDs []model.ToRetireAPIKeyIdsItems, skip string) {
-	ids := make([]string, 0, len(toRetireAPIKeyIDs))
-	remoteIds := make(map[string][]string)
+// retiredAPIKeys returns the keys to invalidate, skip is the ID of a key that is still in use.
+func retiredAPIKeys(toRetireAPIKeyIDs []model.ToRetireAPIKeyIdsItems, skip string) []model.ToRetireAPIKeyIdsItems {
+	keys := make([]model.ToRetireAPIKeyIdsItems, 0, len(toRetireAPIKeyIDs))
 	for _, k := range toRetireAPIKeyIDs {
 		if k.ID == skip || k.ID == "" {
 			continue
 		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// invalidateAPIKeys invalidates the retired API keys of an agent through inv, or inline when inv is nil.
+// reason is recorded in the audit events of the invalidations.
+func invalidateAPIKeys(ctx context.Context, zlog zerolog.Logger, bulk bulk.Bulk, inv *invalidator.Invalidator, agentID, reason string, toRetireAPIKeyIDs []model.ToRetireAPIKeyIdsItems, skip string) {
+	keys := retiredAPIKeys(toRetireAPIKeyIDs, skip)
+	if inv != nil {
+		inv.Invalidate(keys...)
+		for output, ids := range idsByOutput(keys) {
+			audit.APIKey(zlog, audit.APIKeyEvent{
+				Action:  audit.ActionAPIKeyInvalidateQueued,
+				Reason:  reason,
+				AgentID: agentID,
+				Output:  output,
+				IDs:     ids,
+			}, nil)
+		}
+		return
+	}
+
+	ids := make([]string, 0, len(keys))
+	remoteIds := make(map[string][]string)
+	for _, k := range keys {
 		if k.Output != "" {
-			if remoteIds[k.Output] == nil {
-				remoteIds[k.Output] = make([]string, 0)
-			}
 			remoteIds[k.Output] = append(remoteIds[k.Output], k.ID)
 		} else {
 			ids = append(ids, k.ID)
@@ -714,30 +771,27 @@ func invalidateAPIKeys(ctx context.Context, zlog zerolog.Logger, bulk bulk.Bulk,
 	}
 	if len(ids) > 0 {
 		zlog.Info().Strs("fleet.policy.apiKeyIDsToRetire", ids).Msg("Invalidate old API keys")
-		if err := bulk.APIKeyInvalidate(ctx, ids...); err != nil {
+		err := bulk.APIKeyInvalidate(ctx, ids...)
+		audit.APIKey(zlog, audit.APIKeyEvent{Action: audit.ActionAPIKeyInvalidate, Reason: reason, AgentID: agentID, IDs: ids}, err)
+		if err != nil {
 			zlog.Info().Err(err).Strs("ids", ids).Msg("Failed to invalidate API keys")
 		}
 	}
 	// using remote es bulker to invalidate api key
 	for outputName, outputIds := range remoteIds {
-		outputBulk := bulk.GetBulker(outputName)
-
-		if outputBulk == nil {
-			// read output config from .fleet-policies, not filtering by policy id as agent could be reassigned
-			policy, err := dl.QueryOutputFromPolicy(ctx, bulk, outputName)
-			if err != nil || policy == nil {
-				zlog.Warn().Str(logger.PolicyOutputName, outputName).Any("ids", outputIds).Msg("Output policy not found, API keys will be orphaned")
-			} else {
-				outputBulk, _, err = bulk.CreateAndGetBulker(ctx, zlog, outputName, policy.Data.Outputs)
-				if err != nil {
-					zlog.Warn().Str(logger.PolicyOutputName, outputName).Any("ids", outputIds).Msg("Failed to recreate output bulker, API keys will be orphaned")
-				}
-			}
-		}
-		if outputBulk != nil {
-			if err := outputBulk.APIKeyInvalidate(ctx, outputIds...); err != nil {
-				zlog.Info().Err(err).Strs("ids", outputIds).Str(logger.PolicyOutputName, outputName).Msg("Failed to invalidate API keys")
-			}
+		err := invalidator.InvalidateOutput(ctx, zlog, bulk, outputName, outputIds)
+		audit.APIKey(zlog, audit.APIKeyEvent{Action: audit.ActionAPIKeyInvalidate, Reason: reason, AgentID: agentID, Output: outputName, IDs: outputIds}, err)
+		if err != nil {
+			zlog.Warn().Err(err).Strs("ids", outputIds).Str(logger.PolicyOutputName, outputName).Msg("Failed to invalidate API keys, API keys will be orphaned")
 		}
 	}
 }
+
+// idsByOutput groups the IDs of keys by output name.
+func idsByOutput(keys []model.ToRetireAPIKeyIdsItems) map[string][]string {
+	ids := make(map[string][]string)
+	for _, k := range keys {
+		ids[k.Output] = append(ids[k.Output], k.ID)
+	}
+	return ids
+}
@@ -14,14 +14,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
@@ -281,6 +284,25 @@ func TestHandleAckEvents(t *testing.T) {
 				return m
 			},
 		},
+		{
+			name: "action of another namespace",
+			events: []AckRequest_Events_Item{{
+				json.RawMessage(`{
+				"action_id": "2b12dcd8-bde0-4045-92dc-c4b27668d733"
+			    }`),
+			}},
+			res: newAckResponse(true, []AckResponseItem{newAckResponseItem(http.StatusNotFound)}),
+			err: &HTTPError{Status: http.StatusNotFound},
+			bulker: func(t *testing.T) *ftesting.MockBulk {
+				m := ftesting.NewMockBulk()
+				m.On("Search", mock.Anything, mock.Anything, mock.MatchedBy(matchAction(t, "2b12dcd8-bde0-4045-92dc-c4b27668d733")), mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{
+					Hits: []es.HitT{{
+						Source: []byte(`{"action_id":"2b12dcd8-bde0-4045-92dc-c4b27668d733","type":"UPGRADE","namespaces":["other"]}`),
+					}},
+				}}, nil)
+				return m
+			},
+		},
 		{
 			name: "action found, create result general error",
 			events: []AckRequest_Events_Item{{
@@ -497,7 +519,7 @@ func TestHandleAckEvents(t *testing.T) {
 			}
 
 			bulker := tc.bulker(t)
-			ack := NewAckT(cfg, bulker, cache)
+			ack := NewAckT(cfg, bulker, cache, nil, nil, nil)
 
 			res, err := ack.handleAckEvents(ctx, logger, agent, tc.events)
 			assert.Equal(t, tc.res, res)
@@ -568,12 +590,33 @@ func TestInvalidateAPIKeys(t *testing.T) {
 
 		logger := testlog.SetLogger(t)
 		ack := &AckT{bulk: bulker}
-		ack.invalidateAPIKeys(context.Background(), logger, out.ToRetireAPIKeyIds, skip)
+		ack.invalidateAPIKeys(context.Background(), logger, "agent-id", audit.ReasonRetired, out.ToRetireAPIKeyIds, skip)
 
 		bulker.AssertExpectations(t)
 	}
 }
 
+func TestInvalidateAPIKeysQueued(t *testing.T) {
+	toRetire := []model.ToRetireAPIKeyIdsItems{{
+		ID: "inUse",
+	}, {
+		ID: "toRetire1",
+	}, {
+		ID:     "toRetire2",
+		Output: "remote1",
+	}}
+
+	bulker := ftesting.NewMockBulk()
+	inv := invalidator.New(bulker, config.APIKeyInvalidation{Path: filepath.Join(t.TempDir(), "state.json")})
+
+	logger := testlog.SetLogger(t)
+	ack := &AckT{bulk: bulker, inv: inv}
+	ack.invalidateAPIKeys(context.Background(), logger, "agent-id", audit.ReasonRetired, toRetire, "inUse")
+
+	assert.Equal(t, 2, inv.Pending())
+	bulker.AssertExpectations(t)
+}
+
 func TestInvalidateAPIKeysRemoteOutput(t *testing.T) {
 	toRetire := []model.ToRetireAPIKeyIdsItems{{
 		ID:     "toRetire1",
@@ -601,7 +644,7 @@ func TestInvalidateAPIKeysRemoteOutput(t *testing.T) {
 
 	logger := testlog.SetLogger(t)
 	ack := &AckT{bulk: bulker}
-	ack.invalidateAPIKeys(context.Background(), logger, toRetire, "")
+	ack.invalidateAPIKeys(context.Background(), logger, "agent-id", audit.ReasonRetired, toRetire, "")
 
 	bulker.AssertExpectations(t)
 	remoteBulker.AssertExpectations(t)
@@ -636,7 +679,7 @@ func TestInvalidateAPIKeysRemoteOutputReadFromPolicies(t *testing.T) {
 
 	logger := testlog.SetLogger(t)
 	ack := &AckT{bulk: bulker}
-	ack.invalidateAPIKeys(context.Background(), logger, toRetire, "")
+	ack.invalidateAPIKeys(context.Background(), logger, "agent-id", audit.ReasonRetired, toRetire, "")
 
 	bulker.AssertExpectations(t)
 	remoteBulker.AssertExpectations(t)
@@ -664,7 +707,7 @@ func TestInvalidateAPIKeysRemoteOutputReadFromPoliciesNotFound(t *testing.T) {
 
 	logger := testlog.SetLogger(t)
 	ack := &AckT{bulk: bulker}
-	ack.invalidateAPIKeys(context.Background(), logger, toRetire, "")
+	ack.invalidateAPIKeys(context.Background(), logger, "agent-id", audit.ReasonRetired, toRetire, "")
 
 	bulker.AssertExpectations(t)
 	remoteBulker.AssertExpectations(t)
@@ -753,7 +796,7 @@ func TestAckHandleUpgrade(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			logger := testlog.SetLogger(t)
 			bulker := tc.bulker(t)
-			ack := NewAckT(cfg, bulker, cache)
+			ack := NewAckT(cfg, bulker, cache, nil, nil, nil)
 
 			err := ack.handleUpgrade(ctx, logger, agent, tc.event)
 			assert.NoError(t, err)
@@ -787,7 +830,7 @@ func TestValidateAckRequest(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			wr := httptest.NewRecorder()
-			ack := NewAckT(tc.cfg, nil, nil)
+			ack := NewAckT(tc.cfg, nil, nil, nil, nil, nil)
 			ackRes, err := ack.validateRequest(logger, wr, tc.req)
 			if tc.expErr == nil {
 				assert.NoError(t, err)
@@ -0,0 +1,310 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	agentReassignBatchSize = 1000
+	maxAgentReassignIDs    = 10000
+
+	// agentReassignActionExpiration is the expiration of the POLICY_REASSIGN actions, an agent that checks in
+	// later is sent its new policy all the same.
+	agentReassignActionExpiration = 24 * time.Hour
+)
+
+var ErrAgentReassignForbidden = errors.New("api key is not allowed to reassign agents")
+
+type AgentReassignT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewAgentReassignT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache) *AgentReassignT {
+	return &AgentReassignT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handleReassign assigns the agents of the request to a policy. The agents are updated in batches and a
+// POLICY_REASSIGN action is created for each batch, it ends the long poll of the agents that then check in and are
+// sent the POLICY_CHANGE of their new policy.
+// The API key of the request must have write privileges on .fleet-agents and .fleet-actions.
+func (rt *AgentReassignT) handleReassign(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := rt.authAPIKey(r, rt.bulker, rt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	ok, err := key.HasPrivileges(ctx, rt.bulker.Client(), []string{dl.FleetAgents, dl.FleetActions}, []string{"write"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAgentReassignForbidden
+	}
+	namespaces, err := apiKeyNamespaces(ctx, rt.cfg, rt.bulker, key)
+	if err != nil {
+		return err
+	}
+
+	var req AgentReassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return &BadRequestErr{msg: "unable to decode agent reassign request", nextErr: err}
+	}
+	filter, err := agentReassignFilter(&req)
+	if err != nil {
+		return err
+	}
+	filter.Namespaces = namespaces
+	zlog = zlog.With().Str(LogPolicyID, req.PolicyId).Logger()
+
+	if err := rt.checkPolicy(ctx, req.PolicyId, namespaces); err != nil {
+		return err
+	}
+
+	resp, err := rt.reassign(ctx, zlog, filter, req.PolicyId)
+	if err != nil {
+		return err
+	}
+	if len(filter.IDs) > 0 {
+		resp.Failed = append(resp.Failed, missingAgents(filter.IDs, resp.found)...)
+	}
+	zlog.Info().Int("reassigned", resp.Reassigned).Int("failed", len(resp.Failed)).Msg("agents reassigned")
+
+	out, err := json.Marshal(resp.AgentReassignAPIResponse)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// checkPolicy returns ErrPolicyNotFound when the policy does not exist or is not in the namespaces of the API key.
+func (rt *AgentReassignT) checkPolicy(ctx context.Context, policyID string, namespaces []string) error {
+	span, ctx := apm.StartSpan(ctx, "checkPolicy", "search")
+	defer span.End()
+	policies, err := dl.QueryLatestPolicies(ctx, rt.bulker, dl.WithTimeout(rt.cfg.Timeouts.Query))
+	if err != nil {
+		return err
+	}
+	for i := range policies {
+		if policies[i].PolicyID == policyID && authorizeNamespaces(namespaces, policies[i].Namespaces) == nil {
+			return nil
+		}
+	}
+	return ErrPolicyNotFound
+}
+
+type agentReassignResult struct {
+	AgentReassignAPIResponse
+	found map[string]bool
+}
+
+// reassign updates the active agents matching the filter, the agents already assigned to the policy are skipped.
+// It stops at the first batch that can not be searched or updated.
+func (rt *AgentReassignT) reassign(ctx context.Context, zlog zerolog.Logger, filter dl.AgentFilter, policyID string) (*agentReassignResult, error) {
+	resp := &agentReassignResult{
+		AgentReassignAPIResponse: AgentReassignAPIResponse{
+			PolicyId:  policyID,
+			Failed:    []string{},
+			ActionIds: []string{},
+		},
+		found: make(map[string]bool),
+	}
+
+	var searchAfter []interface{}
+	for {
+		span, sCtx := apm.StartSpan(ctx, "searchAgents", "search")
+		agents, next, err := dl.SearchAgents(sCtx, rt.bulker, filter, agentReassignBatchSize, searchAfter, dl.WithTimeout(rt.cfg.Timeouts.Query))
+		span.End()
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(agents))
+		var namespaces []string
+		for i := range agents {
+			resp.found[agents[i].Id] = true
+			if agents[i].PolicyID != policyID {
+				ids = append(ids, agents[i].Id)
+				namespaces = appendNamespaces(namespaces, agents[i].Namespaces)
+			}
+		}
+		if slices.Equal(namespaces, []string{dl.DefaultNamespace}) {
+			// the actions without namespaces are in the default namespace
+			namespaces = nil
+		}
+		if len(ids) > 0 {
+			if err := rt.reassignBatch(ctx, zlog, ids, namespaces, policyID, resp); err != nil {
+				return nil, err
+			}
+		}
+
+		if next == nil {
+			return resp, nil
+		}
+		searchAfter = next
+	}
+}
+
+// reassignBatch updates the agents and creates the POLICY_REASSIGN action of the agents that are updated, the action
+// is in the namespaces of the agents so it is delivered to each of them.
+func (rt *AgentReassignT) reassignBatch(ctx context.Context, zlog zerolog.Logger, ids, namespaces []string, policyID string, resp *agentReassignResult) error {
+	span, ctx := apm.StartSpan(ctx, "reassignAgents", "update")
+	defer span.End()
+
+	failed, err := dl.ReassignAgents(ctx, rt.bulker, ids, policyID)
+	if err != nil {
+		return err
+	}
+	resp.Failed = append(resp.Failed, failed...)
+	agents := ids
+	if len(failed) > 0 {
+		isFailed := make(map[string]bool, len(failed))
+		for _, id := range failed {
+			isFailed[id] = true
+		}
+		agents = make([]string, 0, len(ids)-len(failed))
+		for _, id := range ids {
+			if !isFailed[id] {
+				agents = append(agents, id)
+			}
+		}
+		zlog.Warn().Int("failed", len(failed)).Msg("unable to reassign agents")
+	}
+	if len(agents) == 0 {
+		return nil
+	}
+	resp.Reassigned += len(agents)
+
+	actionID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("unable to generate the action ID: %w", err)
+	}
+	data, err := json.Marshal(ActionPolicyReassign{PolicyId: policyID})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	action := model.Action{
+		ActionID:   actionID.String(),
+		Agents:     agents,
+		Data:       data,
+		Expiration: now.Add(agentReassignActionExpiration).Format(time.RFC3339),
+		Timestamp:  now.Format(time.RFC3339),
+		Type:       string(POLICYREASSIGN),
+		Namespaces: namespaces,
+	}
+	if err := dl.CreateAction(ctx, rt.bulker, action); err != nil {
+		// The agents are reassigned, they are sent their new policy on their next checkin.
+		zlog.Warn().Err(err).Str(logger.ActionID, action.ActionID).Msg("unable to create the policy reassign action")
+		return nil
+	}
+	resp.ActionIds = append(resp.ActionIds, action.ActionID)
+	return nil
+}
+
+// appendNamespaces adds the namespaces of an agent to the namespaces of a batch, the agents without namespaces are in
+// the default namespace.
+func appendNamespaces(namespaces, agentNamespaces []string) []string {
+	if len(agentNamespaces) == 0 {
+		agentNamespaces = []string{dl.DefaultNamespace}
+	}
+	for _, ns := range agentNamespaces {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// agentReassignFilter validates the reassign request, it returns the filter of the agents to reassign.
+func agentReassignFilter(req *AgentReassignRequest) (dl.AgentFilter, error) {
+	active := true
+	filter := dl.AgentFilter{Active: &active}
+	if req.PolicyId == "" {
+		return filter, &BadRequestErr{msg: "policy_id is required"}
+	}
+	if (req.Agents == nil) == (req.Query == nil) {
+		return filter, &BadRequestErr{msg: "one of agents or query is required"}
+	}
+
+	if req.Agents != nil {
+		if len(*req.Agents) == 0 || len(*req.Agents) > maxAgentReassignIDs {
+			return filter, &BadRequestErr{msg: fmt.Sprintf("agents must have between 1 and %d IDs", maxAgentReassignIDs)}
+		}
+		for _, id := range *req.Agents {
+			if id == "" {
+				return filter, &BadRequestErr{msg: "agent IDs must not be empty"}
+			}
+		}
+		filter.IDs = *req.Agents
+		return filter, nil
+	}
+
+	q := req.Query
+	if q.PolicyId != nil {
+		filter.PolicyID = *q.PolicyId
+	}
+	if q.Tags != nil {
+		filter.Tags = *q.Tags
+	}
+	if q.Status != nil {
+		filter.Statuses = *q.Status
+	}
+	if q.LocalMetadata != nil {
+		for k := range *q.LocalMetadata {
+			if k == "" {
+				return filter, &BadRequestErr{msg: "local_metadata fields must not be empty"}
+			}
+		}
+		filter.LocalMetadata = *q.LocalMetadata
+	}
+	if filter.PolicyID == "" && len(filter.Tags) == 0 && len(filter.Statuses) == 0 && len(filter.LocalMetadata) == 0 {
+		return filter, &BadRequestErr{msg: "query must have at least one filter"}
+	}
+	return filter, nil
+}
+
+// missingAgents returns the IDs that are not found, each ID once.
+func missingAgents(ids []string, found map[string]bool) []string {
+	var missing []string
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !found[id] && !seen[id] {
+			missing = append(missing, id)
+		}
+		seen[id] = true
+	}
+	return missing
+}
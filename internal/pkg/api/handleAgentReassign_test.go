@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandleAgentReassign(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+
+	policies := &es.ResultT{Aggregations: map[string]es.Aggregation{
+		dl.FieldPolicyID: {Buckets: []es.Bucket{{
+			Aggregations: map[string]es.HitsT{dl.FieldRevisionIdx: {Hits: []es.HitT{{Source: []byte(`{"policy_id":"new-policy","revision_idx":1}`)}}}},
+		}}},
+	}}
+	hits := []es.HitT{
+		{ID: "agent-1", Source: []byte(`{"active":true,"policy_id":"old-policy"}`)},
+		{ID: "agent-2", Source: []byte(`{"active":true,"policy_id":"new-policy"}`)},
+		{ID: "agent-3", Source: []byte(`{"active":true,"policy_id":"old-policy"}`)},
+	}
+
+	tests := []struct {
+		name       string
+		privileged bool
+		body       string
+		status     int
+	}{{
+		name:       "not privileged",
+		privileged: false,
+		body:       `{"policy_id":"new-policy","agents":["agent-1"]}`,
+		status:     http.StatusForbidden,
+	}, {
+		name:       "agents and query",
+		privileged: true,
+		body:       `{"policy_id":"new-policy","agents":["agent-1"],"query":{"tags":["linux"]}}`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:       "query without filters",
+		privileged: true,
+		body:       `{"policy_id":"new-policy","query":{}}`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:       "unknown policy",
+		privileged: true,
+		body:       `{"policy_id":"unknown-policy","agents":["agent-1"]}`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:       "agents",
+		privileged: true,
+		body:       `{"policy_id":"new-policy","agents":["agent-1","agent-2","agent-3","missing"]}`,
+		status:     http.StatusOK,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(client)
+			fakebulk.On("Search", mock.Anything, dl.FleetPolicies, mock.Anything, mock.Anything).Return(policies, nil).Maybe()
+
+			var query []byte
+			var ops []bulk.MultiOp
+			var actionBody []byte
+			if tc.status == http.StatusOK {
+				fakebulk.On("Search", mock.Anything, dl.FleetAgents, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					query = args.Get(2).([]byte) //nolint:errcheck // test
+				}).Return(&es.ResultT{HitsT: es.HitsT{Hits: hits}}, nil).Once()
+				fakebulk.On("MUpdate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					ops = args.Get(1).([]bulk.MultiOp) //nolint:errcheck // test
+				}).Return([]bulk.BulkIndexerResponseItem{{DocumentID: "agent-1", Status: http.StatusOK}, {DocumentID: "agent-3", Status: http.StatusConflict}}, nil).Once()
+				fakebulk.On("Create", mock.Anything, dl.FleetActions, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					actionBody = args.Get(3).([]byte) //nolint:errcheck // test
+				}).Return("", nil).Once()
+			}
+
+			rt := NewAgentReassignT(cfg, fakebulk, nil)
+			rt.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			router := newRouter(cfg, &apiServer{art: rt}, nil, nil, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/agents/reassign", strings.NewReader(tc.body)))
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+			fakebulk.AssertExpectations(t)
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			assert.Contains(t, string(query), `{"terms":{"_id":["agent-1","agent-2","agent-3","missing"]}}`)
+			require.Len(t, ops, 2, "the agent already assigned to the policy is not updated")
+			assert.Equal(t, "agent-1", ops[0].ID)
+			assert.Equal(t, "agent-3", ops[1].ID)
+			assert.Contains(t, string(ops[0].Body), `"policy_id":"new-policy"`)
+
+			var action model.Action
+			require.NoError(t, json.Unmarshal(actionBody, &action))
+			assert.Equal(t, string(POLICYREASSIGN), action.Type)
+			assert.Equal(t, []string{"agent-1"}, action.Agents)
+			assert.JSONEq(t, `{"policy_id":"new-policy"}`, string(action.Data))
+
+			var resp AgentReassignAPIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "new-policy", resp.PolicyId)
+			assert.Equal(t, 1, resp.Reassigned)
+			assert.Equal(t, []string{"agent-3", "missing"}, resp.Failed)
+			assert.Equal(t, []string{action.ActionID}, resp.ActionIds)
+		})
+	}
+}
+
+func TestAppendNamespaces(t *testing.T) {
+	var namespaces []string
+	namespaces = appendNamespaces(namespaces, []string{"space"})
+	namespaces = appendNamespaces(namespaces, nil)
+	namespaces = appendNamespaces(namespaces, []string{"other", "space"})
+	assert.Equal(t, []string{"space", "default", "other"}, namespaces)
+}
@@ -0,0 +1,177 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	defaultAgentSearchSize = 100
+	maxAgentSearchSize     = 1000
+)
+
+var ErrAgentSearchForbidden = errors.New("api key is not allowed to search agents")
+
+type AgentSearchT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewAgentSearchT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache) *AgentSearchT {
+	return &AgentSearchT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handleSearch returns a page of the agents matching the filters of the request.
+// The API key of the request must have read privileges on .fleet-agents.
+func (st *AgentSearchT) handleSearch(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := st.authAPIKey(r, st.bulker, st.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	ok, err := key.HasPrivileges(ctx, st.bulker.Client(), []string{dl.FleetAgents}, []string{"read"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAgentSearchForbidden
+	}
+	namespaces, err := apiKeyNamespaces(ctx, st.cfg, st.bulker, key)
+	if err != nil {
+		return err
+	}
+
+	var req AgentSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return &BadRequestErr{msg: "unable to decode agent search request", nextErr: err}
+	}
+	filter, size, err := agentSearchFilter(&req)
+	if err != nil {
+		return err
+	}
+	filter.Namespaces = namespaces
+	var searchAfter []interface{}
+	if req.SearchAfter != nil {
+		searchAfter = *req.SearchAfter
+	}
+
+	span, ctx := apm.StartSpan(ctx, "searchAgents", "search")
+	agents, next, err := dl.SearchAgents(ctx, st.bulker, filter, size, searchAfter, dl.WithTimeout(st.cfg.Timeouts.Query))
+	span.End()
+	if err != nil {
+		return err
+	}
+	zlog.Debug().Int("size", size).Int("agents", len(agents)).Msg("agents searched")
+
+	resp := AgentSearchAPIResponse{Items: make([]AgentSearchItem, 0, len(agents))}
+	for i := range agents {
+		item, err := agentSearchItem(&agents[i])
+		if err != nil {
+			return err
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	if next != nil {
+		resp.SearchAfter = &next
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// agentSearchFilter validates the search request, it returns the filter of the agents and the size of the page.
+func agentSearchFilter(req *AgentSearchRequest) (dl.AgentFilter, int, error) {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	filter := dl.AgentFilter{Active: &active}
+	if req.PolicyId != nil {
+		filter.PolicyID = *req.PolicyId
+	}
+	if req.Tags != nil {
+		filter.Tags = *req.Tags
+	}
+	if req.Status != nil {
+		filter.Statuses = *req.Status
+	}
+	if req.LocalMetadata != nil {
+		for k := range *req.LocalMetadata {
+			if k == "" {
+				return filter, 0, &BadRequestErr{msg: "local_metadata fields must not be empty"}
+			}
+		}
+		filter.LocalMetadata = *req.LocalMetadata
+	}
+
+	size := defaultAgentSearchSize
+	if req.Size != nil {
+		size = *req.Size
+	}
+	if size < 1 || size > maxAgentSearchSize {
+		return filter, 0, &BadRequestErr{msg: fmt.Sprintf("size must be between 1 and %d", maxAgentSearchSize)}
+	}
+	return filter, size, nil
+}
+
+func agentSearchItem(agent *model.Agent) (AgentSearchItem, error) {
+	item := AgentSearchItem{
+		Id:         agent.Id,
+		Active:     agent.Active,
+		EnrolledAt: agent.EnrolledAt,
+	}
+	if agent.PolicyID != "" {
+		item.PolicyId = &agent.PolicyID
+	}
+	if agent.PolicyRevisionIdx != 0 {
+		item.PolicyRevisionIdx = &agent.PolicyRevisionIdx
+	}
+	if len(agent.Tags) > 0 {
+		item.Tags = &agent.Tags
+	}
+	if agent.LastCheckinStatus != "" {
+		item.Status = &agent.LastCheckinStatus
+	}
+	if agent.LastCheckin != "" {
+		item.LastCheckin = &agent.LastCheckin
+	}
+	if agent.Agent != nil && agent.Agent.Version != "" {
+		item.Version = &agent.Agent.Version
+	}
+	if len(agent.LocalMetadata) > 0 {
+		var md map[string]interface{}
+		if err := json.Unmarshal(agent.LocalMetadata, &md); err != nil {
+			return item, fmt.Errorf("unable to decode the local metadata of agent %s: %w", agent.Id, err)
+		}
+		item.LocalMetadata = &md
+	}
+	return item, nil
+}
@@ -0,0 +1,117 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandleAgentSearch(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+
+	hits := []es.HitT{{
+		ID:     "agent-1",
+		Source: []byte(`{"active":true,"enrolled_at":"2024-06-10T00:00:00Z","policy_id":"policy-id","tags":["linux"],"last_checkin_status":"online","agent":{"id":"agent-1","version":"8.14.0"},"local_metadata":{"os":{"family":"debian"}}}`),
+		Sort:   []interface{}{float64(1717977600000), "agent-1"},
+	}}
+
+	tests := []struct {
+		name       string
+		privileged bool
+		body       string
+		status     int
+		query      string
+	}{{
+		name:       "not privileged",
+		privileged: false,
+		body:       `{}`,
+		status:     http.StatusForbidden,
+	}, {
+		name:       "size too large",
+		privileged: true,
+		body:       `{"size":1001}`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:       "filters",
+		privileged: true,
+		body:       `{"policy_id":"policy-id","tags":["linux"],"status":["online"],"local_metadata":{"os.family":"debian"},"size":1,"search_after":[1717977500000,"agent-0"]}`,
+		status:     http.StatusOK,
+		query:      `{"query":{"bool":{"filter":[{"term":{"active":true}},{"term":{"policy_id":"policy-id"}},{"term":{"tags":"linux"}},{"terms":{"last_checkin_status":["online"]}},{"term":{"local_metadata.os.family":"debian"}}]}},"search_after":[1717977500000,"agent-0"],"size":1}`,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(client)
+			if tc.query != "" {
+				fakebulk.On("Search", mock.Anything, dl.FleetAgents, mock.MatchedBy(func(body []byte) bool {
+					var query map[string]interface{}
+					require.NoError(t, json.Unmarshal(body, &query))
+					delete(query, "_source")
+					delete(query, "sort")
+					b, err := json.Marshal(query)
+					require.NoError(t, err)
+					return assert.JSONEq(t, tc.query, string(b))
+				}), mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: hits}}, nil).Once()
+			}
+
+			st := NewAgentSearchT(cfg, fakebulk, nil)
+			st.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			router := newRouter(cfg, &apiServer{ast: st}, nil, nil, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/agents/search", strings.NewReader(tc.body)))
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+			fakebulk.AssertExpectations(t)
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var resp AgentSearchAPIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Len(t, resp.Items, 1)
+			item := resp.Items[0]
+			assert.Equal(t, "agent-1", item.Id)
+			assert.True(t, item.Active)
+			assert.Equal(t, "policy-id", *item.PolicyId)
+			assert.Equal(t, []string{"linux"}, *item.Tags)
+			assert.Equal(t, "online", *item.Status)
+			assert.Equal(t, "8.14.0", *item.Version)
+			assert.Equal(t, map[string]interface{}{"family": "debian"}, (*item.LocalMetadata)["os"])
+			require.NotNil(t, resp.SearchAfter, "a full page has a next page")
+			assert.Equal(t, []interface{}{float64(1717977600000), "agent-1"}, *resp.SearchAfter)
+		})
+	}
+}
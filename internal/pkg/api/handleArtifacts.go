@@ -6,6 +6,7 @@ package api
 
 import (
 	"bytes"
+	"compress/zlib"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
@@ -15,6 +16,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
@@ -42,27 +46,64 @@ var (
 )
 
 type ArtifactT struct {
+	cfg        *config.Server
 	bulker     bulk.Bulk
 	cache      cache.Cache
 	esThrottle *throttle.Throttle
+	upstream   *artifactUpstream
+
+	policyLimits policyLimiter
+}
+
+// artifactUpstream is used to retrieve artifacts that are not present in Elasticsearch.
+type artifactUpstream struct {
+	baseURL string
+	headers map[string]string
+	maxBody int64
+	client  *http.Client
 }
 
 func NewArtifactT(cfg *config.Server, bulker bulk.Bulk, cache cache.Cache) *ArtifactT {
-	return &ArtifactT{
+	at := &ArtifactT{
+		cfg:        cfg,
 		bulker:     bulker,
 		cache:      cache,
 		esThrottle: throttle.NewThrottle(defaultMaxParallel),
+
+		policyLimits: newPolicyLimiter(&cfg.Limits, func(p *config.PolicyLimits) *config.Limit { return p.ArtifactLimit }),
+	}
+
+	if ucfg := cfg.Artifacts.Upstream; ucfg.Enabled {
+		client, err := ucfg.HTTPClient()
+		if err != nil {
+			// The configuration is validated on load, so this should not occur.
+			zerolog.Ctx(context.TODO()).Error().Err(err).Msg("Unable to create artifact upstream client, upstream is disabled")
+			return at
+		}
+		at.upstream = &artifactUpstream{
+			baseURL: strings.TrimSuffix(ucfg.URL, "/"),
+			headers: ucfg.Headers,
+			maxBody: ucfg.MaxBodyByteSize,
+			client:  client,
+		}
+		zerolog.Ctx(context.TODO()).Info().Str("upstream", at.upstream.baseURL).Msg("Artifact upstream enabled")
 	}
+	return at
 }
 
 func (at ArtifactT) handleArtifacts(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, id, sha2 string) error {
 	// Authenticate the APIKey; retrieve agent record.
 	// Note: This is going to be a bit slow even if we hit the cache on the api key.
 	// In order to validate that the agent still has that api key, we fetch the agent record from elastic.
-	agent, err := authAgent(r, nil, at.bulker, at.cache)
+	agent, err := authAgent(r, nil, at.cfg, at.bulker, at.cache)
 	if err != nil {
 		return err
 	}
+	release, err := at.policyLimits.acquire(w, r, agent.PolicyID)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	zlog = zlog.With().Str(LogAccessAPIKeyID, agent.AccessAPIKeyID).Logger()
 	ctx := zlog.WithContext(r.Context())
@@ -108,11 +149,20 @@ func (at ArtifactT) processRequest(ctx context.Context, zlog zerolog.Logger, age
 	}
 
 	// Grab artifact, whether from cache or elastic.
-	artifact, err := at.getArtifact(ctx, zlog, id, sha2)
+	artifact, err := at.getArtifact(ctx, zlog, agent.Namespaces, id, sha2)
 	if err != nil {
 		return nil, err
 	}
 
+	// Artifacts of other namespaces are reported as not found so their existence is not disclosed.
+	if !dl.InNamespaces(artifact.Namespaces, agent.Namespaces) {
+		zlog.Warn().
+			Strs("artifact_namespaces", artifact.Namespaces).
+			Strs("agent_namespaces", agent.Namespaces).
+			Msg("Artifact namespace mismatch")
+		return nil, dl.ErrNotFound
+	}
+
 	// Sanity check; just in case something underneath is misbehaving
 	if artifact.Identifier != id || artifact.DecodedSha256 != sha2 {
 		err = ErrorRecord
@@ -154,27 +204,63 @@ func (at ArtifactT) authorizeArtifact(ctx context.Context, _ *model.Agent, _, _
 
 // Return artifact from cache by sha2 or fetch directly from Elastic.
 // Update cache on successful retrieval from Elastic.
-func (at ArtifactT) getArtifact(ctx context.Context, zlog zerolog.Logger, ident, sha2 string) (*model.Artifact, error) {
+// The artifact lookup and cache entry are scoped to the passed namespaces.
+func (at ArtifactT) getArtifact(ctx context.Context, zlog zerolog.Logger, namespaces []string, ident, sha2 string) (*model.Artifact, error) {
 	span, ctx := apm.StartSpan(ctx, "getArtifact", "process")
 	defer span.End()
 
 	// Check the cache; return immediately if found.
-	if artifact, ok := at.cache.GetArtifact(ident, sha2); ok {
+	cacheNS := artifactCacheNamespace(namespaces)
+	if artifact, ok := at.cache.GetArtifact(cacheNS, ident, sha2); ok {
 		return &artifact, nil
 	}
 
+	// Missing artifacts are not looked up again until their not found entry expires.
+	notFoundID := cacheNS + ":" + ident + ":" + sha2
+	if at.cache.NotFound(cache.NotFoundArtifact, notFoundID) {
+		return nil, dl.ErrNotFound
+	}
+
 	// Fetch the artifact from elastic
-	art, err := at.fetchArtifact(ctx, zlog, ident, sha2)
+	art, err := at.fetchArtifact(ctx, zlog, namespaces, ident, sha2)
+	// Not present locally; fall back to the upstream. The upstream does not report the namespaces of its artifacts,
+	// they belong to the default namespace and are not fetched for the agents outside of it.
+	if errors.Is(err, dl.ErrNotFound) && at.upstream != nil && dl.InNamespaces(nil, namespaces) {
+		art, err = at.fetchUpstreamArtifact(ctx, zlog, ident, sha2)
+		if errors.Is(err, dl.ErrNotFound) {
+			at.cache.SetNotFound(cache.NotFoundArtifact, notFoundID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		at.cache.SetArtifact(cacheNS, *art)
+		return art, nil
+	}
+	if errors.Is(err, dl.ErrNotFound) {
+		at.cache.SetNotFound(cache.NotFoundArtifact, notFoundID)
+	}
 	if err != nil {
 		zlog.Info().Err(err).Msg("Fail retrieve artifact")
 		return nil, err
 	}
 
+	if err := decodeArtifact(ctx, zlog, art); err != nil {
+		return nil, err
+	}
+
+	// Update the cache.
+	at.cache.SetArtifact(cacheNS, *art)
+
+	return art, nil
+}
+
+// decodeArtifact replaces the base64 encoded body of an artifact read from Elastic with the validated payload.
+func decodeArtifact(ctx context.Context, zlog zerolog.Logger, art *model.Artifact) error {
 	// The 'Body' field type is Raw; extract to string.
 	var srcPayload string
-	if err = json.Unmarshal(art.Body, &srcPayload); err != nil {
+	if err := json.Unmarshal(art.Body, &srcPayload); err != nil {
 		zlog.Error().Err(err).Msg("Cannot unmarshal artifact payload")
-		return nil, err
+		return err
 	}
 
 	// Artifact is stored base64 encoded in ElasticSearch.
@@ -183,32 +269,28 @@ func (at ArtifactT) getArtifact(ctx context.Context, zlog zerolog.Logger, ident,
 	dstPayload, err := base64.StdEncoding.DecodeString(srcPayload)
 	if err != nil {
 		zlog.Error().Err(err).Msg("Fail base64 decode artifact")
-		return nil, err
+		return err
 	}
 
 	// Validate the sha256 hash; this is just good hygiene.
 	vSpan, _ := apm.StartSpan(ctx, "validateArtifact", "validate")
-	if err = validateSha2Data(dstPayload, art.EncodedSha256); err != nil {
+	if err := validateSha2Data(dstPayload, art.EncodedSha256); err != nil {
 		vSpan.End()
 		zlog.Error().Err(err).Msg("Fail sha2 hash validation")
-		return nil, err
+		return err
 	}
 	vSpan.End()
 
 	// Reassign decoded payload before adding to cache, avoid base64 decode on cache hit.
 	art.Body = dstPayload
-
-	// Update the cache.
-	at.cache.SetArtifact(*art)
-
-	return art, nil
+	return nil
 }
 
 // Attempt to fetch the artifact from Elastic
 // TODO: Design a mechanism to mitigate a DDOS attack on bogus hashes.
 // Perhaps have a cache of the most recently used hashes available, and items that aren't
 // in the cache can do a lookup but throttle as below.  We could update the cache every 10m or so.
-func (at ArtifactT) fetchArtifact(ctx context.Context, zlog zerolog.Logger, ident, sha2 string) (*model.Artifact, error) {
+func (at ArtifactT) fetchArtifact(ctx context.Context, zlog zerolog.Logger, namespaces []string, ident, sha2 string) (*model.Artifact, error) {
 	span, ctx := apm.StartSpan(ctx, "fetchArtifact", "search")
 	defer span.End()
 	// Throttle prevents more than N outstanding requests to elastic globally and per sha2.
@@ -219,7 +301,7 @@ func (at ArtifactT) fetchArtifact(ctx context.Context, zlog zerolog.Logger, iden
 	}
 
 	start := time.Now()
-	artifact, err := dl.FindArtifact(ctx, at.bulker, ident, sha2)
+	artifact, err := dl.FindArtifact(ctx, at.bulker, namespaces, ident, sha2)
 
 	zlog.Info().
 		Err(err).
@@ -232,6 +314,122 @@ func (at ArtifactT) fetchArtifact(ctx context.Context, zlog zerolog.Logger, iden
 	return artifact, nil
 }
 
+// fetchUpstreamArtifact retrieves the artifact from the configured upstream.
+// The upstream returns the decoded body, as this endpoint does, so it is validated against the requested sha2 before it is cached.
+// The artifact is assigned to the default namespace, the upstream is read with the credentials of fleet-server whatever
+// the namespaces of the requester are.
+func (at ArtifactT) fetchUpstreamArtifact(ctx context.Context, zlog zerolog.Logger, ident, sha2 string) (*model.Artifact, error) {
+	span, ctx := apm.StartSpan(ctx, "fetchUpstreamArtifact", "external")
+	defer span.End()
+	if token := at.esThrottle.Acquire(sha2, defaultThrottleTTL); token == nil {
+		return nil, ErrorThrottle
+	} else {
+		defer token.Release()
+	}
+
+	start := time.Now()
+	body, err := at.upstream.fetch(ctx, ident, sha2)
+
+	zlog.Info().
+		Err(err).
+		Str("upstream", at.upstream.baseURL).
+		Int64(ECSEventDuration, time.Since(start).Nanoseconds()).
+		Msg("fetch upstream artifact")
+
+	if err != nil {
+		return nil, fmt.Errorf("fetchUpstreamArtifact: %w", err)
+	}
+
+	art := &model.Artifact{
+		Identifier:    ident,
+		DecodedSha256: sha2,
+		Body:          body,
+		EncodedSize:   int64(len(body)),
+		Namespaces:    []string{dl.DefaultNamespace},
+	}
+	vSpan, _ := apm.StartSpan(ctx, "validateArtifact", "validate")
+	art.CompressionAlgorithm, err = validateUpstreamArtifact(body, sha2, at.upstream.maxBody)
+	vSpan.End()
+	if err != nil {
+		zlog.Error().Err(err).Str("upstream", at.upstream.baseURL).Msg("Fail sha2 hash validation of upstream artifact")
+		return nil, err
+	}
+
+	cntArtifacts.upstream.Inc()
+	return art, nil
+}
+
+func (u *artifactUpstream) fetch(ctx context.Context, ident, sha2 string) ([]byte, error) {
+	reqURL := u.baseURL + "/api/fleet/artifacts/" + url.PathEscape(ident) + "/" + url.PathEscape(sha2)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range u.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, dl.ErrNotFound
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUpstreamStatus, resp.StatusCode)
+	}
+
+	// Read one byte past the limit to detect oversized responses.
+	var b bytes.Buffer
+	n, err := io.Copy(&b, io.LimitReader(resp.Body, u.maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > u.maxBody {
+		return nil, fmt.Errorf("upstream artifact exceeds %d bytes", u.maxBody)
+	}
+	return b.Bytes(), nil
+}
+
+// validateUpstreamArtifact ensures that the payload retrieved from an upstream matches the requested sha2.
+// The requested sha2 is of the decoded artifact, so a zlib compressed body is inflated before it's checked.
+// The detected compression algorithm is returned.
+func validateUpstreamArtifact(body []byte, sha2 string, maxDecoded int64) (string, error) {
+	if err := validateSha2Data(body, sha2); err == nil {
+		return "", nil
+	} else if !errors.Is(err, ErrorMismatchSha2) {
+		return "", err
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return "", ErrorMismatchSha2
+	}
+	defer zr.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(zr, maxDecoded)); err != nil {
+		return "", ErrorMismatchSha2
+	}
+	if hex.EncodeToString(h.Sum(nil)) != sha2 {
+		return "", ErrorMismatchSha2
+	}
+	return "zlib", nil
+}
+
+// artifactCacheNamespace returns the namespace used to scope cached artifacts for a requester with namespaces.
+func artifactCacheNamespace(namespaces []string) string {
+	if len(namespaces) == 0 {
+		return dl.DefaultNamespace
+	}
+	ns := slices.Clone(namespaces)
+	slices.Sort(ns)
+	return strings.Join(slices.Compact(ns), ",")
+}
+
 func validateSha2String(sha2 string) error {
 
 	if len(sha2) != 64 {
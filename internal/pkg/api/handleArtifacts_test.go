@@ -0,0 +1,242 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/cache"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func Test_ArtifactT_fetchUpstreamArtifact(t *testing.T) {
+	body := []byte(`{"entries":[]}`)
+	h := sha256.Sum256(body)
+	sha2 := hex.EncodeToString(h[:])
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name        string
+		status      int
+		content     []byte
+		maxBody     int64
+		compression string
+		valid       bool
+		err         error
+	}{{
+		name:    "plain body",
+		status:  http.StatusOK,
+		content: body,
+		maxBody: 1024,
+		valid:   true,
+	}, {
+		name:        "zlib body",
+		status:      http.StatusOK,
+		content:     compressed.Bytes(),
+		maxBody:     1024,
+		compression: "zlib",
+		valid:       true,
+	}, {
+		name:    "sha2 mismatch",
+		status:  http.StatusOK,
+		content: []byte("not the artifact"),
+		maxBody: 1024,
+		err:     ErrorMismatchSha2,
+	}, {
+		name:    "not found",
+		status:  http.StatusNotFound,
+		maxBody: 1024,
+		err:     dl.ErrNotFound,
+	}, {
+		name:    "upstream error",
+		status:  http.StatusInternalServerError,
+		maxBody: 1024,
+		err:     ErrUpstreamStatus,
+	}, {
+		name:    "body too large",
+		status:  http.StatusOK,
+		content: body,
+		maxBody: 4,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/fleet/artifacts/endpoint-exceptionlist-linux-v1/"+sha2, r.URL.Path)
+				require.Equal(t, "ApiKey test", r.Header.Get("Authorization"))
+				w.WriteHeader(tc.status)
+				_, _ = w.Write(tc.content)
+			}))
+			defer server.Close()
+
+			c := cache.NewMockCache()
+			cfg := &config.Server{}
+			cfg.InitDefaults()
+			cfg.Artifacts.Upstream.Enabled = true
+			cfg.Artifacts.Upstream.URL = server.URL + "/"
+			cfg.Artifacts.Upstream.Headers = map[string]string{"Authorization": "ApiKey test"}
+			cfg.Artifacts.Upstream.MaxBodyByteSize = tc.maxBody
+
+			at := NewArtifactT(cfg, nil, c)
+			require.NotNil(t, at.upstream)
+
+			art, err := at.fetchUpstreamArtifact(context.Background(), testlog.SetLogger(t), "endpoint-exceptionlist-linux-v1", sha2)
+			if tc.valid {
+				require.NoError(t, err)
+				require.Equal(t, sha2, art.DecodedSha256)
+				require.Equal(t, tc.content, []byte(art.Body))
+				require.Equal(t, tc.compression, art.CompressionAlgorithm)
+				require.Equal(t, []string{"default"}, art.Namespaces)
+			} else {
+				require.Error(t, err)
+				if tc.err != nil {
+					require.ErrorIs(t, err, tc.err)
+				}
+			}
+			c.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ArtifactT_processRequest_UpstreamNamespaces(t *testing.T) {
+	body := []byte(`{"entries":[]}`)
+	h := sha256.Sum256(body)
+	sha2 := hex.EncodeToString(h[:])
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		cacheNS    string
+		upstream   int
+		err        error
+	}{{
+		name:       "default namespace",
+		namespaces: nil,
+		cacheNS:    "default",
+		upstream:   1,
+	}, {
+		// the artifact of the upstream may belong to another space, it is not read for the agents of space A
+		name:       "other namespace",
+		namespaces: []string{"spaceA"},
+		cacheNS:    "spaceA",
+		err:        dl.ErrNotFound,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Search", mock.Anything, dl.FleetArtifacts, mock.Anything, mock.Anything).Return(&es.ResultT{}, nil).Once()
+			c := cache.NewMockCache()
+			c.On("GetArtifact", tc.cacheNS, "endpoint-exceptionlist-linux-v1", sha2).Return(model.Artifact{}, false).Once()
+			c.On("NotFound", mock.Anything, mock.Anything).Return(false).Once()
+			if tc.err != nil {
+				c.On("SetNotFound", mock.Anything, mock.Anything).Once()
+			} else {
+				c.On("SetArtifact", tc.cacheNS, mock.Anything).Once()
+			}
+
+			cfg := &config.Server{}
+			cfg.InitDefaults()
+			cfg.Artifacts.Upstream.Enabled = true
+			cfg.Artifacts.Upstream.URL = server.URL
+			at := NewArtifactT(cfg, bulker, c)
+
+			agent := &model.Agent{Namespaces: tc.namespaces}
+			_, err := at.processRequest(context.Background(), testlog.SetLogger(t), agent, "endpoint-exceptionlist-linux-v1", sha2)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.upstream, requests)
+			c.AssertExpectations(t)
+			bulker.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_NewArtifactT_UpstreamDisabled(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	at := NewArtifactT(cfg, nil, cache.NewMockCache())
+	require.Nil(t, at.upstream)
+}
+
+func Test_ArtifactT_processRequest_Namespaces(t *testing.T) {
+	artifact := model.Artifact{
+		Identifier:    "ident",
+		DecodedSha256: "sha2",
+		Namespaces:    []string{"space1"},
+		Body:          []byte("test"),
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		cacheNS    string
+		err        error
+	}{{
+		name:       "same namespace",
+		namespaces: []string{"space1"},
+		cacheNS:    "space1",
+	}, {
+		name:       "other namespace",
+		namespaces: []string{"space2", "default"},
+		cacheNS:    "default,space2",
+		err:        dl.ErrNotFound,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := cache.NewMockCache()
+			c.On("GetArtifact", tc.cacheNS, "ident", "sha2").Return(artifact, true).Once()
+
+			cfg := &config.Server{}
+			cfg.InitDefaults()
+			at := NewArtifactT(cfg, nil, c)
+
+			agent := &model.Agent{Namespaces: tc.namespaces}
+			_, err := at.processRequest(context.Background(), testlog.SetLogger(t), agent, "ident", "sha2")
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			c.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_artifactCacheNamespace(t *testing.T) {
+	require.Equal(t, "default", artifactCacheNamespace(nil))
+	require.Equal(t, "a,b", artifactCacheNamespace([]string{"b", "a", "b"}))
+}
@@ -12,19 +12,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"math/rand"
 	"net/http"
 	"reflect"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/action"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/checkin"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
@@ -44,6 +51,7 @@ var (
 	ErrNoPolicyOutput         = errors.New("output section not found")
 	ErrFailInjectAPIKey       = errors.New("failure to inject api key")
 	ErrInvalidUpgradeMetadata = errors.New("invalid upgrade metadata")
+	ErrPolicyTooLarge         = errors.New("policy too large")
 )
 
 const (
@@ -80,6 +88,10 @@ type CheckinT struct {
 	// effectiveness of the pool is controlled by rate limiter configured through the limit.action_limit attribute.
 	gwPool sync.Pool
 	bulker bulk.Bulk
+	inv    *invalidator.Invalidator
+
+	policyLimits policyLimiter
+	versions     *agentVersionRules
 }
 
 func NewCheckinT(
@@ -92,7 +104,10 @@ func NewCheckinT(
 	ad *action.Dispatcher,
 	tr *action.TokenResolver,
 	bulker bulk.Bulk,
+	inv *invalidator.Invalidator,
 ) *CheckinT {
+	// the rules are validated with the configuration
+	versions, _ := newAgentVersionRules(&cfg.AgentVersions)
 	ct := &CheckinT{
 		verCon: verCon,
 		cfg:    cfg,
@@ -112,6 +127,10 @@ func NewCheckinT(
 			},
 		},
 		bulker: bulker,
+		inv:    inv,
+
+		policyLimits: newPolicyLimiter(&cfg.Limits, func(p *config.PolicyLimits) *config.Limit { return p.CheckinLimit }),
+		versions:     versions,
 	}
 
 	return ct
@@ -120,16 +139,22 @@ func NewCheckinT(
 func (ct *CheckinT) handleCheckin(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, id, userAgent string) error {
 	start := time.Now()
 
-	agent, err := authAgent(r, &id, ct.bulker, ct.cache)
+	agent, err := authAgent(r, &id, ct.cfg, ct.bulker, ct.cache)
 	if err != nil {
 		// invalidate remote API keys of force unenrolled agents
 		if errors.Is(err, ErrAgentInactive) && agent != nil {
 			ctx := zlog.WithContext(r.Context())
-			invalidateAPIKeysOfInactiveAgent(ctx, zlog, ct.bulker, agent)
+			ct.invalidateAPIKeysOfInactiveAgent(ctx, zlog, agent)
 		}
 		return err
 	}
 
+	release, err := ct.policyLimits.acquire(w, r, agent.PolicyID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	zlog = zlog.With().Str(LogAccessAPIKeyID, agent.AccessAPIKeyID).Logger()
 	ctx := zlog.WithContext(r.Context())
 	r = r.WithContext(ctx)
@@ -138,13 +163,16 @@ func (ct *CheckinT) handleCheckin(zlog zerolog.Logger, w http.ResponseWriter, r
 	if err != nil {
 		return err
 	}
+	if err := ct.versions.check(zlog, ver, agent.PolicyID); err != nil {
+		return err
+	}
 
 	// Safely check if the agent version is different, return empty string otherwise
 	newVer := agent.CheckDifferentVersion(ver)
 	return ct.ProcessRequest(zlog, w, r, start, agent, newVer)
 }
 
-func invalidateAPIKeysOfInactiveAgent(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, agent *model.Agent) {
+func (ct *CheckinT) invalidateAPIKeysOfInactiveAgent(ctx context.Context, zlog zerolog.Logger, agent *model.Agent) {
 	remoteAPIKeys := make([]model.ToRetireAPIKeyIdsItems, 0)
 	apiKeys := agent.APIKeyIDs()
 	for _, key := range apiKeys {
@@ -153,7 +181,7 @@ func invalidateAPIKeysOfInactiveAgent(ctx context.Context, zlog zerolog.Logger,
 		}
 	}
 	zlog.Info().Any("fleet.policy.apiKeyIDsToRetire", remoteAPIKeys).Msg("handleCheckin invalidate remote API keys")
-	invalidateAPIKeys(ctx, zlog, bulker, remoteAPIKeys, "")
+	invalidateAPIKeys(ctx, zlog, ct.bulker, ct.inv, agent.Id, audit.ReasonAgentInactive, remoteAPIKeys, "")
 }
 
 // validatedCheckin is a struct to wrap all the things that validateRequest returns.
@@ -170,12 +198,8 @@ func (ct *CheckinT) validateRequest(zlog zerolog.Logger, w http.ResponseWriter,
 	span, ctx := apm.StartSpan(r.Context(), "validateRequest", "validate")
 	defer span.End()
 
-	body := r.Body
-	// Limit the size of the body to prevent malicious agent from exhausting RAM in server
-	if ct.cfg.Limits.CheckinLimit.MaxBody > 0 {
-		body = http.MaxBytesReader(w, body, ct.cfg.Limits.CheckinLimit.MaxBody)
-	}
-	readCounter := datacounter.NewReaderCounter(body)
+	// the size of the body is limited by the limiter of the route
+	readCounter := datacounter.NewReaderCounter(r.Body)
 
 	var val validatedCheckin
 	var req CheckinRequest
@@ -276,7 +300,13 @@ func (ct *CheckinT) ProcessRequest(zlog zerolog.Logger, w http.ResponseWriter, r
 	actCh := aSub.Ch()
 
 	// Subscribe to policy manager for changes on PolicyId > policyRev
-	sub, err := ct.pm.Subscribe(agent.Id, agent.PolicyID, agent.PolicyRevisionIdx, agent.PolicyCoordinatorIdx)
+	revIdx := agent.PolicyRevisionIdx
+	if maxAge := ct.cfg.APIKeyRotation.MaxAge; maxAge > 0 && policy.APIKeyRotationDue(agent, maxAge, time.Now()) {
+		// Subscribe from revision 0 so the policy is sent again with the rotated output API keys.
+		zlog.Info().Dur("max_age", maxAge).Msg("output API key reached its maximum age, sending policy with new keys")
+		revIdx = 0
+	}
+	sub, err := ct.pm.Subscribe(agent.Id, agent.PolicyID, revIdx, agent.PolicyCoordinatorIdx)
 	if err != nil {
 		return fmt.Errorf("subscribe policy monitor: %w", err)
 	}
@@ -287,6 +317,14 @@ func (ct *CheckinT) ProcessRequest(zlog zerolog.Logger, w http.ResponseWriter, r
 		}
 	}()
 
+	// Report the agent health so a staged rollout of the policy revision it runs can be held when agents degrade.
+	switch req.Status {
+	case CheckinRequestStatusOnline:
+		ct.pm.ReportHealth(agent.Id, agent.PolicyID, agent.PolicyRevisionIdx, true)
+	case CheckinRequestStatusDegraded, CheckinRequestStatusError:
+		ct.pm.ReportHealth(agent.Id, agent.PolicyID, agent.PolicyRevisionIdx, false)
+	}
+
 	// Update check-in timestamp on timeout
 	tick := time.NewTicker(ct.cfg.Timeouts.CheckinTimestamp)
 	defer tick.Stop()
@@ -323,11 +361,22 @@ func (ct *CheckinT) ProcessRequest(zlog zerolog.Logger, w http.ResponseWriter, r
 	if err != nil {
 		return err
 	}
-	pendingActions = filterActions(zlog, agent.Id, pendingActions)
+	pendingActions = filterActions(zlog, agent, pendingActions)
 	actions, ackToken = convertActions(zlog, agent.Id, pendingActions)
 
 	span, ctx := apm.StartSpan(r.Context(), "longPoll", "process")
 	if len(actions) == 0 {
+		// the long poll of a draining server ends at the offset of the agent within the drain window
+		drainStarted := drain.FromContext(ctx).Started()
+		var (
+			drainTimer *time.Timer
+			drainEnd   <-chan time.Time
+		)
+		defer func() {
+			if drainTimer != nil {
+				drainTimer.Stop()
+			}
+		}()
 	LOOP:
 		for {
 			select {
@@ -345,12 +394,12 @@ func (ct *CheckinT) ProcessRequest(zlog zerolog.Logger, w http.ResponseWriter, r
 				return ctx.Err()
 			case acdocs := <-actCh:
 				var acs []Action
-				acdocs = filterActions(zlog, agent.Id, acdocs)
+				acdocs = filterActions(zlog, agent, acdocs)
 				acs, ackToken = convertActions(zlog, agent.Id, acdocs)
 				actions = append(actions, acs...)
 				break LOOP
 			case policy := <-sub.Output():
-				actionResp, err := processPolicy(ctx, zlog, ct.bulker, agent.Id, policy)
+				actionResp, err := ct.processPolicy(ctx, zlog, agent.Id, policy, acceptsPolicyEncoding(req, kEncodingGzip))
 				if err != nil {
 					span.End()
 					return fmt.Errorf("processPolicy: %w", err)
@@ -360,6 +409,19 @@ func (ct *CheckinT) ProcessRequest(zlog zerolog.Logger, w http.ResponseWriter, r
 			case <-longPoll.C:
 				zlog.Trace().Msg("fire long poll")
 				break LOOP
+			case <-drainStarted:
+				// the agents of a draining server check in with another server of the tier
+				drainStarted = nil
+				delay := drainDelay(drain.FromContext(ctx), ct.cfg.Drain.Window, agent.Id, time.Now())
+				if delay <= 0 {
+					zlog.Trace().Msg("end long poll on drain")
+					break LOOP
+				}
+				drainTimer = time.NewTimer(delay)
+				drainEnd = drainTimer.C
+			case <-drainEnd:
+				zlog.Trace().Msg("end long poll on drain")
+				break LOOP
 			case <-tick.C:
 				err := ct.bc.CheckIn(agent.Id, string(req.Status), req.Message, nil, rawComponents, nil, ver, unhealthyReason)
 				if err != nil {
@@ -551,6 +613,16 @@ func (ct *CheckinT) writeResponse(zlog zerolog.Logger, w http.ResponseWriter, r
 			Int64("timeout", fromPtr(action.Timeout)).
 			Msg("Action delivered to agent on checkin")
 	}
+	if backoff := ct.backoffHint(r); backoff != nil {
+		zlog.Debug().Str("reason", string(backoff.Reason)).Msg("Backoff hint sent to agent on checkin")
+		resp.Backoff = backoff
+		w.Header().Set("Retry-After", strconv.Itoa(backoff.RetryAfter))
+	}
+	if drain.FromContext(ctx).Draining() {
+		// the next checkin opens a connection, likely with another server behind the load balancer
+		w.Header().Set("Connection", "close")
+	}
+
 	rSpan, _ := apm.StartSpan(ctx, "response", "write")
 	defer rSpan.End()
 
@@ -600,6 +672,54 @@ func (ct *CheckinT) writeResponse(zlog zerolog.Logger, w http.ResponseWriter, r
 	return err
 }
 
+// drainDelay returns how long the long poll of the agent lasts once the server drains. The agents are spread over
+// the drain window by a hash of their ID, so a long poll ends at the same offset wherever the agent checks in.
+func drainDelay(state *drain.State, window time.Duration, agentID string, now time.Time) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	since, _ := state.Since()
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(agentID))
+	offset := time.Duration(float64(window) * float64(h.Sum32()) / (1 << 32))
+	return since.Add(offset).Sub(now)
+}
+
+// backoffHint returns the backoff hint of the response when the server is draining or under pressure, nil otherwise.
+// The checkin limiter is saturated when most of its max is in use or when its rate is exhausted, and Elasticsearch
+// is considered under pressure when the bulker queues more operations than it flushes.
+func (ct *CheckinT) backoffHint(r *http.Request) *CheckinBackoff {
+	if drain.FromContext(r.Context()).Draining() {
+		backoff := &CheckinBackoff{
+			PollInterval: ct.cfg.Drain.RetryAfter.String(),
+			Reason:       Draining,
+			RetryAfter:   int(ct.cfg.Drain.RetryAfter.Seconds()),
+		}
+		if len(ct.cfg.Drain.Hosts) > 0 {
+			hosts := ct.cfg.Drain.Hosts
+			backoff.Hosts = &hosts
+		}
+		return backoff
+	}
+	cfg := ct.cfg.CheckinBackoff
+	if !cfg.Enabled {
+		return nil
+	}
+	var reason CheckinBackoffReason
+	if l, ok := limit.FromContext(r.Context()); ok && (l.Usage() >= cfg.LimiterThreshold || l.RateLimited()) {
+		reason = Limits
+	} else if cfg.QueueThreshold > 0 && ct.bulker.QueueDepth() >= cfg.QueueThreshold {
+		reason = Elasticsearch
+	} else {
+		return nil
+	}
+	return &CheckinBackoff{
+		PollInterval: cfg.PollInterval.String(),
+		Reason:       reason,
+		RetryAfter:   int(cfg.RetryAfter.Seconds()),
+	}
+}
+
 func acceptsEncoding(r *http.Request, encoding string) bool {
 	for _, v := range r.Header.Values("Accept-Encoding") {
 		if v == encoding {
@@ -649,11 +769,16 @@ func (ct *CheckinT) fetchAgentPendingActions(ctx context.Context, seqno sqn.SeqN
 // The source of this list are documents from the fleet actions index.
 // The POLICY_CHANGE action that the agent receives are generated by the fleet-server when it detects a different policy in processRequest()
 // The UPDATE_TAGS, FORCE_UNENROLL actions are UI only actions, should not be delivered to agents
-func filterActions(zlog zerolog.Logger, agentID string, actions []model.Action) []model.Action {
+// The actions of namespaces the agent is not in are removed too.
+func filterActions(zlog zerolog.Logger, agent *model.Agent, actions []model.Action) []model.Action {
 	resp := make([]model.Action, 0, len(actions))
 	for _, action := range actions {
 		if valid := validActionTypes[action.Type]; !valid {
-			zlog.Info().Str(logger.AgentID, agentID).Str(logger.ActionID, action.ActionID).Str(logger.ActionType, action.Type).Msg("Removing action found in index from check in response")
+			zlog.Info().Str(logger.AgentID, agent.Id).Str(logger.ActionID, action.ActionID).Str(logger.ActionType, action.Type).Msg("Removing action found in index from check in response")
+			continue
+		}
+		if !dl.InNamespaces(action.Namespaces, agent.Namespaces) {
+			zlog.Warn().Str(logger.AgentID, agent.Id).Str(logger.ActionID, action.ActionID).Strs("action.namespaces", action.Namespaces).Msg("Removing action of another namespace from check in response")
 			continue
 		}
 		resp = append(resp, action)
@@ -781,7 +906,8 @@ func convertActions(zlog zerolog.Logger, agentID string, actions []model.Action)
 // A new policy exists for this agent.  Perform the following:
 //   - Generate and update default ApiKey if roles have changed.
 //   - Rewrite the policy for delivery to the agent injecting the key material.
-func processPolicy(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, agentID string, pp *policy.ParsedPolicy) (*Action, error) {
+//   - Gzip the policy if gzipPolicy is set and it is larger than the compression threshold.
+func (ct *CheckinT) processPolicy(ctx context.Context, zlog zerolog.Logger, agentID string, pp *policy.ParsedPolicy, gzipPolicy bool) (*Action, error) {
 	var links []apm.SpanLink = nil // set to a nil array to preserve default behaviour if no policy links are found
 	if err := pp.Links.Trace.Validate(); err == nil {
 		links = []apm.SpanLink{pp.Links}
@@ -797,7 +923,7 @@ func processPolicy(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, a
 
 	// Repull and decode the agent object. Do not trust the cache.
 	bSpan, bCtx := apm.StartSpan(ctx, "findAgent", "search")
-	agent, err := dl.FindAgent(bCtx, bulker, dl.QueryAgentByID, dl.FieldID, agentID)
+	agent, err := dl.FindAgent(bCtx, ct.bulker, dl.QueryAgentByID, dl.FieldID, agentID, dl.WithTimeout(ct.cfg.Timeouts.Query))
 	bSpan.End()
 	if err != nil {
 		zlog.Error().Err(err).Msg("fail find agent record")
@@ -810,35 +936,60 @@ func processPolicy(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, a
 
 	data := model.ClonePolicyData(pp.Policy.Data)
 	for policyName, policyOutput := range data.Outputs {
-		err := policy.ProcessOutputSecret(ctx, policyOutput, bulker)
+		err := policy.ProcessOutputSecret(ctx, policyOutput, ct.bulker)
 		if err != nil {
 			return nil, fmt.Errorf("failed to process output secrets %q: %w",
 				policyName, err)
 		}
 	}
-	// Iterate through the policy outputs and prepare them
-	for _, policyOutput := range pp.Outputs {
-		err = policyOutput.Prepare(ctx, zlog, bulker, &agent, data.Outputs)
+	inputs, outputs := pp.Inputs, pp.Outputs
+	if agent.NamespaceOverride != "" {
+		// Route the data of the agent to its own namespace, the outputs are prepared with the matching permissions.
+		inputs, outputs, err = policy.OverrideNamespace(agent.NamespaceOverride, pp.Inputs, pp.Outputs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to prepare output %q: %w",
-				policyOutput.Name, err)
+			zlog.Warn().Err(err).Str("namespace_override", agent.NamespaceOverride).Msg("ignoring agent namespace override")
+			inputs, outputs = pp.Inputs, pp.Outputs
 		}
 	}
+	if maxAge := ct.cfg.APIKeyRotation.MaxAge; maxAge > 0 {
+		outputs = policy.WithAPIKeyRotation(outputs, maxAge)
+	}
+	// Iterate through the policy outputs and prepare them
+	if err := policy.PrepareOutputs(ctx, zlog, ct.bulker, &agent, outputs, data.Outputs); err != nil {
+		return nil, err
+	}
 	// Add replace inputs with agent prepared version.
-	data.Inputs = pp.Inputs
+	data.Inputs = inputs
 
 	// JSON transformations to turn a model.PolicyData into an Action.data
 	p, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
-	d := PolicyData{}
-	err = json.Unmarshal(p, &d)
-	if err != nil {
-		return nil, err
+	if maxSize := ct.cfg.PolicyMaxSize; maxSize > 0 && len(p) > maxSize {
+		return nil, fmt.Errorf("%w: rendered policy is %d bytes, the limit is %d bytes", ErrPolicyTooLarge, len(p), maxSize)
+	}
+	var change ActionPolicyChange
+	if gzipPolicy && len(p) > ct.cfg.CompressionThresh && ct.cfg.CompressionLevel != flate.NoCompression {
+		compressed, err := ct.gzipPolicy(p)
+		if err != nil {
+			return nil, err
+		}
+		zlog.Trace().
+			Int("srcSz", len(p)).
+			Int("dstSz", len(compressed)).
+			Msg("compressing policy")
+		change.PolicyGzip = &compressed
+	} else {
+		d := PolicyData{}
+		err = json.Unmarshal(p, &d)
+		if err != nil {
+			return nil, err
+		}
+		change.Policy = &d
 	}
 	ad := Action_Data{}
-	err = ad.FromActionPolicyChange(ActionPolicyChange{d})
+	err = ad.FromActionPolicyChange(change)
 	if err != nil {
 		return nil, err
 	}
@@ -855,10 +1006,47 @@ func processPolicy(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, a
 	return &resp, nil
 }
 
-func getAgentAndVerifyAPIKeyID(ctx context.Context, bulker bulk.Bulk, agentID string, apiKeyID string) (*model.Agent, error) {
+// gzipPolicy compresses the rendered policy with a writer of the pool used for the responses.
+func (ct *CheckinT) gzipPolicy(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zipper, _ := ct.gwPool.Get().(*gzip.Writer)
+	defer ct.gwPool.Put(zipper)
+	zipper.Reset(&buf)
+
+	if _, err := zipper.Write(p); err != nil {
+		return nil, fmt.Errorf("policy gzip write: %w", err)
+	}
+	if err := zipper.Close(); err != nil {
+		return nil, fmt.Errorf("policy gzip close: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// acceptsPolicyEncoding returns true if the agent listed encoding in the policy_encodings of its checkin request.
+func acceptsPolicyEncoding(req *CheckinRequest, encoding string) bool {
+	if req.PolicyEncodings == nil {
+		return false
+	}
+	return slices.Contains(*req.PolicyEncodings, encoding)
+}
+
+// getAgent gets the agent document by id, the agents that are not found are not looked up again until their
+// not found entry in the cache expires.
+func getAgent(ctx context.Context, bulker bulk.Bulk, c cache.Cache, agentID string) (model.Agent, error) {
+	if c.NotFound(cache.NotFoundAgent, agentID) {
+		return model.Agent{}, dl.ErrNotFound
+	}
+	agent, err := dl.GetAgent(ctx, bulker, agentID)
+	if errors.Is(err, dl.ErrNotFound) {
+		c.SetNotFound(cache.NotFoundAgent, agentID)
+	}
+	return agent, err
+}
+
+func getAgentAndVerifyAPIKeyID(ctx context.Context, bulker bulk.Bulk, c cache.Cache, agentID string, apiKeyID string) (*model.Agent, error) {
 	span, ctx := apm.StartSpan(ctx, "getAgentAndVerifyAPIKeyID", "read")
 	defer span.End()
-	agent, err := dl.GetAgent(ctx, bulker, agentID)
+	agent, err := getAgent(ctx, bulker, c, agentID)
 	if err != nil {
 		if errors.Is(err, dl.ErrNotFound) {
 			err = ErrAgentNotFound
@@ -874,12 +1062,16 @@ func getAgentAndVerifyAPIKeyID(ctx context.Context, bulker bulk.Bulk, agentID st
 	return &agent, err
 }
 
-func findAgentByAPIKeyID(ctx context.Context, bulker bulk.Bulk, id string) (*model.Agent, error) {
+func findAgentByAPIKeyID(ctx context.Context, bulker bulk.Bulk, c cache.Cache, id string) (*model.Agent, error) {
 	span, ctx := apm.StartSpan(ctx, "findAgentByID", "search")
 	defer span.End()
+	if c.NotFound(cache.NotFoundAgentAPIKey, id) {
+		return &model.Agent{}, ErrAgentNotFound
+	}
 	agent, err := dl.FindAgent(ctx, bulker, dl.QueryAgentByAssessAPIKeyID, dl.FieldAccessAPIKeyID, id)
 	if err != nil {
 		if errors.Is(err, dl.ErrNotFound) {
+			c.SetNotFound(cache.NotFoundAgentAPIKey, id)
 			err = ErrAgentNotFound
 		} else {
 			err = fmt.Errorf("findAgentByApiKeyId: %w", err)
@@ -7,9 +7,12 @@
 package api
 
 import (
+	"bytes"
 	"compress/flate"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -17,11 +20,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/checkin"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	mockmonitor "github.com/elastic/fleet-server/v7/internal/pkg/monitor/mock"
 	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
@@ -261,11 +267,27 @@ func TestFilterActions(t *testing.T) {
 			ActionID: "1234",
 		}},
 		resp: []model.Action{},
+	}, {
+		name: "filter actions of other namespaces",
+		actions: []model.Action{{
+			ActionID:   "1234",
+			Type:       "UPGRADE",
+			Namespaces: []string{"other"},
+		}, {
+			ActionID:   "5678",
+			Type:       "UPGRADE",
+			Namespaces: []string{"default", "other"},
+		}},
+		resp: []model.Action{{
+			ActionID:   "5678",
+			Type:       "UPGRADE",
+			Namespaces: []string{"default", "other"},
+		}},
 	}}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			logger := testlog.SetLogger(t)
-			resp := filterActions(logger, "agent-id", tc.actions)
+			resp := filterActions(logger, &model.Agent{ESDocument: model.ESDocument{Id: "agent-id"}}, tc.actions)
 			assert.Equal(t, tc.resp, resp)
 		})
 	}
@@ -300,7 +322,7 @@ func TestResolveSeqNo(t *testing.T) {
 			bulker := ftesting.NewMockBulk()
 			pim := mockmonitor.NewMockMonitor()
 			pm := policy.NewMonitor(bulker, pim, config.ServerLimits{PolicyLimit: config.Limit{Interval: 5 * time.Millisecond, Burst: 1}})
-			ct := NewCheckinT(verCon, cfg, c, bc, pm, nil, nil, nil, nil)
+			ct := NewCheckinT(verCon, cfg, c, bc, pm, nil, nil, nil, nil, nil)
 
 			resp, _ := ct.resolveSeqNo(ctx, logger, tc.req, tc.agent)
 			assert.Equal(t, tc.resp, resp)
@@ -634,7 +656,7 @@ func Test_CheckinT_writeResponse(t *testing.T) {
 		CompressionThresh: 1,
 	}
 
-	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk())
+	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk(), nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -650,13 +672,206 @@ func Test_CheckinT_writeResponse(t *testing.T) {
 	}
 }
 
+// queueBulk is a bulker with a fixed queue depth.
+type queueBulk struct {
+	*ftesting.MockBulk
+	depth int
+}
+
+func (b queueBulk) QueueDepth() int {
+	return b.depth
+}
+
+func TestCheckinBackoffHint(t *testing.T) {
+	backoff := config.CheckinBackoff{}
+	backoff.InitDefaults()
+	backoff.QueueThreshold = 10
+
+	tests := []struct {
+		name    string
+		enabled bool
+		limit   *config.Limit
+		depth   int
+		expect  *CheckinBackoff
+	}{{
+		name:    "no pressure",
+		enabled: true,
+		limit:   &config.Limit{Interval: time.Millisecond, Burst: 10, Max: 10},
+	}, {
+		name:    "limiter saturated",
+		enabled: true,
+		limit:   &config.Limit{Interval: time.Millisecond, Burst: 10, Max: 1},
+		depth:   10,
+		expect:  &CheckinBackoff{PollInterval: "1m0s", Reason: Limits, RetryAfter: 30},
+	}, {
+		name:    "bulk queue full",
+		enabled: true,
+		limit:   &config.Limit{Interval: time.Millisecond, Burst: 10, Max: 10},
+		depth:   10,
+		expect:  &CheckinBackoff{PollInterval: "1m0s", Reason: Elasticsearch, RetryAfter: 30},
+	}, {
+		name:  "disabled",
+		limit: &config.Limit{Interval: time.Millisecond, Burst: 10, Max: 1},
+		depth: 10,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Server{CheckinBackoff: backoff}
+			cfg.CheckinBackoff.Enabled = test.enabled
+			ct := NewCheckinT(mustBuildConstraints("8.0.0"), cfg, nil, nil, nil, nil, nil, nil, queueBulk{ftesting.NewMockBulk(), test.depth}, nil)
+
+			var hint *CheckinBackoff
+			h := limit.NewLimiter(test.limit).Wrap("checkin", nil, zerolog.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hint = ct.backoffHint(r)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+			assert.Equal(t, test.expect, hint)
+		})
+	}
+}
+
+func Test_CheckinT_writeResponse_backoff(t *testing.T) {
+	cfg := &config.Server{CompressionThresh: 1024}
+	cfg.CheckinBackoff.InitDefaults()
+	cfg.CheckinBackoff.QueueThreshold = 1
+	ct := NewCheckinT(mustBuildConstraints("8.0.0"), cfg, nil, nil, nil, nil, nil, nil, queueBulk{ftesting.NewMockBulk(), 1}, nil)
+
+	wr := httptest.NewRecorder()
+	err := ct.writeResponse(testlog.SetLogger(t), wr, &http.Request{}, &model.Agent{}, CheckinResponse{Action: "checkin"})
+	require.NoError(t, err)
+	assert.Equal(t, "30", wr.Header().Get("Retry-After"))
+
+	var resp CheckinResponse
+	require.NoError(t, json.Unmarshal(wr.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Backoff)
+	assert.Equal(t, Elasticsearch, resp.Backoff.Reason)
+	assert.Equal(t, "1m0s", resp.Backoff.PollInterval)
+}
+
+func Test_CheckinT_writeResponse_draining(t *testing.T) {
+	cfg := &config.Server{CompressionThresh: 1024}
+	cfg.Drain.InitDefaults()
+	cfg.Drain.Hosts = []string{"https://fleet-2.example.com:8220"}
+	ct := NewCheckinT(mustBuildConstraints("8.0.0"), cfg, nil, nil, nil, nil, nil, nil, queueBulk{ftesting.NewMockBulk(), 0}, nil)
+
+	state := drain.New()
+	state.Start(drain.ReasonSignal)
+	req := (&http.Request{}).WithContext(drain.WithContext(context.Background(), state))
+	wr := httptest.NewRecorder()
+	err := ct.writeResponse(testlog.SetLogger(t), wr, req, &model.Agent{}, CheckinResponse{Action: "checkin"})
+	require.NoError(t, err)
+	assert.Equal(t, "5", wr.Header().Get("Retry-After"))
+	assert.Equal(t, "close", wr.Header().Get("Connection"))
+
+	var resp CheckinResponse
+	require.NoError(t, json.Unmarshal(wr.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Backoff)
+	assert.Equal(t, Draining, resp.Backoff.Reason, "the drain hint is sent while the pressure hints are disabled")
+	assert.Equal(t, "5s", resp.Backoff.PollInterval)
+	require.NotNil(t, resp.Backoff.Hosts)
+	assert.Equal(t, cfg.Drain.Hosts, *resp.Backoff.Hosts)
+}
+
+func TestDrainDelay(t *testing.T) {
+	state := drain.New()
+	state.Start(drain.ReasonSignal)
+	since, _ := state.Since()
+	window := time.Minute
+
+	assert.Zero(t, drainDelay(state, 0, "agent-1", since), "the long polls end at once without a window")
+
+	agents := 1000
+	var early int
+	for i := 0; i < agents; i++ {
+		delay := drainDelay(state, window, fmt.Sprintf("agent-%d", i), since)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.Less(t, delay, window)
+		if delay < window/2 {
+			early++
+		}
+		assert.Equal(t, delay-10*time.Second, drainDelay(state, window, fmt.Sprintf("agent-%d", i), since.Add(10*time.Second)), "the offset of an agent is stable")
+	}
+	assert.InDelta(t, agents/2, early, float64(agents)/10, "the agents are spread over the window")
+}
+
+func TestProcessPolicy(t *testing.T) {
+	pp, err := policy.NewParsedPolicy(context.Background(), nil, model.Policy{
+		PolicyID:    "policy-id",
+		RevisionIdx: 1,
+		Data: &model.PolicyData{
+			ID:      "policy-id",
+			Outputs: map[string]map[string]interface{}{"default": {"type": "logstash"}},
+			Inputs:  []map[string]interface{}{{"type": "logfile", "id": strings.Repeat("x", 256)}},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		maxSize    int
+		gzipPolicy bool
+		err        error
+	}{{
+		name: "plain policy",
+	}, {
+		name:       "gzip policy",
+		gzipPolicy: true,
+	}, {
+		name:    "policy too large",
+		maxSize: 128,
+		err:     ErrPolicyTooLarge,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mBulk := ftesting.NewMockBulk()
+			mBulk.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{
+				Hits: []es.HitT{{ID: "agent-id", Source: []byte(`{"active":true,"policy_id":"policy-id"}`)}},
+			}}, nil)
+			cfg := &config.Server{
+				CompressionLevel:  flate.BestSpeed,
+				CompressionThresh: 1,
+				PolicyMaxSize:     tc.maxSize,
+			}
+			ct := NewCheckinT(mustBuildConstraints("8.0.0"), cfg, nil, nil, nil, nil, nil, nil, mBulk, nil)
+
+			action, err := ct.processPolicy(context.Background(), testlog.SetLogger(t), "agent-id", pp, tc.gzipPolicy)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			change, err := action.Data.AsActionPolicyChange()
+			require.NoError(t, err)
+
+			var d PolicyData
+			if tc.gzipPolicy {
+				require.Nil(t, change.Policy)
+				require.NotNil(t, change.PolicyGzip)
+				zr, err := gzip.NewReader(bytes.NewReader(*change.PolicyGzip))
+				require.NoError(t, err)
+				require.NoError(t, json.NewDecoder(zr).Decode(&d))
+			} else {
+				require.Nil(t, change.PolicyGzip)
+				require.NotNil(t, change.Policy)
+				d = *change.Policy
+			}
+			require.NotNil(t, d.Id)
+			assert.Equal(t, "policy-id", *d.Id)
+			require.NotNil(t, d.Inputs)
+			assert.Len(t, *d.Inputs, 1)
+			mBulk.AssertExpectations(t)
+		})
+	}
+}
+
 func Benchmark_CheckinT_writeResponse(b *testing.B) {
 	verCon := mustBuildConstraints("8.0.0")
 	cfg := &config.Server{
 		CompressionLevel:  flate.BestSpeed,
 		CompressionThresh: 1,
 	}
-	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk())
+	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk(), nil)
 
 	logger := zerolog.Nop()
 	req := &http.Request{
@@ -682,7 +897,7 @@ func BenchmarkParallel_CheckinT_writeResponse(b *testing.B) {
 		CompressionLevel:  flate.BestSpeed,
 		CompressionThresh: 1,
 	}
-	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk())
+	ct := NewCheckinT(verCon, cfg, nil, nil, nil, nil, nil, nil, ftesting.NewMockBulk(), nil)
 
 	logger := zerolog.Nop()
 	req := &http.Request{
@@ -934,7 +1149,7 @@ func TestValidateCheckinRequest(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			checkin := NewCheckinT(verCon, tc.cfg, nil, nil, nil, nil, nil, nil, nil)
+			checkin := NewCheckinT(verCon, tc.cfg, nil, nil, nil, nil, nil, nil, nil, nil)
 			wr := httptest.NewRecorder()
 			logger := testlog.SetLogger(t)
 			valid, err := checkin.validateRequest(logger, wr, tc.req, time.Time{}, nil)
@@ -951,3 +1166,22 @@ func TestValidateCheckinRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAgentNotFound(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	bulker := ftesting.NewMockBulk()
+	bulker.On("ReadRaw", mock.Anything, dl.FleetAgents, "agent-id", mock.Anything).Return(&bulk.MgetResponseItem{}, es.ErrElasticNotFound).Once()
+	c := testcache.NewMockCache()
+	c.On("NotFound", cache.NotFoundAgent, "agent-id").Return(false).Once()
+	c.On("SetNotFound", cache.NotFoundAgent, "agent-id").Return().Once()
+
+	_, err := getAgent(ctx, bulker, c, "agent-id")
+	assert.ErrorIs(t, err, dl.ErrNotFound)
+
+	// the missing agent is not looked up again
+	c.On("NotFound", cache.NotFoundAgent, "agent-id").Return(true).Once()
+	_, err = getAgent(ctx, bulker, c, "agent-id")
+	assert.ErrorIs(t, err, dl.ErrNotFound)
+	bulker.AssertNumberOfCalls(t, "ReadRaw", 1)
+	c.AssertExpectations(t)
+}
@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
+)
+
+var ErrDiagnosticsForbidden = errors.New("api key is not allowed to collect diagnostics")
+
+type DiagnosticsT struct {
+	cfg        *config.Config
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	monitors   map[string]monitor.GlobalCheckpointProvider
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+// NewDiagnosticsT returns the handler of the diagnostics bundle, monitors are the index monitors whose checkpoints are reported by name.
+func NewDiagnosticsT(cfg *config.Config, bulker bulk.Bulk, c cache.Cache, monitors map[string]monitor.GlobalCheckpointProvider) *DiagnosticsT {
+	return &DiagnosticsT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		monitors:   monitors,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// diagnosticsInfo is the build information of the bundle.
+type diagnosticsInfo struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	BuildTime time.Time `json:"build_time"`
+	Collected time.Time `json:"collected"`
+}
+
+// handleDiagnostics writes a zip archive with the state of the server. The archive is built before it is written
+// so a failure is reported as an error response and not as a truncated archive.
+func (dt *DiagnosticsT) handleDiagnostics(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, bi build.Info) error {
+	key, err := dt.authAPIKey(r, dt.bulker, dt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+
+	ok, err := key.HasPrivileges(zlog.WithContext(r.Context()), dt.bulker.Client(), []string{dl.FleetServers}, []string{"all"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDiagnosticsForbidden
+	}
+	if len(dt.cfg.Inputs) > 0 {
+		if err := authorizeUnscoped(r.Context(), &dt.cfg.Inputs[0].Server, dt.bulker, key); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	var buf bytes.Buffer
+	if err := dt.writeBundle(&buf, bi, now); err != nil {
+		return fmt.Errorf("unable to collect diagnostics: %w", err)
+	}
+	zlog.Info().Int("size", buf.Len()).Msg("diagnostics collected")
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fleet-server-diagnostics-%s.zip"`, now.Format("20060102T150405Z")))
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (dt *DiagnosticsT) writeBundle(buf *bytes.Buffer, bi build.Info, now time.Time) error {
+	zw := zip.NewWriter(buf)
+
+	checkpoints := make(map[string]sqn.SeqNo, len(dt.monitors))
+	for name, m := range dt.monitors {
+		checkpoints[name] = m.GetCheckpoint()
+	}
+	files := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+	}{
+		{"info.json", jsonFile(diagnosticsInfo{Version: bi.Version, Commit: bi.Commit, BuildTime: bi.BuildTime, Collected: now})},
+		{"config.json", jsonFile(dt.cfg.Redact())},
+		{"stats.json", jsonFile(monitoring.CollectStructSnapshot(monitoring.Default, monitoring.Full, false))},
+		{"checkpoints.json", jsonFile(checkpoints)},
+		{"timeline.json", jsonFile(timeline.Events())},
+		{"logs.ndjson", func(b *bytes.Buffer) error {
+			for _, entry := range logger.RecentLogs() {
+				b.Write(entry)
+			}
+			return nil
+		}},
+		{"goroutine.pprof", profileFile("goroutine")},
+		{"heap.pprof", profileFile("heap")},
+	}
+	for _, f := range files {
+		var b bytes.Buffer
+		if err := f.write(&b); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func jsonFile(v interface{}) func(*bytes.Buffer) error {
+	return func(b *bytes.Buffer) error {
+		enc := json.NewEncoder(b)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+}
+
+func profileFile(name string) func(*bytes.Buffer) error {
+	return func(b *bytes.Buffer) error {
+		return pprof.Lookup(name).WriteTo(b, 0)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
+)
+
+type checkpointProvider sqn.SeqNo
+
+func (c checkpointProvider) GetCheckpoint() sqn.SeqNo { return sqn.SeqNo(c) }
+
+func TestHandleDiagnostics(t *testing.T) {
+	cfg := &config.Config{Inputs: []config.Input{{}}}
+	cfg.Inputs[0].Server.InitDefaults()
+	cfg.Output.Elasticsearch.ServiceToken = "secret-token"
+
+	for _, privileged := range []bool{true, false} {
+		t.Run(fmt.Sprintf("privileged %t", privileged), func(t *testing.T) {
+			es, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(es)
+
+			diag := NewDiagnosticsT(cfg, fakebulk, nil, map[string]monitor.GlobalCheckpointProvider{"actions": checkpointProvider{42}})
+			diag.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			timeline.Record(timeline.KindLeadership, "took policy leadership", map[string]string{"policy_id": "policy"})
+			router := newRouter(&cfg.Inputs[0].Server, &apiServer{diag: diag, bi: build.Info{Version: "8.15.0"}}, nil, nil, nil)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/diagnostics", nil))
+			if !privileged {
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+				return
+			}
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+			zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+			require.NoError(t, err)
+			files := make(map[string][]byte)
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				require.NoError(t, err)
+				files[f.Name], err = io.ReadAll(rc)
+				require.NoError(t, err)
+				rc.Close()
+			}
+			for _, name := range []string{"info.json", "config.json", "stats.json", "checkpoints.json", "timeline.json", "logs.ndjson", "goroutine.pprof", "heap.pprof"} {
+				assert.Contains(t, files, name)
+			}
+			assert.NotContains(t, string(files["config.json"]), "secret-token", "the configuration is redacted")
+			assert.NotEmpty(t, files["goroutine.pprof"])
+
+			var checkpoints map[string][]int64
+			require.NoError(t, json.Unmarshal(files["checkpoints.json"], &checkpoints))
+			assert.Equal(t, map[string][]int64{"actions": {42}}, checkpoints)
+
+			var events []timeline.Event
+			require.NoError(t, json.Unmarshal(files["timeline.json"], &events))
+			require.NotEmpty(t, events)
+			assert.Equal(t, "took policy leadership", events[len(events)-1].Message)
+
+			var info diagnosticsInfo
+			require.NoError(t, json.Unmarshal(files["info.json"], &info))
+			assert.Equal(t, "8.15.0", info.Version)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+)
+
+var (
+	ErrDrainForbidden   = errors.New("api key is not allowed to drain the server")
+	ErrDrainUnavailable = errors.New("the server has no drain mode")
+)
+
+type DrainT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewDrainT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache) *DrainT {
+	return &DrainT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handleDrain enters the drain mode of the server, the server of the request stops at the end of the drain period.
+// Draining a server that already drains is not an error, the response is the state of the running drain.
+func (dt *DrainT) handleDrain(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := dt.authAPIKey(r, dt.bulker, dt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+
+	ok, err := key.HasPrivileges(zlog.WithContext(r.Context()), dt.bulker.Client(), []string{dl.FleetServers}, []string{"all"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDrainForbidden
+	}
+	if err := authorizeUnscoped(r.Context(), dt.cfg, dt.bulker, key); err != nil {
+		return err
+	}
+
+	state := drain.FromContext(r.Context())
+	if state == nil {
+		return ErrDrainUnavailable
+	}
+	if state.Start(drain.ReasonAPI) {
+		zlog.Info().Dur("period", dt.cfg.Drain.Period).Msg("drain requested")
+	}
+	since, _ := state.Since()
+
+	out, err := json.Marshal(DrainAPIResponse{
+		StartedAt: since,
+		StopsAt:   since.Add(dt.cfg.Drain.Period),
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandleDrain(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+
+	for _, privileged := range []bool{true, false} {
+		t.Run(fmt.Sprintf("privileged %t", privileged), func(t *testing.T) {
+			es, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(es)
+
+			dt := NewDrainT(cfg, fakebulk, nil)
+			dt.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			router := newRouter(cfg, &apiServer{drain: dt}, nil, nil, nil)
+			state := drain.New()
+			ctx := drain.WithContext(context.Background(), state)
+
+			var first DrainAPIResponse
+			for i := 0; i < 2; i++ {
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/drain", nil).WithContext(ctx))
+				if !privileged {
+					assert.Equal(t, http.StatusForbidden, rec.Code)
+					assert.False(t, state.Draining())
+					return
+				}
+				require.Equal(t, http.StatusAccepted, rec.Code)
+				assert.True(t, state.Draining())
+
+				var resp DrainAPIResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, cfg.Drain.Period, resp.StopsAt.Sub(resp.StartedAt))
+				if i == 0 {
+					first = resp
+				} else {
+					assert.True(t, first.StartedAt.Equal(resp.StartedAt), "draining again keeps the running drain")
+				}
+			}
+			_, reason := state.Since()
+			assert.Equal(t, drain.ReasonAPI, reason)
+		})
+	}
+}
@@ -6,6 +6,7 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,13 +17,16 @@ import (
 
 	"github.com/elastic/elastic-agent-libs/str"
 	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
 	"github.com/elastic/fleet-server/v7/internal/pkg/rollback"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
 	"go.elastic.co/apm/v2"
 
@@ -38,6 +42,9 @@ const (
 	EnrollEphemeral = "EPHEMERAL"
 	EnrollPermanent = "PERMANENT"
 	EnrollTemporary = "TEMPORARY"
+
+	// unenrolledReasonReplaced is the unenrolled reason of an agent replaced by an agent with its enrollment ID.
+	unenrolledReasonReplaced = "replaced"
 )
 
 const kFleetAccessRolesJSON = `
@@ -60,18 +67,40 @@ var (
 )
 
 type EnrollerT struct {
-	verCon version.Constraints
-	cfg    *config.Server
-	bulker bulk.Bulk
-	cache  cache.Cache
+	verCon   version.Constraints
+	cfg      *config.Server
+	bulker   bulk.Bulk
+	cache    cache.Cache
+	policies *policy.FileSource
+
+	policyLimits policyLimiter
+	versions     *agentVersionRules
+	// signingSecret derives the signing keys of the agents when the request signatures are verified.
+	signingSecret []byte
 }
 
-func NewEnrollerT(verCon version.Constraints, cfg *config.Server, bulker bulk.Bulk, c cache.Cache) (*EnrollerT, error) {
+func NewEnrollerT(verCon version.Constraints, cfg *config.Server, bulker bulk.Bulk, c cache.Cache, policies *policy.FileSource) (*EnrollerT, error) {
+	var signingSecret []byte
+	if cfg.RequestSigning.Enabled {
+		var err error
+		if signingSecret, err = cfg.RequestSigning.DecodeSecret(); err != nil {
+			return nil, err
+		}
+	}
+	versions, err := newAgentVersionRules(&cfg.AgentVersions)
+	if err != nil {
+		return nil, err
+	}
 	return &EnrollerT{
-		verCon: verCon,
-		cfg:    cfg,
-		bulker: bulker,
-		cache:  c,
+		verCon:   verCon,
+		cfg:      cfg,
+		bulker:   bulker,
+		cache:    c,
+		policies: policies,
+
+		policyLimits:  newPolicyLimiter(&cfg.Limits, func(p *config.PolicyLimits) *config.Limit { return p.EnrollLimit }),
+		versions:      versions,
+		signingSecret: signingSecret,
 	}, nil
 }
 
@@ -115,14 +144,23 @@ func (et *EnrollerT) processRequest(zlog zerolog.Logger, w http.ResponseWriter,
 		zlog.Debug().Msgf("Found enrollment key %s", key.APIKeyID)
 		enrollAPI = key
 	}
-	body := r.Body
-
-	// Limit the size of the body to prevent malicious agent from exhausting RAM in server
-	if et.cfg.Limits.EnrollLimit.MaxBody > 0 {
-		body = http.MaxBytesReader(w, body, et.cfg.Limits.EnrollLimit.MaxBody)
+	if err := et.checkPolicyNamespaces(r.Context(), enrollAPI); err != nil {
+		return nil, err
 	}
-
-	readCounter := datacounter.NewReaderCounter(body)
+	if err := et.versions.check(zlog, ver, enrollAPI.PolicyID); err != nil {
+		return nil, err
+	}
+	release, err := et.policyLimits.acquire(w, r, enrollAPI.PolicyID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	clientCert, err := et.clientCertificate(r)
+	if err != nil {
+		return nil, err
+	}
+	// the size of the body is limited by the limiter of the route
+	readCounter := datacounter.NewReaderCounter(r.Body)
 
 	// Parse the request body
 	req, err := validateRequest(r.Context(), readCounter)
@@ -132,7 +170,20 @@ func (et *EnrollerT) processRequest(zlog zerolog.Logger, w http.ResponseWriter,
 
 	cntEnroll.bodyIn.Add(readCounter.Count())
 
-	return et._enroll(r.Context(), rb, zlog, req, enrollAPI.PolicyID, enrollAPI.Namespaces, ver)
+	return et._enroll(r.Context(), rb, zlog, req, enrollAPI.PolicyID, enrollAPI.Namespaces, ver, clientCert)
+}
+
+// clientCertificate returns the identity of the client certificate the agent is bound to, empty when the agents
+// are not bound to their certificate.
+func (et *EnrollerT) clientCertificate(r *http.Request) (string, error) {
+	if !et.cfg.ClientCertificates.Enabled {
+		return "", nil
+	}
+	cert := requestCertificate(r)
+	if cert == nil {
+		return "", ErrClientCertificateRequired
+	}
+	return clientCertificateIdentity(et.cfg.ClientCertificates.Identity, cert), nil
 }
 
 // retrieveStaticTokenEnrollmentToken fetches the enrollment key record from the config static tokens.
@@ -157,9 +208,10 @@ func (et *EnrollerT) retrieveStaticTokenEnrollmentToken(ctx context.Context, zlo
 		}
 
 		return &model.EnrollmentAPIKey{
-			PolicyID: p.PolicyID,
-			APIKey:   pt.TokenKey,
-			Active:   true,
+			PolicyID:   p.PolicyID,
+			APIKey:     pt.TokenKey,
+			Active:     true,
+			Namespaces: p.Namespaces,
 		}, nil
 
 	}
@@ -167,8 +219,31 @@ func (et *EnrollerT) retrieveStaticTokenEnrollmentToken(ctx context.Context, zlo
 	return nil, nil
 }
 
+// checkPolicyNamespaces ensures that the policy of the enrollment key is in the namespaces of the key when the namespaces
+// of the API keys are enabled, an agent can not be enrolled in a policy of another namespace.
+func (et *EnrollerT) checkPolicyNamespaces(ctx context.Context, enrollAPI *model.EnrollmentAPIKey) error {
+	if !et.cfg.Tenancy.Enabled {
+		return nil
+	}
+	span, ctx := apm.StartSpan(ctx, "checkPolicyNamespaces", "validate")
+	defer span.End()
+	p, err := et.fetchPolicy(ctx, enrollAPI.PolicyID)
+	if err != nil {
+		return err
+	}
+	if !dl.InNamespaces(p.Namespaces, enrollAPI.Namespaces) {
+		return ErrNamespaceForbidden
+	}
+	return nil
+}
+
 func (et *EnrollerT) fetchPolicy(ctx context.Context, policyID string) (model.Policy, error) {
-	policies, err := dl.QueryLatestPolicies(ctx, et.bulker)
+	queryPolicies := dl.QueryLatestPolicies
+	if et.policies != nil {
+		// policies are served from files rather than the policies index
+		queryPolicies = et.policies.QueryLatestPolicies
+	}
+	policies, err := queryPolicies(ctx, et.bulker)
 	if err != nil {
 		return model.Policy{}, err
 	}
@@ -193,6 +268,7 @@ func (et *EnrollerT) _enroll(
 	policyID string,
 	namespaces []string,
 	ver string,
+	clientCert string,
 ) (*EnrollResponse, error) {
 	var agent model.Agent
 	var enrollmentID string
@@ -214,6 +290,15 @@ func (et *EnrollerT) _enroll(
 				return nil, err
 			}
 		}
+		// the agent of another namespace with the same enrollment ID is left untouched
+		if agent.Id != "" && !dl.InNamespaces(agent.Namespaces, namespaces) {
+			zlog.Warn().
+				Str("EnrollmentId", enrollmentID).
+				Str("AgentId", agent.Id).
+				Strs("agent.namespaces", agent.Namespaces).
+				Msg("Agent with EnrollmentId is in another namespace")
+			agent = model.Agent{}
+		}
 		vSpan.End()
 	}
 	now := time.Now()
@@ -225,30 +310,42 @@ func (et *EnrollerT) _enroll(
 	}
 
 	agentID := u.String()
+
+	// Agents that enroll with a service token are bound to its identity instead of an access API key
+	var serviceToken string
+	if req.ServiceToken != nil {
+		serviceToken, err = et.authServiceToken(ctx, zlog, *req.ServiceToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// only delete existing agent if it never checked in
 	if agent.Id != "" && agent.LastCheckin == "" {
 		zlog.Debug().
 			Str("EnrollmentId", enrollmentID).
 			Str("AgentId", agent.Id).
 			Str("APIKeyID", agent.AccessAPIKeyID).
-			Msg("Invalidate old api key and remove existing agent with the same enrollment_id")
-		// invalidate previous api key
-		err := invalidateAPIKey(ctx, zlog, et.bulker, agent.AccessAPIKeyID)
-		if err != nil {
-			zlog.Error().Err(err).
-				Str("EnrollmentId", enrollmentID).
-				Str("AgentId", agent.Id).
-				Str("APIKeyID", agent.AccessAPIKeyID).
-				Msg("Error when trying to invalidate API key of old agent with enrollment id")
-			return nil, err
+			Msg("Invalidate old api key and tombstone existing agent with the same enrollment_id")
+		// invalidate previous api key, agents enrolled with a service token have none
+		if agent.AccessAPIKeyID != "" {
+			err := invalidateAPIKey(ctx, zlog, et.bulker, agent.Id, audit.ReasonReEnroll, agent.AccessAPIKeyID)
+			if err != nil {
+				zlog.Error().Err(err).
+					Str("EnrollmentId", enrollmentID).
+					Str("AgentId", agent.Id).
+					Str("APIKeyID", agent.AccessAPIKeyID).
+					Msg("Error when trying to invalidate API key of old agent with enrollment id")
+				return nil, err
+			}
 		}
-		// delete existing agent to recreate with new api key
-		err = deleteAgent(ctx, zlog, et.bulker, agent.Id)
+		// tombstone existing agent to recreate with new api key, its enrollment is kept until the tombstone retention
+		err := dl.TombstoneAgent(ctx, et.bulker, &agent, unenrolledReasonReplaced)
 		if err != nil {
 			zlog.Error().Err(err).
 				Str("EnrollmentId", enrollmentID).
 				Str("AgentId", agent.Id).
-				Msg("Error when trying to delete old agent with enrollment id")
+				Msg("Error when trying to tombstone old agent with enrollment id")
 			return nil, err
 		}
 	}
@@ -260,25 +357,34 @@ func (et *EnrollerT) _enroll(
 	}
 
 	// Generate the Fleet Agent access api key
-	accessAPIKey, err := generateAccessAPIKey(ctx, et.bulker, agentID)
-	if err != nil {
-		return nil, err
-	}
+	var accessAPIKey *apikey.APIKey
+	if serviceToken == "" {
+		accessAPIKey, err = generateAccessAPIKey(ctx, et.bulker, agentID)
+		keyEvent := audit.APIKeyEvent{Action: audit.ActionAPIKeyCreate, Reason: audit.ReasonEnroll, AgentID: agentID}
+		if accessAPIKey != nil {
+			keyEvent.IDs = []string{accessAPIKey.ID}
+		}
+		audit.APIKey(zlog, keyEvent, err)
+		if err != nil {
+			return nil, err
+		}
 
-	// Register invalidate API key function for enrollment error rollback
-	rb.Register("invalidate API key", func(ctx context.Context) error {
-		return invalidateAPIKey(ctx, zlog, et.bulker, accessAPIKey.ID)
-	})
+		// Register invalidate API key function for enrollment error rollback
+		rb.Register("invalidate API key", func(ctx context.Context) error {
+			return invalidateAPIKey(ctx, zlog, et.bulker, agentID, audit.ReasonEnrollRollback, accessAPIKey.ID)
+		})
+	}
 
 	agentData := model.Agent{
-		Active:         true,
-		PolicyID:       policyID,
-		Namespaces:     namespaces,
-		Type:           string(req.Type),
-		EnrolledAt:     now.UTC().Format(time.RFC3339),
-		LocalMetadata:  localMeta,
-		AccessAPIKeyID: accessAPIKey.ID,
-		ActionSeqNo:    []int64{sqn.UndefinedSeqNo},
+		Active:             true,
+		PolicyID:           policyID,
+		Namespaces:         namespaces,
+		Type:               string(req.Type),
+		EnrolledAt:         now.UTC().Format(time.RFC3339),
+		LocalMetadata:      localMeta,
+		AccessServiceToken: serviceToken,
+		ClientCertificate:  clientCert,
+		ActionSeqNo:        []int64{sqn.UndefinedSeqNo},
 		Agent: &model.AgentMetadata{
 			ID:      agentID,
 			Version: ver,
@@ -286,11 +392,19 @@ func (et *EnrollerT) _enroll(
 		Tags:         removeDuplicateStr(req.Metadata.Tags),
 		EnrollmentID: enrollmentID,
 	}
+	if accessAPIKey != nil {
+		agentData.AccessAPIKeyID = accessAPIKey.ID
+	}
 
 	err = createFleetAgent(ctx, et.bulker, agentID, agentData)
 	if err != nil {
 		return nil, err
 	}
+	// The agent may have been looked up before it enrolled.
+	et.cache.ClearNotFound(cache.NotFoundAgent, agentID)
+	if accessAPIKey != nil {
+		et.cache.ClearNotFound(cache.NotFoundAgentAPIKey, accessAPIKey.ID)
+	}
 
 	// Register delete fleet agent for enrollment error rollback
 	rb.Register("delete agent", func(ctx context.Context) error {
@@ -300,7 +414,6 @@ func (et *EnrollerT) _enroll(
 	resp := EnrollResponse{
 		Action: "created",
 		Item: EnrollResponseItem{
-			AccessApiKey:         accessAPIKey.Token(),
 			AccessApiKeyId:       agentData.AccessAPIKeyID,
 			Active:               agentData.Active,
 			EnrolledAt:           agentData.EnrolledAt,
@@ -314,12 +427,39 @@ func (et *EnrollerT) _enroll(
 		},
 	}
 
-	// We are Kool & and the Gang; cache the access key to avoid the roundtrip on impending checkin
-	et.cache.SetAPIKey(*accessAPIKey, true)
+	if accessAPIKey != nil {
+		resp.Item.AccessApiKey = accessAPIKey.Token()
+		// We are Kool & and the Gang; cache the access key to avoid the roundtrip on impending checkin
+		et.cache.SetAPIKey(*accessAPIKey, true)
+	}
+	if et.signingSecret != nil {
+		key := base64.StdEncoding.EncodeToString(signing.Key(et.signingSecret, agentID))
+		resp.Item.SigningKey = &key
+	}
 
 	return &resp, nil
 }
 
+// authServiceToken validates the service token of an enroll request.
+// It returns the identity of the token the agent is bound to, "<service account>/<token name>".
+func (et *EnrollerT) authServiceToken(ctx context.Context, zlog zerolog.Logger, token string) (string, error) {
+	if !et.cfg.ServiceTokenAuth.Enabled {
+		return "", ErrServiceTokenAuthDisabled
+	}
+	identity, err := authServiceToken(ctx, et.bulker, et.cache, token)
+	if err != nil {
+		return "", err
+	}
+	if !et.cfg.ServiceTokenAuth.Allowed(identity) {
+		zlog.Warn().
+			Err(ErrServiceTokenNotAllowed).
+			Str(LogServiceToken, identity).
+			Msg("service token is not allowed to enroll")
+		return "", ErrServiceTokenNotAllowed
+	}
+	return identity, nil
+}
+
 // Helper function to remove duplicate agent tags.
 // Note that this implementation will also sort the tags alphabetically.
 func removeDuplicateStr(strSlice []string) []string {
@@ -340,7 +480,7 @@ func deleteAgent(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, age
 	return nil
 }
 
-func invalidateAPIKey(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, apikeyID string) error {
+func invalidateAPIKey(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, agentID, reason, apikeyID string) error {
 	// hack-a-rama:  We purposely do not force a "refresh:true" on the Apikey creation
 	// because doing so causes the api call to slow down at scale. It is already very slow.
 	// So we have to wait for the key to become visible until we can invalidate it.
@@ -376,7 +516,9 @@ LOOP:
 		}
 	}
 
-	if err := bulker.APIKeyInvalidate(ctx, apikeyID); err != nil {
+	err := bulker.APIKeyInvalidate(ctx, apikeyID)
+	audit.APIKey(zlog, audit.APIKeyEvent{Action: audit.ActionAPIKeyInvalidate, Reason: reason, AgentID: agentID, IDs: []string{apikeyID}}, err)
+	if err != nil {
 		zlog.Error().Err(err).Msg("fail invalidate apiKey")
 		return err
 	}
@@ -401,6 +543,9 @@ func writeResponse(ctx context.Context, zlog zerolog.Logger, w http.ResponseWrit
 		return fmt.Errorf("fail send enroll response: %w", err)
 	}
 
+	e := audit.FromContext(ctx)
+	e.AgentID, e.PolicyID = resp.Item.Id, resp.Item.PolicyId
+
 	zlog.Info().
 		Str(LogAgentID, resp.Item.Id).
 		Str(LogPolicyID, resp.Item.PolicyId).
@@ -501,9 +646,15 @@ func (et *EnrollerT) fetchEnrollmentKeyRecord(ctx context.Context, id string) (*
 	if key, ok := et.cache.GetEnrollmentAPIKey(id); ok {
 		return &key, nil
 	}
+	if et.cache.NotFound(cache.NotFoundEnrollKey, id) {
+		return nil, fmt.Errorf("FindEnrollmentAPIKey: %w", dl.ErrNotFound)
+	}
 
 	// Pull API key record from .fleet-enrollment-api-keys
 	rec, err := dl.FindEnrollmentAPIKey(ctx, et.bulker, dl.QueryEnrollmentAPIKeyByID, dl.FieldAPIKeyID, id)
+	if errors.Is(err, dl.ErrNotFound) {
+		et.cache.SetNotFound(cache.NotFoundEnrollKey, id)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("FindEnrollmentAPIKey: %w", err)
 	}
@@ -7,7 +7,9 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"reflect"
 	"strings"
@@ -20,10 +22,12 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/rollback"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
 	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRemoveDuplicateStr(t *testing.T) {
@@ -76,7 +80,7 @@ func TestEnroll(t *testing.T) {
 	cfg := &config.Server{}
 	c, _ := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
 	bulker := ftesting.NewMockBulk()
-	et, _ := NewEnrollerT(verCon, cfg, bulker, c)
+	et, _ := NewEnrollerT(verCon, cfg, bulker, c, nil)
 
 	bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&es.ResultT{
 		HitsT: es.HitsT{
@@ -90,42 +94,139 @@ func TestEnroll(t *testing.T) {
 		}, nil)
 	bulker.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
 		"", nil)
-	resp, _ := et._enroll(ctx, rb, zlog, req, "1234", []string{}, "8.9.0")
+	resp, _ := et._enroll(ctx, rb, zlog, req, "1234", []string{}, "8.9.0", "")
 
 	if resp.Action != "created" {
 		t.Fatal("enroll failed")
 	}
+	assert.Nil(t, resp.Item.SigningKey, "no signing key without the request signing")
+
+	secret := bytes.Repeat([]byte("s"), 32)
+	cfg.RequestSigning = config.RequestSigning{Enabled: true, Secret: base64.StdEncoding.EncodeToString(secret)}
+	et, err := NewEnrollerT(verCon, cfg, bulker, c, nil)
+	require.NoError(t, err)
+	resp, err = et._enroll(ctx, rb, zlog, req, "1234", []string{}, "8.9.0", "")
+	require.NoError(t, err)
+	require.NotNil(t, resp.Item.SigningKey)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(signing.Key(secret, resp.Item.Id)), *resp.Item.SigningKey)
 }
 
-func TestEnrollerT_retrieveStaticTokenEnrollmentToken(t *testing.T) {
-	bulkerBuilder := func(policies ...model.Policy) func() bulk.Bulk {
-		return func() bulk.Bulk {
+func TestEnrollServiceToken(t *testing.T) {
+	serviceInfo := &apikey.SecurityInfo{
+		UserName: "elastic/fleet-server",
+		Token:    &apikey.TokenInfo{Name: "agents", Type: "_service_account_index"},
+	}
+	tests := []struct {
+		name string
+		cfg  config.ServiceTokenAuth
+		err  error
+	}{
+		{name: "disabled", err: ErrServiceTokenAuthDisabled},
+		{name: "any service account", cfg: config.ServiceTokenAuth{Enabled: true}},
+		{name: "allowed service account", cfg: config.ServiceTokenAuth{Enabled: true, Principals: []string{"elastic/fleet-server"}}},
+		{name: "allowed token", cfg: config.ServiceTokenAuth{Enabled: true, Principals: []string{"elastic/fleet-server/agents"}}},
+		{name: "principal not allowed", cfg: config.ServiceTokenAuth{Enabled: true, Principals: []string{"elastic/fleet-server/other"}}, err: ErrServiceTokenNotAllowed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token := "token"
+			req := &EnrollRequest{
+				Type:         "PERMANENT",
+				ServiceToken: &token,
+				Metadata: EnrollMetadata{
+					UserProvided: []byte("{}"),
+					Local:        []byte("{}"),
+				},
+			}
+			c, _ := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
 			bulker := ftesting.NewMockBulk()
+			bulker.On("ServiceTokenAuth", mock.Anything, token).Return(serviceInfo, nil)
+			var agent model.Agent
+			bulker.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil).Run(func(args mock.Arguments) {
+				assert.NoError(t, json.Unmarshal(args.Get(3).([]byte), &agent))
+			})
+			et, _ := NewEnrollerT(mustBuildConstraints("8.9.0"), &config.Server{ServiceTokenAuth: tc.cfg}, bulker, c, nil)
 
-			hits := []es.HitT{}
-			for _, p := range policies {
-				b, _ := json.Marshal(p)
-				hits = append(hits, es.HitT{
-					Source: b,
-				})
+			resp, err := et._enroll(context.Background(), &rollback.Rollback{}, zerolog.Nop(), req, "1234", []string{}, "8.9.0", "")
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				bulker.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
 			}
-			res := &es.ResultT{
-				HitsT: es.HitsT{},
-				Aggregations: map[string]es.Aggregation{
-					"policy_id": {
-						Buckets: []es.Bucket{
-							{
-								Aggregations: map[string]es.HitsT{
-									"revision_idx": {
-										Hits: hits,
-									},
-								},
+			assert.NoError(t, err)
+			assert.Empty(t, resp.Item.AccessApiKey)
+			assert.Empty(t, resp.Item.AccessApiKeyId)
+			assert.Equal(t, "elastic/fleet-server/agents", agent.AccessServiceToken)
+			bulker.AssertNotCalled(t, "APIKeyCreate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// latestPoliciesResult returns the result of the search of the latest policies.
+func latestPoliciesResult(policies ...model.Policy) *es.ResultT {
+	hits := []es.HitT{}
+	for _, p := range policies {
+		b, _ := json.Marshal(p)
+		hits = append(hits, es.HitT{
+			Source: b,
+		})
+	}
+	return &es.ResultT{
+		HitsT: es.HitsT{},
+		Aggregations: map[string]es.Aggregation{
+			"policy_id": {
+				Buckets: []es.Bucket{
+					{
+						Aggregations: map[string]es.HitsT{
+							"revision_idx": {
+								Hits: hits,
 							},
 						},
 					},
 				},
+			},
+		},
+	}
+}
+
+func TestEnrollerT_checkPolicyNamespaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		namespaces []string
+		err        error
+	}{
+		{name: "disabled", namespaces: []string{"other"}},
+		{name: "in the namespaces of the key", enabled: true, namespaces: []string{"other", "space"}},
+		{name: "not in the namespaces of the key", enabled: true, namespaces: []string{"other"}, err: ErrNamespaceForbidden},
+		{name: "key in the default namespace", enabled: true, err: ErrNamespaceForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(latestPoliciesResult(model.Policy{
+				PolicyID:   "policy",
+				Namespaces: []string{"space"},
+			}), nil)
+			et := &EnrollerT{
+				cfg:    &config.Server{Tenancy: config.Tenancy{Enabled: tc.enabled}},
+				bulker: bulker,
 			}
-			bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(res, nil)
+			err := et.checkPolicyNamespaces(context.Background(), &model.EnrollmentAPIKey{PolicyID: "policy", Namespaces: tc.namespaces})
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEnrollerT_retrieveStaticTokenEnrollmentToken(t *testing.T) {
+	bulkerBuilder := func(policies ...model.Policy) func() bulk.Bulk {
+		return func() bulk.Bulk {
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(latestPoliciesResult(policies...), nil)
 			return bulker
 		}
 	}
@@ -170,7 +271,8 @@ func TestEnrollerT_retrieveStaticTokenEnrollmentToken(t *testing.T) {
 					},
 				},
 				bulker: bulkerBuilder(model.Policy{
-					PolicyID: "dummy-policy",
+					PolicyID:   "dummy-policy",
+					Namespaces: []string{"space"},
 				}),
 			},
 			args: args{
@@ -179,9 +281,10 @@ func TestEnrollerT_retrieveStaticTokenEnrollmentToken(t *testing.T) {
 				},
 			},
 			want: &model.EnrollmentAPIKey{
-				APIKey:   "abcdefg",
-				Active:   true,
-				PolicyID: "dummy-policy",
+				APIKey:     "abcdefg",
+				Active:     true,
+				PolicyID:   "dummy-policy",
+				Namespaces: []string{"space"},
 			},
 			wantErr: false,
 		},
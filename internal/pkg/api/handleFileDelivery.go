@@ -7,24 +7,29 @@ package api
 import (
 	"context"
 	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file/delivery"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 )
 
 type FileDeliveryT struct {
+	cfg         *config.Server
 	bulker      bulk.Bulk
 	cache       cache.Cache
 	chunkClient *elasticsearch.Client
 	deliverer   *delivery.Deliverer
-	authAgent   func(*http.Request, *string, bulk.Bulk, cache.Cache) (*model.Agent, error) // injectable for testing purposes
+	authAgent   func(*http.Request, *string, *config.Server, bulk.Bulk, cache.Cache) (*model.Agent, error) // injectable for testing purposes
 }
 
 func NewFileDeliveryT(cfg *config.Server, bulker bulk.Bulk, chunkClient *elasticsearch.Client, cache cache.Cache) *FileDeliveryT {
@@ -34,6 +39,7 @@ func NewFileDeliveryT(cfg *config.Server, bulker bulk.Bulk, chunkClient *elastic
 		Msg("upload limits")
 
 	return &FileDeliveryT{
+		cfg:         cfg,
 		chunkClient: chunkClient,
 		bulker:      bulker,
 		cache:       cache,
@@ -43,16 +49,28 @@ func NewFileDeliveryT(cfg *config.Server, bulker bulk.Bulk, chunkClient *elastic
 }
 
 func (ft *FileDeliveryT) handleSendFile(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, fileID string) error {
-	agent, err := ft.authAgent(r, nil, ft.bulker, ft.cache)
+	agent, err := ft.authAgent(r, nil, ft.cfg, ft.bulker, ft.cache)
 	if err != nil {
 		return err
 	}
 
-	// find file
+	// the wrapped writer counts the bytes written and keeps the http.Flusher and io.ReaderFrom of w
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	err = ft.sendFile(zlog, ww, r, fileID, agent)
+	auditFileDelivery(zlog, fileID, agent.Agent.ID, int64(ww.BytesWritten()), err)
+	return err
+}
+
+func (ft *FileDeliveryT) sendFile(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, fileID string, agent *model.Agent) error {
+	// find file, only files targeted at this agent are returned
 	info, err := ft.deliverer.FindFileForAgent(r.Context(), fileID, agent.Agent.ID)
 	if err != nil {
 		return err
 	}
+	// files staged for other namespaces are treated as missing
+	if !dl.InNamespaces(info.Namespaces, agent.Namespaces) {
+		return delivery.ErrNoFile
+	}
 
 	chunks, err := ft.deliverer.LocateChunks(r.Context(), zlog, fileID)
 	if errors.Is(err, delivery.ErrNoFile) {
@@ -71,6 +89,10 @@ func (ft *FileDeliveryT) handleSendFile(zlog zerolog.Logger, w http.ResponseWrit
 		w.Header().Set("Content-Type", info.File.MimeType)
 	}
 
+	if info.File.Name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.File.Name}))
+	}
+
 	if info.File.Size > 0 {
 		w.Header().Set("Content-Length", strconv.FormatInt(info.File.Size, 10))
 	}
@@ -82,3 +104,19 @@ func (ft *FileDeliveryT) handleSendFile(zlog zerolog.Logger, w http.ResponseWrit
 	// stream the chunks out
 	return ft.deliverer.SendFile(r.Context(), zlog, w, chunks, fileID)
 }
+
+// auditFileDelivery records the outcome of every delivery attempt made by an authenticated agent.
+func auditFileDelivery(zlog zerolog.Logger, fileID, agentID string, n int64, err error) {
+	e := zlog.Info()
+	outcome := "success"
+	if err != nil {
+		e = zlog.Warn().Err(err)
+		outcome = "failure"
+	}
+	e.Str(logger.ECSEventAction, "file-delivery").
+		Str(logger.ECSEventOutcome, outcome).
+		Str(logger.AgentID, agentID).
+		Str("fileID", fileID).
+		Int64(logger.ECSHTTPResponseBodyBytes, n).
+		Msg("file delivery")
+}
@@ -7,7 +7,9 @@
 package api
 
 import (
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -21,10 +23,12 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file/delivery"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 
 	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -183,6 +187,52 @@ func TestFileDelivery(t *testing.T) {
 	assert.Equal(t, []byte{0xAB, 0xCD}, rec.Body.Bytes())
 }
 
+func TestFileDeliveryAuditsBytesWritten(t *testing.T) {
+	_, si, tx, bulk := prepareFileDeliveryMock(t)
+	rec := httptest.NewRecorder()
+
+	bulk.On("Search", mock.Anything, isFileMetaSearch, mock.Anything, mock.Anything, mock.Anything).Return(
+		&es.ResultT{
+			HitsT: es.HitsT{
+				Hits: []es.HitT{{
+					ID:     "X",
+					Index:  fmt.Sprintf(delivery.FileHeaderIndexPattern, "endpoint"),
+					Source: []byte(`{"file": {"Status": "READY", "name": "somefile", "Meta": {"target_agents": ["someagent"]}, "size": 2}}`),
+				}},
+			},
+		}, nil,
+	)
+	bulk.On("Search", mock.Anything, isFileChunkSearch, mock.Anything, mock.Anything, mock.Anything).Return(
+		&es.ResultT{
+			HitsT: es.HitsT{
+				Hits: []es.HitT{{
+					ID:    "X.0",
+					Index: fmt.Sprintf(delivery.FileDataIndexPattern, "endpoint"),
+					Fields: map[string]interface{}{
+						file.FieldBaseID: []interface{}{"X"},
+						file.FieldLast:   []interface{}{true},
+					},
+				}},
+			},
+		}, nil,
+	)
+	tx.Response = sendBodyBytes(hexDecode("A7665F696E64657878212E666C6565742D66696C6564656C69766572792D646174612D656E64706F696E74635F69646578797A2E30685F76657273696F6E01675F7365715F6E6F016D5F7072696D6172795F7465726D0165666F756E64F5666669656C6473A164646174618142ABCD"))
+
+	var buf bytes.Buffer
+	err := si.ft.handleSendFile(zerolog.New(&buf), rec, httptest.NewRequest(http.MethodGet, "/api/fleet/file/X", nil), "X")
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte{0xAB, 0xCD}, rec.Body.Bytes())
+	var entry map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for entry[logger.ECSEventAction] != "file-delivery" {
+		entry = nil
+		require.NoError(t, dec.Decode(&entry))
+	}
+	assert.Equal(t, "success", entry[logger.ECSEventOutcome])
+	assert.EqualValues(t, 2, entry[logger.ECSHTTPResponseBodyBytes])
+}
+
 func TestFileDeliveryMultipleChunks(t *testing.T) {
 	hr, _, tx, bulk := prepareFileDeliveryMock(t)
 	rec := httptest.NewRecorder()
@@ -317,9 +367,52 @@ func TestFileDeliverySetsHeaders(t *testing.T) {
 	require.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
 	assert.Equal(t, "4", rec.Header().Get("Content-Length"))
+	assert.Equal(t, `attachment; filename=test.csv`, rec.Header().Get("Content-Disposition"))
 	assert.Empty(t, rec.Header().Get("X-File-SHA2"))
 }
 
+func TestFileDeliveryRejectsOtherNamespace(t *testing.T) {
+	hr, _, tx, bulk := prepareFileDeliveryMock(t)
+	rec := httptest.NewRecorder()
+
+	bulk.On("Search", mock.Anything, isFileMetaSearch, mock.Anything, mock.Anything, mock.Anything).Return(
+		&es.ResultT{
+			HitsT: es.HitsT{
+				Hits: []es.HitT{{
+					ID:      "X",
+					SeqNo:   1,
+					Version: 1,
+					Index:   fmt.Sprintf(delivery.FileHeaderIndexPattern, "endpoint"),
+					Source: []byte(`{
+						"file": {
+							"created": "2023-06-05T15:23:37.499Z",
+							"Status": "READY",
+							"Updated": "2023-06-05T15:23:37.499Z",
+							"name": "test.csv",
+							"mime_type": "text/csv",
+							"Meta": {
+								"target_agents": ["someagent"],
+								"action_id": ""
+							},
+							"size": 4
+						},
+						"namespaces": ["space1"]
+					}`),
+				}},
+			},
+		}, nil,
+	)
+	tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+		t.Fatal("chunks of a file in another namespace should not be read")
+		return nil, nil
+	}
+
+	hr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/file/X", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	bulk.AssertNotCalled(t, "Search", mock.Anything, isFileChunkSearch, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestFileDeliverySetsHashWhenPresent(t *testing.T) {
 	hr, _, tx, bulk := prepareFileDeliveryMock(t)
 	rec := httptest.NewRecorder()
@@ -407,7 +500,7 @@ func prepareFileDeliveryMock(t *testing.T) (http.Handler, apiServer, *MockTransp
 			chunkClient: mockES,
 			cache:       c,
 			deliverer:   delivery.New(mockES, fakebulk, maxFileSize),
-			authAgent: func(r *http.Request, id *string, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+			authAgent: func(r *http.Request, id *string, cfg *config.Server, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 				return &model.Agent{
 					ESDocument: model.ESDocument{
 						Id: "foo",
@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/coordinator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+)
+
+var ErrPolicyLeadersForbidden = errors.New("api key is not allowed to read the policy leaders")
+
+type PolicyLeadersT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	policies   monitor.GlobalCheckpointProvider
+	elector    coordinator.Elector
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+// NewPolicyLeadersT returns the handler of the policy leaders, policies is the monitor of the policies index whose
+// checkpoint is reported, it may be nil. The leaders are read from elector, the .fleet-policies-leader index when nil.
+func NewPolicyLeadersT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache, policies monitor.GlobalCheckpointProvider, elector coordinator.Elector) *PolicyLeadersT {
+	if elector == nil {
+		elector = coordinator.NewElasticsearchElector(bulker, dl.WithTimeout(cfg.Timeouts.Query))
+	}
+	return &PolicyLeadersT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		policies:   policies,
+		elector:    elector,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handlePolicyLeaders returns the leader election state of the policies.
+// The API key of the request must have read privileges on .fleet-policies and .fleet-policies-leader.
+func (pt *PolicyLeadersT) handlePolicyLeaders(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := pt.authAPIKey(r, pt.bulker, pt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	ok, err := key.HasPrivileges(ctx, pt.bulker.Client(), []string{dl.FleetPolicies, dl.FleetPoliciesLeader}, []string{"read"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPolicyLeadersForbidden
+	}
+	if err := authorizeUnscoped(ctx, pt.cfg, pt.bulker, key); err != nil {
+		return err
+	}
+
+	span, ctx := apm.StartSpan(ctx, "policyLeaderships", "search")
+	leaderships, err := coordinator.PolicyLeaderships(ctx, pt.bulker, pt.elector, time.Now().UTC(), dl.WithTimeout(pt.cfg.Timeouts.Query))
+	span.End()
+	if err != nil {
+		return err
+	}
+
+	resp := PolicyLeadersAPIResponse{Items: make([]PolicyLeadersItem, 0, len(leaderships))}
+	for _, l := range leaderships {
+		resp.Items = append(resp.Items, policyLeadersItem(l))
+	}
+	if pt.policies != nil {
+		if checkpoint := pt.policies.GetCheckpoint(); checkpoint.IsSet() {
+			v := checkpoint.Value()
+			resp.PoliciesCheckpoint = &v
+		}
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func policyLeadersItem(l coordinator.PolicyLeadership) PolicyLeadersItem {
+	item := PolicyLeadersItem{
+		PolicyId:       l.PolicyID,
+		RevisionIdx:    l.RevisionIdx,
+		CoordinatorIdx: l.CoordinatorIdx,
+		State:          PolicyLeadersItemState(l.State),
+	}
+	if l.Leader != nil {
+		item.ServerId = &l.Leader.ID
+		item.ServerVersion = &l.Leader.Version
+		item.HeldAt = &l.HeldAt
+		item.LeaseExpiresAt = &l.ExpiresAt
+	}
+	return item
+}
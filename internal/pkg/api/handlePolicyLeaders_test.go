@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandlePolicyLeaders(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+
+	now := time.Now().UTC()
+	policies := &es.ResultT{Aggregations: map[string]es.Aggregation{
+		dl.FieldPolicyID: {Buckets: []es.Bucket{{
+			Aggregations: map[string]es.HitsT{dl.FieldRevisionIdx: {Hits: []es.HitT{{Source: []byte(`{"policy_id":"policy-c","revision_idx":1,"coordinator_idx":0}`)}}}},
+		}, {
+			Aggregations: map[string]es.HitsT{dl.FieldRevisionIdx: {Hits: []es.HitT{{Source: []byte(`{"policy_id":"policy-a","revision_idx":2,"coordinator_idx":1}`)}}}},
+		}, {
+			Aggregations: map[string]es.HitsT{dl.FieldRevisionIdx: {Hits: []es.HitT{{Source: []byte(`{"policy_id":"policy-b","revision_idx":3,"coordinator_idx":1}`)}}}},
+		}}},
+	}}
+	leaders := &es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{
+		ID:     "policy-a",
+		Source: []byte(fmt.Sprintf(`{"server":{"id":"server-1","version":"8.15.0"},"@timestamp":%q}`, now.Format(time.RFC3339Nano))),
+	}, {
+		ID:     "policy-b",
+		Source: []byte(fmt.Sprintf(`{"server":{"id":"server-2","version":"8.14.0"},"@timestamp":%q}`, now.Add(-time.Hour).Format(time.RFC3339Nano))),
+	}}}}
+
+	tests := []struct {
+		name       string
+		privileged bool
+		status     int
+	}{{
+		name:       "not privileged",
+		privileged: false,
+		status:     http.StatusForbidden,
+	}, {
+		name:       "leaders",
+		privileged: true,
+		status:     http.StatusOK,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(client)
+			if tc.status == http.StatusOK {
+				fakebulk.On("Search", mock.Anything, dl.FleetPolicies, mock.Anything, mock.Anything).Return(policies, nil).Once()
+				fakebulk.On("Search", mock.Anything, dl.FleetPoliciesLeader, mock.Anything, mock.Anything).Return(leaders, nil).Once()
+			}
+
+			pt := NewPolicyLeadersT(cfg, fakebulk, nil, checkpointProvider{42}, nil)
+			pt.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			router := newRouter(cfg, &apiServer{pl: pt}, nil, nil, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/policies/leaders", nil))
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+			fakebulk.AssertExpectations(t)
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			var resp PolicyLeadersAPIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotNil(t, resp.PoliciesCheckpoint)
+			assert.Equal(t, int64(42), *resp.PoliciesCheckpoint)
+			require.Len(t, resp.Items, 3)
+
+			held := resp.Items[0]
+			assert.Equal(t, "policy-a", held.PolicyId)
+			assert.Equal(t, int64(2), held.RevisionIdx)
+			assert.Equal(t, int64(1), held.CoordinatorIdx)
+			assert.Equal(t, Held, held.State)
+			assert.Equal(t, "server-1", *held.ServerId)
+			assert.Equal(t, "8.15.0", *held.ServerVersion)
+			assert.True(t, held.LeaseExpiresAt.After(*held.HeldAt))
+
+			expired := resp.Items[1]
+			assert.Equal(t, "policy-b", expired.PolicyId)
+			assert.Equal(t, Expired, expired.State)
+			assert.Equal(t, "server-2", *expired.ServerId)
+
+			vacant := resp.Items[2]
+			assert.Equal(t, "policy-c", vacant.PolicyId)
+			assert.Equal(t, Vacant, vacant.State)
+			assert.Nil(t, vacant.ServerId)
+			assert.Nil(t, vacant.HeldAt)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+)
+
+var (
+	ErrPolicyValidateForbidden = errors.New("api key is not allowed to read policies")
+	ErrPolicyDataRequired      = errors.New("policy data is required")
+)
+
+type PolicyValidatorT struct {
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewPolicyValidatorT(bulker bulk.Bulk, c cache.Cache) *PolicyValidatorT {
+	return &PolicyValidatorT{
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handleValidate validates the policy document in the request body.
+// The caller must be allowed to read .fleet-policies, as the response reveals whether the secrets referenced by the policy exist.
+func (pv *PolicyValidatorT) handleValidate(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := pv.authAPIKey(r, pv.bulker, pv.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAccessAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	ok, err := key.HasPrivileges(ctx, pv.bulker.Client(), []string{dl.FleetPolicies}, []string{"read"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPolicyValidateForbidden
+	}
+
+	var req PolicyValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return &BadRequestErr{msg: "unable to decode policy validate request", nextErr: err}
+	}
+	if len(req.Data) == 0 {
+		return ErrPolicyDataRequired
+	}
+	// round trip through JSON so the policy is read exactly as the monitor reads it from the index
+	p, err := json.Marshal(req.Data)
+	if err != nil {
+		return err
+	}
+	var data model.PolicyData
+	if err := json.Unmarshal(p, &data); err != nil {
+		return &BadRequestErr{msg: "unable to decode policy data", nextErr: err}
+	}
+	if req.PolicyId != nil {
+		zlog = zlog.With().Str(LogPolicyID, *req.PolicyId).Logger()
+	}
+
+	span, ctx := apm.StartSpan(ctx, "validatePolicy", "process")
+	res := policy.Validate(ctx, pv.bulker, &data)
+	span.End()
+
+	zlog.Debug().
+		Bool("valid", res.Valid()).
+		Int("errors", len(res.Errors)).
+		Int("warnings", len(res.Warnings)).
+		Int64(logger.RevisionIdx, data.Revision).
+		Msg("policy validated")
+
+	resp := PolicyValidateAPIResponse{
+		Valid:    res.Valid(),
+		Errors:   validationIssues(res.Errors),
+		Warnings: validationIssues(res.Warnings),
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func validationIssues(issues []policy.ValidationIssue) []PolicyValidationIssue {
+	result := make([]PolicyValidationIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, PolicyValidationIssue{
+			Path:    issue.Path,
+			Message: issue.Message,
+		})
+	}
+	return result
+}
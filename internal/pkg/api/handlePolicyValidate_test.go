@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandlePolicyValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		privileged bool
+		body       string
+		status     int
+		expect     string
+	}{{
+		name:       "valid policy",
+		privileged: true,
+		body:       `{"policy_id":"policy","data":{"id":"policy","outputs":{"default":{"type":"logstash"}},"inputs":[{"type":"logfile"}]}}`,
+		status:     http.StatusOK,
+		expect:     `{"valid":true,"errors":[],"warnings":[]}`,
+	}, {
+		name:       "policy with errors",
+		privileged: true,
+		body:       `{"data":{"id":"policy","outputs":{"default":{"type":"elasticsearch"}}}}`,
+		status:     http.StatusOK,
+		expect: `{"valid":false,
+			"errors":[{"path":"output_permissions.default","message":"output permission sections not found"}],
+			"warnings":[{"path":"inputs","message":"policy does not contain any inputs"}]}`,
+	}, {
+		name:       "missing data",
+		privileged: true,
+		body:       `{"policy_id":"policy"}`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:       "malformed body",
+		privileged: true,
+		body:       `{"data":`,
+		status:     http.StatusBadRequest,
+	}, {
+		name:   "api key without read privileges",
+		body:   `{"data":{"id":"policy","outputs":{"default":{"type":"logstash"}}}}`,
+		status: http.StatusForbidden,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			es, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(es)
+
+			si := apiServer{
+				pv: &PolicyValidatorT{
+					bulker: fakebulk,
+					authAPIKey: func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+						return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+					},
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/fleet/policies/validate", strings.NewReader(tc.body))
+			Handler(&si).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.expect != "" {
+				assert.JSONEq(t, tc.expect, rec.Body.String())
+			}
+		})
+	}
+}
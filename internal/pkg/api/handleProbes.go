@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
+)
+
+const (
+	probeLive     = "live"
+	probeReady    = "ready"
+	probeNotReady = "not_ready"
+	probeOK       = "ok"
+)
+
+// probesT serves the liveness and readiness endpoints, they are not part of the fleet API.
+type probesT struct {
+	cfg  *config.Probes
+	bulk bulk.Bulk
+	sm   policy.SelfMonitor
+}
+
+type probeResponse struct {
+	Status string `json:"status"`
+	// Checks are the results of the readiness criteria, ok or why the criteria are not met.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// handleLive answers as long as the server serves requests.
+func (p *probesT) handleLive(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, r, http.StatusOK, probeResponse{Status: probeLive})
+}
+
+// handleReady answers 200 when the configured criteria are met and the server is not shutting down, 503 otherwise.
+func (p *probesT) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	resp := probeResponse{Status: probeReady, Checks: make(map[string]string, 4)}
+	fail := func(check, reason string) {
+		resp.Status = probeNotReady
+		resp.Checks[check] = reason
+	}
+
+	// The context of the requests is cancelled once the server starts shutting down, the other criteria are not checked then.
+	if ctx.Err() != nil {
+		fail("draining", "shutting down")
+		writeProbe(w, r, http.StatusServiceUnavailable, resp)
+		return
+	}
+	// A draining server still serves the requests, the load balancers send the new connections to the other servers.
+	if since, reason := drain.FromContext(ctx).Since(); !since.IsZero() {
+		fail("draining", "draining since "+since.Format(time.RFC3339)+" on "+reason)
+	} else {
+		resp.Checks["draining"] = probeOK
+	}
+	// A server in standby does not serve the agents until it is promoted.
+	if standby.FromContext(ctx).Standby() {
+		fail("standby", "in standby")
+	} else {
+		resp.Checks["standby"] = probeOK
+	}
+	if p.cfg.Elasticsearch {
+		if _, err := pingElasticsearch(ctx, p.bulk, p.cfg.PingTimeout); err != nil {
+			fail("elasticsearch", err.Error())
+		} else {
+			resp.Checks["elasticsearch"] = probeOK
+		}
+	}
+	if p.cfg.Policies {
+		if state := p.sm.State(); state != client.UnitStateHealthy {
+			fail("policies", state.String())
+		} else {
+			resp.Checks["policies"] = probeOK
+		}
+	}
+
+	code := http.StatusOK
+	if resp.Status != probeReady {
+		code = http.StatusServiceUnavailable
+	}
+	writeProbe(w, r, code, resp)
+}
+
+func writeProbe(w http.ResponseWriter, r *http.Request, code int, resp probeResponse) {
+	data, err := json.Marshal(&resp)
+	if err != nil {
+		ErrorResp(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		hlog.FromRequest(r).Debug().Err(err).Msg("fail writing probe response")
+	}
+}
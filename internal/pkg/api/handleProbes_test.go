@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestProbes(t *testing.T) {
+	esSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	}))
+	defer esSrv.Close()
+	esCli, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{esSrv.URL}})
+	require.NoError(t, err)
+	downCli, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{"http://127.0.0.1:1"}, MaxRetries: 1})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		es       *elasticsearch.Client
+		state    client.UnitState
+		cfg      func(*config.Probes)
+		cancel   bool
+		drain    bool
+		standby  bool
+		code     int
+		failures []string
+	}{
+		{name: "ready", es: esCli, state: client.UnitStateHealthy, code: http.StatusOK},
+		{name: "elasticsearch unreachable", es: downCli, state: client.UnitStateHealthy, code: http.StatusServiceUnavailable, failures: []string{"elasticsearch"}},
+		{name: "policies not loaded", es: esCli, state: client.UnitStateStarting, code: http.StatusServiceUnavailable, failures: []string{"policies"}},
+		{name: "draining", es: esCli, state: client.UnitStateHealthy, cancel: true, code: http.StatusServiceUnavailable, failures: []string{"draining"}},
+		{name: "drain mode", es: esCli, state: client.UnitStateHealthy, drain: true, code: http.StatusServiceUnavailable, failures: []string{"draining"}},
+		{name: "standby", es: esCli, state: client.UnitStateHealthy, standby: true, code: http.StatusServiceUnavailable, failures: []string{"standby"}},
+		{name: "criteria disabled", es: downCli, state: client.UnitStateStarting, code: http.StatusOK, cfg: func(p *config.Probes) {
+			p.Elasticsearch = false
+			p.Policies = false
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Server{}
+			cfg.InitDefaults()
+			cfg.Probes.PingTimeout = time.Second
+			if tc.cfg != nil {
+				tc.cfg(&cfg.Probes)
+			}
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Client").Return(tc.es)
+			probes := &probesT{cfg: &cfg.Probes, bulk: bulker, sm: &mockPolicyMonitor{state: tc.state}}
+			router := newRouter(cfg, &apiServer{}, probes, nil, nil)
+
+			ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+			defer cancel()
+			if tc.drain {
+				state := drain.New()
+				state.Start(drain.ReasonAPI)
+				ctx = drain.WithContext(ctx, state)
+			}
+			if tc.standby {
+				ctx = standby.WithContext(ctx, standby.New(true))
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/live", nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, "the server is live regardless of the readiness criteria")
+
+			if tc.cancel {
+				cancel()
+			}
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/ready", nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, tc.code, w.Code)
+
+			var res probeResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			if len(tc.failures) == 0 {
+				assert.Equal(t, probeReady, res.Status)
+			} else {
+				assert.Equal(t, probeNotReady, res.Status)
+			}
+			for check, result := range res.Checks {
+				if slices.Contains(tc.failures, check) {
+					assert.NotEqual(t, probeOK, result, check)
+				} else {
+					assert.Equal(t, probeOK, result, check)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/profile"
+)
+
+// kPprofPrefix is the path the /debug/pprof/ endpoints are served under on the API listener.
+const kPprofPrefix = "/api/fleet"
+
+var ErrProfilerForbidden = errors.New("api key is not allowed to change the profiler settings")
+
+type ProfilerT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewProfilerT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache) *ProfilerT {
+	return &ProfilerT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// authorize ensures the API key of the request is allowed to profile the server, it must have all privileges on .fleet-servers.
+func (pt *ProfilerT) authorize(zlog zerolog.Logger, r *http.Request) (zerolog.Logger, error) {
+	key, err := pt.authAPIKey(r, pt.bulker, pt.cache)
+	if err != nil {
+		return zlog, err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+
+	ok, err := key.HasPrivileges(zlog.WithContext(r.Context()), pt.bulker.Client(), []string{dl.FleetServers}, []string{"all"})
+	if err != nil {
+		return zlog, err
+	}
+	if !ok {
+		return zlog, ErrProfilerForbidden
+	}
+	if err := authorizeUnscoped(r.Context(), pt.cfg, pt.bulker, key); err != nil {
+		return zlog, err
+	}
+	return zlog, nil
+}
+
+// handleUpdate changes the runtime profiling settings, the settings that are not in the request are left unchanged.
+func (pt *ProfilerT) handleUpdate(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	zlog, err := pt.authorize(zlog, r)
+	if err != nil {
+		return err
+	}
+
+	var req ProfilerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return &BadRequestErr{msg: "unable to decode profiler request", nextErr: err}
+	}
+	if req.BlockProfileRate != nil && *req.BlockProfileRate < 0 {
+		return &BadRequestErr{msg: "block_profile_rate must not be negative"}
+	}
+	if req.MutexProfileFraction != nil && *req.MutexProfileFraction < 0 {
+		return &BadRequestErr{msg: "mutex_profile_fraction must not be negative"}
+	}
+
+	s := profile.Update(func(s *profile.Settings) {
+		if req.Enabled != nil {
+			s.Enabled = *req.Enabled
+		}
+		if req.BlockProfileRate != nil {
+			s.BlockRate = *req.BlockProfileRate
+		}
+		if req.MutexProfileFraction != nil {
+			s.MutexFraction = *req.MutexProfileFraction
+		}
+	})
+	zlog.Info().
+		Bool("enabled", s.Enabled).
+		Int("block_rate", s.BlockRate).
+		Int("mutex_fraction", s.MutexFraction).
+		Msg("profiler settings changed")
+
+	out, err := json.Marshal(ProfilerAPIResponse{
+		Enabled:              s.Enabled,
+		BlockProfileRate:     s.BlockRate,
+		MutexProfileFraction: s.MutexFraction,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// pprofHandler serves the pprof endpoints to the API keys that are allowed to profile the server.
+func (pt *ProfilerT) pprofHandler() http.Handler {
+	h := profile.Handler(kPprofPrefix)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := pt.authorize(hlog.FromRequest(r).With().Logger(), r); err != nil {
+			cntProfiler.IncError(err)
+			w.Header().Set("Content-Type", "application/json")
+			ErrorResp(w, r, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/profile"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandleProfiler(t *testing.T) {
+	t.Cleanup(func() { profile.Apply(profile.Settings{}) })
+
+	tests := []struct {
+		name       string
+		privileged bool
+		body       string
+		status     int
+		expect     string
+		pprof      int
+	}{{
+		name:       "enable",
+		privileged: true,
+		body:       `{"enabled":true,"block_profile_rate":1000}`,
+		status:     http.StatusOK,
+		expect:     `{"enabled":true,"block_profile_rate":1000,"mutex_profile_fraction":0}`,
+		pprof:      http.StatusOK,
+	}, {
+		name:       "unset settings are unchanged",
+		privileged: true,
+		body:       `{"mutex_profile_fraction":5}`,
+		status:     http.StatusOK,
+		expect:     `{"enabled":true,"block_profile_rate":1000,"mutex_profile_fraction":5}`,
+		pprof:      http.StatusOK,
+	}, {
+		name:       "negative rate",
+		privileged: true,
+		body:       `{"block_profile_rate":-1}`,
+		status:     http.StatusBadRequest,
+		pprof:      http.StatusOK,
+	}, {
+		name:       "disable",
+		privileged: true,
+		body:       `{"enabled":false,"block_profile_rate":0,"mutex_profile_fraction":0}`,
+		status:     http.StatusOK,
+		expect:     `{"enabled":false,"block_profile_rate":0,"mutex_profile_fraction":0}`,
+		pprof:      http.StatusNotFound,
+	}, {
+		name:   "api key without privileges",
+		body:   `{"enabled":true}`,
+		status: http.StatusForbidden,
+		pprof:  http.StatusForbidden,
+	}}
+
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			es, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(es)
+
+			prof := &ProfilerT{
+				bulker: fakebulk,
+				authAPIKey: func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+					return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+				},
+			}
+			router := newRouter(cfg, &apiServer{prof: prof}, nil, prof, nil)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/fleet/profiler", strings.NewReader(tc.body))
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.expect != "" {
+				assert.JSONEq(t, tc.expect, rec.Body.String())
+			}
+
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/api/fleet/debug/pprof/cmdline", nil)
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tc.pprof, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
+)
+
+var (
+	ErrPromoteForbidden   = errors.New("api key is not allowed to promote the server")
+	ErrStandbyUnavailable = errors.New("the server has no standby mode")
+)
+
+type PromoteT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewPromoteT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache) *PromoteT {
+	return &PromoteT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handlePromote promotes the server of the request out of the standby mode.
+// Promoting a server that is not in standby is not an error, the response is the state of the earlier promotion.
+func (pt *PromoteT) handlePromote(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request) error {
+	key, err := pt.authAPIKey(r, pt.bulker, pt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+
+	ok, err := key.HasPrivileges(zlog.WithContext(r.Context()), pt.bulker.Client(), []string{dl.FleetServers}, []string{"all"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPromoteForbidden
+	}
+	if err := authorizeUnscoped(r.Context(), pt.cfg, pt.bulker, key); err != nil {
+		return err
+	}
+
+	state := standby.FromContext(r.Context())
+	if state == nil {
+		return ErrStandbyUnavailable
+	}
+	if state.Promote(standby.ReasonAPI) {
+		zlog.Info().Msg("promotion requested")
+	}
+
+	var resp PromoteAPIResponse
+	if at, reason := state.PromotedAt(); !at.IsZero() {
+		resp.PromotedAt = &at
+		resp.Reason = &reason
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestHandlePromote(t *testing.T) {
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	cfg.Standby.Enabled = true
+
+	for _, privileged := range []bool{true, false} {
+		t.Run(fmt.Sprintf("privileged %t", privileged), func(t *testing.T) {
+			es, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(es)
+
+			pt := NewPromoteT(cfg, fakebulk, nil)
+			pt.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			router := newRouter(cfg, &apiServer{promote: pt}, nil, nil, nil)
+			state := standby.New(true)
+			ctx := standby.WithContext(context.Background(), state)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/fleet/promote", nil).WithContext(ctx))
+			if !privileged {
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+				assert.True(t, state.Standby())
+				return
+			}
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.False(t, state.Standby())
+
+			var resp PromoteAPIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotNil(t, resp.Reason)
+			assert.Equal(t, standby.ReasonAPI, *resp.Reason)
+			require.NotNil(t, resp.PromotedAt)
+		})
+	}
+}
+
+func TestStandbyFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		redirect string
+		path     string
+		code     int
+	}{{
+		name: "checkin",
+		path: "/api/fleet/agents/agent-id/checkin",
+		code: http.StatusServiceUnavailable,
+	}, {
+		name:     "checkin redirected",
+		redirect: "https://active.example.com:8220/",
+		path:     "/api/fleet/agents/agent-id/checkin",
+		code:     http.StatusTemporaryRedirect,
+	}, {
+		name: "status",
+		path: "/api/status",
+		code: http.StatusOK,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.ServerStandby{RedirectURL: tc.redirect}
+			cfg.InitDefaults()
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			ctx := standby.WithContext(context.Background(), standby.New(true))
+
+			rec := httptest.NewRecorder()
+			(&standbyFilter{cfg: cfg}).middleware(next).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, nil).WithContext(ctx))
+			require.Equal(t, tc.code, rec.Code)
+			switch tc.code {
+			case http.StatusServiceUnavailable:
+				assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+			case http.StatusTemporaryRedirect:
+				assert.Equal(t, "https://active.example.com:8220"+tc.path, rec.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const unenrolledReasonRevoked = "revoked"
+
+var ErrAgentRevokeForbidden = errors.New("api key is not allowed to revoke agents")
+
+type RevokerT struct {
+	cfg        *config.Server
+	bulker     bulk.Bulk
+	cache      cache.Cache
+	inv        *invalidator.Invalidator
+	authAPIKey func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error) // injectable for testing purposes
+}
+
+func NewRevokerT(cfg *config.Server, bulker bulk.Bulk, c cache.Cache, inv *invalidator.Invalidator) *RevokerT {
+	return &RevokerT{
+		cfg:        cfg,
+		bulker:     bulker,
+		cache:      c,
+		inv:        inv,
+		authAPIKey: authAPIKey,
+	}
+}
+
+// handleRevoke revokes all the credentials of an agent.
+// The agent is marked inactive first so its requests are rejected by every fleet-server even if the invalidation
+// of its API keys fails, the keys that could not be invalidated are handed to the background invalidator.
+func (rt *RevokerT) handleRevoke(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, id string) error {
+	key, err := rt.authAPIKey(r, rt.bulker, rt.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	ok, err := key.HasPrivileges(ctx, rt.bulker.Client(), []string{dl.FleetAgents}, []string{"write"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAgentRevokeForbidden
+	}
+	namespaces, err := apiKeyNamespaces(ctx, rt.cfg, rt.bulker, key)
+	if err != nil {
+		return err
+	}
+
+	agent, err := getAgent(ctx, rt.bulker, rt.cache, id)
+	if errors.Is(err, dl.ErrNotFound) {
+		return ErrAgentNotFound
+	} else if err != nil {
+		return fmt.Errorf("GetAgent: %w", err)
+	}
+	if err := authorizeNamespaces(namespaces, agent.Namespaces); err != nil {
+		return err
+	}
+
+	resp, err := rt.revoke(ctx, zlog, &agent)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func (rt *RevokerT) revoke(ctx context.Context, zlog zerolog.Logger, agent *model.Agent) (*AgentRevokeAPIResponse, error) {
+	span, ctx := apm.StartSpan(ctx, "revokeAgent", "process")
+	defer span.End()
+
+	if err := dl.TombstoneAgent(ctx, rt.bulker, agent, unenrolledReasonRevoked); err != nil {
+		return nil, fmt.Errorf("revoke: %w", err)
+	}
+
+	resp := &AgentRevokeAPIResponse{
+		Id:                   agent.Id,
+		InvalidatedApiKeyIds: make([]string, 0),
+		PendingApiKeyIds:     make([]string, 0),
+	}
+	keys := agent.APIKeyIDs()
+	// Only the access API key is cached, an unknown key is stored as not enabled so later requests fail
+	// without a round trip to Elasticsearch.
+	if agent.AccessAPIKeyID != "" {
+		rt.cache.SetAPIKey(apikey.APIKey{ID: agent.AccessAPIKeyID}, false)
+	}
+
+	var pending []model.ToRetireAPIKeyIdsItems
+	for output, ids := range idsByOutput(keys) {
+		err := invalidator.InvalidateOutput(ctx, zlog, rt.bulker, output, ids)
+		audit.APIKey(zlog, audit.APIKeyEvent{Action: audit.ActionAPIKeyInvalidate, Reason: audit.ReasonRevoked, AgentID: agent.Id, Output: output, IDs: ids}, err)
+		if err != nil {
+			zlog.Warn().Err(err).Strs("ids", ids).Str(logger.PolicyOutputName, output).Msg("Failed to invalidate API keys of revoked agent")
+			resp.PendingApiKeyIds = append(resp.PendingApiKeyIds, ids...)
+			for _, id := range ids {
+				pending = append(pending, model.ToRetireAPIKeyIdsItems{ID: id, Output: output})
+			}
+			continue
+		}
+		resp.InvalidatedApiKeyIds = append(resp.InvalidatedApiKeyIds, ids...)
+	}
+	if len(pending) > 0 && rt.inv != nil {
+		rt.inv.Invalidate(pending...)
+	}
+	sort.Strings(resp.InvalidatedApiKeyIds)
+	sort.Strings(resp.PendingApiKeyIds)
+
+	zlog.Info().
+		Strs("invalidated", resp.InvalidatedApiKeyIds).
+		Strs("pending", resp.PendingApiKeyIds).
+		Msg("agent credentials revoked")
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testcache "github.com/elastic/fleet-server/v7/internal/pkg/testing/cache"
+)
+
+func TestHandleRevoke(t *testing.T) {
+	agent, err := json.Marshal(model.Agent{
+		Active:         true,
+		AccessAPIKeyID: "access",
+		Outputs: map[string]*model.PolicyOutput{
+			"default": {APIKeyID: "output"},
+			"remote":  {APIKeyID: "remote-output"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		privileged bool
+		found      bool
+		status     int
+		expect     string
+	}{{
+		name:       "revoke agent",
+		privileged: true,
+		found:      true,
+		status:     http.StatusOK,
+		expect:     `{"id":"agent-id","invalidated_api_key_ids":["access","output"],"pending_api_key_ids":["remote-output"]}`,
+	}, {
+		name:       "agent not found",
+		privileged: true,
+		status:     http.StatusNotFound,
+	}, {
+		name:   "api key without write privileges",
+		found:  true,
+		status: http.StatusForbidden,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			esClient, tx := mockESClient(t)
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.privileged))),
+				}, nil
+			}
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("Client").Return(esClient)
+			if tc.found {
+				fakebulk.On("ReadRaw", mock.Anything, dl.FleetAgents, "agent-id", mock.Anything).Return(&bulk.MgetResponseItem{Found: true, Source: agent}, nil)
+			} else {
+				fakebulk.On("ReadRaw", mock.Anything, dl.FleetAgents, "agent-id", mock.Anything).Return(&bulk.MgetResponseItem{}, es.ErrElasticNotFound)
+			}
+			fakebulk.On("Update", mock.Anything, dl.FleetAgents, "agent-id", mock.MatchedBy(func(body []byte) bool {
+				return strings.Contains(string(body), `"active":false`) && strings.Contains(string(body), `"unenrolled_reason":"revoked"`)
+			}), mock.Anything).Return(nil)
+			fakebulk.On("APIKeyInvalidate", mock.Anything, []string{"access", "output"}).Return(nil)
+			remote := itesting.NewMockBulk()
+			remote.On("APIKeyInvalidate", mock.Anything, []string{"remote-output"}).Return(errors.New("unavailable"))
+			fakebulk.On("GetBulker", "remote").Return(remote)
+
+			c := testcache.NewMockCache()
+			c.On("SetAPIKey", apikey.APIKey{ID: "access"}, false).Return()
+			c.On("NotFound", cache.NotFoundAgent, "agent-id").Return(false).Maybe()
+			c.On("SetNotFound", cache.NotFoundAgent, "agent-id").Return().Maybe()
+			inv := invalidator.New(fakebulk, config.APIKeyInvalidation{Path: filepath.Join(t.TempDir(), "state.json")})
+
+			si := apiServer{
+				rt: &RevokerT{
+					bulker: fakebulk,
+					cache:  c,
+					inv:    inv,
+					authAPIKey: func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+						return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+					},
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent-id/revoke", nil)
+			Handler(&si).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.expect == "" {
+				fakebulk.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.JSONEq(t, tc.expect, rec.Body.String())
+			assert.Equal(t, 1, inv.Pending(), "the keys that failed to be invalidated are retried in the background")
+			c.AssertExpectations(t)
+		})
+	}
+}
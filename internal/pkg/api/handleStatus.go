@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/coordinator"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
 
@@ -27,6 +29,9 @@ import (
 
 const (
 	kStatusMod = "status"
+
+	// kStatusPingTimeout bounds the ping of Elasticsearch of a verbose status request.
+	kStatusPingTimeout = 5 * time.Second
 )
 
 type AuthFunc func(*http.Request) (*apikey.APIKey, error)
@@ -36,10 +41,26 @@ type StatusT struct {
 	bulk   bulk.Bulk
 	cache  cache.Cache
 	authfn AuthFunc
+	pm     policy.Monitor
+	cord   coordinator.Monitor
 }
 
 type OptFunc func(*StatusT)
 
+// WithPolicyMonitor includes the dispatch lag of the policy monitor in the verbose status responses.
+func WithPolicyMonitor(pm policy.Monitor) OptFunc {
+	return func(st *StatusT) {
+		st.pm = pm
+	}
+}
+
+// WithCoordinator includes the state of the leader election in the verbose status responses.
+func WithCoordinator(cord coordinator.Monitor) OptFunc {
+	return func(st *StatusT) {
+		st.cord = cord
+	}
+}
+
 func NewStatusT(cfg *config.Server, bulker bulk.Bulk, cache cache.Cache, opts ...OptFunc) *StatusT {
 	st := &StatusT{
 		cfg:   cfg,
@@ -62,7 +83,7 @@ func (st StatusT) authenticate(r *http.Request) (*apikey.APIKey, error) {
 	return authAPIKey(r, st.bulk, st.cache)
 }
 
-func (st StatusT) handleStatus(zlog zerolog.Logger, sm policy.SelfMonitor, bi build.Info, r *http.Request, w http.ResponseWriter) error {
+func (st StatusT) handleStatus(zlog zerolog.Logger, sm policy.SelfMonitor, bi build.Info, r *http.Request, w http.ResponseWriter, verbose bool) error {
 	authed := true
 	if _, aerr := st.authfn(r); aerr != nil {
 		zlog.Debug().Err(aerr).Msg("unauthenticated status request, return short status response only")
@@ -85,6 +106,32 @@ func (st StatusT) handleStatus(zlog zerolog.Logger, sm policy.SelfMonitor, bi bu
 			BuildTime: &bt,
 		}
 		sSpan.End()
+
+		pSpan, pCtx := apm.StartSpan(ctx, "getPolicyReadiness", "process")
+		if readiness := sm.Readiness(pCtx); readiness != nil {
+			policies := make([]StatusResponsePolicy, 0, len(readiness))
+			for _, pr := range readiness {
+				p := StatusResponsePolicy{
+					Id:              pr.PolicyID,
+					Revision:        pr.Revision,
+					Ready:           pr.Ready,
+					OutputsPrepared: pr.OutputsPrepared,
+					AgentsPending:   pr.AgentsPending,
+				}
+				if pr.Message != "" {
+					p.Message = &pr.Message
+				}
+				policies = append(policies, p)
+			}
+			resp.Policies = &policies
+		}
+		pSpan.End()
+
+		if verbose {
+			dSpan, dCtx := apm.StartSpan(ctx, "getDependencies", "process")
+			resp.Dependencies = st.dependencies(dCtx)
+			dSpan.End()
+		}
 	}
 	span.End()
 
@@ -129,3 +176,111 @@ func (st StatusT) handleStatus(zlog zerolog.Logger, sm policy.SelfMonitor, bi bu
 
 	return nil
 }
+
+// dependencies returns the health of the dependencies of fleet-server included in a verbose status response.
+func (st StatusT) dependencies(ctx context.Context) *StatusResponseDependencies {
+	deps := &StatusResponseDependencies{
+		Elasticsearch: st.pingElasticsearch(ctx),
+		Bulker:        &StatusResponseBulker{QueueDepth: st.bulk.QueueDepth()},
+	}
+	if st.pm != nil {
+		deps.PolicyMonitor = &StatusResponsePolicyMonitor{DispatchLagMs: st.pm.DispatchLag().Milliseconds()}
+	}
+	if st.cord != nil {
+		state := st.cord.State()
+		deps.Coordinator = &StatusResponseCoordinator{Leading: state.Leading}
+		if !state.Checked.IsZero() {
+			checked := state.Checked.Format(time.RFC3339)
+			deps.Coordinator.Checked = &checked
+		}
+		if state.Members > 0 {
+			deps.Coordinator.Members = &state.Members
+		}
+	}
+	if limits := st.limits(); len(limits) > 0 {
+		deps.Limits = &limits
+	}
+	return deps
+}
+
+func (st StatusT) pingElasticsearch(ctx context.Context) *StatusResponseElasticsearch {
+	latency, err := pingElasticsearch(ctx, st.bulk, kStatusPingTimeout)
+	resp := &StatusResponseElasticsearch{LatencyMs: latency.Milliseconds()}
+	if err != nil {
+		msg := err.Error()
+		resp.Error = &msg
+		return resp
+	}
+	resp.Available = true
+	return resp
+}
+
+// pingElasticsearch pings the Elasticsearch cluster of the bulker and returns the round trip of the ping.
+func pingElasticsearch(ctx context.Context, bulker bulk.Bulk, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := bulker.Client()
+	start := time.Now()
+	res, err := client.Ping(client.Ping.WithContext(ctx))
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return latency, fmt.Errorf("ping failed with status %d", res.StatusCode)
+	}
+	return latency, nil
+}
+
+// limits returns the saturation of the limits of concurrent requests and connections that are configured.
+func (st StatusT) limits() []StatusResponseLimit {
+	l := st.cfg.Limits
+	routes := []struct {
+		name  string
+		limit int64
+		stats *routeStats
+	}{
+		{"checkin", l.CheckinLimit.Max, &cntCheckin},
+		{"enroll", l.EnrollLimit.Max, &cntEnroll},
+		{"artifact", l.ArtifactLimit.Max, &cntArtifacts.routeStats},
+		{"acks", l.AckLimit.Max, &cntAcks},
+		{"status", l.StatusLimit.Max, &cntStatus},
+		{"uploadBegin", l.UploadStartLimit.Max, &cntUploadStart},
+		{"uploadChunk", l.UploadChunkLimit.Max, &cntUploadChunk},
+		{"uploadComplete", l.UploadEndLimit.Max, &cntUploadEnd},
+		{"uploadStatus", l.UploadStatusLimit.Max, &cntUploadStatus},
+		{"deliverFile", l.DeliverFileLimit.Max, &cntFileDeliv},
+		{"getPGPKey", l.GetPGPKey.Max, &cntGetPGP},
+		{"policyValidate", l.PolicyValidateLimit.Max, &cntPolicyValidate},
+		{"revoke", l.RevokeLimit.Max, &cntRevoke},
+		{"profiler", l.ProfilerLimit.Max, &cntProfiler},
+		{"diagnostics", l.DiagnosticsLimit.Max, &cntDiagnostics},
+		{"drain", l.DrainLimit.Max, &cntDrain},
+		{"agentSearch", l.AgentSearchLimit.Max, &cntAgentSearch},
+		{"agentReassign", l.AgentReassignLimit.Max, &cntAgentReassign},
+		{"policyLeaders", l.PolicyLeadersLimit.Max, &cntPolicyLeaders},
+		{"promote", l.PromoteLimit.Max, &cntPromote},
+	}
+
+	limits := make([]StatusResponseLimit, 0, len(routes)+1)
+	if l.MaxConnections > 0 {
+		limits = append(limits, newStatusLimit("connections", int64(l.MaxConnections), cntHTTPActive.metric.Get()))
+	}
+	for _, rt := range routes {
+		if rt.limit > 0 {
+			limits = append(limits, newStatusLimit(rt.name, rt.limit, rt.stats.active.metric.Get()))
+		}
+	}
+	return limits
+}
+
+func newStatusLimit(name string, limit int64, active uint64) StatusResponseLimit {
+	return StatusResponseLimit{
+		Name:       name,
+		Active:     int(active),
+		Max:        int(limit),
+		Saturation: float64(active) / float64(limit),
+	}
+}
@@ -20,7 +20,11 @@ import (
 	fbuild "github.com/elastic/fleet-server/v7/internal/pkg/build"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/coordinator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+	"github.com/elastic/go-elasticsearch/v8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,7 +39,8 @@ func withAuthFunc(authfn AuthFunc) OptFunc {
 }
 
 type mockPolicyMonitor struct {
-	state client.UnitState
+	state     client.UnitState
+	readiness []policy.PolicyReadiness
 }
 
 func (pm *mockPolicyMonitor) Run(ctx context.Context) error {
@@ -46,6 +51,10 @@ func (pm *mockPolicyMonitor) State() client.UnitState {
 	return pm.state
 }
 
+func (pm *mockPolicyMonitor) Readiness(_ context.Context) []policy.PolicyReadiness {
+	return pm.readiness
+}
+
 func TestHandleStatus(t *testing.T) {
 	ctx := context.Background()
 
@@ -87,7 +96,13 @@ func TestHandleStatus(t *testing.T) {
 					state := client.UnitState(k)
 					r := apiServer{
 						st: NewStatusT(cfg, nil, c, withAuthFunc(tc.AuthFn)),
-						sm: &mockPolicyMonitor{state},
+						sm: &mockPolicyMonitor{state: state, readiness: []policy.PolicyReadiness{{
+							PolicyID:        "fleet-server-policy",
+							Revision:        2,
+							OutputsPrepared: true,
+							AgentsPending:   1,
+							Message:         "1 agents have not received revision 2",
+						}}},
 						bi: fbuild.Info{
 							Version:   "8.1.0",
 							Commit:    "4eff928",
@@ -120,11 +135,119 @@ func TestHandleStatus(t *testing.T) {
 						assert.Equal(t, r.bi.Version, *res.Version.Number)
 						assert.Equal(t, r.bi.Commit, *res.Version.BuildHash)
 						assert.Equal(t, r.bi.BuildTime.Format(time.RFC3339), *res.Version.BuildTime)
+						require.NotNil(t, res.Policies)
+						require.Len(t, *res.Policies, 1)
+						p := (*res.Policies)[0]
+						assert.Equal(t, "fleet-server-policy", p.Id)
+						assert.Equal(t, int64(2), p.Revision)
+						assert.True(t, p.OutputsPrepared)
+						assert.False(t, p.Ready)
+						assert.Equal(t, 1, p.AgentsPending)
+						require.NotNil(t, p.Message)
 					} else {
 						require.Nil(t, res.Version)
+						require.Nil(t, res.Policies)
 					}
 				})
 			}
 		})
 	}
 }
+
+type lagPolicyMonitor struct {
+	policy.Monitor
+	lag time.Duration
+}
+
+func (pm *lagPolicyMonitor) DispatchLag() time.Duration {
+	return pm.lag
+}
+
+type stateCoordinator struct {
+	coordinator.Monitor
+	state coordinator.State
+}
+
+func (c *stateCoordinator) State() coordinator.State {
+	return c.state
+}
+
+func TestHandleStatusVerbose(t *testing.T) {
+	esSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	}))
+	defer esSrv.Close()
+	esCli, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{esSrv.URL}})
+	require.NoError(t, err)
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(esCli)
+
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	cfg.Limits.MaxConnections = 100
+	cfg.Limits.CheckinLimit.Max = 10
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	checked := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	authfnOk := func(r *http.Request) (*apikey.APIKey, error) {
+		return nil, nil
+	}
+	authfnFail := func(r *http.Request) (*apikey.APIKey, error) {
+		return nil, apikey.ErrNoAuthHeader
+	}
+	tests := []struct {
+		name   string
+		authfn AuthFunc
+		query  string
+		deps   bool
+	}{
+		{name: "authenticated verbose", authfn: authfnOk, query: "?verbose=true", deps: true},
+		{name: "authenticated", authfn: authfnOk},
+		{name: "non authenticated verbose", authfn: authfnFail, query: "?verbose=true"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := testlog.SetLogger(t).WithContext(context.Background())
+			r := apiServer{
+				st: NewStatusT(cfg, bulker, c, withAuthFunc(tc.authfn),
+					WithPolicyMonitor(&lagPolicyMonitor{lag: 1500 * time.Millisecond}),
+					WithCoordinator(&stateCoordinator{state: coordinator.State{Leading: 2, Checked: checked, Members: 3}})),
+				sm: &mockPolicyMonitor{state: client.UnitStateHealthy},
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/api/status"+tc.query, nil)
+			Handler(&r).ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var res StatusAPIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			if !tc.deps {
+				assert.Nil(t, res.Dependencies)
+				return
+			}
+			deps := res.Dependencies
+			require.NotNil(t, deps)
+			require.NotNil(t, deps.Elasticsearch)
+			assert.True(t, deps.Elasticsearch.Available)
+			assert.Nil(t, deps.Elasticsearch.Error)
+			require.NotNil(t, deps.Bulker)
+			assert.Zero(t, deps.Bulker.QueueDepth)
+			require.NotNil(t, deps.PolicyMonitor)
+			assert.Equal(t, int64(1500), deps.PolicyMonitor.DispatchLagMs)
+			require.NotNil(t, deps.Coordinator)
+			assert.Equal(t, 2, deps.Coordinator.Leading)
+			require.NotNil(t, deps.Coordinator.Checked)
+			assert.Equal(t, checked.Format(time.RFC3339), *deps.Coordinator.Checked)
+			require.NotNil(t, deps.Coordinator.Members)
+			assert.Equal(t, 3, *deps.Coordinator.Members)
+			require.NotNil(t, deps.Limits)
+			require.Len(t, *deps.Limits, 2)
+			assert.Equal(t, "connections", (*deps.Limits)[0].Name)
+			assert.Equal(t, 100, (*deps.Limits)[0].Max)
+			assert.Equal(t, "checkin", (*deps.Limits)[1].Name)
+			assert.Equal(t, 10, (*deps.Limits)[1].Max)
+		})
+	}
+}
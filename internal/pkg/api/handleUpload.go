@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,7 +22,8 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file"
-	"github.com/elastic/fleet-server/v7/internal/pkg/file/cbor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/encryption"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file/uploader"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/go-elasticsearch/v8"
@@ -40,32 +42,56 @@ var (
 
 	ErrAgentIDMissing       = errors.New("required field agent_id is missing")
 	ErrFileInfoBodyRequired = fmt.Errorf("file info body is required")
+
+	ErrUploadContentsForbidden = errors.New("api key is not allowed to read the upload contents")
 )
 
 // FIXME Should we use the structs in openapi.gen.go instead of the generic ones? Will need to rework the uploader if we do
 type UploadT struct {
+	cfg         *config.Server
 	bulker      bulk.Bulk
 	chunkClient *elasticsearch.Client
 	cache       cache.Cache
 	uploader    *uploader.Uploader
-	authAgent   func(*http.Request, *string, bulk.Bulk, cache.Cache) (*model.Agent, error) // injectable for testing purposes
-	authAPIKey  func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error)        // as above
+	authAgent   func(*http.Request, *string, *config.Server, bulk.Bulk, cache.Cache) (*model.Agent, error) // injectable for testing purposes
+	authAPIKey  func(*http.Request, bulk.Bulk, cache.Cache) (*apikey.APIKey, error)                        // as above
 }
 
-func NewUploadT(cfg *config.Server, bulker bulk.Bulk, chunkClient *elasticsearch.Client, cache cache.Cache) *UploadT {
+func NewUploadT(cfg *config.Server, bulker bulk.Bulk, chunkClient *elasticsearch.Client, cache cache.Cache) (*UploadT, error) {
 	zerolog.Ctx(context.TODO()).Info().
 		Interface("limits", cfg.Limits.ArtifactLimit).
 		Int64("maxFileSize", maxFileSize).
+		Str("storage", cfg.Uploads.Storage.Type).
+		Interface("quotas", cfg.Uploads.Quotas).
+		Bool("encryption", cfg.Uploads.Encryption.Enabled).
+		Int("maxParallelChunks", cfg.Uploads.MaxParallelChunks).
 		Msg("upload limits")
 
+	store, err := storage.New(cfg.Uploads.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create upload storage: %w", err)
+	}
+	cipher, err := encryption.New(cfg.Uploads.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create upload encryption: %w", err)
+	}
+	opts := []uploader.Option{
+		uploader.WithStorage(store),
+		uploader.WithQuotas(cfg.Uploads.Quotas),
+		uploader.WithEncryption(cipher),
+		uploader.WithMaxParallelChunks(cfg.Uploads.MaxParallelChunks),
+		uploader.WithChunkSize(cfg.Limits.UploadChunkLimit.MaxBody),
+	}
+
 	return &UploadT{
+		cfg:         cfg,
 		chunkClient: chunkClient,
 		bulker:      bulker,
 		cache:       cache,
-		uploader:    uploader.New(chunkClient, bulker, cache, maxFileSize, maxUploadTimer),
+		uploader:    uploader.New(chunkClient, bulker, cache, maxFileSize, maxUploadTimer, opts...),
 		authAgent:   authAgent,
 		authAPIKey:  authAPIKey,
-	}
+	}, nil
 }
 
 func (ut *UploadT) validateUploadBeginRequest(ctx context.Context, reader io.Reader) (uploader.JSDict, string, error) {
@@ -95,7 +121,7 @@ func (ut *UploadT) handleUploadBegin(_ zerolog.Logger, w http.ResponseWriter, r
 		return err
 	}
 
-	agent, err := ut.authAgent(r, &agentID, ut.bulker, ut.cache)
+	agent, err := ut.authAgent(r, &agentID, ut.cfg, ut.bulker, ut.cache)
 	if err != nil {
 		return err
 	}
@@ -114,6 +140,9 @@ func (ut *UploadT) handleUploadBegin(_ zerolog.Logger, w http.ResponseWriter, r
 		ChunkSize: info.ChunkSize,
 		UploadId:  info.ID,
 	}
+	if n := ut.uploader.MaxParallelChunks(); n > 0 {
+		resp.MaxParallelChunks = &n
+	}
 	out, err := json.Marshal(resp)
 	if err != nil {
 		return err
@@ -132,16 +161,21 @@ func (ut *UploadT) handleUploadChunk(zlog zerolog.Logger, w http.ResponseWriter,
 	if err != nil {
 		return err
 	}
+	// the chunks are authenticated with the API key only, the signature is verified with the key of the agent of
+	// the upload
+	if err := verifyRequestSignature(r, upinfo.AgentID); err != nil {
+		zlog.Warn().Err(err).Str(LogAgentID, upinfo.AgentID).Msg("request signature verification failed")
+		return err
+	}
 
-	// prevent over-sized chunks
-	data := http.MaxBytesReader(w, r.Body, file.MaxChunkSize)
+	// prevent over-sized chunks, the chunk size of an upload is set when it begins
+	data := http.MaxBytesReader(w, r.Body, upinfo.ChunkSize)
 
 	// compute hash as we stream it
 	hash := sha256.New()
 	copier := io.TeeReader(data, hash)
 
-	ce := cbor.NewChunkWriter(copier, chunkInfo.Last, chunkInfo.BID, chunkInfo.SHA2, upinfo.ChunkSize)
-	if err := uploader.IndexChunk(r.Context(), ut.chunkClient, ce, upinfo.Source, chunkInfo.BID, chunkInfo.Pos); err != nil {
+	if err := ut.uploader.WriteChunk(r.Context(), upinfo, chunkInfo, copier); err != nil {
 		return err
 	}
 
@@ -151,12 +185,23 @@ func (ut *UploadT) handleUploadChunk(zlog zerolog.Logger, w http.ResponseWriter,
 	if !strings.EqualFold(chunkHash, hashsum) {
 		// delete document, since we wrote it, but the hash was invalid
 		// context scoped to allow this operation to finish even if client disconnects
-		if err := uploader.DeleteChunk(ctx, ut.bulker, upinfo.Source, chunkInfo.BID, chunkInfo.Pos); err != nil {
-			zlog.Warn().Err(err).
+		if err := ut.uploader.DeleteChunk(ctx, upinfo, chunkInfo); err != nil {
+			// The stored chunk carries the hash the agent sent, so it would pass finalization with corrupt contents.
+			// Fail the upload rather than risk accepting a corrupt file.
+			zlog.Error().Err(err).
 				Str("source", upinfo.Source).
 				Str("fileID", chunkInfo.BID).
 				Int("chunkNum", chunkInfo.Pos).
-				Msg("a chunk hash mismatch occurred, and fleet server was unable to remove the invalid chunk")
+				Msg("a chunk hash mismatch occurred, and fleet server was unable to remove the invalid chunk, marking upload as failed")
+			reason := fmt.Sprintf("chunk %d hash mismatch, the invalid chunk could not be removed", chunkInfo.Pos)
+			if err := uploader.SetFailed(ctx, ut.bulker, upinfo, reason); err != nil {
+				zlog.Error().Err(err).Str("fileID", chunkInfo.BID).Msg("unable to mark upload as failed")
+			}
+			upinfo.Status = file.StatusFail
+			upinfo.FailureReason = reason
+			ut.cache.SetUpload(upinfo.ID, upinfo)
+			span.End()
+			return fmt.Errorf("%w: chunk %d hash mismatch", uploader.ErrUploadStopped, chunkInfo.Pos)
 		}
 		span.End()
 		return uploader.ErrHashMismatch
@@ -169,6 +214,141 @@ func (ut *UploadT) handleUploadChunk(zlog zerolog.Logger, w http.ResponseWriter,
 	return nil
 }
 
+func (ut *UploadT) handleUploadStatus(_ zerolog.Logger, w http.ResponseWriter, r *http.Request, uplID string) error {
+	// the credentials are authenticated before the upload is read, so the upload ids can not be probed anonymously
+	key, err := ut.authUploadStatusCredentials(r)
+	if err != nil {
+		return err
+	}
+	info, chunks, err := ut.uploader.Progress(r.Context(), uplID)
+	if err != nil {
+		return err
+	}
+	if err := ut.authUploadStatus(r, info, key); err != nil {
+		return err
+	}
+
+	span, _ := apm.StartSpan(r.Context(), "response", "write")
+	defer span.End()
+
+	received := make([]int, 0, len(chunks))
+	for _, c := range chunks {
+		received = append(received, c.Pos)
+	}
+	resp := UploadStatusAPIResponse{
+		UploadId:      info.ID,
+		ChunkSize:     info.ChunkSize,
+		Status:        string(info.Status),
+		Chunks:        received,
+		NextChunk:     uploader.NextChunk(chunks),
+		FileSize:      info.Total,
+		ChunkCount:    info.Count,
+		BytesReceived: uploader.ReceivedBytes(chunks),
+		Outstanding:   uploader.OutstandingChunks(info, chunks),
+		Verification:  uploadVerification(info.Status),
+	}
+	if info.FailureReason != "" {
+		resp.FailureReason = &info.FailureReason
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(out)
+	return err
+}
+
+// authUploadStatusCredentials authenticates the API key or the service token of the request, whoever it belongs to.
+// The API key is returned, nil for a service token.
+func (ut *UploadT) authUploadStatusCredentials(r *http.Request) (*apikey.APIKey, error) {
+	if token, ok := apikey.ExtractServiceToken(r); ok {
+		if ut.cfg == nil || !ut.cfg.ServiceTokenAuth.Enabled {
+			return nil, ErrServiceTokenAuthDisabled
+		}
+		_, err := authServiceToken(r.Context(), ut.bulker, ut.cache, token)
+		return nil, err
+	}
+	return ut.authAPIKey(r, ut.bulker, ut.cache)
+}
+
+// authUploadStatus allows the agent that started the upload to query it.
+// Operators may query any upload with an API key that can read the upload metadata, which they could otherwise search directly,
+// the API keys scoped to namespaces are limited to the uploads of their namespaces. key is the authenticated API key of
+// the request, nil for a service token.
+func (ut *UploadT) authUploadStatus(r *http.Request, info file.Info, key *apikey.APIKey) error {
+	_, err := ut.authAgent(r, &info.AgentID, ut.cfg, ut.bulker, ut.cache)
+	if err == nil || key == nil {
+		return err
+	}
+	ok, perr := key.HasPrivileges(r.Context(), ut.bulker.Client(), []string{fmt.Sprintf(uploader.UploadHeaderIndexPattern, info.Source)}, []string{"read"})
+	if perr != nil {
+		return perr
+	}
+	if !ok {
+		return err
+	}
+	namespaces, nerr := apiKeyNamespaces(r.Context(), ut.cfg, ut.bulker, key)
+	if nerr != nil {
+		return nerr
+	}
+	if nerr := authorizeNamespaces(namespaces, info.Namespaces); nerr != nil {
+		return nerr
+	}
+	zerolog.Ctx(r.Context()).Debug().Str(LogAccessAPIKeyID, key.ID).Str("uploadID", info.ID).Msg("upload status requested by operator")
+	return nil
+}
+
+// handleUploadContents streams the contents of a verified upload, read from the configured upload storage and
+// decrypted. The API key of the request must have read privileges on the upload data index of the integration.
+func (ut *UploadT) handleUploadContents(zlog zerolog.Logger, w http.ResponseWriter, r *http.Request, uplID string) error {
+	key, err := ut.authAPIKey(r, ut.bulker, ut.cache)
+	if err != nil {
+		return err
+	}
+	zlog = zlog.With().Str(LogAPIKeyID, key.ID).Logger()
+	ctx := zlog.WithContext(r.Context())
+
+	info, chunks, err := ut.uploader.Contents(ctx, uplID)
+	if err != nil {
+		return err
+	}
+	ok, err := key.HasPrivileges(ctx, ut.bulker.Client(), []string{fmt.Sprintf(uploader.UploadDataIndexPattern, info.Source)}, []string{"read"})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUploadContentsForbidden
+	}
+	namespaces, err := apiKeyNamespaces(ctx, ut.cfg, ut.bulker, key)
+	if err != nil {
+		return err
+	}
+	if err := authorizeNamespaces(namespaces, info.Namespaces); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Total, 10))
+	if err := ut.uploader.WriteContents(ctx, w, info, chunks); err != nil {
+		return err
+	}
+	zlog.Debug().Str("uploadID", info.ID).Msg("upload contents read by operator")
+	return nil
+}
+
+// uploadVerification describes whether the contents of the upload have been verified against its transithash.
+func uploadVerification(status file.Status) UploadStatusResponseVerification {
+	switch status {
+	case file.StatusDone:
+		return UploadStatusResponseVerificationVerified
+	case file.StatusFail:
+		return UploadStatusResponseVerificationFailed
+	default:
+		return UploadStatusResponseVerificationPending
+	}
+}
+
 func (ut *UploadT) validateUploadCompleteRequest(r *http.Request, id string) (string, error) {
 	span, ctx := apm.StartSpan(r.Context(), "validateRequest", "validate")
 	defer span.End()
@@ -179,13 +359,13 @@ func (ut *UploadT) validateUploadCompleteRequest(r *http.Request, id string) (st
 	}
 	// need to auth that it matches the ID in the initial
 	// doc, but that means we had to doc-lookup early
-	if _, err := ut.authAgent(r, &info.AgentID, ut.bulker, ut.cache); err != nil {
+	if _, err := ut.authAgent(r, &info.AgentID, ut.cfg, ut.bulker, ut.cache); err != nil {
 		return "", fmt.Errorf("error authenticating for upload finalization: %w", err)
 	}
 
 	var req UploadCompleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return "", &BadRequestErr{msg: "unable to decode upload complete request"}
+		return "", &BadRequestErr{msg: "unable to decode upload complete request", nextErr: err}
 	}
 
 	hash := strings.TrimSpace(req.Transithash.Sha256)
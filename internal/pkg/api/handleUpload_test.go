@@ -8,9 +8,12 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -26,8 +29,10 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file/uploader"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
 	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	"github.com/elastic/go-elasticsearch/v8"
 
@@ -248,11 +253,11 @@ func TestUploadBeginAuth(t *testing.T) {
 				rt.ut.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
 					return nil, apikey.ErrInvalidToken
 				}
-				rt.ut.authAgent = func(r *http.Request, s *string, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+				rt.ut.authAgent = func(r *http.Request, s *string, cfg *config.Server, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 					return nil, apikey.ErrInvalidToken
 				}
 			} else {
-				rt.ut.authAgent = func(r *http.Request, s *string, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+				rt.ut.authAgent = func(r *http.Request, s *string, cfg *config.Server, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 					if *s != tc.AgentFromAPIKey { // real AuthAgent provides this facility
 						return nil, ErrAgentIdentity
 					}
@@ -429,6 +434,114 @@ func TestChunkUploadRequiresChunkHashHeader(t *testing.T) {
 
 }
 
+func TestChunkUploadRejectsInvalidChunkHash(t *testing.T) {
+	data := []byte("filedata")
+	mockUploadID := "abc123"
+
+	tests := []struct {
+		Name string
+		Hash string
+	}{
+		{"Non-hex hash is rejected", strings.Repeat("z", 64)},
+		{"Short hash is rejected", "0c4a81b85a6b7ff0"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			hr, _, fakebulk, mtx := prepareUploaderMock(t)
+			mockUploadInfoResult(fakebulk, file.Info{
+				DocID:     "bar.foo",
+				ID:        mockUploadID,
+				ChunkSize: maxFileSize,
+				Total:     10,
+				Count:     1,
+				Start:     time.Now(),
+				Status:    file.StatusProgress,
+				Source:    "agent",
+				AgentID:   "foo",
+				ActionID:  "bar",
+			})
+			mtx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				t.Fatal("chunk with invalid hash should not be sent to elasticsearch")
+				return nil, nil
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/fleet/uploads/"+mockUploadID+"/0", bytes.NewReader(data))
+			req.Header.Set("X-Chunk-SHA2", tc.Hash)
+
+			hr.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "ErrInvalidChunkHash")
+		})
+	}
+}
+
+func TestChunkUploadHashMismatch(t *testing.T) {
+	data := []byte("filedata")
+	hasher := sha256.New()
+	_, err := hasher.Write([]byte("otherdata"))
+	require.NoError(t, err)
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	mockUploadID := "abc123"
+
+	tests := []struct {
+		Name              string
+		DeleteResponse    string
+		ExpectErrContains string
+		ExpectFailed      bool
+	}{
+		{"Removed chunk may be retried", `{"deleted":1,"failures":[]}`, "ErrHashMismatch", false},
+		{"Unremovable chunk fails the upload", `{"deleted":0,"failures":[{"cause":{"type":"some_error"}}]}`, "ErrUploadStopped", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			hr, _, fakebulk, mtx := prepareUploaderMock(t)
+			mockUploadInfoResult(fakebulk, file.Info{
+				DocID:     "bar.foo",
+				ID:        mockUploadID,
+				ChunkSize: maxFileSize,
+				Total:     10,
+				Count:     1,
+				Start:     time.Now(),
+				Status:    file.StatusProgress,
+				Source:    "agent",
+				AgentID:   "foo",
+				ActionID:  "bar",
+			})
+
+			var deleted, failed bool
+			mtx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				_, err := io.Copy(io.Discard, req.Body)
+				require.NoError(t, err)
+				if strings.HasSuffix(req.URL.Path, "/_delete_by_query") {
+					deleted = true
+					assert.Equal(t, "true", req.URL.Query().Get("refresh"))
+					return sendBodyString(tc.DeleteResponse), nil
+				}
+				if strings.HasSuffix(req.URL.Path, "/_update_by_query") {
+					failed = true
+				}
+				return sendBodyString("{}"), nil
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/fleet/uploads/"+mockUploadID+"/0", bytes.NewReader(data))
+			req.Header.Set("X-Chunk-SHA2", hash)
+
+			hr.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.ExpectErrContains)
+			assert.True(t, deleted, "mismatched chunk should be deleted")
+			assert.Equal(t, tc.ExpectFailed, failed, "upload status should only be set to failed if the chunk cannot be removed")
+		})
+	}
+}
+
 func TestChunkUploadStatus(t *testing.T) {
 	data := []byte("filedata")
 	hasher := sha256.New()
@@ -558,6 +671,10 @@ func TestChunkUploadWritesTimestamp(t *testing.T) {
 	})
 
 	mtx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/_update_by_query") {
+			// the upload moves from awaiting to uploading
+			return mtx.Response, nil
+		}
 		body, err := io.ReadAll(req.Body)
 		require.NoError(t, err)
 
@@ -572,6 +689,67 @@ func TestChunkUploadWritesTimestamp(t *testing.T) {
 	hr.ServeHTTP(rec, req)
 }
 
+func TestChunkUploadRequestSignature(t *testing.T) {
+	data := []byte("filedata")
+	hash := sha256.Sum256(data)
+	mockUploadID := "abc123"
+
+	secret := bytes.Repeat([]byte("s"), 32)
+	cfg := config.RequestSigning{Enabled: true, Secret: base64.StdEncoding.EncodeToString(secret)}
+	cfg.InitDefaults()
+
+	hr, _, fakebulk, mtx := prepareUploaderMock(t)
+	info := file.Info{
+		DocID:     "bar.foo",
+		ID:        mockUploadID,
+		ChunkSize: maxFileSize,
+		Total:     10,
+		Count:     1,
+		Start:     time.Now(),
+		Status:    file.StatusAwaiting,
+		Source:    "agent",
+		AgentID:   "foo",
+		ActionID:  "bar",
+	}
+	mockUploadInfoResult(fakebulk, info)
+	mockUploadInfoResult(fakebulk, info)
+	written := false
+	mtx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "/_update_by_query") {
+			written = true
+		}
+		return mtx.Response, nil
+	}
+	handler := newRequestSigning(&cfg).middleware(hr)
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/api/fleet/uploads/"+mockUploadID+"/0", bytes.NewReader(data))
+		req.Header.Set("X-Chunk-SHA2", hex.EncodeToString(hash[:]))
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "the unsigned chunks are rejected")
+	assert.False(t, written)
+
+	req := newRequest()
+	sreq := &signing.Request{
+		Method:    req.Method,
+		URI:       req.URL.RequestURI(),
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		Nonce:     "0123456789abcdef",
+		BodyHash:  hash[:],
+	}
+	req.Header.Set(signing.HeaderTimestamp, sreq.Timestamp)
+	req.Header.Set(signing.HeaderNonce, sreq.Nonce)
+	req.Header.Set(signing.HeaderSignature, signing.Sign(signing.Key(secret, "foo"), sreq))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "the chunks are signed with the key of the agent of the upload")
+	assert.True(t, written)
+}
+
 /*
 	Upload finalization route testing
 */
@@ -619,11 +797,11 @@ func TestUploadCompleteRequiresMatchingAuth(t *testing.T) {
 				rt.ut.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
 					return nil, apikey.ErrInvalidToken
 				}
-				rt.ut.authAgent = func(r *http.Request, s *string, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+				rt.ut.authAgent = func(r *http.Request, s *string, cfg *config.Server, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 					return nil, apikey.ErrInvalidToken
 				}
 			} else {
-				rt.ut.authAgent = func(r *http.Request, s *string, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+				rt.ut.authAgent = func(r *http.Request, s *string, cfg *config.Server, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 					if *s != tc.AgentFromAPIKey { // real AuthAgent provides this facility
 						return nil, ErrAgentIdentity
 					}
@@ -992,6 +1170,175 @@ func TestUploadCompleteBadRequests(t *testing.T) {
 	assert.Equal(t, rec.Body.String(), "{\"statusCode\":400,\"error\":\"BadRequest\",\"message\":\"Bad request: unable to decode upload complete request\"}")
 }
 
+func TestUploadStatus(t *testing.T) {
+	mockUploadID := "abc123"
+
+	tests := []struct {
+		Name          string
+		Agent         string
+		Privileged    bool // whether a key that is not the agent's may read upload metadata
+		Chunks        []int
+		Status        file.Status
+		FailureReason string
+		ExpectStatus  int
+		ExpectBody    string
+	}{
+		{"No chunks received", "foo", false, nil, file.StatusProgress, "", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[],"next_chunk":0,"status":"UPLOADING","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":0,"outstanding":[0,1,2],"verification":"pending"}`},
+		{"Gap in received chunks", "foo", false, []int{2, 0}, file.StatusProgress, "", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[0,2],"next_chunk":1,"status":"UPLOADING","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":8388608,"outstanding":[1],"verification":"pending"}`},
+		{"All chunks received", "foo", false, []int{0, 1, 2}, file.StatusProgress, "", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[0,1,2],"next_chunk":3,"status":"UPLOADING","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":12582912,"outstanding":[],"verification":"pending"}`},
+		{"Completed upload is verified", "foo", false, []int{0, 1, 2}, file.StatusDone, "", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[0,1,2],"next_chunk":3,"status":"READY","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":12582912,"outstanding":[],"verification":"verified"}`},
+		{"Failed upload reports the reason", "foo", false, nil, file.StatusFail, "transithash does not match the chunk hashes", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[],"next_chunk":0,"status":"UPLOAD_ERROR","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":0,"outstanding":[0,1,2],"verification":"failed","failure_reason":"transithash does not match the chunk hashes"}`},
+		{"Operator with read privileges may query", "other", true, []int{0}, file.StatusProgress, "", http.StatusOK,
+			`{"chunk_size":4194304,"chunks":[0],"next_chunk":1,"status":"UPLOADING","upload_id":"abc123","file_size":12582912,"chunk_count":3,"bytes_received":4194304,"outstanding":[1,2],"verification":"pending"}`},
+		{"Agent ID not matching upload is rejected", "other", false, nil, file.StatusProgress, "", http.StatusForbidden, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			hr, rt, fakebulk, tx := prepareUploaderMock(t)
+			mockInfo := file.Info{
+				DocID:         "bar.foo",
+				ID:            mockUploadID,
+				ChunkSize:     file.MaxChunkSize,
+				Total:         file.MaxChunkSize * 3,
+				Count:         3,
+				Start:         time.Now().Add(-time.Minute),
+				Status:        tc.Status,
+				Source:        "agent",
+				AgentID:       "foo",
+				ActionID:      "bar",
+				FailureReason: tc.FailureReason,
+			}
+			chunks := make([]file.ChunkInfo, 0, len(tc.Chunks))
+			for _, pos := range tc.Chunks {
+				chunks = append(chunks, file.ChunkInfo{
+					Pos:  pos,
+					BID:  mockInfo.DocID,
+					Last: pos == mockInfo.Count-1,
+					Size: int(file.MaxChunkSize),
+					SHA2: "0c4a81b85a6b7ff00bde6c32e1e8be33b4b793b3b7b5cb03db93f77f7c9374d1", // sample value
+				})
+			}
+			mockUploadInfoResult(fakebulk, mockInfo)
+			mockChunkResult(fakebulk, chunks)
+
+			rt.ut.authAgent = func(r *http.Request, s *string, cfg *config.Server, b bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+				if *s != tc.Agent {
+					return nil, ErrAgentIdentity
+				}
+				return &model.Agent{Agent: &model.AgentMetadata{ID: tc.Agent}}, nil
+			}
+			rt.ut.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.Privileged))),
+				}, nil
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/fleet/uploads/"+mockUploadID, nil)
+			hr.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.ExpectStatus, rec.Code)
+			if tc.ExpectBody != "" {
+				assert.JSONEq(t, tc.ExpectBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUploadStatusUnauthenticated(t *testing.T) {
+	hr, rt, fakebulk, _ := prepareUploaderMock(t)
+	rt.ut.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+		return nil, ErrAPIKeyNotEnabled
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/fleet/uploads/abc123", nil)
+	hr.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	fakebulk.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUploadContents(t *testing.T) {
+	mockUploadID := "abc123"
+
+	tests := []struct {
+		Name         string
+		Privileged   bool
+		Status       file.Status
+		ExpectStatus int
+		ExpectBody   string
+	}{
+		{"Operator with read privileges reads the contents", true, file.StatusDone, http.StatusOK, "chunk0chunk1"},
+		{"Key without read privileges is rejected", false, file.StatusDone, http.StatusForbidden, ""},
+		{"Upload not verified is rejected", true, file.StatusProgress, http.StatusConflict, ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			hr, rt, fakebulk, tx := prepareUploaderMock(t)
+			store, err := storage.NewFilesystem(t.TempDir())
+			require.NoError(t, err)
+			rt.ut.uploader = uploader.New(nil, fakebulk, rt.ut.cache, maxFileSize, maxUploadTimer, uploader.WithStorage(store))
+
+			mockInfo := file.Info{
+				DocID:     "bar.foo",
+				ID:        mockUploadID,
+				ChunkSize: 6,
+				Total:     12,
+				Count:     2,
+				Start:     time.Now().Add(-time.Minute),
+				Status:    tc.Status,
+				Source:    "agent",
+				AgentID:   "foo",
+				ActionID:  "bar",
+			}
+			var chunks []file.ChunkInfo
+			for i, data := range []string{"chunk0", "chunk1"} {
+				_, err := store.Put(context.Background(), storage.ChunkKey(mockInfo.Source, mockInfo.DocID, i), strings.NewReader(data), int64(len(data)))
+				require.NoError(t, err)
+				chunks = append(chunks, file.ChunkInfo{Pos: i, BID: mockInfo.DocID, Last: i == 1, Size: len(data), SHA2: "0c4a81b85a6b7ff00bde6c32e1e8be33b4b793b3b7b5cb03db93f77f7c9374d1"})
+			}
+			mockUploadInfoResult(fakebulk, mockInfo)
+			mockChunkResult(fakebulk, chunks)
+
+			rt.ut.authAPIKey = func(r *http.Request, b bulk.Bulk, c cache.Cache) (*apikey.APIKey, error) {
+				return &apikey.APIKey{ID: "operator", Key: "secret"}, nil
+			}
+			tx.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"has_all_requested":%t}`, tc.Privileged))),
+				}, nil
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/fleet/uploads/"+mockUploadID+"/contents", nil)
+			hr.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.ExpectStatus, rec.Code)
+			if tc.ExpectBody != "" {
+				assert.Equal(t, tc.ExpectBody, rec.Body.String())
+				assert.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
 /*
 	Helpers and mocks
 */
@@ -1035,7 +1382,7 @@ func prepareUploaderMock(t *testing.T) (http.Handler, apiServer, *itesting.MockB
 			chunkClient: es,
 			cache:       c,
 			uploader:    uploader.New(es, fakebulk, c, maxFileSize, maxUploadTimer),
-			authAgent: func(r *http.Request, id *string, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
+			authAgent: func(r *http.Request, id *string, cfg *config.Server, bulker bulk.Bulk, c cache.Cache) (*model.Agent, error) {
 				return &model.Agent{
 					ESDocument: model.ESDocument{
 						Id: "foo",
@@ -1081,8 +1428,9 @@ func mockUploadInfoResult(bulker *itesting.MockBulk, info file.Info) {
 			"ChunkSize": info.ChunkSize,
 			"Status":    info.Status,
 		},
-		"upload_id":    info.ID,
-		"upload_start": info.Start.UnixMilli(),
+		"upload_id":      info.ID,
+		"upload_start":   info.Start.UnixMilli(),
+		"failure_reason": info.FailureReason,
 	})
 
 	bulker.On("Search",
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+)
+
+var errHTTP3WithoutTLS = errors.New("http3 requires TLS")
+
+// runHTTP3 serves the api over HTTP/3 on the UDP address addr until ctx is done. QUIC has no head-of-line blocking
+// between the streams of a connection and survives the changes of address of the agents, which helps the long
+// polls and the downloads of the agents on lossy links. The connection limit of the server does not apply.
+func (s *server) runHTTP3(ctx context.Context, addr string) error {
+	if s.cfg.TLS == nil || !s.cfg.TLS.IsEnabled() {
+		return errHTTP3WithoutTLS
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.mut.Lock()
+	s.tls, err = newTLSReloader(s.cfg.Host, s.cfg.TLS, s.cfg.ClientCertificates)
+	if err != nil {
+		s.mut.Unlock()
+		return err
+	}
+	go s.tls.watch(ctx, s.cfg.TLSReloadInterval)
+	s.h3 = &http3.Server{
+		Handler:        s.handler,
+		TLSConfig:      s.tls.serverConfig(),
+		MaxHeaderBytes: s.cfg.Limits.MaxHeaderByteSize,
+		QuicConfig: &quic.Config{
+			MaxIdleTimeout: s.cfg.Timeouts.Idle,
+			// the long polls of checkin send no data for longer than the idle timeout
+			KeepAlivePeriod: s.cfg.Timeouts.Idle / 2,
+		},
+		ConnContext: func(connCtx context.Context, _ quic.Connection) context.Context {
+			return drain.WithContext(zerolog.Ctx(ctx).WithContext(connCtx), drain.FromContext(ctx))
+		},
+	}
+	s.ctx = ctx
+	s.running = true
+	srv := s.h3
+	s.mut.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		zerolog.Ctx(ctx).Info().Msgf("Listening on %s", s.addr)
+		errCh <- srv.Serve(conn)
+	}()
+
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		// http3 has no graceful shutdown, the requests in progress are aborted
+		err = srv.Close()
+		<-errCh
+	}
+	s.mut.Lock()
+	s.running = false
+	s.mut.Unlock()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error while serving API listener: %w", err)
+	}
+	return nil
+}
+
+// altSvc advertises the HTTP/3 listener in the responses of the routes it serves, so the clients that support
+// HTTP/3 use it for their next requests.
+func altSvc(cfg *config.Server) func(http.Handler) http.Handler {
+	value := `h3=":` + strconv.Itoa(int(cfg.HTTP3Port())) + `"; ma=86400`
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor < 3 && cfg.ServesHTTP3(requestOperation(r)) {
+				w.Header().Set("Alt-Svc", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	libsconfig "github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fbuild "github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/certs"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func Test_server_HTTP3(t *testing.T) {
+	if fips.Required {
+		t.Skip("HTTP/3 requires TLS 1.3, the FIPS mode is restricted to TLS 1.2")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = testlog.SetLogger(t).WithContext(ctx)
+
+	ca := certs.GenCA(t)
+	cert := certs.GenCert(t, ca)
+	ucfg, err := yaml.NewConfig([]byte(fmt.Sprintf("enabled: true\ncertificate: %q\nkey: %q\n", certs.CertToFile(t, cert, "cert"), certs.KeyToFile(t, cert, "key"))))
+	require.NoError(t, err)
+	tlsCFG := &tlscommon.ServerConfig{}
+	require.NoError(t, tlsCFG.Unpack(libsconfig.C(*ucfg)))
+
+	port, err := ftesting.FreePort()
+	require.NoError(t, err)
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	cfg.Host = "localhost"
+	cfg.Port = port
+	cfg.TLS = tlsCFG
+	cfg.HTTP3 = config.HTTP3{Enabled: true, Routes: []string{"status"}}
+	h3Cfg, ok := cfg.HTTP3Server()
+	require.True(t, ok)
+
+	st := NewStatusT(cfg, nil, nil)
+	sm := &mockPolicyMonitor{state: client.UnitStateHealthy}
+	srv := NewServer(cfg.BindEndpoints()[0], cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h3Srv := NewServer(h3Cfg.HTTP3Endpoint(), &h3Cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.Run(ctx)
+	}()
+	go func() {
+		errCh <- h3Srv.Run(ctx)
+	}()
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(ca.Leaf)
+	tlsClientCfg := &tls.Config{RootCAs: certPool, MinVersion: tls.VersionTLS12}
+	url := fmt.Sprintf("https://localhost:%d/api/status", port)
+
+	t.Run("alt-svc advertised over TCP", func(t *testing.T) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsClientCfg}, Timeout: time.Second}
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = httpClient.Get(url) //nolint:bodyclose // closed below
+			return err == nil
+		}, 5*time.Second, 20*time.Millisecond)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf(`h3=":%d"; ma=86400`, port), resp.Header.Get("Alt-Svc"))
+	})
+
+	h3Client := &http.Client{Transport: &http3.RoundTripper{TLSClientConfig: tlsClientCfg}, Timeout: time.Second}
+	defer h3Client.Transport.(*http3.RoundTripper).Close()
+	t.Run("served over HTTP/3", func(t *testing.T) {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = h3Client.Get(url) //nolint:bodyclose // closed below
+			return err == nil
+		}, 5*time.Second, 20*time.Millisecond)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, resp.ProtoMajor)
+		assert.Empty(t, resp.Header.Get("Alt-Svc"))
+	})
+
+	t.Run("routes not served over HTTP/3", func(t *testing.T) {
+		resp, err := h3Client.Get(fmt.Sprintf("https://localhost:%d/api/fleet/agents/enroll", port))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		require.NoError(t, h3Srv.Reload(&h3Cfg))
+		noTLS := h3Cfg
+		noTLS.TLS = nil
+		require.ErrorIs(t, h3Srv.Reload(&noTLS), errTLSToggled)
+	})
+
+	cancel()
+	require.NoError(t, <-errCh)
+	require.NoError(t, <-errCh)
+}
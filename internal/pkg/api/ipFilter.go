@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// ErrAddressDenied is returned for the requests of the clients whose address is not allowed by the IP filter.
+var ErrAddressDenied = errors.New("client address is not allowed")
+
+// ipFilter rejects the requests of the clients whose address is not allowed by the rule of the server or of the
+// route, before the requests are authenticated. The address of a client is the address given by the PROXY header
+// of its connection. The peers of the Unix sockets are allowed, the socket permissions restrict them, the requests of
+// the other listeners whose address is not an IP address are denied.
+type ipFilter struct {
+	server ipRule
+	routes map[string]ipRule
+}
+
+type ipRule struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+func newIPFilter(cfg *config.IPFilter) *ipFilter {
+	f := &ipFilter{server: newIPRule(cfg.IPFilterRule), routes: make(map[string]ipRule, len(cfg.Routes))}
+	for route, r := range cfg.Routes {
+		f.routes[route] = newIPRule(r)
+	}
+	return f
+}
+
+// newIPRule returns the rule of cfg. The configuration is validated when it is unpacked, a rule that is not valid
+// denies all the addresses.
+func newIPRule(cfg config.IPFilterRule) ipRule {
+	allow, deny, err := cfg.Prefixes()
+	if err != nil {
+		return ipRule{deny: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}}
+	}
+	return ipRule{allow: allow, deny: deny}
+}
+
+// allows returns true when addr is not denied and is allowed, all the addresses that are not denied are allowed
+// when the rule allows no network.
+func (r ipRule) allows(addr netip.Addr) bool {
+	for _, prefix := range r.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(r.allow) == 0 {
+		return true
+	}
+	for _, prefix := range r.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ipFilter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil {
+			// the remote address of a Unix socket peer is not an IP address
+			if unixSocketRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cntIPDenied.Inc()
+			zerolog.Ctx(r.Context()).Warn().Err(err).Str(logger.ECSClientAddress, r.RemoteAddr).Msg("Request denied by the IP filter, the client address is not an IP address")
+			ErrorResp(w, r, ErrAddressDenied)
+			return
+		}
+		addr := addrPort.Addr().Unmap()
+		op := requestOperation(r)
+		allowed := f.server.allows(addr)
+		if rule, ok := f.routes[op]; ok && allowed {
+			allowed = rule.allows(addr)
+		}
+		if !allowed {
+			cntIPDenied.Inc()
+			zerolog.Ctx(r.Context()).Debug().Str(logger.Route, op).Str(logger.ECSClientAddress, addr.String()).Msg("Request denied by the IP filter")
+			ErrorResp(w, r, ErrAddressDenied)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unixSocketRequest returns true when r was received on a Unix socket listener.
+func unixSocketRequest(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
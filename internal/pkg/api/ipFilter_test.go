@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestIPFilter(t *testing.T) {
+	f := newIPFilter(&config.IPFilter{
+		IPFilterRule: config.IPFilterRule{Deny: []string{"192.0.2.66"}},
+		Routes: map[string]config.IPFilterRule{
+			"enroll": {Allow: []string{"192.0.2.0/24", "2001:db8::/32"}},
+		},
+	})
+	handler := f.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		path   string
+		remote string
+		local  net.Addr
+		status int
+	}{
+		{name: "route without rule", path: "/api/status", remote: "198.51.100.1:4242", status: http.StatusOK},
+		{name: "denied by the server", path: "/api/status", remote: "192.0.2.66:4242", status: http.StatusForbidden},
+		{name: "allowed by the route", path: "/api/fleet/agents/enroll", remote: "192.0.2.10:4242", status: http.StatusOK},
+		{name: "allowed IPv6", path: "/api/fleet/agents/enroll", remote: "[2001:db8::1]:4242", status: http.StatusOK},
+		{name: "IPv4-mapped address", path: "/api/fleet/agents/enroll", remote: "[::ffff:192.0.2.10]:4242", status: http.StatusOK},
+		{name: "not allowed by the route", path: "/api/fleet/agents/enroll", remote: "198.51.100.1:4242", status: http.StatusForbidden},
+		{name: "denied by the server on an allowed route", path: "/api/fleet/agents/enroll", remote: "192.0.2.66:4242", status: http.StatusForbidden},
+		{name: "unix socket peer", path: "/api/fleet/agents/enroll", remote: "@", local: &net.UnixAddr{Name: "/run/fleet-server.sock", Net: "unix"}, status: http.StatusOK},
+		{name: "address that is not an IP address", path: "/api/status", remote: "@", local: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 8220}, status: http.StatusForbidden},
+		{name: "address that is not an IP address without listener", path: "/api/status", remote: "not an address", status: http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			req.RemoteAddr = tc.remote
+			if tc.local != nil {
+				req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, tc.local))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestIPFilterInvalidRule(t *testing.T) {
+	f := newIPFilter(&config.IPFilter{IPFilterRule: config.IPFilterRule{Allow: []string{"not an address"}}})
+	handler := f.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.RemoteAddr = "192.0.2.10:4242"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
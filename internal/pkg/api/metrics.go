@@ -8,7 +8,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/elastic/elastic-agent-libs/api"
 	cfglib "github.com/elastic/elastic-agent-libs/config"
@@ -16,15 +18,23 @@ import (
 	"github.com/elastic/elastic-agent-system-metrics/report"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/rs/zerolog"
 	apmprometheus "go.elastic.co/apm/module/apmprometheus/v2"
 	"go.elastic.co/apm/v2"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/coordinator"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/gc"
 	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
 	"github.com/elastic/fleet-server/v7/version"
 )
 
@@ -34,17 +44,37 @@ var (
 	cntHTTPNew    *statsCounter
 	cntHTTPClose  *statsCounter
 	cntHTTPActive *statsGauge
-
-	cntCheckin     routeStats
-	cntEnroll      routeStats
-	cntAcks        routeStats
-	cntStatus      routeStats
-	cntUploadStart routeStats
-	cntUploadChunk routeStats
-	cntUploadEnd   routeStats
-	cntFileDeliv   routeStats
-	cntGetPGP      routeStats
-	cntArtifacts   artifactStats
+	cntIPDenied   *statsCounter
+	cntLimitScale *statsValue
+
+	cntCheckin        routeStats
+	cntEnroll         routeStats
+	cntAcks           routeStats
+	cntStatus         routeStats
+	cntUploadStart    routeStats
+	cntUploadChunk    routeStats
+	cntUploadEnd      routeStats
+	cntUploadStatus   routeStats
+	cntFileDeliv      routeStats
+	cntGetPGP         routeStats
+	cntPolicyValidate routeStats
+	cntRevoke         routeStats
+	cntProfiler       routeStats
+	cntDiagnostics    routeStats
+	cntDrain          routeStats
+	cntAgentSearch    routeStats
+	cntAgentReassign  routeStats
+	cntPolicyLeaders  routeStats
+	cntPromote        routeStats
+	cntArtifacts      artifactStats
+
+	cntSecretCache secretCacheStats
+	cntAPIKeys     apiKeyStats
+	cntCache       map[string]*cacheStats
+	cntBulker      bulkerStats
+	cntInactive    inactiveAgentsStats
+	cntLeadership  leadershipStats
+	cntMonitors    map[string]*monitorStats
 
 	infoReg sync.Once
 )
@@ -62,6 +92,10 @@ func init() {
 	cntHTTPNew = newCounter(registry, "tcp_open")
 	cntHTTPClose = newCounter(registry, "tcp_close")
 	cntHTTPActive = newGauge(registry, "tcp_active")
+	cntIPDenied = newCounter(registry, "ip_denied")
+	cntLimitScale = newValue(registry.newRegistry("limits"), "scale")
+	cntLimitScale.Set(limit.Scale())
+	limit.SetMetrics(limit.Metrics{Scale: cntLimitScale})
 
 	routesRegistry := registry.newRegistry("routes")
 
@@ -73,9 +107,72 @@ func init() {
 	cntUploadStart.Register(routesRegistry.newRegistry("uploadStart"))
 	cntUploadChunk.Register(routesRegistry.newRegistry("uploadChunk"))
 	cntUploadEnd.Register(routesRegistry.newRegistry("uploadEnd"))
+	cntUploadStatus.Register(routesRegistry.newRegistry("uploadStatus"))
 	cntFileDeliv.Register(routesRegistry.newRegistry("deliverFile"))
 	cntGetPGP.Register(routesRegistry.newRegistry("getPGPKey"))
+	cntPolicyValidate.Register(routesRegistry.newRegistry("policyValidate"))
+	cntRevoke.Register(routesRegistry.newRegistry("revoke"))
+	cntProfiler.Register(routesRegistry.newRegistry("profiler"))
+	cntDiagnostics.Register(routesRegistry.newRegistry("diagnostics"))
+	cntDrain.Register(routesRegistry.newRegistry("drain"))
+	cntAgentSearch.Register(routesRegistry.newRegistry("agentSearch"))
+	cntAgentReassign.Register(routesRegistry.newRegistry("agentReassign"))
+	cntPolicyLeaders.Register(routesRegistry.newRegistry("policyLeaders"))
+	cntPromote.Register(routesRegistry.newRegistry("promote"))
+
+	cntSecretCache.Register(registry.newRegistry("secret_cache"))
+	cntAPIKeys.Register(registry.newRegistry("api_keys"))
+	audit.SetAPIKeyMetrics(audit.APIKeyMetrics{
+		Created:     cntAPIKeys.created,
+		Updated:     cntAPIKeys.updated,
+		Invalidated: cntAPIKeys.invalidated,
+		Failed:      cntAPIKeys.failed,
+	})
+
+	cacheRegistry := registry.newRegistry("cache")
+	cntCache = make(map[string]*cacheStats, len(cache.Types))
+	cacheMetrics := make(map[string]cache.Metrics, len(cache.Types))
+	for _, typ := range cache.Types {
+		cs := &cacheStats{}
+		cs.Register(cacheRegistry.newRegistry(typ))
+		cntCache[typ] = cs
+		cacheMetrics[typ] = cs.metrics()
+	}
+	cache.SetMetrics(cacheMetrics)
+
+	cntBulker.Register(registry.newRootRegistry("bulker"))
+	bulk.SetMetrics(cntBulker.metrics())
+
+	cntInactive.Register(registry.newRootRegistry("gc").newRegistry("inactive_agents"))
+	gc.SetInactiveAgentsMetrics(gc.InactiveAgentsMetrics{
+		Unenrolled: cntInactive.unenrolled,
+		Failed:     cntInactive.failed,
+	})
 
+	cntLeadership.Register(registry.newRootRegistry("coordinator").newRegistry("leadership"))
+	coordinator.SetMetrics(coordinator.Metrics{
+		Acquired: cntLeadership.acquired,
+		Lost:     cntLeadership.lost,
+		Released: cntLeadership.released,
+		LeaseAge: cntLeadership.leaseAge,
+	})
+
+	monitorRegistry := registry.newRootRegistry("monitor")
+	cntMonitors = make(map[string]*monitorStats, len(monitoredIndices))
+	lag := make(map[string]monitor.Gauge, len(monitoredIndices))
+	for index, name := range monitoredIndices {
+		ms := &monitorStats{}
+		ms.Register(monitorRegistry.newRegistry(name))
+		cntMonitors[index] = ms
+		lag[index] = ms.lag
+	}
+	monitor.SetMetrics(monitor.Metrics{Lag: lag})
+}
+
+// monitoredIndices are the indices of the index monitors by name of their metrics.
+var monitoredIndices = map[string]string{
+	dl.FleetPolicies: "policies",
+	dl.FleetActions:  "actions",
 }
 
 // metricsRegistry wraps libbeat and prometheus registries
@@ -106,6 +203,15 @@ func (r *metricsRegistry) newRegistry(name string) *metricsRegistry {
 	}
 }
 
+// newRootRegistry returns a top level registry whose metrics are exposed with the prometheus metrics of r.
+func (r *metricsRegistry) newRootRegistry(name string) *metricsRegistry {
+	return &metricsRegistry{
+		fullName: name,
+		registry: monitoring.Default.NewRegistry(name),
+		promReg:  r.promReg,
+	}
+}
+
 // statsGauge wraps gauges for internal libbeat and prometheus
 type statsGauge struct {
 	metric *monitoring.Uint
@@ -139,6 +245,29 @@ func (g *statsGauge) Dec() {
 	g.gauge.Dec()
 }
 
+// statsValue wraps gauges that are set to a measured value for internal libbeat and prometheus
+type statsValue struct {
+	metric *monitoring.Float
+	gauge  prometheus.Gauge
+}
+
+func newValue(registry *metricsRegistry, name string) *statsValue {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: registry.fullName,
+		Name:      name,
+	})
+	registry.promReg.MustRegister(g)
+	return &statsValue{
+		metric: monitoring.NewFloat(registry.registry, name),
+		gauge:  g,
+	}
+}
+
+func (g *statsValue) Set(v float64) {
+	g.metric.Set(v)
+	g.gauge.Set(v)
+}
+
 // statsCounter wraps counters for internal libbeat and prometheus
 type statsCounter struct {
 	metric  *monitoring.Uint
@@ -167,6 +296,72 @@ func (g *statsCounter) Inc() {
 	g.counter.Inc()
 }
 
+// statsHistogram wraps histograms for prometheus, libbeat gets the count and the sum of the observations
+// and the p50, p95 and p99 quantiles estimated from the buckets.
+type statsHistogram struct {
+	count     *monitoring.Uint
+	sum       *monitoring.Float
+	histogram prometheus.Histogram
+}
+
+func newHistogram(registry *metricsRegistry, name string) *statsHistogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: registry.fullName,
+		Name:      name,
+		Buckets:   prometheus.DefBuckets,
+	})
+	registry.promReg.MustRegister(h)
+	reg := registry.registry.NewRegistry(name)
+	sh := &statsHistogram{
+		count:     monitoring.NewUint(reg, "count"),
+		sum:       monitoring.NewFloat(reg, "sum"),
+		histogram: h,
+	}
+	for name, q := range map[string]float64{"p50": 0.5, "p95": 0.95, "p99": 0.99} {
+		q := q
+		monitoring.NewFunc(reg, name, func(_ monitoring.Mode, v monitoring.Visitor) {
+			v.OnFloat(sh.quantile(q))
+		})
+	}
+	return sh
+}
+
+func (h *statsHistogram) Observe(v float64) {
+	h.count.Inc()
+	h.sum.Add(v)
+	h.histogram.Observe(v)
+}
+
+// quantile estimates the q quantile of the observations with a linear interpolation within the bucket
+// of the quantile, like the histogram_quantile function of prometheus.
+// The observations over the last bucket are estimated at the upper bound of the last bucket.
+func (h *statsHistogram) quantile(q float64) float64 {
+	var m dto.Metric
+	if err := h.histogram.Write(&m); err != nil {
+		return 0
+	}
+	total := m.GetHistogram().GetSampleCount()
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	var (
+		prevCount uint64
+		prevBound float64
+	)
+	for _, b := range m.GetHistogram().GetBucket() {
+		count := b.GetCumulativeCount()
+		if float64(count) >= rank {
+			if count == prevCount {
+				return prevBound
+			}
+			return prevBound + (b.GetUpperBound()-prevBound)*(rank-float64(prevCount))/float64(count-prevCount)
+		}
+		prevCount, prevBound = count, b.GetUpperBound()
+	}
+	return prevBound
+}
+
 // routeStats is the generic collection metrics that we collect per API route.
 type routeStats struct {
 	active    *statsGauge
@@ -177,6 +372,8 @@ type routeStats struct {
 	drop      *statsCounter
 	bodyIn    *statsCounter
 	bodyOut   *statsCounter
+	latency   *statsHistogram
+	status    map[int]*statsCounter // by the class of the response code, like 2 for 2xx
 }
 
 func (rt *routeStats) Register(registry *metricsRegistry) {
@@ -188,6 +385,11 @@ func (rt *routeStats) Register(registry *metricsRegistry) {
 	rt.drop = newCounter(registry, "drop")
 	rt.bodyIn = newCounter(registry, "body_in")
 	rt.bodyOut = newCounter(registry, "body_out")
+	rt.latency = newHistogram(registry, "latency_seconds")
+	rt.status = make(map[int]*statsCounter, 4)
+	for class := 2; class <= 5; class++ {
+		rt.status[class] = newCounter(registry, fmt.Sprintf("status_%dxx", class))
+	}
 }
 
 func (rt *routeStats) IncError(err error) {
@@ -209,17 +411,30 @@ func (rt *routeStats) IncStart() func() {
 	return rt.active.Dec
 }
 
+// Observe records the latency and the response code of a request of the route, a response that is not written is a 200.
+func (rt *routeStats) Observe(d time.Duration, status int) {
+	rt.latency.Observe(d.Seconds())
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if c, ok := rt.status[status/100]; ok {
+		c.Inc()
+	}
+}
+
 // artifactStats is the collection of metrics we collect for the artifact route.
 type artifactStats struct {
 	routeStats
 	notFound *statsCounter
 	throttle *statsCounter
+	upstream *statsCounter
 }
 
 func (rt *artifactStats) Register(registry *metricsRegistry) {
 	rt.routeStats.Register(registry)
 	rt.notFound = newCounter(registry, "not_found")
 	rt.throttle = newCounter(registry, "throttle")
+	rt.upstream = newCounter(registry, "upstream")
 }
 
 func (rt *artifactStats) IncError(err error) {
@@ -233,9 +448,166 @@ func (rt *artifactStats) IncError(err error) {
 	}
 }
 
+// secretCacheStats is the collection of metrics we collect for the policy secrets cache.
+type secretCacheStats struct {
+	hit   *statsCounter
+	miss  *statsCounter
+	evict *statsCounter
+}
+
+func (sc *secretCacheStats) Register(registry *metricsRegistry) {
+	sc.hit = newCounter(registry, "hit")
+	sc.miss = newCounter(registry, "miss")
+	sc.evict = newCounter(registry, "evict")
+}
+
+// apiKeyStats is the collection of metrics we collect for the API keys created, updated and invalidated by fleet-server.
+type apiKeyStats struct {
+	created     *statsCounter
+	updated     *statsCounter
+	invalidated *statsCounter
+	failed      *statsCounter
+}
+
+func (ak *apiKeyStats) Register(registry *metricsRegistry) {
+	ak.created = newCounter(registry, "created")
+	ak.updated = newCounter(registry, "updated")
+	ak.invalidated = newCounter(registry, "invalidated")
+	ak.failed = newCounter(registry, "failed")
+}
+
+// inactiveAgentsStats is the collection of metrics we collect for the unenrollment of inactive agents.
+type inactiveAgentsStats struct {
+	unenrolled *statsCounter
+	failed     *statsCounter
+}
+
+func (is *inactiveAgentsStats) Register(registry *metricsRegistry) {
+	is.unenrolled = newCounter(registry, "unenrolled")
+	is.failed = newCounter(registry, "failed")
+}
+
+// leadershipStats is the collection of metrics we collect for the leader election of the policies.
+type leadershipStats struct {
+	acquired *statsCounter
+	lost     *statsCounter
+	released *statsCounter
+	leaseAge *statsValue
+}
+
+func (ls *leadershipStats) Register(registry *metricsRegistry) {
+	ls.acquired = newCounter(registry, "acquired")
+	ls.lost = newCounter(registry, "lost")
+	ls.released = newCounter(registry, "released")
+	ls.leaseAge = newValue(registry, "lease_age_seconds")
+}
+
+// monitorStats is the collection of metrics we collect for an index monitor.
+type monitorStats struct {
+	lag *statsValue
+}
+
+func (ms *monitorStats) Register(registry *metricsRegistry) {
+	ms.lag = newValue(registry, "checkpoint_lag")
+}
+
+// cacheStats is the collection of metrics we collect for a type of entries of the cache.
+type cacheStats struct {
+	hit         *statsCounter
+	miss        *statsCounter
+	set         *statsCounter
+	reject      *statsCounter
+	evict       *statsCounter
+	costAdded   *statsCounter
+	costEvicted *statsCounter
+}
+
+func (cs *cacheStats) Register(registry *metricsRegistry) {
+	cs.hit = newCounter(registry, "hit")
+	cs.miss = newCounter(registry, "miss")
+	cs.set = newCounter(registry, "set")
+	cs.reject = newCounter(registry, "reject")
+	cs.evict = newCounter(registry, "evict")
+	cs.costAdded = newCounter(registry, "cost_added")
+	cs.costEvicted = newCounter(registry, "cost_evicted")
+}
+
+func (cs *cacheStats) metrics() cache.Metrics {
+	return cache.Metrics{
+		Hits:        cs.hit,
+		Misses:      cs.miss,
+		Sets:        cs.set,
+		Rejects:     cs.reject,
+		Evictions:   cs.evict,
+		CostAdded:   cs.costAdded,
+		CostEvicted: cs.costEvicted,
+	}
+}
+
+// bulkerStats is the collection of metrics we collect for the bulker queues.
+type bulkerStats struct {
+	active *statsGauge
+	queues map[string]*bulkerQueueStats
+}
+
+type bulkerQueueStats struct {
+	flushes  *statsCounter
+	failures *statsCounter
+	ops      *statsCounter
+	bytes    *statsCounter
+	latency  *statsHistogram
+}
+
+func (bs *bulkerStats) Register(registry *metricsRegistry) {
+	bs.active = newGauge(registry, "active")
+	bs.queues = make(map[string]*bulkerQueueStats, len(bulk.QueueTypes))
+	for _, typ := range bulk.QueueTypes {
+		qr := registry.newRegistry(typ)
+		bs.queues[typ] = &bulkerQueueStats{
+			flushes:  newCounter(qr, "flushes"),
+			failures: newCounter(qr, "failures"),
+			ops:      newCounter(qr, "ops"),
+			bytes:    newCounter(qr, "bytes"),
+			latency:  newHistogram(qr, "latency_seconds"),
+		}
+	}
+}
+
+func (bs *bulkerStats) metrics() bulk.Metrics {
+	m := bulk.Metrics{
+		Active: bs.active,
+		Queues: make(map[string]bulk.QueueMetrics, len(bs.queues)),
+	}
+	for typ, qs := range bs.queues {
+		m.Queues[typ] = bulk.QueueMetrics{
+			Flushes:  qs.flushes,
+			Failures: qs.failures,
+			Ops:      qs.ops,
+			Bytes:    qs.bytes,
+			Latency:  qs.latency,
+		}
+	}
+	return m
+}
+
+// SecretCacheMetrics returns the counters the policy secrets cache reports to.
+func SecretCacheMetrics() policy.SecretCacheMetrics {
+	return policy.SecretCacheMetrics{
+		Hits:      cntSecretCache.hit,
+		Misses:    cntSecretCache.miss,
+		Evictions: cntSecretCache.evict,
+	}
+}
+
+// MetricsGatherer returns the gatherer of the prometheus metrics of the /metrics endpoint.
+func MetricsGatherer() prometheus.Gatherer {
+	return registry.promReg
+}
+
 // InitMetrics initializes metrics exposure mechanisms.
 // If tracer is not nil, prometheus metrics are shipped through the tracer.
 // If cfg.http.enabled is true a /stats endpoint is created to expose libbeat metrics and a /metrics endpoint is created to expose prometheus metrics on the specified interface.
+// The prometheus metrics include the metrics of the routes, the limiters, the caches and the bulker queues.
 func InitMetrics(ctx context.Context, cfg *config.Config, bi build.Info, tracer *apm.Tracer) (*api.Server, error) {
 	if tracer != nil {
 		tracer.RegisterMetricsGatherer(apmprometheus.Wrap(registry.promReg))
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/elastic-agent-libs/api"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+)
+
+type testMetricsRouter map[string]api.HandlerFunc
+
+func (r testMetricsRouter) AddRoute(path string, h api.HandlerFunc) {
+	r[path] = h
+}
+
+func TestPrometheusEndpoint(t *testing.T) {
+	router := testMetricsRouter{}
+	attachPrometheusEndpoint(router, registry.promReg, build.Info{Version: "test"})
+	require.Contains(t, router, "/metrics")
+
+	cntBulker.queues["read"].latency.Observe(0.01)
+
+	rec := httptest.NewRecorder()
+	router["/metrics"](rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	for _, name := range []string{
+		"http_server_routes_checkin_total",
+		"http_server_routes_checkin_limit_rate",
+		"http_server_routes_checkin_latency_seconds_bucket",
+		"http_server_routes_checkin_status_5xx",
+		"http_server_cache_api_keys_hit",
+		"http_server_limits_scale",
+		"bulker_active",
+		"coordinator_leadership_acquired",
+		"coordinator_leadership_lease_age_seconds",
+		"monitor_policies_checkpoint_lag",
+		"monitor_actions_checkpoint_lag",
+		"bulker_read_flushes",
+		`bulker_read_latency_seconds_bucket{le="0.01"} 1`,
+		"service_info",
+	} {
+		assert.Contains(t, rec.Body.String(), name)
+	}
+}
+
+func TestHistogramQuantiles(t *testing.T) {
+	reg := &metricsRegistry{
+		fullName: "test",
+		registry: monitoring.NewRegistry(),
+		promReg:  prometheus.NewRegistry(),
+	}
+	h := newHistogram(reg, "latency_seconds")
+	assert.Zero(t, h.quantile(0.5), "no observations")
+
+	for i := 0; i < 90; i++ {
+		h.Observe(0.003) // in the (0.0, 0.005] bucket
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(0.75) // in the (0.5, 1] bucket
+	}
+	assert.InDelta(t, 0.0025/0.9, h.quantile(0.5), 1e-9)
+	assert.InDelta(t, 0.75, h.quantile(0.95), 1e-9)
+	assert.InDelta(t, 0.95, h.quantile(0.99), 1e-9)
+
+	h.Observe(30) // over the last bucket
+	assert.Equal(t, 10.0, h.quantile(1))
+
+	snapshot := monitoring.CollectFlatSnapshot(reg.registry, monitoring.Full, false)
+	assert.Equal(t, int64(101), snapshot.Ints["latency_seconds.count"])
+	assert.Contains(t, snapshot.Floats, "latency_seconds.p99")
+}
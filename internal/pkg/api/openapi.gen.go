@@ -49,6 +49,13 @@ const (
 	ActionSettingsLogLevelWarning ActionSettingsLogLevel = "warning"
 )
 
+// Defines values for CheckinBackoffReason.
+const (
+	Draining      CheckinBackoffReason = "draining"
+	Elasticsearch CheckinBackoffReason = "elasticsearch"
+	Limits        CheckinBackoffReason = "limits"
+)
+
 // Defines values for CheckinRequestStatus.
 const (
 	CheckinRequestStatusDegraded CheckinRequestStatus = "degraded"
@@ -84,6 +91,13 @@ const (
 	STATE        EventType = "STATE"
 )
 
+// Defines values for PolicyLeadersItemState.
+const (
+	Expired PolicyLeadersItemState = "expired"
+	Held    PolicyLeadersItemState = "held"
+	Vacant  PolicyLeadersItemState = "vacant"
+)
+
 // Defines values for StatusResponseStatus.
 const (
 	Configuring StatusResponseStatus = "configuring"
@@ -126,6 +140,13 @@ const (
 	Endpoint UploadBeginRequestSrc = "endpoint"
 )
 
+// Defines values for UploadStatusResponseVerification.
+const (
+	UploadStatusResponseVerificationFailed   UploadStatusResponseVerification = "failed"
+	UploadStatusResponseVerificationPending  UploadStatusResponseVerification = "pending"
+	UploadStatusResponseVerificationVerified UploadStatusResponseVerification = "verified"
+)
+
 // AckRequest The request an elastic-agent sends to fleet-serve to acknowledge the execution of one or more actions.
 type AckRequest struct {
 	Events []AckRequest_Events_Item `json:"events"`
@@ -212,9 +233,13 @@ type ActionCancel struct {
 type ActionInputAction = map[string]interface{}
 
 // ActionPolicyChange The POLICY_CHANGE action data.
+// Exactly one of `policy` or `policy_gzip` is set, `policy_gzip` is only used for agents that list `gzip` in the `policy_encodings` of their checkin request.
 type ActionPolicyChange struct {
 	// Policy The full policy that an agent should run after combining with local configuration/env vars.
-	Policy PolicyData `json:"policy"`
+	Policy *PolicyData `json:"policy,omitempty"`
+
+	// PolicyGzip The base64 encoded, gzip compressed JSON serialized policy data.
+	PolicyGzip *[]byte `json:"policy_gzip,omitempty"`
 }
 
 // ActionPolicyReassign The POLICY_REASSIGN action data.
@@ -260,6 +285,146 @@ type ActionUpgrade struct {
 	Version string `json:"version"`
 }
 
+// AgentReassignQuery The filters of the agents to reassign, the active agents matching all the filters that are set are reassigned.
+type AgentReassignQuery struct {
+	// LocalMetadata Match the agents whose local_metadata fields have the values, the fields are keyed by their dotted path such as os.family.
+	LocalMetadata *map[string]string `json:"local_metadata,omitempty"`
+
+	// PolicyId Match the agents of the policy.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// Status Match the agents whose last checkin status is one of the statuses.
+	Status *[]string `json:"status,omitempty"`
+
+	// Tags Match the agents that have all the tags.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// AgentReassignRequest The agents to reassign to a policy, listed by ID or matched by a query.
+type AgentReassignRequest struct {
+	// Agents The IDs of the agents, it can not be set with query.
+	Agents *[]string `json:"agents,omitempty"`
+
+	// PolicyId The policy the agents are assigned to.
+	PolicyId string `json:"policy_id"`
+
+	// Query The filters of the agents to reassign, the active agents matching all the filters that are set are reassigned.
+	Query *AgentReassignQuery `json:"query,omitempty"`
+}
+
+// AgentReassignAPIResponse The result of the reassignment of agents to a policy.
+type AgentReassignAPIResponse struct {
+	// ActionIds The IDs of the POLICY_REASSIGN actions sent to the reassigned agents.
+	ActionIds []string `json:"action_ids"`
+
+	// Failed The IDs of the agents that could not be reassigned.
+	Failed []string `json:"failed"`
+
+	// PolicyId The policy the agents are assigned to.
+	PolicyId string `json:"policy_id"`
+
+	// Reassigned The number of agents assigned to the policy, the agents already assigned to it are not counted.
+	Reassigned int `json:"reassigned"`
+}
+
+// AgentSearchItem An agent matched by a search.
+type AgentSearchItem struct {
+	// Active True if the agent is enrolled.
+	Active bool `json:"active"`
+
+	// EnrolledAt When the agent enrolled.
+	EnrolledAt string `json:"enrolled_at"`
+
+	// Id The agent ID.
+	Id string `json:"id"`
+
+	// LastCheckin When the agent last checked in.
+	LastCheckin *string `json:"last_checkin,omitempty"`
+
+	// LocalMetadata The local metadata of the agent.
+	LocalMetadata *map[string]interface{} `json:"local_metadata,omitempty"`
+
+	// PolicyId The policy of the agent.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// PolicyRevisionIdx The revision of the policy the agent runs.
+	PolicyRevisionIdx *int64 `json:"policy_revision_idx,omitempty"`
+
+	// Status The last checkin status of the agent.
+	Status *string `json:"status,omitempty"`
+
+	// Tags The tags of the agent.
+	Tags *[]string `json:"tags,omitempty"`
+
+	// Version The version of the agent.
+	Version *string `json:"version,omitempty"`
+}
+
+// AgentSearchRequest The filters of an agent search, the agents match all the filters that are set.
+type AgentSearchRequest struct {
+	// Active Match the active or the inactive agents, the active agents are matched when it is not set.
+	Active *bool `json:"active,omitempty"`
+
+	// LocalMetadata Match the agents whose local_metadata fields have the values, the fields are keyed by their dotted path such as os.family.
+	LocalMetadata *map[string]string `json:"local_metadata,omitempty"`
+
+	// PolicyId Match the agents of the policy.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// SearchAfter The search_after of the previous response, to request the next page.
+	SearchAfter *[]interface{} `json:"search_after,omitempty"`
+
+	// Size The maximum number of agents in the response, 100 when it is not set.
+	Size *int `json:"size,omitempty"`
+
+	// Status Match the agents whose last checkin status is one of the statuses.
+	Status *[]string `json:"status,omitempty"`
+
+	// Tags Match the agents that have all the tags.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// AgentSearchAPIResponse A page of the agents matched by a search, ordered by enrollment date.
+type AgentSearchAPIResponse struct {
+	Items []AgentSearchItem `json:"items"`
+
+	// SearchAfter Set when the page is full, the search_after of the request for the next page.
+	SearchAfter *[]interface{} `json:"search_after,omitempty"`
+}
+
+// AgentRevokeAPIResponse The result of revoking the credentials of an agent.
+type AgentRevokeAPIResponse struct {
+	// Id The agent ID
+	Id string `json:"id"`
+
+	// InvalidatedApiKeyIds The IDs of the access and output API keys of the agent that were invalidated.
+	InvalidatedApiKeyIds []string `json:"invalidated_api_key_ids"`
+
+	// PendingApiKeyIds The IDs of the API keys that could not be invalidated.
+	// Their invalidation is retried in the background.
+	PendingApiKeyIds []string `json:"pending_api_key_ids"`
+}
+
+// CheckinBackoff A hint to check in less often, sent while fleet-server is under pressure so the agents do not add to the load.
+type CheckinBackoff struct {
+	// Hosts The fleet-server hosts the agent is steered to while fleet-server is draining, it checks in with one of them instead of this fleet-server.
+	Hosts *[]string `json:"hosts,omitempty"`
+
+	// PollInterval The suggested minimum interval between two checkins while fleet-server is under pressure. A duration string such as "5m".
+	PollInterval string `json:"poll_interval"`
+
+	// Reason The pressure fleet-server is under.
+	// limits when the concurrent checkins or their rate are close to the checkin limits, elasticsearch when the writes to Elasticsearch are backed up. draining when fleet-server is draining before it stops.
+	Reason CheckinBackoffReason `json:"reason"`
+
+	// RetryAfter The suggested delay in seconds before the next checkin, also sent as the Retry-After header of the response.
+	RetryAfter int `json:"retry_after"`
+}
+
+// CheckinBackoffReason The pressure fleet-server is under.
+// limits when the concurrent checkins or their rate are close to the checkin limits, elasticsearch when the writes to Elasticsearch are backed up. draining when fleet-server is draining before it stops.
+type CheckinBackoffReason string
+
 // CheckinRequest defines model for checkinRequest.
 type CheckinRequest struct {
 	// AckToken The ack_token form a previous response if the agent has checked in before.
@@ -280,6 +445,11 @@ type CheckinRequest struct {
 	// Message State message, may be overridden or use the error message of a failing component.
 	Message string `json:"message"`
 
+	// PolicyEncodings An optional list of the encodings the agent can decode the policy of a POLICY_CHANGE action from.
+	// If it contains `gzip` and the rendered policy is larger than the server's compression threshold, fleet-server sends the
+	// gzip compressed policy in the `policy_gzip` attribute of the action data instead of the `policy` attribute.
+	PolicyEncodings *[]string `json:"policy_encodings,omitempty"`
+
 	// PollTimeout An optional timeout value that informs fleet-server of when a client will time out on it's checkin request.
 	// If not specified fleet-server will use the timeout values specified in the config (defaults to 5m polling and a 10m write timeout).
 	// The value, if specified is expected to be a string that is parsable by [time.ParseDuration](https://pkg.go.dev/time#ParseDuration).
@@ -306,6 +476,27 @@ type CheckinResponse struct {
 
 	// Actions A list of actions that the agent must execute.
 	Actions *[]Action `json:"actions,omitempty"`
+
+	// Backoff A hint to check in less often, sent while fleet-server is under pressure so the agents do not add to the load.
+	Backoff *CheckinBackoff `json:"backoff,omitempty"`
+}
+
+// DrainAPIResponse The drain mode of the server.
+type DrainAPIResponse struct {
+	// StartedAt When the server entered the drain mode.
+	StartedAt time.Time `json:"started_at"`
+
+	// StopsAt When the server stops, at the end of the drain period.
+	StopsAt time.Time `json:"stops_at"`
+}
+
+// PromoteAPIResponse The standby mode of the server.
+type PromoteAPIResponse struct {
+	// PromotedAt When the server was promoted, absent when the server did not start in standby.
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+
+	// Reason What promoted the server, api or config.
+	Reason *string `json:"reason,omitempty"`
 }
 
 // DiagnosticsEvent defines model for diagnosticsEvent.
@@ -389,6 +580,11 @@ type EnrollRequest struct {
 	// Deprecated:
 	SharedId *string `json:"shared_id,omitempty"`
 
+	// ServiceToken An Elasticsearch service token the agent authenticates with instead of an access API key.
+	// Requires server.service_token_auth to be enabled, the agent is bound to the service account and token name of the token.
+	// No access API key is generated and the response has an empty access_api_key.
+	ServiceToken *string `json:"service_token,omitempty"`
+
 	// Type The enrollment type of the agent.
 	// The agent only supports the PERMANENT value.
 	// In the future the enrollment type may be used to indicate agents that use fleet for reporting and monitoring, but do not use policies.
@@ -445,6 +641,10 @@ type EnrollResponseItem struct {
 	// PolicyId The policy ID that the agent is enrolled with. Decoded from the API key used in the request.
 	PolicyId string `json:"policy_id"`
 
+	// SigningKey The base64 encoded key the agent signs its requests with, only set when fleet-server verifies the request signatures.
+	// The signature is the hex encoded HMAC-SHA256 of the method, the path and query, the X-Fleet-Signature-Timestamp and X-Fleet-Signature-Nonce headers and the hex encoded SHA-256 of the body, each followed by a newline except the last, and is sent in the X-Fleet-Signature header.
+	SigningKey *string `json:"signing_key,omitempty"`
+
 	// Status Agent status from fleet-server.
 	// fleet-ui may differ.
 	//
@@ -604,7 +804,7 @@ type PolicyData struct {
 	// OutputPermissions Elasticsearch permissions that the agent requires in order to run the policy.
 	OutputPermissions *map[string]interface{} `json:"output_permissions,omitempty"`
 
-	// Outputs A map of all outputs that the agent running the policy can use to send data to.
+	// Outputs A map of all outputs that the agent running the policy can use to send data to. An output may name a fallback output in its failover.fallback attribute, fleet-server then sets failover.preferred to the output the agent should prefer.
 	Outputs *map[string]interface{} `json:"outputs,omitempty"`
 
 	// Revision The revision number of the policy. Should match revision_idx.
@@ -614,11 +814,109 @@ type PolicyData struct {
 	Signed *ActionSignature `json:"signed,omitempty" yaml:"signed"`
 }
 
+// PolicyValidateRequest A policy document to validate before it is saved
+type PolicyValidateRequest struct {
+	// Data The policy data, as it is stored in the data attribute of a .fleet-policies document
+	Data map[string]interface{} `json:"data"`
+
+	// PolicyId The ID of the policy the document belongs to, used for logging
+	PolicyId *string `json:"policy_id,omitempty"`
+}
+
+// PolicyValidateAPIResponse The result of the validation of a policy document. The policy can be dispatched to agents if there are no errors.
+type PolicyValidateAPIResponse struct {
+	// Errors Issues that would prevent the policy from being dispatched to agents
+	Errors []PolicyValidationIssue `json:"errors"`
+
+	// Valid True if no errors were found
+	Valid bool `json:"valid"`
+
+	// Warnings Issues that would not prevent the policy from being dispatched, but are likely mistakes
+	Warnings []PolicyValidationIssue `json:"warnings"`
+}
+
+// PolicyValidationIssue A problem found in a policy document
+type PolicyValidationIssue struct {
+	// Message A description of the issue
+	Message string `json:"message"`
+
+	// Path The dotted path of the policy attribute the issue relates to
+	Path string `json:"path"`
+}
+
+// PolicyLeadersItem The leader election state of a policy.
+type PolicyLeadersItem struct {
+	// CoordinatorIdx The coordinator index of the latest revision, 0 until the leader of the policy coordinates the revision and it can be rolled out.
+	CoordinatorIdx int64 `json:"coordinator_idx"`
+
+	// HeldAt When the leader last took or renewed the leadership of the policy.
+	HeldAt *time.Time `json:"held_at,omitempty"`
+
+	// LeaseExpiresAt When the leadership expires if the leader does not renew it, another fleet-server can then take it.
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+
+	// PolicyId The policy ID.
+	PolicyId string `json:"policy_id"`
+
+	// RevisionIdx The latest revision of the policy.
+	RevisionIdx int64 `json:"revision_idx"`
+
+	// ServerId The ID of the fleet-server that led the policy last.
+	ServerId *string `json:"server_id,omitempty"`
+
+	// ServerVersion The version of the fleet-server that led the policy last.
+	ServerVersion *string `json:"server_version,omitempty"`
+
+	// State held when a fleet-server holds the leadership of the policy, expired when its lease expired without being renewed and vacant when no fleet-server ever led the policy.
+	State PolicyLeadersItemState `json:"state"`
+}
+
+// PolicyLeadersItemState held when a fleet-server holds the leadership of the policy, expired when its lease expired without being renewed and vacant when no fleet-server ever led the policy.
+type PolicyLeadersItemState string
+
+// PolicyLeadersAPIResponse The leader election state of the policies.
+type PolicyLeadersAPIResponse struct {
+	Items []PolicyLeadersItem `json:"items"`
+
+	// PoliciesCheckpoint The global checkpoint of .fleet-policies processed by the fleet-server that answers, the policy changes after it are not seen by the server yet.
+	PoliciesCheckpoint *int64 `json:"policies_checkpoint,omitempty"`
+}
+
+// ProfilerRequest The runtime profiling settings to change, the settings that are not set are left unchanged.
+type ProfilerRequest struct {
+	// BlockProfileRate The rate of the block profile in nanoseconds, as set by runtime.SetBlockProfileRate. 0 turns the block profile off.
+	BlockProfileRate *int `json:"block_profile_rate,omitempty"`
+
+	// Enabled Serve the pprof endpoints under /api/fleet/debug/pprof/ on the API listener.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MutexProfileFraction On average 1/n of the mutex contention events are reported, as set by runtime.SetMutexProfileFraction. 0 turns the mutex profile off.
+	MutexProfileFraction *int `json:"mutex_profile_fraction,omitempty"`
+}
+
+// ProfilerAPIResponse The runtime profiling settings in effect.
+type ProfilerAPIResponse struct {
+	// BlockProfileRate The rate of the block profile in nanoseconds.
+	BlockProfileRate int `json:"block_profile_rate"`
+
+	// Enabled True if the pprof endpoints are served on the API listener.
+	Enabled bool `json:"enabled"`
+
+	// MutexProfileFraction The fraction of the mutex contention events that are reported.
+	MutexProfileFraction int `json:"mutex_profile_fraction"`
+}
+
 // StatusAPIResponse Status response information.
 type StatusAPIResponse struct {
+	// Dependencies Health of the dependencies of fleet-server, included in the response to an authorized verbose status request.
+	Dependencies *StatusResponseDependencies `json:"dependencies,omitempty"`
+
 	// Name Service name.
 	Name string `json:"name"`
 
+	// Policies The readiness of the policies with a Fleet Server integration, included in the response to an authorized status request.
+	Policies *[]StatusResponsePolicy `json:"policies,omitempty"`
+
 	// Status A Unit state that fleet-server may report.
 	// Unit state is defined in the elastic-agent-client specification.
 	Status StatusResponseStatus `json:"status"`
@@ -627,6 +925,96 @@ type StatusAPIResponse struct {
 	Version *StatusResponseVersion `json:"version,omitempty"`
 }
 
+// StatusResponseBulker State of the bulker, the batching of the Elasticsearch operations.
+type StatusResponseBulker struct {
+	// QueueDepth The number of operations queued and not flushed to Elasticsearch yet.
+	QueueDepth int `json:"queue_depth"`
+}
+
+// StatusResponseCoordinator State of the leader election of the policies.
+type StatusResponseCoordinator struct {
+	// Checked The date-time leadership was last ensured.
+	Checked *string `json:"checked,omitempty"`
+
+	// Leading The number of policies the fleet-server leads.
+	Leading int `json:"leading"`
+
+	// Members The number of fleet-servers the policies are sharded across, absent when sharding is disabled.
+	Members *int `json:"members,omitempty"`
+}
+
+// StatusResponseDependencies Health of the dependencies of fleet-server, included in the response to an authorized verbose status request.
+type StatusResponseDependencies struct {
+	// Bulker State of the bulker, the batching of the Elasticsearch operations.
+	Bulker *StatusResponseBulker `json:"bulker,omitempty"`
+
+	// Coordinator State of the leader election of the policies.
+	Coordinator *StatusResponseCoordinator `json:"coordinator,omitempty"`
+
+	// Elasticsearch Health of the Elasticsearch cluster, measured with a ping.
+	Elasticsearch *StatusResponseElasticsearch `json:"elasticsearch,omitempty"`
+
+	// Limits The limits of concurrent requests and connections that are configured.
+	Limits *[]StatusResponseLimit `json:"limits,omitempty"`
+
+	// PolicyMonitor State of the dispatch of the policy changes to the agents.
+	PolicyMonitor *StatusResponsePolicyMonitor `json:"policy_monitor,omitempty"`
+}
+
+// StatusResponseElasticsearch Health of the Elasticsearch cluster, measured with a ping.
+type StatusResponseElasticsearch struct {
+	// Available True when the ping succeeded.
+	Available bool `json:"available"`
+
+	// Error Why the ping failed.
+	Error *string `json:"error,omitempty"`
+
+	// LatencyMs The round trip of the ping in milliseconds.
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// StatusResponseLimit Saturation of the limit of concurrent requests of a route, or of the connections.
+type StatusResponseLimit struct {
+	// Active The number of requests or connections in progress.
+	Active int `json:"active"`
+
+	// Max The configured limit.
+	Max int `json:"max"`
+
+	// Name The route, like checkin, or connections for the connection limit.
+	Name string `json:"name"`
+
+	// Saturation The ratio of active to max.
+	Saturation float64 `json:"saturation"`
+}
+
+// StatusResponsePolicy Readiness of a policy with a Fleet Server integration, included in the response to an authorized status request.
+type StatusResponsePolicy struct {
+	// AgentsPending The number of active agents of the policy that have not received the latest revision yet.
+	AgentsPending int `json:"agents_pending"`
+
+	// Id The policy ID.
+	Id string `json:"id"`
+
+	// Message Why the policy is not ready.
+	Message *string `json:"message,omitempty"`
+
+	// OutputsPrepared True when the permissions needed to create the output API keys are defined for all the Elasticsearch outputs of the policy.
+	OutputsPrepared bool `json:"outputs_prepared"`
+
+	// Ready True when the outputs of the policy are prepared and all its active agents run the latest revision.
+	Ready bool `json:"ready"`
+
+	// Revision The latest revision of the policy.
+	Revision int64 `json:"revision"`
+}
+
+// StatusResponsePolicyMonitor State of the dispatch of the policy changes to the agents.
+type StatusResponsePolicyMonitor struct {
+	// DispatchLagMs For how long the oldest pending policy change has been waiting for dispatch, in milliseconds.
+	DispatchLagMs int64 `json:"dispatch_lag_ms"`
+}
+
 // StatusResponseStatus A Unit state that fleet-server may report.
 // Unit state is defined in the elastic-agent-client specification.
 type StatusResponseStatus string
@@ -792,6 +1180,9 @@ type UploadBeginAPIResponse struct {
 	// ChunkSize The required size (in bytes) that the file must be segmented into for each chunk
 	ChunkSize int64 `json:"chunk_size"`
 
+	// MaxParallelChunks The number of chunks that may be uploaded at once. Chunks may be uploaded in any order. Omitted if there is no limit.
+	MaxParallelChunks *int `json:"max_parallel_chunks,omitempty"`
+
 	// UploadId A unique identifier for the ensuing upload operation
 	UploadId string `json:"upload_id"`
 }
@@ -805,6 +1196,45 @@ type UploadCompleteRequest struct {
 	} `json:"transithash"`
 }
 
+// UploadStatusAPIResponse The state of an upload operation, used by agents to resume an interrupted upload and by operators to debug uploads that do not complete
+type UploadStatusAPIResponse struct {
+	// BytesReceived The number of bytes of file contents received so far
+	BytesReceived int64 `json:"bytes_received"`
+
+	// ChunkCount The number of chunks the file is made of
+	ChunkCount int `json:"chunk_count"`
+
+	// ChunkSize The required size (in bytes) that the file must be segmented into for each chunk
+	ChunkSize int64 `json:"chunk_size"`
+
+	// Chunks The positions of the chunks that have been received, in ascending order
+	Chunks []int `json:"chunks"`
+
+	// FailureReason Why the upload failed, only present for failed uploads
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	// FileSize The size of the file in bytes
+	FileSize int64 `json:"file_size"`
+
+	// NextChunk The position of the first chunk that has not been received. Equal to the number of chunks in the file if all chunks have been received.
+	NextChunk int `json:"next_chunk"`
+
+	// Outstanding The positions of the chunks that have not been received, in ascending order
+	Outstanding []int `json:"outstanding"`
+
+	// Status The status of the upload operation
+	Status string `json:"status"`
+
+	// UploadId The upload operation identifier
+	UploadId string `json:"upload_id"`
+
+	// Verification Whether the file contents have been verified against the transithash sent to complete the upload. pending until the upload is completed, verified once it is done, failed if the upload failed.
+	Verification UploadStatusResponseVerification `json:"verification"`
+}
+
+// UploadStatusResponseVerification Whether the file contents have been verified against the transithash sent to complete the upload. pending until the upload is completed, verified once it is done, failed if the upload failed.
+type UploadStatusResponseVerification string
+
 // ApiVersion defines model for apiVersion.
 type ApiVersion = string
 
@@ -861,6 +1291,24 @@ type AgentEnrollParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// AgentReassignParams defines parameters for AgentReassign.
+type AgentReassignParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// AgentSearchParams defines parameters for AgentSearch.
+type AgentSearchParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // AgentAcksParams defines parameters for AgentAcks.
 type AgentAcksParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -889,6 +1337,15 @@ type AgentCheckinParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// AgentRevokeParams defines parameters for AgentRevoke.
+type AgentRevokeParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // ArtifactParams defines parameters for Artifact.
 type ArtifactParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -898,6 +1355,24 @@ type ArtifactParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// GetDiagnosticsParams defines parameters for GetDiagnostics.
+type GetDiagnosticsParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// DrainParams defines parameters for Drain.
+type DrainParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // GetFileParams defines parameters for GetFile.
 type GetFileParams struct {
 	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
@@ -907,6 +1382,42 @@ type GetFileParams struct {
 	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
 }
 
+// GetPolicyLeadersParams defines parameters for GetPolicyLeaders.
+type GetPolicyLeadersParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// PolicyValidateParams defines parameters for PolicyValidate.
+type PolicyValidateParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// UpdateProfilerParams defines parameters for UpdateProfiler.
+type UpdateProfilerParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// PromoteParams defines parameters for Promote.
+type PromoteParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadBeginParams defines parameters for UploadBegin.
 type UploadBeginParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -916,6 +1427,15 @@ type UploadBeginParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// UploadStatusParams defines parameters for UploadStatus.
+type UploadStatusParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadCompleteParams defines parameters for UploadComplete.
 type UploadCompleteParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -925,9 +1445,19 @@ type UploadCompleteParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// UploadContentsParams defines parameters for UploadContents.
+type UploadContentsParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadChunkParams defines parameters for UploadChunk.
 type UploadChunkParams struct {
-	// XChunkSHA2 the SHA256 hash of the body contents for this request
+	// XChunkSHA2 The hex encoded SHA256 hash of the body contents for this request.
+	// A chunk whose contents do not match this hash is rejected with a 400 `ErrHashMismatch` and may be uploaded again.
 	XChunkSHA2 string `json:"X-Chunk-SHA2"`
 
 	// XRequestId The request tracking ID for APM.
@@ -939,6 +1469,9 @@ type UploadChunkParams struct {
 
 // StatusParams defines parameters for Status.
 type StatusParams struct {
+	// Verbose Include the health of the dependencies of fleet-server in the response of an authenticated request.
+	Verbose *bool `form:"verbose,omitempty" json:"verbose,omitempty"`
+
 	// XRequestId The request tracking ID for APM.
 	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
 
@@ -949,12 +1482,24 @@ type StatusParams struct {
 // AgentEnrollJSONRequestBody defines body for AgentEnroll for application/json ContentType.
 type AgentEnrollJSONRequestBody = EnrollRequest
 
+// AgentReassignJSONRequestBody defines body for AgentReassign for application/json ContentType.
+type AgentReassignJSONRequestBody = AgentReassignRequest
+
+// AgentSearchJSONRequestBody defines body for AgentSearch for application/json ContentType.
+type AgentSearchJSONRequestBody = AgentSearchRequest
+
 // AgentAcksJSONRequestBody defines body for AgentAcks for application/json ContentType.
 type AgentAcksJSONRequestBody = AckRequest
 
 // AgentCheckinJSONRequestBody defines body for AgentCheckin for application/json ContentType.
 type AgentCheckinJSONRequestBody = CheckinRequest
 
+// PolicyValidateJSONRequestBody defines body for PolicyValidate for application/json ContentType.
+type PolicyValidateJSONRequestBody = PolicyValidateRequest
+
+// UpdateProfilerJSONRequestBody defines body for UpdateProfiler for application/json ContentType.
+type UpdateProfilerJSONRequestBody = ProfilerRequest
+
 // UploadBeginJSONRequestBody defines body for UploadBegin for application/json ContentType.
 type UploadBeginJSONRequestBody = UploadBeginRequest
 
@@ -1617,23 +2162,58 @@ type ServerInterface interface {
 	// (POST /api/fleet/agents/enroll)
 	AgentEnroll(w http.ResponseWriter, r *http.Request, params AgentEnrollParams)
 
+	// Reassign agents to a policy
+	// (POST /api/fleet/agents/reassign)
+	AgentReassign(w http.ResponseWriter, r *http.Request, params AgentReassignParams)
+
+	// Search the agents
+	// (POST /api/fleet/agents/search)
+	AgentSearch(w http.ResponseWriter, r *http.Request, params AgentSearchParams)
+
 	// (POST /api/fleet/agents/{id}/acks)
 	AgentAcks(w http.ResponseWriter, r *http.Request, id string, params AgentAcksParams)
 
 	// (POST /api/fleet/agents/{id}/checkin)
 	AgentCheckin(w http.ResponseWriter, r *http.Request, id string, params AgentCheckinParams)
+	// Revoke the credentials of an agent
+	// (POST /api/fleet/agents/{id}/revoke)
+	AgentRevoke(w http.ResponseWriter, r *http.Request, id string, params AgentRevokeParams)
 
 	// (GET /api/fleet/artifacts/{id}/{sha2})
 	Artifact(w http.ResponseWriter, r *http.Request, id string, sha2 string, params ArtifactParams)
+	// Collect a diagnostics bundle
+	// (GET /api/fleet/diagnostics)
+	GetDiagnostics(w http.ResponseWriter, r *http.Request, params GetDiagnosticsParams)
+	// Drain the server
+	// (POST /api/fleet/drain)
+	Drain(w http.ResponseWriter, r *http.Request, params DrainParams)
 	// retrieve stored file for integration
 	// (GET /api/fleet/file/{id})
 	GetFile(w http.ResponseWriter, r *http.Request, id string, params GetFileParams)
+	// Get the leaders of the policies
+	// (GET /api/fleet/policies/leaders)
+	GetPolicyLeaders(w http.ResponseWriter, r *http.Request, params GetPolicyLeadersParams)
+	// Validate a policy document
+	// (POST /api/fleet/policies/validate)
+	PolicyValidate(w http.ResponseWriter, r *http.Request, params PolicyValidateParams)
+	// Change the runtime profiling settings
+	// (PUT /api/fleet/profiler)
+	UpdateProfiler(w http.ResponseWriter, r *http.Request, params UpdateProfilerParams)
+	// Promote the server
+	// (POST /api/fleet/promote)
+	Promote(w http.ResponseWriter, r *http.Request, params PromoteParams)
 	// Initiate a file upload process
 	// (POST /api/fleet/uploads)
 	UploadBegin(w http.ResponseWriter, r *http.Request, params UploadBeginParams)
+	// Retrieve the state of a file upload process
+	// (GET /api/fleet/uploads/{id})
+	UploadStatus(w http.ResponseWriter, r *http.Request, id string, params UploadStatusParams)
 	// Complete a file upload process
 	// (POST /api/fleet/uploads/{id})
 	UploadComplete(w http.ResponseWriter, r *http.Request, id string, params UploadCompleteParams)
+	// Retrieve the contents of a completed file upload
+	// (GET /api/fleet/uploads/{id}/contents)
+	UploadContents(w http.ResponseWriter, r *http.Request, id string, params UploadContentsParams)
 	// Upload a section of file data
 	// (PUT /api/fleet/uploads/{id}/{chunkNum})
 	UploadChunk(w http.ResponseWriter, r *http.Request, id string, chunkNum int, params UploadChunkParams)
@@ -1657,6 +2237,18 @@ func (_ Unimplemented) AgentEnroll(w http.ResponseWriter, r *http.Request, param
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Reassign agents to a policy
+// (POST /api/fleet/agents/reassign)
+func (_ Unimplemented) AgentReassign(w http.ResponseWriter, r *http.Request, params AgentReassignParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Search the agents
+// (POST /api/fleet/agents/search)
+func (_ Unimplemented) AgentSearch(w http.ResponseWriter, r *http.Request, params AgentSearchParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // (POST /api/fleet/agents/{id}/acks)
 func (_ Unimplemented) AgentAcks(w http.ResponseWriter, r *http.Request, id string, params AgentAcksParams) {
 	w.WriteHeader(http.StatusNotImplemented)
@@ -1667,29 +2259,83 @@ func (_ Unimplemented) AgentCheckin(w http.ResponseWriter, r *http.Request, id s
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Revoke the credentials of an agent
+// (POST /api/fleet/agents/{id}/revoke)
+func (_ Unimplemented) AgentRevoke(w http.ResponseWriter, r *http.Request, id string, params AgentRevokeParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // (GET /api/fleet/artifacts/{id}/{sha2})
 func (_ Unimplemented) Artifact(w http.ResponseWriter, r *http.Request, id string, sha2 string, params ArtifactParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-// retrieve stored file for integration
-// (GET /api/fleet/file/{id})
+// Collect a diagnostics bundle
+// (GET /api/fleet/diagnostics)
+func (_ Unimplemented) GetDiagnostics(w http.ResponseWriter, r *http.Request, params GetDiagnosticsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Drain the server
+// (POST /api/fleet/drain)
+func (_ Unimplemented) Drain(w http.ResponseWriter, r *http.Request, params DrainParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// retrieve stored file for integration
+// (GET /api/fleet/file/{id})
 func (_ Unimplemented) GetFile(w http.ResponseWriter, r *http.Request, id string, params GetFileParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Get the leaders of the policies
+// (GET /api/fleet/policies/leaders)
+func (_ Unimplemented) GetPolicyLeaders(w http.ResponseWriter, r *http.Request, params GetPolicyLeadersParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Validate a policy document
+// (POST /api/fleet/policies/validate)
+func (_ Unimplemented) PolicyValidate(w http.ResponseWriter, r *http.Request, params PolicyValidateParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Change the runtime profiling settings
+// (PUT /api/fleet/profiler)
+func (_ Unimplemented) UpdateProfiler(w http.ResponseWriter, r *http.Request, params UpdateProfilerParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Promote the server
+// (POST /api/fleet/promote)
+func (_ Unimplemented) Promote(w http.ResponseWriter, r *http.Request, params PromoteParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Initiate a file upload process
 // (POST /api/fleet/uploads)
 func (_ Unimplemented) UploadBegin(w http.ResponseWriter, r *http.Request, params UploadBeginParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Retrieve the state of a file upload process
+// (GET /api/fleet/uploads/{id})
+func (_ Unimplemented) UploadStatus(w http.ResponseWriter, r *http.Request, id string, params UploadStatusParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Complete a file upload process
 // (POST /api/fleet/uploads/{id})
 func (_ Unimplemented) UploadComplete(w http.ResponseWriter, r *http.Request, id string, params UploadCompleteParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Retrieve the contents of a completed file upload
+// (GET /api/fleet/uploads/{id}/contents)
+func (_ Unimplemented) UploadContents(w http.ResponseWriter, r *http.Request, id string, params UploadContentsParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Upload a section of file data
 // (PUT /api/fleet/uploads/{id}/{chunkNum})
 func (_ Unimplemented) UploadChunk(w http.ResponseWriter, r *http.Request, id string, chunkNum int, params UploadChunkParams) {
@@ -1719,34 +2365,773 @@ func (siw *ServerInterfaceWrapper) GetPGPKey(w http.ResponseWriter, r *http.Requ
 	// ------------- Path parameter "major" -------------
 	var major int
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "major", runtime.ParamLocationPath, chi.URLParam(r, "major"), &major)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "major", Err: err})
-		return
+	err = runtime.BindStyledParameterWithLocation("simple", false, "major", runtime.ParamLocationPath, chi.URLParam(r, "major"), &major)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "major", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "minor" -------------
+	var minor int
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "minor", runtime.ParamLocationPath, chi.URLParam(r, "minor"), &minor)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "minor", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "patch" -------------
+	var patch int
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "patch", runtime.ParamLocationPath, chi.URLParam(r, "patch"), &patch)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "patch", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetPGPKeyParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetPGPKey(w, r, major, minor, patch, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentEnroll operation middleware
+func (siw *ServerInterfaceWrapper) AgentEnroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentEnrollParams
+
+	headers := r.Header
+
+	// ------------- Required header parameter "User-Agent" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("User-Agent")]; found {
+		var UserAgent UserAgent
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "User-Agent", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, valueList[0], &UserAgent)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "User-Agent", Err: err})
+			return
+		}
+
+		params.UserAgent = UserAgent
+
+	} else {
+		err := fmt.Errorf("Header parameter User-Agent is required, but not found")
+		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "User-Agent", Err: err})
+		return
+	}
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentEnroll(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentReassign operation middleware
+func (siw *ServerInterfaceWrapper) AgentReassign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentReassignParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentReassign(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentSearch operation middleware
+func (siw *ServerInterfaceWrapper) AgentSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentSearchParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentSearch(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentAcks operation middleware
+func (siw *ServerInterfaceWrapper) AgentAcks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentAcksParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentAcks(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentCheckin operation middleware
+func (siw *ServerInterfaceWrapper) AgentCheckin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentCheckinParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "Accept-Encoding" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Accept-Encoding")]; found {
+		var AcceptEncoding string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Accept-Encoding", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "Accept-Encoding", runtime.ParamLocationHeader, valueList[0], &AcceptEncoding)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Accept-Encoding", Err: err})
+			return
+		}
+
+		params.AcceptEncoding = &AcceptEncoding
+
+	}
+
+	// ------------- Required header parameter "User-Agent" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("User-Agent")]; found {
+		var UserAgent UserAgent
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "User-Agent", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, valueList[0], &UserAgent)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "User-Agent", Err: err})
+			return
+		}
+
+		params.UserAgent = UserAgent
+
+	} else {
+		err := fmt.Errorf("Header parameter User-Agent is required, but not found")
+		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "User-Agent", Err: err})
+		return
+	}
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentCheckin(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AgentRevoke operation middleware
+func (siw *ServerInterfaceWrapper) AgentRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AgentRevokeParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AgentRevoke(w, r, id, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Artifact operation middleware
+func (siw *ServerInterfaceWrapper) Artifact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "sha2" -------------
+	var sha2 string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "sha2", runtime.ParamLocationPath, chi.URLParam(r, "sha2"), &sha2)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sha2", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ArtifactParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.Artifact(w, r, id, sha2, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetDiagnostics operation middleware
+func (siw *ServerInterfaceWrapper) GetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetDiagnosticsParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDiagnostics(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Drain operation middleware
+func (siw *ServerInterfaceWrapper) Drain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DrainParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			return
+		}
+
+		params.XRequestId = &XRequestId
+
+	}
+
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			return
+		}
+
+		params.ElasticApiVersion = &ElasticApiVersion
+
 	}
 
-	// ------------- Path parameter "minor" -------------
-	var minor int
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.Drain(w, r, params)
+	}))
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "minor", runtime.ParamLocationPath, chi.URLParam(r, "minor"), &minor)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "minor", Err: err})
-		return
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	// ------------- Path parameter "patch" -------------
-	var patch int
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
 
-	err = runtime.BindStyledParameterWithLocation("simple", false, "patch", runtime.ParamLocationPath, chi.URLParam(r, "patch"), &patch)
+// GetFile operation middleware
+func (siw *ServerInterfaceWrapper) GetFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "patch", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
 		return
 	}
 
-	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetPGPKeyParams
+	var params GetFileParams
 
 	headers := r.Header
 
@@ -1789,7 +3174,7 @@ func (siw *ServerInterfaceWrapper) GetPGPKey(w http.ResponseWriter, r *http.Requ
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetPGPKey(w, r, major, minor, patch, params)
+		siw.Handler.GetFile(w, r, id, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -1799,8 +3184,8 @@ func (siw *ServerInterfaceWrapper) GetPGPKey(w http.ResponseWriter, r *http.Requ
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// AgentEnroll operation middleware
-func (siw *ServerInterfaceWrapper) AgentEnroll(w http.ResponseWriter, r *http.Request) {
+// GetPolicyLeaders operation middleware
+func (siw *ServerInterfaceWrapper) GetPolicyLeaders(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
@@ -1808,33 +3193,10 @@ func (siw *ServerInterfaceWrapper) AgentEnroll(w http.ResponseWriter, r *http.Re
 	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params AgentEnrollParams
+	var params GetPolicyLeadersParams
 
 	headers := r.Header
 
-	// ------------- Required header parameter "User-Agent" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("User-Agent")]; found {
-		var UserAgent UserAgent
-		n := len(valueList)
-		if n != 1 {
-			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "User-Agent", Count: n})
-			return
-		}
-
-		err = runtime.BindStyledParameterWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, valueList[0], &UserAgent)
-		if err != nil {
-			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "User-Agent", Err: err})
-			return
-		}
-
-		params.UserAgent = UserAgent
-
-	} else {
-		err := fmt.Errorf("Header parameter User-Agent is required, but not found")
-		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "User-Agent", Err: err})
-		return
-	}
-
 	// ------------- Optional header parameter "X-Request-Id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
 		var XRequestId RequestId
@@ -1874,7 +3236,7 @@ func (siw *ServerInterfaceWrapper) AgentEnroll(w http.ResponseWriter, r *http.Re
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.AgentEnroll(w, r, params)
+		siw.Handler.GetPolicyLeaders(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -1884,25 +3246,16 @@ func (siw *ServerInterfaceWrapper) AgentEnroll(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// AgentAcks operation middleware
-func (siw *ServerInterfaceWrapper) AgentAcks(w http.ResponseWriter, r *http.Request) {
+// PolicyValidate operation middleware
+func (siw *ServerInterfaceWrapper) PolicyValidate(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
-	// ------------- Path parameter "id" -------------
-	var id string
-
-	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
-		return
-	}
-
-	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params AgentAcksParams
+	var params PolicyValidateParams
 
 	headers := r.Header
 
@@ -1945,7 +3298,7 @@ func (siw *ServerInterfaceWrapper) AgentAcks(w http.ResponseWriter, r *http.Requ
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.AgentAcks(w, r, id, params)
+		siw.Handler.PolicyValidate(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -1955,70 +3308,81 @@ func (siw *ServerInterfaceWrapper) AgentAcks(w http.ResponseWriter, r *http.Requ
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// AgentCheckin operation middleware
-func (siw *ServerInterfaceWrapper) AgentCheckin(w http.ResponseWriter, r *http.Request) {
+// UpdateProfiler operation middleware
+func (siw *ServerInterfaceWrapper) UpdateProfiler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
-	// ------------- Path parameter "id" -------------
-	var id string
-
-	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
-		return
-	}
-
-	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params AgentCheckinParams
+	var params UpdateProfilerParams
 
 	headers := r.Header
 
-	// ------------- Optional header parameter "Accept-Encoding" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("Accept-Encoding")]; found {
-		var AcceptEncoding string
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
 		n := len(valueList)
 		if n != 1 {
-			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "Accept-Encoding", Count: n})
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
 			return
 		}
 
-		err = runtime.BindStyledParameterWithLocation("simple", false, "Accept-Encoding", runtime.ParamLocationHeader, valueList[0], &AcceptEncoding)
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
 		if err != nil {
-			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "Accept-Encoding", Err: err})
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
 			return
 		}
 
-		params.AcceptEncoding = &AcceptEncoding
+		params.XRequestId = &XRequestId
 
 	}
 
-	// ------------- Required header parameter "User-Agent" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("User-Agent")]; found {
-		var UserAgent UserAgent
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
 		n := len(valueList)
 		if n != 1 {
-			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "User-Agent", Count: n})
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
 			return
 		}
 
-		err = runtime.BindStyledParameterWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, valueList[0], &UserAgent)
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
 		if err != nil {
-			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "User-Agent", Err: err})
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
 			return
 		}
 
-		params.UserAgent = UserAgent
+		params.ElasticApiVersion = &ElasticApiVersion
 
-	} else {
-		err := fmt.Errorf("Header parameter User-Agent is required, but not found")
-		siw.ErrorHandlerFunc(w, r, &RequiredHeaderError{ParamName: "User-Agent", Err: err})
-		return
 	}
 
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateProfiler(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Promote operation middleware
+func (siw *ServerInterfaceWrapper) Promote(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PromoteParams
+
+	headers := r.Header
+
 	// ------------- Optional header parameter "X-Request-Id" -------------
 	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
 		var XRequestId RequestId
@@ -2058,7 +3422,7 @@ func (siw *ServerInterfaceWrapper) AgentCheckin(w http.ResponseWriter, r *http.R
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.AgentCheckin(w, r, id, params)
+		siw.Handler.Promote(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -2068,34 +3432,16 @@ func (siw *ServerInterfaceWrapper) AgentCheckin(w http.ResponseWriter, r *http.R
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// Artifact operation middleware
-func (siw *ServerInterfaceWrapper) Artifact(w http.ResponseWriter, r *http.Request) {
+// UploadBegin operation middleware
+func (siw *ServerInterfaceWrapper) UploadBegin(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
-	// ------------- Path parameter "id" -------------
-	var id string
-
-	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
-		return
-	}
-
-	// ------------- Path parameter "sha2" -------------
-	var sha2 string
-
-	err = runtime.BindStyledParameterWithLocation("simple", false, "sha2", runtime.ParamLocationPath, chi.URLParam(r, "sha2"), &sha2)
-	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sha2", Err: err})
-		return
-	}
-
 	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params ArtifactParams
+	var params UploadBeginParams
 
 	headers := r.Header
 
@@ -2138,7 +3484,7 @@ func (siw *ServerInterfaceWrapper) Artifact(w http.ResponseWriter, r *http.Reque
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.Artifact(w, r, id, sha2, params)
+		siw.Handler.UploadBegin(w, r, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -2148,8 +3494,8 @@ func (siw *ServerInterfaceWrapper) Artifact(w http.ResponseWriter, r *http.Reque
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// GetFile operation middleware
-func (siw *ServerInterfaceWrapper) GetFile(w http.ResponseWriter, r *http.Request) {
+// UploadStatus operation middleware
+func (siw *ServerInterfaceWrapper) UploadStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
@@ -2165,51 +3511,53 @@ func (siw *ServerInterfaceWrapper) GetFile(w http.ResponseWriter, r *http.Reques
 
 	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
 
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetFileParams
+	var params UploadStatusParams
 
 	headers := r.Header
 
-	// ------------- Optional header parameter "elastic-api-version" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
-		var ElasticApiVersion ApiVersion
+	// ------------- Optional header parameter "X-Request-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
+		var XRequestId RequestId
 		n := len(valueList)
 		if n != 1 {
-			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
 			return
 		}
 
-		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
 		if err != nil {
-			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
 			return
 		}
 
-		params.ElasticApiVersion = &ElasticApiVersion
+		params.XRequestId = &XRequestId
 
 	}
 
-	// ------------- Optional header parameter "X-Request-Id" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("X-Request-Id")]; found {
-		var XRequestId RequestId
+	// ------------- Optional header parameter "elastic-api-version" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("elastic-api-version")]; found {
+		var ElasticApiVersion ApiVersion
 		n := len(valueList)
 		if n != 1 {
-			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "X-Request-Id", Count: n})
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "elastic-api-version", Count: n})
 			return
 		}
 
-		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, valueList[0], &XRequestId)
+		err = runtime.BindStyledParameterWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, valueList[0], &ElasticApiVersion)
 		if err != nil {
-			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "X-Request-Id", Err: err})
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "elastic-api-version", Err: err})
 			return
 		}
 
-		params.XRequestId = &XRequestId
+		params.ElasticApiVersion = &ElasticApiVersion
 
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetFile(w, r, id, params)
+		siw.Handler.UploadStatus(w, r, id, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -2219,16 +3567,25 @@ func (siw *ServerInterfaceWrapper) GetFile(w http.ResponseWriter, r *http.Reques
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// UploadBegin operation middleware
-func (siw *ServerInterfaceWrapper) UploadBegin(w http.ResponseWriter, r *http.Request) {
+// UploadComplete operation middleware
+func (siw *ServerInterfaceWrapper) UploadComplete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, chi.URLParam(r, "id"), &id)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
 	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params UploadBeginParams
+	var params UploadCompleteParams
 
 	headers := r.Header
 
@@ -2271,7 +3628,7 @@ func (siw *ServerInterfaceWrapper) UploadBegin(w http.ResponseWriter, r *http.Re
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.UploadBegin(w, r, params)
+		siw.Handler.UploadComplete(w, r, id, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -2281,8 +3638,8 @@ func (siw *ServerInterfaceWrapper) UploadBegin(w http.ResponseWriter, r *http.Re
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// UploadComplete operation middleware
-func (siw *ServerInterfaceWrapper) UploadComplete(w http.ResponseWriter, r *http.Request) {
+// UploadContents operation middleware
+func (siw *ServerInterfaceWrapper) UploadContents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
@@ -2296,10 +3653,10 @@ func (siw *ServerInterfaceWrapper) UploadComplete(w http.ResponseWriter, r *http
 		return
 	}
 
-	ctx = context.WithValue(ctx, AgentApiKeyScopes, []string{})
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params UploadCompleteParams
+	var params UploadContentsParams
 
 	headers := r.Header
 
@@ -2342,7 +3699,7 @@ func (siw *ServerInterfaceWrapper) UploadComplete(w http.ResponseWriter, r *http
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.UploadComplete(w, r, id, params)
+		siw.Handler.UploadContents(w, r, id, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -2466,6 +3823,14 @@ func (siw *ServerInterfaceWrapper) Status(w http.ResponseWriter, r *http.Request
 	// Parameter object where we will unmarshal all parameters from the context
 	var params StatusParams
 
+	// ------------- Optional query parameter "verbose" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "verbose", r.URL.Query(), &params.Verbose)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "verbose", Err: err})
+		return
+	}
+
 	headers := r.Header
 
 	// ------------- Optional header parameter "X-Request-Id" -------------
@@ -2636,24 +4001,57 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/fleet/agents/enroll", wrapper.AgentEnroll)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/agents/reassign", wrapper.AgentReassign)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/agents/search", wrapper.AgentSearch)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/fleet/agents/{id}/acks", wrapper.AgentAcks)
 	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/fleet/agents/{id}/checkin", wrapper.AgentCheckin)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/agents/{id}/revoke", wrapper.AgentRevoke)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/fleet/artifacts/{id}/{sha2}", wrapper.Artifact)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/fleet/diagnostics", wrapper.GetDiagnostics)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/drain", wrapper.Drain)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/api/fleet/file/{id}", wrapper.GetFile)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/fleet/policies/leaders", wrapper.GetPolicyLeaders)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/policies/validate", wrapper.PolicyValidate)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/api/fleet/profiler", wrapper.UpdateProfiler)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/api/fleet/promote", wrapper.Promote)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/fleet/uploads", wrapper.UploadBegin)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/fleet/uploads/{id}", wrapper.UploadStatus)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/api/fleet/uploads/{id}", wrapper.UploadComplete)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/api/fleet/uploads/{id}/contents", wrapper.UploadContents)
+	})
 	r.Group(func(r chi.Router) {
 		r.Put(options.BaseURL+"/api/fleet/uploads/{id}/{chunkNum}", wrapper.UploadChunk)
 	})
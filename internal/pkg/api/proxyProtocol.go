@@ -0,0 +1,278 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const (
+	proxyV1MaxLength = 107 // the longest v1 header, a TCP6 header with the longest addresses
+
+	// defaultProxyMaxPending bounds the headers read at once when the connections are not limited.
+	defaultProxyMaxPending = 1024
+)
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	errProxyHeaderMissing = errors.New("missing PROXY protocol header")
+)
+
+// proxyListener reads the PROXY protocol header of the connections of the trusted sources, the connections it
+// accepts have the address of the client as their remote address. The headers are read apart from Accept so a
+// slow client does not hold up the connections of the other clients, up to maxPending connections are read at once
+// and the next ones wait in the backlog of the listener.
+type proxyListener struct {
+	net.Listener
+	trusted []netip.Prefix
+	timeout time.Duration
+	pending chan struct{}
+
+	accepted  chan acceptedConn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type acceptedConn struct {
+	conn net.Conn
+	err  error
+}
+
+// newProxyListener returns the listener reading the headers of the connections of ln, maxPending is the limit of
+// the connections of the server, 0 when they are not limited.
+func newProxyListener(ctx context.Context, ln net.Listener, cfg config.ProxyProtocol, maxPending int) (*proxyListener, error) {
+	trusted, err := cfg.TrustedPrefixes()
+	if err != nil {
+		return nil, err
+	}
+	if maxPending <= 0 {
+		maxPending = defaultProxyMaxPending
+	}
+	l := &proxyListener{
+		Listener: ln,
+		trusted:  trusted,
+		timeout:  cfg.HeaderTimeout,
+		pending:  make(chan struct{}, maxPending),
+		accepted: make(chan acceptedConn),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop(ctx)
+	return l, nil
+}
+
+func (l *proxyListener) acceptLoop(ctx context.Context) {
+	for {
+		select {
+		case l.pending <- struct{}{}:
+		case <-l.done:
+			return
+		}
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			<-l.pending
+			select {
+			case l.accepted <- acceptedConn{err: err}:
+			case <-l.done:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.readHeader(ctx, conn)
+	}
+}
+
+// readHeader reads the header of conn and delivers it to Accept, the pending slot of the connection is released once
+// it is delivered.
+func (l *proxyListener) readHeader(ctx context.Context, conn net.Conn) {
+	defer func() { <-l.pending }()
+	if !l.trustedSource(conn.RemoteAddr()) {
+		l.deliver(conn)
+		return
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(l.timeout))
+	br := bufio.NewReader(conn)
+	remote, err := readProxyHeader(br)
+	if err != nil {
+		conn.Close()
+		select {
+		case <-l.done:
+			return
+		default:
+		}
+		if errors.Is(err, io.EOF) {
+			// a port check of the load balancer
+			return
+		}
+		zerolog.Ctx(ctx).Warn().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("Invalid PROXY protocol header, closing the connection")
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	l.deliver(&proxyConn{Conn: conn, r: br, remote: remote})
+}
+
+func (l *proxyListener) deliver(conn net.Conn) {
+	select {
+	case l.accepted <- acceptedConn{conn: conn}:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// trustedSource returns true when the header of the connections of addr is read.
+func (l *proxyListener) trustedSource(addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.UnixAddr:
+		// the socket permissions restrict its peers
+		return true
+	case *net.TCPAddr:
+		ip, ok := netip.AddrFromSlice(a.IP)
+		if !ok {
+			return false
+		}
+		ip = ip.Unmap()
+		for _, prefix := range l.trusted {
+			if prefix.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Accept returns the next connection with its header read.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case a := <-l.accepted:
+		return a.conn, a.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *proxyListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// proxyConn is a connection whose remote address is the address of the client given by its PROXY header.
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remote
+}
+
+// readProxyHeader reads the v1 or v2 PROXY protocol header of br and returns the address of the client. The
+// address is nil when the header does not give one, like the LOCAL command of v2 and the UNKNOWN protocol of v1,
+// the connections of the trusted sources without a header are refused as their address would be the proxy's one.
+func readProxyHeader(br *bufio.Reader) (net.Addr, error) {
+	sig, err := br.Peek(len(proxyV2Signature))
+	switch {
+	case bytes.Equal(sig, proxyV2Signature):
+		return readProxyV2Header(br)
+	case bytes.HasPrefix(sig, proxyV1Prefix):
+		return readProxyV1Header(br)
+	case len(sig) == 0 && err != nil:
+		return nil, err
+	}
+	// the first bytes of a TLS handshake or of an HTTP request
+	return nil, errProxyHeaderMissing
+}
+
+func readProxyV1Header(br *bufio.Reader) (net.Addr, error) {
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading v1 header: %w", err)
+	}
+	if len(line) > proxyV1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("malformed v1 header")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.New("malformed v1 header")
+	}
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil || ip.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("malformed v1 header source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("malformed v1 header source port %q", fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+func readProxyV2Header(br *bufio.Reader) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("reading v2 header: %w", err)
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 header version %d", hdr[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, fmt.Errorf("reading v2 header addresses: %w", err)
+	}
+
+	switch hdr[12] & 0x0f {
+	case 0x0: // LOCAL, the connection of the load balancer itself
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported v2 header command %d", hdr[12]&0x0f)
+	}
+	// the TLVs after the addresses are ignored
+	switch hdr[13] >> 4 {
+	case 0x1: // AF_INET
+		if len(payload) < 12 {
+			return nil, errors.New("malformed v2 header IPv4 addresses")
+		}
+		ip := netip.AddrFrom4([4]byte(payload[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, binary.BigEndian.Uint16(payload[8:10]))), nil
+	case 0x2: // AF_INET6
+		if len(payload) < 36 {
+			return nil, errors.New("malformed v2 header IPv6 addresses")
+		}
+		ip := netip.AddrFrom16([16]byte(payload[0:16])).Unmap()
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, binary.BigEndian.Uint16(payload[32:34]))), nil
+	}
+	// AF_UNSPEC and AF_UNIX do not give a client address
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func proxyV2Header(cmd, fam byte, addrs []byte) []byte {
+	b := append([]byte{}, proxyV2Signature...)
+	b = append(b, 0x20|cmd, fam, byte(len(addrs)>>8), byte(len(addrs)))
+	return append(b, addrs...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v4 := []byte{192, 0, 2, 10, 10, 0, 0, 1, 0x1f, 0x90, 0x20, 0x3c} // 192.0.2.10:8080 to 10.0.0.1:8252
+	v6 := append(append(net.ParseIP("2001:db8::1").To16(), net.ParseIP("2001:db8::2").To16()...), 0x1f, 0x90, 0x20, 0x3c)
+	tests := []struct {
+		name   string
+		header []byte
+		remote string
+		err    string
+	}{
+		{name: "v1 tcp4", header: []byte("PROXY TCP4 192.0.2.10 10.0.0.1 8080 8252\r\n"), remote: "192.0.2.10:8080"},
+		{name: "v1 tcp6", header: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 8080 8252\r\n"), remote: "[2001:db8::1]:8080"},
+		{name: "v1 unknown", header: []byte("PROXY UNKNOWN\r\n")},
+		{name: "v1 malformed", header: []byte("PROXY TCP4 192.0.2.10\r\n"), err: "malformed v1 header"},
+		{name: "v1 mismatched family", header: []byte("PROXY TCP4 2001:db8::1 2001:db8::2 8080 8252\r\n"), err: "source address"},
+		{name: "v1 too long", header: []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), err: "malformed v1 header"},
+		{name: "v2 tcp4", header: proxyV2Header(0x1, 0x11, v4), remote: "192.0.2.10:8080"},
+		{name: "v2 tcp6", header: proxyV2Header(0x1, 0x21, v6), remote: "[2001:db8::1]:8080"},
+		{name: "v2 with tlvs", header: proxyV2Header(0x1, 0x11, append(append([]byte{}, v4...), 0x04, 0x00, 0x01, 0x00)), remote: "192.0.2.10:8080"},
+		{name: "v2 local", header: proxyV2Header(0x0, 0x00, nil)},
+		{name: "v2 unspec", header: proxyV2Header(0x1, 0x00, nil)},
+		{name: "v2 short addresses", header: proxyV2Header(0x1, 0x11, v4[:8]), err: "malformed v2 header"},
+		{name: "v2 bad command", header: proxyV2Header(0x2, 0x11, v4), err: "unsupported v2 header command"},
+		{name: "no header", header: nil, err: "missing PROXY protocol header"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			br := bufio.NewReader(io.MultiReader(bytes.NewReader(tc.header), strings.NewReader("GET / HTTP/1.1\r\n")))
+			remote, err := readProxyHeader(br)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.remote == "" {
+				assert.Nil(t, remote)
+			} else {
+				require.NotNil(t, remote)
+				assert.Equal(t, tc.remote, remote.String())
+			}
+			rest, err := io.ReadAll(br)
+			require.NoError(t, err)
+			assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest), "the header is consumed")
+		})
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = testlog.SetLogger(t).WithContext(ctx)
+
+	accept := func(t *testing.T, trusted string, header string) (net.Conn, net.Conn) {
+		base, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ln, err := newProxyListener(ctx, base, config.ProxyProtocol{Enabled: true, TrustedSources: []string{trusted}, HeaderTimeout: time.Second}, 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { ln.Close() })
+
+		client, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		_, err = client.Write([]byte(header + "hello"))
+		require.NoError(t, err)
+
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return client, conn
+	}
+
+	t.Run("trusted source", func(t *testing.T) {
+		_, conn := accept(t, "127.0.0.0/8", "PROXY TCP4 192.0.2.10 10.0.0.1 8080 8252\r\n")
+		assert.Equal(t, "192.0.2.10:8080", conn.RemoteAddr().String())
+		b := make([]byte, 5)
+		_, err := io.ReadFull(conn, b)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(b))
+	})
+
+	t.Run("untrusted source", func(t *testing.T) {
+		client, conn := accept(t, "10.0.0.0/8", "PROXY TCP4 192.0.2.10 10.0.0.1 8080 8252\r\n")
+		assert.Equal(t, client.LocalAddr().String(), conn.RemoteAddr().String())
+		b := make([]byte, 5)
+		_, err := io.ReadFull(conn, b)
+		require.NoError(t, err)
+		assert.Equal(t, "PROXY", string(b), "the header of an untrusted source is not read")
+	})
+
+	t.Run("trusted source without header", func(t *testing.T) {
+		base, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ln, err := newProxyListener(ctx, base, config.ProxyProtocol{Enabled: true, TrustedSources: []string{"127.0.0.1"}, HeaderTimeout: time.Second}, 0)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		client, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+		require.NoError(t, err)
+		_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = client.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF, "the connection is closed")
+	})
+
+	t.Run("pending headers are bounded", func(t *testing.T) {
+		base, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		timeout := 200 * time.Millisecond
+		ln, err := newProxyListener(ctx, base, config.ProxyProtocol{Enabled: true, TrustedSources: []string{"127.0.0.1"}, HeaderTimeout: timeout}, 1)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		start := time.Now()
+		slow, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		defer slow.Close()
+		time.Sleep(50 * time.Millisecond)
+		fast, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		defer fast.Close()
+		_, err = fast.Write([]byte("PROXY TCP4 192.0.2.11 10.0.0.1 8081 8252\r\n"))
+		require.NoError(t, err)
+
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, "192.0.2.11:8081", conn.RemoteAddr().String())
+		assert.GreaterOrEqual(t, time.Since(start), timeout, "the header is read once the slow header times out")
+	})
+
+	t.Run("slow header does not block accept", func(t *testing.T) {
+		base, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ln, err := newProxyListener(ctx, base, config.ProxyProtocol{Enabled: true, TrustedSources: []string{"127.0.0.1"}, HeaderTimeout: time.Minute}, 0)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		slow, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		defer slow.Close()
+		fast, err := net.Dial("tcp", base.Addr().String())
+		require.NoError(t, err)
+		defer fast.Close()
+		_, err = fast.Write([]byte("PROXY TCP4 192.0.2.11 10.0.0.1 8081 8252\r\n"))
+		require.NoError(t, err)
+
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, "192.0.2.11:8081", conn.RemoteAddr().String())
+
+		require.NoError(t, ln.Close())
+		_, err = ln.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+	})
+}
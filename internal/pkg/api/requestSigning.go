@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
+)
+
+type signedRequestKey struct{}
+
+// signedRequest is the signature of a request, it is verified once the agent of the request is authenticated.
+type signedRequest struct {
+	verifier *signing.Verifier
+	req      *signing.Request
+}
+
+type requestSigning struct {
+	verifier *signing.Verifier
+	maxBody  int64
+}
+
+// newRequestSigning returns the middleware that reads the signatures of the requests. The configuration is
+// validated when it is loaded, the secret is valid.
+func newRequestSigning(cfg *config.RequestSigning) *requestSigning {
+	verifier, _ := signing.NewVerifier(cfg)
+	return &requestSigning{verifier: verifier, maxBody: cfg.MaxBody}
+}
+
+// middleware reads the signature of the request and buffers the body it covers, up to the max body size, the signature
+// is verified by the authentication of the agent as the signing key depends on the agent. The bodies of the requests
+// without a signature are not buffered, they are bounded by the limits of their routes.
+func (s *requestSigning) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(signing.HeaderSignature) != "" && r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+		}
+		req, err := signing.ReadRequest(r)
+		if err != nil {
+			ErrorResp(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), signedRequestKey{}, &signedRequest{verifier: s.verifier, req: req})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verifyRequestSignature verifies the signature of the request of the agent when the signatures are verified.
+func verifyRequestSignature(r *http.Request, agentID string) error {
+	sr, ok := r.Context().Value(signedRequestKey{}).(*signedRequest)
+	if !ok || sr.verifier == nil {
+		return nil
+	}
+	return sr.verifier.Verify(agentID, sr.req)
+}
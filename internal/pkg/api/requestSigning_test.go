@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/signing"
+)
+
+func TestRequestSigning(t *testing.T) {
+	secret := bytes.Repeat([]byte("s"), 32)
+	cfg := config.RequestSigning{Enabled: true, Secret: base64.StdEncoding.EncodeToString(secret)}
+	cfg.InitDefaults()
+
+	var verifyErr error
+	var body string
+	handler := newRequestSigning(&cfg).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifyErr = verifyRequestSignature(r, "agent")
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(b)
+	}))
+
+	sign := func(r *http.Request, nonce, body string) {
+		hash := sha256.Sum256([]byte(body))
+		req := &signing.Request{
+			Method:    r.Method,
+			URI:       r.URL.RequestURI(),
+			Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+			Nonce:     nonce,
+			BodyHash:  hash[:],
+		}
+		r.Header.Set(signing.HeaderTimestamp, req.Timestamp)
+		r.Header.Set(signing.HeaderNonce, nonce)
+		r.Header.Set(signing.HeaderSignature, signing.Sign(signing.Key(secret, "agent"), req))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent/acks", bytes.NewBufferString(`{"events":[]}`))
+	sign(r, "0123456789abcdef", `{"events":[]}`)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, `{"events":[]}`, body, "the handler reads the signed body")
+
+	r = httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent/acks", bytes.NewBufferString(`{"events":[{}]}`))
+	sign(r, "fedcba9876543210", `{"events":[]}`)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.ErrorIs(t, verifyErr, signing.ErrSignatureInvalid)
+
+	r = httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent/acks", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.ErrorIs(t, verifyErr, signing.ErrSignatureMissing)
+
+	cfg.MaxBody = 8
+	r = httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent/acks", bytes.NewBufferString(`{"events":[]}`))
+	sign(r, "0011223344556677", `{"events":[]}`)
+	rec := httptest.NewRecorder()
+	verifyErr = nil
+	newRequestSigning(&cfg).middleware(handler).ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "the bodies larger than max_body_byte_size are not read")
+
+	r = httptest.NewRequest(http.MethodPost, "/api/fleet/uploads/upload/0", bytes.NewBufferString(`unsigned chunk`))
+	rec = httptest.NewRecorder()
+	newRequestSigning(&cfg).middleware(handler).ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusOK, rec.Code, "the bodies of the unsigned requests are bounded by their routes")
+	assert.Equal(t, "unsigned chunk", body)
+
+	assert.NoError(t, verifyRequestSignature(r, "agent"), "the signatures are not verified without the middleware")
+	assert.Equal(t, http.StatusUnauthorized, NewHTTPErrResp(signing.ErrSignatureReplay).StatusCode)
+}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// listenReusePort opens n SO_REUSEPORT sockets on the TCP address and returns a listener of their connections.
+// The kernel spreads the new connections over the sockets and each socket is accepted from by its own goroutine.
+func listenReusePort(ctx context.Context, addr string, n int) (net.Listener, error) {
+	listenCfg := net.ListenConfig{Control: reusePortControl}
+	lns := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		ln, err := listenCfg.Listen(ctx, "tcp", addr)
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("unable to open SO_REUSEPORT socket %d of %s: %w", i+1, addr, err)
+		}
+		if i == 0 {
+			// the next sockets are bound to the port given to the first one when the port is 0
+			addr = ln.Addr().String()
+		}
+		lns = append(lns, ln)
+	}
+	return newMultiListener(lns), nil
+}
+
+// multiListener is a listener of the connections of several listeners, each accepted from by its own goroutine.
+type multiListener struct {
+	lns       []net.Listener
+	accepted  chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newMultiListener(lns []net.Listener) *multiListener {
+	l := &multiListener{
+		lns:      lns,
+		accepted: make(chan acceptResult),
+		done:     make(chan struct{}),
+	}
+	l.wg.Add(len(lns))
+	for _, ln := range lns {
+		go l.acceptLoop(ln)
+	}
+	return l
+}
+
+func (l *multiListener) acceptLoop(ln net.Listener) {
+	defer l.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		select {
+		case l.accepted <- acceptResult{conn: conn, err: err}:
+		case <-l.done:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (l *multiListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-l.accepted:
+		return r.conn, r.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes all the sockets and waits for their accept goroutines to return.
+func (l *multiListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		for _, ln := range l.lns {
+			err = errors.Join(err, ln.Close())
+		}
+		l.wg.Wait()
+	})
+	return err
+}
+
+// Addr returns the address of the first socket, all the sockets share it.
+func (l *multiListener) Addr() net.Addr {
+	return l.lns[0].Addr()
+}
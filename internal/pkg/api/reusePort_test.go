@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration && !windows
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fbuild "github.com/elastic/fleet-server/v7/internal/pkg/build"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestListenReusePort(t *testing.T) {
+	ln, err := listenReusePort(context.Background(), "127.0.0.1:0", 4)
+	require.NoError(t, err)
+	ml, ok := ln.(*multiListener)
+	require.True(t, ok)
+	require.Len(t, ml.lns, 4)
+	for _, l := range ml.lns {
+		assert.Equal(t, ln.Addr().String(), l.Addr().String())
+	}
+
+	for i := 0; i < 16; i++ {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		conn.Close()
+		c.Close()
+	}
+
+	require.NoError(t, ln.Close())
+	_, err = ln.Accept()
+	require.ErrorIs(t, err, net.ErrClosed)
+	_, err = net.DialTimeout("tcp", ln.Addr().String(), time.Second)
+	require.Error(t, err)
+}
+
+func Test_server_RunReusePort(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	defer cancel()
+
+	port, err := ftesting.FreePort()
+	require.NoError(t, err)
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	cfg.Host = "localhost"
+	cfg.Port = port
+	cfg.ReusePort = config.ReusePort{Enabled: true, Sockets: 4, Servers: 2}
+	addr := cfg.BindEndpoints()[0]
+
+	st := NewStatusT(cfg, nil, nil)
+	srv := NewServer(addr, cfg, nil, nil, nil, nil, st, &mockPolicyMonitor{state: client.UnitStateHealthy}, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run(ctx)
+	}()
+
+	status := func() bool {
+		httpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+		resp, err := httpClient.Get("http://" + addr + "/api/status")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}
+	require.Eventually(t, status, 5*time.Second, 20*time.Millisecond)
+	for i := 0; i < 16; i++ {
+		require.True(t, status())
+	}
+
+	srv.mut.Lock()
+	assert.Len(t, srv.srvs, 2)
+	srv.mut.Unlock()
+	reloaded := *cfg
+	reloaded.ReusePort.Servers = 3
+	require.NoError(t, srv.Reload(&reloaded))
+	require.True(t, status())
+	srv.mut.Lock()
+	assert.Len(t, srv.srvs, 3)
+	srv.mut.Unlock()
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// routeTimeouts replaces the read and write deadlines the http server sets on the connection of a request with the
+// timeouts of its route, so the checkins can be given a long write window while the other routes are kept short.
+type routeTimeouts struct {
+	routes map[string]config.RouteTimeouts
+}
+
+func newRouteTimeouts(cfg *config.ServerTimeouts) *routeTimeouts {
+	return &routeTimeouts{routes: cfg.Routes}
+}
+
+func (t *routeTimeouts) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := requestOperation(r)
+		rt, ok := t.routes[op]
+		if !ok || op == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rc := http.NewResponseController(w) //nolint:bodyclose // we are working with a ResponseWriter not a Response
+		if rt.Read > 0 {
+			if err := rc.SetReadDeadline(start.Add(rt.Read)); err != nil {
+				zerolog.Ctx(r.Context()).Debug().Err(err).Str(logger.Route, op).Msg("Unable to set the read deadline of the route.")
+			}
+		}
+		if rt.Write > 0 {
+			if err := rc.SetWriteDeadline(start.Add(rt.Write)); err != nil {
+				zerolog.Ctx(r.Context()).Debug().Err(err).Str(logger.Route, op).Msg("Unable to set the write deadline of the route.")
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
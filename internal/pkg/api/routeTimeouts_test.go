@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	timeouts := newRouteTimeouts(&config.ServerTimeouts{
+		Routes: map[string]config.RouteTimeouts{
+			"acks":    {Write: 50 * time.Millisecond},
+			"enroll":  {Read: 50 * time.Millisecond},
+			"checkin": {Write: time.Minute},
+		},
+	})
+	r := chi.NewRouter()
+	r.Use(timeouts.middleware)
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}
+	r.Post("/api/fleet/agents/{id}/checkin", slow)
+	r.Post("/api/fleet/agents/{id}/acks", slow)
+	r.Post("/api/fleet/agents/enroll", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestTimeout)
+		}
+	})
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.ReadTimeout = time.Minute
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	post := func(path string, body io.Reader) (*http.Response, error) {
+		resp, err := client.Post(srv.URL+path, "application/json", body)
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	t.Run("longer write timeout", func(t *testing.T) {
+		resp, err := post("/api/fleet/agents/agent-1/checkin", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("shorter write timeout", func(t *testing.T) {
+		_, err := post("/api/fleet/agents/agent-1/acks", nil)
+		require.Error(t, err)
+	})
+
+	t.Run("shorter read timeout", func(t *testing.T) {
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = pw.Write([]byte("{"))
+			time.Sleep(200 * time.Millisecond)
+			_, _ = io.Copy(pw, strings.NewReader("}"))
+			pw.Close()
+		}()
+		resp, err := post("/api/fleet/agents/enroll", pr)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+	})
+}
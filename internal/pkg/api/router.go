@@ -5,9 +5,11 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
@@ -15,18 +17,50 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
-	"go.elastic.co/apm/module/apmchiv5/v2"
 	"go.elastic.co/apm/v2"
 )
 
-func newRouter(cfg *config.ServerLimits, si ServerInterface, tracer *apm.Tracer) http.Handler {
+func newRouter(cfg *config.Server, si ServerInterface, probes *probesT, prof *ProfilerT, tracer *apm.Tracer) http.Handler {
 	r := chi.NewRouter()
 	if tracer != nil {
-		r.Use(apmchiv5.Middleware(apmchiv5.WithTracer(tracer)))
+		r.Use(newTracing(tracer, cfg.Instrumentation.Sampling).middleware)
 	}
 	r.Use(logger.Middleware) // Attach middlewares to router directly so the occur before any request parsing/validation
+	r.Use(newSlowRequests(cfg.SlowRequests).middleware)
 	r.Use(middleware.Recoverer)
-	r.Use(Limiter(cfg).middleware)
+	if cfg.IPFilter.Enabled() {
+		r.Use(newIPFilter(&cfg.IPFilter).middleware)
+	}
+	if len(cfg.Routes) > 0 {
+		r.Use(routeFilter(cfg))
+	}
+	if cfg.CORS.Enabled {
+		r.Use(newCORS(&cfg.CORS).middleware)
+	}
+	if len(cfg.Timeouts.Routes) > 0 {
+		r.Use(newRouteTimeouts(&cfg.Timeouts).middleware)
+	}
+	if cfg.HTTP3.Enabled {
+		r.Use(altSvc(cfg))
+	}
+	if cfg.Standby.Enabled {
+		r.Use((&standbyFilter{cfg: &cfg.Standby}).middleware)
+	}
+	r.Use(Limiter(&cfg.Limits).middleware)
+	if cfg.RequestSigning.Enabled {
+		r.Use(newRequestSigning(&cfg.RequestSigning).middleware)
+	}
+	if cfg.Audit.Enabled {
+		r.Use(auditRequests)
+	}
+	if probes != nil {
+		r.Get("/live", probes.handleLive)
+		r.Get("/ready", probes.handleReady)
+	}
+	if prof != nil {
+		// the pprof endpoints have wildcard paths, they are not part of the openapi spec
+		r.Handle("/api/fleet/debug/pprof/*", prof.pprofHandler())
+	}
 	return HandlerWithOptions(si, ChiServerOptions{
 		BaseRouter:       r,
 		ErrorHandlerFunc: ErrorResp,
@@ -35,6 +69,22 @@ func newRouter(cfg *config.ServerLimits, si ServerInterface, tracer *apm.Tracer)
 	})
 }
 
+// ErrRouteNotServed is returned for the routes a listener is not configured to serve, like checkin on a management listener.
+var ErrRouteNotServed = errors.New("route is not served by this listener")
+
+// routeFilter rejects the requests of the routes the server does not serve.
+func routeFilter(cfg *config.Server) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.ServesRoute(requestOperation(r)) {
+				ErrorResp(w, r, ErrRouteNotServed)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // limiter wraps routes with metrics and rate limits.
 //
 // auth is handled elsewhere.
@@ -47,8 +97,18 @@ type limiter struct {
 	uploadBegin    *limit.Limiter
 	uploadChunk    *limit.Limiter
 	uploadComplete *limit.Limiter
+	uploadStatus   *limit.Limiter
 	deliverFile    *limit.Limiter
 	getPGPKey      *limit.Limiter
+	policyValidate *limit.Limiter
+	revoke         *limit.Limiter
+	profiler       *limit.Limiter
+	diagnostics    *limit.Limiter
+	drain          *limit.Limiter
+	agentSearch    *limit.Limiter
+	agentReassign  *limit.Limiter
+	policyLeaders  *limit.Limiter
+	promote        *limit.Limiter
 }
 
 func Limiter(cfg *config.ServerLimits) *limiter {
@@ -61,11 +121,44 @@ func Limiter(cfg *config.ServerLimits) *limiter {
 		uploadBegin:    limit.NewLimiter(&cfg.UploadStartLimit),
 		uploadChunk:    limit.NewLimiter(&cfg.UploadChunkLimit),
 		uploadComplete: limit.NewLimiter(&cfg.UploadEndLimit),
+		uploadStatus:   limit.NewLimiter(&cfg.UploadStatusLimit),
 		deliverFile:    limit.NewLimiter(&cfg.DeliverFileLimit),
 		getPGPKey:      limit.NewLimiter(&cfg.GetPGPKey),
+		policyValidate: limit.NewLimiter(&cfg.PolicyValidateLimit),
+		revoke:         limit.NewLimiter(&cfg.RevokeLimit),
+		profiler:       limit.NewLimiter(&cfg.ProfilerLimit),
+		diagnostics:    limit.NewLimiter(&cfg.DiagnosticsLimit),
+		drain:          limit.NewLimiter(&cfg.DrainLimit),
+		agentSearch:    limit.NewLimiter(&cfg.AgentSearchLimit),
+		agentReassign:  limit.NewLimiter(&cfg.AgentReassignLimit),
+		policyLeaders:  limit.NewLimiter(&cfg.PolicyLeadersLimit),
+		promote:        limit.NewLimiter(&cfg.PromoteLimit),
 	}
 }
 
+// policyLimiter holds the limiters of an agent route for the policies with policy limits.
+type policyLimiter map[string]*limit.Limiter
+
+// newPolicyLimiter returns the limiters of the route of fn for the policies of cfg that set a limit for it.
+func newPolicyLimiter(cfg *config.ServerLimits, fn func(*config.PolicyLimits) *config.Limit) policyLimiter {
+	pl := make(policyLimiter)
+	for i := range cfg.PolicyLimits {
+		if l := fn(&cfg.PolicyLimits[i]); l != nil {
+			pl[cfg.PolicyLimits[i].PolicyID] = limit.NewLimiter(l)
+		}
+	}
+	return pl
+}
+
+// acquire takes the limits of the policy of the agent of the request, the returned func releases them.
+func (pl policyLimiter) acquire(w http.ResponseWriter, r *http.Request, policyID string) (func(), error) {
+	l, ok := pl[policyID]
+	if !ok {
+		return func() {}, nil
+	}
+	return l.Acquire(w, r)
+}
+
 var pgpReg = regexp.MustCompile(`\/api\/agents\/upgrades\/[0-9]+\.[0-9]+\.[0-9]+\/pgp-public-key`)
 
 // pathToOperation determines the endpoint passed on the request path.
@@ -80,6 +173,18 @@ func pathToOperation(path string) string {
 	if path == "/api/fleet/uploads" {
 		return "uploadBegin"
 	}
+	if path == "/api/fleet/profiler" || strings.HasPrefix(path, "/api/fleet/debug/pprof") {
+		return "profiler"
+	}
+	if path == "/api/fleet/diagnostics" {
+		return "diagnostics"
+	}
+	if path == "/api/fleet/drain" {
+		return "drain"
+	}
+	if path == "/api/fleet/promote" {
+		return "promote"
+	}
 	if pgpReg.MatchString(path) {
 		return "getPGPKey"
 	}
@@ -88,15 +193,25 @@ func pathToOperation(path string) string {
 		pp := strings.Split(strings.TrimPrefix(path, "/"), "/")
 		if len(pp) == 4 {
 			if pp[2] == "agents" {
+				if pp[3] == "search" {
+					return "agentSearch"
+				}
+				if pp[3] == "reassign" {
+					return "agentReassign"
+				}
 				return "enroll"
 			} else if pp[2] == "uploads" {
 				return "uploadComplete"
 			} else if pp[2] == "file" {
 				return "deliverFile"
+			} else if pp[2] == "policies" && pp[3] == "validate" {
+				return "policyValidate"
+			} else if pp[2] == "policies" && pp[3] == "leaders" {
+				return "policyLeaders"
 			}
 		} else if len(pp) == 5 {
 			if pp[2] == "agents" {
-				if pp[4] == "acks" || pp[4] == "checkin" {
+				if pp[4] == "acks" || pp[4] == "checkin" || pp[4] == "revoke" {
 					return pp[4]
 				}
 			} else if pp[2] == "uploads" {
@@ -109,33 +224,83 @@ func pathToOperation(path string) string {
 	return ""
 }
 
+// requestOperation determines the endpoint of the request.
+func requestOperation(r *http.Request) string {
+	op := pathToOperation(r.URL.Path)
+	if op == "uploadComplete" && r.Method == http.MethodGet {
+		// uploadStatus shares the path of uploadComplete
+		op = "uploadStatus"
+	}
+	if op == "uploadChunk" && r.Method == http.MethodGet {
+		// the contents of an upload are read from the path of its chunks
+		op = "uploadContents"
+	}
+	return op
+}
+
 func (l *limiter) middleware(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		switch pathToOperation(r.URL.Path) {
+		op := requestOperation(r)
+		var (
+			lim   *limit.Limiter
+			stats limit.StatIncer
+			rs    *routeStats
+			level = zerolog.DebugLevel
+		)
+		switch op {
 		case "enroll":
-			l.enroll.Wrap("enroll", &cntEnroll, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.enroll, &cntEnroll, &cntEnroll
 		case "acks":
-			l.ack.Wrap("acks", &cntAcks, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.ack, &cntAcks, &cntAcks
 		case "checkin":
-			l.checkin.Wrap("checkin", &cntCheckin, zerolog.WarnLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.checkin, &cntCheckin, &cntCheckin
+			level = zerolog.WarnLevel
 		case "artifact":
-			l.artifact.Wrap("artifact", &cntArtifacts, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.artifact, &cntArtifacts, &cntArtifacts.routeStats
 		case "uploadBegin":
-			l.uploadBegin.Wrap("uploadBegin", &cntUploadStart, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.uploadBegin, &cntUploadStart, &cntUploadStart
 		case "uploadComplete":
-			l.uploadComplete.Wrap("uploadComplete", &cntUploadEnd, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.uploadComplete, &cntUploadEnd, &cntUploadEnd
+		case "uploadStatus", "uploadContents":
+			// the contents of the uploads are read with the limits of their status
+			lim, stats, rs = l.uploadStatus, &cntUploadStatus, &cntUploadStatus
 		case "uploadChunk":
-			l.uploadChunk.Wrap("uploadChunk", &cntUploadChunk, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.uploadChunk, &cntUploadChunk, &cntUploadChunk
 		case "deliverFile":
-			l.deliverFile.Wrap("deliverFile", &cntFileDeliv, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.deliverFile, &cntFileDeliv, &cntFileDeliv
 		case "getPGPKey":
-			l.getPGPKey.Wrap("getPGPKey", &cntGetPGP, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.getPGPKey, &cntGetPGP, &cntGetPGP
+		case "policyValidate":
+			lim, stats, rs = l.policyValidate, &cntPolicyValidate, &cntPolicyValidate
+		case "revoke":
+			lim, stats, rs = l.revoke, &cntRevoke, &cntRevoke
+		case "profiler":
+			lim, stats, rs = l.profiler, &cntProfiler, &cntProfiler
+		case "diagnostics":
+			lim, stats, rs = l.diagnostics, &cntDiagnostics, &cntDiagnostics
+		case "drain":
+			lim, stats, rs = l.drain, &cntDrain, &cntDrain
+		case "agentSearch":
+			lim, stats, rs = l.agentSearch, &cntAgentSearch, &cntAgentSearch
+		case "agentReassign":
+			lim, stats, rs = l.agentReassign, &cntAgentReassign, &cntAgentReassign
+		case "policyLeaders":
+			lim, stats, rs = l.policyLeaders, &cntPolicyLeaders, &cntPolicyLeaders
+		case "promote":
+			lim, stats, rs = l.promote, &cntPromote, &cntPromote
 		case "status":
-			l.status.Wrap("status", &cntStatus, zerolog.DebugLevel)(next).ServeHTTP(w, r)
+			lim, stats, rs = l.status, &cntStatus, &cntStatus
 		default:
 			// no tracking or limits
 			next.ServeHTTP(w, r)
+			return
 		}
+
+		// The latency and the response code include the responses of the limiter.
+		rc := logger.NewResponseCounter(w)
+		start := time.Now()
+		lim.Wrap(op, stats, level)(next).ServeHTTP(rc, r)
+		rs.Observe(time.Since(start), rc.StatusCode())
 	}
 	return http.HandlerFunc(fn)
 }
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,6 +35,17 @@ func TestPathToOperation(t *testing.T) {
 		{"/api/fleet/file", ""},
 		{"/api/fleet/file/abc", "deliverFile"},
 		{"/api/fleet/artifacts/some-id/hash", "artifact"},
+		{"/api/fleet/policies/validate", "policyValidate"},
+		{"/api/fleet/agents/some-id/revoke", "revoke"},
+		{"/api/fleet/profiler", "profiler"},
+		{"/api/fleet/debug/pprof/heap", "profiler"},
+		{"/api/fleet/diagnostics", "diagnostics"},
+		{"/api/fleet/drain", "drain"},
+		{"/api/fleet/promote", "promote"},
+		{"/api/fleet/agents/search", "agentSearch"},
+		{"/api/fleet/agents/reassign", "agentReassign"},
+		{"/api/fleet/policies/leaders", "policyLeaders"},
+		{"/api/fleet/policies/other", ""},
 		{"/api/fleet/unimplemented/some-id", ""},
 		{"/api/flet/agents/some-id/acks", ""},
 		{"/api/fleet/agents/some-id/other", ""},
@@ -101,3 +113,76 @@ func TestLimiter(t *testing.T) {
 		})
 	}
 }
+
+func TestLimiterRouteStats(t *testing.T) {
+	count := cntStatus.latency.count.Get()
+	ok := cntStatus.status[2].metric.Get()
+	limited := cntStatus.status[4].metric.Get()
+
+	h := testStatusServer(t, &config.ServerLimits{})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/status", nil))
+	h = testStatusServer(t, &config.ServerLimits{StatusLimit: config.Limit{Interval: -1 * time.Second, Burst: -1, Max: -1}})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/status", nil))
+
+	assert.Equal(t, count+2, cntStatus.latency.count.Get(), "the rejected requests are included in the latency")
+	assert.Equal(t, ok+1, cntStatus.status[2].metric.Get())
+	assert.Equal(t, limited+1, cntStatus.status[4].metric.Get())
+}
+
+func TestRouteFilter(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(routeFilter(&config.Server{Routes: []string{"status"}}))
+	r.Get("/api/status", func(w http.ResponseWriter, req *http.Request) {})
+	r.Post("/api/fleet/agents/{id}/checkin", func(w http.ResponseWriter, req *http.Request) {})
+	r.Get("/live", func(w http.ResponseWriter, req *http.Request) {})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/status", http.StatusOK},
+		{http.MethodPost, "/api/fleet/agents/some-id/checkin", http.StatusNotFound},
+		{http.MethodGet, "/live", http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.status, rec.Code)
+		})
+	}
+}
+
+func TestPolicyLimiter(t *testing.T) {
+	cfg := &config.ServerLimits{PolicyLimits: []config.PolicyLimits{{
+		PolicyID:     "byod",
+		CheckinLimit: &config.Limit{Interval: time.Hour, Burst: 2, Max: 1},
+	}, {
+		PolicyID: "other",
+		AckLimit: &config.Limit{Max: 1},
+	}}}
+	pl := newPolicyLimiter(cfg, func(p *config.PolicyLimits) *config.Limit { return p.CheckinLimit })
+	assert.Len(t, pl, 1, "only the policies with a limit for the route have a limiter")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/agent-id/checkin", nil)
+	release, err := pl.acquire(w, r, "byod")
+	assert.NoError(t, err)
+	_, err = pl.acquire(w, r, "byod")
+	assert.ErrorIs(t, err, limit.ErrMaxLimit)
+	release()
+	_, err = pl.acquire(w, r, "byod")
+	var raErr *limit.RetryAfterError
+	assert.ErrorAs(t, err, &raErr, "the burst is used")
+	assert.ErrorIs(t, err, limit.ErrRateLimit)
+
+	release, err = pl.acquire(w, r, "unlimited")
+	assert.NoError(t, err, "the agents of the policies without limits are not limited")
+	release()
+
+	w = httptest.NewRecorder()
+	ErrorResp(w, r, raErr)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
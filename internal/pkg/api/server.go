@@ -12,72 +12,91 @@ import (
 	slog "log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 
-	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/fleet-server/v7/internal/pkg/build"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+	"github.com/quic-go/quic-go/http3"
 	"go.elastic.co/apm/v2"
 
 	"github.com/rs/zerolog"
 )
 
+var (
+	errServerNotRunning = errors.New("server is not running")
+	errTLSToggled       = errors.New("TLS can not be enabled or disabled without a restart")
+)
+
 type server struct {
-	cfg     *config.Server
 	addr    string
 	handler http.Handler
+
+	mut     sync.Mutex
+	cfg     *config.Server
+	running bool
+	acc     *acceptor
+	tls     *tlsReloader
+	srvs    []*http.Server // the http servers the accepted connections are spread over
+	h3      *http3.Server  // the server of an HTTP/3 endpoint, with no srvs
+	ctx     context.Context
+	errCh   chan error
+
+	// the http servers replaced by a reload drain their connections until drainCtx is cancelled
+	drainCtx    context.Context
+	drainCancel context.CancelFunc
+	draining    sync.WaitGroup
 }
 
 // NewServer creates a new HTTP api for the passed addr.
 //
 // The server has a listener specific conn limit and endpoint specific rate-limits.
 // The underlying API structs (such as *CheckinT) may be shared between servers.
-func NewServer(addr string, cfg *config.Server, ct *CheckinT, et *EnrollerT, at *ArtifactT, ack *AckT, st *StatusT, sm policy.SelfMonitor, bi build.Info, ut *UploadT, ft *FileDeliveryT, pt *PGPRetrieverT, bulker bulk.Bulk, tracer *apm.Tracer) *server {
+func NewServer(addr string, cfg *config.Server, ct *CheckinT, et *EnrollerT, at *ArtifactT, ack *AckT, st *StatusT, sm policy.SelfMonitor, bi build.Info, ut *UploadT, ft *FileDeliveryT, pt *PGPRetrieverT, pv *PolicyValidatorT, rt *RevokerT, prof *ProfilerT, diag *DiagnosticsT, drain *DrainT, ast *AgentSearchT, art *AgentReassignT, pl *PolicyLeadersT, promote *PromoteT, bulker bulk.Bulk, tracer *apm.Tracer) *server {
 	a := &apiServer{
-		ct:     ct,
-		et:     et,
-		at:     at,
-		ack:    ack,
-		st:     st,
-		sm:     sm,
-		bi:     bi,
-		ut:     ut,
-		ft:     ft,
-		pt:     pt,
-		bulker: bulker,
+		ct:      ct,
+		et:      et,
+		at:      at,
+		ack:     ack,
+		st:      st,
+		sm:      sm,
+		bi:      bi,
+		ut:      ut,
+		ft:      ft,
+		pt:      pt,
+		pv:      pv,
+		rt:      rt,
+		prof:    prof,
+		diag:    diag,
+		drain:   drain,
+		ast:     ast,
+		art:     art,
+		pl:      pl,
+		promote: promote,
+		bulker:  bulker,
 	}
 	return &server{
 		addr:    addr,
 		cfg:     cfg,
-		handler: newRouter(&cfg.Limits, a, tracer),
+		handler: newRouter(cfg, a, &probesT{cfg: &cfg.Probes, bulk: bulker, sm: sm}, prof, tracer),
 	}
 }
 
 func (s *server) Run(ctx context.Context) error {
-	rdto := s.cfg.Timeouts.Read
-	wrto := s.cfg.Timeouts.Write
-	idle := s.cfg.Timeouts.Idle
-	rdhr := s.cfg.Timeouts.ReadHeader
-	mhbz := s.cfg.Limits.MaxHeaderByteSize
-
-	srv := http.Server{
-		Addr:              s.addr,
-		Handler:           s.handler,
-		ReadTimeout:       rdto,
-		ReadHeaderTimeout: rdhr,
-		WriteTimeout:      wrto,
-		IdleTimeout:       idle,
-		MaxHeaderBytes:    mhbz,
-		BaseContext:       func(net.Listener) context.Context { return ctx },
-		ErrorLog:          errLogger(ctx),
-		ConnState:         diagConn,
+	if addr, ok := strings.CutPrefix(s.addr, config.HTTP3Prefix); ok {
+		return s.runHTTP3(ctx, addr)
+	}
+	var ln net.Listener
+	var err error
+	if s.cfg.ReusePort.Enabled && !strings.HasPrefix(s.addr, config.UnixSocketPrefix) {
+		ln, err = listenReusePort(ctx, s.addr, s.cfg.ReusePort.SocketCount())
+	} else {
+		ln, err = listen(ctx, s.addr, s.cfg.Socket)
 	}
-
-	var listenCfg net.ListenConfig
-	ln, err := listenCfg.Listen(ctx, "tcp", s.addr)
 	if err != nil {
 		return err
 	}
@@ -89,6 +108,15 @@ func (s *server) Run(ctx context.Context) error {
 		}
 	}()
 
+	if s.cfg.ProxyProtocol.Enabled {
+		// the limiter counts the connections once their header is read, the headers read at once are bounded by it
+		pl, err := newProxyListener(ctx, ln, s.cfg.ProxyProtocol, s.cfg.Limits.MaxConnections)
+		if err != nil {
+			return err
+		}
+		ln = pl
+	}
+
 	// Conn Limiter must be before the TLS handshake in the stack;
 	// The server should not eat the cost of the handshake if there
 	// is no capacity to service the connection.
@@ -96,51 +124,165 @@ func (s *server) Run(ctx context.Context) error {
 	// being at the top of the stack.
 	ln = wrapConnLimitter(ctx, ln, s.cfg)
 
+	s.mut.Lock()
 	if s.cfg.TLS != nil && s.cfg.TLS.IsEnabled() {
-		commonTLSCfg, err := tlscommon.LoadTLSServerConfig(s.cfg.TLS)
+		s.tls, err = newTLSReloader(s.cfg.Host, s.cfg.TLS, s.cfg.ClientCertificates)
 		if err != nil {
+			s.mut.Unlock()
 			return err
 		}
-		srv.TLSConfig = commonTLSCfg.BuildServerConfig(s.cfg.Host)
-
-		// Must enable http/2 in the configuration explicitly.
-		// (see https://golang.org/pkg/net/http/#Server.Serve)
-		srv.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
-
-		ln = tls.NewListener(ln, srv.TLSConfig)
-
+		go s.tls.watch(ctx, s.cfg.TLSReloadInterval)
 	} else {
 		zerolog.Ctx(ctx).Warn().Msg("Exposed over insecure HTTP; enablement of TLS is strongly recommended")
 	}
-
-	// Start the API server on another goroutine and return any non ErrServerClosed errors through a channel.
-	errCh := make(chan error)
-	go func(ctx context.Context, errCh chan error, ln net.Listener) {
-		zerolog.Ctx(ctx).Info().Msgf("Listening on %s", s.addr)
-		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			errCh <- err
-		}
-	}(ctx, errCh, ln)
+	s.acc = newAcceptor(ln)
+	s.ctx = ctx
+	s.drainCtx, s.drainCancel = context.WithCancel(context.WithoutCancel(ctx))
+	// Any non ErrServerClosed errors of the http servers are returned through the channel.
+	s.errCh = make(chan error, 1)
+	s.srvs = s.serve(s.cfg)
+	s.running = true
+	s.mut.Unlock()
 
 	select {
 	// Listen and return any errors that occur from the server listener
-	case err := <-errCh:
+	case err := <-s.errCh:
+		_ = s.stop()
 		if !errors.Is(err, context.Canceled) {
 			return fmt.Errorf("error while serving API listener: %w", err)
 		}
 	// Do a clean shutdown if the context is cancelled
 	case <-ctx.Done():
-		sCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeouts.Drain)
-		defer cancel()
-		if err := srv.Shutdown(sCtx); err != nil {
-			cErr := srv.Close() // force it closed
-			return errors.Join(fmt.Errorf("error while shutting down api listener: %w", err), cErr)
+		if err := s.stop(); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// Reload applies the listener settings of cfg, the TLS configuration and the connection timeouts, to the new
+// connections of the running server. The established connections keep the settings they were accepted with until
+// they are closed, so the long polls of the agents are not interrupted. The other settings are not reloaded.
+// An HTTP/3 server reloads its TLS configuration only.
+func (s *server) Reload(cfg *config.Server) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if !s.running {
+		return errServerNotRunning
+	}
+	if (s.tls != nil) != (cfg.TLS != nil && cfg.TLS.IsEnabled()) {
+		return errTLSToggled
+	}
+	if s.tls != nil {
+		if err := s.tls.load(cfg.TLS); err != nil {
+			return fmt.Errorf("unable to reload the TLS configuration: %w", err)
+		}
+	}
+
+	if s.h3 != nil {
+		s.cfg = cfg
+		zerolog.Ctx(s.ctx).Info().Msgf("Reloaded the TLS configuration of %s", s.addr)
+		return nil
+	}
+
+	prev, prevCfg := s.srvs, s.cfg
+	s.cfg = cfg
+	s.srvs = s.serve(cfg)
+	s.draining.Add(1)
+	go func() {
+		defer s.draining.Done()
+		// the previous connections are drained for as long as a checkin may last
+		ctx, cancel := context.WithTimeout(s.drainCtx, prevCfg.Timeouts.CheckinMaxPoll+prevCfg.Timeouts.CheckinJitter+prevCfg.Timeouts.Drain)
+		defer cancel()
+		_ = shutdownServers(ctx, prev)
+	}()
+	zerolog.Ctx(s.ctx).Info().Msgf("Reloaded the listener settings of %s", s.addr)
+	return nil
+}
+
+// serve starts the http servers with the settings of cfg accepting the next connections of the server.
+// It is called with the lock held.
+func (s *server) serve(cfg *config.Server) []*http.Server {
+	srvs := make([]*http.Server, 0, cfg.ReusePort.ServerCount())
+	for i := 0; i < cap(srvs); i++ {
+		srvs = append(srvs, s.serveOne(cfg))
+	}
+	zerolog.Ctx(s.ctx).Info().Int("servers", len(srvs)).Msgf("Listening on %s", s.addr)
+	return srvs
+}
+
+// serveOne starts an http server with the settings of cfg, it shares the accepted connections with the other
+// http servers of the server.
+func (s *server) serveOne(cfg *config.Server) *http.Server {
+	ctx := s.ctx
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.handler,
+		ReadTimeout:       cfg.Timeouts.Read,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+		MaxHeaderBytes:    cfg.Limits.MaxHeaderByteSize,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		ErrorLog:          errLogger(ctx),
+		ConnState:         diagConn,
+	}
+
+	var ln net.Listener = s.acc.listener()
+	if s.tls != nil {
+		// each http server configures http/2 on its own copy of the configuration
+		srv.TLSConfig = s.tls.serverConfig()
+		ln = tls.NewListener(ln, srv.TLSConfig)
+	}
+
+	go func(ctx context.Context, errCh chan error, ln net.Listener) {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}
+	}(ctx, s.errCh, ln)
+	return srv
+}
+
+// stop shuts down the http servers of the server within the drain timeout, including the servers still draining
+// their connections after a reload.
+func (s *server) stop() error {
+	s.mut.Lock()
+	srvs, drain := s.srvs, s.cfg.Timeouts.Drain
+	s.running = false
+	s.mut.Unlock()
+	defer s.acc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	err := shutdownServers(ctx, srvs)
+	s.drainCancel()
+	s.draining.Wait()
+	return err
+}
+
+// shutdownServers shuts down the http servers concurrently, the servers that are not shut down when ctx is done
+// are closed.
+func shutdownServers(ctx context.Context, srvs []*http.Server) error {
+	errs := make([]error, len(srvs))
+	var wg sync.WaitGroup
+	for i, srv := range srvs {
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			if sErr := srv.Shutdown(ctx); sErr != nil {
+				cErr := srv.Close() // force it closed
+				errs[i] = errors.Join(fmt.Errorf("error while shutting down api listener: %w", sErr), cErr)
+			}
+		}(i, srv)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 func diagConn(c net.Conn, s http.ConnState) {
 	if c == nil {
 		return
@@ -178,6 +320,84 @@ func wrapConnLimitter(ctx context.Context, ln net.Listener, cfg *config.Server)
 	return ln
 }
 
+// acceptor accepts the connections of a listener for the successive http servers of a server: a reloaded server
+// starts an http server with the new settings on the same listener, so the connections waiting to be accepted
+// are not refused, and the previous http server stops accepting connections.
+type acceptor struct {
+	ln       net.Listener
+	accepted chan acceptResult
+	done     chan struct{}
+	once     sync.Once
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newAcceptor(ln net.Listener) *acceptor {
+	a := &acceptor{
+		ln:       ln,
+		accepted: make(chan acceptResult),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *acceptor) run() {
+	for {
+		conn, err := a.ln.Accept()
+		select {
+		case a.accepted <- acceptResult{conn: conn, err: err}:
+		case <-a.done:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+	}
+}
+
+// Close stops handing out the connections, the listener is closed by its owner.
+func (a *acceptor) Close() {
+	a.once.Do(func() { close(a.done) })
+}
+
+// listener returns a listener of the connections accepted next, closing it does not close the underlying listener.
+func (a *acceptor) listener() net.Listener {
+	return &acceptorListener{a: a, closed: make(chan struct{})}
+}
+
+type acceptorListener struct {
+	a      *acceptor
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (l *acceptorListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-l.a.accepted:
+		return r.conn, r.err
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-l.a.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *acceptorListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *acceptorListener) Addr() net.Addr {
+	return l.a.ln.Addr()
+}
+
 type stubLogger struct {
 	log zerolog.Logger
 }
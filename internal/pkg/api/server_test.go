@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -25,7 +26,6 @@ import (
 
 	fbuild "github.com/elastic/fleet-server/v7/internal/pkg/build"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
-	"github.com/elastic/fleet-server/v7/internal/pkg/monitor/mock"
 	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	"github.com/elastic/fleet-server/v7/internal/pkg/testing/certs"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
@@ -43,7 +43,7 @@ func Test_server_Run(t *testing.T) {
 	cfg.Port = port
 	addr := cfg.BindEndpoints()[0]
 
-	srv := NewServer(addr, cfg, nil, nil, nil, nil, nil, nil, fbuild.Info{}, nil, nil, nil, nil, nil)
+	srv := NewServer(addr, cfg, nil, nil, nil, nil, nil, nil, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 	started := make(chan struct{}, 1)
 	errCh := make(chan error, 1)
@@ -88,8 +88,7 @@ client_authentication: "optional"
 
 func Test_server_ClientCert(t *testing.T) {
 	// prep self monitor mock for status endpoint
-	sm := mock.NewMockMonitor()
-	sm.On("State").Return(client.UnitStateHealthy)
+	sm := &mockPolicyMonitor{state: client.UnitStateHealthy}
 
 	// prep server tls config
 	ca := certs.GenCA(t)
@@ -124,7 +123,7 @@ func Test_server_ClientCert(t *testing.T) {
 		cfg.TLS = tlsCFG
 
 		st := NewStatusT(cfg, nil, nil)
-		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil)
+		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 		// make http client with no client certs
 		certPool := x509.NewCertPool()
@@ -194,7 +193,7 @@ func Test_server_ClientCert(t *testing.T) {
 		cfg.TLS = tlsCFG
 
 		st := NewStatusT(cfg, nil, nil)
-		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil)
+		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 		// make http client with valid client certs
 		clientCert := certs.GenCert(t, ca)
@@ -266,7 +265,7 @@ func Test_server_ClientCert(t *testing.T) {
 		cfg.TLS = tlsCFG
 
 		st := NewStatusT(cfg, nil, nil)
-		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil)
+		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 		// make http client with invalid client certs
 		clientCA := certs.GenCA(t)
@@ -349,7 +348,7 @@ key: %s`,
 		cfg.TLS = tlsCFG
 
 		st := NewStatusT(cfg, nil, nil)
-		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil)
+		srv := NewServer(addr, cfg, nil, nil, nil, nil, st, sm, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 
 		// make http client with valid client certs
 		clientCert := certs.GenCert(t, ca)
@@ -406,3 +405,87 @@ key: %s`,
 		}
 	})
 }
+
+func Test_server_Reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = testlog.SetLogger(t).WithContext(ctx)
+
+	ca := certs.GenCA(t)
+	cert := certs.GenCert(t, ca)
+	certPath := certs.CertToFile(t, cert, "cert")
+	keyPath := certs.KeyToFile(t, cert, "key")
+	ucfg, err := yaml.NewConfig([]byte(fmt.Sprintf("enabled: true\ncertificate: %q\nkey: %q\n", certPath, keyPath)))
+	require.NoError(t, err)
+	tlsCFG := &tlscommon.ServerConfig{}
+	require.NoError(t, tlsCFG.Unpack(libsconfig.C(*ucfg)))
+
+	port, err := ftesting.FreePort()
+	require.NoError(t, err)
+	cfg := &config.Server{}
+	cfg.InitDefaults()
+	cfg.Host = "localhost"
+	cfg.Port = port
+	cfg.TLS = tlsCFG
+	cfg.TLSReloadInterval = 10 * time.Millisecond
+	addr := cfg.BindEndpoints()[0]
+
+	st := NewStatusT(cfg, nil, nil)
+	srv := NewServer(addr, cfg, nil, nil, nil, nil, st, &mockPolicyMonitor{state: client.UnitStateHealthy}, fbuild.Info{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.ErrorIs(t, srv.Reload(cfg), errServerNotRunning)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run(ctx)
+	}()
+
+	status := func(ca tls.Certificate) error {
+		certPool := x509.NewCertPool()
+		certPool.AddCert(ca.Leaf)
+		httpClient := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: certPool},
+				DisableKeepAlives: true,
+			},
+			Timeout: time.Second,
+		}
+		resp, err := httpClient.Get("https://" + addr + "/api/status")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+	require.Eventually(t, func() bool { return status(ca) == nil }, 5*time.Second, 20*time.Millisecond)
+
+	t.Run("rotated certificate files", func(t *testing.T) {
+		rotatedCA := certs.GenCA(t)
+		rotated := certs.GenCert(t, rotatedCA)
+		require.NoError(t, os.Rename(certs.CertToFile(t, rotated, "cert"), certPath))
+		require.NoError(t, os.Rename(certs.KeyToFile(t, rotated, "key"), keyPath))
+		// make sure the modification time changes on file systems with a coarse resolution
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certPath, future, future))
+		require.NoError(t, os.Chtimes(keyPath, future, future))
+
+		require.Eventually(t, func() bool { return status(rotatedCA) == nil }, 5*time.Second, 20*time.Millisecond)
+		require.Error(t, status(ca))
+		ca = rotatedCA
+	})
+
+	t.Run("listener settings", func(t *testing.T) {
+		reloaded := *cfg
+		reloaded.Timeouts.Idle = time.Minute
+		require.NoError(t, srv.Reload(&reloaded))
+		require.NoError(t, status(ca))
+
+		reloaded.TLS = nil
+		require.ErrorIs(t, srv.Reload(&reloaded), errTLSToggled)
+	})
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// slowRequests logs a warning for the requests that take longer than the threshold of their route, with the
+// time spent waiting on Elasticsearch apart from the time spent in fleet-server, so the agents or the queries
+// that are slow can be found without enabling the debug logs.
+type slowRequests struct {
+	cfg config.SlowRequests
+}
+
+func newSlowRequests(cfg config.SlowRequests) *slowRequests {
+	return &slowRequests{cfg: cfg}
+}
+
+func (s *slowRequests) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := requestOperation(r)
+		threshold := s.cfg.RouteThreshold(op)
+		if op == "" || threshold <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var timer es.Timer
+		r = r.WithContext(es.WithTimer(r.Context(), &timer))
+		rc := logger.NewResponseCounter(w)
+		start := time.Now()
+		next.ServeHTTP(rc, r)
+		duration := time.Since(start)
+		if duration < threshold {
+			return
+		}
+
+		// the concurrent calls to Elasticsearch may add up to more than the duration of the request
+		esDuration := timer.Elapsed()
+		e := zerolog.Ctx(r.Context()).Warn().
+			Str(logger.Route, op).
+			Str(logger.ECSHTTPRequestMethod, r.Method).
+			Str(logger.ECSURLPath, r.URL.Path).
+			Int(logger.ECSHTTPResponseCode, rc.StatusCode()).
+			Int64(logger.ECSEventDuration, duration.Nanoseconds()).
+			Int64(logger.ESDuration, esDuration.Nanoseconds()).
+			Int64(logger.HandlerDuration, max(duration-esDuration, 0).Nanoseconds()).
+			Dur("threshold", threshold)
+		if agentID := requestAgentID(r, op); agentID != "" {
+			e = e.Str(logger.AgentID, agentID)
+		}
+		e.Msg("Slow request")
+	})
+}
+
+// requestAgentID returns the ID of the agent of the routes that have it in their path.
+// The URL parameters are known once the request is routed.
+func requestAgentID(r *http.Request, op string) string {
+	switch op {
+	case "acks", "checkin", "revoke":
+		return chi.URLParam(r, "id")
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+func TestSlowRequests(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+
+	s := newSlowRequests(config.SlowRequests{
+		Threshold: 10 * time.Millisecond,
+		Routes:    map[string]time.Duration{"acks": 0},
+	})
+	r := chi.NewRouter()
+	r.Use(s.middleware)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		// half of the time is spent on Elasticsearch
+		es.AddTime(r.Context(), 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	r.Post("/api/fleet/agents/{id}/checkin", handler)
+	r.Post("/api/fleet/agents/{id}/acks", handler)
+	r.Get("/api/status", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(method, path string) {
+		req := httptest.NewRequest(method, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req.WithContext(log.WithContext(req.Context())))
+	}
+
+	serve(http.MethodGet, "/api/status")
+	serve(http.MethodPost, "/api/fleet/agents/agent-1/acks")
+	assert.Zero(t, buf.Len(), "the fast requests and the disabled routes are not logged")
+
+	serve(http.MethodPost, "/api/fleet/agents/agent-1/checkin")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "checkin", entry[logger.Route])
+	assert.Equal(t, "agent-1", entry[logger.AgentID])
+	assert.Equal(t, float64(http.StatusServiceUnavailable), entry[logger.ECSHTTPResponseCode])
+	assert.Equal(t, float64(10*time.Millisecond), entry[logger.ESDuration])
+	assert.GreaterOrEqual(t, entry[logger.HandlerDuration], float64(10*time.Millisecond))
+	assert.GreaterOrEqual(t, entry[logger.ECSEventDuration], float64(20*time.Millisecond))
+}
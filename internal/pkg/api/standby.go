@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
+)
+
+// ErrStandby is returned for the requests of the agents to a server in standby that does not redirect them.
+var ErrStandby = errors.New("server is in standby")
+
+// agentOperations are the operations of the requests of the agents, they are not served in standby.
+var agentOperations = map[string]bool{
+	"enroll":         true,
+	"checkin":        true,
+	"acks":           true,
+	"artifact":       true,
+	"uploadBegin":    true,
+	"uploadChunk":    true,
+	"uploadComplete": true,
+	"uploadStatus":   true,
+	"deliverFile":    true,
+	"getPGPKey":      true,
+}
+
+// standbyFilter rejects the requests of the agents while the server is in standby, they are redirected to the
+// active server or answered with a retry hint. The status, the probes and the management requests, like the
+// promotion, are served.
+type standbyFilter struct {
+	cfg *config.ServerStandby
+}
+
+func (f *standbyFilter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !standby.FromContext(r.Context()).Standby() || !agentOperations[requestOperation(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if f.cfg.RedirectURL != "" {
+			http.Redirect(w, r, strings.TrimSuffix(f.cfg.RedirectURL, "/")+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.cfg.RetryAfter.Seconds())))
+		ErrorResp(w, r, ErrStandby)
+	})
+}
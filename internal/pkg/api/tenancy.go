@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+)
+
+// ErrNamespaceForbidden is returned when the API key of an admin API is not allowed in the namespaces of a document,
+// or when an API that is not scoped by namespaces is called with a key scoped to namespaces.
+var ErrNamespaceForbidden = errors.New("api key is not allowed in the namespace")
+
+// apiKeyNamespaces returns the namespaces the API key of an admin API is scoped to, the namespaces of the principal
+// owning the key. The owner is set by Elasticsearch, the keys created with a scoped key have the same owner. It
+// returns nil when the key is allowed in every namespace or when the namespaces of the API keys are not enabled, and
+// ErrNamespaceForbidden when the owner has no namespaces.
+func apiKeyNamespaces(ctx context.Context, cfg *config.Server, bulker bulk.Bulk, key *apikey.APIKey) ([]string, error) {
+	if cfg == nil || !cfg.Tenancy.Enabled {
+		return nil, nil
+	}
+	span, ctx := apm.StartSpan(ctx, "apiKeyNamespaces", "auth")
+	defer span.End()
+	meta, err := bulker.APIKeyRead(ctx, key.ID, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the owner of the api key: %w", err)
+	}
+	if meta.Username == "" {
+		return nil, fmt.Errorf("%w: the owner of the api key is unknown", ErrNamespaceForbidden)
+	}
+	namespaces, ok := cfg.Tenancy.Namespaces(meta.Username, meta.Realm)
+	if !ok {
+		zerolog.Ctx(ctx).Info().Str(LogAPIKeyID, key.ID).Str("username", meta.Username).Str("realm", meta.Realm).
+			Msg("the owner of the api key has no tenancy namespaces")
+		return nil, fmt.Errorf("%w: the owner of the api key has no namespaces", ErrNamespaceForbidden)
+	}
+	if slices.Contains(namespaces, config.AllNamespaces) {
+		return nil, nil
+	}
+	return namespaces, nil
+}
+
+// authorizeNamespaces ensures that the API key scoped to namespaces is allowed in the namespaces of a document.
+func authorizeNamespaces(namespaces, docNamespaces []string) error {
+	if len(namespaces) == 0 || dl.InNamespaces(docNamespaces, namespaces) {
+		return nil
+	}
+	return ErrNamespaceForbidden
+}
+
+// authorizeUnscoped ensures that the API key is not scoped to namespaces, the APIs acting on the whole deployment are
+// restricted to the keys that are not scoped.
+func authorizeUnscoped(ctx context.Context, cfg *config.Server, bulker bulk.Bulk, key *apikey.APIKey) error {
+	namespaces, err := apiKeyNamespaces(ctx, cfg, bulker, key)
+	if err != nil {
+		return err
+	}
+	if len(namespaces) > 0 {
+		return ErrNamespaceForbidden
+	}
+	return nil
+}
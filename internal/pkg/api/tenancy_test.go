@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestAPIKeyNamespaces(t *testing.T) {
+	ctx := context.Background()
+	key := &apikey.APIKey{ID: "operator"}
+
+	fakebulk := itesting.NewMockBulk()
+	namespaces, err := apiKeyNamespaces(ctx, &config.Server{}, fakebulk, key)
+	require.NoError(t, err)
+	assert.Nil(t, namespaces)
+	fakebulk.AssertNotCalled(t, "APIKeyRead", mock.Anything, mock.Anything)
+
+	cfg := &config.Server{Tenancy: config.Tenancy{
+		Enabled: true,
+		Principals: []config.TenancyPrincipal{
+			{Username: "ops", Namespaces: []string{"space"}},
+			{Username: "ops", Realm: "saml", Namespaces: []string{"other"}},
+			{Username: "admin", Namespaces: []string{config.AllNamespaces}},
+		},
+	}}
+	tests := []struct {
+		name       string
+		meta       *bulk.APIKeyMetadata
+		namespaces []string
+		err        error
+	}{
+		{name: "principal", meta: &bulk.APIKeyMetadata{Username: "ops", Realm: "native"}, namespaces: []string{"space"}},
+		{name: "principal of the realm", meta: &bulk.APIKeyMetadata{Username: "ops", Realm: "saml"}, namespaces: []string{"other"}},
+		{name: "all namespaces", meta: &bulk.APIKeyMetadata{Username: "admin", Realm: "native"}},
+		{name: "metadata namespaces are ignored", meta: &bulk.APIKeyMetadata{Username: "guest", Realm: "native", Metadata: apikey.Metadata{ManagedBy: "guest"}}, err: ErrNamespaceForbidden},
+		{name: "unknown owner", meta: &bulk.APIKeyMetadata{}, err: ErrNamespaceForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakebulk := itesting.NewMockBulk()
+			fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(tc.meta, nil).Once()
+			namespaces, err := apiKeyNamespaces(ctx, cfg, fakebulk, key)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.namespaces, namespaces)
+		})
+	}
+
+	fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(&bulk.APIKeyMetadata{}, apikey.ErrAPIKeyNotFound).Once()
+	_, err = apiKeyNamespaces(ctx, cfg, fakebulk, key)
+	assert.ErrorIs(t, err, apikey.ErrAPIKeyNotFound)
+}
+
+func TestAuthorizeNamespaces(t *testing.T) {
+	assert.NoError(t, authorizeNamespaces(nil, []string{"space"}), "the keys that are not scoped are allowed in every namespace")
+	assert.NoError(t, authorizeNamespaces([]string{"space"}, []string{"other", "space"}))
+	assert.NoError(t, authorizeNamespaces([]string{"default"}, nil), "the documents without namespaces are in the default namespace")
+	assert.ErrorIs(t, authorizeNamespaces([]string{"space"}, nil), ErrNamespaceForbidden)
+	assert.ErrorIs(t, authorizeNamespaces([]string{"space"}, []string{"other"}), ErrNamespaceForbidden)
+}
+
+func TestAuthorizeUnscoped(t *testing.T) {
+	cfg := &config.Server{Tenancy: config.Tenancy{
+		Enabled: true,
+		Principals: []config.TenancyPrincipal{
+			{Username: "admin", Namespaces: []string{config.AllNamespaces}},
+			{Username: "ops", Namespaces: []string{"space"}},
+		},
+	}}
+	key := &apikey.APIKey{ID: "operator"}
+
+	fakebulk := itesting.NewMockBulk()
+	fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(&bulk.APIKeyMetadata{ID: "operator", Username: "admin"}, nil).Once()
+	assert.NoError(t, authorizeUnscoped(context.Background(), cfg, fakebulk, key))
+
+	fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(&bulk.APIKeyMetadata{ID: "operator", Username: "ops"}, nil).Once()
+	err := authorizeUnscoped(context.Background(), cfg, fakebulk, key)
+	assert.ErrorIs(t, err, ErrNamespaceForbidden)
+	assert.Equal(t, http.StatusForbidden, NewHTTPErrResp(err).StatusCode)
+
+	fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(&bulk.APIKeyMetadata{ID: "operator", Username: "derived"}, nil).Once()
+	assert.ErrorIs(t, authorizeUnscoped(context.Background(), cfg, fakebulk, key), ErrNamespaceForbidden, "the owners that are not listed are refused")
+
+	fakebulk.On("APIKeyRead", mock.Anything, "operator").Return(&bulk.APIKeyMetadata{}, errors.New("network error")).Once()
+	assert.Error(t, authorizeUnscoped(context.Background(), cfg, fakebulk, key))
+}
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"maps"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+// tlsReloader provides the TLS configuration of the handshakes of a server. The configuration is rebuilt when the
+// server is reloaded or when the certificate, key, certificate authority or revocation list files change, so the
+// certificates are rotated without closing the established connections.
+type tlsReloader struct {
+	host    string
+	certs   config.ClientCertificates
+	ocsp    *ocspChecker
+	current atomic.Pointer[tls.Config]
+
+	mut      sync.Mutex
+	cfg      *tlscommon.ServerConfig
+	modTimes map[string]time.Time
+}
+
+func newTLSReloader(host string, cfg *tlscommon.ServerConfig, certs config.ClientCertificates) (*tlsReloader, error) {
+	r := &tlsReloader{host: host, certs: certs}
+	if certs.OCSP.Enabled {
+		r.ocsp = newOCSPChecker(certs.OCSP)
+	}
+	if err := r.load(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load builds the TLS configuration of cfg, the previous configuration is kept on error.
+func (r *tlsReloader) load(cfg *tlscommon.ServerConfig) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	// the files are checked before they are read, so a change made while they are read is seen by the next check
+	modTimes := tlsFileModTimes(cfg, r.certs.CRLs)
+	commonTLSCfg, err := tlscommon.LoadTLSServerConfig(cfg)
+	if err != nil {
+		return err
+	}
+	revocation, err := newRevocationChecker(r.certs, r.ocsp)
+	if err != nil {
+		return err
+	}
+	tlsCfg := fips.Restrict(commonTLSCfg.BuildServerConfig(r.host))
+	if revocation != nil {
+		verify := tlsCfg.VerifyConnection
+		tlsCfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			return revocation.verifyConnection(cs)
+		}
+	}
+
+	// Must enable http/2 in the configuration explicitly.
+	// (see https://golang.org/pkg/net/http/#Server.Serve)
+	tlsCfg.NextProtos = []string{"h2", "http/1.1"}
+
+	r.current.Store(tlsCfg)
+	r.cfg = cfg
+	r.modTimes = modTimes
+	return nil
+}
+
+// serverConfig returns the configuration of a listener, its handshakes use the configuration loaded last.
+func (r *tlsReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// watch reloads the configuration when the files it was loaded from are modified, until ctx is done.
+func (r *tlsReloader) watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		r.mut.Lock()
+		cfg := r.cfg
+		changed := !maps.Equal(r.modTimes, tlsFileModTimes(cfg, r.certs.CRLs))
+		r.mut.Unlock()
+		if !changed {
+			continue
+		}
+		if err := r.load(cfg); err != nil {
+			// the files may be partially written, they are read again on the next check
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to reload the TLS certificates, the previous certificates are kept")
+			continue
+		}
+		zerolog.Ctx(ctx).Info().Msg("TLS certificates reloaded")
+	}
+}
+
+// tlsFileModTimes returns the modification time of the files of cfg and of the revocation lists. The certificates
+// and keys given inline are not files and are skipped.
+func tlsFileModTimes(cfg *tlscommon.ServerConfig, crls []string) map[string]time.Time {
+	paths := append([]string{cfg.Certificate.Certificate, cfg.Certificate.Key, cfg.Certificate.PassphrasePath}, cfg.CAs...)
+	paths = append(paths, crls...)
+	modTimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if fi, err := os.Stat(path); err == nil {
+			modTimes[path] = fi.ModTime()
+		}
+	}
+	return modTimes
+}
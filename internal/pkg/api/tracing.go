@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.elastic.co/apm/module/apmhttp/v2"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// elasticTracestateKey is the tracestate entry the sample rate of a trace is propagated with.
+const elasticTracestateKey = "es"
+
+// tracing starts the APM transactions of the requests.
+// Unlike the apmhttp middleware the root transactions of the routes with a sample rate are sampled at that
+// rate instead of the rate of the tracer, so a busy route like checkin does not drown out the other routes.
+// The traces started by an upstream service keep the sampling decision of the service.
+type tracing struct {
+	tracer *apm.Tracer
+	ignore apmhttp.RequestIgnorerFunc
+	rates  map[string]float64
+	errors bool
+}
+
+func newTracing(tracer *apm.Tracer, cfg config.InstrumentationSampling) *tracing {
+	return &tracing{
+		tracer: tracer,
+		ignore: apmhttp.NewDynamicServerRequestIgnorer(tracer),
+		rates:  cfg.Routes,
+		errors: cfg.Errors,
+	}
+}
+
+func (t *tracing) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.tracer.Recording() || t.ignore(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := requestName(r)
+		start := time.Now()
+		opts := apm.TransactionOptions{Start: start}
+		traceContext, upstream := requestTraceContext(r)
+		rate, routeSampled := t.rates[requestOperation(r)]
+		switch {
+		case upstream:
+			opts.TraceContext = traceContext
+		case routeSampled:
+			opts.TraceContext = newRootTraceContext(rand.Float64() < rate, rate) //nolint:gosec // sampling does not need a secure random source
+		}
+		tx := t.tracer.StartTransactionOptions(name, "request", opts)
+		r = apmhttp.RequestWithContext(apm.ContextWithTransaction(r.Context(), tx), r)
+		body := t.tracer.CaptureHTTPRequestBody(r)
+		if body != nil {
+			r = apmhttp.RequestWithContext(apm.ContextWithBodyCapturer(r.Context(), body), r)
+		}
+
+		w, resp := apmhttp.WrapResponseWriter(w)
+		next.ServeHTTP(w, r)
+		if resp.StatusCode == 0 {
+			resp.StatusCode = http.StatusOK
+		}
+
+		if !upstream && t.errors && !tx.Sampled() && resp.StatusCode >= 500 {
+			tx = t.resample(tx, name, start)
+		}
+		apmhttp.SetTransactionContext(tx, r, resp, body)
+		body.Discard()
+		tx.End()
+	})
+}
+
+// resample replaces the unsampled transaction of a failed request by a sampled transaction with the same IDs,
+// so the errors captured during the request refer to it. The spans of the request were not recorded.
+// The transaction is reported with a sample rate of 1 as it would be reported no matter the rate of the route.
+func (t *tracing) resample(tx *apm.Transaction, name string, start time.Time) *apm.Transaction {
+	traceContext := tx.TraceContext()
+	tx.Discard()
+	return t.tracer.StartTransactionOptions(name, "request", apm.TransactionOptions{
+		TraceContext: apm.TraceContext{
+			Trace:   traceContext.Trace,
+			Options: traceContext.Options.WithRecorded(true),
+			State:   apm.NewTraceState(apm.TraceStateEntry{Key: elasticTracestateKey, Value: formatSampleRate(1)}),
+		},
+		TransactionID: traceContext.Span,
+		Start:         start,
+	})
+}
+
+// newRootTraceContext returns the trace context of a new trace with the sampling decision of the route.
+// The tracer does not sample a transaction started with a trace context and no parent span, it keeps the decision.
+func newRootTraceContext(sampled bool, rate float64) apm.TraceContext {
+	var tc apm.TraceContext
+	binary.LittleEndian.PutUint64(tc.Trace[:8], rand.Uint64()) //nolint:gosec // trace ids do not need a secure random source
+	binary.LittleEndian.PutUint64(tc.Trace[8:], rand.Uint64()) //nolint:gosec // trace ids do not need a secure random source
+	tc.Options = tc.Options.WithRecorded(sampled)
+	if !sampled {
+		// the unsampled transactions are reported with a rate of 0 so they are not counted in the aggregations
+		rate = 0
+	}
+	tc.State = apm.NewTraceState(apm.TraceStateEntry{Key: elasticTracestateKey, Value: formatSampleRate(rate)})
+	return tc
+}
+
+// formatSampleRate formats the sample rate of the elastic tracestate entry.
+func formatSampleRate(rate float64) string {
+	return "s:" + strconv.FormatFloat(rate, 'g', 4, 64)
+}
+
+// requestTraceContext returns the trace context propagated by the headers of the request.
+func requestTraceContext(r *http.Request) (apm.TraceContext, bool) {
+	for _, header := range []string{apmhttp.W3CTraceparentHeader, apmhttp.ElasticTraceparentHeader} {
+		if values := r.Header[header]; len(values) == 1 && values[0] != "" {
+			if tc, err := apmhttp.ParseTraceparentHeader(values[0]); err == nil {
+				tc.State, _ = apmhttp.ParseTracestateHeader(r.Header[apmhttp.TracestateHeader]...)
+				return tc, true
+			}
+		}
+	}
+	return apm.TraceContext{}, false
+}
+
+// requestName returns the transaction name of the request, the method and the parametrized route.
+func requestName(r *http.Request) string {
+	routePath := r.URL.Path
+	if r.URL.RawPath != "" {
+		routePath = r.URL.RawPath
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.Routes != nil {
+		tctx := chi.NewRouteContext()
+		if rctx.Routes.Match(tctx, r.Method, routePath) {
+			return r.Method + " " + tctx.RoutePattern()
+		}
+	}
+	return apmhttp.UnknownRouteRequestName(r)
+}
@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2"
+	"go.elastic.co/apm/v2/apmtest"
+	"go.elastic.co/apm/v2/model"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestTracingSampling(t *testing.T) {
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+
+	tr := newTracing(tracer.Tracer, config.InstrumentationSampling{
+		Routes: map[string]float64{"checkin": 0, "acks": 1},
+		Errors: true,
+	})
+	r := chi.NewRouter()
+	r.Use(tr.middleware)
+	r.Post("/api/fleet/agents/{id}/checkin", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	r.Post("/api/fleet/agents/{id}/acks", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name    string
+		path    string
+		header  string
+		sampled bool
+		rate    float64
+	}{
+		{name: "unsampled route", path: "/api/fleet/agents/agent-1/checkin"},
+		{name: "sampled route", path: "/api/fleet/agents/agent-1/acks", sampled: true, rate: 1},
+		{name: "failed request", path: "/api/fleet/agents/agent-1/checkin?fail", sampled: true, rate: 1},
+		{name: "upstream decision", path: "/api/fleet/agents/agent-1/checkin", header: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", sampled: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer.ResetPayloads()
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("traceparent", tc.header)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			tracer.Flush(nil)
+
+			txs := tracer.Payloads().Transactions
+			require.Len(t, txs, 1)
+			tx := txs[0]
+			assert.Equal(t, tc.sampled, sampled(tx))
+			if tc.header != "" {
+				assert.NotZero(t, tx.ParentID, "the transaction continues the upstream trace")
+				return
+			}
+			assert.Zero(t, tx.ParentID)
+			require.NotNil(t, tx.SampleRate)
+			assert.Equal(t, tc.rate, *tx.SampleRate)
+		})
+	}
+}
+
+func TestTracingTracerRate(t *testing.T) {
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+	tracer.SetSampler(apm.NewRatioSampler(0))
+
+	r := chi.NewRouter()
+	r.Use(newTracing(tracer.Tracer, config.InstrumentationSampling{}).middleware)
+	r.Get("/api/status", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	tracer.Flush(nil)
+	txs := tracer.Payloads().Transactions
+	require.Len(t, txs, 1)
+	assert.Equal(t, "GET /api/status", txs[0].Name)
+	assert.False(t, sampled(txs[0]), "the routes without a rate are sampled by the tracer")
+}
+
+// sampled returns the sampling decision of a recorded transaction, it is omitted for the sampled transactions.
+func sampled(tx model.Transaction) bool {
+	return tx.Sampled == nil || *tx.Sampled
+}
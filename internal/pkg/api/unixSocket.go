@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// listen returns the listener of the address, a TCP address or the endpoint of a Unix socket.
+func listen(ctx context.Context, addr string, socket config.UnixSocket) (net.Listener, error) {
+	var listenCfg net.ListenConfig
+	path, ok := strings.CutPrefix(addr, config.UnixSocketPrefix)
+	if !ok {
+		return listenCfg.Listen(ctx, "tcp", addr)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := listenCfg.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// the socket file is removed when the listener is closed
+	if mode, ok := socket.FileMode(); ok {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("unable to set the permissions of socket %s: %w", path, err)
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleSocket removes the socket file left by a server that did not exit cleanly, the other files are kept.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("unable to listen on socket %s: the file exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
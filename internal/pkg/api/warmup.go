@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+)
+
+// WarmCaches loads the hot entries into the caches before the server accepts requests: the latest revision of
+// every policy, the active enrollment keys and the most recently created artifacts.
+// Failures are only logged, the entries that are not loaded are fetched on their first request as usual.
+func WarmCaches(ctx context.Context, cfg config.CacheWarmup, bulker bulk.Bulk, c cache.Cache, pm policy.Monitor) {
+	span, ctx := apm.StartSpan(ctx, "warmCaches", "process")
+	defer span.End()
+
+	zlog := zerolog.Ctx(ctx).With().Str("ctx", "cache warmup").Logger()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+
+	var (
+		wg         sync.WaitGroup
+		enrollKeys int
+		artifacts  int
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		if err := pm.Preload(ctx); err != nil {
+			zlog.Warn().Err(err).Msg("Failed to preload policies")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		enrollKeys = warmEnrollmentKeys(ctx, zlog, cfg.EnrollKeys, bulker, c)
+	}()
+	go func() {
+		defer wg.Done()
+		artifacts = warmArtifacts(ctx, zlog, cfg.Artifacts, bulker, c)
+	}()
+	wg.Wait()
+
+	zlog.Info().
+		Int("enroll_keys", enrollKeys).
+		Int("artifacts", artifacts).
+		Dur("duration", time.Since(start)).
+		Msg("Cache warm-up done")
+}
+
+func warmEnrollmentKeys(ctx context.Context, zlog zerolog.Logger, size int, bulker bulk.Bulk, c cache.Cache) int {
+	if size <= 0 {
+		return 0
+	}
+	recs, err := dl.FindActiveEnrollmentAPIKeys(ctx, bulker, size)
+	if err != nil {
+		zlog.Warn().Err(err).Msg("Failed to load enrollment keys")
+		return 0
+	}
+	for _, rec := range recs {
+		c.SetEnrollmentAPIKey(rec.APIKeyID, rec, int64(len(rec.APIKey)))
+	}
+	return len(recs)
+}
+
+func warmArtifacts(ctx context.Context, zlog zerolog.Logger, size int, bulker bulk.Bulk, c cache.Cache) int {
+	if size <= 0 {
+		return 0
+	}
+	arts, err := dl.FindRecentArtifacts(ctx, bulker, size)
+	if err != nil {
+		zlog.Warn().Err(err).Msg("Failed to load artifacts")
+		return 0
+	}
+	n := 0
+	for i := range arts {
+		art := &arts[i]
+		alog := zlog.With().Str("ident", art.Identifier).Str("sha2", art.DecodedSha256).Logger()
+		if err := decodeArtifact(ctx, alog, art); err != nil {
+			continue
+		}
+		// The artifact is cached for the agents of exactly its namespaces, the others load it on their first request.
+		c.SetArtifact(artifactCacheNamespace(art.Namespaces), *art)
+		n++
+	}
+	return n
+}
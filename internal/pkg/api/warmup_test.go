@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testcache "github.com/elastic/fleet-server/v7/internal/pkg/testing/cache"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+type preloadMonitor struct {
+	policy.Monitor
+	err    error
+	called bool
+}
+
+func (m *preloadMonitor) Preload(_ context.Context) error {
+	m.called = true
+	return m.err
+}
+
+func TestWarmCaches(t *testing.T) {
+	payload := []byte("artifact payload")
+	sum := sha256.Sum256(payload)
+	sha2 := hex.EncodeToString(sum[:])
+	artifactsRes := &es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{
+		{ID: "1", Source: []byte(fmt.Sprintf(`{"identifier":"ident","decoded_sha256":"%s","encoded_sha256":"%s","namespaces":["space1"],"body":"%s","created":""}`,
+			sha2, sha2, base64.StdEncoding.EncodeToString(payload)))},
+		{ID: "2", Source: []byte(`{"identifier":"broken","decoded_sha256":"sha2","encoded_sha256":"00","body":"Ym9keQ==","created":""}`)},
+	}}}
+	enrollRes := &es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{
+		{ID: "1", Source: []byte(`{"api_key_id":"key-id","api_key":"key","policy_id":"policy","active":true}`)},
+	}}}
+
+	t.Run("hot entries are cached", func(t *testing.T) {
+		ctx := testlog.SetLogger(t).WithContext(context.Background())
+		bulker := ftesting.NewMockBulk()
+		bulker.On("Search", mock.Anything, dl.FleetArtifacts, mock.Anything, mock.Anything).Return(artifactsRes, nil).Once()
+		bulker.On("Search", mock.Anything, dl.FleetEnrollmentAPIKeys, mock.Anything, mock.Anything).Return(enrollRes, nil).Once()
+		c := testcache.NewMockCache()
+		c.On("SetEnrollmentAPIKey", "key-id", mock.MatchedBy(func(k model.EnrollmentAPIKey) bool { return k.PolicyID == "policy" }), int64(3)).Return().Once()
+		c.On("SetArtifact", "space1", mock.MatchedBy(func(a model.Artifact) bool { return string(a.Body) == string(payload) })).Return().Once()
+		pm := &preloadMonitor{}
+
+		WarmCaches(ctx, config.CacheWarmup{Enabled: true, EnrollKeys: 10, Artifacts: 10}, bulker, c, pm)
+		assert.True(t, pm.called)
+		bulker.AssertExpectations(t)
+		c.AssertExpectations(t)
+	})
+
+	t.Run("failures do not stop the warm-up", func(t *testing.T) {
+		ctx := testlog.SetLogger(t).WithContext(context.Background())
+		bulker := ftesting.NewMockBulk()
+		bulker.On("Search", mock.Anything, dl.FleetArtifacts, mock.Anything, mock.Anything).Return(artifactsRes, nil).Once()
+		c := testcache.NewMockCache()
+		c.On("SetArtifact", "space1", mock.Anything).Return().Once()
+		pm := &preloadMonitor{err: errors.New("unavailable")}
+
+		WarmCaches(ctx, config.CacheWarmup{Enabled: true, EnrollKeys: -1, Artifacts: 10}, bulker, c, pm)
+		assert.True(t, pm.called)
+		bulker.AssertNotCalled(t, "Search", mock.Anything, dl.FleetEnrollmentAPIKeys, mock.Anything, mock.Anything)
+		c.AssertExpectations(t)
+	})
+}
@@ -38,6 +38,9 @@ type APIKeyMetadata struct {
 	ID              string
 	Metadata        Metadata
 	RoleDescriptors json.RawMessage
+	// Username and Realm are the owner of the key, set by Elasticsearch.
+	Username string
+	Realm    string
 }
 
 // Read gathers APIKeyMetadata from Elasticsearch using the given client.
@@ -68,6 +71,8 @@ func Read(ctx context.Context, client *elasticsearch.Client, id string, withOwne
 		ID              string          `json:"id"`
 		Metadata        Metadata        `json:"metadata"`
 		RoleDescriptors json.RawMessage `json:"role_descriptors"`
+		Username        string          `json:"username"`
+		Realm           string          `json:"realm"`
 	}
 	type GetAPIKeyResponse struct {
 		APIKeys []APIKeyResponse `json:"api_keys"`
@@ -90,6 +95,8 @@ func Read(ctx context.Context, client *elasticsearch.Client, id string, withOwne
 		ID:              first.ID,
 		Metadata:        first.Metadata,
 		RoleDescriptors: first.RoleDescriptors,
+		Username:        first.Username,
+		Realm:           first.Realm,
 	}, nil
 }
 
@@ -5,6 +5,7 @@
 package apikey
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -29,6 +30,7 @@ type SecurityInfo struct {
 	Enabled     bool              `json:"enabled"`
 	AuthRealm   map[string]string `json:"authentication_realm"`
 	LookupRealm map[string]string `json:"lookup_realm"`
+	Token       *TokenInfo        `json:"token,omitempty"`
 }
 
 // Authenticate will return the SecurityInfo associated with the APIKey (retrieved from Elasticsearch).
@@ -67,3 +69,49 @@ func (k APIKey) Authenticate(ctx context.Context, es *elasticsearch.Client) (*Se
 
 	return &info, nil
 }
+
+// HasPrivileges reports whether the APIKey holds all of the index privileges on each of the indices.
+func (k APIKey) HasPrivileges(ctx context.Context, es *elasticsearch.Client, indices []string, privileges []string) (bool, error) {
+	token := fmt.Sprintf("%s%s", authPrefix, k.Token())
+
+	body, err := json.Marshal(map[string]interface{}{
+		"index": []map[string]interface{}{{
+			"names":      indices,
+			"privileges": privileges,
+		}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req := esapi.SecurityHasPrivilegesRequest{
+		Body:   bytes.NewReader(body),
+		Header: map[string][]string{AuthKey: []string{token}},
+	}
+
+	res, err := req.Do(ctx, es)
+	if err != nil {
+		return false, fmt.Errorf("apikey has privileges request %s: %w", k.ID, err)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.IsError() {
+		returnError := ErrUnauthorized
+		if res.StatusCode == 429 {
+			returnError = ErrElasticsearchAuthLimit
+		}
+		return false, fmt.Errorf("%w: %w", returnError, fmt.Errorf("apikey has privileges response %s: %s", k.ID, res.String()))
+	}
+
+	var resp struct {
+		HasAllRequested bool `json:"has_all_requested"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return false, fmt.Errorf("apikey has privileges parse %s: %w", k.ID, err)
+	}
+
+	return resp.HasAllRequested, nil
+}
@@ -7,7 +7,9 @@ package apikey
 import (
 	"context"
 	"encoding/base64"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/testing/esutil"
@@ -44,3 +46,35 @@ func TestAuth401(t *testing.T) {
 
 	assert.Equal(t, "unauthorized: apikey auth response  foo: [401 Unauthorized] ", err.Error())
 }
+
+func TestHasPrivileges(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte(rawToken))
+	apiKey, err := NewAPIKeyFromToken(token)
+	assert.NoError(t, err)
+
+	for _, has := range []bool{true, false} {
+		mockES, mockTransport := esutil.MockESClient(t)
+		mockTransport.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/_security/user/_has_privileges", req.URL.Path)
+			assert.Equal(t, "ApiKey "+token, req.Header.Get(AuthKey))
+			body := `{"has_all_requested":false}`
+			if has {
+				body = `{"has_all_requested":true}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		}
+		ok, err := apiKey.HasPrivileges(context.Background(), mockES, []string{".fleet-fileds-fromhost-meta-agent"}, []string{"read"})
+		assert.NoError(t, err)
+		assert.Equal(t, has, ok)
+	}
+}
+
+func TestHasPrivileges401(t *testing.T) {
+	ctx, apiKey, mockES := setup(t, 401)
+	_, err := apiKey.HasPrivileges(ctx, mockES, []string{"index"}, []string{"read"})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
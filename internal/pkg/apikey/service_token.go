@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+const bearerPrefix = "Bearer "
+
+// TokenInfo describes the token used to authenticate a request.
+type TokenInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ServiceTokenIdentity returns the identity of a service token, "<service account>/<token name>".
+// It returns an empty string if info was not authenticated with a service token.
+func (info *SecurityInfo) ServiceTokenIdentity() string {
+	if info == nil || info.Token == nil || info.Token.Name == "" || !strings.HasPrefix(info.Token.Type, "_service_account") {
+		return ""
+	}
+	return info.UserName + "/" + info.Token.Name
+}
+
+// ExtractServiceToken returns the bearer token of the request.
+// It returns false if the request does not use bearer authentication.
+func ExtractServiceToken(r *http.Request) (string, bool) {
+	s, ok := r.Header[AuthKey]
+	if !ok || len(s) != 1 || !strings.HasPrefix(s[0], bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(s[0][len(bearerPrefix):])
+	return token, token != ""
+}
+
+// ServiceTokenAuthenticate returns the SecurityInfo associated with an Elasticsearch service token.
+// Note: Prefer the bulk wrapper on this API
+func ServiceTokenAuthenticate(ctx context.Context, es *elasticsearch.Client, token string) (*SecurityInfo, error) {
+	req := esapi.SecurityAuthenticateRequest{
+		Header: map[string][]string{AuthKey: []string{bearerPrefix + token}},
+	}
+
+	res, err := req.Do(ctx, es)
+	if err != nil {
+		return nil, fmt.Errorf("service token auth request: %w", err)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	if res.IsError() {
+		returnError := ErrUnauthorized
+		if res.StatusCode == 429 {
+			returnError = ErrElasticsearchAuthLimit
+		}
+		return nil, fmt.Errorf("%w: %w", returnError, fmt.Errorf("service token auth response: %s", res.String()))
+	}
+
+	var info SecurityInfo
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("service token auth parse: %w", err)
+	}
+	if info.ServiceTokenIdentity() == "" {
+		return nil, fmt.Errorf("%w: credentials are not a service token", ErrUnauthorized)
+	}
+	return &info, nil
+}
@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apikey
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/esutil"
+)
+
+func TestExtractServiceToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		token  string
+		ok     bool
+	}{
+		{name: "no header"},
+		{name: "api key", header: []string{"ApiKey Zm9vOmJhcg=="}},
+		{name: "empty bearer", header: []string{"Bearer  "}},
+		{name: "multiple headers", header: []string{"Bearer a", "Bearer b"}},
+		{name: "bearer", header: []string{"Bearer AAEAAWVsYXN0aWM "}, token: "AAEAAWVsYXN0aWM", ok: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &http.Request{Header: http.Header{}}
+			if tc.header != nil {
+				r.Header[AuthKey] = tc.header
+			}
+			token, ok := ExtractServiceToken(r)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.token, token)
+		})
+	}
+}
+
+func TestServiceTokenAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		identity string
+		err      error
+	}{{
+		name:     "service token",
+		status:   http.StatusOK,
+		body:     `{"username":"elastic/fleet-server","token":{"name":"agents","type":"_service_account_index"}}`,
+		identity: "elastic/fleet-server/agents",
+	}, {
+		name:   "not a service token",
+		status: http.StatusOK,
+		body:   `{"username":"elastic","token":{"name":"access","type":"_oauth2"}}`,
+		err:    ErrUnauthorized,
+	}, {
+		name:   "unauthorized",
+		status: http.StatusUnauthorized,
+		body:   `{}`,
+		err:    ErrUnauthorized,
+	}, {
+		name:   "auth limit",
+		status: http.StatusTooManyRequests,
+		body:   `{}`,
+		err:    ErrElasticsearchAuthLimit,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockES, mockTransport := esutil.MockESClient(t)
+			mockTransport.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/_security/_authenticate", req.URL.Path)
+				assert.Equal(t, "Bearer token", req.Header.Get(AuthKey))
+				return &http.Response{
+					StatusCode: tc.status,
+					Header:     http.Header{"Content-Type": []string{"application/json"}, "X-Elastic-Product": []string{"Elasticsearch"}},
+					Body:       io.NopCloser(strings.NewReader(tc.body)),
+				}, nil
+			}
+			info, err := ServiceTokenAuthenticate(context.Background(), mockES, "token")
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.identity, info.ServiceTokenIdentity())
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package audit records audit events for the security relevant operations of fleet-server.
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// Action is an operation recorded by the audit events.
+type Action string
+
+const (
+	ActionAPIKeyCreate     Action = "api-key-create"
+	ActionAPIKeyUpdate     Action = "api-key-update"
+	ActionAPIKeyInvalidate Action = "api-key-invalidate"
+	// ActionAPIKeyInvalidateQueued is recorded when keys are queued for a background invalidation,
+	// the invalidation itself is recorded as ActionAPIKeyInvalidate once it is sent.
+	ActionAPIKeyInvalidateQueued Action = "api-key-invalidate-queued"
+)
+
+// Reasons of the API key operations.
+const (
+	ReasonEnroll            = "enroll"
+	ReasonReEnroll          = "re-enroll"
+	ReasonEnrollRollback    = "enroll-rollback"
+	ReasonOutputKey         = "output-key-missing"
+	ReasonOutputChanged     = "output-changed"
+	ReasonOutputRemoved     = "output-removed"
+	ReasonRotation          = "rotation"
+	ReasonPermissionsChange = "permissions-changed"
+	ReasonRolesCleanup      = "stale-roles-removed"
+	ReasonRetired           = "retired"
+	ReasonUnenroll          = "unenroll"
+	ReasonAgentInactive     = "agent-inactive"
+	ReasonRevoked           = "revoked"
+)
+
+const (
+	fieldAPIKeyIDs    = "fleet.apikey.ids"
+	fieldAPIKeyReason = "fleet.apikey.reason"
+)
+
+// Counter is incremented by the audit events to report the API key operations.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// APIKeyMetrics are the counters API key audit events report to, nil counters are ignored.
+type APIKeyMetrics struct {
+	Created     Counter
+	Updated     Counter
+	Invalidated Counter
+	Failed      Counter
+}
+
+var (
+	metricsMut sync.RWMutex
+	metrics    APIKeyMetrics
+)
+
+// SetAPIKeyMetrics sets the counters the API key audit events report to.
+func SetAPIKeyMetrics(m APIKeyMetrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metrics = m
+}
+
+// APIKeyEvent describes an operation on API keys.
+type APIKeyEvent struct {
+	Action Action
+	Reason string
+	// AgentID is the agent the keys belong to, it is empty for the batched invalidations of several agents.
+	AgentID string
+	// Output is the name of the output of the keys, it is empty for the access keys and the keys of the fleet-server output.
+	Output string
+	IDs    []string
+}
+
+// APIKey logs e as an audit event and records it in the audit log, err is the error of the operation.
+// The keys of the operation are added to the matching counter.
+func APIKey(zlog zerolog.Logger, e APIKeyEvent, err error) {
+	ev := zlog.Info()
+	outcome := "success"
+	switch {
+	case err != nil:
+		ev = zlog.Warn().Err(err)
+		outcome = "failure"
+	case e.Action == ActionAPIKeyInvalidateQueued:
+		outcome = "unknown"
+	}
+	ev = ev.Str(logger.ECSEventAction, string(e.Action)).
+		Str(logger.ECSEventOutcome, outcome).
+		Strs(fieldAPIKeyIDs, e.IDs)
+	if e.Reason != "" {
+		ev = ev.Str(fieldAPIKeyReason, e.Reason)
+	}
+	if e.AgentID != "" {
+		ev = ev.Str(logger.AgentID, e.AgentID)
+	}
+	if e.Output != "" {
+		ev = ev.Str(logger.PolicyOutputName, e.Output)
+	}
+	ev.Msg("api key audit")
+
+	fields := map[string]interface{}{fieldAPIKeyIDs: e.IDs}
+	if e.Reason != "" {
+		fields[fieldAPIKeyReason] = e.Reason
+	}
+	if e.Output != "" {
+		fields[logger.PolicyOutputName] = e.Output
+	}
+	Record(zlog.WithContext(context.Background()), Event{Action: e.Action, Outcome: outcome, AgentID: e.AgentID, Fields: fields}, err)
+
+	metricsMut.RLock()
+	m := metrics
+	metricsMut.RUnlock()
+	var c Counter
+	switch {
+	case err != nil:
+		c = m.Failed
+	case e.Action == ActionAPIKeyCreate:
+		c = m.Created
+	case e.Action == ActionAPIKeyUpdate:
+		c = m.Updated
+	case e.Action == ActionAPIKeyInvalidate:
+		c = m.Invalidated
+	}
+	n := len(e.IDs)
+	if err != nil && n == 0 {
+		// a failed create has no key ID
+		n = 1
+	}
+	if c != nil && n > 0 {
+		c.Add(uint64(n))
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCounter uint64
+
+func (c *testCounter) Add(delta uint64) {
+	*c += testCounter(delta)
+}
+
+func TestAPIKey(t *testing.T) {
+	var created, updated, invalidated, failed testCounter
+	SetAPIKeyMetrics(APIKeyMetrics{
+		Created:     &created,
+		Updated:     &updated,
+		Invalidated: &invalidated,
+		Failed:      &failed,
+	})
+	t.Cleanup(func() {
+		SetAPIKeyMetrics(APIKeyMetrics{})
+	})
+
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyCreate, Reason: ReasonEnroll, AgentID: "agent", IDs: []string{"a"}}, nil)
+	assert.JSONEq(t, `{"level":"info","event.action":"api-key-create","event.outcome":"success","fleet.apikey.ids":["a"],"fleet.apikey.reason":"enroll","fleet.agent.id":"agent","message":"api key audit"}`, buf.String())
+
+	buf.Reset()
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyInvalidate, Output: "remote", IDs: []string{"b", "c"}}, errors.New("unavailable"))
+	assert.JSONEq(t, `{"level":"warn","error":"unavailable","event.action":"api-key-invalidate","event.outcome":"failure","fleet.apikey.ids":["b","c"],"fleet.policy.output.name":"remote","message":"api key audit"}`, buf.String())
+
+	buf.Reset()
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyInvalidateQueued, IDs: []string{"d"}}, nil)
+	assert.Contains(t, buf.String(), `"event.outcome":"unknown"`)
+
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyUpdate, IDs: []string{"e"}}, nil)
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyInvalidate, IDs: []string{"f", "g"}}, nil)
+	APIKey(log, APIKeyEvent{Action: ActionAPIKeyCreate}, errors.New("failed"))
+
+	assert.Equal(t, testCounter(1), created)
+	assert.Equal(t, testCounter(1), updated)
+	assert.Equal(t, testCounter(2), invalidated, "queued invalidations are not counted")
+	assert.Equal(t, testCounter(3), failed)
+}
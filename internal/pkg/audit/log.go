@@ -0,0 +1,350 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/file"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// Actions of the events of the audit log, the API key operations are recorded with their own actions.
+const (
+	ActionAgentEnroll    Action = "agent-enroll"
+	ActionAgentUnenroll  Action = "agent-unenroll"
+	ActionAgentAck       Action = "agent-ack"
+	ActionAgentRevoke    Action = "agent-revoke"
+	ActionAgentReassign  Action = "agent-reassign"
+	ActionAgentSearch    Action = "agent-search"
+	ActionUploadStart    Action = "file-upload-start"
+	ActionUploadFinish   Action = "file-upload-finish"
+	ActionUploadRead     Action = "file-upload-read"
+	ActionPolicyLeaders  Action = "policy-leaders-get"
+	ActionProfilerUpdate Action = "profiler-update"
+	ActionDiagnostics    Action = "diagnostics-get"
+	ActionServerDrain    Action = "server-drain"
+	ActionServerPromote  Action = "server-promote"
+)
+
+// Outcomes of the events.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeUnknown = "unknown"
+)
+
+const (
+	ecsVersion   = "1.6.0"
+	auditDataset = "fleet_server.audit"
+
+	fieldEventHash = "event.hash"
+
+	// fileExt is the extension the rotator adds to the files of the audit log.
+	fileExt = ".ndjson"
+
+	// tailSize is the size of the end of the audit log read on open to continue the chain of the hashes.
+	tailSize = 64 * 1024
+)
+
+// Event is an event of the audit log.
+type Event struct {
+	Action Action
+	// Outcome is the outcome of the operation, it is set from the error of the operation when empty.
+	Outcome    string
+	AgentID    string
+	PolicyID   string
+	APIKeyID   string
+	RequestID  string
+	ClientIP   string
+	StatusCode int
+	// Fields are the additional fields of the event.
+	Fields map[string]interface{}
+}
+
+// ErrHeadNotFound is returned by Verify when the anchored hash of the last event is not found, the events after it
+// were removed.
+var ErrHeadNotFound = errors.New("the anchored audit log head is not found")
+
+// Log writes the events of the audit log as ECS documents, one by line. The event.hash of an event is the
+// HMAC-SHA256, with the key of the configuration, of the event.hash of the previous event and of the line of the event
+// without its event.hash, so the events can not be removed or altered without breaking the chain or knowing the key.
+// The hash of the last event, the head of the chain, is anchored in the fleet-server log at most every anchor interval
+// and on close, so the events removed from the end of the files are detected up to the last anchor.
+type Log struct {
+	mut        sync.Mutex
+	out        io.WriteCloser
+	key        []byte
+	prev       string
+	now        func() time.Time
+	log        zerolog.Logger
+	anchor     time.Duration
+	anchoredAt time.Time
+	anchored   bool
+}
+
+// Open opens the audit log of cfg, the chain of the hashes continues from the last event of the file. The heads of the
+// chain are anchored in the logger of ctx.
+func Open(ctx context.Context, cfg config.Audit) (*Log, error) {
+	key, err := cfg.DecodeKey()
+	if err != nil {
+		return nil, err
+	}
+	files := cfg.Files
+	filename := filepath.Join(files.Path, files.Name)
+	prev, err := lastHash(filename + "-*" + fileExt)
+	if err != nil {
+		return nil, err
+	}
+	rotator, err := file.NewFileRotator(filename,
+		file.MaxSizeBytes(files.MaxSize),
+		file.MaxBackups(files.MaxBackups),
+		file.Permissions(os.FileMode(files.Permissions)),
+		file.Interval(files.Interval),
+		file.RotateOnStartup(files.RotateOnStartup),
+	)
+	if err != nil {
+		return nil, err
+	}
+	l := newLog(rotator, key, prev)
+	l.log = zerolog.Ctx(ctx).With().Str("ctx", "audit log").Logger()
+	l.anchor = cfg.AnchorInterval
+	return l, nil
+}
+
+func newLog(out io.WriteCloser, key []byte, prev string) *Log {
+	return &Log{out: out, key: key, prev: prev, now: time.Now, log: zerolog.Nop(), anchored: true}
+}
+
+// lastHash returns the event.hash of the last event of the files of the audit log that match pattern, empty when
+// there is none.
+func lastHash(pattern string) (string, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", err
+	}
+	var (
+		last    string
+		lastMod time.Time
+	)
+	for _, filename := range filenames {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return "", err
+		}
+		if info.Size() > 0 && !info.ModTime().Before(lastMod) {
+			last, lastMod = filename, info.ModTime()
+		}
+	}
+	if last == "" {
+		return "", nil
+	}
+	return fileHash(last)
+}
+
+// fileHash returns the event.hash of the last event of the file.
+func fileHash(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	offset := info.Size() - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	var last map[string]interface{}
+	if err := json.Unmarshal(lines[len(lines)-1], &last); err != nil {
+		// a partial line of a crash starts a new chain
+		return "", nil //nolint:nilerr // see above
+	}
+	hash, _ := last[fieldEventHash].(string)
+	return hash, nil
+}
+
+// Close anchors the head of the chain and closes the file of the audit log.
+func (l *Log) Close() error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	if !l.anchored {
+		l.anchorHead()
+	}
+	return l.out.Close()
+}
+
+// anchorHead writes the hash of the last event to the fleet-server log, apart from the files of the audit log.
+func (l *Log) anchorHead() {
+	l.log.Info().Str(fieldEventHash, l.prev).Msg("Audit log head")
+	l.anchoredAt, l.anchored = l.now(), true
+}
+
+// Write writes e to the audit log, err is the error of the operation.
+func (l *Log) Write(e Event, err error) error {
+	doc := make(map[string]interface{}, len(e.Fields)+16)
+	for k, v := range e.Fields {
+		doc[k] = v
+	}
+	outcome := e.Outcome
+	if outcome == "" {
+		outcome = OutcomeSuccess
+		if err != nil {
+			outcome = OutcomeFailure
+		}
+	}
+	doc["ecs.version"] = ecsVersion
+	doc["event.kind"] = "event"
+	doc["event.dataset"] = auditDataset
+	doc[logger.ECSEventAction] = string(e.Action)
+	doc[logger.ECSEventOutcome] = outcome
+	doc[logger.ECSMessage] = "audit " + string(e.Action)
+	setNotEmpty(doc, logger.AgentID, e.AgentID)
+	setNotEmpty(doc, logger.PolicyID, e.PolicyID)
+	setNotEmpty(doc, logger.APIKeyID, e.APIKeyID)
+	setNotEmpty(doc, logger.ECSHTTPRequestID, e.RequestID)
+	setNotEmpty(doc, logger.ECSClientIP, e.ClientIP)
+	if e.StatusCode != 0 {
+		doc[logger.ECSHTTPResponseCode] = e.StatusCode
+	}
+	if err != nil {
+		doc[logger.ECSErrorMessage] = err.Error()
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	doc[logger.ECSTimestamp] = l.now().UTC().Format(time.RFC3339Nano)
+	line, mErr := json.Marshal(doc)
+	if mErr != nil {
+		return mErr
+	}
+	doc[fieldEventHash] = chainHash(l.key, l.prev, line)
+	line, mErr = json.Marshal(doc)
+	if mErr != nil {
+		return mErr
+	}
+	if _, wErr := l.out.Write(append(line, '\n')); wErr != nil {
+		return wErr
+	}
+	l.prev = doc[fieldEventHash].(string) //nolint:errcheck // set above
+	l.anchored = false
+	if l.now().Sub(l.anchoredAt) >= l.anchor {
+		l.anchorHead()
+	}
+	return nil
+}
+
+// chainHash returns the event.hash of the line of an event without its event.hash, prev is the event.hash of the
+// previous event.
+func chainHash(key []byte, prev string, line []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(prev))
+	h.Write(line)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify checks the chain of the hashes of the events of r with key, prev is the event.hash of the event before the
+// first one, empty at the start of the audit log. head is the last hash anchored in the fleet-server log, the chain
+// must contain it; it is not checked when empty. It returns the number of the line of the first event that breaks the
+// chain, 0 when the chain is intact, and ErrHeadNotFound when the events after the head were removed.
+func Verify(r io.Reader, key []byte, prev, head string) (int, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	found := head == ""
+	for n := 1; ; n++ {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			if !found {
+				return 0, ErrHeadNotFound
+			}
+			return 0, nil
+		} else if err != nil {
+			return n, err
+		}
+		hash, _ := doc[fieldEventHash].(string)
+		delete(doc, fieldEventHash)
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return n, err
+		}
+		if !hmac.Equal([]byte(chainHash(key, prev, line)), []byte(hash)) {
+			return n, nil
+		}
+		found = found || hash == head
+		prev = hash
+	}
+}
+
+func setNotEmpty(doc map[string]interface{}, key, value string) {
+	if value != "" {
+		doc[key] = value
+	}
+}
+
+var (
+	logMut   sync.RWMutex
+	auditLog *Log
+)
+
+// SetLog sets the audit log the events are recorded to, nil disables the audit log.
+func SetLog(l *Log) {
+	logMut.Lock()
+	defer logMut.Unlock()
+	auditLog = l
+}
+
+func getLog() *Log {
+	logMut.RLock()
+	defer logMut.RUnlock()
+	return auditLog
+}
+
+// Record records e in the audit log when it is enabled, err is the error of the operation.
+func Record(ctx context.Context, e Event, err error) {
+	l := getLog()
+	if l == nil {
+		return
+	}
+	if wErr := l.Write(e, err); wErr != nil {
+		zerolog.Ctx(ctx).Error().Err(wErr).Str(logger.ECSEventAction, string(e.Action)).Msg("unable to write the audit event")
+	}
+}
+
+type ctxEventKey struct{}
+
+// WithEvent returns a context that carries e, the handlers of a request add the fields they know to it with
+// FromContext.
+func WithEvent(ctx context.Context, e *Event) context.Context {
+	return context.WithValue(ctx, ctxEventKey{}, e)
+}
+
+// FromContext returns the event of the request of ctx, or an event that is not recorded when there is none.
+func FromContext(ctx context.Context) *Event {
+	if e, ok := ctx.Value(ctxEventKey{}).(*Event); ok {
+		return e
+	}
+	return &Event{}
+}
@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestLogWrite(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLog(nopCloser{&buf}, testKey, "")
+	l.now = func() time.Time { return time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, l.Write(Event{Action: ActionAgentEnroll, AgentID: "agent", PolicyID: "policy", APIKeyID: "key", RequestID: "req", ClientIP: "10.0.0.1", StatusCode: 200}, nil))
+	require.NoError(t, l.Write(Event{Action: ActionAgentRevoke, Fields: map[string]interface{}{"fleet.revoke.reason": "test"}}, errors.New("not found")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "1.6.0", first["ecs.version"])
+	assert.Equal(t, "fleet_server.audit", first["event.dataset"])
+	assert.Equal(t, "agent-enroll", first["event.action"])
+	assert.Equal(t, "success", first["event.outcome"])
+	assert.Equal(t, "agent", first["fleet.agent.id"])
+	assert.Equal(t, "policy", first["fleet.policy.id"])
+	assert.Equal(t, "key", first["fleet.apikey.id"])
+	assert.Equal(t, "req", first["http.request.id"])
+	assert.Equal(t, "10.0.0.1", first["client.ip"])
+	assert.EqualValues(t, 200, first["http.response.status_code"])
+	assert.Equal(t, "2024-06-10T12:00:00Z", first["@timestamp"])
+
+	assert.Equal(t, "failure", second["event.outcome"])
+	assert.Equal(t, "not found", second["error.message"])
+	assert.Equal(t, "test", second["fleet.revoke.reason"])
+	assert.NotContains(t, second, "fleet.agent.id")
+	assert.NotEqual(t, first["event.hash"], second["event.hash"])
+
+	n, err := Verify(strings.NewReader(buf.String()), testKey, "", "")
+	require.NoError(t, err)
+	assert.Zero(t, n, "the chain is intact")
+
+	n, err = Verify(strings.NewReader(buf.String()), []byte("another key of thirty two bytes!"), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "the chain is not valid with another key")
+}
+
+func TestVerifyTampered(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLog(nopCloser{&buf}, testKey, "")
+	for _, action := range []Action{ActionAgentEnroll, ActionAgentAck, ActionAgentUnenroll} {
+		require.NoError(t, l.Write(Event{Action: action, AgentID: "agent"}, nil))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	altered := strings.Join([]string{lines[0], strings.Replace(lines[1], `"agent"`, `"other"`, 1), lines[2]}, "\n")
+	n, err := Verify(strings.NewReader(altered), testKey, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "the altered event breaks the chain")
+
+	removed := strings.Join([]string{lines[0], lines[2]}, "\n")
+	n, err = Verify(strings.NewReader(removed), testKey, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "the event after the removed one breaks the chain")
+
+	var last map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &last))
+	head := last["event.hash"].(string)
+	n, err = Verify(strings.NewReader(strings.Join(lines, "\n")), testKey, "", head)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	_, err = Verify(strings.NewReader(strings.Join(lines[:2], "\n")), testKey, "", head)
+	assert.ErrorIs(t, err, ErrHeadNotFound, "the events removed from the end are detected with the anchored head")
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	cfg := config.Audit{Enabled: true}
+	cfg.InitDefaults()
+	cfg.Key = base64.StdEncoding.EncodeToString(testKey)
+	cfg.Files.Path = t.TempDir()
+	var logs bytes.Buffer
+	ctx := zerolog.New(&logs).WithContext(context.Background())
+
+	l, err := Open(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, l.Write(Event{Action: ActionServerDrain}, nil))
+	require.NoError(t, l.Close())
+
+	l, err = Open(ctx, cfg)
+	require.NoError(t, err)
+	require.NoError(t, l.Write(Event{Action: ActionServerPromote}, nil))
+	require.NoError(t, l.Write(Event{Action: ActionServerDrain}, nil))
+	require.NoError(t, l.Close())
+
+	files, err := filepath.Glob(filepath.Join(cfg.Files.Path, cfg.Files.Name+"-*.ndjson"))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "the file is not rotated on startup")
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	// the head is anchored on the first event of each run, then every anchor interval and on close
+	heads := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, heads, 3)
+	var anchor map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(heads[2]), &anchor))
+	assert.Equal(t, "Audit log head", anchor["message"])
+	n, err := Verify(f, testKey, "", anchor["event.hash"].(string))
+	require.NoError(t, err)
+	assert.Zero(t, n, "the chain continues across the restarts")
+}
+
+func TestRecord(t *testing.T) {
+	Record(context.Background(), Event{Action: ActionAgentAck}, nil) // no audit log, nothing is recorded
+
+	var buf bytes.Buffer
+	SetLog(newLog(nopCloser{&buf}, testKey, ""))
+	t.Cleanup(func() {
+		SetLog(nil)
+	})
+
+	ctx := WithEvent(context.Background(), &Event{Action: ActionAgentAck})
+	FromContext(ctx).AgentID = "agent"
+	Record(ctx, *FromContext(ctx), nil)
+	assert.Contains(t, buf.String(), `"fleet.agent.id":"agent"`)
+
+	FromContext(context.Background()).AgentID = "ignored"
+	assert.Empty(t, FromContext(context.Background()).AgentID)
+}
@@ -14,9 +14,13 @@ import (
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TODO:
@@ -396,3 +400,82 @@ func BenchmarkMockBulk(b *testing.B) {
 		b.Run(strconv.Itoa(n), bindFunc(n))
 	}
 }
+
+type blockingBulkTransport struct {
+	mockBulkTransport
+	release chan struct{}
+}
+
+func (m *blockingBulkTransport) Perform(req *http.Request) (*http.Response, error) {
+	<-m.release
+	return m.mockBulkTransport.Perform(req)
+}
+
+func TestQueueDepth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := &blockingBulkTransport{release: make(chan struct{})}
+	bulker := NewBulker(transport, nil, WithFlushThresholdCount(1))
+	go func() { _ = bulker.Run(ctx) }()
+
+	errCh := make(chan error)
+	go func() {
+		_, err := bulker.Create(ctx, "test", "1", []byte(`{}`))
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool { return bulker.QueueDepth() == 1 }, time.Second, time.Millisecond, "the operation is queued until the flush completes")
+
+	close(transport.release)
+	require.NoError(t, <-errCh)
+	assert.Eventually(t, func() bool { return bulker.QueueDepth() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestWriteMsearchBodyTimeout(t *testing.T) {
+	bulker := NewBulker(nil, nil)
+	tests := []struct {
+		name    string
+		body    string
+		timeout time.Duration
+		want    string
+	}{
+		{"no timeout", `{"size":1}`, 0, `{"size":1}`},
+		{"timeout", `{"size":1}`, 1500 * time.Millisecond, `{"timeout":"1500ms","size":1}`},
+		{"empty body", ` {} `, time.Second, `{"timeout":"1000ms"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf Buf
+			require.NoError(t, bulker.writeMsearchBody(&buf, []byte(tc.body), tc.timeout))
+			assert.Equal(t, tc.want+"\n", string(buf.Bytes()))
+		})
+	}
+
+	var buf Buf
+	assert.ErrorIs(t, bulker.writeMsearchBody(&buf, []byte(`{"size":`), time.Second), es.ErrInvalidBody)
+}
+
+func TestWriteMsearchMetaPreference(t *testing.T) {
+	bulker := NewBulker(nil, nil, WithSearchPreference("_local"))
+
+	var buf Buf
+	opt := bulker.parseOpts(WithIgnoreUnavailble())
+	require.NoError(t, bulker.writeMsearchMeta(&buf, ".fleet-agents", nil, nil, opt.IgnoreUnavailable, opt.Preference))
+	assert.JSONEq(t, `{"index":".fleet-agents","ignore_unavailable":true,"preference":"_local"}`, string(buf.Bytes()))
+
+	buf.Reset()
+	opt = bulker.parseOpts(WithPreference("agent-1"))
+	require.NoError(t, bulker.writeMsearchMeta(&buf, ".fleet-agents", nil, nil, opt.IgnoreUnavailable, opt.Preference))
+	assert.JSONEq(t, `{"index":".fleet-agents","preference":"agent-1"}`, string(buf.Bytes()), "the preference of a search overrides the preference of the bulker")
+
+	buf.Reset()
+	require.NoError(t, NewBulker(nil, nil).writeMsearchMeta(&buf, "", nil, nil, false, ""))
+	assert.Equal(t, "{}\n", string(buf.Bytes()))
+}
+
+func TestSearchTimeout(t *testing.T) {
+	// the bulker is not running, the search waits until its timeout
+	bulker := NewBulker(nil, nil)
+	_, err := bulker.Search(context.Background(), "testidx", []byte(`{"hey":"now"}`), WithTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, err, es.ErrTimeout)
+}
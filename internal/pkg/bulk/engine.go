@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
@@ -69,9 +70,15 @@ type Bulk interface {
 	APIKeyInvalidate(ctx context.Context, ids ...string) error
 	APIKeyUpdate(ctx context.Context, id, outputPolicyHash string, roles []byte) error
 
+	// ServiceTokenAuth authenticates an Elasticsearch service token
+	ServiceTokenAuth(ctx context.Context, token string) (*SecurityInfo, error)
+
 	// Accessor used to talk to elastic search direcly bypassing bulk engine
 	Client() *elasticsearch.Client
 
+	// QueueDepth returns the number of operations queued and not flushed yet.
+	QueueDepth() int
+
 	CreateAndGetBulker(ctx context.Context, zlog zerolog.Logger, outputName string, outputMap map[string]map[string]interface{}) (Bulk, bool, error)
 	GetBulker(outputName string) Bulk
 	GetBulkerMap() map[string]Bulk
@@ -94,6 +101,7 @@ type Bulker struct {
 	bulkerMap             map[string]Bulk
 	cancelFn              context.CancelFunc
 	remoteOutputMutex     sync.RWMutex
+	queued                atomic.Int64 // operations queued and not flushed yet
 }
 
 const (
@@ -230,13 +238,19 @@ func (b *Bulker) createRemoteEsClient(ctx context.Context, outputName string, ou
 }
 
 func elasticsearchOptions(instumented bool, bi build.Info) []es.ConfigOption {
-	options := []es.ConfigOption{es.WithUserAgent("Remote-Fleet-Server", bi)}
+	options := []es.ConfigOption{es.WithUserAgent("Remote-Fleet-Server", bi), es.TimeRoundTripper()}
 	if instumented {
 		options = append(options, es.InstrumentRoundTripper())
 	}
 	return options
 }
 
+// QueueDepth returns the number of operations queued and not flushed yet, including the operations waiting
+// to be queued and the operations of the flushes in progress.
+func (b *Bulker) QueueDepth() int {
+	return len(b.ch) + int(b.queued.Load())
+}
+
 func (b *Bulker) Client() *elasticsearch.Client {
 	client, ok := b.es.(*elasticsearch.Client)
 	if !ok {
@@ -387,6 +401,7 @@ func (b *Bulker) Run(ctx context.Context) error {
 			// Update pending count on target queue
 			q.cnt += 1
 			q.pending += blk.buf.Len()
+			b.queued.Add(1)
 
 			// Update threshold counters
 			itemCnt += 1
@@ -457,6 +472,7 @@ func (b *Bulker) flushQueue(ctx context.Context, w *semaphore.Weighted, queue qu
 
 	go func() {
 		start := time.Now()
+		done := recordFlushStart(queue)
 
 		if b.tracer != nil {
 			trans := b.tracer.StartTransaction(fmt.Sprintf("Flush queue %s", queue.Type()), "bulker")
@@ -480,6 +496,8 @@ func (b *Bulker) flushQueue(ctx context.Context, w *semaphore.Weighted, queue qu
 			err = b.flushBulk(ctx, queue)
 		}
 
+		done(err)
+		b.queued.Add(-int64(queue.cnt))
 		if err != nil {
 			failQueue(queue, err)
 			apm.CaptureError(ctx, err).Send()
@@ -510,7 +528,7 @@ func failQueue(queue queueT, err error) {
 }
 
 func (b *Bulker) parseOpts(opts ...Opt) optionsT {
-	var opt optionsT
+	opt := optionsT{Preference: b.opts.searchPreference}
 	for _, o := range opts {
 		o(&opt)
 	}
@@ -567,6 +585,8 @@ func (b *Bulker) validateBody(body []byte) error {
 
 func (b *Bulker) dispatch(ctx context.Context, blk *bulkT) respT {
 	start := time.Now()
+	// the wait on the queue is part of the time spent on Elasticsearch
+	defer func() { es.AddTime(ctx, time.Since(start)) }()
 
 	// Dispatch to bulk Run loop
 	select {
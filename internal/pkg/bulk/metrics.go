@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package bulk
+
+import (
+	"sync"
+	"time"
+)
+
+// QueueTypes are the types of the queues of the bulker, as named in the metrics.
+var QueueTypes = []string{"bulk", "read", "search", "fleetSearch", "refreshBulk", "refreshRead", "apiKeyUpdate"}
+
+// Counter is a counter the bulker reports to.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// Gauge is a gauge the bulker reports to.
+type Gauge interface {
+	Inc()
+	Dec()
+}
+
+// Observer records the durations of the flushes in seconds.
+type Observer interface {
+	Observe(v float64)
+}
+
+// QueueMetrics are the metrics of one type of queue, nil entries are ignored.
+type QueueMetrics struct {
+	// Flushes is the number of flushes of the queue and Failures the number of flushes that failed.
+	Flushes  Counter
+	Failures Counter
+	// Ops and Bytes are the number of operations and the size of their payload flushed.
+	Ops   Counter
+	Bytes Counter
+	// Latency is the duration of the flushes, the round trip of the requests to Elasticsearch.
+	Latency Observer
+}
+
+// Metrics are the metrics the bulkers report to, including the bulkers of the remote outputs.
+type Metrics struct {
+	// Active is the number of flushes in progress.
+	Active Gauge
+	// Queues are the metrics by type of queue.
+	Queues map[string]QueueMetrics
+}
+
+var (
+	metricsMut sync.RWMutex
+	metrics    Metrics
+)
+
+// SetMetrics sets the metrics the bulkers report to.
+func SetMetrics(m Metrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metrics = m
+}
+
+func getMetrics() Metrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return metrics
+}
+
+// recordFlushStart reports the start of the flush of a queue, the returned func reports its end.
+func recordFlushStart(queue queueT) func(err error) {
+	m := getMetrics()
+	if m.Active != nil {
+		m.Active.Inc()
+	}
+	start := time.Now()
+	return func(err error) {
+		if m.Active != nil {
+			m.Active.Dec()
+		}
+		qm := m.Queues[queue.Type()]
+		add(qm.Flushes, 1)
+		add(qm.Ops, uint64(queue.cnt))
+		add(qm.Bytes, uint64(queue.pending))
+		if err != nil {
+			add(qm.Failures, 1)
+		}
+		if qm.Latency != nil {
+			qm.Latency.Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+func add(c Counter, delta uint64) {
+	if c != nil {
+		c.Add(delta)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package bulk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCounter uint64
+
+func (c *testCounter) Add(delta uint64) {
+	*c += testCounter(delta)
+}
+
+type testGauge int
+
+func (g *testGauge) Inc() { *g++ }
+func (g *testGauge) Dec() { *g-- }
+
+type testObserver []float64
+
+func (o *testObserver) Observe(v float64) {
+	*o = append(*o, v)
+}
+
+func TestQueueTypes(t *testing.T) {
+	require.Len(t, QueueTypes, int(kNumQueues))
+	for i := queueType(0); i < kNumQueues; i++ {
+		assert.Equal(t, queueT{ty: i}.Type(), QueueTypes[i])
+	}
+}
+
+func TestRecordFlush(t *testing.T) {
+	var (
+		active                         testGauge
+		flushes, failures, ops, nbytes testCounter
+		latency                        testObserver
+	)
+	SetMetrics(Metrics{
+		Active: &active,
+		Queues: map[string]QueueMetrics{
+			"read": {Flushes: &flushes, Failures: &failures, Ops: &ops, Bytes: &nbytes, Latency: &latency},
+		},
+	})
+	t.Cleanup(func() { SetMetrics(Metrics{}) })
+
+	done := recordFlushStart(queueT{ty: kQueueRead, cnt: 3, pending: 100})
+	assert.Equal(t, testGauge(1), active)
+	done(nil)
+	recordFlushStart(queueT{ty: kQueueRead, cnt: 1, pending: 10})(errors.New("unavailable"))
+	recordFlushStart(queueT{ty: kQueueBulk, cnt: 1, pending: 10})(nil)
+
+	assert.Equal(t, testGauge(0), active)
+	assert.Equal(t, testCounter(2), flushes)
+	assert.Equal(t, testCounter(1), failures)
+	assert.Equal(t, testCounter(4), ops)
+	assert.Equal(t, testCounter(110), nbytes)
+	assert.Len(t, latency, 2)
+}
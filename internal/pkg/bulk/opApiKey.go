@@ -51,6 +51,16 @@ func (b *Bulker) APIKeyAuth(ctx context.Context, key APIKey) (*SecurityInfo, err
 	return key.Authenticate(ctx, b.Client())
 }
 
+func (b *Bulker) ServiceTokenAuth(ctx context.Context, token string) (*SecurityInfo, error) {
+	span, ctx := apm.StartSpan(ctx, "authServiceToken", "auth")
+	defer span.End()
+	if err := b.apikeyLimit.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	defer b.apikeyLimit.Release(1)
+	return apikey.ServiceTokenAuthenticate(ctx, b.Client(), token)
+}
+
 func (b *Bulker) APIKeyCreate(ctx context.Context, name, ttl string, roles []byte, meta interface{}) (*APIKey, error) {
 	span, ctx := apm.StartSpan(ctx, "createAPIKey", "auth")
 	defer span.End()
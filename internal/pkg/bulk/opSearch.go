@@ -8,7 +8,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
@@ -23,6 +25,15 @@ func (b *Bulker) Search(ctx context.Context, index string, body []byte, opts ...
 	span, ctx := apm.StartSpan(ctx, "Bulker: search", "bulker")
 	defer span.End()
 	opt := b.parseOpts(append(opts, withAPMLinkedContext(ctx))...)
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
+	}
+	// do not queue the search of a request that is already cancelled
+	if err := ctx.Err(); err != nil {
+		return nil, searchError(err, opt.Timeout)
+	}
 	action := ActionSearch
 
 	// Use /_fleet/_fleet_msearch fleet plugin endpoint if need to wait for checkpoints
@@ -35,18 +46,18 @@ func (b *Bulker) Search(ctx context.Context, index string, body []byte, opts ...
 	const kSlop = 64
 	blk.buf.Grow(len(body) + kSlop)
 
-	if err := b.writeMsearchMeta(&blk.buf, index, opt.Indices, opt.WaitForCheckpoints, opt.IgnoreUnavailable); err != nil {
+	if err := b.writeMsearchMeta(&blk.buf, index, opt.Indices, opt.WaitForCheckpoints, opt.IgnoreUnavailable, opt.Preference); err != nil {
 		return nil, err
 	}
 
-	if err := b.writeMsearchBody(&blk.buf, body); err != nil {
+	if err := b.writeMsearchBody(&blk.buf, body, opt.Timeout); err != nil {
 		return nil, err
 	}
 
 	// Process response
 	resp := b.dispatch(ctx, blk)
 	if resp.err != nil {
-		return nil, resp.err
+		return nil, searchError(resp.err, opt.Timeout)
 	}
 	b.freeBlk(blk)
 
@@ -57,10 +68,14 @@ func (b *Bulker) Search(ctx context.Context, index string, body []byte, opts ...
 	if !ok {
 		return nil, fmt.Errorf("unable to cast response as type *MsearchResponseItem, detected type: %T", resp.data)
 	}
+	if opt.Timeout > 0 && r.TimedOut {
+		// the hits are the partial results collected before the timeout
+		return nil, fmt.Errorf("search timed out after %v: %w", opt.Timeout, es.ErrTimeout)
+	}
 	return &es.ResultT{HitsT: r.Hits, Aggregations: r.Aggregations}, nil
 }
 
-func (b *Bulker) writeMsearchMeta(buf *Buf, index string, moreIndices []string, checkpoints []int64, ignoreUnavailble bool) error {
+func (b *Bulker) writeMsearchMeta(buf *Buf, index string, moreIndices []string, checkpoints []int64, ignoreUnavailble bool, preference string) error {
 	if err := b.validateIndex(index); err != nil {
 		return err
 	}
@@ -99,6 +114,19 @@ func (b *Bulker) writeMsearchMeta(buf *Buf, index string, moreIndices []string,
 		needComma = true
 	}
 
+	if preference != "" {
+		if needComma {
+			_, _ = buf.WriteString(`,`)
+		}
+		_, _ = buf.WriteString(`"preference": `)
+		if d, err := json.Marshal(preference); err != nil {
+			return err
+		} else {
+			_, _ = buf.Write(d)
+		}
+		needComma = true
+	}
+
 	if len(checkpoints) > 0 {
 		if needComma {
 			_, _ = buf.WriteString(`,`)
@@ -113,11 +141,35 @@ func (b *Bulker) writeMsearchMeta(buf *Buf, index string, moreIndices []string,
 	return nil
 }
 
-func (b *Bulker) writeMsearchBody(buf *Buf, body []byte) error {
-	_, _ = buf.Write(body)
+// writeMsearchBody writes the body of a search, the timeout is added to the body when it is set.
+func (b *Bulker) writeMsearchBody(buf *Buf, body []byte, timeout time.Duration) error {
+	if err := b.validateBody(body); err != nil {
+		return err
+	}
+	trimmed := bytes.TrimSpace(body)
+	if timeout <= 0 || len(trimmed) < 2 || trimmed[0] != '{' {
+		_, _ = buf.Write(body)
+		_, _ = buf.WriteRune('\n')
+		return nil
+	}
+
+	_, _ = buf.WriteString(`{"timeout":"`)
+	_, _ = buf.WriteString(strconv.FormatInt(timeout.Milliseconds(), 10))
+	_, _ = buf.WriteString(`ms"`)
+	if rest := bytes.TrimSpace(trimmed[1:]); rest[0] != '}' {
+		_, _ = buf.WriteRune(',')
+	}
+	_, _ = buf.Write(trimmed[1:])
 	_, _ = buf.WriteRune('\n')
+	return nil
+}
 
-	return b.validateBody(body)
+// searchError returns es.ErrTimeout when the search was cancelled by its timeout.
+func searchError(err error, timeout time.Duration) error {
+	if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("search timed out after %v: %w", timeout, es.ErrTimeout)
+	}
+	return err
 }
 
 func (b *Bulker) flushSearch(ctx context.Context, queue queueT) error {
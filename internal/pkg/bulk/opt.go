@@ -25,6 +25,8 @@ type optionsT struct {
 	Indices            []string
 	WaitForCheckpoints []int64
 	IgnoreUnavailable  bool
+	Timeout            time.Duration
+	Preference         string
 	spanLink           *apm.SpanLink
 }
 
@@ -55,6 +57,23 @@ func WithIndex(idx string) Opt {
 	}
 }
 
+// WithTimeout sets the timeout of a search, it is sent as the timeout of the search to Elasticsearch and the search
+// returns es.ErrTimeout once it expires.
+func WithTimeout(d time.Duration) Opt {
+	return func(opt *optionsT) {
+		opt.Timeout = d
+	}
+}
+
+// WithPreference sets the preference of a search, the shard copies that run it: _local, _only_local,
+// _prefer_nodes:<nodes>, _only_nodes:<nodes>, _shards:<shards> or a custom string that sends the searches with the
+// same string to the same copies. It overrides the search preference of the bulker.
+func WithPreference(p string) Opt {
+	return func(opt *optionsT) {
+		opt.Preference = p
+	}
+}
+
 // WithWaitForCheckpoints will set the checkpoints parameters
 // Applicable to _fleet_msearch, wait_for_checkpoints parameters
 func WithWaitForCheckpoints(checkpoints []int64) Opt {
@@ -89,6 +108,7 @@ type bulkOptT struct {
 	apikeyMaxParallel int
 	apikeyMaxReqSize  int
 	policyTokens      []config.PolicyToken
+	searchPreference  string
 	bi                build.Info
 }
 
@@ -152,6 +172,14 @@ func WithPolicyTokens(tokens []config.PolicyToken) BulkOpt {
 	}
 }
 
+// WithSearchPreference sets the preference of the searches that do not set one. Without a preference Elasticsearch
+// picks the shard copies of a search with adaptive replica selection.
+func WithSearchPreference(p string) BulkOpt {
+	return func(opt *bulkOptT) {
+		opt.searchPreference = p
+	}
+}
+
 func WithBi(bi build.Info) BulkOpt {
 	return func(opt *bulkOptT) {
 		opt.bi = bi
@@ -185,6 +213,7 @@ func (o *bulkOptT) MarshalZerologObject(e *zerolog.Event) {
 	e.Int("blockQueueSz", o.blockQueueSz)
 	e.Int("apikeyMaxParallel", o.apikeyMaxParallel)
 	e.Int("apikeyMaxReqSize", o.apikeyMaxReqSize)
+	e.Str("searchPreference", o.searchPreference)
 }
 
 // BulkOptsFromCfg transforms config to a slize of BulkOpt
@@ -209,5 +238,6 @@ func BulkOptsFromCfg(cfg *config.Config) []BulkOpt {
 		WithAPIKeyMaxParallel(maxKeyParallel),
 		WithAPIKeyMaxRequestSize(cfg.Output.Elasticsearch.MaxContentLength),
 		WithPolicyTokens(policyTokens),
+		WithSearchPreference(bulkCfg.SearchPreference),
 	}
 }
@@ -0,0 +1,215 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	apiKeyStateInterval = time.Minute
+	apiKeyStateSaltSize = 32
+)
+
+// hashedAPIKey is the cached value of an API key loaded from the state file, it holds the HMAC of the key.
+type hashedAPIKey string
+
+type apiKeyStateEntry struct {
+	Hash    string    `json:"hash"`
+	Expires time.Time `json:"expires"`
+}
+
+// apiKeyStateFile is the content of the API key state file.
+type apiKeyStateFile struct {
+	Salt []byte                      `json:"salt"`
+	Keys map[string]apiKeyStateEntry `json:"keys"`
+}
+
+// apiKeyState tracks the API keys validated by the cache so they are written to a file and loaded again
+// when fleet-server restarts. Keys are stored as an HMAC with a random salt that is kept in the file, the
+// keys themselves are never written.
+type apiKeyState struct {
+	path string
+
+	mut     sync.Mutex
+	salt    []byte
+	entries map[string]apiKeyStateEntry
+	dirty   bool
+}
+
+func newAPIKeyState(path string) (*apiKeyState, error) {
+	salt := make([]byte, apiKeyStateSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate API key state salt: %w", err)
+	}
+	return &apiKeyState{
+		path:    path,
+		salt:    salt,
+		entries: make(map[string]apiKeyStateEntry),
+	}, nil
+}
+
+func (s *apiKeyState) hash(key string) string {
+	mac := hmac.New(sha256.New, s.salt)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matches returns true if h is the hash of key.
+func (s *apiKeyState) matches(h hashedAPIKey, key string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return hmac.Equal([]byte(h), []byte(s.hash(key)))
+}
+
+// add records a validated key until it expires.
+func (s *apiKeyState) add(id, key string, expires time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.entries[id] = apiKeyStateEntry{Hash: s.hash(key), Expires: expires}
+	s.dirty = true
+}
+
+// remove forgets a key, it is called when a key is found to be disabled.
+func (s *apiKeyState) remove(id string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, ok := s.entries[id]; ok {
+		delete(s.entries, id)
+		s.dirty = true
+	}
+}
+
+// load reads the state file, the salt of the file replaces the salt of s.
+// It returns the keys that have not expired.
+func (s *apiKeyState) load(now time.Time) (map[string]apiKeyStateEntry, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var f apiKeyStateFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode API key state file: %w", err)
+	}
+	if len(f.Salt) != apiKeyStateSaltSize {
+		return nil, errors.New("invalid API key state file salt")
+	}
+
+	valid := make(map[string]apiKeyStateEntry, len(f.Keys))
+	for id, e := range f.Keys {
+		if e.Expires.After(now) {
+			valid[id] = e
+		}
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.salt = f.Salt
+	s.entries = make(map[string]apiKeyStateEntry, len(valid))
+	for id, e := range valid {
+		s.entries[id] = e
+	}
+	return valid, nil
+}
+
+// save writes the keys that have not expired to the state file if they changed since the last save.
+func (s *apiKeyState) save(now time.Time) error {
+	s.mut.Lock()
+	changed := s.dirty
+	f := apiKeyStateFile{Salt: s.salt, Keys: make(map[string]apiKeyStateEntry, len(s.entries))}
+	for id, e := range s.entries {
+		if !e.Expires.After(now) {
+			delete(s.entries, id)
+			changed = true
+			continue
+		}
+		f.Keys[id] = e
+	}
+	s.dirty = false
+	s.mut.Unlock()
+	if !changed {
+		return nil
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file that is renamed into place, so a partial write never replaces the state.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".api-key-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // removal fails once the file is renamed
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// RunAPIKeyState writes the validated API keys to the API key state file at an interval and when the
+// context is cancelled. It does nothing while cache.api_key_state_path is not set.
+func (c *CacheT) RunAPIKeyState(ctx context.Context) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "api key cache state").Logger()
+	tick := time.NewTicker(apiKeyStateInterval)
+	defer tick.Stop()
+
+	save := func() {
+		c.mut.RLock()
+		keys := c.keys
+		c.mut.RUnlock()
+		if keys == nil {
+			return
+		}
+		if err := keys.save(time.Now()); err != nil {
+			log.Warn().Err(err).Str("path", keys.path).Msg("Failed to save the API key cache state")
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return nil
+		case <-tick.C:
+			save()
+		}
+	}
+}
+
+// validHashedAPIKey returns true if v is a key loaded from the state file that matches key.
+func (c *CacheT) validHashedAPIKey(v interface{}, key string) bool {
+	h, ok := v.(hashedAPIKey)
+	return ok && c.keys != nil && c.keys.matches(h, key)
+}
+
+// warmAPIKeys adds the keys of the state to the cache.
+// WARNING: Expects c.mut to be held.
+func (c *CacheT) warmAPIKeys(entries map[string]apiKeyStateEntry, now time.Time) {
+	for id, e := range entries {
+		scopedKey := "api:" + id
+		c.cache.SetWithTTL(scopedKey, hashedAPIKey(e.Hash), int64(len(scopedKey)+len(e.Hash)), e.Expires.Sub(now))
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// mapCacher is a Cacher that stores every value synchronously.
+type mapCacher struct {
+	mut     sync.Mutex
+	entries map[interface{}]interface{}
+}
+
+func newMapCacher() *mapCacher {
+	return &mapCacher{entries: make(map[interface{}]interface{})}
+}
+
+func (m *mapCacher) Get(key interface{}) (interface{}, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	v, ok := m.entries[key]
+	return v, ok
+}
+
+func (m *mapCacher) Set(key, value interface{}, _ int64) bool {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.entries[key] = value
+	return true
+}
+
+func (m *mapCacher) SetWithTTL(key, value interface{}, cost int64, _ time.Duration) bool {
+	return m.Set(key, value, cost)
+}
+
+func (m *mapCacher) Del(key interface{}) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	delete(m.entries, key)
+}
+
+func (m *mapCacher) Close() {}
+
+func TestAPIKeyState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := config.Cache{APIKeyTTL: time.Hour, APIKeyStatePath: path}
+
+	c := &CacheT{cache: newMapCacher(), cfg: cfg}
+	require.NoError(t, c.setAPIKeyState(path))
+	c.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, true)
+	c.SetAPIKey(APIKey{ID: "disabled", Key: "disabled-secret"}, true)
+	c.SetAPIKey(APIKey{ID: "disabled", Key: "disabled-secret"}, false)
+	c.keys.add("expired", "expired-secret", time.Now().Add(-time.Minute))
+	require.NoError(t, c.keys.save(time.Now()))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "valid-secret", "only a hash of the keys is written")
+	assert.NotContains(t, string(b), "disabled")
+	assert.NotContains(t, string(b), "expired")
+
+	restarted := &CacheT{cache: newMapCacher(), cfg: cfg}
+	require.NoError(t, restarted.setAPIKeyState(path))
+	assert.True(t, restarted.ValidAPIKey(APIKey{ID: "valid", Key: "valid-secret"}))
+	assert.False(t, restarted.ValidAPIKey(APIKey{ID: "valid", Key: "other-secret"}))
+	assert.False(t, restarted.ValidAPIKey(APIKey{ID: "disabled", Key: "disabled-secret"}))
+
+	t.Run("tracked keys are warmed into a new cache", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+		restarted.cache = newMapCacher()
+		restarted.warmAPIKeys(restarted.keys.entries, time.Now())
+		assert.True(t, restarted.ValidAPIKey(APIKey{ID: "valid", Key: "valid-secret"}))
+	})
+}
+
+func TestAPIKeyStateInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"salt":"c2hvcnQ=","keys":{}}`), 0o600))
+
+	c := &CacheT{cache: newMapCacher()}
+	require.NoError(t, c.setAPIKeyState(path), "a broken state file does not fail the cache")
+	require.NotNil(t, c.keys)
+
+	c.cfg.APIKeyTTL = time.Hour
+	c.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, true)
+	require.NoError(t, c.keys.save(time.Now()))
+	_, err := c.keys.load(time.Now())
+	require.NoError(t, err, "the state file is replaced")
+}
+
+func TestRunAPIKeyState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	c := &CacheT{cache: newMapCacher(), cfg: config.Cache{APIKeyTTL: time.Hour}}
+	require.NoError(t, c.setAPIKeyState(path))
+	c.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, c.RunAPIKeyState(ctx))
+	assert.FileExists(t, path, "the state is saved on shutdown")
+}
@@ -3,13 +3,18 @@
 // you may not use this file except in compliance with the Elastic License.
 
 // Package cache implements an in-memory cache used to track API keys, actions, and artifacts.
+// The API key validations and artifacts may also be shared with other fleet-server instances through Redis,
+// which also broadcasts the invalidations of the entries to them.
 
 //nolint:goconst // easier to read scoped keys if no constants are used
 package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
 	"math/rand"
 	"sync"
 	"time"
@@ -31,31 +36,51 @@ type Cache interface {
 	SetAPIKey(key APIKey, enabled bool)
 	ValidAPIKey(key APIKey) bool
 
+	SetServiceToken(token, identity string)
+	GetServiceToken(token string) (string, bool)
+
 	SetEnrollmentAPIKey(id string, key model.EnrollmentAPIKey, cost int64)
 	GetEnrollmentAPIKey(id string) (model.EnrollmentAPIKey, bool)
 
-	SetArtifact(artifact model.Artifact)
-	GetArtifact(ident, sha2 string) (model.Artifact, bool)
+	SetArtifact(namespace string, artifact model.Artifact)
+	GetArtifact(namespace, ident, sha2 string) (model.Artifact, bool)
 
 	SetUpload(id string, info file.Info)
 	GetUpload(id string) (file.Info, bool)
 
 	SetPGPKey(id string, p []byte)
 	GetPGPKey(id string) ([]byte, bool)
+
+	SetNotFound(kind, id string)
+	NotFound(kind, id string) bool
+	ClearNotFound(kind, id string)
 }
 
+// Kinds of documents whose lookups are cached when they are not found.
+const (
+	NotFoundAgent       = "agent"
+	NotFoundAgentAPIKey = "agent_api_key" // agent looked up by the ID of its access API key
+	NotFoundArtifact    = "artifact"
+	NotFoundEnrollKey   = "enroll_key"
+)
+
 type APIKey = apikey.APIKey
 type SecurityInfo = apikey.SecurityInfo
 
 type CacheT struct {
 	cache Cacher
 	cfg   config.Cache
+	keys  *apiKeyState
 	mut   sync.RWMutex
 }
 
+// notFound is the cached value of a lookup of a missing document.
+type notFound struct{}
+
 type actionCache struct {
 	actionID   string
 	actionType string
+	namespaces []string
 }
 
 // New creates a new cache.
@@ -69,6 +94,9 @@ func New(cfg config.Cache) (*CacheT, error) {
 		cache: cache,
 		cfg:   cfg,
 	}
+	if err := c.setAPIKeyState(cfg.APIKeyStatePath); err != nil {
+		return nil, err
+	}
 
 	return &c, nil
 }
@@ -89,13 +117,45 @@ func (c *CacheT) Reconfigure(cfg config.Cache) error {
 	// And assign new one
 	c.cfg = cfg
 	c.cache = cache
+
+	// Keep the validated API keys in the new cache
+	if c.keys != nil && c.keys.path == cfg.APIKeyStatePath {
+		c.keys.mut.Lock()
+		entries := maps.Clone(c.keys.entries)
+		c.keys.mut.Unlock()
+		c.warmAPIKeys(entries, time.Now())
+		return nil
+	}
+	return c.setAPIKeyState(cfg.APIKeyStatePath)
+}
+
+// setAPIKeyState loads the API key state file at path into the cache, path may be empty to disable the state.
+// WARNING: Expects c.mut to be held or c not shared yet.
+func (c *CacheT) setAPIKeyState(path string) error {
+	c.keys = nil
+	if path == "" {
+		return nil
+	}
+	keys, err := newAPIKeyState(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entries, err := keys.load(now)
+	if err != nil {
+		// a broken state file only costs the validation of the keys again
+		zerolog.Ctx(context.TODO()).Warn().Err(err).Str("path", path).Msg("Failed to load the API key cache state")
+	}
+	c.warmAPIKeys(entries, now)
+	c.keys = keys
+	zerolog.Ctx(context.TODO()).Info().Int("count", len(entries)).Str("path", path).Msg("Loaded API key cache state")
 	return nil
 }
 
 // SetAction sets an action in the cache.
 //
-// This will only cache the action ID and action Type. So `GetAction` will only
-// return a `model.Action` with `ActionId` and `Type` set.
+// This will only cache the action ID, action Type and Namespaces. So `GetAction` will only
+// return a `model.Action` with `ActionId`, `Type` and `Namespaces` set.
 func (c *CacheT) SetAction(action model.Action) {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
@@ -104,8 +164,12 @@ func (c *CacheT) SetAction(action model.Action) {
 	v := actionCache{
 		actionID:   action.ActionID,
 		actionType: action.Type,
+		namespaces: action.Namespaces,
 	}
 	cost := len(action.ActionID) + len(action.Type)
+	for _, ns := range action.Namespaces {
+		cost += len(ns)
+	}
 	ttl := c.cfg.ActionTTL
 	ok := c.cache.SetWithTTL(scopedKey, v, int64(cost), ttl)
 	zerolog.Ctx(context.TODO()).Trace().
@@ -117,8 +181,8 @@ func (c *CacheT) SetAction(action model.Action) {
 
 // GetAction returns an action from the cache.
 //
-// This will only return a `model.Action` with the action ID and action Type set.
-// This is because `SetAction` So `GetAction` will only cache the action ID and action Type.
+// This will only return a `model.Action` with the action ID, action Type and Namespaces set.
+// This is because `SetAction` So `GetAction` will only cache the action ID, action Type and Namespaces.
 func (c *CacheT) GetAction(id string) (model.Action, bool) {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
@@ -133,8 +197,9 @@ func (c *CacheT) GetAction(id string) (model.Action, bool) {
 			return model.Action{}, false
 		}
 		return model.Action{
-			ActionID: action.actionID,
-			Type:     action.actionType,
+			ActionID:   action.actionID,
+			Type:       action.actionType,
+			Namespaces: action.namespaces,
 		}, ok
 	}
 
@@ -170,6 +235,13 @@ func (c *CacheT) SetAPIKey(key APIKey, enabled bool) {
 
 	cost := len(scopedKey) + len(val)
 	ok := c.cache.SetWithTTL(scopedKey, val, int64(cost), ttl)
+	if c.keys != nil {
+		if enabled {
+			c.keys.add(key.ID, key.Key, time.Now().Add(ttl))
+		} else {
+			c.keys.remove(key.ID)
+		}
+	}
 	zerolog.Ctx(context.TODO()).Trace().
 		Bool("ok", ok).
 		Bool("enabled", enabled).
@@ -188,11 +260,15 @@ func (c *CacheT) ValidAPIKey(key APIKey) bool {
 	scopedKey := "api:" + key.ID
 	v, ok := c.cache.Get(scopedKey)
 	if ok {
-		switch v {
-		case "":
+		switch {
+		case v == "":
 			log.Trace().Str("id", key.ID).Msg("ApiKey cache HIT on disabled KEY")
-		case key.Key:
+		case v == key.Key:
 			log.Trace().Str("id", key.ID).Msg("ApiKey cache HIT")
+		case c.validHashedAPIKey(v, key.Key):
+			log.Trace().Str("id", key.ID).Msg("ApiKey cache HIT on loaded KEY")
+		case validSharedAPIKey(v, key.Key):
+			log.Trace().Str("id", key.ID).Msg("ApiKey cache HIT on shared KEY")
 		default:
 			log.Trace().Str("id", key.ID).Msg("ApiKey cache MISMATCH")
 			ok = false
@@ -203,6 +279,47 @@ func (c *CacheT) ValidAPIKey(key APIKey) bool {
 	return ok
 }
 
+// SetServiceToken caches the identity of a validated service token for the API key TTL.
+// The token is stored as a hash, the cached token itself is never kept.
+func (c *CacheT) SetServiceToken(token, identity string) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	scopedKey := serviceTokenKey(token)
+	cost := len(scopedKey) + len(identity)
+	ok := c.cache.SetWithTTL(scopedKey, identity, int64(cost), c.cfg.APIKeyTTL)
+	zerolog.Ctx(context.TODO()).Trace().
+		Bool("ok", ok).
+		Str("identity", identity).
+		Dur("ttl", c.cfg.APIKeyTTL).
+		Int("cost", cost).
+		Msg("Service token cache SET")
+}
+
+// GetServiceToken returns the identity of a validated service token.
+func (c *CacheT) GetServiceToken(token string) (string, bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	log := zerolog.Ctx(context.TODO())
+	if v, ok := c.cache.Get(serviceTokenKey(token)); ok {
+		identity, ok := v.(string)
+		if !ok {
+			log.Error().Msg("Service token cache cast fail")
+			return "", false
+		}
+		log.Trace().Str("identity", identity).Msg("Service token cache HIT")
+		return identity, true
+	}
+	log.Trace().Msg("Service token cache MISS")
+	return "", false
+}
+
+func serviceTokenKey(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return "svc:" + hex.EncodeToString(h[:])
+}
+
 // GetEnrollmentAPIKey returns the enrollment API key by ID.
 func (c *CacheT) GetEnrollmentAPIKey(id string) (model.EnrollmentAPIKey, bool) { //nolint:dupl // similar getters to support strong typing
 	c.mut.RLock()
@@ -241,16 +358,18 @@ func (c *CacheT) SetEnrollmentAPIKey(id string, key model.EnrollmentAPIKey, cost
 		Msg("EnrollmentApiKey cache SET")
 }
 
-func makeArtifactKey(ident, sha2 string) string {
-	return fmt.Sprintf("artifact:%s:%s", ident, sha2)
+// makeArtifactKey scopes the artifact key to the namespace of the requester.
+// Artifacts retrieved for one namespace are never returned for another.
+func makeArtifactKey(namespace, ident, sha2 string) string {
+	return fmt.Sprintf("artifact:%s:%s:%s", namespace, ident, sha2)
 }
 
-func (c *CacheT) GetArtifact(ident, sha2 string) (model.Artifact, bool) {
+func (c *CacheT) GetArtifact(namespace, ident, sha2 string) (model.Artifact, bool) {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
 
 	log := zerolog.Ctx(context.TODO())
-	scopedKey := makeArtifactKey(ident, sha2)
+	scopedKey := makeArtifactKey(namespace, ident, sha2)
 	if v, ok := c.cache.Get(scopedKey); ok {
 		log.Trace().Str("key", scopedKey).Msg("Artifact cache HIT")
 		key, ok := v.(model.Artifact)
@@ -268,11 +387,11 @@ func (c *CacheT) GetArtifact(ident, sha2 string) (model.Artifact, bool) {
 
 // SetArtifact will set the cached artifact
 // TODO: strip body and spool to on disk cache if larger than a size threshold
-func (c *CacheT) SetArtifact(artifact model.Artifact) {
+func (c *CacheT) SetArtifact(namespace string, artifact model.Artifact) {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
 
-	scopedKey := makeArtifactKey(artifact.Identifier, artifact.DecodedSha256)
+	scopedKey := makeArtifactKey(namespace, artifact.Identifier, artifact.DecodedSha256)
 	cost := int64(len(artifact.Body))
 	ttl := c.cfg.ArtifactTTL
 
@@ -353,3 +472,48 @@ func (c *CacheT) GetPGPKey(id string) ([]byte, bool) {
 	}
 	return nil, false
 }
+
+func makeNotFoundKey(kind, id string) string {
+	return "miss:" + kind + ":" + id
+}
+
+// SetNotFound records that the document of kind with id does not exist, for the not found TTL.
+// It should be cleared with ClearNotFound when fleet-server creates the document.
+func (c *CacheT) SetNotFound(kind, id string) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	ttl := c.cfg.NotFoundTTL
+	if ttl <= 0 {
+		return
+	}
+	scopedKey := makeNotFoundKey(kind, id)
+	ok := c.cache.SetWithTTL(scopedKey, notFound{}, int64(len(scopedKey)), ttl)
+	zerolog.Ctx(context.TODO()).Trace().
+		Bool("ok", ok).
+		Str("key", scopedKey).
+		Dur("ttl", ttl).
+		Msg("Not found cache SET")
+}
+
+// NotFound returns true if the document of kind with id was recently not found.
+func (c *CacheT) NotFound(kind, id string) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	scopedKey := makeNotFoundKey(kind, id)
+	_, ok := c.cache.Get(scopedKey)
+	zerolog.Ctx(context.TODO()).Trace().
+		Bool("hit", ok).
+		Str("key", scopedKey).
+		Msg("Not found cache GET")
+	return ok
+}
+
+// ClearNotFound removes the not found record of the document of kind with id.
+func (c *CacheT) ClearNotFound(kind, id string) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	c.cache.Del(makeNotFoundKey(kind, id))
+}
@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+func TestCacheNotFound(t *testing.T) {
+	c := &CacheT{
+		cache: newMapCacher(),
+		cfg:   config.Cache{NotFoundTTL: time.Minute},
+	}
+	assert.False(t, c.NotFound(NotFoundAgent, "id"))
+
+	c.SetNotFound(NotFoundAgent, "id")
+	assert.True(t, c.NotFound(NotFoundAgent, "id"))
+	assert.False(t, c.NotFound(NotFoundAgent, "other"))
+	assert.False(t, c.NotFound(NotFoundArtifact, "id"), "the kinds of documents do not share their entries")
+
+	c.ClearNotFound(NotFoundAgent, "id")
+	assert.False(t, c.NotFound(NotFoundAgent, "id"))
+
+	t.Run("disabled with a negative TTL", func(t *testing.T) {
+		c := &CacheT{
+			cache: newMapCacher(),
+			cfg:   config.Cache{NotFoundTTL: -1},
+		}
+		c.SetNotFound(NotFoundAgent, "id")
+		assert.False(t, c.NotFound(NotFoundAgent, "id"))
+	})
+}
+
+func TestCacheAction(t *testing.T) {
+	c := &CacheT{
+		cache: newMapCacher(),
+		cfg:   config.Cache{ActionTTL: time.Minute},
+	}
+	c.SetAction(model.Action{ActionID: "action", Type: "UPGRADE", Agents: []string{"agent"}, Namespaces: []string{"space"}})
+
+	action, ok := c.GetAction("action")
+	assert.True(t, ok)
+	assert.Equal(t, model.Action{ActionID: "action", Type: "UPGRADE", Namespaces: []string{"space"}}, action, "the namespaces are kept to check the acks")
+}
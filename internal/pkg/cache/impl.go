@@ -5,12 +5,34 @@
 package cache
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 )
 
 type Cacher interface {
 	Get(key interface{}) (interface{}, bool)
 	Set(key, value interface{}, cost int64) bool
 	SetWithTTL(key, value interface{}, cost int64, ttl time.Duration) bool
+	Del(key interface{})
 	Close()
 }
+
+// withBackend returns the Cacher of the configured backend, local is the in-memory cache of the fleet-server.
+func withBackend(cfg config.Cache, local Cacher) (Cacher, error) {
+	switch cfg.Backend {
+	case "", config.CacheBackendMemory:
+		return local, nil
+	case config.CacheBackendRedis:
+		c, err := newRedisCacher(local, cfg.Redis)
+		if err != nil {
+			local.Close()
+			return nil, err
+		}
+		return c, nil
+	default:
+		local.Close()
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
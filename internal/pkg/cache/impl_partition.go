@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// minPartitionCounters is the lowest number of counters of a partition when it is derived from the shared cache.
+const minPartitionCounters = 1000
+
+// newCacherFn creates an in-memory Cacher with the given capacity.
+type newCacherFn func(numCounters, maxCost int64) (Cacher, error)
+
+// partitionedCacher routes the entries to the cache of their type by the scope of their key,
+// the entries of a type without a partition are kept in the shared cache.
+type partitionedCacher struct {
+	shared     Cacher
+	partitions map[string]Cacher // by type of entries
+}
+
+// newPartitionedCache returns the shared cache, wrapped to route the entries of the configured partitions to their own cache.
+func newPartitionedCache(cfg config.Cache, newFn newCacherFn) (Cacher, error) {
+	shared, err := newFn(cfg.NumCounters, cfg.MaxCost)
+	if err != nil {
+		return nil, err
+	}
+	c := &partitionedCacher{
+		shared:     shared,
+		partitions: make(map[string]Cacher),
+	}
+	for _, p := range []struct {
+		typ string
+		cfg config.CachePartition
+	}{
+		{typ: TypeAPIKeys, cfg: cfg.APIKeys},
+		{typ: TypeEnrollKeys, cfg: cfg.EnrollKeys},
+		{typ: TypeArtifacts, cfg: cfg.Artifacts},
+		{typ: TypeActions, cfg: cfg.Actions},
+	} {
+		pc, err := newPartition(cfg, p.cfg, newFn)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("cache partition %s: %w", p.typ, err)
+		}
+		if pc != nil {
+			c.partitions[p.typ] = pc
+		}
+	}
+	if len(c.partitions) == 0 {
+		return shared, nil
+	}
+	return c, nil
+}
+
+// newPartition returns the cache of a partition, nil if its entries are kept in the shared cache.
+func newPartition(cfg config.Cache, pcfg config.CachePartition, newFn newCacherFn) (Cacher, error) {
+	switch pcfg.Admission {
+	case "", config.CacheAdmissionLFU:
+	case config.CacheAdmissionNone:
+		return noAdmission{}, nil
+	default:
+		return nil, fmt.Errorf("unknown admission policy %q", pcfg.Admission)
+	}
+	if pcfg.MaxCost <= 0 {
+		return nil, nil
+	}
+	numCounters := pcfg.NumCounters
+	if numCounters <= 0 {
+		numCounters = minPartitionCounters
+		if cfg.MaxCost > 0 {
+			numCounters = max(numCounters, int64(float64(cfg.NumCounters)*float64(pcfg.MaxCost)/float64(cfg.MaxCost)))
+		}
+	}
+	return newFn(numCounters, pcfg.MaxCost)
+}
+
+func (c *partitionedCacher) cacher(key interface{}) Cacher {
+	if pc, ok := c.partitions[keyType(key)]; ok {
+		return pc
+	}
+	return c.shared
+}
+
+func (c *partitionedCacher) Get(key interface{}) (interface{}, bool) {
+	return c.cacher(key).Get(key)
+}
+
+func (c *partitionedCacher) Set(key, value interface{}, cost int64) bool {
+	return c.cacher(key).Set(key, value, cost)
+}
+
+func (c *partitionedCacher) SetWithTTL(key, value interface{}, cost int64, ttl time.Duration) bool {
+	return c.cacher(key).SetWithTTL(key, value, cost, ttl)
+}
+
+func (c *partitionedCacher) Del(key interface{}) {
+	c.cacher(key).Del(key)
+}
+
+func (c *partitionedCacher) Close() {
+	c.shared.Close()
+	for _, pc := range c.partitions {
+		pc.Close()
+	}
+}
+
+// noAdmission is the cache of a partition that does not admit any entry.
+type noAdmission struct{}
+
+func (noAdmission) Get(_ interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+func (noAdmission) Set(_, _ interface{}, _ int64) bool {
+	return false
+}
+
+func (noAdmission) SetWithTTL(_, _ interface{}, _ int64, _ time.Duration) bool {
+	return false
+}
+
+func (noAdmission) Del(_ interface{}) {}
+
+func (noAdmission) Close() {}
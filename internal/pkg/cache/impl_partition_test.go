@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+type sizedCacher struct {
+	*mapCacher
+	numCounters, maxCost int64
+}
+
+func TestPartitionedCache(t *testing.T) {
+	var created []*sizedCacher
+	newFn := func(numCounters, maxCost int64) (Cacher, error) {
+		c := &sizedCacher{mapCacher: newMapCacher(), numCounters: numCounters, maxCost: maxCost}
+		created = append(created, c)
+		return c, nil
+	}
+
+	t.Run("without partitions", func(t *testing.T) {
+		created = nil
+		c, err := newPartitionedCache(config.Cache{NumCounters: 100000, MaxCost: 1000}, newFn)
+		require.NoError(t, err)
+		require.Len(t, created, 1)
+		assert.Same(t, created[0], c, "the shared cache is used directly")
+	})
+
+	t.Run("partitions", func(t *testing.T) {
+		created = nil
+		cfg := config.Cache{
+			NumCounters: 100000,
+			MaxCost:     1000,
+			APIKeys:     config.CachePartition{MaxCost: 500},
+			Artifacts:   config.CachePartition{MaxCost: 5000, NumCounters: 42},
+			Actions:     config.CachePartition{Admission: config.CacheAdmissionNone},
+		}
+		c, err := newPartitionedCache(cfg, newFn)
+		require.NoError(t, err)
+		require.Len(t, created, 3)
+		shared, apiKeys, artifacts := created[0], created[1], created[2]
+		assert.Equal(t, int64(50000), apiKeys.numCounters, "the counters are scaled to the share of the shared max cost")
+		assert.Equal(t, int64(42), artifacts.numCounters)
+
+		ct := &CacheT{cache: c, cfg: config.Cache{APIKeyTTL: time.Hour, ArtifactTTL: time.Hour, ActionTTL: time.Hour, EnrollKeyTTL: time.Hour}}
+		ct.SetAPIKey(APIKey{ID: "id", Key: "key"}, true)
+		ct.SetServiceToken("token", "elastic/fleet-server/agents")
+		ct.SetArtifact("ns", model.Artifact{Identifier: "ident", DecodedSha256: "sha2"})
+		ct.SetEnrollmentAPIKey("enroll", model.EnrollmentAPIKey{}, 1)
+		ct.SetAction(model.Action{ActionID: "action"})
+
+		assert.Len(t, apiKeys.entries, 2)
+		assert.Len(t, artifacts.entries, 1)
+		assert.Len(t, shared.entries, 1, "enrollment keys are kept in the shared cache")
+		assert.True(t, ct.ValidAPIKey(APIKey{ID: "id", Key: "key"}))
+		_, ok := ct.GetAction("action")
+		assert.False(t, ok, "actions are not admitted")
+	})
+
+	t.Run("unknown admission policy", func(t *testing.T) {
+		_, err := newPartitionedCache(config.Cache{Artifacts: config.CachePartition{Admission: "lru"}}, newFn)
+		assert.ErrorContains(t, err, "artifacts")
+	})
+}
@@ -0,0 +1,288 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//nolint:goconst // easier to read scoped keys if no constants are used
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	defaultRedisKeyPrefix = "fleet-server:"
+	// redisBackoff is how long the Redis server is not used after a failed request.
+	redisBackoff = 5 * time.Second
+	// redisInvalidations is the channel, after the key prefix, the invalidated keys are published on.
+	redisInvalidations = "invalidations"
+)
+
+// sharedAPIKey is the cached value of an API key validated by another fleet-server, it holds the SHA-256 of the key.
+type sharedAPIKey string
+
+// validSharedAPIKey returns true if v is a key validated by another fleet-server that matches key.
+func validSharedAPIKey(v interface{}, key string) bool {
+	h, ok := v.(sharedAPIKey)
+	if !ok {
+		return false
+	}
+	sum := sha256.Sum256([]byte(key))
+	return subtle.ConstantTimeCompare([]byte(h), []byte(hex.EncodeToString(sum[:]))) == 1
+}
+
+// sharedArtifact is the Redis value of an artifact, the cached body is the decoded payload which is not JSON.
+type sharedArtifact struct {
+	model.Artifact
+	Body []byte `json:"body"`
+}
+
+// redisCacher shares the API key validations, service tokens and artifacts of the local cache with the other
+// fleet-server instances through a Redis server. The local cache is always used first, Redis is only read on
+// a local miss and written through on a set. The other entries are only kept in the local cache.
+//
+// The deleted entries and the disabled API keys are published to the other instances, which drop their local
+// copy, so an invalidation does not wait for the TTL of the entries cached by the other instances.
+type redisCacher struct {
+	local     Cacher
+	client    *redisClient
+	prefix    string
+	downUntil atomic.Int64
+
+	// origin identifies the invalidations published by this cache.
+	origin string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRedisCacher(local Cacher, cfg config.RedisCache) (*redisCacher, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("redis cache backend requires an address")
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = defaultRedisKeyPrefix
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &redisCacher{
+		local:  local,
+		client: newRedisClient(cfg),
+		prefix: prefix,
+		origin: hex.EncodeToString(b),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go c.listen(ctx)
+	return c, nil
+}
+
+func (c *redisCacher) Get(key interface{}) (interface{}, bool) {
+	if v, ok := c.local.Get(key); ok {
+		return v, ok
+	}
+	k, ok := sharedKey(key)
+	if !ok || !c.up() {
+		return nil, false
+	}
+	b, ttl, err := c.client.get(c.prefix + k)
+	if err != nil {
+		c.fail(err, "GET")
+		return nil, false
+	}
+	if b == nil {
+		return nil, false
+	}
+	v, err := decodeShared(k, b)
+	if err != nil {
+		zerolog.Ctx(context.TODO()).Warn().Err(err).Str("key", k).Msg("Failed to decode shared cache entry")
+		return nil, false
+	}
+	if ttl > 0 {
+		c.local.SetWithTTL(key, v, int64(len(k)+len(b)), ttl)
+	}
+	return v, true
+}
+
+func (c *redisCacher) Set(key, value interface{}, cost int64) bool {
+	ok := c.local.Set(key, value, cost)
+	c.share(key, value, 0)
+	if disabledAPIKey(key, value) {
+		c.invalidate(key)
+	}
+	return ok
+}
+
+func (c *redisCacher) SetWithTTL(key, value interface{}, cost int64, ttl time.Duration) bool {
+	ok := c.local.SetWithTTL(key, value, cost, ttl)
+	c.share(key, value, ttl)
+	if disabledAPIKey(key, value) {
+		c.invalidate(key)
+	}
+	return ok
+}
+
+func (c *redisCacher) Del(key interface{}) {
+	c.local.Del(key)
+	if k, ok := sharedKey(key); ok && c.up() {
+		if err := c.client.del(c.prefix + k); err != nil {
+			c.fail(err, "DEL")
+		}
+	}
+	c.invalidate(key)
+}
+
+func (c *redisCacher) Close() {
+	c.cancel()
+	<-c.done
+	c.local.Close()
+	c.client.Close()
+}
+
+// invalidate publishes key to the other fleet-server instances so they drop their local entry.
+func (c *redisCacher) invalidate(key interface{}) {
+	k, ok := key.(string)
+	if !ok || !c.up() {
+		return
+	}
+	if err := c.client.publish(c.prefix+redisInvalidations, c.origin+" "+k); err != nil {
+		c.fail(err, "PUBLISH")
+	}
+}
+
+// listen drops the local entries invalidated by the other fleet-server instances until ctx is done.
+// The subscription is retried after redisBackoff when it fails, the invalidations published meanwhile are
+// lost and the entries expire with their TTL.
+func (c *redisCacher) listen(ctx context.Context) {
+	defer close(c.done)
+	log := zerolog.Ctx(context.TODO())
+	for {
+		err := c.client.subscribe(ctx, c.prefix+redisInvalidations, func(msg []byte) {
+			origin, key, ok := bytes.Cut(msg, []byte(" "))
+			if !ok || string(origin) == c.origin {
+				return
+			}
+			log.Trace().Str("key", string(key)).Msg("Cache entry invalidated by another fleet-server")
+			c.local.Del(string(key))
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Err(err).Dur("backoff", redisBackoff).Msg("Redis cache invalidations subscription failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(redisBackoff):
+		}
+	}
+}
+
+// share writes value to Redis if key is shared.
+func (c *redisCacher) share(key, value interface{}, ttl time.Duration) {
+	k, ok := sharedKey(key)
+	if !ok || !c.up() {
+		return
+	}
+	b, ok := encodeShared(k, value)
+	if !ok {
+		return
+	}
+	if err := c.client.set(c.prefix+k, b, ttl); err != nil {
+		c.fail(err, "SET")
+	}
+}
+
+func (c *redisCacher) up() bool {
+	return time.Now().UnixNano() >= c.downUntil.Load()
+}
+
+func (c *redisCacher) fail(err error, op string) {
+	c.downUntil.Store(time.Now().Add(redisBackoff).UnixNano())
+	zerolog.Ctx(context.TODO()).Warn().Err(err).Str("op", op).Dur("backoff", redisBackoff).Msg("Redis cache request failed, using the local cache only")
+}
+
+// disabledAPIKey returns true if value disables the API key of key.
+func disabledAPIKey(key, value interface{}) bool {
+	k, ok := key.(string)
+	return ok && strings.HasPrefix(k, "api:") && value == ""
+}
+
+// sharedKey returns key if its entries are shared between fleet-server instances.
+func sharedKey(key interface{}) (string, bool) {
+	k, ok := key.(string)
+	if !ok {
+		return "", false
+	}
+	return k, strings.HasPrefix(k, "api:") || strings.HasPrefix(k, "svc:") || strings.HasPrefix(k, "artifact:")
+}
+
+// encodeShared returns the Redis value of a shared entry. API keys are only shared as a hash.
+func encodeShared(key string, value interface{}) ([]byte, bool) {
+	switch {
+	case strings.HasPrefix(key, "api:"):
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return []byte("0"), true
+			}
+			sum := sha256.Sum256([]byte(v))
+			return []byte("1" + hex.EncodeToString(sum[:])), true
+		case sharedAPIKey:
+			return []byte("1" + string(v)), true
+		}
+		// keys loaded from the state file are only hashed with the salt of this fleet-server
+		return nil, false
+	case strings.HasPrefix(key, "svc:"):
+		v, ok := value.(string)
+		return []byte(v), ok
+	case strings.HasPrefix(key, "artifact:"):
+		v, ok := value.(model.Artifact)
+		if !ok {
+			return nil, false
+		}
+		b, err := json.Marshal(sharedArtifact{Artifact: v, Body: v.Body})
+		return b, err == nil
+	}
+	return nil, false
+}
+
+// decodeShared returns the cache value of a shared entry read from Redis.
+func decodeShared(key string, b []byte) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(key, "api:"):
+		switch {
+		case string(b) == "0":
+			return "", nil
+		case len(b) > 1 && b[0] == '1':
+			return sharedAPIKey(b[1:]), nil
+		}
+		return nil, errors.New("malformed api key entry")
+	case strings.HasPrefix(key, "svc:"):
+		return string(b), nil
+	case strings.HasPrefix(key, "artifact:"):
+		var v sharedArtifact
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		v.Artifact.Body = v.Body
+		return v.Artifact, nil
+	}
+	return nil, errors.New("unshared key")
+}
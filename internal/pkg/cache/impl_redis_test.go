@@ -0,0 +1,284 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+// fakeRedis is a Redis server that only supports the commands used by the redis cache backend.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mut     sync.Mutex
+	entries map[string]string
+	expires map[string]time.Time
+	subs    map[string][]net.Conn
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeRedis{ln: ln, password: password, entries: map[string]string{}, expires: map[string]time.Time{}, subs: map[string][]net.Conn{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		v, err := readReply(r)
+		if err != nil {
+			return
+		}
+		arr, _ := v.([]interface{})
+		cmd := make([]string, 0, len(arr))
+		for _, a := range arr {
+			b, _ := a.([]byte)
+			cmd = append(cmd, string(b))
+		}
+		var reply string
+		switch {
+		case len(cmd) == 0:
+			reply = "-ERR protocol error\r\n"
+		case cmd[0] == "AUTH":
+			authed = cmd[len(cmd)-1] == s.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case cmd[0] == "SUBSCRIBE":
+			// the confirmation is written with the lock held so it comes before the published messages
+			s.mut.Lock()
+			s.subs[cmd[1]] = append(s.subs[cmd[1]], conn)
+			_, err := fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(cmd[1]), cmd[1])
+			s.mut.Unlock()
+			if err != nil {
+				return
+			}
+			continue
+		default:
+			reply = s.exec(cmd)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) exec(cmd []string) string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if len(cmd) > 1 {
+		if exp, ok := s.expires[cmd[1]]; ok && time.Now().After(exp) {
+			delete(s.entries, cmd[1])
+			delete(s.expires, cmd[1])
+		}
+	}
+	switch cmd[0] {
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := s.entries[cmd[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "PTTL":
+		if _, ok := s.entries[cmd[1]]; !ok {
+			return ":-2\r\n"
+		}
+		exp, ok := s.expires[cmd[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(exp).Milliseconds())
+	case "DEL":
+		_, ok := s.entries[cmd[1]]
+		delete(s.entries, cmd[1])
+		delete(s.expires, cmd[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "PUBLISH":
+		for _, sub := range s.subs[cmd[1]] {
+			fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(cmd[1]), cmd[1], len(cmd[2]), cmd[2])
+		}
+		return fmt.Sprintf(":%d\r\n", len(s.subs[cmd[1]]))
+	case "SET":
+		s.entries[cmd[1]] = cmd[2]
+		delete(s.expires, cmd[1])
+		if len(cmd) == 5 && cmd[3] == "PX" {
+			ms, _ := strconv.Atoi(cmd[4])
+			s.expires[cmd[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func (s *fakeRedis) keys() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	keys := make([]string, 0, len(s.entries))
+	for k := range s.entries {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *fakeRedis) subscribers() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	n := 0
+	for _, subs := range s.subs {
+		n += len(subs)
+	}
+	return n
+}
+
+func newRedisTestCache(t *testing.T, cfg config.RedisCache) *CacheT {
+	t.Helper()
+	c, err := withBackend(config.Cache{Backend: config.CacheBackendRedis, Redis: cfg}, newMapCacher())
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	return &CacheT{cache: c, cfg: config.Cache{APIKeyTTL: time.Hour, ArtifactTTL: time.Hour, ActionTTL: time.Hour, NotFoundTTL: time.Hour}}
+}
+
+func TestRedisCacheShared(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	cfg := config.RedisCache{Address: srv.ln.Addr().String(), Password: "secret", DB: 1}
+	first := newRedisTestCache(t, cfg)
+	second := newRedisTestCache(t, cfg)
+
+	first.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, true)
+	first.SetAPIKey(APIKey{ID: "disabled", Key: "disabled-secret"}, false)
+	first.SetServiceToken("token", "elastic/fleet-server/agents")
+	first.SetArtifact("ns", model.Artifact{Identifier: "ident", DecodedSha256: "sha2", Body: []byte("body")})
+	first.SetAction(model.Action{ActionID: "action", Type: "UPGRADE"})
+
+	for _, k := range srv.keys() {
+		assert.True(t, strings.HasPrefix(k, defaultRedisKeyPrefix), "key %s is prefixed", k)
+	}
+	for _, k := range srv.keys() {
+		assert.NotContains(t, srv.entries[k], "valid-secret", "only a hash of the keys is shared")
+	}
+
+	assert.True(t, second.ValidAPIKey(APIKey{ID: "valid", Key: "valid-secret"}))
+	assert.False(t, second.ValidAPIKey(APIKey{ID: "valid", Key: "other-secret"}))
+	assert.False(t, second.ValidAPIKey(APIKey{ID: "unknown", Key: "valid-secret"}))
+	identity, ok := second.GetServiceToken("token")
+	assert.True(t, ok)
+	assert.Equal(t, "elastic/fleet-server/agents", identity)
+	artifact, ok := second.GetArtifact("ns", "ident", "sha2")
+	assert.True(t, ok)
+	assert.Equal(t, "body", string(artifact.Body))
+	_, ok = second.GetAction("action")
+	assert.False(t, ok, "actions are not shared")
+
+	t.Run("the disabled key is shared", func(t *testing.T) {
+		first.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, false)
+		other := newRedisTestCache(t, cfg)
+		v, ok := other.cache.Get("api:valid")
+		assert.True(t, ok)
+		assert.Equal(t, "", v)
+	})
+}
+
+func TestRedisCacheInvalidations(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	cfg := config.RedisCache{Address: srv.ln.Addr().String()}
+	first := newRedisTestCache(t, cfg)
+	second := newRedisTestCache(t, cfg)
+	require.Eventually(t, func() bool { return srv.subscribers() == 2 }, time.Second, 10*time.Millisecond)
+
+	first.SetAPIKey(APIKey{ID: "key", Key: "secret"}, true)
+	assert.True(t, second.ValidAPIKey(APIKey{ID: "key", Key: "secret"}), "the key is now in the local cache of second")
+	second.SetNotFound(NotFoundAgent, "agent")
+
+	first.SetAPIKey(APIKey{ID: "key", Key: "secret"}, false)
+	assert.Eventually(t, func() bool {
+		v, ok := second.cache.Get("api:key")
+		return ok && v == ""
+	}, time.Second, 10*time.Millisecond, "second reads the disabled key instead of its local copy")
+	first.ClearNotFound(NotFoundAgent, "agent")
+	assert.Eventually(t, func() bool { return !second.NotFound(NotFoundAgent, "agent") }, time.Second, 10*time.Millisecond,
+		"the entries that are not shared are also invalidated")
+
+	t.Run("own invalidations are ignored", func(t *testing.T) {
+		first.SetAPIKey(APIKey{ID: "other", Key: "secret"}, false)
+		time.Sleep(50 * time.Millisecond)
+		rc, ok := first.cache.(*redisCacher)
+		require.True(t, ok)
+		v, ok := rc.local.Get("api:other")
+		assert.True(t, ok)
+		assert.Equal(t, "", v)
+	})
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	c := newRedisTestCache(t, config.RedisCache{Address: addr, Timeout: 50 * time.Millisecond})
+	c.SetAPIKey(APIKey{ID: "valid", Key: "valid-secret"}, true)
+	assert.True(t, c.ValidAPIKey(APIKey{ID: "valid", Key: "valid-secret"}), "the local cache is used without redis")
+	assert.False(t, c.ValidAPIKey(APIKey{ID: "unknown", Key: "valid-secret"}))
+	rc, ok := c.cache.(*redisCacher)
+	require.True(t, ok)
+	assert.False(t, rc.up(), "redis is skipped after a failure")
+}
+
+func TestRedisCacheConfig(t *testing.T) {
+	_, err := withBackend(config.Cache{Backend: config.CacheBackendRedis}, newMapCacher())
+	assert.Error(t, err, "an address is required")
+	_, err = withBackend(config.Cache{Backend: "memcached"}, newMapCacher())
+	assert.Error(t, err)
+	local := newMapCacher()
+	c, err := withBackend(config.Cache{}, local)
+	require.NoError(t, err)
+	assert.Same(t, local, c)
+
+	srv := newFakeRedis(t, "secret")
+	client := newRedisClient(config.RedisCache{Address: srv.ln.Addr().String(), Password: "wrong"})
+	defer client.Close()
+	err = client.set("key", []byte("value"), 0)
+	var rerr redisError
+	assert.True(t, errors.As(err, &rerr), "the authentication error is returned")
+}
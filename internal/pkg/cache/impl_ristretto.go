@@ -13,10 +13,28 @@ import (
 )
 
 func newCache(cfg config.Cache) (Cacher, error) {
+	local, err := newPartitionedCache(cfg, newRistretto)
+	if err != nil {
+		return nil, err
+	}
+	c, err := withBackend(cfg, local)
+	if err != nil {
+		return nil, err
+	}
+	return statsCacher{c}, nil
+}
+
+func newRistretto(numCounters, maxCost int64) (Cacher, error) {
 	rcfg := &ristretto.Config{
-		NumCounters: cfg.NumCounters,
-		MaxCost:     cfg.MaxCost,
+		NumCounters: numCounters,
+		MaxCost:     maxCost,
 		BufferItems: 64,
+		OnEvict: func(item *ristretto.Item) {
+			recordEviction(item.Value, item.Cost)
+		},
+		OnReject: func(item *ristretto.Item) {
+			recordReject(item.Value)
+		},
 	}
 
 	return ristretto.NewCache(rcfg)
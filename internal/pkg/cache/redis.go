@@ -0,0 +1,327 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+const (
+	defaultRedisTimeout  = 100 * time.Millisecond
+	defaultRedisPoolSize = 10
+)
+
+// redisError is an error reply of the Redis server, the connection is still usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// redisClient is a minimal client of the Redis serialization protocol (RESP2), it only supports the
+// commands needed by the cache. Idle connections are kept in a pool.
+type redisClient struct {
+	cfg   config.RedisCache
+	conns chan *redisConn
+}
+
+func newRedisClient(cfg config.RedisCache) *redisClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRedisTimeout
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = defaultRedisPoolSize
+	}
+	return &redisClient{
+		cfg:   cfg,
+		conns: make(chan *redisConn, cfg.PoolSize),
+	}
+}
+
+// do sends the commands in a single round trip and returns their replies.
+// An error reply of a command is returned as a redisError in its place.
+func (c *redisClient) do(cmds ...[]string) ([]interface{}, error) {
+	conn, err := c.conn()
+	if err != nil {
+		return nil, err
+	}
+	replies, err := conn.do(time.Now().Add(c.cfg.Timeout), cmds...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case c.conns <- conn:
+	default:
+		conn.Close()
+	}
+	return replies, nil
+}
+
+// get returns the value of key and its remaining time to live, 0 if it does not expire.
+// It returns a nil value if the key does not exist.
+func (c *redisClient) get(key string) ([]byte, time.Duration, error) {
+	replies, err := c.do([]string{"GET", key}, []string{"PTTL", key})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(redisError); ok {
+			return nil, 0, err
+		}
+	}
+	v, _ := replies[0].([]byte)
+	ttl, _ := replies[1].(int64)
+	if v == nil || ttl == -2 {
+		return nil, 0, nil
+	}
+	return v, time.Duration(max(ttl, 0)) * time.Millisecond, nil
+}
+
+// set sets the value of key, it expires after ttl unless ttl is 0.
+func (c *redisClient) set(key string, value []byte, ttl time.Duration) error {
+	cmd := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		cmd = append(cmd, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	replies, err := c.do(cmd)
+	if err != nil {
+		return err
+	}
+	if err, ok := replies[0].(redisError); ok {
+		return err
+	}
+	return nil
+}
+
+// del deletes key.
+func (c *redisClient) del(key string) error {
+	replies, err := c.do([]string{"DEL", key})
+	if err != nil {
+		return err
+	}
+	if err, ok := replies[0].(redisError); ok {
+		return err
+	}
+	return nil
+}
+
+// publish publishes msg on channel.
+func (c *redisClient) publish(channel, msg string) error {
+	replies, err := c.do([]string{"PUBLISH", channel, msg})
+	if err != nil {
+		return err
+	}
+	if err, ok := replies[0].(redisError); ok {
+		return err
+	}
+	return nil
+}
+
+// subscribe calls fn with the messages published on channel until ctx is done or the connection fails.
+// The subscription has its own connection, it is not taken from the pool.
+func (c *redisClient) subscribe(ctx context.Context, channel string, fn func(msg []byte)) error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	replies, err := conn.do(time.Now().Add(c.cfg.Timeout), []string{"SUBSCRIBE", channel})
+	if err != nil {
+		return fmt.Errorf("redis subscribe: %w", err)
+	}
+	if err, ok := replies[0].(redisError); ok {
+		return err
+	}
+	// messages are waited for without a deadline, the connection is closed when ctx is done
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+	for {
+		reply, err := readReply(conn.r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("redis subscribe: %w", err)
+		}
+		arr, _ := reply.([]interface{})
+		if len(arr) != 3 {
+			continue
+		}
+		if kind, _ := arr[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		if msg, ok := arr[2].([]byte); ok {
+			fn(msg)
+		}
+	}
+}
+
+func (c *redisClient) Close() {
+	for {
+		select {
+		case conn := <-c.conns:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (c *redisClient) conn() (*redisConn, error) {
+	select {
+	case conn := <-c.conns:
+		return conn, nil
+	default:
+	}
+	return c.dial()
+}
+
+// dial opens a new connection, authenticated and on the configured database.
+func (c *redisClient) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: c.cfg.Timeout}
+	var nc net.Conn
+	var err error
+	if c.cfg.TLS {
+		host, _, _ := net.SplitHostPort(c.cfg.Address)
+		nc, err = tls.DialWithDialer(dialer, "tcp", c.cfg.Address, fips.Restrict(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}))
+	} else {
+		nc, err = dialer.Dial("tcp", c.cfg.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis connect: %w", err)
+	}
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+
+	var cmds [][]string
+	if c.cfg.Password != "" {
+		if c.cfg.Username != "" {
+			cmds = append(cmds, []string{"AUTH", c.cfg.Username, c.cfg.Password})
+		} else {
+			cmds = append(cmds, []string{"AUTH", c.cfg.Password})
+		}
+	}
+	if c.cfg.DB != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(c.cfg.DB)})
+	}
+	if len(cmds) > 0 {
+		replies, err := conn.do(time.Now().Add(c.cfg.Timeout), cmds...)
+		if err == nil {
+			for _, reply := range replies {
+				if rerr, ok := reply.(redisError); ok {
+					err = rerr
+				}
+			}
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis connect: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (conn *redisConn) do(deadline time.Time, cmds ...[]string) ([]interface{}, error) {
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, cmd := range cmds {
+		writeCommand(&b, cmd)
+	}
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(cmds))
+	for range cmds {
+		reply, err := readReply(conn.r)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+// writeCommand writes cmd as an array of bulk strings.
+func writeCommand(b *strings.Builder, cmd []string) {
+	fmt.Fprintf(b, "*%d\r\n", len(cmd))
+	for _, arg := range cmd {
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readReply reads a reply, simple strings are returned as string, bulk strings as []byte, integers as int64
+// and arrays as []interface{}. Null bulk strings and arrays are returned as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk string length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+}
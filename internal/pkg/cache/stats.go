@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+// Types of cache entries the statistics are reported by.
+const (
+	TypeAPIKeys    = "api_keys"
+	TypeEnrollKeys = "enroll_keys"
+	TypeArtifacts  = "artifacts"
+	TypeActions    = "actions"
+	TypeNotFound   = "not_found"
+	TypeOther      = "other"
+)
+
+// Types are all the types of cache entries.
+var Types = []string{TypeAPIKeys, TypeEnrollKeys, TypeArtifacts, TypeActions, TypeNotFound, TypeOther}
+
+// scopeTypes are the types of the entries by the scope of their key.
+var scopeTypes = map[string]string{
+	"api":      TypeAPIKeys,
+	"svc":      TypeAPIKeys,
+	"record":   TypeEnrollKeys,
+	"artifact": TypeArtifacts,
+	"action":   TypeActions,
+	"miss":     TypeNotFound,
+}
+
+// Counter is a counter the cache reports to.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// Metrics are the counters the entries of one type report to, nil counters are ignored.
+type Metrics struct {
+	Hits   Counter
+	Misses Counter
+	// Sets is the number of entries set, Rejects the number of entries that were not admitted.
+	Sets    Counter
+	Rejects Counter
+	// Evictions is the number of entries evicted or expired.
+	Evictions   Counter
+	CostAdded   Counter
+	CostEvicted Counter
+}
+
+var (
+	metricsMut sync.RWMutex
+	metrics    map[string]Metrics
+)
+
+// SetMetrics sets the counters the cache reports to by type of entries.
+func SetMetrics(m map[string]Metrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metrics = m
+}
+
+func metricsOf(typ string) Metrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return metrics[typ]
+}
+
+func add(c Counter, delta uint64) {
+	if c != nil {
+		c.Add(delta)
+	}
+}
+
+// keyType returns the type of the entry of key.
+func keyType(key interface{}) string {
+	if k, ok := key.(string); ok {
+		if scope, _, ok := strings.Cut(k, ":"); ok {
+			if typ, ok := scopeTypes[scope]; ok {
+				return typ
+			}
+		}
+	}
+	return TypeOther
+}
+
+// valueType returns the type of the entry of value, the in-memory cache only reports the values of its evictions.
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case string, hashedAPIKey, sharedAPIKey:
+		return TypeAPIKeys
+	case model.EnrollmentAPIKey:
+		return TypeEnrollKeys
+	case model.Artifact:
+		return TypeArtifacts
+	case actionCache:
+		return TypeActions
+	case notFound:
+		return TypeNotFound
+	}
+	return TypeOther
+}
+
+// recordEviction reports the eviction of value from the in-memory cache.
+func recordEviction(value interface{}, cost int64) {
+	m := metricsOf(valueType(value))
+	add(m.Evictions, 1)
+	add(m.CostEvicted, uint64(max(cost, 0)))
+}
+
+// recordReject reports an entry the in-memory cache did not admit.
+func recordReject(value interface{}) {
+	add(metricsOf(valueType(value)).Rejects, 1)
+}
+
+// statsCacher reports the hits, misses and sets of a Cacher.
+type statsCacher struct {
+	Cacher
+}
+
+func (c statsCacher) Get(key interface{}) (interface{}, bool) {
+	v, ok := c.Cacher.Get(key)
+	m := metricsOf(keyType(key))
+	if ok {
+		add(m.Hits, 1)
+	} else {
+		add(m.Misses, 1)
+	}
+	return v, ok
+}
+
+func (c statsCacher) Set(key, value interface{}, cost int64) bool {
+	ok := c.Cacher.Set(key, value, cost)
+	c.record(key, cost, ok)
+	return ok
+}
+
+func (c statsCacher) SetWithTTL(key, value interface{}, cost int64, ttl time.Duration) bool {
+	ok := c.Cacher.SetWithTTL(key, value, cost, ttl)
+	c.record(key, cost, ok)
+	return ok
+}
+
+func (c statsCacher) record(key interface{}, cost int64, ok bool) {
+	m := metricsOf(keyType(key))
+	if !ok {
+		add(m.Rejects, 1)
+		return
+	}
+	add(m.Sets, 1)
+	add(m.CostAdded, uint64(max(cost, 0)))
+}
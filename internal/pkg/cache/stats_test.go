@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package cache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+type testCounter struct {
+	v atomic.Uint64
+}
+
+func (c *testCounter) Add(delta uint64) {
+	c.v.Add(delta)
+}
+
+type testMetrics struct {
+	hits, misses, sets, rejects, evictions, costAdded, costEvicted testCounter
+}
+
+func setTestMetrics(t *testing.T) map[string]*testMetrics {
+	t.Helper()
+	tm := make(map[string]*testMetrics)
+	m := make(map[string]Metrics)
+	for _, typ := range Types {
+		c := &testMetrics{}
+		tm[typ] = c
+		m[typ] = Metrics{
+			Hits:        &c.hits,
+			Misses:      &c.misses,
+			Sets:        &c.sets,
+			Rejects:     &c.rejects,
+			Evictions:   &c.evictions,
+			CostAdded:   &c.costAdded,
+			CostEvicted: &c.costEvicted,
+		}
+	}
+	SetMetrics(m)
+	t.Cleanup(func() { SetMetrics(nil) })
+	return tm
+}
+
+func TestCacheStats(t *testing.T) {
+	tm := setTestMetrics(t)
+
+	c := &CacheT{
+		cache: statsCacher{newMapCacher()},
+		cfg:   config.Cache{APIKeyTTL: time.Hour, ArtifactTTL: time.Hour, ActionTTL: time.Hour},
+	}
+	c.SetAPIKey(APIKey{ID: "id", Key: "key"}, true)
+	assert.True(t, c.ValidAPIKey(APIKey{ID: "id", Key: "key"}))
+	assert.False(t, c.ValidAPIKey(APIKey{ID: "other", Key: "key"}))
+	c.SetArtifact("ns", model.Artifact{Identifier: "ident", DecodedSha256: "sha2", Body: []byte("body")})
+	_, ok := c.GetAction("action")
+	assert.False(t, ok)
+
+	assert.Equal(t, uint64(1), tm[TypeAPIKeys].sets.v.Load())
+	assert.Equal(t, uint64(len("api:id")+len("key")), tm[TypeAPIKeys].costAdded.v.Load())
+	assert.Equal(t, uint64(1), tm[TypeAPIKeys].hits.v.Load())
+	assert.Equal(t, uint64(1), tm[TypeAPIKeys].misses.v.Load())
+	assert.Equal(t, uint64(4), tm[TypeArtifacts].costAdded.v.Load())
+	assert.Equal(t, uint64(1), tm[TypeActions].misses.v.Load())
+
+	t.Run("evictions are reported by the type of the value", func(t *testing.T) {
+		recordEviction(model.Artifact{}, 10)
+		recordEviction(hashedAPIKey("hash"), 5)
+		recordReject(actionCache{})
+		assert.Equal(t, uint64(1), tm[TypeArtifacts].evictions.v.Load())
+		assert.Equal(t, uint64(10), tm[TypeArtifacts].costEvicted.v.Load())
+		assert.Equal(t, uint64(5), tm[TypeAPIKeys].costEvicted.v.Load())
+		assert.Equal(t, uint64(1), tm[TypeActions].rejects.v.Load())
+	})
+
+	t.Run("not admitted entries are rejects", func(t *testing.T) {
+		c, err := newPartitionedCache(config.Cache{Actions: config.CachePartition{Admission: config.CacheAdmissionNone}}, func(_, _ int64) (Cacher, error) {
+			return newMapCacher(), nil
+		})
+		require.NoError(t, err)
+		statsCacher{c}.SetWithTTL("action:id", actionCache{}, 1, time.Hour)
+		assert.Equal(t, uint64(2), tm[TypeActions].rejects.v.Load())
+	})
+}
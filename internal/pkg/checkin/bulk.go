@@ -13,15 +13,21 @@ import (
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/spool"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
 
 	"github.com/rs/zerolog"
 )
 
-const defaultFlushInterval = 10 * time.Second
+const (
+	defaultFlushInterval = 10 * time.Second
+	// finalFlushTimeout bounds the flush of the pending checkins when the server stops.
+	finalFlushTimeout = 10 * time.Second
+)
 
 type optionsT struct {
 	flushInterval time.Duration
+	spool         *spool.Spool
 }
 
 type Opt func(*optionsT)
@@ -32,6 +38,13 @@ func WithFlushInterval(d time.Duration) Opt {
 	}
 }
 
+// WithSpool keeps the checkins in the spool when elasticsearch is unavailable.
+func WithSpool(s *spool.Spool) Opt {
+	return func(opt *optionsT) {
+		opt.spool = s
+	}
+}
+
 type extraT struct {
 	meta       []byte
 	seqNo      sqn.SeqNo
@@ -130,7 +143,7 @@ func (bc *Bulk) CheckIn(id string, status string, message string, meta []byte, c
 	return nil
 }
 
-// Run starts the flush timer and exit only when the context is cancelled.
+// Run starts the flush timer and exit only when the context is cancelled, the pending checkins are flushed then.
 func (bc *Bulk) Run(ctx context.Context) error {
 
 	tick := time.NewTicker(bc.opts.flushInterval)
@@ -151,6 +164,13 @@ LOOP:
 		}
 	}
 
+	// The bulker outlives the subsystems of the server, the last checkins are not lost on a restart.
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalFlushTimeout)
+	defer cancel()
+	if ferr := bc.flush(flushCtx); ferr != nil {
+		zerolog.Ctx(ctx).Error().Err(ferr).Msg("Final bulk checkin flush failed")
+	}
+
 	return err
 }
 
@@ -254,7 +274,10 @@ func (bc *Bulk) flush(ctx context.Context) error {
 		opts = append(opts, bulk.WithRefresh())
 	}
 
-	_, err = bc.bulker.MUpdate(ctx, updates, opts...)
+	err = bc.opts.spool.Write(bc.spoolOps(updates, needRefresh), func() error {
+		_, err := bc.bulker.MUpdate(ctx, updates, opts...)
+		return err
+	})
 
 	zerolog.Ctx(ctx).Trace().
 		Err(err).
@@ -265,3 +288,15 @@ func (bc *Bulk) flush(ctx context.Context) error {
 
 	return err
 }
+
+// spoolOps returns the spool operations of the updates, nothing is allocated without a spool.
+func (bc *Bulk) spoolOps(updates []bulk.MultiOp, refresh bool) []spool.Op {
+	if bc.opts.spool == nil {
+		return nil
+	}
+	ops := make([]spool.Op, len(updates))
+	for i, u := range updates {
+		ops[i] = spool.Op{Action: spool.ActionUpdate, Index: u.Index, ID: u.ID, Body: u.Body, Refresh: refresh}
+	}
+	return ops
+}
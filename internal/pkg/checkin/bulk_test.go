@@ -8,11 +8,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/spool"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
 	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
@@ -206,6 +210,55 @@ func TestBulkSimple(t *testing.T) {
 	}
 }
 
+func TestBulkRunFinalFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	mockBulk := ftesting.NewMockBulk()
+	mockBulk.On("MUpdate", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), mock.Anything, mock.Anything).Return([]bulk.BulkIndexerResponseItem{}, nil).Once()
+	bc := NewBulk(mockBulk, WithFlushInterval(time.Hour))
+
+	if err := bc.CheckIn("agent-1", "online", "", nil, nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := bc.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	mockBulk.AssertExpectations(t)
+}
+
+func TestBulkFlushSpool(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	cfg := config.Spool{Enabled: true, Path: t.TempDir()}
+	cfg.InitDefaults()
+	sp, err := spool.Open(ctx, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockBulk := ftesting.NewMockBulk()
+	mockBulk.On("MUpdate", mock.Anything, mock.Anything, mock.Anything).Return([]bulk.BulkIndexerResponseItem{}, &net.OpError{Op: "dial", Err: errors.New("connection refused")}).Once()
+	bc := NewBulk(mockBulk, WithSpool(sp))
+
+	if err := bc.CheckIn("agent-1", "online", "", nil, nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.flush(ctx); err != nil {
+		t.Fatalf("expected the checkins to be spooled, got %v", err)
+	}
+	if !sp.Pending() {
+		t.Fatal("expected the checkins in the spool")
+	}
+
+	// the checkins that follow are spooled until the spool is replayed
+	if err := bc.CheckIn("agent-2", "online", "", nil, nil, nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mockBulk.AssertExpectations(t)
+}
+
 func validateTimestamp(tb testing.TB, start time.Time, ts string) {
 	if t1, err := time.Parse(time.RFC3339, ts); err != nil {
 		tb.Error("expected rfc3999")
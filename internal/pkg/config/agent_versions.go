@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+)
+
+// AgentVersions are the rules on the versions of the agents checked when they enroll and check in, to keep the
+// incompatible or vulnerable builds of the agents out of the fleet. They apply in addition to the versions supported
+// by the server, the pre-release suffixes of the versions are ignored.
+type AgentVersions struct {
+	// MinVersion is the minimum version of the agents, like 8.12.0.
+	MinVersion string `config:"min_version"`
+	// Blocked are the versions or the version constraints of the agents refused, like 8.13.1 or ">= 8.14.0, < 8.14.2".
+	Blocked []string `config:"blocked"`
+	// Policies are the versions allowed for the agents of a policy.
+	Policies []PolicyAgentVersions `config:"policies"`
+	// WarnOnly logs the agents whose version is refused without refusing them, to evaluate the rules before they are
+	// enforced.
+	WarnOnly bool `config:"warn_only"`
+}
+
+// PolicyAgentVersions are the versions allowed for the agents of a policy, like ">= 8.14" for a policy using the
+// inputs of 8.14.
+type PolicyAgentVersions struct {
+	PolicyID string `config:"policy_id"`
+	// Allowed is the version constraint of the agents of the policy.
+	Allowed string `config:"allowed"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *AgentVersions) Validate() error {
+	if c.MinVersion != "" {
+		if _, err := version.NewVersion(c.MinVersion); err != nil {
+			return fmt.Errorf("agent_versions min_version %q is invalid: %w", c.MinVersion, err)
+		}
+	}
+	for _, blocked := range c.Blocked {
+		if _, err := version.NewConstraint(blocked); err != nil {
+			return fmt.Errorf("agent_versions blocked version %q is invalid: %w", blocked, err)
+		}
+	}
+	policies := make(map[string]bool, len(c.Policies))
+	for _, p := range c.Policies {
+		if p.PolicyID == "" {
+			return errors.New("agent_versions policies require a policy_id")
+		}
+		if policies[p.PolicyID] {
+			return fmt.Errorf("agent_versions of policy %s are set more than once", p.PolicyID)
+		}
+		policies[p.PolicyID] = true
+		if _, err := version.NewConstraint(p.Allowed); err != nil {
+			return fmt.Errorf("agent_versions allowed versions %q of policy %s are invalid: %w", p.Allowed, p.PolicyID, err)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true when a rule is set.
+func (c *AgentVersions) Enabled() bool {
+	return c.MinVersion != "" || len(c.Blocked) > 0 || len(c.Policies) > 0
+}
@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		err     string
+		enabled bool
+	}{
+		{name: "empty", cfg: "{}"},
+		{name: "warn only", cfg: "warn_only: true"},
+		{name: "min version", cfg: "min_version: 8.12.0", enabled: true},
+		{name: "blocked", cfg: "blocked: [8.13.1, \">= 8.14.0, < 8.14.2\"]", enabled: true},
+		{name: "policies", cfg: "policies:\n  - policy_id: p1\n    allowed: \">= 8.14\"", enabled: true},
+		{name: "invalid min version", cfg: "min_version: latest", err: "agent_versions min_version \"latest\" is invalid"},
+		{name: "invalid blocked", cfg: "blocked: [\"~~ 8\"]", err: "agent_versions blocked version \"~~ 8\" is invalid"},
+		{name: "policy without id", cfg: "policies:\n  - allowed: \">= 8.14\"", err: "agent_versions policies require a policy_id"},
+		{name: "duplicate policy", cfg: "policies:\n  - policy_id: p1\n    allowed: \">= 8.14\"\n  - policy_id: p1\n    allowed: \">= 8.15\"", err: "agent_versions of policy p1 are set more than once"},
+		{name: "invalid policy versions", cfg: "policies:\n  - policy_id: p1", err: "agent_versions allowed versions \"\" of policy p1 are invalid"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var v AgentVersions
+			err = c.Unpack(&v, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.enabled, v.Enabled())
+		})
+	}
+}
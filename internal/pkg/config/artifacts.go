@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+const (
+	defaultArtifactUpstreamTimeout = 30 * time.Second
+	defaultArtifactUpstreamMaxBody = 100 * 1024 * 1024 // 100MiB
+)
+
+// Artifacts is the configuration for the artifacts endpoint.
+type Artifacts struct {
+	Upstream ArtifactUpstream `config:"upstream"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Artifacts) InitDefaults() {
+	c.Upstream.InitDefaults()
+}
+
+// ArtifactUpstream is the configuration of an upstream fleet-server or artifact registry.
+// When enabled, artifacts that can not be found in Elasticsearch are retrieved from the upstream and cached locally.
+type ArtifactUpstream struct {
+	Enabled bool `config:"enabled"`
+	// URL is the base URL of the upstream, the artifact path (/api/fleet/artifacts/{id}/{sha2}) is appended to it.
+	URL string `config:"url"`
+	// Headers are added to every upstream request, it is used to pass credentials to the upstream.
+	Headers         map[string]string `config:"headers"`
+	TLS             *tlscommon.Config `config:"ssl"`
+	Timeout         time.Duration     `config:"timeout"`
+	MaxBodyByteSize int64             `config:"max_body_byte_size"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ArtifactUpstream) InitDefaults() {
+	c.Timeout = defaultArtifactUpstreamTimeout
+	c.MaxBodyByteSize = defaultArtifactUpstreamMaxBody
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ArtifactUpstream) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid artifact upstream url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("artifact upstream url must use http or https, got %q", c.URL)
+	}
+	if c.TLS != nil && c.TLS.IsEnabled() {
+		if _, err := tlscommon.LoadTLSConfig(c.TLS); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTPClient returns a client that is used to communicate with the upstream.
+func (c *ArtifactUpstream) HTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // DefaultTransport is always a *http.Transport
+	if c.TLS != nil && c.TLS.IsEnabled() {
+		tls, err := tlscommon.LoadTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tls.ToConfig()
+	}
+	transport.TLSClientConfig = fips.Restrict(transport.TLSClientConfig)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultAuditFileName       = "fleet-server-audit"
+	defaultAuditKeepFiles      = 30
+	defaultAuditRotateSize     = 10 * 1024 * 1024
+	defaultAuditAnchorInterval = time.Minute
+
+	minAuditKeySize = 32
+)
+
+// Audit is the configuration of the audit log, a dedicated file of ECS events that records the mutating operations,
+// like the enrollments, the acks, the API key operations and the uploads, and the calls of the admin endpoints.
+// Each event carries the HMAC-SHA256 of the hash of the previous one and of itself, so an event that is altered or
+// removed breaks the chain, and the hash of the last event is written to the fleet-server log so the events removed
+// from the end of the files are detected.
+type Audit struct {
+	Enabled bool `config:"enabled"`
+	// Key is the base64 encoded key of the hashes, of 32 bytes at least. It is best provided through the keystore or
+	// a secret provider, the files can be rewritten by whoever knows it.
+	Key string `config:"key"`
+	// AnchorInterval is how often at most the hash of the last event is written to the fleet-server log, the events
+	// written since the last anchor are not protected from being removed from the end of the files.
+	AnchorInterval time.Duration `config:"anchor_interval"`
+	Files          AuditFiles    `config:"files"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Audit) InitDefaults() {
+	c.AnchorInterval = defaultAuditAnchorInterval
+	c.Files.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Audit) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.AnchorInterval <= 0 {
+		return errors.New("audit anchor_interval must be positive")
+	}
+	_, err := c.DecodeKey()
+	return err
+}
+
+// DecodeKey returns the raw key of the hashes.
+func (c *Audit) DecodeKey() ([]byte, error) {
+	if c.Key == "" {
+		return nil, errors.New("audit key is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("audit key must be base64 encoded: %w", err)
+	}
+	if len(key) < minAuditKeySize {
+		return nil, fmt.Errorf("audit key must be %d bytes at least, got %d", minAuditKeySize, len(key))
+	}
+	return key, nil
+}
+
+// AuditFiles is the file of the audit log and its rotation and retention, the settings are the ones of the files of
+// the logs with different defaults. The files are named after the name, the date and an index, like
+// fleet-server-audit-20240610-1.ndjson.
+type AuditFiles LoggingFiles
+
+// InitDefaults initializes the defaults for the configuration. The files are not rotated on startup, so the chain of
+// the hashes continues in the same file.
+func (c *AuditFiles) InitDefaults() {
+	(*LoggingFiles)(c).InitDefaults()
+	c.Name = defaultAuditFileName
+	c.MaxSize = defaultAuditRotateSize
+	c.MaxBackups = defaultAuditKeepFiles
+	c.RotateOnStartup = false
+}
@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAuditKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{name: "disabled without key", cfg: "enabled: false"},
+		{name: "enabled", cfg: "enabled: true\nkey: " + testAuditKey},
+		{name: "enabled without key", cfg: "enabled: true", err: "audit key is required"},
+		{name: "key not base64", cfg: "enabled: true\nkey: not-base64!", err: "audit key must be base64 encoded"},
+		{name: "short key", cfg: "enabled: true\nkey: c2hvcnQ=", err: "audit key must be 32 bytes at least, got 5"},
+		{name: "anchor interval", cfg: "enabled: true\nanchor_interval: 0s\nkey: " + testAuditKey, err: "audit anchor_interval must be positive"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var v Audit
+			err = c.Unpack(&v, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, time.Minute, v.AnchorInterval)
+		})
+	}
+}
@@ -16,6 +16,24 @@ const (
 	defaultArtifactTTL  = time.Hour * 24
 	defaultAPIKeyTTL    = time.Minute * 15 // APIKey validation is a bottleneck.
 	defaultAPIKeyJitter = time.Minute * 5  // Jitter allows some randomness on APIKeyTTL, zero to disable
+	defaultSecretTTL    = time.Minute * 5
+	defaultNotFoundTTL  = time.Second * 10
+
+	defaultWarmupTimeout    = time.Second * 30
+	defaultWarmupEnrollKeys = 1000
+	defaultWarmupArtifacts  = 100
+)
+
+// Cache backends.
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// Cache admission policies.
+const (
+	CacheAdmissionLFU  = "lfu"
+	CacheAdmissionNone = "none"
 )
 
 type Cache struct {
@@ -26,6 +44,72 @@ type Cache struct {
 	ArtifactTTL  time.Duration `config:"ttl_artifact"`
 	APIKeyTTL    time.Duration `config:"ttl_api_key"`
 	APIKeyJitter time.Duration `config:"jitter_api_key"`
+	SecretTTL    time.Duration `config:"ttl_secret"`
+	// NotFoundTTL is how long a lookup of a missing agent, artifact or enrollment key is answered from the cache
+	// without querying Elasticsearch again, a negative value disables it.
+	NotFoundTTL time.Duration `config:"ttl_not_found"`
+	// APIKeyStatePath is the file where the validated API keys are kept across restarts, so a restart does
+	// not validate the key of every agent with Elasticsearch again. Only a salted hash of the keys is
+	// written. Empty disables it.
+	APIKeyStatePath string `config:"api_key_state_path"`
+	// Backend is the cache backend. The memory backend, the default, keeps the cache in each fleet-server.
+	// The redis backend also shares the API key validations and artifacts with the other fleet-server
+	// instances connected to the same Redis server, so scaled out instances do not each warm their own cache.
+	// The invalidations of the entries, like a revoked API key, are broadcast to the other instances through
+	// Redis as they happen.
+	Backend string     `config:"backend"`
+	Redis   RedisCache `config:"redis"`
+
+	// The partitions give a type of entries its own capacity, so artifacts do not evict the API keys.
+	// Service tokens are kept with the API keys.
+	APIKeys    CachePartition `config:"api_keys"`
+	EnrollKeys CachePartition `config:"enroll_keys"`
+	Artifacts  CachePartition `config:"artifacts"`
+	Actions    CachePartition `config:"actions"`
+
+	Warmup CacheWarmup `config:"warmup"`
+}
+
+// CacheWarmup is the configuration of the startup phase that loads the hot entries into the cache
+// before the server accepts requests, so the first checkins after a restart do not all miss the cache.
+type CacheWarmup struct {
+	Enabled bool `config:"enabled"`
+	// Timeout of the warm-up, the server starts once it is reached. Defaults to 30s.
+	Timeout time.Duration `config:"timeout"`
+	// EnrollKeys is the number of active enrollment keys loaded, defaults to 1000. A negative value loads none.
+	EnrollKeys int `config:"enroll_keys"`
+	// Artifacts is the number of the most recently created artifacts loaded, defaults to 100. A negative value loads none.
+	Artifacts int `config:"artifacts"`
+}
+
+// CachePartition is the configuration of the cache of one type of entries.
+type CachePartition struct {
+	// MaxCost of the dedicated cache of the entries, 0 keeps them in the shared cache sized by max_cost.
+	MaxCost int64 `config:"max_cost"`
+	// NumCounters of the dedicated cache, defaults to num_counters scaled to the share of max_cost.
+	NumCounters int64 `config:"num_counters"`
+	// TTL of the entries, overrides the ttl_* setting of the type when set.
+	TTL time.Duration `config:"ttl"`
+	// Admission is the admission policy of the entries. lfu, the default, only admits a new entry over the
+	// entries it would evict when it is used more often. none does not cache the entries.
+	Admission string `config:"admission"`
+}
+
+// RedisCache is the configuration of the Redis server of the redis cache backend.
+type RedisCache struct {
+	Address  string `config:"address"`
+	Username string `config:"username"`
+	Password string `config:"password"`
+	DB       int    `config:"db"`
+	// TLS connects to the server with TLS, the certificate is verified with the system roots.
+	TLS bool `config:"tls"`
+	// KeyPrefix is prepended to the keys, defaults to "fleet-server:".
+	KeyPrefix string `config:"key_prefix"`
+	// Timeout of each request to the server, defaults to 100ms. The cache of the fleet-server is used alone
+	// while the server is not reachable.
+	Timeout time.Duration `config:"timeout"`
+	// PoolSize is the number of idle connections kept open, defaults to 10.
+	PoolSize int `config:"pool_size"`
 }
 
 func (c *Cache) InitDefaults() {}
@@ -34,6 +118,19 @@ func (c *Cache) InitDefaults() {}
 func (c *Cache) LoadLimits(limits *envLimits) {
 	l := limits.Cache
 
+	// The TTL of a partition takes precedence over the ttl_* setting of the type
+	if c.Actions.TTL != 0 {
+		c.ActionTTL = c.Actions.TTL
+	}
+	if c.EnrollKeys.TTL != 0 {
+		c.EnrollKeyTTL = c.EnrollKeys.TTL
+	}
+	if c.Artifacts.TTL != 0 {
+		c.ArtifactTTL = c.Artifacts.TTL
+	}
+	if c.APIKeys.TTL != 0 {
+		c.APIKeyTTL = c.APIKeys.TTL
+	}
 	if c.NumCounters == 0 {
 		c.NumCounters = l.NumCounters
 	}
@@ -55,19 +152,44 @@ func (c *Cache) LoadLimits(limits *envLimits) {
 	if c.APIKeyJitter == 0 {
 		c.APIKeyJitter = defaultAPIKeyJitter
 	}
+	if c.SecretTTL == 0 {
+		c.SecretTTL = defaultSecretTTL
+	}
+	if c.NotFoundTTL == 0 {
+		c.NotFoundTTL = defaultNotFoundTTL
+	}
+	if c.Warmup.Timeout == 0 {
+		c.Warmup.Timeout = defaultWarmupTimeout
+	}
+	if c.Warmup.EnrollKeys == 0 {
+		c.Warmup.EnrollKeys = defaultWarmupEnrollKeys
+	}
+	if c.Warmup.Artifacts == 0 {
+		c.Warmup.Artifacts = defaultWarmupArtifacts
+	}
 }
 
 // CopyCache returns a copy of the config's Cache settings
 func CopyCache(cfg *Config) Cache {
 	ccfg := cfg.Inputs[0].Cache
 	return Cache{
-		NumCounters:  ccfg.NumCounters,
-		MaxCost:      ccfg.MaxCost,
-		ActionTTL:    ccfg.ActionTTL,
-		EnrollKeyTTL: ccfg.EnrollKeyTTL,
-		ArtifactTTL:  ccfg.ArtifactTTL,
-		APIKeyTTL:    ccfg.APIKeyTTL,
-		APIKeyJitter: ccfg.APIKeyJitter,
+		NumCounters:     ccfg.NumCounters,
+		MaxCost:         ccfg.MaxCost,
+		ActionTTL:       ccfg.ActionTTL,
+		EnrollKeyTTL:    ccfg.EnrollKeyTTL,
+		ArtifactTTL:     ccfg.ArtifactTTL,
+		APIKeyTTL:       ccfg.APIKeyTTL,
+		APIKeyJitter:    ccfg.APIKeyJitter,
+		SecretTTL:       ccfg.SecretTTL,
+		NotFoundTTL:     ccfg.NotFoundTTL,
+		APIKeyStatePath: ccfg.APIKeyStatePath,
+		Backend:         ccfg.Backend,
+		Redis:           ccfg.Redis,
+		APIKeys:         ccfg.APIKeys,
+		EnrollKeys:      ccfg.EnrollKeys,
+		Artifacts:       ccfg.Artifacts,
+		Actions:         ccfg.Actions,
+		Warmup:          ccfg.Warmup,
 	}
 }
 
@@ -80,4 +202,26 @@ func (c *Cache) MarshalZerologObject(e *zerolog.Event) {
 	e.Dur("artifactTTL", c.ArtifactTTL)
 	e.Dur("apiKeyTTL", c.APIKeyTTL)
 	e.Dur("apiKeyJitter", c.APIKeyJitter)
+	e.Dur("secretTTL", c.SecretTTL)
+	e.Dur("notFoundTTL", c.NotFoundTTL)
+	e.Str("apiKeyStatePath", c.APIKeyStatePath)
+	e.Str("backend", c.Backend)
+	e.Object("apiKeys", &c.APIKeys)
+	e.Object("enrollKeys", &c.EnrollKeys)
+	e.Object("artifacts", &c.Artifacts)
+	e.Object("actions", &c.Actions)
+	e.Bool("warmup", c.Warmup.Enabled)
+	if c.Backend == CacheBackendRedis {
+		e.Str("redisAddress", c.Redis.Address)
+		e.Int("redisDB", c.Redis.DB)
+		e.Str("redisKeyPrefix", c.Redis.KeyPrefix)
+	}
+}
+
+// MarshalZerologObject turns the cache partition settings into a zerolog event
+func (p *CachePartition) MarshalZerologObject(e *zerolog.Event) {
+	e.Int64("maxCost", p.MaxCost)
+	e.Int64("numCounters", p.NumCounters)
+	e.Dur("ttl", p.TTL)
+	e.Str("admission", p.Admission)
 }
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/go-ucfg"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is a problem of the configuration found by a check, the server does not start on an error.
+type Problem struct {
+	Setting  string `json:"setting,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Setting == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Setting, p.Message)
+}
+
+// DeprecatedOptions returns a warning for each deprecated setting of c.
+func DeprecatedOptions(c *ucfg.Config) []Problem {
+	var problems []Problem
+	for opt, message := range deprecatedConfigOptions {
+		if ok, _ := c.Has(opt, -1, DefaultOptions...); ok {
+			problems = append(problems, Problem{Setting: opt, Severity: SeverityWarning, Message: message})
+		}
+	}
+	return problems
+}
+
+// Check returns the problems of the configuration that are not found when it is unpacked: the TLS material of the
+// server and its listeners that can not be loaded and the limits and timeouts that are not consistent.
+// It is called after LoadServerLimits.
+func (c *Config) Check() []Problem {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.Validate(); err != nil {
+		return []Problem{{Setting: "inputs", Severity: SeverityError, Message: err.Error()}}
+	}
+
+	srv := &c.Inputs[0].Server
+	problems := checkServerTLS("inputs.0.server.ssl", srv.TLS)
+	problems = append(problems, srv.Limits.check("inputs.0.server.limits")...)
+	problems = append(problems, srv.Timeouts.check("inputs.0.server.timeouts")...)
+	if srv.Tenancy.Enabled && len(srv.Tenancy.Principals) == 0 {
+		problems = append(problems, Problem{Setting: "inputs.0.server.tenancy.principals", Severity: SeverityWarning, Message: "no principal is set, every API key of the admin APIs is refused"})
+	}
+	for i, l := range srv.Listeners {
+		prefix := fmt.Sprintf("inputs.0.server.listeners.%d", i)
+		problems = append(problems, checkServerTLS(prefix+".ssl", l.TLS)...)
+		if l.Limits != nil {
+			problems = append(problems, l.Limits.check(prefix+".limits")...)
+		}
+	}
+	return problems
+}
+
+func checkServerTLS(setting string, cfg *tlscommon.ServerConfig) []Problem {
+	if cfg == nil || !cfg.IsEnabled() {
+		return nil
+	}
+	if _, err := tlscommon.LoadTLSServerConfig(cfg); err != nil {
+		return []Problem{{Setting: setting, Severity: SeverityError, Message: fmt.Sprintf("unable to load the TLS configuration: %v", err)}}
+	}
+	return nil
+}
+
+// check returns the route limits that reject all the requests of their route, and the limits allowing more
+// parallel requests than the connection limit.
+func (c *ServerLimits) check(setting string) []Problem {
+	var problems []Problem
+	if c.MaxConnections < 0 {
+		problems = append(problems, Problem{Setting: setting + ".max_connections", Severity: SeverityError, Message: "must not be negative"})
+	}
+	v := reflect.ValueOf(*c)
+	for i := 0; i < v.NumField(); i++ {
+		l, ok := v.Field(i).Interface().(Limit)
+		if !ok {
+			continue
+		}
+		name := setting + "." + v.Type().Field(i).Tag.Get("config")
+		if l.Interval > 0 && l.Burst < 1 {
+			problems = append(problems, Problem{Setting: name, Severity: SeverityError, Message: "an interval without a burst rejects all the requests"})
+		}
+		if l.Max < 0 || l.MaxBody < 0 {
+			problems = append(problems, Problem{Setting: name, Severity: SeverityError, Message: "max and max_body_byte_size must not be negative"})
+		}
+		if c.MaxConnections > 0 && l.Max > int64(c.MaxConnections) {
+			problems = append(problems, Problem{Setting: name, Severity: SeverityWarning, Message: fmt.Sprintf("max %d is more than the %d connections of the server", l.Max, c.MaxConnections)})
+		}
+	}
+	return problems
+}
+
+// check returns the timeouts that end the checkins of the agents before their long poll.
+func (c *ServerTimeouts) check(setting string) []Problem {
+	var problems []Problem
+	name := setting + ".write"
+	if c.Routes["checkin"].Write > 0 {
+		name = setting + ".routes.checkin.write"
+	}
+	if write, read := c.RouteWrite("checkin"), c.RouteRead("checkin"); write > 0 && write < read+c.CheckinLongPoll {
+		problems = append(problems, Problem{Setting: name, Severity: SeverityWarning, Message: fmt.Sprintf("%v is less than the read timeout plus the checkin long poll %v", write, read+c.CheckinLongPoll)})
+	}
+	if c.CheckinMaxPoll > 0 && c.CheckinMaxPoll < c.CheckinLongPoll {
+		problems = append(problems, Problem{Setting: setting + ".checkin_max_poll", Severity: SeverityWarning, Message: fmt.Sprintf("%v is less than the checkin long poll %v", c.CheckinMaxPoll, c.CheckinLongPoll)})
+	}
+	return problems
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+func TestDeprecatedOptions(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("inputs:\n  - server:\n      limits:\n        max_connections: 10\n"), DefaultOptions...)
+	require.NoError(t, err)
+	problems := DeprecatedOptions(c)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "inputs.0.server.limits.max_connections", problems[0].Setting)
+	assert.Equal(t, SeverityWarning, problems[0].Severity)
+}
+
+func TestConfigCheck(t *testing.T) {
+	newConfig := func() *Config {
+		cfg := &Config{}
+		cfg.InitDefaults()
+		require.NoError(t, cfg.LoadServerLimits())
+		return cfg
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.Empty(t, newConfig().Check())
+	})
+
+	t.Run("no input", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Inputs = nil
+		problems := cfg.Check()
+		require.Len(t, problems, 1)
+		assert.Equal(t, SeverityError, problems[0].Severity)
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		cfg := newConfig()
+		srv := &cfg.Inputs[0].Server
+		srv.Listeners = []Listener{{Port: 8222, TLS: &tlscommon.ServerConfig{Certificate: tlscommon.CertificateConfig{Certificate: "/missing/cert.pem", Key: "/missing/key.pem"}}}}
+		problems := cfg.Check()
+		require.Len(t, problems, 1)
+		assert.Equal(t, SeverityError, problems[0].Severity)
+		if fips.Required {
+			// the key of the listener is read by the validation of the FIPS mode
+			assert.Equal(t, "inputs", problems[0].Setting)
+			assert.Contains(t, problems[0].Message, "inputs.0.server.listeners.0.ssl")
+			return
+		}
+		assert.Equal(t, "inputs.0.server.listeners.0.ssl", problems[0].Setting)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		cfg := newConfig()
+		limits := &cfg.Inputs[0].Server.Limits
+		limits.MaxConnections = 10000
+		limits.CheckinLimit = Limit{Interval: time.Millisecond, Max: 50}
+		limits.AckLimit.Max = 20000
+		assert.ElementsMatch(t, []Problem{
+			{Setting: "inputs.0.server.limits.checkin_limit", Severity: SeverityError, Message: "an interval without a burst rejects all the requests"},
+			{Setting: "inputs.0.server.limits.ack_limit", Severity: SeverityWarning, Message: "max 20000 is more than the 10000 connections of the server"},
+		}, cfg.Check())
+	})
+
+	t.Run("timeouts", func(t *testing.T) {
+		cfg := newConfig()
+		timeouts := &cfg.Inputs[0].Server.Timeouts
+		timeouts.Write = time.Minute
+		timeouts.CheckinMaxPoll = time.Minute
+		problems := cfg.Check()
+		require.Len(t, problems, 2)
+		assert.Equal(t, "inputs.0.server.timeouts.write", problems[0].Setting)
+		assert.Equal(t, "inputs.0.server.timeouts.checkin_max_poll", problems[1].Setting)
+	})
+
+	t.Run("checkin route write timeout", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Inputs[0].Server.Timeouts.Routes = map[string]RouteTimeouts{"checkin": {Write: time.Minute}, "acks": {Write: time.Second}}
+		problems := cfg.Check()
+		require.Len(t, problems, 1)
+		assert.Equal(t, "inputs.0.server.timeouts.routes.checkin.write", problems[0].Setting)
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultCheckinBackoffLimiterThreshold = 0.9
+	defaultCheckinBackoffPollInterval     = time.Minute
+	defaultCheckinBackoffRetryAfter       = 30 * time.Second
+)
+
+// CheckinBackoff is the configuration of the backoff hint sent to the agents in the checkin responses when the
+// server is under pressure, so the agents slow down before the server has to reject their requests.
+type CheckinBackoff struct {
+	// Enabled sends the backoff hint when the server is under pressure.
+	Enabled bool `config:"enabled"`
+	// LimiterThreshold is the share of the checkin limiter max in use above which the hint is sent.
+	LimiterThreshold float64 `config:"limiter_threshold"`
+	// QueueThreshold is the number of requests queued by the bulker above which the hint is sent, 0 disables the check.
+	QueueThreshold int `config:"queue_threshold"`
+	// PollInterval is the interval the agents are asked to check in at.
+	PollInterval time.Duration `config:"poll_interval"`
+	// RetryAfter is the delay of the Retry-After header of the responses.
+	RetryAfter time.Duration `config:"retry_after"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *CheckinBackoff) InitDefaults() {
+	c.Enabled = true
+	c.LimiterThreshold = defaultCheckinBackoffLimiterThreshold
+	c.PollInterval = defaultCheckinBackoffPollInterval
+	c.RetryAfter = defaultCheckinBackoffRetryAfter
+}
+
+// Validate ensures that the configuration is valid.
+func (c *CheckinBackoff) Validate() error {
+	if c.LimiterThreshold <= 0 || c.LimiterThreshold > 1 {
+		return fmt.Errorf("checkin backoff limiter threshold must be in (0, 1], got %v", c.LimiterThreshold)
+	}
+	if c.QueueThreshold < 0 {
+		return fmt.Errorf("checkin backoff queue threshold must not be negative, got %d", c.QueueThreshold)
+	}
+	if c.PollInterval < 0 || c.RetryAfter < 0 {
+		return fmt.Errorf("checkin backoff durations must not be negative, got poll interval %v and retry after %v", c.PollInterval, c.RetryAfter)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+const (
+	// ClientCertificateIdentitySubject binds the agents to the subject of their certificate, it stays the same
+	// when the certificate is renewed.
+	ClientCertificateIdentitySubject = "subject"
+	// ClientCertificateIdentityPublicKey binds the agents to the public key of their certificate.
+	ClientCertificateIdentityPublicKey = "public_key"
+
+	defaultOCSPTimeout = 5 * time.Second
+)
+
+// ClientCertificates is the configuration of the mutual TLS authentication of the agents. The agents enroll with a
+// client certificate verified by the certificate authorities of the ssl configuration and the identity of the
+// certificate is bound to the agent, so its API key alone can not be used to impersonate it.
+// The revocation checks apply to all the client certificates, the binding is enabled or not.
+type ClientCertificates struct {
+	// Enabled requires a client certificate to enroll and binds the agents to it.
+	Enabled bool `config:"enabled"`
+	// Identity is what binds a certificate to an agent, subject or public_key. The agents keep the identity they
+	// enrolled with when it changes.
+	Identity string `config:"identity"`
+	// CRLs are the paths of the certificate revocation lists, in PEM or DER, the client certificates are checked
+	// against. The lists are read again when they change.
+	CRLs []string `config:"crls"`
+	OCSP OCSP     `config:"ocsp"`
+}
+
+// OCSP is the configuration of the revocation checks of the client certificates with the OCSP responders of their
+// issuers. The responses are cached until their next update.
+type OCSP struct {
+	Enabled bool          `config:"enabled"`
+	Timeout time.Duration `config:"timeout"`
+	// SoftFail accepts the certificates whose status can not be retrieved, the revoked certificates are always rejected.
+	SoftFail bool `config:"soft_fail"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ClientCertificates) InitDefaults() {
+	c.Identity = ClientCertificateIdentitySubject
+	c.OCSP.Timeout = defaultOCSPTimeout
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ClientCertificates) Validate() error {
+	if c.Identity != ClientCertificateIdentitySubject && c.Identity != ClientCertificateIdentityPublicKey {
+		return fmt.Errorf("client certificates identity must be %s or %s, got %q", ClientCertificateIdentitySubject, ClientCertificateIdentityPublicKey, c.Identity)
+	}
+	if c.OCSP.Enabled && c.OCSP.Timeout <= 0 {
+		return fmt.Errorf("client certificates ocsp timeout must be positive, got %v", c.OCSP.Timeout)
+	}
+	return nil
+}
+
+// Revocation returns true when the client certificates are checked for revocation.
+func (c *ClientCertificates) Revocation() bool {
+	return len(c.CRLs) > 0 || c.OCSP.Enabled
+}
+
+// validateClientCertificates ensures the client certificates are required when the agents are bound to them.
+func (c *Server) validateClientCertificates() error {
+	if !c.ClientCertificates.Enabled {
+		return nil
+	}
+	if c.TLS == nil || !c.TLS.IsEnabled() || c.TLS.ClientAuth == nil || *c.TLS.ClientAuth != tlscommon.TLSClientAuthRequired {
+		return errors.New("client certificates require ssl to be enabled with client_authentication required")
+	}
+	return nil
+}
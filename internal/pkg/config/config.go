@@ -8,8 +8,10 @@ package config
 import (
 	"context"
 	"errors"
+	"reflect"
 	"sync"
 
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/fleet-server/v7/version"
 	"github.com/elastic/go-ucfg"
 	"github.com/elastic/go-ucfg/flag"
@@ -51,11 +53,18 @@ type Config struct {
 	Inputs  []Input `config:"inputs"`
 	Logging Logging `config:"logging"`
 	HTTP    HTTP    `config:"http"`
+	Secrets Secrets `config:"secrets"`
 	m       sync.Mutex
+
+	// source are the settings the configuration was unpacked from, with their secret references, so the
+	// secrets can be read again.
+	source map[string]interface{}
+	// secrets are the values read from the secret providers by setting.
+	secrets map[string]interface{}
 }
 
 var deprecatedConfigOptions = map[string]string{
-	"inputs[0].limits.max_connections": "max_connections has been deprecated and will be removed in a future release. Please configure server limits using max_agents instead.",
+	"inputs.0.server.limits.max_connections": "max_connections has been deprecated and will be removed in a future release. Please configure server limits using max_agents instead.",
 }
 
 // InitDefaults initializes the defaults for the configuration.
@@ -64,6 +73,7 @@ func (c *Config) InitDefaults() {
 	c.Inputs[0].InitDefaults()
 	c.Logging.InitDefaults()
 	c.HTTP.InitDefaults()
+	c.Secrets.Vault.InitDefaults()
 }
 
 func (c *Config) GetFleetInput() (Input, error) {
@@ -83,6 +93,9 @@ func (c *Config) Validate() error {
 	if len(c.Inputs) > 1 {
 		return errors.New("only 1 fleet-server input can be defined")
 	}
+	if c.Inputs[0].Server.FIPS.Enabled {
+		return c.validateFIPS()
+	}
 	return nil
 }
 
@@ -101,6 +114,11 @@ func (c *Config) LoadServerLimits() error {
 	agentLimits := loadLimits(fleetInput.Server.Limits.MaxAgents)
 	fleetInput.Cache.LoadLimits(agentLimits)
 	fleetInput.Server.Limits.LoadLimits(agentLimits)
+	for _, l := range fleetInput.Server.Listeners {
+		if l.Limits != nil {
+			l.Limits.LoadLimits(agentLimits)
+		}
+	}
 	return nil
 }
 
@@ -167,28 +185,82 @@ func redactOutput(cfg *Config) Output {
 		redacted.Elasticsearch.TLS = &newTLS
 	}
 
+	redacted.Elasticsearch.Headers = redactHeaders(redacted.Elasticsearch.Headers)
+	redacted.Elasticsearch.ProxyHeaders = redactHeaders(redacted.Elasticsearch.ProxyHeaders)
+
 	return redacted
 }
 
+func redactServerTLS(tls *tlscommon.ServerConfig) *tlscommon.ServerConfig {
+	if tls == nil {
+		return nil
+	}
+	newTLS := *tls
+
+	if newTLS.Certificate.Key != "" {
+		newTLS.Certificate.Key = kRedacted
+	}
+	if newTLS.Certificate.Passphrase != "" {
+		newTLS.Certificate.Passphrase = kRedacted
+	}
+
+	return &newTLS
+}
+
 func redactServer(cfg *Config) Server {
 	redacted := cfg.Inputs[0].Server
 
-	if redacted.TLS != nil {
-		newTLS := *redacted.TLS
-
-		if newTLS.Certificate.Key != "" {
-			newTLS.Certificate.Key = kRedacted
-		}
-		if newTLS.Certificate.Passphrase != "" {
-			newTLS.Certificate.Passphrase = kRedacted
+	redacted.TLS = redactServerTLS(redacted.TLS)
+	if len(redacted.Listeners) > 0 {
+		listeners := make([]Listener, len(redacted.Listeners))
+		for i, l := range redacted.Listeners {
+			l.TLS = redactServerTLS(l.TLS)
+			listeners[i] = l
 		}
+		redacted.Listeners = listeners
+	}
+
+	if redacted.Uploads.Storage.S3.SecretAccessKey != "" {
+		redacted.Uploads.Storage.S3.SecretAccessKey = kRedacted
+	}
+	if redacted.Uploads.Encryption.Key != "" {
+		redacted.Uploads.Encryption.Key = kRedacted
+	}
+	if redacted.RequestSigning.Secret != "" {
+		redacted.RequestSigning.Secret = kRedacted
+	}
 
-		redacted.TLS = &newTLS
+	if redacted.Instrumentation.APIKey != "" {
+		redacted.Instrumentation.APIKey = kRedacted
+	}
+	if redacted.Instrumentation.SecretToken != "" {
+		redacted.Instrumentation.SecretToken = kRedacted
+	}
+	redacted.Instrumentation.OTLP.Headers = redactHeaders(redacted.Instrumentation.OTLP.Headers)
+
+	if len(redacted.StaticPolicyTokens.PolicyTokens) > 0 {
+		tokens := make([]PolicyToken, len(redacted.StaticPolicyTokens.PolicyTokens))
+		for i, pt := range redacted.StaticPolicyTokens.PolicyTokens {
+			tokens[i] = PolicyToken{TokenKey: kRedacted, PolicyID: pt.PolicyID}
+		}
+		redacted.StaticPolicyTokens.PolicyTokens = tokens
 	}
 
 	return redacted
 }
 
+// redactHeaders returns a copy of the headers with the values redacted, they may hold credentials.
+func redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	redacted := make(map[string]string, len(headers))
+	for k := range headers {
+		redacted[k] = kRedacted
+	}
+	return redacted
+}
+
 // Redact returns a copy of the config with all sensitive attributes redacted.
 func (c *Config) Redact() *Config {
 	redacted := &Config{
@@ -197,31 +269,121 @@ func (c *Config) Redact() *Config {
 		Inputs:  make([]Input, 1),
 		Logging: c.Logging,
 		HTTP:    c.HTTP,
+		Secrets: c.Secrets,
+	}
+	if redacted.Secrets.Vault.Token != "" {
+		redacted.Secrets.Vault.Token = kRedacted
 	}
 	redacted.Inputs[0].Server = redactServer(c)
 	redacted.Output = redactOutput(c)
 	return redacted
 }
 
-func checkDeprecatedOptions(deprecatedOpts map[string]string, c *ucfg.Config) {
-	for opt, message := range deprecatedOpts {
-		if c.HasField(opt) {
-			zerolog.Ctx(context.TODO()).Warn().Msg(message)
-		}
+func checkDeprecatedOptions(c *ucfg.Config) {
+	for _, p := range DeprecatedOptions(c) {
+		zerolog.Ctx(context.TODO()).Warn().Msg(p.Message)
 	}
 }
 
-// FromConfig returns Config from the ucfg.Config.
+// FromConfig returns Config from the ucfg.Config, the settings with a secret provider suffix are read from
+// their provider.
 func FromConfig(c *ucfg.Config) (*Config, error) {
-	checkDeprecatedOptions(deprecatedConfigOptions, c)
-	cfg := &Config{}
-	err := c.Unpack(cfg, DefaultOptions...)
+	checkDeprecatedOptions(c)
+	var source map[string]interface{}
+	if err := c.Unpack(&source, DefaultOptions...); err != nil {
+		return nil, err
+	}
+	return fromSource(context.TODO(), source)
+}
+
+func fromSource(ctx context.Context, source map[string]interface{}) (*Config, error) {
+	settings, _ := copySettings(source).(map[string]interface{})
+
+	// the settings of the secrets section are needed to read the other secrets, they are not read from Vault
+	var secrets Secrets
+	secrets.Vault.InitDefaults()
+	if s, ok := settings["secrets"]; ok {
+		if err := newSecretResolver(ctx, nil).resolve("secrets", s); err != nil {
+			return nil, err
+		}
+		sc, err := ucfg.NewFrom(s, DefaultOptions...)
+		if err != nil {
+			return nil, err
+		}
+		if err := sc.Unpack(&secrets, DefaultOptions...); err != nil {
+			return nil, err
+		}
+	}
+	fipsMode, err := fipsEnabled(settings)
+	if err != nil {
+		return nil, err
+	}
+	vault, err := newVaultClient(secrets.Vault, fipsMode)
+	if err != nil {
+		return nil, err
+	}
+	r := newSecretResolver(ctx, vault)
+	if err := r.resolve("", settings); err != nil {
+		return nil, err
+	}
+
+	c, err := ucfg.NewFrom(settings, DefaultOptions...)
 	if err != nil {
 		return nil, err
 	}
+	cfg := &Config{}
+	if err := c.Unpack(cfg, DefaultOptions...); err != nil {
+		return nil, err
+	}
+	// the settings are kept to read the secrets again
+	if len(r.resolved) > 0 {
+		cfg.source, cfg.secrets = source, r.resolved
+	}
 	return cfg, nil
 }
 
+// RefreshSecrets reads the secrets of the configuration again. It returns the configuration with the new secrets
+// and true when one of them changed.
+func (c *Config) RefreshSecrets(ctx context.Context) (*Config, bool, error) {
+	c.m.Lock()
+	source, secrets, agent := c.source, c.secrets, c.Fleet.Agent
+	c.m.Unlock()
+	if len(secrets) == 0 {
+		return c, false, nil
+	}
+	cfg, err := fromSource(ctx, source)
+	if err != nil {
+		return nil, false, err
+	}
+	if reflect.DeepEqual(secrets, cfg.secrets) {
+		return c, false, nil
+	}
+	if cfg.Fleet.Agent.ID == "" {
+		// the metadata of a standalone server
+		cfg.Fleet.Agent = agent
+	}
+	return cfg, true, nil
+}
+
+// copySettings returns a deep copy of the settings of a config unpacked as a map.
+func copySettings(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = copySettings(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = copySettings(e)
+		}
+		return s
+	}
+	return v
+}
+
 // LoadFile take a path and load the file and return a new configuration.
 // Only used in tests
 func LoadFile(path string) (*Config, error) {
@@ -39,14 +39,16 @@ func TestConfig(t *testing.T) {
 						Server: defaultServer(),
 						Cache:  defaultCache(),
 						Monitor: Monitor{
-							FetchSize:          defaultFetchSize,
-							PollTimeout:        defaultPollTimeout,
-							PolicyDebounceTime: defaultPolicyDebounceTime,
+							FetchSize:             defaultFetchSize,
+							PollTimeout:           defaultPollTimeout,
+							PolicyDebounceTime:    defaultPolicyDebounceTime,
+							PolicyDispatchMaxWait: defaultPolicyDispatchMaxWait,
 						},
 					},
 				},
 				Logging: defaultLogging(),
 				HTTP:    defaultHTTP(),
+				Secrets: defaultSecrets(),
 			},
 		},
 		"fleet-logging": {
@@ -68,14 +70,16 @@ func TestConfig(t *testing.T) {
 						Server: defaultServer(),
 						Cache:  defaultCache(),
 						Monitor: Monitor{
-							FetchSize:          defaultFetchSize,
-							PollTimeout:        defaultPollTimeout,
-							PolicyDebounceTime: defaultPolicyDebounceTime,
+							FetchSize:             defaultFetchSize,
+							PollTimeout:           defaultPollTimeout,
+							PolicyDebounceTime:    defaultPolicyDebounceTime,
+							PolicyDispatchMaxWait: defaultPolicyDispatchMaxWait,
 						},
 					},
 				},
 				Logging: defaultLogging(),
 				HTTP:    defaultHTTP(),
+				Secrets: defaultSecrets(),
 			},
 		},
 		"input": {
@@ -90,14 +94,16 @@ func TestConfig(t *testing.T) {
 						Server: defaultServer(),
 						Cache:  defaultCache(),
 						Monitor: Monitor{
-							FetchSize:          defaultFetchSize,
-							PollTimeout:        defaultPollTimeout,
-							PolicyDebounceTime: defaultPolicyDebounceTime,
+							FetchSize:             defaultFetchSize,
+							PollTimeout:           defaultPollTimeout,
+							PolicyDebounceTime:    defaultPolicyDebounceTime,
+							PolicyDispatchMaxWait: defaultPolicyDispatchMaxWait,
 						},
 					},
 				},
 				Logging: defaultLogging(),
 				HTTP:    defaultHTTP(),
+				Secrets: defaultSecrets(),
 			},
 		},
 		"input-config": {
@@ -110,9 +116,10 @@ func TestConfig(t *testing.T) {
 					{
 						Type: "fleet-server",
 						Server: Server{
-							Host:         "localhost",
-							Port:         8888,
-							InternalPort: 8221,
+							Host:              "localhost",
+							Port:              8888,
+							InternalPort:      8221,
+							TLSReloadInterval: time.Minute,
 							Timeouts: ServerTimeouts{
 								Read:             20 * time.Second,
 								ReadHeader:       5 * time.Second,
@@ -123,6 +130,7 @@ func TestConfig(t *testing.T) {
 								CheckinJitter:    30 * time.Second,
 								CheckinMaxPoll:   10 * time.Minute,
 								Drain:            10 * time.Second,
+								Query:            30 * time.Second,
 							},
 							Profiler: ServerProfiler{
 								Enabled: false,
@@ -137,17 +145,34 @@ func TestConfig(t *testing.T) {
 								UpstreamURL: defaultPGPUpstreamURL,
 								Dir:         filepath.Join(retrieveExecutableDir(), defaultPGPDirectoryName),
 							},
+							Artifacts:          defaultServerArtifacts(),
+							Uploads:            defaultServerUploads(),
+							PolicyRollout:      defaultServerPolicyRollout(),
+							Probes:             defaultServerProbes(),
+							CheckinBackoff:     defaultServerCheckinBackoff(),
+							ProxyProtocol:      defaultServerProxyProtocol(),
+							CORS:               defaultServerCORS(),
+							ClientCertificates: defaultServerClientCertificates(),
+							Drain:              defaultServerDrain(),
+							Standby:            defaultServerStandby(),
+							Coordinator:        defaultServerCoordinator(),
+							Audit:              defaultServerAudit(),
+							FIPS:               defaultServerFIPS(),
+							RequestSigning:     defaultServerRequestSigning(),
+							Spool:              defaultServerSpool(),
 						},
 						Cache: generateCache(0),
 						Monitor: Monitor{
-							FetchSize:          defaultFetchSize,
-							PollTimeout:        defaultPollTimeout,
-							PolicyDebounceTime: defaultPolicyDebounceTime,
+							FetchSize:             defaultFetchSize,
+							PollTimeout:           defaultPollTimeout,
+							PolicyDebounceTime:    defaultPolicyDebounceTime,
+							PolicyDispatchMaxWait: defaultPolicyDispatchMaxWait,
 						},
 					},
 				},
 				Logging: defaultLogging(),
 				HTTP:    defaultHTTP(),
+				Secrets: defaultSecrets(),
 			},
 		},
 		"bad-input": {
@@ -215,14 +240,16 @@ func TestConfig(t *testing.T) {
 					Server: defaultServer(),
 					Cache:  generateCache(2500),
 					Monitor: Monitor{
-						FetchSize:          defaultFetchSize,
-						PollTimeout:        defaultPollTimeout,
-						PolicyDebounceTime: defaultPolicyDebounceTime,
+						FetchSize:             defaultFetchSize,
+						PollTimeout:           defaultPollTimeout,
+						PolicyDebounceTime:    defaultPolicyDebounceTime,
+						PolicyDispatchMaxWait: defaultPolicyDispatchMaxWait,
 					},
 				},
 			},
 			Logging: defaultLogging(),
 			HTTP:    defaultHTTP(),
+			Secrets: defaultSecrets(),
 		}
 		expected.Inputs[0].Server.Limits = generateServerLimits(2500)
 		t.Log("After expect")
@@ -324,6 +351,7 @@ func generateCache(maxAgents int) Cache {
 func generateServerLimits(maxAgents int) ServerLimits {
 	var d ServerLimits
 	d.MaxAgents = maxAgents
+	d.Adaptive.InitDefaults()
 	d.LoadLimits(loadLimits(maxAgents))
 	return d
 }
@@ -340,6 +368,96 @@ func defaultServerGC() GC {
 	return d
 }
 
+func defaultServerArtifacts() Artifacts {
+	var d Artifacts
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerUploads() Uploads {
+	var d Uploads
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerPolicyRollout() PolicyRollout {
+	var d PolicyRollout
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerProbes() Probes {
+	var d Probes
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerCheckinBackoff() CheckinBackoff {
+	var d CheckinBackoff
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerProxyProtocol() ProxyProtocol {
+	var d ProxyProtocol
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerCORS() CORS {
+	var d CORS
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerClientCertificates() ClientCertificates {
+	var d ClientCertificates
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerDrain() ServerDrain {
+	var d ServerDrain
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerStandby() ServerStandby {
+	var d ServerStandby
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerCoordinator() Coordinator {
+	var d Coordinator
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerAudit() Audit {
+	var d Audit
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerFIPS() FIPS {
+	var d FIPS
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerRequestSigning() RequestSigning {
+	var d RequestSigning
+	d.InitDefaults()
+	return d
+}
+
+func defaultServerSpool() Spool {
+	var d Spool
+	d.InitDefaults()
+	return d
+}
+
 func defaultLogging() Logging {
 	var d Logging
 	d.InitDefaults()
@@ -352,6 +470,12 @@ func defaultHTTP() HTTP {
 	return d
 }
 
+func defaultSecrets() Secrets {
+	var d Secrets
+	d.Vault.InitDefaults()
+	return d
+}
+
 func defaultFleet() Fleet {
 	return Fleet{
 		Agent: Agent{
@@ -388,3 +512,38 @@ func TestConfigFromEnv(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "test-val", c.Output.Elasticsearch.ServiceToken)
 }
+
+func TestCachePartitionTTL(t *testing.T) {
+	c := Cache{
+		ArtifactTTL: time.Hour,
+		APIKeyTTL:   time.Hour,
+		APIKeys:     CachePartition{TTL: time.Minute},
+	}
+	c.LoadLimits(loadLimits(0))
+	assert.Equal(t, time.Minute, c.APIKeyTTL, "the TTL of the partition takes precedence")
+	assert.Equal(t, time.Hour, c.ArtifactTTL)
+	assert.Equal(t, defaultActionTTL, c.ActionTTL)
+}
+
+func TestRedact(t *testing.T) {
+	c := &Config{Inputs: []Input{{}}}
+	c.Output.Elasticsearch.ServiceToken = "token"
+	c.Output.Elasticsearch.Headers = map[string]string{"Authorization": "Bearer secret"}
+	c.Inputs[0].Server.Instrumentation.APIKey = "apm-key"
+	c.Inputs[0].Server.Instrumentation.OTLP.Headers = map[string]string{"Authorization": "ApiKey secret"}
+	c.Inputs[0].Server.StaticPolicyTokens.PolicyTokens = []PolicyToken{{TokenKey: "policy-token", PolicyID: "policy-1"}}
+	c.Inputs[0].Server.RequestSigning.Secret = "signing-secret"
+	c.Secrets.Vault.Token = "vault-token"
+
+	r := c.Redact()
+	assert.Equal(t, kRedacted, r.Output.Elasticsearch.ServiceToken)
+	assert.Equal(t, map[string]string{"Authorization": kRedacted}, r.Output.Elasticsearch.Headers)
+	assert.Equal(t, kRedacted, r.Inputs[0].Server.Instrumentation.APIKey)
+	assert.Equal(t, map[string]string{"Authorization": kRedacted}, r.Inputs[0].Server.Instrumentation.OTLP.Headers)
+	assert.Equal(t, []PolicyToken{{TokenKey: kRedacted, PolicyID: "policy-1"}}, r.Inputs[0].Server.StaticPolicyTokens.PolicyTokens)
+	assert.Equal(t, kRedacted, r.Inputs[0].Server.RequestSigning.Secret)
+	assert.Equal(t, kRedacted, r.Secrets.Vault.Token)
+
+	assert.Equal(t, "Bearer secret", c.Output.Elasticsearch.Headers["Authorization"], "the config is not changed")
+	assert.Equal(t, "policy-token", c.Inputs[0].Server.StaticPolicyTokens.PolicyTokens[0].TokenKey)
+}
@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultShardingVirtualNodes = 64
+	defaultLeasePrefix          = "fleet-policy-leader"
+
+	// ElectorElasticsearch elects the leaders of the policies with the documents of the .fleet-policies-leader index.
+	ElectorElasticsearch = "elasticsearch"
+	// ElectorKubernetes elects the leaders of the policies with Kubernetes Leases.
+	ElectorKubernetes = "kubernetes"
+)
+
+// Coordinator is the configuration of the leader election of the policies.
+type Coordinator struct {
+	// Backend is the leader election backend, elasticsearch or kubernetes.
+	Backend    string                `config:"backend"`
+	Kubernetes CoordinatorKubernetes `config:"kubernetes"`
+	Sharding   CoordinatorSharding   `config:"sharding"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Coordinator) InitDefaults() {
+	c.Backend = ElectorElasticsearch
+	c.Kubernetes.InitDefaults()
+	c.Sharding.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Coordinator) Validate() error {
+	switch c.Backend {
+	case ElectorElasticsearch, ElectorKubernetes:
+		return nil
+	default:
+		return fmt.Errorf("coordinator backend must be %s or %s, got %q", ElectorElasticsearch, ElectorKubernetes, c.Backend)
+	}
+}
+
+// CoordinatorKubernetes is the configuration of the Kubernetes Lease backend. The server uses the service account of
+// its pod, which must be allowed to get, list, create and update the leases of the namespace.
+type CoordinatorKubernetes struct {
+	// Namespace is the namespace of the leases, the namespace of the pod when empty.
+	Namespace string `config:"namespace"`
+	// LeasePrefix is the prefix of the names of the leases of the policies.
+	LeasePrefix string `config:"lease_prefix"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *CoordinatorKubernetes) InitDefaults() {
+	c.LeasePrefix = defaultLeasePrefix
+}
+
+// Validate ensures that the configuration is valid.
+func (c *CoordinatorKubernetes) Validate() error {
+	if c.LeasePrefix == "" {
+		return errors.New("coordinator kubernetes lease_prefix is required")
+	}
+	return nil
+}
+
+// CoordinatorSharding is the configuration of the sharding of the policies. When enabled the policies are
+// partitioned across the healthy servers with a consistent hash of their ID, a server only takes the leadership of
+// the policies it owns and releases the others, so the policies move as servers join or leave.
+type CoordinatorSharding struct {
+	Enabled bool `config:"enabled"`
+	// VirtualNodes is the number of points of each server on the hash ring, more points spread the policies more evenly.
+	VirtualNodes int `config:"virtual_nodes"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *CoordinatorSharding) InitDefaults() {
+	c.VirtualNodes = defaultShardingVirtualNodes
+}
+
+// Validate ensures that the configuration is valid.
+func (c *CoordinatorSharding) Validate() error {
+	if c.VirtualNodes < 1 {
+		return fmt.Errorf("coordinator sharding virtual_nodes must be at least 1, got %d", c.VirtualNodes)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// CORSAnyOrigin is the allowed origin that allows all the origins.
+	CORSAnyOrigin = "*"
+
+	defaultCORSMaxAge = 10 * time.Minute
+)
+
+// CORS is the configuration of the cross-origin requests of the browsers, so the dashboards served from another
+// origin can call the management routes of the server without a reverse proxy adding the headers.
+type CORS struct {
+	Enabled bool `config:"enabled"`
+	// AllowedOrigins are the origins allowed to send requests, like https://dashboard.example.com; * allows all.
+	AllowedOrigins []string `config:"allowed_origins"`
+	// AllowedMethods are the methods the origins may use.
+	AllowedMethods []string `config:"allowed_methods"`
+	// AllowedHeaders are the request headers the origins may send, like Authorization.
+	AllowedHeaders []string `config:"allowed_headers"`
+	// AllowCredentials allows the browsers to send the cookies and the client certificates of the origins.
+	AllowCredentials bool `config:"allow_credentials"`
+	// MaxAge is how long the browsers cache the answer to a preflight request.
+	MaxAge time.Duration `config:"max_age"`
+	// Routes are the operations the cross-origin requests are allowed for, like status or diagnostics.
+	Routes []string `config:"routes"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *CORS) InitDefaults() {
+	c.AllowedMethods = []string{http.MethodGet, http.MethodHead}
+	c.AllowedHeaders = []string{"Authorization", "Content-Type", "Elastic-Api-Version"}
+	c.MaxAge = defaultCORSMaxAge
+	c.Routes = []string{"status"}
+}
+
+// Validate ensures that the configuration is valid.
+func (c *CORS) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("cors requires allowed origins")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == CORSAnyOrigin {
+			if c.AllowCredentials {
+				return errors.New("cors allowed origin * can not be used with allow_credentials")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("cors allowed origin %q is not a scheme and a host like https://dashboard.example.com", origin)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("cors max age must not be negative, got %v", c.MaxAge)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{name: "disabled", cfg: "{}"},
+		{name: "origins", cfg: "enabled: true\nallowed_origins: [\"https://dashboard.example.com\", \"http://localhost:3000/\"]"},
+		{name: "any origin", cfg: "enabled: true\nallowed_origins: [\"*\"]"},
+		{name: "no origin", cfg: "enabled: true", err: "cors requires allowed origins"},
+		{name: "origin without scheme", cfg: "enabled: true\nallowed_origins: [dashboard.example.com]", err: "is not a scheme and a host"},
+		{name: "origin with a path", cfg: "enabled: true\nallowed_origins: [\"https://example.com/dashboard\"]", err: "is not a scheme and a host"},
+		{name: "any origin with credentials", cfg: "enabled: true\nallowed_origins: [\"*\"]\nallow_credentials: true", err: "can not be used with allow_credentials"},
+		{name: "negative max age", cfg: "enabled: true\nallowed_origins: [\"*\"]\nmax_age: -1s", err: "must not be negative"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var cors CORS
+			err = c.Unpack(&cors, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCORSListener(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("port: 8222\ncors:\n  enabled: true\n  allowed_origins: [\"https://dashboard.example.com\"]\n  routes: [status, diagnostics]"), DefaultOptions...)
+	require.NoError(t, err)
+	var l Listener
+	require.NoError(t, c.Unpack(&l, DefaultOptions...))
+
+	srv := Server{}
+	srv.InitDefaults()
+	srv.Listeners = []Listener{l}
+	servers := srv.ListenerServers()
+	require.Len(t, servers, 1)
+	assert.False(t, srv.CORS.Enabled)
+	assert.True(t, servers[0].CORS.Enabled)
+	assert.Equal(t, []string{"status", "diagnostics"}, servers[0].CORS.Routes)
+	assert.Equal(t, 10*time.Minute, servers[0].CORS.MaxAge)
+}
@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultDrainPeriod     = 30 * time.Second
+	defaultDrainRetryAfter = 5 * time.Second
+)
+
+// ServerDrain is the configuration of the drain mode, entered on a signal or through the drain endpoint. A draining
+// server ends the long polls of the agents and answers their checkins at once with a backoff hint, reports itself
+// as not ready, and stops at the end of the drain period.
+type ServerDrain struct {
+	// OnSignal drains the server on the first SIGINT or SIGTERM instead of stopping it, the next signal stops it.
+	OnSignal bool `config:"on_signal"`
+	// Period is how long the server drains before it stops.
+	Period time.Duration `config:"period"`
+	// RetryAfter is the delay the agents are asked to wait before they check in with another server.
+	RetryAfter time.Duration `config:"retry_after"`
+	// Window spreads the end of the long polls of the agents over this duration from the start of the drain, so they
+	// do not all reconnect to the other servers at once. 0 ends them at once, it must not exceed the period.
+	Window time.Duration `config:"window"`
+	// Hosts are the fleet-server hosts the agents are steered to, listed in the draining backoff hint.
+	Hosts []string `config:"hosts"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ServerDrain) InitDefaults() {
+	c.Period = defaultDrainPeriod
+	c.RetryAfter = defaultDrainRetryAfter
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ServerDrain) Validate() error {
+	if c.Period < 0 || c.RetryAfter < 0 {
+		return fmt.Errorf("drain durations must not be negative, got period %v and retry after %v", c.Period, c.RetryAfter)
+	}
+	if c.Window < 0 || c.Window > c.Period {
+		return fmt.Errorf("drain window must be between 0 and the period %v, got %v", c.Period, c.Window)
+	}
+	for _, host := range c.Hosts {
+		u, err := url.Parse(host)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("drain hosts must be absolute http or https URLs, got %q", host)
+		}
+	}
+	return nil
+}
@@ -72,6 +72,11 @@ const (
 	defaultUploadChunkMax      = 10
 	defaultUploadChunkMaxBody  = 1024 * 1024 * 4 // this is also enforced in handler, a chunk MAY NOT be larger than 4 MiB
 
+	defaultUploadStatusInterval = time.Millisecond * 100
+	defaultUploadStatusBurst    = 5
+	defaultUploadStatusMax      = 10
+	defaultUploadStatusMaxBody  = 0
+
 	defaultFileDelivInterval = time.Millisecond * 100
 	defaultFileDelivBurst    = 5
 	defaultFileDelivMax      = 10
@@ -81,6 +86,51 @@ const (
 	defaultPGPRetrievalBurst    = 25
 	defaultPGPRetrievalMax      = 50
 	defaultPGPRetrievalMaxBody  = 0
+
+	defaultPolicyValidateInterval = time.Millisecond * 100
+	defaultPolicyValidateBurst    = 5
+	defaultPolicyValidateMax      = 10
+	defaultPolicyValidateMaxBody  = 1024 * 1024 * 2
+
+	defaultRevokeInterval = time.Millisecond * 100
+	defaultRevokeBurst    = 10
+	defaultRevokeMax      = 10
+	defaultRevokeMaxBody  = 0
+
+	defaultProfilerInterval = time.Second
+	defaultProfilerBurst    = 5
+	defaultProfilerMax      = 5
+	defaultProfilerMaxBody  = 1024
+
+	defaultDiagnosticsInterval = time.Second * 10
+	defaultDiagnosticsBurst    = 1
+	defaultDiagnosticsMax      = 1
+	defaultDiagnosticsMaxBody  = 0
+
+	defaultDrainInterval = time.Second
+	defaultDrainBurst    = 1
+	defaultDrainMax      = 1
+	defaultDrainMaxBody  = 0
+
+	defaultAgentSearchInterval = time.Millisecond * 100
+	defaultAgentSearchBurst    = 5
+	defaultAgentSearchMax      = 5
+	defaultAgentSearchMaxBody  = 1024 * 64
+
+	defaultAgentReassignInterval = time.Second
+	defaultAgentReassignBurst    = 2
+	defaultAgentReassignMax      = 2
+	defaultAgentReassignMaxBody  = 1024 * 1024
+
+	defaultPolicyLeadersInterval = time.Second
+	defaultPolicyLeadersBurst    = 5
+	defaultPolicyLeadersMax      = 5
+	defaultPolicyLeadersMaxBody  = 0
+
+	defaultPromoteInterval = time.Second
+	defaultPromoteBurst    = 1
+	defaultPromoteMax      = 1
+	defaultPromoteMaxBody  = 0
 )
 
 type valueRange struct {
@@ -139,18 +189,28 @@ type serverLimitDefaults struct {
 	PolicyThrottle time.Duration `config:"policy_throttle"` // deprecated: replaced by policy_limit
 	MaxConnections int           `config:"max_connections"`
 
-	ActionLimit      limit `config:"action_limit"`
-	PolicyLimit      limit `config:"policy_limit"`
-	CheckinLimit     limit `config:"checkin_limit"`
-	ArtifactLimit    limit `config:"artifact_limit"`
-	EnrollLimit      limit `config:"enroll_limit"`
-	AckLimit         limit `config:"ack_limit"`
-	StatusLimit      limit `config:"status_limit"`
-	UploadStartLimit limit `config:"upload_start_limit"`
-	UploadEndLimit   limit `config:"upload_end_limit"`
-	UploadChunkLimit limit `config:"upload_chunk_limit"`
-	DeliverFileLimit limit `config:"file_delivery_limit"`
-	GetPGPKeyLimit   limit `config:"pgp_retrieval_limit"`
+	ActionLimit         limit `config:"action_limit"`
+	PolicyLimit         limit `config:"policy_limit"`
+	CheckinLimit        limit `config:"checkin_limit"`
+	ArtifactLimit       limit `config:"artifact_limit"`
+	EnrollLimit         limit `config:"enroll_limit"`
+	AckLimit            limit `config:"ack_limit"`
+	StatusLimit         limit `config:"status_limit"`
+	UploadStartLimit    limit `config:"upload_start_limit"`
+	UploadEndLimit      limit `config:"upload_end_limit"`
+	UploadChunkLimit    limit `config:"upload_chunk_limit"`
+	UploadStatusLimit   limit `config:"upload_status_limit"`
+	DeliverFileLimit    limit `config:"file_delivery_limit"`
+	GetPGPKeyLimit      limit `config:"pgp_retrieval_limit"`
+	PolicyValidateLimit limit `config:"policy_validate_limit"`
+	RevokeLimit         limit `config:"revoke_limit"`
+	ProfilerLimit       limit `config:"profiler_limit"`
+	DiagnosticsLimit    limit `config:"diagnostics_limit"`
+	DrainLimit          limit `config:"drain_limit"`
+	AgentSearchLimit    limit `config:"agent_search_limit"`
+	AgentReassignLimit  limit `config:"agent_reassign_limit"`
+	PolicyLeadersLimit  limit `config:"policy_leaders_limit"`
+	PromoteLimit        limit `config:"promote_limit"`
 }
 
 func defaultserverLimitDefaults() *serverLimitDefaults {
@@ -212,6 +272,12 @@ func defaultserverLimitDefaults() *serverLimitDefaults {
 			Max:      defaultUploadChunkMax,
 			MaxBody:  defaultUploadChunkMaxBody,
 		},
+		UploadStatusLimit: limit{
+			Interval: defaultUploadStatusInterval,
+			Burst:    defaultUploadStatusBurst,
+			Max:      defaultUploadStatusMax,
+			MaxBody:  defaultUploadStatusMaxBody,
+		},
 		DeliverFileLimit: limit{
 			Interval: defaultFileDelivInterval,
 			Burst:    defaultFileDelivBurst,
@@ -224,6 +290,60 @@ func defaultserverLimitDefaults() *serverLimitDefaults {
 			Max:      defaultPGPRetrievalMax,
 			MaxBody:  defaultPGPRetrievalMaxBody,
 		},
+		PolicyValidateLimit: limit{
+			Interval: defaultPolicyValidateInterval,
+			Burst:    defaultPolicyValidateBurst,
+			Max:      defaultPolicyValidateMax,
+			MaxBody:  defaultPolicyValidateMaxBody,
+		},
+		RevokeLimit: limit{
+			Interval: defaultRevokeInterval,
+			Burst:    defaultRevokeBurst,
+			Max:      defaultRevokeMax,
+			MaxBody:  defaultRevokeMaxBody,
+		},
+		ProfilerLimit: limit{
+			Interval: defaultProfilerInterval,
+			Burst:    defaultProfilerBurst,
+			Max:      defaultProfilerMax,
+			MaxBody:  defaultProfilerMaxBody,
+		},
+		DiagnosticsLimit: limit{
+			Interval: defaultDiagnosticsInterval,
+			Burst:    defaultDiagnosticsBurst,
+			Max:      defaultDiagnosticsMax,
+			MaxBody:  defaultDiagnosticsMaxBody,
+		},
+		DrainLimit: limit{
+			Interval: defaultDrainInterval,
+			Burst:    defaultDrainBurst,
+			Max:      defaultDrainMax,
+			MaxBody:  defaultDrainMaxBody,
+		},
+		AgentSearchLimit: limit{
+			Interval: defaultAgentSearchInterval,
+			Burst:    defaultAgentSearchBurst,
+			Max:      defaultAgentSearchMax,
+			MaxBody:  defaultAgentSearchMaxBody,
+		},
+		AgentReassignLimit: limit{
+			Interval: defaultAgentReassignInterval,
+			Burst:    defaultAgentReassignBurst,
+			Max:      defaultAgentReassignMax,
+			MaxBody:  defaultAgentReassignMaxBody,
+		},
+		PolicyLeadersLimit: limit{
+			Interval: defaultPolicyLeadersInterval,
+			Burst:    defaultPolicyLeadersBurst,
+			Max:      defaultPolicyLeadersMax,
+			MaxBody:  defaultPolicyLeadersMaxBody,
+		},
+		PromoteLimit: limit{
+			Interval: defaultPromoteInterval,
+			Burst:    defaultPromoteBurst,
+			Max:      defaultPromoteMax,
+			MaxBody:  defaultPromoteMaxBody,
+		},
 	}
 }
 
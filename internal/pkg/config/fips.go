@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/go-ucfg"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+// FIPS is the configuration of the FIPS mode. In the FIPS mode the TLS connections of the server and of its clients
+// are restricted to TLS 1.2 and to the approved cipher suites and curves, and the server does not start with
+// settings that are not approved. It is always enabled in the builds that require it.
+type FIPS struct {
+	Enabled bool `config:"enabled"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *FIPS) InitDefaults() {
+	c.Enabled = fips.Required
+}
+
+// Validate ensures that the configuration is valid.
+func (c *FIPS) Validate() error {
+	if fips.Required && !c.Enabled {
+		return errors.New("fips can not be disabled, fleet-server is built to require the FIPS mode")
+	}
+	return nil
+}
+
+// fipsEnabled returns the FIPS mode of the settings, they are read before their secrets are resolved.
+func fipsEnabled(settings map[string]interface{}) (bool, error) {
+	c, err := ucfg.NewFrom(settings, DefaultOptions...)
+	if err != nil {
+		return false, err
+	}
+	var cfg struct {
+		Inputs []struct {
+			Server struct {
+				FIPS FIPS `config:"fips"`
+			} `config:"server"`
+		} `config:"inputs"`
+	}
+	if err := c.Unpack(&cfg, DefaultOptions...); err != nil {
+		return false, err
+	}
+	if len(cfg.Inputs) == 0 {
+		return fips.Required, nil
+	}
+	return cfg.Inputs[0].Server.FIPS.Enabled, nil
+}
+
+// validateFIPS returns the TLS settings of c that are not approved in the FIPS mode.
+func (c *Config) validateFIPS() error {
+	srv := &c.Inputs[0].Server
+	errs := []error{
+		checkFIPSServerTLS("inputs.0.server.ssl", srv.TLS),
+		checkFIPSTLS("output.elasticsearch.ssl", c.Output.Elasticsearch.TLS),
+		checkFIPSTLS("inputs.0.server.artifacts.upstream.ssl", srv.Artifacts.Upstream.TLS),
+		checkFIPSTLS("inputs.0.server.uploads.storage.s3.ssl", srv.Uploads.Storage.S3.TLS),
+		checkFIPSTLS("secrets.vault.ssl", c.Secrets.Vault.TLS),
+	}
+	for i, l := range srv.Listeners {
+		errs = append(errs, checkFIPSServerTLS(fmt.Sprintf("inputs.0.server.listeners.%d.ssl", i), l.TLS))
+	}
+	return errors.Join(errs...)
+}
+
+func checkFIPSServerTLS(setting string, cfg *tlscommon.ServerConfig) error {
+	if cfg == nil || !cfg.IsEnabled() {
+		return nil
+	}
+	curves := make([]tls.CurveID, 0, len(cfg.CurveTypes))
+	for _, ct := range cfg.CurveTypes {
+		curves = append(curves, tls.CurveID(ct))
+	}
+	return checkFIPSSettings(setting, cfg.Versions, cfg.CipherSuites, curves, cfg.Certificate.Key)
+}
+
+func checkFIPSTLS(setting string, cfg *tlscommon.Config) error {
+	if cfg == nil || !cfg.IsEnabled() {
+		return nil
+	}
+	curves := make([]tls.CurveID, 0, len(cfg.CurveTypes))
+	for _, ct := range cfg.CurveTypes {
+		curves = append(curves, tls.CurveID(ct))
+	}
+	return checkFIPSSettings(setting, cfg.Versions, cfg.CipherSuites, curves, cfg.Certificate.Key)
+}
+
+func checkFIPSSettings(setting string, versions []tlscommon.TLSVersion, suites []tlscommon.CipherSuite, curves []tls.CurveID, key string) error {
+	var errs []error
+	for _, v := range versions {
+		errs = append(errs, fips.CheckVersion(uint16(v)))
+	}
+	for _, s := range suites {
+		errs = append(errs, fips.CheckCipherSuite(uint16(s)))
+	}
+	for _, c := range curves {
+		errs = append(errs, fips.CheckCurve(c))
+	}
+	errs = append(errs, fips.CheckKey(key))
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", setting, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !requirefips
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+func TestFIPS(t *testing.T) {
+	load := func(t *testing.T, s string) error {
+		t.Helper()
+		c, err := yaml.NewConfig([]byte(s), DefaultOptions...)
+		require.NoError(t, err)
+		_, err = FromConfig(c)
+		return err
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.NoError(t, load(t, `
+output.elasticsearch.ssl.cipher_suites: [ECDHE-RSA-CHACHA20-POLY1205]
+inputs:
+  - type: fleet-server
+`))
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		assert.NoError(t, load(t, `
+output.elasticsearch.ssl:
+  supported_protocols: [TLSv1.2]
+  cipher_suites: [ECDHE-RSA-AES-256-GCM-SHA384]
+  curve_types: [P-384]
+inputs:
+  - type: fleet-server
+    server.fips.enabled: true
+`))
+	})
+
+	t.Run("not approved", func(t *testing.T) {
+		err := load(t, `
+output.elasticsearch.ssl.cipher_suites: [ECDHE-RSA-CHACHA20-POLY1205]
+secrets.vault.ssl:
+  curve_types: [X25519]
+  supported_protocols: [TLSv1.1, TLSv1.2]
+inputs:
+  - type: fleet-server
+    server.fips.enabled: true
+`)
+		require.ErrorContains(t, err, fips.ErrNotApproved.Error())
+		assert.ErrorContains(t, err, "output.elasticsearch.ssl")
+		assert.ErrorContains(t, err, "secrets.vault.ssl")
+		assert.ErrorContains(t, err, "X25519")
+		assert.ErrorContains(t, err, "TLS 1.1")
+	})
+}
@@ -9,16 +9,93 @@ import "time"
 const (
 	defaultScheduleInterval            = time.Hour
 	defaultCleanupIntervalAfterExpired = "30d" // cleanup expired actions with expiration time older than 30 days from now
+	defaultUploadsStaleAfter           = 48 * time.Hour
+	defaultActionResultsRetention      = 30 * 24 * time.Hour
+	defaultFilesRetention              = 30 * 24 * time.Hour
+	defaultOutputHealthRetention       = 7 * 24 * time.Hour
+	defaultTombstonesRetention         = 30 * 24 * time.Hour
+	defaultInactiveAgentsBatchSize     = 100
 )
 
 // GC is the configuration for the Fleet Server data garbage collection.
-// Currently manages the expired actions cleanup
+// Manages the expired actions, stale file uploads, old policy revisions, inactive agents and agent tombstones cleanup
 type GC struct {
-	ScheduleInterval            time.Duration `config:"schedule_interval"`
-	CleanupAfterExpiredInterval string        `config:"cleanup_after_expired_interval"`
+	ScheduleInterval            time.Duration    `config:"schedule_interval"`
+	CleanupAfterExpiredInterval string           `config:"cleanup_after_expired_interval"`
+	Uploads                     UploadsGC        `config:"uploads"`
+	Policies                    PoliciesGC       `config:"policies"`
+	Lifecycle                   LifecycleGC      `config:"lifecycle"`
+	Tombstones                  TombstonesGC     `config:"tombstones"`
+	InactiveAgents              InactiveAgentsGC `config:"inactive_agents"`
 }
 
 func (g *GC) InitDefaults() {
 	g.ScheduleInterval = defaultScheduleInterval
 	g.CleanupAfterExpiredInterval = defaultCleanupIntervalAfterExpired
+	g.Uploads.InitDefaults()
+	g.Lifecycle.InitDefaults()
+	g.Tombstones.InitDefaults()
+	g.InactiveAgents.InitDefaults()
+}
+
+// UploadsGC is the configuration for the cleanup of agent file uploads.
+type UploadsGC struct {
+	// StaleAfter is the age after which unfinished, failed and deleted uploads are removed, 0 disables the cleanup.
+	StaleAfter time.Duration `config:"stale_after"`
+	// Retention is the age after which completed uploads are removed, 0 keeps them.
+	Retention time.Duration `config:"retention"`
+}
+
+func (u *UploadsGC) InitDefaults() {
+	u.StaleAfter = defaultUploadsStaleAfter
+}
+
+// PoliciesGC is the configuration for the cleanup of old policy revisions.
+type PoliciesGC struct {
+	// KeepRevisions is the number of revisions kept for each policy, 0 disables the cleanup.
+	// The latest revision coordinated by a fleet-server is always kept.
+	KeepRevisions int `config:"keep_revisions" validate:"min=0"`
+}
+
+// LifecycleGC is the configuration of the retention of the data streams fleet-server writes to, for the clusters
+// where their lifecycle is not set up by Kibana. The retention is set as the data stream lifecycle, it applies to
+// the backing indices that are not managed by an ILM policy.
+type LifecycleGC struct {
+	Enabled bool `config:"enabled"`
+	// ActionResultsRetention is the retention of the action results, 0 keeps the lifecycle of the data stream.
+	ActionResultsRetention time.Duration `config:"action_results_retention"`
+	// FilesRetention is the retention of the files uploaded by the agents and delivered to them.
+	FilesRetention time.Duration `config:"files_retention"`
+	// OutputHealthRetention is the retention of the output health reports.
+	OutputHealthRetention time.Duration `config:"output_health_retention"`
+}
+
+func (l *LifecycleGC) InitDefaults() {
+	l.ActionResultsRetention = defaultActionResultsRetention
+	l.FilesRetention = defaultFilesRetention
+	l.OutputHealthRetention = defaultOutputHealthRetention
+}
+
+// TombstonesGC is the configuration for the cleanup of the tombstones of the agents.
+// The document of an agent that is unenrolled, revoked or replaced is kept as a tombstone: its API keys are invalidated
+// and its enrollment history is kept until the retention.
+type TombstonesGC struct {
+	// Retention is the age after which the tombstones are deleted, 0 keeps them.
+	Retention time.Duration `config:"retention"`
+}
+
+func (t *TombstonesGC) InitDefaults() {
+	t.Retention = defaultTombstonesRetention
+}
+
+// InactiveAgentsGC is the configuration for the unenrollment of the agents that have not checked in for longer than the
+// unenroll timeout of their policy. The agents are unenrolled by Kibana when it is not enabled.
+type InactiveAgentsGC struct {
+	Enabled bool `config:"enabled"`
+	// BatchSize is the number of agents unenrolled together, their API keys are invalidated with a call per output.
+	BatchSize int `config:"batch_size" validate:"min=1"`
+}
+
+func (i *InactiveAgentsGC) InitDefaults() {
+	i.BatchSize = defaultInactiveAgentsBatchSize
 }
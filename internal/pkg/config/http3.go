@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import "errors"
+
+// HTTP3Prefix is the prefix of the endpoints of the HTTP/3 listeners.
+const HTTP3Prefix = "quic:"
+
+// defaultHTTP3Routes are the agent endpoints with long polls and large downloads that suffer the most from the
+// head-of-line blocking of TCP on lossy links.
+var defaultHTTP3Routes = []string{"checkin", "artifact", "deliverFile"}
+
+// HTTP3 is the configuration of the HTTP/3 (QUIC) listener of the agent endpoints. The listener has the host and
+// TLS configuration of the server, the TCP responses of its routes advertise it with an Alt-Svc header.
+type HTTP3 struct {
+	Enabled bool `config:"enabled"`
+	// Port is the UDP port of the listener, the port of the server when 0.
+	Port uint16 `config:"port"`
+	// Routes are the operations served over HTTP/3, checkin, artifact and deliverFile when empty.
+	Routes []string `config:"routes"`
+}
+
+// HTTP3Server returns the configuration of the HTTP/3 listener, false when it is not enabled.
+func (c *Server) HTTP3Server() (Server, bool) {
+	if !c.HTTP3.Enabled {
+		return Server{}, false
+	}
+	s := *c
+	s.Listeners = nil
+	s.Socket = UnixSocket{}
+	s.Port = c.HTTP3Port()
+	s.Routes = c.HTTP3.routes()
+	return s, true
+}
+
+// HTTP3Endpoint returns the endpoint of the HTTP/3 listener of the configuration returned by HTTP3Server.
+func (c *Server) HTTP3Endpoint() string {
+	return HTTP3Prefix + c.BindAddress()
+}
+
+// HTTP3Port returns the UDP port of the HTTP/3 listener.
+func (c *Server) HTTP3Port() uint16 {
+	if c.HTTP3.Port != 0 {
+		return c.HTTP3.Port
+	}
+	return c.Port
+}
+
+// ServesHTTP3 returns true when the route of operation op is served over HTTP/3.
+func (c *Server) ServesHTTP3(op string) bool {
+	if !c.HTTP3.Enabled || op == "" {
+		return false
+	}
+	for _, route := range c.HTTP3.routes() {
+		if route == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *HTTP3) routes() []string {
+	if len(c.Routes) == 0 {
+		return defaultHTTP3Routes
+	}
+	return c.Routes
+}
+
+// validateHTTP3 ensures the HTTP/3 listener has TLS, QUIC is always encrypted.
+func (c *Server) validateHTTP3() error {
+	if c.HTTP3.Enabled && (c.TLS == nil || !c.TLS.IsEnabled()) {
+		return errors.New("http3 requires ssl to be enabled")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP3Server(t *testing.T) {
+	c, err := yaml.NewConfig([]byte(`
+host: 0.0.0.0
+port: 8220
+ssl.enabled: true
+ssl.certificate: /creds/cert.pem
+ssl.key: /creds/key.pem
+http3.enabled: true
+http3.port: 8443
+listeners:
+  - port: 8222
+`), DefaultOptions...)
+	require.NoError(t, err)
+	var s Server
+	s.InitDefaults()
+	require.NoError(t, c.Unpack(&s, DefaultOptions...))
+
+	h3, ok := s.HTTP3Server()
+	require.True(t, ok)
+	assert.Equal(t, "quic:0.0.0.0:8443", h3.HTTP3Endpoint())
+	assert.Equal(t, []string{"checkin", "artifact", "deliverFile"}, h3.Routes)
+	assert.Empty(t, h3.Listeners)
+	assert.Equal(t, uint16(8443), s.HTTP3Port())
+
+	assert.True(t, s.ServesHTTP3("checkin"))
+	assert.False(t, s.ServesHTTP3("enroll"))
+	assert.False(t, s.ServesHTTP3(""))
+	assert.False(t, s.ListenerServers()[0].HTTP3.Enabled, "the listeners do not advertise the HTTP/3 listener")
+
+	var disabled Server
+	disabled.InitDefaults()
+	_, ok = disabled.HTTP3Server()
+	assert.False(t, ok)
+
+	c, err = yaml.NewConfig([]byte("http3.enabled: true\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var invalid Server
+	assert.ErrorContains(t, c.Unpack(&invalid, DefaultOptions...), "http3 requires ssl to be enabled")
+}
@@ -19,17 +19,33 @@ const (
 	kDefaultInternalHost = "localhost"
 	kDefaultInternalPort = 8221
 	fleetInputType       = "fleet-server"
+
+	kDefaultTLSReloadInterval = time.Minute
 )
 
 // Policy is the configuration policy to use.
 type Policy struct {
 	ID string `config:"id"`
+	// Files configures a stand-alone fleet-server to serve policies from local files instead of the policies index.
+	Files PolicyFiles `config:"files"`
+}
+
+// PolicyFiles is the configuration of the local policy files.
+type PolicyFiles struct {
+	// Path is a policy file or a directory of policy files, serving policies from files is disabled if it is empty.
+	Path string `config:"path"`
+	// WatchInterval is how often the files are checked for changes, 10s is used if it is not set.
+	WatchInterval time.Duration `config:"watch_interval"`
 }
 
 // ServerProfiler is the configuration for profiling the server.
 type ServerProfiler struct {
 	Enabled bool   `config:"enabled"`
 	Bind    string `config:"bind"`
+	// BlockRate is the block profile rate in nanoseconds, 0 turns the block profile off.
+	BlockRate int `config:"block_rate"`
+	// MutexFraction reports on average 1/n of the mutex contention events, 0 turns the mutex profile off.
+	MutexFraction int `config:"mutex_fraction"`
 }
 
 // InitDefaults initializes the defaults for the configuration.
@@ -49,6 +65,9 @@ type ServerBulk struct {
 	FlushThresholdCount int           `config:"flush_threshold_cnt"`
 	FlushThresholdSize  int           `config:"flush_threshold_size"`
 	FlushMaxPending     int           `config:"flush_max_pending"`
+	// SearchPreference is the preference of the searches, the shard copies that run them. Elasticsearch picks the
+	// copies with adaptive replica selection when it is empty.
+	SearchPreference string `config:"search_preference"`
 }
 
 func (c *ServerBulk) InitDefaults() {
@@ -58,17 +77,43 @@ func (c *ServerBulk) InitDefaults() {
 	c.FlushMaxPending = 8
 }
 
+// searchPreferencePrefixes are the prefixes of the preferences of Elasticsearch that are followed by nodes or shards.
+var searchPreferencePrefixes = []string{"_prefer_nodes:", "_only_nodes:", "_shards:"}
+
+// Validate ensures that the configuration is valid.
+func (c *ServerBulk) Validate() error {
+	p := c.SearchPreference
+	if !strings.HasPrefix(p, "_") || p == "_local" || p == "_only_local" {
+		return nil
+	}
+	for _, prefix := range searchPreferencePrefixes {
+		if strings.HasPrefix(p, prefix) && len(p) > len(prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown search preference %q, a custom preference must not start with _", p)
+}
+
 // Server is the configuration for the server
 type (
 	Server struct {
 		Host               string                  `config:"host"`
 		Port               uint16                  `config:"port"`
 		InternalPort       uint16                  `config:"internal_port"`
+		Socket             UnixSocket              `config:"socket"` // a Unix socket the api listens on in addition to the ports
+		HTTP3              HTTP3                   `config:"http3"`
+		ProxyProtocol      ProxyProtocol           `config:"proxy_protocol"`
+		ReusePort          ReusePort               `config:"reuse_port"`
+		IPFilter           IPFilter                `config:"ip_filter"`
+		CORS               CORS                    `config:"cors"`
+		ClientCertificates ClientCertificates      `config:"client_certificates"`
 		TLS                *tlscommon.ServerConfig `config:"ssl"`
+		TLSReloadInterval  time.Duration           `config:"ssl_reload_interval"` // how often the certificate files are checked for changes, 0 disables it
 		Timeouts           ServerTimeouts          `config:"timeouts"`
 		Profiler           ServerProfiler          `config:"profiler"`
 		CompressionLevel   int                     `config:"compression_level"`
 		CompressionThresh  int                     `config:"compression_threshold"`
+		PolicyMaxSize      int                     `config:"policy_max_size"`
 		Limits             ServerLimits            `config:"limits"`
 		Runtime            Runtime                 `config:"runtime"`
 		Bulk               ServerBulk              `config:"bulk"`
@@ -76,6 +121,26 @@ type (
 		Instrumentation    Instrumentation         `config:"instrumentation"`
 		StaticPolicyTokens StaticPolicyTokens      `config:"static_policy_tokens"`
 		PGP                PGP                     `config:"pgp"`
+		Artifacts          Artifacts               `config:"artifacts"`
+		Uploads            Uploads                 `config:"uploads"`
+		PolicyRollout      PolicyRollout           `config:"policy_rollout"`
+		APIKeyInvalidation APIKeyInvalidation      `config:"api_key_invalidation"`
+		APIKeyRotation     APIKeyRotation          `config:"api_key_rotation"`
+		ServiceTokenAuth   ServiceTokenAuth        `config:"service_token_auth"`
+		Probes             Probes                  `config:"probes"`
+		SlowRequests       SlowRequests            `config:"slow_requests"`
+		CheckinBackoff     CheckinBackoff          `config:"checkin_backoff"`
+		Drain              ServerDrain             `config:"drain"`
+		Standby            ServerStandby           `config:"standby"`
+		Coordinator        Coordinator             `config:"coordinator"`
+		Audit              Audit                   `config:"audit"`
+		FIPS               FIPS                    `config:"fips"`
+		RequestSigning     RequestSigning          `config:"request_signing"`
+		Spool              Spool                   `config:"spool"`
+		Tenancy            Tenancy                 `config:"tenancy"`
+		AgentVersions      AgentVersions           `config:"agent_versions"`
+		Routes             []string                `config:"routes"` // the operations served, like checkin or status, all when empty
+		Listeners          []Listener              `config:"listeners"`
 	}
 
 	StaticPolicyTokens struct {
@@ -97,6 +162,7 @@ func (c *Server) InitDefaults() {
 	c.Host = kDefaultHost
 	c.Port = kDefaultPort
 	c.InternalPort = kDefaultInternalPort
+	c.TLSReloadInterval = kDefaultTLSReloadInterval
 	c.Timeouts.InitDefaults()
 	c.CompressionLevel = flate.BestSpeed
 	c.CompressionThresh = 1024
@@ -106,17 +172,46 @@ func (c *Server) InitDefaults() {
 	c.Bulk.InitDefaults()
 	c.GC.InitDefaults()
 	c.PGP.InitDefaults()
+	c.Artifacts.InitDefaults()
+	c.Uploads.InitDefaults()
+	c.PolicyRollout.InitDefaults()
+	c.Probes.InitDefaults()
+	c.CheckinBackoff.InitDefaults()
+	c.ProxyProtocol.InitDefaults()
+	c.CORS.InitDefaults()
+	c.ClientCertificates.InitDefaults()
+	c.Drain.InitDefaults()
+	c.Standby.InitDefaults()
+	c.Coordinator.InitDefaults()
+	c.Audit.InitDefaults()
+	c.FIPS.InitDefaults()
+	c.RequestSigning.InitDefaults()
+	c.Spool.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Server) Validate() error {
+	if err := c.validateHTTP3(); err != nil {
+		return err
+	}
+	if err := c.validateRequestSigning(); err != nil {
+		return err
+	}
+	return c.validateClientCertificates()
 }
 
 // BindEndpoints returns the binding address for the all HTTP server listeners.
 func (c *Server) BindEndpoints() []string {
 	primaryAddress := c.BindAddress()
-	endpoints := make([]string, 0, 2)
+	endpoints := make([]string, 0, 3)
 	endpoints = append(endpoints, primaryAddress)
 
 	if internalAddress := c.BindInternalAddress(); internalAddress != "" && internalAddress != ":0" && internalAddress != primaryAddress {
 		endpoints = append(endpoints, internalAddress)
 	}
+	if socket := c.Socket.Endpoint(); socket != "" {
+		endpoints = append(endpoints, socket)
+	}
 
 	return endpoints
 }
@@ -49,3 +49,14 @@ func TestBindAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestServerBulkValidate(t *testing.T) {
+	for _, p := range []string{"", "_local", "_only_local", "_shards:0,1", "_prefer_nodes:node-1", "agent-1"} {
+		c := ServerBulk{SearchPreference: p}
+		assert.NoError(t, c.Validate(), p)
+	}
+	for _, p := range []string{"_primary", "_shards:", "_only_nodes:"} {
+		c := ServerBulk{SearchPreference: p}
+		assert.Error(t, c.Validate(), p)
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+
 	apmtransport "go.elastic.co/apm/v2/transport"
 )
 
@@ -38,6 +41,44 @@ type Instrumentation struct {
 	GlobalLabels string `config:"global_labels"`
 	// TransactionSampleRate sets the sample rate  - may be specified with ELASTIC_APM_TRANSACTION_SAMPLE_RATE
 	TransactionSampleRate string `config:"transaction_sample_rate"`
+	// OTLP exports the traces and the metrics to an OpenTelemetry backend, alone or alongside the APM server.
+	OTLP OTLP `config:"otlp"`
+	// Sampling overrides the transaction sample rate for the routes of the API.
+	Sampling InstrumentationSampling `config:"sampling"`
+}
+
+// InstrumentationSampling configures the sampling of the transactions of the API routes.
+type InstrumentationSampling struct {
+	// Routes are the sample rates of the routes by operation, like checkin or enroll. The routes that are
+	// not listed are sampled at the transaction_sample_rate.
+	Routes map[string]float64 `config:"routes"`
+	// Errors reports the transactions of the requests that fail with a 5xx status even when they are not sampled.
+	Errors bool `config:"errors"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *InstrumentationSampling) Validate() error {
+	for route, rate := range c.Routes {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampling rate of route %s must be between 0 and 1, got %v", route, rate)
+		}
+	}
+	return nil
+}
+
+// OTLP configures the export of the traces and the metrics with the OpenTelemetry protocol over HTTP.
+type OTLP struct {
+	Enabled bool `config:"enabled"`
+	// Endpoint is the base URL of the OTLP/HTTP receiver, the signals are sent to its /v1/traces and
+	// /v1/metrics paths - may be specified with OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string `config:"endpoint"`
+	// Headers are added to the export requests, for example to authenticate - may be specified with OTEL_EXPORTER_OTLP_HEADERS
+	Headers map[string]string  `config:"headers"`
+	TLS     InstrumentationTLS `config:"tls"`
+	// Timeout of an export request, defaults to 10s.
+	Timeout time.Duration `config:"timeout"`
+	// MetricsInterval is the interval the metrics are exported at, defaults to 60s.
+	MetricsInterval time.Duration `config:"metrics_interval"`
 }
 
 type InstrumentationTLS struct {
@@ -60,33 +101,9 @@ func (c *Instrumentation) APMHTTPTransportOptions() (apmtransport.HTTPTransportO
 		hosts = append(hosts, u)
 	}
 
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: c.TLS.SkipVerify, //nolint:gosec // users can disable tls validation
-	}
-
-	if c.TLS.ServerCertificate != "" {
-		p, err := os.ReadFile(c.TLS.ServerCertificate)
-		if err != nil {
-			return apmtransport.HTTPTransportOptions{}, fmt.Errorf("unable to read instrumentation certificate: %w", err)
-		}
-		block, _ := pem.Decode(p)
-		cert, err := x509.ParseCertificate(block.Bytes)
-		if err != nil {
-			return apmtransport.HTTPTransportOptions{}, fmt.Errorf("unable to parse instrumentation certificate: %w", err)
-		}
-		tlsConfig.InsecureSkipVerify = true
-		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-			return verifyPeerCertificate(rawCerts, cert)
-		}
-	}
-
-	if c.TLS.ServerCA != "" {
-		pool, errs := tlscommon.LoadCertificateAuthorities([]string{c.TLS.ServerCA})
-		// FIXME once we update elastic-agent-libs to go 1.20 we can return multiple errors directly with errors.Join()
-		if len(errs) != 0 {
-			return apmtransport.HTTPTransportOptions{}, fmt.Errorf("unable to load instrumentation cas: %w", errors.Join(errs...))
-		}
-		tlsConfig.RootCAs = pool
+	tlsConfig, err := c.TLS.ClientConfig()
+	if err != nil {
+		return apmtransport.HTTPTransportOptions{}, err
 	}
 
 	apiKey := c.APIKey
@@ -115,6 +132,39 @@ func (c *Instrumentation) APMHTTPTransportOptions() (apmtransport.HTTPTransportO
 	}, nil
 }
 
+// ClientConfig returns the TLS configuration of the clients of the instrumentation servers.
+func (c *InstrumentationTLS) ClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.SkipVerify, //nolint:gosec // users can disable tls validation
+	}
+
+	if c.ServerCertificate != "" {
+		p, err := os.ReadFile(c.ServerCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read instrumentation certificate: %w", err)
+		}
+		block, _ := pem.Decode(p)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse instrumentation certificate: %w", err)
+		}
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPeerCertificate(rawCerts, cert)
+		}
+	}
+
+	if c.ServerCA != "" {
+		pool, errs := tlscommon.LoadCertificateAuthorities([]string{c.ServerCA})
+		// FIXME once we update elastic-agent-libs to go 1.20 we can return multiple errors directly with errors.Join()
+		if len(errs) != 0 {
+			return nil, fmt.Errorf("unable to load instrumentation cas: %w", errors.Join(errs...))
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return fips.Restrict(tlsConfig), nil
+}
+
 // verifyPeerCertificate copied from elastic/apm-agent-go/transport/http.go with the following alterations:
 // - replaced use of pkg/errors with fmt
 func verifyPeerCertificate(rawCerts [][]byte, trusted *x509.Certificate) error {
@@ -179,3 +179,11 @@ func testFile(t *testing.T, content string) string {
 	require.NoError(t, err)
 	return f.Name()
 }
+
+func TestInstrumentationSamplingValidate(t *testing.T) {
+	c := &InstrumentationSampling{Routes: map[string]float64{"checkin": 0.01, "enroll": 1}}
+	assert.NoError(t, c.Validate())
+
+	c.Routes["acks"] = 1.5
+	assert.ErrorContains(t, c.Validate(), "route acks")
+}
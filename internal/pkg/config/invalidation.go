@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+const defaultAPIKeyInvalidationFileName = "api-key-invalidations.json"
+
+// APIKeyInvalidation is the configuration for the background invalidation of retired API keys.
+// A zero value selects the default of the setting.
+type APIKeyInvalidation struct {
+	// Path is the file where the pending invalidations are kept across restarts.
+	// By default it is [executable directory]/api-key-invalidations.json
+	Path string `config:"path"`
+	// BatchSize is the maximum number of API keys invalidated by a single security API call.
+	BatchSize int `config:"batch_size" validate:"min=0"`
+	// FlushInterval is the interval at which the pending invalidations are sent, it is also the initial retry delay.
+	FlushInterval time.Duration `config:"flush_interval"`
+	// MaxRetryDelay is the longest delay between the retries of failed invalidations.
+	MaxRetryDelay time.Duration `config:"max_retry_delay"`
+}
+
+// StatePath returns the file where the pending invalidations are kept.
+func (c *APIKeyInvalidation) StatePath() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return filepath.Join(retrieveExecutableDir(), defaultAPIKeyInvalidationFileName)
+}
@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// IPFilter is the configuration of the addresses of the clients allowed to send requests to the server, it is
+// evaluated before the requests are authenticated. The routes can be restricted further, like the enrollments
+// to the provisioning networks.
+type IPFilter struct {
+	IPFilterRule `config:",inline"`
+	// Routes are the rules of the routes by operation, like enroll or status. The requests of a route must pass
+	// the rule of the server and the rule of the route.
+	Routes map[string]IPFilterRule `config:"routes"`
+}
+
+// IPFilterRule is a list of the addresses or the networks in CIDR notation allowed and denied. An address that is
+// denied is denied even when it is allowed, all the addresses that are not denied are allowed when Allow is empty.
+type IPFilterRule struct {
+	Allow []string `config:"allow"`
+	Deny  []string `config:"deny"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *IPFilter) Validate() error {
+	if _, _, err := c.Prefixes(); err != nil {
+		return err
+	}
+	for route, rule := range c.Routes {
+		if _, _, err := rule.Prefixes(); err != nil {
+			return fmt.Errorf("ip filter of route %s: %w", route, err)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true when the filter has a rule.
+func (c *IPFilter) Enabled() bool {
+	return !c.IPFilterRule.empty() || len(c.Routes) > 0
+}
+
+func (c *IPFilterRule) empty() bool {
+	return len(c.Allow) == 0 && len(c.Deny) == 0
+}
+
+// Prefixes returns the networks allowed and denied by the rule.
+func (c *IPFilterRule) Prefixes() (allow, deny []netip.Prefix, err error) {
+	if allow, err = parsePrefixes("ip filter allowed", c.Allow); err != nil {
+		return nil, nil, err
+	}
+	if deny, err = parsePrefixes("ip filter denied", c.Deny); err != nil {
+		return nil, nil, err
+	}
+	return allow, deny, nil
+}
+
+// parsePrefixes returns the networks of the sources, an address is a network of a single address.
+func parsePrefixes(kind string, sources []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(sources))
+	for _, source := range sources {
+		prefix, err := netip.ParsePrefix(source)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(source)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%s source %q is not an address or a network", kind, source)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
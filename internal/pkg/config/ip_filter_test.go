@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		err     string
+		enabled bool
+	}{
+		{name: "empty", cfg: "{}"},
+		{name: "server rule", cfg: "allow: [10.0.0.0/8]\ndeny: [10.0.0.1]", enabled: true},
+		{name: "route rule", cfg: "routes:\n  enroll:\n    allow: [\"2001:db8::/32\", 192.0.2.0/24]", enabled: true},
+		{name: "invalid server source", cfg: "deny: [lb.example.com]", err: "ip filter denied source \"lb.example.com\" is not an address or a network"},
+		{name: "invalid route source", cfg: "routes:\n  enroll:\n    allow: [10.0.0.0/33]", err: "ip filter of route enroll"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var f IPFilter
+			err = c.Unpack(&f, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.enabled, f.Enabled())
+		})
+	}
+}
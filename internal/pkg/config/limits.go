@@ -5,9 +5,23 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
+// MaxUploadChunkSize bounds the max_body_byte_size of the upload chunk limit, the size of the chunks the agents
+// split their uploads into.
+const MaxUploadChunkSize = 64 * 1024 * 1024 // 64 MiB
+
+const (
+	defaultAdaptiveInterval        = 5 * time.Second
+	defaultAdaptiveCPUThreshold    = 0.8
+	defaultAdaptiveMemoryThreshold = 0.85
+	defaultAdaptiveFloor           = 0.25
+	defaultAdaptiveCeiling         = 2
+)
+
 type Limit struct {
 	Interval time.Duration `config:"interval"`
 	Burst    int           `config:"burst"`
@@ -21,22 +35,116 @@ type ServerLimits struct {
 	MaxHeaderByteSize int           `config:"max_header_byte_size"`
 	MaxConnections    int           `config:"max_connections"`
 
-	ActionLimit      Limit `config:"action_limit"`
-	PolicyLimit      Limit `config:"policy_limit"`
-	CheckinLimit     Limit `config:"checkin_limit"`
-	ArtifactLimit    Limit `config:"artifact_limit"`
-	EnrollLimit      Limit `config:"enroll_limit"`
-	AckLimit         Limit `config:"ack_limit"`
-	StatusLimit      Limit `config:"status_limit"`
-	UploadStartLimit Limit `config:"upload_start_limit"`
-	UploadEndLimit   Limit `config:"upload_end_limit"`
-	UploadChunkLimit Limit `config:"upload_chunk_limit"`
-	DeliverFileLimit Limit `config:"file_delivery_limit"`
-	GetPGPKey        Limit `config:"pgp_retrieval_limit"`
+	ActionLimit         Limit `config:"action_limit"`
+	PolicyLimit         Limit `config:"policy_limit"`
+	CheckinLimit        Limit `config:"checkin_limit"`
+	ArtifactLimit       Limit `config:"artifact_limit"`
+	EnrollLimit         Limit `config:"enroll_limit"`
+	AckLimit            Limit `config:"ack_limit"`
+	StatusLimit         Limit `config:"status_limit"`
+	UploadStartLimit    Limit `config:"upload_start_limit"`
+	UploadEndLimit      Limit `config:"upload_end_limit"`
+	UploadChunkLimit    Limit `config:"upload_chunk_limit"`
+	UploadStatusLimit   Limit `config:"upload_status_limit"`
+	DeliverFileLimit    Limit `config:"file_delivery_limit"`
+	GetPGPKey           Limit `config:"pgp_retrieval_limit"`
+	PolicyValidateLimit Limit `config:"policy_validate_limit"`
+	RevokeLimit         Limit `config:"revoke_limit"`
+	ProfilerLimit       Limit `config:"profiler_limit"`
+	DiagnosticsLimit    Limit `config:"diagnostics_limit"`
+	DrainLimit          Limit `config:"drain_limit"`
+	AgentSearchLimit    Limit `config:"agent_search_limit"`
+	AgentReassignLimit  Limit `config:"agent_reassign_limit"`
+	PolicyLeadersLimit  Limit `config:"policy_leaders_limit"`
+	PromoteLimit        Limit `config:"promote_limit"`
+
+	Adaptive AdaptiveLimits `config:"adaptive"`
+
+	PolicyLimits []PolicyLimits `config:"policy_limits"`
+}
+
+// PolicyLimits are the limits of the agent routes for the agents of a policy, like stricter limits for the agents
+// of an untrusted policy. They apply in addition to the limits of the routes and are shared by the agents of the
+// policy. The fields a limit does not set are the ones of the limit of its route.
+type PolicyLimits struct {
+	PolicyID      string `config:"policy_id"`
+	CheckinLimit  *Limit `config:"checkin_limit"`
+	ArtifactLimit *Limit `config:"artifact_limit"`
+	EnrollLimit   *Limit `config:"enroll_limit"`
+	AckLimit      *Limit `config:"ack_limit"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ServerLimits) InitDefaults() {
+	c.Adaptive.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ServerLimits) Validate() error {
+	if c.UploadChunkLimit.MaxBody > MaxUploadChunkSize {
+		return fmt.Errorf("upload_chunk_limit max_body_byte_size must be at most %d, got %d", MaxUploadChunkSize, c.UploadChunkLimit.MaxBody)
+	}
+	policies := make(map[string]bool, len(c.PolicyLimits))
+	for _, p := range c.PolicyLimits {
+		if p.PolicyID == "" {
+			return errors.New("policy_limits require a policy_id")
+		}
+		if policies[p.PolicyID] {
+			return fmt.Errorf("policy_limits of policy %s are set more than once", p.PolicyID)
+		}
+		policies[p.PolicyID] = true
+	}
+	return nil
+}
+
+// AdaptiveLimits scales the rate and the burst of the route limits with the load of the server, instead of relying
+// only on the presets of the agent count. The scale decreases while the CPU, the memory or the bulker queue is above
+// its threshold and recovers while none is, between the floor and the ceiling. The max limits are not scaled.
+type AdaptiveLimits struct {
+	Enabled bool `config:"enabled"`
+	// Interval is the interval of the measures of the load.
+	Interval time.Duration `config:"interval"`
+	// CPUThreshold is the share of the CPUs available to the process above which the server is under pressure.
+	CPUThreshold float64 `config:"cpu_threshold"`
+	// MemoryThreshold is the share of the memory limit of the runtime, or of the memory of the host without a limit,
+	// above which the server is under pressure.
+	MemoryThreshold float64 `config:"memory_threshold"`
+	// QueueThreshold is the number of requests queued by the bulker above which the server is under pressure, 0
+	// disables the check.
+	QueueThreshold int `config:"queue_threshold"`
+	// Floor and Ceiling bound the scale of the rate and the burst of the limits.
+	Floor   float64 `config:"floor"`
+	Ceiling float64 `config:"ceiling"`
 }
 
 // InitDefaults initializes the defaults for the configuration.
-func (c *ServerLimits) InitDefaults() {}
+func (c *AdaptiveLimits) InitDefaults() {
+	c.Interval = defaultAdaptiveInterval
+	c.CPUThreshold = defaultAdaptiveCPUThreshold
+	c.MemoryThreshold = defaultAdaptiveMemoryThreshold
+	c.Floor = defaultAdaptiveFloor
+	c.Ceiling = defaultAdaptiveCeiling
+}
+
+// Validate ensures that the configuration is valid.
+func (c *AdaptiveLimits) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("adaptive limits interval must be positive, got %v", c.Interval)
+	}
+	if c.CPUThreshold <= 0 || c.CPUThreshold > 1 || c.MemoryThreshold <= 0 || c.MemoryThreshold > 1 {
+		return fmt.Errorf("adaptive limits thresholds must be in (0, 1], got cpu %v and memory %v", c.CPUThreshold, c.MemoryThreshold)
+	}
+	if c.QueueThreshold < 0 {
+		return fmt.Errorf("adaptive limits queue threshold must not be negative, got %d", c.QueueThreshold)
+	}
+	if c.Floor <= 0 || c.Floor > 1 || c.Ceiling < 1 {
+		return fmt.Errorf("adaptive limits floor must be in (0, 1] and ceiling at least 1, got %v and %v", c.Floor, c.Ceiling)
+	}
+	return nil
+}
 
 func (c *ServerLimits) LoadLimits(limits *envLimits) {
 	l := limits.Server
@@ -61,8 +169,40 @@ func (c *ServerLimits) LoadLimits(limits *envLimits) {
 	c.UploadStartLimit = mergeEnvLimit(c.UploadStartLimit, l.UploadStartLimit)
 	c.UploadEndLimit = mergeEnvLimit(c.UploadEndLimit, l.UploadEndLimit)
 	c.UploadChunkLimit = mergeEnvLimit(c.UploadChunkLimit, l.UploadChunkLimit)
+	c.UploadStatusLimit = mergeEnvLimit(c.UploadStatusLimit, l.UploadStatusLimit)
 	c.DeliverFileLimit = mergeEnvLimit(c.DeliverFileLimit, l.DeliverFileLimit)
 	c.GetPGPKey = mergeEnvLimit(c.GetPGPKey, l.GetPGPKeyLimit)
+	c.PolicyValidateLimit = mergeEnvLimit(c.PolicyValidateLimit, l.PolicyValidateLimit)
+	c.RevokeLimit = mergeEnvLimit(c.RevokeLimit, l.RevokeLimit)
+	c.ProfilerLimit = mergeEnvLimit(c.ProfilerLimit, l.ProfilerLimit)
+	c.DiagnosticsLimit = mergeEnvLimit(c.DiagnosticsLimit, l.DiagnosticsLimit)
+	c.DrainLimit = mergeEnvLimit(c.DrainLimit, l.DrainLimit)
+	c.AgentSearchLimit = mergeEnvLimit(c.AgentSearchLimit, l.AgentSearchLimit)
+	c.AgentReassignLimit = mergeEnvLimit(c.AgentReassignLimit, l.AgentReassignLimit)
+	c.PolicyLeadersLimit = mergeEnvLimit(c.PolicyLeadersLimit, l.PolicyLeadersLimit)
+	c.PromoteLimit = mergeEnvLimit(c.PromoteLimit, l.PromoteLimit)
+
+	for i := range c.PolicyLimits {
+		p := &c.PolicyLimits[i]
+		p.CheckinLimit = mergePolicyLimit(p.CheckinLimit, c.CheckinLimit)
+		p.ArtifactLimit = mergePolicyLimit(p.ArtifactLimit, c.ArtifactLimit)
+		p.EnrollLimit = mergePolicyLimit(p.EnrollLimit, c.EnrollLimit)
+		p.AckLimit = mergePolicyLimit(p.AckLimit, c.AckLimit)
+	}
+}
+
+// mergePolicyLimit sets the fields of the limit of a policy that are not set to the ones of the limit of the route.
+func mergePolicyLimit(L *Limit, route Limit) *Limit {
+	if L == nil {
+		return nil
+	}
+	result := mergeEnvLimit(*L, limit{
+		Interval: route.Interval,
+		Burst:    route.Burst,
+		Max:      route.Max,
+		MaxBody:  route.MaxBody,
+	})
+	return &result
 }
 
 func mergeEnvLimit(L Limit, l limit) Limit {
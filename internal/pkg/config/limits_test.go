@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerLimitsUploadChunkSize(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("upload_chunk_limit:\n  max_body_byte_size: 16777216\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var limits ServerLimits
+	require.NoError(t, c.Unpack(&limits, DefaultOptions...))
+	require.Equal(t, int64(16*1024*1024), limits.UploadChunkLimit.MaxBody)
+
+	c, err = yaml.NewConfig([]byte("upload_chunk_limit:\n  max_body_byte_size: 134217728\n"), DefaultOptions...)
+	require.NoError(t, err)
+	require.ErrorContains(t, c.Unpack(&limits, DefaultOptions...), "upload_chunk_limit max_body_byte_size must be at most")
+}
+
+func TestServerLimitsPolicyLimits(t *testing.T) {
+	c, err := yaml.NewConfig([]byte(`
+checkin_limit:
+  interval: 1ms
+  burst: 1000
+  max: 10000
+policy_limits:
+  - policy_id: byod
+    checkin_limit:
+      max: 100
+      max_body_byte_size: 1024
+`), DefaultOptions...)
+	require.NoError(t, err)
+	var limits ServerLimits
+	require.NoError(t, c.Unpack(&limits, DefaultOptions...))
+	limits.LoadLimits(loadLimits(0))
+
+	require.Len(t, limits.PolicyLimits, 1)
+	p := limits.PolicyLimits[0]
+	require.Equal(t, "byod", p.PolicyID)
+	require.Equal(t, &Limit{Interval: time.Millisecond, Burst: 1000, Max: 100, MaxBody: 1024}, p.CheckinLimit, "the fields that are not set are the ones of the route")
+	require.Nil(t, p.AckLimit, "the routes without a limit are only limited by the route limits")
+
+	c, err = yaml.NewConfig([]byte("policy_limits:\n  - policy_id: byod\n  - policy_id: byod\n"), DefaultOptions...)
+	require.NoError(t, err)
+	require.ErrorContains(t, c.Unpack(&ServerLimits{}, DefaultOptions...), "policy_limits of policy byod are set more than once")
+
+	c, err = yaml.NewConfig([]byte("policy_limits:\n  - ack_limit:\n      max: 1\n"), DefaultOptions...)
+	require.NoError(t, err)
+	require.ErrorContains(t, c.Unpack(&ServerLimits{}, DefaultOptions...), "policy_limits require a policy_id")
+}
+
+func TestServerRequestSigningChunkSize(t *testing.T) {
+	load := func(s string) error {
+		c, err := yaml.NewConfig([]byte(s), DefaultOptions...)
+		require.NoError(t, err)
+		var srv Server
+		return c.Unpack(&srv, DefaultOptions...)
+	}
+	const signing = "request_signing:\n  enabled: true\n  secret: MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n"
+
+	require.NoError(t, load(signing+"limits.upload_chunk_limit.max_body_byte_size: 8388608\n"))
+	require.ErrorContains(t, load(signing+"limits.upload_chunk_limit.max_body_byte_size: 16777216\n"),
+		"request signing max_body_byte_size 8388608 is smaller than the upload_chunk_limit max_body_byte_size 16777216")
+	require.NoError(t, load(signing+"request_signing.max_body_byte_size: 16777216\nlimits.upload_chunk_limit.max_body_byte_size: 16777216\n"))
+	require.NoError(t, load("limits.upload_chunk_limit.max_body_byte_size: 16777216\n"), "the limit is not checked without signing")
+}
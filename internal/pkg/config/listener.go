@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// Listener is an additional listener of the server with its own address, TLS configuration, limits and routes,
+// like an internal listener without client authentication or a management listener serving the status and
+// diagnostics routes only. The settings a listener does not set are the settings of the server.
+type Listener struct {
+	Host string `config:"host"`
+	// Port is the TCP port of the listener, 0 when it only listens on a socket.
+	Port   uint16     `config:"port"`
+	Socket UnixSocket `config:"socket"`
+	// TLS replaces the TLS configuration of the server, ssl.enabled: false disables TLS on the listener.
+	TLS *tlscommon.ServerConfig `config:"ssl"`
+	// Limits replaces the limits of the server, the limits it does not set have their default value.
+	Limits *ServerLimits `config:"limits"`
+	// Routes replaces the routes served by the server.
+	Routes []string `config:"routes"`
+	// ProxyProtocol replaces the PROXY protocol configuration of the server.
+	ProxyProtocol *ProxyProtocol `config:"proxy_protocol"`
+	// IPFilter replaces the IP filter of the server.
+	IPFilter *IPFilter `config:"ip_filter"`
+	// CORS replaces the CORS configuration of the server.
+	CORS *CORS `config:"cors"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Listener) Validate() error {
+	if c.Port == 0 && c.Socket.Path == "" {
+		return errors.New("listener port or socket path must be set")
+	}
+	return nil
+}
+
+// ListenerServers returns the configuration of the additional listeners, the configuration of the server
+// with the settings of each listener.
+func (c *Server) ListenerServers() []Server {
+	servers := make([]Server, 0, len(c.Listeners))
+	for _, l := range c.Listeners {
+		s := *c
+		s.Listeners = nil
+		// the HTTP/3 listener is a listener of the server only
+		s.HTTP3 = HTTP3{}
+		if l.Host != "" {
+			s.Host = l.Host
+		}
+		s.Port = l.Port
+		s.Socket = l.Socket
+		if l.TLS != nil {
+			s.TLS = l.TLS
+		}
+		if l.Limits != nil {
+			s.Limits = *l.Limits
+		}
+		if l.Routes != nil {
+			s.Routes = l.Routes
+		}
+		if l.ProxyProtocol != nil {
+			s.ProxyProtocol = *l.ProxyProtocol
+		}
+		if l.IPFilter != nil {
+			s.IPFilter = *l.IPFilter
+		}
+		if l.CORS != nil {
+			s.CORS = *l.CORS
+		}
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+// ListenerEndpoints returns the endpoints of the configuration of an additional listener, its TCP address when it
+// has a port and its Unix socket.
+func (c *Server) ListenerEndpoints() []string {
+	var endpoints []string
+	if c.Port != 0 {
+		endpoints = append(endpoints, c.BindAddress())
+	}
+	if socket := c.Socket.Endpoint(); socket != "" {
+		endpoints = append(endpoints, socket)
+	}
+	return endpoints
+}
+
+// ServesRoute returns true when the server serves the route of operation op, like checkin or status.
+// The requests that are not for an operation, like the liveness and readiness probes, are always served.
+func (c *Server) ServesRoute(op string) bool {
+	if len(c.Routes) == 0 || op == "" {
+		return true
+	}
+	for _, route := range c.Routes {
+		if route == op {
+			return true
+		}
+	}
+	return false
+}
+
+// UnixSocketPrefix is the prefix of the endpoints of the Unix sockets.
+const UnixSocketPrefix = "unix:"
+
+// UnixSocket is a Unix domain socket the api listens on, for a reverse proxy on the same host that terminates TLS.
+type UnixSocket struct {
+	// Path is the path of the socket file, a stale socket file left by a previous run is removed.
+	Path string `config:"path"`
+	// Mode is the octal permissions of the socket file, like "0660"; the permissions follow the umask when empty.
+	Mode string `config:"mode"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *UnixSocket) Validate() error {
+	if c.Mode == "" {
+		return nil
+	}
+	if _, err := strconv.ParseUint(c.Mode, 8, 32); err != nil {
+		return fmt.Errorf("socket mode must be octal permissions like 0660, got %q", c.Mode)
+	}
+	return nil
+}
+
+// FileMode returns the permissions of the socket file, false when they are not set.
+func (c *UnixSocket) FileMode() (os.FileMode, bool) {
+	mode, err := strconv.ParseUint(c.Mode, 8, 32)
+	if c.Mode == "" || err != nil {
+		return 0, false
+	}
+	return os.FileMode(mode).Perm(), true
+}
+
+// Endpoint returns the endpoint of the socket, empty when it is not set.
+func (c *UnixSocket) Endpoint() string {
+	if c.Path == "" {
+		return ""
+	}
+	return UnixSocketPrefix + c.Path
+}
@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerServers(t *testing.T) {
+	c, err := yaml.NewConfig([]byte(`
+host: 0.0.0.0
+port: 8220
+ssl.enabled: true
+ssl.certificate: /creds/cert.pem
+ssl.key: /creds/key.pem
+ssl.client_authentication: required
+listeners:
+  - port: 8222
+    ssl.enabled: true
+    ssl.certificate: /creds/cert.pem
+    ssl.key: /creds/key.pem
+    limits.max_connections: 10
+  - host: localhost
+    port: 8223
+    routes: [status, diagnostics]
+  - socket.path: /run/fleet-server.sock
+    socket.mode: "0660"
+    ssl.enabled: false
+`), DefaultOptions...)
+	require.NoError(t, err)
+	var s Server
+	s.InitDefaults()
+	require.NoError(t, c.Unpack(&s, DefaultOptions...))
+
+	servers := s.ListenerServers()
+	require.Len(t, servers, 3)
+
+	assert.Equal(t, "0.0.0.0:8222", servers[0].BindAddress())
+	assert.NotEqual(t, s.TLS, servers[0].TLS, "the listener has its own TLS configuration")
+	assert.Equal(t, 10, servers[0].Limits.MaxConnections)
+	assert.True(t, servers[0].ServesRoute("checkin"))
+	assert.Empty(t, servers[0].Listeners)
+
+	assert.Equal(t, "localhost:8223", servers[1].BindAddress())
+	assert.Equal(t, s.TLS, servers[1].TLS, "the listener has the TLS configuration of the server")
+	assert.True(t, servers[1].ServesRoute("diagnostics"))
+	assert.False(t, servers[1].ServesRoute("checkin"))
+	assert.True(t, servers[1].ServesRoute(""), "the probes are served")
+	assert.Equal(t, []string{"localhost:8223"}, servers[1].ListenerEndpoints())
+
+	assert.Equal(t, []string{"unix:/run/fleet-server.sock"}, servers[2].ListenerEndpoints())
+	assert.False(t, servers[2].TLS.IsEnabled())
+	mode, ok := servers[2].Socket.FileMode()
+	assert.True(t, ok)
+	assert.Equal(t, os.FileMode(0o660), mode)
+
+	c, err = yaml.NewConfig([]byte("listeners:\n  - host: localhost\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var invalid Server
+	assert.ErrorContains(t, c.Unpack(&invalid, DefaultOptions...), "listener port or socket path must be set")
+
+	c, err = yaml.NewConfig([]byte("listeners:\n  - socket.path: /run/fleet-server.sock\n    socket.mode: rw\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var invalidMode Server
+	assert.ErrorContains(t, c.Unpack(&invalidMode, DefaultOptions...), "socket mode must be octal permissions")
+}
@@ -0,0 +1,30 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+)
+
+// Memory is the configuration of the in-memory backend, it is used in place of Elasticsearch to run fleet-server
+// without a cluster in the development setups and the tests. The documents and the API keys are kept in memory, and in
+// the state file of Path when it is set.
+type Memory struct {
+	Enabled bool `config:"enabled"`
+	// Path is the JSON file the state is loaded from at start and saved to after the changes, the state is lost at
+	// exit without it. The file can be written beforehand to seed the API keys and the documents, like the enrollment
+	// keys and the policies.
+	Path string `config:"path"`
+}
+
+// Validate ensures that the in-memory backend is only enabled in the development builds.
+func (c *Memory) Validate() error {
+	if c.Enabled && !build.Dev {
+		return errors.New("output.elasticsearch.memory is only available in the builds with the dev tag")
+	}
+	return nil
+}
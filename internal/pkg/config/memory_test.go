@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/build"
+)
+
+func TestMemory(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("enabled: true\npath: state.json"), DefaultOptions...)
+	require.NoError(t, err)
+	var v Memory
+	err = c.Unpack(&v, DefaultOptions...)
+	if build.Dev {
+		require.NoError(t, err)
+		return
+	}
+	require.ErrorContains(t, err, "output.elasticsearch.memory is only available in the builds with the dev tag")
+}
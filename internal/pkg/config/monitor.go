@@ -10,16 +10,24 @@ const (
 	defaultFetchSize          = 1000
 	defaultPollTimeout        = 4 * time.Minute
 	defaultPolicyDebounceTime = time.Second
+
+	defaultPolicyDispatchMaxWait = 30 * time.Second
 )
 
 type Monitor struct {
 	FetchSize          int           `config:"fetch_size"`
 	PollTimeout        time.Duration `config:"poll_timeout"`
 	PolicyDebounceTime time.Duration `config:"policy_debounce_time"`
+	// PolicyDispatchDebounce is how long the policy monitor waits for another revision of a changed policy
+	// before dispatching it to agents, only the latest revision within the window is dispatched. 0 disables it.
+	PolicyDispatchDebounce time.Duration `config:"policy_dispatch_debounce"`
+	// PolicyDispatchMaxWait bounds how long a policy that keeps changing is held back by PolicyDispatchDebounce.
+	PolicyDispatchMaxWait time.Duration `config:"policy_dispatch_max_wait"`
 }
 
 func (m *Monitor) InitDefaults() {
 	m.FetchSize = defaultFetchSize
 	m.PollTimeout = defaultPollTimeout
 	m.PolicyDebounceTime = defaultPolicyDebounceTime
+	m.PolicyDispatchMaxWait = defaultPolicyDispatchMaxWait
 }
@@ -18,6 +18,8 @@ import (
 	urlutil "github.com/elastic/elastic-agent-libs/kibana"
 	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/go-elasticsearch/v8"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
 )
 
 // The timeout would be driven by the server for long poll.
@@ -49,6 +51,7 @@ type Elasticsearch struct {
 	MaxConnPerHost   int               `config:"max_conn_per_host"`
 	Timeout          time.Duration     `config:"timeout"`
 	MaxContentLength int               `config:"max_content_length"`
+	Memory           Memory            `config:"memory"`
 }
 
 // InitDefaults initializes the defaults for the configuration.
@@ -123,6 +126,7 @@ func (c *Elasticsearch) ToESConfig(longPoll bool) (elasticsearch.Config, error)
 		}
 		httpTransport.TLSClientConfig = tls.ToConfig()
 	}
+	httpTransport.TLSClientConfig = fips.Restrict(httpTransport.TLSClientConfig)
 
 	if !c.ProxyDisable {
 		if c.ProxyURL != "" {
@@ -15,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
 
 	"github.com/google/go-cmp/cmp"
@@ -203,6 +204,9 @@ func TestToESConfig(t *testing.T) {
 
 			// cmp.Diff can't handle function pointers.
 			res.Transport.(*http.Transport).Proxy = nil
+			// the TLS settings are restricted in the builds requiring the FIPS mode
+			want := test.result.Transport.(*http.Transport)
+			want.TLSClientConfig = fips.Restrict(want.TLSClientConfig)
 
 			test.result.Header.Set("X-elastic-product-origin", "fleet")
 			assert.True(t, cmp.Equal(test.result, res, copts...), "mismatch (-want +got)\n%s", cmp.Diff(test.result, res, copts...))
@@ -239,6 +243,7 @@ func TestToESConfig(t *testing.T) {
 		}
 
 		es.Transport.(*http.Transport).Proxy = nil
+		expect.Transport.(*http.Transport).TLSClientConfig = fips.Restrict(nil)
 		assert.True(t, cmp.Equal(expect, es, copts...), "mismatch (-want +got)\n%s", cmp.Diff(expect, es, copts...))
 	})
 
@@ -271,6 +276,7 @@ func TestToESConfig(t *testing.T) {
 		}
 
 		es.Transport.(*http.Transport).Proxy = nil
+		expect.Transport.(*http.Transport).TLSClientConfig = fips.Restrict(nil)
 		assert.True(t, cmp.Equal(expect, es, copts...), "mismatch (-want +got)\n%s", cmp.Diff(expect, es, copts...))
 	})
 
@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import "time"
+
+const defaultProbesPingTimeout = 2 * time.Second
+
+// Probes is the configuration of the liveness (/live) and readiness (/ready) endpoints used by orchestrators like Kubernetes.
+// The liveness endpoint succeeds as long as the server answers, the readiness endpoint only when the enabled criteria are
+// met and the server is not shutting down.
+type Probes struct {
+	// Elasticsearch requires Elasticsearch to answer a ping for the server to be ready.
+	Elasticsearch bool `config:"elasticsearch"`
+	// Policies requires the fleet-server policy to be loaded, the self monitor reporting healthy, for the server to be ready.
+	Policies bool `config:"policies"`
+	// PingTimeout bounds the ping of Elasticsearch of a readiness check.
+	PingTimeout time.Duration `config:"ping_timeout"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Probes) InitDefaults() {
+	c.Elasticsearch = true
+	c.Policies = true
+	c.PingTimeout = defaultProbesPingTimeout
+}
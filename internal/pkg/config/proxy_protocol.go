@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+const defaultProxyProtocolHeaderTimeout = 5 * time.Second
+
+// ProxyProtocol is the configuration of the PROXY protocol (v1 and v2) headers sent by the L4 load balancers in
+// front of the server, so the rate limits and the logs see the addresses of the clients instead of the address of
+// the load balancer.
+type ProxyProtocol struct {
+	Enabled bool `config:"enabled"`
+	// TrustedSources are the addresses or the networks in CIDR notation of the load balancers. The header of the
+	// other sources is not read, their connections have their own address. The peers of the Unix sockets are trusted.
+	TrustedSources []string `config:"trusted_sources"`
+	// HeaderTimeout is how long the header of a connection is waited for.
+	HeaderTimeout time.Duration `config:"header_timeout"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ProxyProtocol) InitDefaults() {
+	c.HeaderTimeout = defaultProxyProtocolHeaderTimeout
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ProxyProtocol) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.TrustedSources) == 0 {
+		return errors.New("proxy protocol requires trusted sources")
+	}
+	if c.HeaderTimeout <= 0 {
+		return fmt.Errorf("proxy protocol header timeout must be positive, got %v", c.HeaderTimeout)
+	}
+	_, err := c.TrustedPrefixes()
+	return err
+}
+
+// TrustedPrefixes returns the networks of the trusted sources, an address is a network of a single address.
+func (c *ProxyProtocol) TrustedPrefixes() ([]netip.Prefix, error) {
+	return parsePrefixes("proxy protocol trusted", c.TrustedSources)
+}
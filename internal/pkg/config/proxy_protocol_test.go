@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      string
+		err      string
+		prefixes []netip.Prefix
+	}{
+		{name: "disabled", cfg: "enabled: false"},
+		{
+			name:     "trusted sources",
+			cfg:      "enabled: true\ntrusted_sources: [10.0.0.0/8, 192.0.2.10, \"2001:db8::/32\"]",
+			prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.10/32"), netip.MustParsePrefix("2001:db8::/32")},
+		},
+		{name: "no trusted sources", cfg: "enabled: true", err: "proxy protocol requires trusted sources"},
+		{name: "invalid source", cfg: "enabled: true\ntrusted_sources: [lb.example.com]", err: "is not an address or a network"},
+		{name: "invalid timeout", cfg: "enabled: true\ntrusted_sources: [10.0.0.1]\nheader_timeout: 0s", err: "header timeout must be positive"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var p ProxyProtocol
+			p.InitDefaults()
+			err = c.Unpack(&p, DefaultOptions...)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, defaultProxyProtocolHeaderTimeout, p.HeaderTimeout)
+			if tc.prefixes != nil {
+				prefixes, err := p.TrustedPrefixes()
+				require.NoError(t, err)
+				assert.Equal(t, tc.prefixes, prefixes)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	c, err := yaml.NewConfig([]byte(`
+port: 8220
+proxy_protocol:
+  enabled: true
+  trusted_sources: [10.0.0.0/8]
+listeners:
+  - port: 8222
+    proxy_protocol.enabled: false
+  - port: 8223
+`), DefaultOptions...)
+	require.NoError(t, err)
+	var s Server
+	s.InitDefaults()
+	require.NoError(t, c.Unpack(&s, DefaultOptions...))
+
+	servers := s.ListenerServers()
+	require.Len(t, servers, 2)
+	assert.False(t, servers[0].ProxyProtocol.Enabled)
+	assert.Equal(t, 5*time.Second, servers[0].ProxyProtocol.HeaderTimeout)
+	assert.Equal(t, s.ProxyProtocol, servers[1].ProxyProtocol)
+}
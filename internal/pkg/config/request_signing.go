@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultRequestSigningMaxSkew = 30 * time.Second
+	defaultRequestSigningMaxBody = 8 * 1024 * 1024
+	minRequestSigningSecretSize  = 32
+)
+
+// RequestSigning is the verification of the HMAC signatures of the requests of the agents, over their method, path,
+// body, timestamp and nonce. The signing key of an agent is derived from the secret and the agent ID and is sent
+// to the agent in its enrollment response, so all the fleet-servers of a deployment must share the secret.
+type RequestSigning struct {
+	Enabled bool `config:"enabled"`
+	// Secret is the base64 encoded secret the signing keys are derived from, of at least 32 bytes, it is best
+	// provided through the keystore.
+	Secret string `config:"secret"`
+	// MaxSkew is the largest difference between the timestamp of a signature and the time of the server, the nonces
+	// are remembered for twice as long to reject the replays. The nonces are remembered in memory by each fleet-server,
+	// a request replayed to another fleet-server within MaxSkew is not detected, so it is kept short.
+	MaxSkew time.Duration `config:"max_skew"`
+	// MaxBody is the largest body of a signed request read to verify its signature, the larger requests are rejected.
+	// It can not be smaller than the max_body_byte_size of the upload chunk limit, the chunks are signed.
+	MaxBody int64 `config:"max_body_byte_size"`
+	// AllowUnsigned accepts the requests without a signature, for the agents enrolled before the signing was enabled.
+	// The requests with an invalid signature are rejected.
+	AllowUnsigned bool `config:"allow_unsigned"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *RequestSigning) InitDefaults() {
+	c.MaxSkew = defaultRequestSigningMaxSkew
+	c.MaxBody = defaultRequestSigningMaxBody
+}
+
+// Validate ensures that the configuration is valid.
+func (c *RequestSigning) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxSkew <= 0 {
+		return errors.New("request signing max_skew must be positive")
+	}
+	if c.MaxBody <= 0 {
+		return errors.New("request signing max_body_byte_size must be positive")
+	}
+	_, err := c.DecodeSecret()
+	return err
+}
+
+// DecodeSecret returns the raw secret the signing keys are derived from.
+func (c *RequestSigning) DecodeSecret() ([]byte, error) {
+	if c.Secret == "" {
+		return nil, errors.New("request signing secret is required")
+	}
+	secret, err := base64.StdEncoding.DecodeString(c.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("request signing secret must be base64 encoded: %w", err)
+	}
+	if len(secret) < minRequestSigningSecretSize {
+		return nil, fmt.Errorf("request signing secret must be at least %d bytes, got %d", minRequestSigningSecretSize, len(secret))
+	}
+	return secret, nil
+}
+
+// validateRequestSigning ensures that the signed chunks of the uploads are not larger than the bodies read to verify
+// their signatures.
+func (c *Server) validateRequestSigning() error {
+	if !c.RequestSigning.Enabled {
+		return nil
+	}
+	chunkLimits := []Limit{c.Limits.UploadChunkLimit}
+	for _, l := range c.Listeners {
+		if l.Limits != nil {
+			chunkLimits = append(chunkLimits, l.Limits.UploadChunkLimit)
+		}
+	}
+	for _, l := range chunkLimits {
+		if l.MaxBody > c.RequestSigning.MaxBody {
+			return fmt.Errorf("request signing max_body_byte_size %d is smaller than the upload_chunk_limit max_body_byte_size %d", c.RequestSigning.MaxBody, l.MaxBody)
+		}
+	}
+	return nil
+}
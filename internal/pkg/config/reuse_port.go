@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// maxReusePortSockets bounds the sockets opened on an address, the kernel spreads the connections over them all.
+const maxReusePortSockets = 256
+
+// ReusePort is the configuration of the SO_REUSEPORT sockets of the TCP listeners. The kernel spreads the new
+// connections of a port over its sockets and each socket is accepted from by its own goroutine, so the accepts
+// are not serialized on a single socket on the hosts terminating the long polls of many agents.
+type ReusePort struct {
+	Enabled bool `config:"enabled"`
+	// Sockets is the number of sockets opened on each TCP address, the number of CPUs when 0.
+	Sockets int `config:"sockets"`
+	// Servers is the number of http servers the connections of a listener are spread over, 1 when 0. Each http
+	// server tracks its connections under its own lock. Servers does not require SO_REUSEPORT.
+	Servers int `config:"servers"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ReusePort) Validate() error {
+	if c.Sockets < 0 || c.Sockets > maxReusePortSockets {
+		return fmt.Errorf("reuse_port sockets must be between 0 and %d, got %d", maxReusePortSockets, c.Sockets)
+	}
+	if c.Servers < 0 || c.Servers > maxReusePortSockets {
+		return fmt.Errorf("reuse_port servers must be between 0 and %d, got %d", maxReusePortSockets, c.Servers)
+	}
+	return nil
+}
+
+// SocketCount returns the number of sockets opened on each TCP address, 1 when SO_REUSEPORT is not enabled.
+func (c *ReusePort) SocketCount() int {
+	if !c.Enabled {
+		return 1
+	}
+	if c.Sockets == 0 {
+		return runtime.NumCPU()
+	}
+	return c.Sockets
+}
+
+// ServerCount returns the number of http servers of a listener.
+func (c *ReusePort) ServerCount() int {
+	if c.Servers == 0 {
+		return 1
+	}
+	return c.Servers
+}
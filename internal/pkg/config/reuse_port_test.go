@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReusePort(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		err     string
+		sockets int
+		servers int
+	}{
+		{name: "disabled", cfg: "enabled: false\nsockets: 8", sockets: 1, servers: 1},
+		{name: "default sockets", cfg: "enabled: true", sockets: runtime.NumCPU(), servers: 1},
+		{name: "sockets and servers", cfg: "enabled: true\nsockets: 8\nservers: 4", sockets: 8, servers: 4},
+		{name: "servers without SO_REUSEPORT", cfg: "servers: 2", sockets: 1, servers: 2},
+		{name: "negative sockets", cfg: "enabled: true\nsockets: -1", err: "reuse_port sockets must be between 0 and 256"},
+		{name: "too many servers", cfg: "servers: 1000", err: "reuse_port servers must be between 0 and 256"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var r ReusePort
+			err = c.Unpack(&r, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.sockets, r.SocketCount())
+			assert.Equal(t, tc.servers, r.ServerCount())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPolicyRolloutStageDuration = 10 * time.Minute
+	defaultPolicyRolloutMinAgents     = 5
+	defaultPolicyRolloutMaxUnhealthy  = 0.2
+)
+
+var defaultPolicyRolloutStages = []int{10, 50, 100}
+
+// PolicyRollout is the configuration for the staged release of new policy revisions.
+// When enabled a new revision is first delivered to a percentage of the agents on the policy and is
+// only released further while those agents keep checking in healthy.
+type PolicyRollout struct {
+	Enabled bool `config:"enabled"`
+	// Stages are the ascending percentages of agents that receive a new revision, the last stage must be 100.
+	// Agents are assigned to a stable cohort, the agents of an earlier stage are always part of the later ones.
+	Stages []int `config:"stages"`
+	// StageDuration is how long a stage has to stay healthy before the revision is released to the next one.
+	StageDuration time.Duration `config:"stage_duration"`
+	// MinAgents is the number of agents on the new revision that must have checked in before their health is evaluated.
+	MinAgents int `config:"min_agents"`
+	// MaxUnhealthy is the fraction of agents on the new revision that may report a degraded or error status before the rollout is held.
+	MaxUnhealthy float64 `config:"max_unhealthy"`
+	// Rollback redelivers the previous revision to the agents of a held rollout.
+	Rollback bool `config:"rollback"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *PolicyRollout) InitDefaults() {
+	c.Stages = append([]int(nil), defaultPolicyRolloutStages...)
+	c.StageDuration = defaultPolicyRolloutStageDuration
+	c.MinAgents = defaultPolicyRolloutMinAgents
+	c.MaxUnhealthy = defaultPolicyRolloutMaxUnhealthy
+}
+
+// Validate ensures that the configuration is valid.
+func (c *PolicyRollout) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Stages) == 0 {
+		return errors.New("policy_rollout stages must not be empty")
+	}
+	prev := 0
+	for _, pct := range c.Stages {
+		if pct <= prev || pct > 100 {
+			return fmt.Errorf("policy_rollout stages must be ascending percentages between 1 and 100, got %v", c.Stages)
+		}
+		prev = pct
+	}
+	if prev != 100 {
+		return errors.New("policy_rollout last stage must be 100")
+	}
+	if c.StageDuration <= 0 {
+		return errors.New("policy_rollout stage_duration must be positive")
+	}
+	if c.MinAgents < 0 {
+		return errors.New("policy_rollout min_agents must not be negative")
+	}
+	if c.MaxUnhealthy < 0 || c.MaxUnhealthy > 1 {
+		return errors.New("policy_rollout max_unhealthy must be between 0 and 1")
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import "time"
+
+// APIKeyRotation is the configuration for the scheduled rotation of the output API keys of agents.
+type APIKeyRotation struct {
+	// MaxAge is the age after which the output API key of an agent is replaced, 0 disables the rotation.
+	// Keys without a creation date, created by older fleet-server versions, are replaced on the next checkin.
+	MaxAge time.Duration `config:"max_age"`
+}
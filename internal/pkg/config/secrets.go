@@ -0,0 +1,186 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// The suffixes of the settings read from a secret provider, output.elasticsearch.service_token_file sets
+// output.elasticsearch.service_token to the contents of the file for example.
+const (
+	SecretFileSuffix  = "_file"
+	SecretEnvSuffix   = "_env"
+	SecretVaultSuffix = "_vault"
+
+	defaultVaultTimeout = 10 * time.Second
+)
+
+// Secrets is the configuration of the providers the secret settings are read from.
+//
+// A setting is read from a provider when the setting with the suffix of the provider is set instead of it:
+//
+//	output.elasticsearch.service_token_file: /run/secrets/service_token
+//	inputs.0.server.ssl.key_env: FLEET_SERVER_KEY
+//	inputs.0.server.uploads.encryption.key_vault: secret/data/fleet-server#uploads_key
+//
+// The settings of the secrets section are read from files and environment variables only.
+type Secrets struct {
+	// RefreshInterval is how often the secrets are read again, the server is reconfigured when one of them
+	// changed. 0 disables the refresh.
+	RefreshInterval time.Duration `config:"refresh_interval"`
+	Vault           Vault         `config:"vault"`
+}
+
+// Vault is the configuration of the HashiCorp Vault server the _vault settings are read from. The value of a
+// _vault setting is the path of a secret and the name of one of its fields, like secret/data/fleet-server#token
+// with the KV version 2 engine.
+type Vault struct {
+	// Address is the URL of the Vault server, like https://vault:8200.
+	Address string `config:"address"`
+	// Token authenticates to Vault, it is best given through token_file, written by a Vault agent for example.
+	Token     string            `config:"token"`
+	Namespace string            `config:"namespace"`
+	Timeout   time.Duration     `config:"timeout"`
+	TLS       *tlscommon.Config `config:"ssl"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Vault) InitDefaults() {
+	c.Timeout = defaultVaultTimeout
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Secrets) Validate() error {
+	if c.RefreshInterval < 0 {
+		return fmt.Errorf("secrets refresh_interval must not be negative")
+	}
+	return nil
+}
+
+// secretResolver replaces the settings with a provider suffix by the secrets they refer to.
+type secretResolver struct {
+	ctx   context.Context
+	vault *vaultClient
+	// vaultSecrets are the secrets read from Vault by path, a secret is read once for all its fields
+	vaultSecrets map[string]map[string]interface{}
+	// resolved are the secrets by setting, to find out whether a refresh changed one of them
+	resolved map[string]interface{}
+}
+
+func newSecretResolver(ctx context.Context, vault *vaultClient) *secretResolver {
+	return &secretResolver{
+		ctx:          ctx,
+		vault:        vault,
+		vaultSecrets: make(map[string]map[string]interface{}),
+		resolved:     make(map[string]interface{}),
+	}
+}
+
+// resolve replaces the secret references of v, the settings of a config unpacked as a map, in place.
+func (r *secretResolver) resolve(path string, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := r.resolve(joinPath(path, k), v[k]); err != nil {
+				return err
+			}
+			name, suffix, ok := secretReference(k)
+			if !ok {
+				continue
+			}
+			if _, ok := v[name]; ok {
+				return fmt.Errorf("%s and %s can not be both set", joinPath(path, name), joinPath(path, k))
+			}
+			ref, ok := v[k].(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string", joinPath(path, k))
+			}
+			secret, err := r.read(suffix, ref)
+			if err != nil {
+				return fmt.Errorf("unable to read %s: %w", joinPath(path, k), err)
+			}
+			delete(v, k)
+			v[name] = secret
+			r.resolved[joinPath(path, name)] = secret
+		}
+	case []interface{}:
+		for i, e := range v {
+			if err := r.resolve(joinPath(path, strconv.Itoa(i)), e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *secretResolver) read(suffix, ref string) (interface{}, error) {
+	switch suffix {
+	case SecretFileSuffix:
+		b, err := os.ReadFile(ref)
+		if err != nil {
+			return nil, err
+		}
+		// the files written by editors and most secret stores end with a new line
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case SecretEnvSuffix:
+		s, ok := os.LookupEnv(ref)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return s, nil
+	}
+
+	if r.vault == nil {
+		return nil, fmt.Errorf("secrets.vault.address is not set")
+	}
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return nil, fmt.Errorf("vault reference %q must be <path>#<field>", ref)
+	}
+	secret, ok := r.vaultSecrets[path]
+	if !ok {
+		var err error
+		if secret, err = r.vault.read(r.ctx, path); err != nil {
+			return nil, err
+		}
+		r.vaultSecrets[path] = secret
+	}
+	value, ok := secret[field]
+	if !ok {
+		return nil, fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	return value, nil
+}
+
+// secretReference returns the setting a key with a provider suffix sets.
+func secretReference(key string) (name, suffix string, ok bool) {
+	for _, suffix := range []string{SecretFileSuffix, SecretEnvSuffix, SecretVaultSuffix} {
+		if name, ok := strings.CutSuffix(key, suffix); ok && name != "" {
+			return name, suffix, true
+		}
+	}
+	return "", "", false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,219 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+func newVaultServer(t *testing.T, secrets map[string]map[string]interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		secret, ok := secrets[strings.TrimPrefix(r.URL.Path, "/v1/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		// the KV version 2 engines nest the secret in the data of the response
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": secret, "metadata": map[string]interface{}{"version": 1}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFromConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "service_token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("file-token\n"), 0o600))
+	vaultTokenPath := filepath.Join(dir, "vault_token")
+	require.NoError(t, os.WriteFile(vaultTokenPath, []byte("vault-token"), 0o600))
+	t.Setenv("TEST_FLEET_SERVER_S3_KEY", "env-key")
+	vault := newVaultServer(t, map[string]map[string]interface{}{
+		"secret/data/fleet-server": {"uploads_key": "vault-key", "policy_token": "vault-policy-token"},
+	})
+
+	c, err := yaml.NewConfig([]byte(`
+secrets.vault:
+  address: `+vault.URL+`
+  token_file: `+vaultTokenPath+`
+output.elasticsearch.service_token_file: `+tokenPath+`
+inputs:
+  - type: fleet-server
+    server:
+      uploads.storage.s3.secret_access_key_env: TEST_FLEET_SERVER_S3_KEY
+      uploads.encryption.key_vault: secret/data/fleet-server#uploads_key
+      static_policy_tokens.policy_tokens:
+        - policy_id: policy-1
+          token_key_vault: secret/data/fleet-server#policy_token
+`), DefaultOptions...)
+	require.NoError(t, err)
+	cfg, err := FromConfig(c)
+	require.NoError(t, err)
+
+	assert.Equal(t, "file-token", cfg.Output.Elasticsearch.ServiceToken)
+	assert.Equal(t, "vault-token", cfg.Secrets.Vault.Token)
+	assert.Equal(t, "env-key", cfg.Inputs[0].Server.Uploads.Storage.S3.SecretAccessKey)
+	assert.Equal(t, "vault-key", cfg.Inputs[0].Server.Uploads.Encryption.Key)
+	assert.Equal(t, "vault-policy-token", cfg.Inputs[0].Server.StaticPolicyTokens.PolicyTokens[0].TokenKey)
+}
+
+func TestFromConfigSecretsFIPS(t *testing.T) {
+	if fips.Required {
+		t.Skip("the FIPS mode can not be disabled")
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"uploads_key":"vault-key"},"metadata":{"version":1}}}`))
+	}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	require.False(t, fips.Enabled())
+
+	load := func(fipsMode bool) error {
+		c, err := yaml.NewConfig([]byte(fmt.Sprintf(`
+secrets.vault:
+  address: %s
+  token: vault-token
+  ssl.verification_mode: none
+inputs:
+  - type: fleet-server
+    server:
+      fips.enabled: %t
+      uploads.encryption.key_vault: secret/data/fleet-server#uploads_key
+`, srv.URL, fipsMode)), DefaultOptions...)
+		require.NoError(t, err)
+		_, err = FromConfig(c)
+		return err
+	}
+	assert.NoError(t, load(false))
+	// the Vault client is built before the server sets the FIPS mode of the configuration
+	assert.ErrorContains(t, load(true), "protocol version", "TLS 1.3 is not negotiated in the FIPS mode")
+}
+
+func TestFromConfigSecretsErrors(t *testing.T) {
+	vault := newVaultServer(t, map[string]map[string]interface{}{"secret/data/fleet-server": {"token": "vault"}})
+	tests := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{
+			name: "setting and reference",
+			cfg:  "output.elasticsearch.service_token: token\noutput.elasticsearch.service_token_env: TEST_FLEET_SERVER_TOKEN",
+			err:  "output.elasticsearch.service_token and output.elasticsearch.service_token_env can not be both set",
+		},
+		{
+			name: "missing file",
+			cfg:  "output.elasticsearch.service_token_file: " + filepath.Join(t.TempDir(), "missing"),
+			err:  "unable to read output.elasticsearch.service_token_file",
+		},
+		{
+			name: "missing environment variable",
+			cfg:  "output.elasticsearch.service_token_env: TEST_FLEET_SERVER_MISSING",
+			err:  "environment variable TEST_FLEET_SERVER_MISSING is not set",
+		},
+		{
+			name: "vault is not configured",
+			cfg:  "output.elasticsearch.service_token_vault: secret/data/fleet-server#token",
+			err:  "secrets.vault.address is not set",
+		},
+		{
+			name: "vault reference without a field",
+			cfg:  "secrets.vault.address: " + vault.URL + "\noutput.elasticsearch.service_token_vault: secret/data/fleet-server",
+			err:  "must be <path>#<field>",
+		},
+		{
+			name: "vault permission denied",
+			cfg:  "secrets.vault.address: " + vault.URL + "\noutput.elasticsearch.service_token_vault: secret/data/fleet-server#token",
+			err:  "status 403 permission denied",
+		},
+		{
+			name: "vault missing field",
+			cfg:  "secrets.vault: {address: " + vault.URL + ", token: vault-token}\noutput.elasticsearch.service_token_vault: secret/data/fleet-server#missing",
+			err:  "vault secret secret/data/fleet-server has no field missing",
+		},
+		{
+			name: "vault secrets section",
+			cfg:  "secrets.vault: {address: " + vault.URL + ", token_vault: secret/data/fleet-server#token}",
+			err:  "secrets.vault.address is not set",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			_, err = FromConfig(c)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestRefreshSecrets(t *testing.T) {
+	var token atomic.Value
+	token.Store("token-1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// a KV version 1 engine
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"token": token.Load()}})
+	}))
+	defer srv.Close()
+
+	c, err := yaml.NewConfig([]byte(`
+secrets.vault.address: `+srv.URL+`
+output.elasticsearch.service_token_vault: kv/fleet-server#token
+`), DefaultOptions...)
+	require.NoError(t, err)
+	cfg, err := FromConfig(c)
+	require.NoError(t, err)
+	require.NoError(t, cfg.LoadStandaloneAgentMetadata())
+	assert.Equal(t, "token-1", cfg.Output.Elasticsearch.ServiceToken)
+
+	refreshed, changed, err := cfg.RefreshSecrets(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Same(t, cfg, refreshed)
+
+	token.Store("token-2")
+	refreshed, changed, err = cfg.RefreshSecrets(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "token-2", refreshed.Output.Elasticsearch.ServiceToken)
+	assert.Equal(t, cfg.Fleet.Agent, refreshed.Fleet.Agent, "the standalone metadata is kept")
+	assert.Equal(t, "token-1", cfg.Output.Elasticsearch.ServiceToken, "the config is not changed")
+
+	t.Run("without secrets", func(t *testing.T) {
+		c, err := yaml.NewConfig([]byte(`output.elasticsearch.service_token: token`), DefaultOptions...)
+		require.NoError(t, err)
+		cfg, err := FromConfig(c)
+		require.NoError(t, err)
+		refreshed, changed, err := cfg.RefreshSecrets(context.Background())
+		require.NoError(t, err)
+		assert.False(t, changed)
+		assert.Same(t, cfg, refreshed)
+	})
+}
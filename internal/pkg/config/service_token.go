@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// ServiceTokenAuth is the configuration for agents that authenticate with Elasticsearch service tokens
+// instead of access API keys.
+type ServiceTokenAuth struct {
+	// Enabled allows agents to enroll with a service token.
+	Enabled bool `config:"enabled"`
+	// Principals are the service accounts, or service-account/token-name identities, allowed to enroll.
+	// Any service token is allowed when it is empty.
+	Principals []string `config:"principals"`
+}
+
+// Allowed returns true if an agent may enroll with the service token identity, "<service account>/<token name>".
+func (s ServiceTokenAuth) Allowed(identity string) bool {
+	if !s.Enabled || identity == "" {
+		return false
+	}
+	if len(s.Principals) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(s.Principals, func(p string) bool {
+		return p == identity || strings.HasPrefix(identity, p+"/")
+	})
+}
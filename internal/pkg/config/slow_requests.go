@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SlowRequests is the configuration of the warnings logged for the requests that take longer than a threshold.
+type SlowRequests struct {
+	// Threshold is the duration after which a request is logged as slow, 0 disables the logging.
+	Threshold time.Duration `config:"threshold"`
+	// Routes override the threshold for the routes by operation, like checkin or enroll; 0 disables the logging of a route.
+	Routes map[string]time.Duration `config:"routes"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *SlowRequests) Validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("slow request threshold must not be negative, got %v", c.Threshold)
+	}
+	for route, threshold := range c.Routes {
+		if threshold < 0 {
+			return fmt.Errorf("slow request threshold of route %s must not be negative, got %v", route, threshold)
+		}
+	}
+	return nil
+}
+
+// RouteThreshold returns the threshold of the route of operation op, 0 when the route is not logged.
+func (c *SlowRequests) RouteThreshold(op string) time.Duration {
+	if threshold, ok := c.Routes[op]; ok {
+		return threshold
+	}
+	return c.Threshold
+}
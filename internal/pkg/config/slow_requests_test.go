@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowRequests(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("threshold: 5s\nroutes:\n  checkin: 0\n  enroll: 1s\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var s SlowRequests
+	require.NoError(t, c.Unpack(&s, DefaultOptions...))
+
+	assert.Equal(t, 5*time.Second, s.RouteThreshold("acks"))
+	assert.Equal(t, time.Second, s.RouteThreshold("enroll"))
+	assert.Zero(t, s.RouteThreshold("checkin"), "a route can be left out")
+
+	c, err = yaml.NewConfig([]byte("routes:\n  acks: -1s\n"), DefaultOptions...)
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.Unpack(&s, DefaultOptions...), "route acks")
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultSpoolDirectoryName = "spool"
+	defaultSpoolMaxSize       = 100 * 1024 * 1024
+	defaultSpoolRetryInterval = 10 * time.Second
+)
+
+// Spool is the configuration of the write-ahead spool on the local disk. The checkin status updates, the acks and
+// the action results that can not be written because Elasticsearch is unreachable are kept in the spool and replayed
+// in order once it recovers, the writes that follow are spooled until the spool is empty.
+type Spool struct {
+	Enabled bool `config:"enabled"`
+	// Path is the directory of the spool.
+	// By default it is [executable directory]/spool
+	Path string `config:"path"`
+	// MaxSize is the largest size of the spool in bytes, the writes are failed once it is full.
+	MaxSize int64 `config:"max_size"`
+	// RetryInterval is the interval at which the replay of the spool is attempted.
+	RetryInterval time.Duration `config:"retry_interval"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Spool) InitDefaults() {
+	c.MaxSize = defaultSpoolMaxSize
+	c.RetryInterval = defaultSpoolRetryInterval
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Spool) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxSize <= 0 {
+		return errors.New("spool max_size must be positive")
+	}
+	if c.RetryInterval <= 0 {
+		return errors.New("spool retry_interval must be positive")
+	}
+	return nil
+}
+
+// Dir returns the directory of the spool.
+func (c *Spool) Dir() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return filepath.Join(retrieveExecutableDir(), defaultSpoolDirectoryName)
+}
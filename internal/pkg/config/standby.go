@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const defaultStandbyRetryAfter = 30 * time.Second
+
+// ServerStandby is the configuration of the standby mode. A server that starts in standby runs its monitors and
+// keeps its caches warm, but it redirects the requests of the agents or answers them with a retry hint, and it takes
+// no part in the leader election of the policies, until it is promoted by a configuration that disables the standby
+// mode or through the promote endpoint. A server that started active does not enter the standby mode on a reload.
+type ServerStandby struct {
+	Enabled bool `config:"enabled"`
+	// RedirectURL is the URL of the active fleet-server the requests of the agents are redirected to, with their
+	// path and query. The requests are answered with 503 Service Unavailable when it is empty.
+	RedirectURL string `config:"redirect_url"`
+	// RetryAfter is the delay the agents are asked to wait before they retry when their requests are not redirected.
+	RetryAfter time.Duration `config:"retry_after"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *ServerStandby) InitDefaults() {
+	c.RetryAfter = defaultStandbyRetryAfter
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ServerStandby) Validate() error {
+	if c.RetryAfter < 0 {
+		return fmt.Errorf("standby retry_after must not be negative, got %v", c.RetryAfter)
+	}
+	if c.RedirectURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.RedirectURL)
+	if err != nil {
+		return fmt.Errorf("standby redirect_url is not valid: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("standby redirect_url must be an absolute http or https URL, got %q", c.RedirectURL)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AllNamespaces in the namespaces of a principal allows it in every namespace.
+const AllNamespaces = "*"
+
+// Tenancy is the configuration of the namespaces of the API keys of the admin APIs. The agents, the actions, the
+// artifacts and the enrollment keys are always scoped by their namespaces, once enabled an admin API key only accesses
+// the documents of the namespaces of the principal owning it and the enrollments check that the policy is in the
+// namespaces of the enrollment key.
+type Tenancy struct {
+	// Enabled reads the owners of the API keys of the admin APIs, the service account of fleet-server must be allowed
+	// to read the API keys of the other users.
+	Enabled bool `config:"enabled"`
+	// Principals are the namespaces of the owners of the API keys. The owner of a key is set by Elasticsearch and is
+	// inherited by the keys it creates, the keys of the owners that are not listed are refused.
+	Principals []TenancyPrincipal `config:"principals"`
+}
+
+// TenancyPrincipal are the namespaces of the API keys owned by a user.
+type TenancyPrincipal struct {
+	Username string `config:"username"`
+	// Realm is the realm of the user, the user of any realm matches when it is not set.
+	Realm string `config:"realm"`
+	// Namespaces are the namespaces of the keys of the user, "*" allows them in every namespace and the APIs acting on
+	// the whole server.
+	Namespaces []string `config:"namespaces"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Tenancy) Validate() error {
+	principals := make(map[[2]string]bool, len(c.Principals))
+	for _, p := range c.Principals {
+		if p.Username == "" {
+			return errors.New("tenancy principals require a username")
+		}
+		if len(p.Namespaces) == 0 {
+			return fmt.Errorf("tenancy principal %s requires namespaces", p.Username)
+		}
+		key := [2]string{p.Username, p.Realm}
+		if principals[key] {
+			return fmt.Errorf("tenancy principal %s is set more than once", p.Username)
+		}
+		principals[key] = true
+	}
+	return nil
+}
+
+// Namespaces returns the namespaces of the API keys of the owner, a principal of the realm of the owner is preferred
+// to a principal of any realm. It returns false when the owner is not listed.
+func (c *Tenancy) Namespaces(username, realm string) ([]string, bool) {
+	var match *TenancyPrincipal
+	for i := range c.Principals {
+		p := &c.Principals[i]
+		if p.Username != username || (p.Realm != "" && p.Realm != realm) {
+			continue
+		}
+		if match == nil || p.Realm != "" {
+			match = p
+		}
+	}
+	if match == nil {
+		return nil, false
+	}
+	return match.Namespaces, true
+}
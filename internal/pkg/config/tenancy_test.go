@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenancy(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{name: "empty", cfg: "enabled: true"},
+		{name: "principals", cfg: "principals:\n  - username: ops\n    namespaces: [space]\n  - username: ops\n    realm: saml\n    namespaces: [\"*\"]"},
+		{name: "principal without username", cfg: "principals:\n  - namespaces: [space]", err: "tenancy principals require a username"},
+		{name: "principal without namespaces", cfg: "principals:\n  - username: ops", err: "tenancy principal ops requires namespaces"},
+		{name: "duplicate principal", cfg: "principals:\n  - username: ops\n    namespaces: [a]\n  - username: ops\n    namespaces: [b]", err: "tenancy principal ops is set more than once"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := yaml.NewConfig([]byte(tc.cfg), DefaultOptions...)
+			require.NoError(t, err)
+			var v Tenancy
+			err = c.Unpack(&v, DefaultOptions...)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTenancyNamespaces(t *testing.T) {
+	c := Tenancy{Principals: []TenancyPrincipal{
+		{Username: "ops", Namespaces: []string{"space"}},
+		{Username: "ops", Realm: "saml", Namespaces: []string{"other"}},
+	}}
+	namespaces, ok := c.Namespaces("ops", "native")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"space"}, namespaces)
+	namespaces, ok = c.Namespaces("ops", "saml")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"other"}, namespaces, "the principal of the realm is preferred")
+	_, ok = c.Namespaces("guest", "native")
+	assert.False(t, ok)
+}
@@ -5,6 +5,7 @@
 package config
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -19,6 +20,20 @@ type ServerTimeouts struct {
 	CheckinJitter    time.Duration `config:"checkin_jitter"`
 	CheckinMaxPoll   time.Duration `config:"checkin_max_poll"`
 	Drain            time.Duration `config:"drain"`
+	// Query is the timeout of the Elasticsearch searches of the request handlers that may be slow, like the agent
+	// searches and the agent lookup of the checkins sending a policy, 0 disables it.
+	Query time.Duration `config:"query"`
+	// Routes override the read and write timeouts for the routes by operation, like checkin or artifact.
+	Routes map[string]RouteTimeouts `config:"routes"`
+}
+
+// RouteTimeouts are the timeouts of the requests of a route, a timeout that is 0 is the timeout of the server.
+// The idle timeout is a timeout of the connections, it can not be set for a route.
+type RouteTimeouts struct {
+	// Read is how long from the start of the request to reading the entire body.
+	Read time.Duration `config:"read"`
+	// Write is how long from the start of the request to the end of the response write.
+	Write time.Duration `config:"write"`
 }
 
 // InitDefaults initializes the defaults for the configuration.
@@ -70,4 +85,33 @@ func (c *ServerTimeouts) InitDefaults() {
 	// It is used as a context timeout value for server.ShutDown(ctx).
 	// A long-poll checkin connection should immediately return with a 200 status and the same ackToken it was sent, the same as if the long-poll completed with no changes detected.
 	c.Drain = 10 * time.Second
+
+	// Query bounds the time a handler waits for a slow search, the search is cancelled on Elasticsearch at the timeout.
+	c.Query = 30 * time.Second
+}
+
+// Validate ensures that the configuration is valid.
+func (c *ServerTimeouts) Validate() error {
+	for route, t := range c.Routes {
+		if t.Read < 0 || t.Write < 0 {
+			return fmt.Errorf("timeouts of route %s must not be negative, got read %v and write %v", route, t.Read, t.Write)
+		}
+	}
+	return nil
+}
+
+// RouteWrite returns the write timeout of the route of operation op.
+func (c *ServerTimeouts) RouteWrite(op string) time.Duration {
+	if t := c.Routes[op]; t.Write > 0 {
+		return t.Write
+	}
+	return c.Write
+}
+
+// RouteRead returns the read timeout of the route of operation op.
+func (c *ServerTimeouts) RouteRead(op string) time.Duration {
+	if t := c.Routes[op]; t.Read > 0 {
+		return t.Read
+	}
+	return c.Read
 }
@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/go-ucfg/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTimeoutsRoutes(t *testing.T) {
+	c, err := yaml.NewConfig([]byte("routes:\n  checkin:\n    write: 70m\n  artifact:\n    read: 10s\n    write: 30s\n"), DefaultOptions...)
+	require.NoError(t, err)
+	var timeouts ServerTimeouts
+	timeouts.InitDefaults()
+	require.NoError(t, c.Unpack(&timeouts, DefaultOptions...))
+
+	assert.Equal(t, 70*time.Minute, timeouts.RouteWrite("checkin"))
+	assert.Equal(t, timeouts.Read, timeouts.RouteRead("checkin"))
+	assert.Equal(t, 30*time.Second, timeouts.RouteWrite("artifact"))
+	assert.Equal(t, 10*time.Second, timeouts.RouteRead("artifact"))
+	assert.Equal(t, timeouts.Write, timeouts.RouteWrite("acks"))
+
+	c, err = yaml.NewConfig([]byte("routes:\n  acks:\n    write: -1s\n"), DefaultOptions...)
+	require.NoError(t, err)
+	require.ErrorContains(t, c.Unpack(&timeouts, DefaultOptions...), "timeouts of route acks must not be negative")
+}
@@ -0,0 +1,208 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+const (
+	UploadStorageElasticsearch = "elasticsearch"
+	UploadStorageFilesystem    = "filesystem"
+	UploadStorageS3            = "s3"
+
+	defaultUploadMaxParallelChunks = 8
+
+	defaultUploadStorageS3Region  = "us-east-1"
+	defaultUploadStorageS3Timeout = 30 * time.Second
+)
+
+// Uploads is the configuration for the file upload endpoints.
+type Uploads struct {
+	Storage    UploadStorage    `config:"storage"`
+	Quotas     UploadQuotas     `config:"quotas"`
+	Encryption UploadEncryption `config:"encryption"`
+	// MaxParallelChunks is the number of chunks of an upload an agent may send at once to each fleet-server, 0 disables the limit.
+	MaxParallelChunks int `config:"max_parallel_chunks"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *Uploads) InitDefaults() {
+	c.MaxParallelChunks = defaultUploadMaxParallelChunks
+	c.Storage.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *Uploads) Validate() error {
+	if c.MaxParallelChunks < 0 {
+		return errors.New("uploads max_parallel_chunks must not be negative")
+	}
+	return nil
+}
+
+// UploadEncryption enables encryption of uploaded chunk contents at rest with a server managed AES-GCM key.
+type UploadEncryption struct {
+	Enabled bool `config:"enabled"`
+	// Key is the base64 encoded 16, 24 or 32 byte AES key, it is best provided through the keystore.
+	Key string `config:"key"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *UploadEncryption) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := c.DecodeKey()
+	return err
+}
+
+// DecodeKey returns the raw encryption key.
+func (c *UploadEncryption) DecodeKey() ([]byte, error) {
+	if c.Key == "" {
+		return nil, errors.New("upload encryption key is required")
+	}
+	key, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("upload encryption key must be base64 encoded: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("upload encryption key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+}
+
+// UploadQuotas limit the uploads an agent may start, a zero value disables the quota.
+type UploadQuotas struct {
+	// MaxConcurrent is the number of unfinished uploads an agent may have at once.
+	MaxConcurrent int `config:"max_concurrent"`
+	// MaxBytesPerDay is the total file size an agent may upload in a 24h window.
+	MaxBytesPerDay int64 `config:"max_bytes_per_day"`
+	// MaxFileSize is the largest file accepted for each integration, keyed by the upload src.
+	MaxFileSize map[string]int64 `config:"max_file_size"`
+}
+
+// Validate ensures that the configuration is valid.
+func (c *UploadQuotas) Validate() error {
+	if c.MaxConcurrent < 0 {
+		return errors.New("upload quotas max_concurrent must not be negative")
+	}
+	if c.MaxBytesPerDay < 0 {
+		return errors.New("upload quotas max_bytes_per_day must not be negative")
+	}
+	for src, size := range c.MaxFileSize {
+		if size < 0 {
+			return fmt.Errorf("upload quotas max_file_size for %q must not be negative", src)
+		}
+	}
+	return nil
+}
+
+// UploadStorage selects where the contents of uploaded file chunks are stored.
+// File and chunk metadata are always stored in Elasticsearch.
+type UploadStorage struct {
+	// Type is one of elasticsearch, filesystem or s3.
+	Type       string                  `config:"type"`
+	Filesystem FilesystemUploadStorage `config:"filesystem"`
+	S3         S3UploadStorage         `config:"s3"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *UploadStorage) InitDefaults() {
+	c.Type = UploadStorageElasticsearch
+	c.S3.InitDefaults()
+}
+
+// Validate ensures that the configuration is valid.
+func (c *UploadStorage) Validate() error {
+	switch c.Type {
+	case UploadStorageElasticsearch:
+		return nil
+	case UploadStorageFilesystem:
+		if c.Filesystem.Path == "" {
+			return errors.New("upload storage filesystem.path is required")
+		}
+		return nil
+	case UploadStorageS3:
+		return c.S3.validate()
+	default:
+		return fmt.Errorf("unknown upload storage type %q", c.Type)
+	}
+}
+
+// FilesystemUploadStorage stores chunk contents as files under Path.
+type FilesystemUploadStorage struct {
+	Path string `config:"path"`
+}
+
+// S3UploadStorage stores chunk contents as objects in an S3 compatible object store.
+type S3UploadStorage struct {
+	// Endpoint is the base URL of the object store, for example https://s3.us-east-1.amazonaws.com.
+	Endpoint        string `config:"endpoint"`
+	Bucket          string `config:"bucket"`
+	Region          string `config:"region"`
+	Prefix          string `config:"prefix"`
+	AccessKeyID     string `config:"access_key_id"`
+	SecretAccessKey string `config:"secret_access_key"`
+	// PathStyle addresses the bucket as part of the path instead of the host name, most self hosted stores require it.
+	PathStyle bool              `config:"path_style"`
+	TLS       *tlscommon.Config `config:"ssl"`
+	Timeout   time.Duration     `config:"timeout"`
+}
+
+// InitDefaults initializes the defaults for the configuration.
+func (c *S3UploadStorage) InitDefaults() {
+	c.Region = defaultUploadStorageS3Region
+	c.Timeout = defaultUploadStorageS3Timeout
+}
+
+func (c *S3UploadStorage) validate() error {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid upload storage s3 endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("upload storage s3 endpoint must use http or https, got %q", c.Endpoint)
+	}
+	if c.Bucket == "" {
+		return errors.New("upload storage s3.bucket is required")
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return errors.New("upload storage s3.access_key_id and s3.secret_access_key are required")
+	}
+	if c.TLS != nil && c.TLS.IsEnabled() {
+		if _, err := tlscommon.LoadTLSConfig(c.TLS); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTPClient returns a client that is used to communicate with the object store.
+func (c *S3UploadStorage) HTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // DefaultTransport is always a *http.Transport
+	if c.TLS != nil && c.TLS.IsEnabled() {
+		tls, err := tlscommon.LoadTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tls.ToConfig()
+	}
+	transport.TLSClientConfig = fips.Restrict(transport.TLSClientConfig)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}, nil
+}
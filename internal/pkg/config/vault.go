@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+)
+
+const vaultMaxResponseSize = 1 << 20
+
+// vaultClient reads the secrets of the KV engines of a Vault server.
+type vaultClient struct {
+	cfg    Vault
+	client *http.Client
+}
+
+// newVaultClient returns the client of the configuration, nil when no Vault server is configured. The client is built
+// before the server sets the FIPS mode, its TLS configuration is restricted with the mode of the configuration.
+func newVaultClient(cfg Vault, fipsMode bool) (*vaultClient, error) {
+	if cfg.Address == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets.vault.address: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("secrets.vault.address must use http or https, got %q", cfg.Address)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // DefaultTransport is always a *http.Transport
+	if cfg.TLS != nil && cfg.TLS.IsEnabled() {
+		tls, err := tlscommon.LoadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tls.ToConfig()
+	}
+	transport.TLSClientConfig = fips.RestrictMode(transport.TLSClientConfig, fipsMode)
+	return &vaultClient{
+		cfg:    cfg,
+		client: &http.Client{Transport: transport, Timeout: cfg.Timeout},
+	}, nil
+}
+
+// read returns the fields of the secret at path. The secrets of the KV version 2 engines are nested in the
+// data of the response with their metadata, like the secrets of secret/data/<name>.
+func (c *vaultClient) read(ctx context.Context, path string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.cfg.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", c.cfg.Token)
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []string               `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, vaultMaxResponseSize)).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("unable to decode vault secret %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault secret %s: status %d %s", path, resp.StatusCode, strings.Join(body.Errors, ", "))
+	}
+	if data, ok := body.Data["data"].(map[string]interface{}); ok {
+		if _, ok := body.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return body.Data, nil
+}
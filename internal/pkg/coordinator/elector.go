@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+// Elector is the backend of the leader election of the policies. A policy is led by the server that holds its lease,
+// the lease of a leader that does not renew it within the leader interval expires and any server can take it.
+type Elector interface {
+	// Name returns the name of the backend.
+	Name() string
+
+	// Leaders returns the leases of the policies by policy ID, the policies without a leader are missing.
+	Leaders(ctx context.Context, policyIDs []string) (map[string]model.PolicyLeader, error)
+
+	// Take takes or renews the lease of the policy for the server.
+	Take(ctx context.Context, policyID, serverID, version string) error
+
+	// Release expires the lease of the policy when the server holds it, so another server can take it at once.
+	Release(ctx context.Context, policyID, serverID string, leaderInterval time.Duration) error
+}
+
+// NewElector returns the elector of the backend of cfg. The options are the options of the Elasticsearch backend.
+func NewElector(cfg config.Coordinator, bulker bulk.Bulk, opt ...dl.Option) (Elector, error) {
+	switch cfg.Backend {
+	case config.ElectorElasticsearch, "":
+		return NewElasticsearchElector(bulker, opt...), nil
+	case config.ElectorKubernetes:
+		return NewKubernetesElector(cfg.Kubernetes)
+	default:
+		return nil, fmt.Errorf("unknown coordinator backend %q", cfg.Backend)
+	}
+}
+
+// esElector elects the leaders with the documents of the .fleet-policies-leader index.
+type esElector struct {
+	bulker bulk.Bulk
+	opt    []dl.Option
+}
+
+// NewElasticsearchElector returns the elector that stores the leases in the .fleet-policies-leader index.
+func NewElasticsearchElector(bulker bulk.Bulk, opt ...dl.Option) Elector {
+	return &esElector{bulker: bulker, opt: opt}
+}
+
+func (e *esElector) Name() string {
+	return config.ElectorElasticsearch
+}
+
+func (e *esElector) Leaders(ctx context.Context, policyIDs []string) (map[string]model.PolicyLeader, error) {
+	leaders, err := dl.SearchPolicyLeaders(ctx, e.bulker, policyIDs, e.opt...)
+	if err != nil {
+		return nil, err
+	}
+	if leaders == nil {
+		// the index does not exist yet
+		leaders = map[string]model.PolicyLeader{}
+	}
+	return leaders, nil
+}
+
+func (e *esElector) Take(ctx context.Context, policyID, serverID, version string) error {
+	return dl.TakePolicyLeadership(ctx, e.bulker, policyID, serverID, version, e.opt...)
+}
+
+func (e *esElector) Release(ctx context.Context, policyID, serverID string, leaderInterval time.Duration) error {
+	return dl.ReleasePolicyLeadership(ctx, e.bulker, policyID, serverID, leaderInterval, e.opt...)
+}
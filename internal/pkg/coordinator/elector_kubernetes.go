@@ -0,0 +1,314 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// leaseLabel marks the leases of the policies, so they are listed at once.
+	leaseLabel = "fleet.elastic.co/policy-leader"
+	// leasePolicyAnnotation and leaseVersionAnnotation are the policy of a lease and the version of its holder.
+	leasePolicyAnnotation  = "fleet.elastic.co/policy-id"
+	leaseVersionAnnotation = "fleet.elastic.co/server-version"
+
+	// leaseTimeFormat is the format of the MicroTime of the leases.
+	leaseTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var (
+	// ErrLeaseHeld is returned when the lease of a policy is held by another server and has not expired.
+	ErrLeaseHeld = errors.New("lease is held by another server")
+	// ErrLeaseConflict is returned when the lease of a policy changed since it was read.
+	ErrLeaseConflict = errors.New("lease was updated concurrently")
+
+	errLeaseNotFound = errors.New("lease not found")
+
+	leaseNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+)
+
+// lease is a coordination.k8s.io/v1 Lease.
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       *string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds *int32  `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *string `json:"acquireTime,omitempty"`
+	RenewTime            *string `json:"renewTime,omitempty"`
+	LeaseTransitions     *int32  `json:"leaseTransitions,omitempty"`
+}
+
+type leaseList struct {
+	Items []lease `json:"items"`
+}
+
+// k8sElector elects the leaders with the Kubernetes Leases of a namespace, one lease by policy. The leader election
+// keeps working while Elasticsearch rejects the writes.
+type k8sElector struct {
+	client    *http.Client
+	baseURL   string
+	tokenFile string // the token is read on each request as it is rotated, no token is sent when empty
+	namespace string
+	prefix    string
+	duration  time.Duration
+}
+
+// NewKubernetesElector returns the elector that stores the leases as Kubernetes Leases, with the service account of
+// the pod of the server.
+func NewKubernetesElector(cfg config.CoordinatorKubernetes) (Elector, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("the kubernetes coordinator backend requires fleet-server to run in a pod")
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("the service account CA has no certificate")
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		ns, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the namespace of the pod: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // the default transport is an *http.Transport
+	transport.TLSClientConfig = fips.Restrict(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	return &k8sElector{
+		client:    &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		tokenFile: filepath.Join(serviceAccountDir, "token"),
+		namespace: namespace,
+		prefix:    cfg.LeasePrefix,
+		duration:  defaultLeaderInterval,
+	}, nil
+}
+
+func (e *k8sElector) Name() string {
+	return config.ElectorKubernetes
+}
+
+func (e *k8sElector) Leaders(ctx context.Context, policyIDs []string) (map[string]model.PolicyLeader, error) {
+	wanted := make(map[string]bool, len(policyIDs))
+	for _, id := range policyIDs {
+		wanted[id] = true
+	}
+	var list leaseList
+	if err := e.do(ctx, http.MethodGet, e.leasesPath()+"?labelSelector="+url.QueryEscape(leaseLabel), nil, &list); err != nil {
+		return nil, fmt.Errorf("unable to list the leases: %w", err)
+	}
+
+	leaders := make(map[string]model.PolicyLeader, len(list.Items))
+	for _, l := range list.Items {
+		policyID := l.Metadata.Annotations[leasePolicyAnnotation]
+		if !wanted[policyID] || l.Spec.HolderIdentity == nil || *l.Spec.HolderIdentity == "" || l.Spec.RenewTime == nil {
+			continue
+		}
+		t, err := time.Parse(leaseTimeFormat, *l.Spec.RenewTime)
+		if err != nil {
+			return nil, fmt.Errorf("lease %s: %w", l.Metadata.Name, err)
+		}
+		leader := model.PolicyLeader{Server: &model.ServerMetadata{
+			ID:      *l.Spec.HolderIdentity,
+			Version: l.Metadata.Annotations[leaseVersionAnnotation],
+		}}
+		leader.SetTime(t)
+		leaders[policyID] = leader
+	}
+	return leaders, nil
+}
+
+func (e *k8sElector) Take(ctx context.Context, policyID, serverID, version string) error {
+	now := time.Now().UTC()
+	l, err := e.get(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	create := l == nil
+	if create {
+		l = &lease{Metadata: leaseMetadata{Name: e.leaseName(policyID), Namespace: e.namespace}}
+	} else if holder := l.Spec.HolderIdentity; holder != nil && *holder != "" && *holder != serverID && !e.expired(l, now) {
+		return ErrLeaseHeld
+	}
+
+	l.APIVersion, l.Kind = "coordination.k8s.io/v1", "Lease"
+	if l.Metadata.Labels == nil {
+		l.Metadata.Labels = map[string]string{}
+	}
+	l.Metadata.Labels[leaseLabel] = "true"
+	if l.Metadata.Annotations == nil {
+		l.Metadata.Annotations = map[string]string{}
+	}
+	l.Metadata.Annotations[leasePolicyAnnotation] = policyID
+	l.Metadata.Annotations[leaseVersionAnnotation] = version
+
+	renew := now.Format(leaseTimeFormat)
+	if l.Spec.HolderIdentity == nil || *l.Spec.HolderIdentity != serverID {
+		var transitions int32
+		if l.Spec.LeaseTransitions != nil {
+			transitions = *l.Spec.LeaseTransitions + 1
+		}
+		l.Spec.HolderIdentity = &serverID
+		l.Spec.AcquireTime = &renew
+		l.Spec.LeaseTransitions = &transitions
+	}
+	duration := int32(e.duration.Seconds())
+	l.Spec.LeaseDurationSeconds = &duration
+	l.Spec.RenewTime = &renew
+
+	if create {
+		return e.do(ctx, http.MethodPost, e.leasesPath(), l, nil)
+	}
+	return e.do(ctx, http.MethodPut, e.leasesPath()+"/"+l.Metadata.Name, l, nil)
+}
+
+func (e *k8sElector) Release(ctx context.Context, policyID, serverID string, _ time.Duration) error {
+	l, err := e.get(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	if l == nil || l.Spec.HolderIdentity == nil || *l.Spec.HolderIdentity != serverID {
+		// not leader anymore; nothing to do
+		return nil
+	}
+	l.Spec.HolderIdentity = nil
+	err = e.do(ctx, http.MethodPut, e.leasesPath()+"/"+l.Metadata.Name, l, nil)
+	if errors.Is(err, ErrLeaseConflict) {
+		// another leader took over; nothing to worry about
+		return nil
+	}
+	return err
+}
+
+// get returns the lease of the policy, nil when it does not exist.
+func (e *k8sElector) get(ctx context.Context, policyID string) (*lease, error) {
+	var l lease
+	err := e.do(ctx, http.MethodGet, e.leasesPath()+"/"+e.leaseName(policyID), nil, &l)
+	if errors.Is(err, errLeaseNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the lease of policy %s: %w", policyID, err)
+	}
+	return &l, nil
+}
+
+// expired returns true when the holder of the lease did not renew it within its duration.
+func (e *k8sElector) expired(l *lease, now time.Time) bool {
+	if l.Spec.RenewTime == nil {
+		return true
+	}
+	t, err := time.Parse(leaseTimeFormat, *l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	d := e.duration
+	if l.Spec.LeaseDurationSeconds != nil {
+		d = time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return now.Sub(t) > d
+}
+
+func (e *k8sElector) leasesPath() string {
+	return "/apis/coordination.k8s.io/v1/namespaces/" + url.PathEscape(e.namespace) + "/leases"
+}
+
+// leaseName returns the name of the lease of the policy, the policy ID when it is a valid name or its hash.
+func (e *k8sElector) leaseName(policyID string) string {
+	name := e.prefix + "-" + policyID
+	if len(name) <= 253 && leaseNameRe.MatchString(name) {
+		return name
+	}
+	h := sha256.Sum256([]byte(policyID))
+	return e.prefix + "-" + hex.EncodeToString(h[:10])
+}
+
+// do sends a request to the Kubernetes API, the response is decoded in out when it is not nil.
+func (e *k8sElector) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if e.tokenFile != "" {
+		token, err := os.ReadFile(e.tokenFile)
+		if err != nil {
+			return fmt.Errorf("unable to read the service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errLeaseNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrLeaseConflict
+	case resp.StatusCode >= http.StatusBadRequest:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubernetes API %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLeasesPath         = "/apis/coordination.k8s.io/v1/namespaces/fleet/leases"
+	defaultTestLeasePrefix = "fleet-policy-leader"
+)
+
+// fakeLeases is the leases API of a namespace, the updates of a stale resource version are rejected.
+type fakeLeases struct {
+	mut     sync.Mutex
+	version int
+	leases  map[string]lease
+}
+
+func (f *fakeLeases) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, testLeasesPath), "/")
+
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		var list leaseList
+		for _, l := range f.leases {
+			if _, ok := l.Metadata.Labels[r.URL.Query().Get("labelSelector")]; ok {
+				list.Items = append(list.Items, l)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodGet:
+		l, ok := f.leases[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(l)
+	case r.Method == http.MethodPost, r.Method == http.MethodPut:
+		var l lease
+		if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cur, exists := f.leases[l.Metadata.Name]
+		if (r.Method == http.MethodPost && exists) || (r.Method == http.MethodPut && (!exists || cur.Metadata.ResourceVersion != l.Metadata.ResourceVersion)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.version++
+		l.Metadata.ResourceVersion = strconv.Itoa(f.version)
+		f.leases[l.Metadata.Name] = l
+		_ = json.NewEncoder(w).Encode(l)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestKubernetesElector(t *testing.T) (*k8sElector, *fakeLeases) {
+	t.Helper()
+	leases := &fakeLeases{leases: map[string]lease{}}
+	srv := httptest.NewServer(leases)
+	t.Cleanup(srv.Close)
+	return &k8sElector{
+		client:    srv.Client(),
+		baseURL:   srv.URL,
+		namespace: "fleet",
+		prefix:    defaultTestLeasePrefix,
+		duration:  defaultLeaderInterval,
+	}, leases
+}
+
+func TestKubernetesElector(t *testing.T) {
+	ctx := context.Background()
+	e, leases := newTestKubernetesElector(t)
+
+	leaders, err := e.Leaders(ctx, []string{"policy-1"})
+	require.NoError(t, err)
+	assert.Empty(t, leaders, "no lease was taken yet")
+
+	require.NoError(t, e.Take(ctx, "policy-1", "server-1", "8.15.0"))
+	require.NoError(t, e.Take(ctx, "policy-1", "server-1", "8.15.0"), "the holder renews its lease")
+	assert.ErrorIs(t, e.Take(ctx, "policy-1", "server-2", "8.15.0"), ErrLeaseHeld)
+
+	leaders, err = e.Leaders(ctx, []string{"policy-1", "policy-2"})
+	require.NoError(t, err)
+	require.Len(t, leaders, 1)
+	leader := leaders["policy-1"]
+	assert.Equal(t, "server-1", leader.Server.ID)
+	assert.Equal(t, "8.15.0", leader.Server.Version)
+	held, err := leader.Time()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), held, time.Minute)
+
+	require.NoError(t, e.Release(ctx, "policy-1", "server-2", defaultLeaderInterval), "a server that does not hold the lease does not release it")
+	leaders, err = e.Leaders(ctx, []string{"policy-1"})
+	require.NoError(t, err)
+	assert.Len(t, leaders, 1)
+
+	require.NoError(t, e.Release(ctx, "policy-1", "server-1", defaultLeaderInterval))
+	leaders, err = e.Leaders(ctx, []string{"policy-1"})
+	require.NoError(t, err)
+	assert.Empty(t, leaders, "a released lease has no leader")
+
+	require.NoError(t, e.Take(ctx, "policy-1", "server-2", "8.15.0"), "a released lease is taken at once")
+	l := leases.leases[defaultTestLeasePrefix+"-policy-1"]
+	assert.Equal(t, "server-2", *l.Spec.HolderIdentity)
+	assert.Equal(t, int32(1), *l.Spec.LeaseTransitions)
+}
+
+func TestKubernetesElectorExpired(t *testing.T) {
+	ctx := context.Background()
+	e, leases := newTestKubernetesElector(t)
+
+	require.NoError(t, e.Take(ctx, "policy-1", "server-1", "8.15.0"))
+	name := defaultTestLeasePrefix + "-policy-1"
+	l := leases.leases[name]
+	renew := time.Now().Add(-2 * defaultLeaderInterval).Format(leaseTimeFormat)
+	l.Spec.RenewTime = &renew
+	leases.leases[name] = l
+
+	require.NoError(t, e.Take(ctx, "policy-1", "server-2", "8.15.0"), "an expired lease is taken over")
+	assert.Equal(t, "server-2", *leases.leases[name].Spec.HolderIdentity)
+}
+
+func TestKubernetesElectorLeaseName(t *testing.T) {
+	e := &k8sElector{prefix: defaultTestLeasePrefix}
+	assert.Equal(t, "fleet-policy-leader-2e4f6c2a-fleet-server-policy", e.leaseName("2e4f6c2a-fleet-server-policy"))
+
+	name := e.leaseName("Policy_With Invalid Chars")
+	assert.Regexp(t, leaseNameRe, name)
+	assert.NotEqual(t, name, e.leaseName("Other_Policy"))
+}
@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+// LeaderState is the state of the leadership of a policy.
+type LeaderState string
+
+const (
+	// LeaderHeld is the state of a policy whose leader renews its lease.
+	LeaderHeld LeaderState = "held"
+	// LeaderExpired is the state of a policy whose leader did not renew its lease, any server can take it.
+	LeaderExpired LeaderState = "expired"
+	// LeaderVacant is the state of a policy no server ever led.
+	LeaderVacant LeaderState = "vacant"
+)
+
+// PolicyLeadership is the leader election state of the latest revision of a policy.
+type PolicyLeadership struct {
+	PolicyID       string
+	RevisionIdx    int64
+	CoordinatorIdx int64
+	State          LeaderState
+	// Leader is the server that led the policy last, nil when the policy is vacant.
+	Leader *model.ServerMetadata
+	// HeldAt is when the leader last took or renewed the leadership.
+	HeldAt time.Time
+	// ExpiresAt is when the leadership expires if the leader does not renew it.
+	ExpiresAt time.Time
+}
+
+// PolicyLeaderships returns the leader election state of the policies at now, ordered by policy ID. The leaders are
+// read from elector and their lease is the leader interval of the monitor. The options are the options of the search
+// of the policies.
+func PolicyLeaderships(ctx context.Context, bulker bulk.Bulk, elector Elector, now time.Time, opt ...dl.Option) ([]PolicyLeadership, error) {
+	policies, err := dl.QueryLatestPolicies(ctx, bulker, opt...)
+	if err != nil {
+		if errors.Is(err, es.ErrIndexNotFound) {
+			return []PolicyLeadership{}, nil
+		}
+		return nil, fmt.Errorf("encountered error while querying policies: %w", err)
+	}
+	leaders := map[string]model.PolicyLeader{}
+	if len(policies) > 0 {
+		ids := make([]string, len(policies))
+		for i, p := range policies {
+			ids[i] = p.PolicyID
+		}
+		leaders, err = elector.Leaders(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("encountered error while fetching policy leaders: %w", err)
+		}
+	}
+
+	res := make([]PolicyLeadership, 0, len(policies))
+	for _, p := range policies {
+		l := PolicyLeadership{
+			PolicyID:       p.PolicyID,
+			RevisionIdx:    p.RevisionIdx,
+			CoordinatorIdx: p.CoordinatorIdx,
+			State:          LeaderVacant,
+		}
+		if leader, ok := leaders[p.PolicyID]; ok && leader.Server != nil {
+			t, err := leader.Time()
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %w", p.PolicyID, err)
+			}
+			l.Leader = leader.Server
+			l.HeldAt = t
+			l.ExpiresAt = t.Add(defaultLeaderInterval)
+			// the monitor takes over the leaderships held for longer than the leader interval
+			l.State = LeaderHeld
+			if now.Sub(t) > defaultLeaderInterval {
+				l.State = LeaderExpired
+			}
+		}
+		res = append(res, l)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].PolicyID < res[j].PolicyID })
+	return res, nil
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import "sync"
+
+// Counter is a counter the leader election reports to.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// Gauge is set to the last value measured by the leader election.
+type Gauge interface {
+	Set(v float64)
+}
+
+// Metrics are the metrics the leader election of the policies reports to, nil entries are ignored.
+type Metrics struct {
+	// Acquired is the number of policy leaderships taken by the server, Lost the number it failed to renew and
+	// Released the number it released on shutdown or on a rebalance.
+	Acquired Counter
+	Lost     Counter
+	Released Counter
+	// LeaseAge is the age in seconds of the oldest lease of the policy leaders at the last check, a leader that does
+	// not renew its lease makes it grow past the leader interval.
+	LeaseAge Gauge
+}
+
+var (
+	metricsMut sync.RWMutex
+	metrics    Metrics
+)
+
+// SetMetrics sets the metrics the leader election reports to.
+func SetMetrics(m Metrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metrics = m
+}
+
+func getMetrics() Metrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return metrics
+}
+
+func addCount(c Counter, n int) {
+	if c != nil && n > 0 {
+		c.Add(uint64(n))
+	}
+}
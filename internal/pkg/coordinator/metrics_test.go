@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package coordinator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+type testGauge struct {
+	v float64
+}
+
+func (g *testGauge) Set(v float64) {
+	g.v = v
+}
+
+func TestReportLeaseAge(t *testing.T) {
+	g := &testGauge{v: -1}
+	SetMetrics(Metrics{LeaseAge: g})
+	t.Cleanup(func() { SetMetrics(Metrics{}) })
+
+	now := time.Now().UTC()
+	leader := func(held time.Time) model.PolicyLeader {
+		l := model.PolicyLeader{Server: &model.ServerMetadata{ID: "server"}}
+		l.SetTime(held)
+		return l
+	}
+
+	reportLeaseAge(map[string]model.PolicyLeader{}, now)
+	assert.Equal(t, float64(0), g.v)
+
+	reportLeaseAge(map[string]model.PolicyLeader{
+		"policy-1": leader(now.Add(-10 * time.Second)),
+		"policy-2": leader(now.Add(-45 * time.Second)),
+		"policy-3": {},
+	}, now)
+	assert.InDelta(t, 45, g.v, 0.001)
+}
@@ -11,7 +11,9 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -25,6 +27,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sleep"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
 )
 
 const (
@@ -32,12 +35,26 @@ const (
 	defaultLeaderInterval          = 30 * time.Second // become leader for at least 30 seconds
 	defaultMetadataInterval        = 5 * time.Minute  // update metadata every 5 minutes
 	defaultCoordinatorRestartDelay = 5 * time.Second  // delay in restarting coordinator on failure
+	defaultMemberInterval          = time.Minute      // shard the policies across the servers ensured within a minute
 )
 
 // Monitor monitors the leader election of policies and routes managed policies to the coordinator.
 type Monitor interface {
 	// Run runs the monitor.
 	Run(context.Context) error
+
+	// State returns the state of the leader election of the server.
+	State() State
+}
+
+// State is the state of the leader election of the server.
+type State struct {
+	// Leading is the number of policies the server leads.
+	Leading int
+	// Checked is the time leadership was last ensured, zero until the first check succeeds.
+	Checked time.Time
+	// Members is the number of servers the policies are sharded across, 0 when sharding is disabled.
+	Members int
 }
 
 type policyT struct {
@@ -60,6 +77,13 @@ type monitorT struct {
 	leaderInterval    time.Duration
 	metadataInterval  time.Duration
 	coordRestartDelay time.Duration
+	memberInterval    time.Duration
+
+	elector Elector // the Elasticsearch elector of the leaders index when nil
+
+	sharding config.CoordinatorSharding
+	ring     *hashRing // nil when sharding is disabled
+	members  int
 
 	serversIndex  string
 	policiesIndex string
@@ -70,11 +94,32 @@ type monitorT struct {
 
 	muPoliciesCanceller sync.Mutex
 	policiesCanceller   map[string]context.CancelFunc
+
+	state atomic.Pointer[State]
+}
+
+// MonitorOption configures optional behaviour of the coordinator policy monitor.
+type MonitorOption func(*monitorT)
+
+// WithSharding partitions the policies across the healthy servers as described by cfg.
+func WithSharding(cfg config.CoordinatorSharding) MonitorOption {
+	return func(m *monitorT) {
+		if cfg.Enabled && cfg.VirtualNodes > 0 {
+			m.sharding = cfg
+		}
+	}
+}
+
+// WithElector elects the leaders of the policies with e instead of the .fleet-policies-leader index.
+func WithElector(e Elector) MonitorOption {
+	return func(m *monitorT) {
+		m.elector = e
+	}
 }
 
 // NewMonitor creates a new coordinator policy monitor.
-func NewMonitor(fleet config.Fleet, version string, bulker bulk.Bulk, monitor monitor.Monitor, factory Factory) Monitor {
-	return &monitorT{
+func NewMonitor(fleet config.Fleet, version string, bulker bulk.Bulk, monitor monitor.Monitor, factory Factory, opts ...MonitorOption) Monitor {
+	m := &monitorT{
 		version:           version,
 		fleet:             fleet,
 		bulker:            bulker,
@@ -84,6 +129,7 @@ func NewMonitor(fleet config.Fleet, version string, bulker bulk.Bulk, monitor mo
 		leaderInterval:    defaultLeaderInterval,
 		metadataInterval:  defaultMetadataInterval,
 		coordRestartDelay: defaultCoordinatorRestartDelay,
+		memberInterval:    defaultMemberInterval,
 		serversIndex:      dl.FleetServers,
 		policiesIndex:     dl.FleetPolicies,
 		leadersIndex:      dl.FleetPoliciesLeader,
@@ -91,11 +137,28 @@ func NewMonitor(fleet config.Fleet, version string, bulker bulk.Bulk, monitor mo
 		policies:          make(map[string]policyT),
 		policiesCanceller: make(map[string]context.CancelFunc),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// State returns the state of the leader election of the server.
+func (m *monitorT) State() State {
+	if s := m.state.Load(); s != nil {
+		return *s
+	}
+	return State{}
 }
 
 // Run runs the monitor.
 func (m *monitorT) Run(ctx context.Context) (err error) {
 	log := zerolog.Ctx(ctx).With().Str("ctx", "policy leader manager").Logger()
+	if m.elector == nil {
+		m.elector = NewElasticsearchElector(m.bulker, dl.WithIndexName(m.leadersIndex))
+	}
+	log.Debug().Str("backend", m.elector.Name()).Msg("policy leader election backend")
+
 	// When ID of the Agent is not provided to Fleet Server then the Agent
 	// has not enrolled. The Fleet Server cannot become a leader until the
 	// Agent it is running under has been enrolled.
@@ -165,6 +228,7 @@ func (m *monitorT) Run(ctx context.Context) (err error) {
 		if err == nil && erroredOnLastRequest {
 			erroredOnLastRequest = false
 			log.Info().Msgf("Policy leader monitor successfully recovered after %d attempts", numFailedRequests)
+			timeline.Record(timeline.KindLeadership, "policy leader monitor recovered", map[string]string{"attempts": strconv.Itoa(numFailedRequests)})
 			numFailedRequests = 0
 		}
 	}
@@ -196,7 +260,7 @@ func (m *monitorT) handlePolicies(ctx context.Context, hits []es.HitT) error {
 					return err
 				}
 			}
-		} else {
+		} else if m.owns(policy.PolicyID) {
 			new = true
 		}
 	}
@@ -221,7 +285,16 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 	err := dl.EnsureServer(ctx, m.bulker, m.version, m.agentMetadata, m.hostMetadata, dl.WithIndexName(m.serversIndex))
 
 	if err != nil {
-		return fmt.Errorf("failed to check server status on Elasticsearch (%s): %w", m.hostMetadata.Name, err)
+		if m.elector.Name() == config.ElectorElasticsearch {
+			return fmt.Errorf("failed to check server status on Elasticsearch (%s): %w", m.hostMetadata.Name, err)
+		}
+		// the leases are not stored in Elasticsearch, the policies are still led while it rejects the writes
+		zerolog.Ctx(ctx).Warn().Err(err).Str("ctx", "policy leader manager").Msg("failed to check server status on Elasticsearch")
+	}
+
+	now := time.Now().UTC()
+	if err := m.updateRing(ctx, now); err != nil {
+		return err
 	}
 
 	// fetch current policies and leaders
@@ -230,6 +303,7 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 	if err != nil {
 		if errors.Is(err, es.ErrIndexNotFound) {
 			zerolog.Ctx(ctx).Debug().Str("ctx", "policy leader manager").Str("index", m.policiesIndex).Msg(es.ErrIndexNotFound.Error())
+			m.state.Store(&State{Leading: len(m.policies), Checked: now, Members: m.members})
 			return nil
 		}
 		return fmt.Errorf("encountered error while querying policies: %w", err)
@@ -239,7 +313,7 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 		for i, p := range policies {
 			ids[i] = p.PolicyID
 		}
-		leaders, err = dl.SearchPolicyLeaders(ctx, m.bulker, ids, dl.WithIndexName(m.leadersIndex))
+		leaders, err = m.elector.Leaders(ctx, ids)
 		if err != nil {
 			if !errors.Is(err, es.ErrIndexNotFound) {
 				return fmt.Errorf("encountered error while fetching policy leaders: %w", err)
@@ -247,25 +321,41 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 		}
 	}
 
-	// determine the policies that lead needs to be taken
+	reportLeaseAge(leaders, now)
+
+	// determine the policies that lead needs to be taken, and with sharding the policies this server
+	// leads but no longer owns
 	var lead []model.Policy
-	now := time.Now().UTC()
+	var release []string
 	for _, policy := range policies {
+		owned := m.owns(policy.PolicyID)
 		leader, ok := leaders[policy.PolicyID]
 		if !ok {
 			// new policy want to try to take leadership
-			lead = append(lead, policy)
+			if owned {
+				lead = append(lead, policy)
+			}
 			continue
 		}
 		t, err := leader.Time()
 		if err != nil {
 			return err
 		}
-		if now.Sub(t) > m.leaderInterval || leader.Server.ID == m.agentMetadata.ID {
-			// policy needs a new leader or already leader
+		if leader.Server.ID == m.agentMetadata.ID {
+			// already leader
+			if owned {
+				lead = append(lead, policy)
+			} else {
+				release = append(release, policy.PolicyID)
+			}
+			continue
+		}
+		if now.Sub(t) > m.leaderInterval && owned {
+			// policy needs a new leader
 			lead = append(lead, policy)
 		}
 	}
+	m.releasePolicies(ctx, release)
 
 	// take/keep leadership and start new coordinators
 	res := make(chan policyT)
@@ -278,10 +368,12 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 			}()
 
 			l := zerolog.Ctx(ctx).With().Str("ctx", "policy leader manager").Str(dl.FieldPolicyID, pt.id).Logger()
-			err := dl.TakePolicyLeadership(ctx, m.bulker, pt.id, m.agentMetadata.ID, m.version, dl.WithIndexName(m.leadersIndex))
+			err := m.elector.Take(ctx, pt.id, m.agentMetadata.ID, m.version)
 			if err != nil {
 				l.Warn().Err(err).Msg("monitor.ensureLeadership: failed to take ownership")
 				if pt.cord != nil {
+					timeline.Record(timeline.KindLeadership, "lost policy leadership", map[string]string{dl.FieldPolicyID: pt.id, "error": err.Error()})
+					addCount(getMetrics().Lost, 1)
 					pt.cord = nil
 				}
 				if pt.cordCanceller != nil {
@@ -294,7 +386,7 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 				cord, err := m.factory(p)
 				if err != nil {
 					l.Err(err).Msg("failed to start coordinator")
-					err = dl.ReleasePolicyLeadership(ctx, m.bulker, pt.id, m.agentMetadata.ID, m.leaderInterval, dl.WithIndexName(m.leadersIndex))
+					err = m.elector.Release(ctx, pt.id, m.agentMetadata.ID, m.leaderInterval)
 					if err != nil {
 						l.Err(err).Msg("failed to release policy leadership")
 					}
@@ -306,6 +398,8 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 				go runCoordinatorOutput(cordCtx, cord, m.bulker, l, m.policiesIndex)
 				pt.cord = cord
 				pt.cordCanceller = canceller
+				timeline.Record(timeline.KindLeadership, "took policy leadership", map[string]string{dl.FieldPolicyID: pt.id})
+				addCount(getMetrics().Acquired, 1)
 			} else {
 				err = pt.cord.Update(ctx, p)
 				if err != nil {
@@ -327,9 +421,84 @@ func (m *monitorT) ensureLeadership(ctx context.Context) error {
 			m.policies[r.id] = r
 		}
 	}
+	m.state.Store(&State{Leading: len(m.policies), Checked: now, Members: m.members})
+	return nil
+}
+
+// reportLeaseAge reports the age of the oldest lease of the leaders at now, 0 when no policy has a leader.
+func reportLeaseAge(leaders map[string]model.PolicyLeader, now time.Time) {
+	g := getMetrics().LeaseAge
+	if g == nil {
+		return
+	}
+	var age time.Duration
+	for _, leader := range leaders {
+		t, err := leader.Time()
+		if err != nil || leader.Server == nil {
+			continue
+		}
+		if d := now.Sub(t); d > age {
+			age = d
+		}
+	}
+	g.Set(age.Seconds())
+}
+
+// updateRing builds the hash ring of the servers that ensured their document within the member interval, it does
+// nothing when sharding is disabled. This server is always a member of the ring.
+func (m *monitorT) updateRing(ctx context.Context, now time.Time) error {
+	if !m.sharding.Enabled {
+		return nil
+	}
+	servers, err := dl.SearchServersSince(ctx, m.bulker, now.Add(-m.memberInterval), dl.WithIndexName(m.serversIndex))
+	if err != nil {
+		return fmt.Errorf("encountered error while searching servers: %w", err)
+	}
+	members := []string{m.agentMetadata.ID}
+	for _, s := range servers {
+		if s.Server != nil && s.Server.ID != "" {
+			members = append(members, s.Server.ID)
+		}
+	}
+	ring := newHashRing(members, m.sharding.VirtualNodes)
+	if ring.size() != m.members {
+		zerolog.Ctx(ctx).Info().Str("ctx", "policy leader manager").Int("members", ring.size()).Int("previous", m.members).Msg("policy sharding members changed, rebalancing policies")
+		timeline.Record(timeline.KindLeadership, "policy sharding members changed", map[string]string{"members": strconv.Itoa(ring.size())})
+		m.members = ring.size()
+	}
+	m.ring = ring
 	return nil
 }
 
+// owns returns true when the policy is owned by this server on the hash ring, or when sharding is disabled.
+func (m *monitorT) owns(policyID string) bool {
+	return m.ring == nil || m.ring.owner(policyID) == m.agentMetadata.ID
+}
+
+// releasePolicies stops the coordinators of the policies this server leads but no longer owns on the hash ring and
+// releases their leadership, so the servers that own them take them on their next check.
+func (m *monitorT) releasePolicies(ctx context.Context, ids []string) {
+	for _, id := range ids {
+		l := zerolog.Ctx(ctx).With().Str("ctx", "policy leader manager").Str(dl.FieldPolicyID, id).Logger()
+		if pt, ok := m.policies[id]; ok && pt.cordCanceller != nil {
+			pt.cordCanceller()
+		}
+		delete(m.policies, id)
+		m.muPoliciesCanceller.Lock()
+		delete(m.policiesCanceller, id)
+		m.muPoliciesCanceller.Unlock()
+
+		err := m.elector.Release(ctx, id, m.agentMetadata.ID, m.leaderInterval)
+		if err != nil {
+			l.Warn().Err(err).Msg("monitor.releasePolicies: failed to release leadership")
+			continue
+		}
+		l.Info().Msg("released policy leadership owned by another server")
+		timeline.Record(timeline.KindLeadership, "released policy leadership", map[string]string{dl.FieldPolicyID: id, "reason": "rebalance"})
+		addCount(getMetrics().Released, 1)
+	}
+}
+
 // releaseLeadership releases current leadership
 func (m *monitorT) releaseLeadership() {
 	var wg sync.WaitGroup
@@ -339,11 +508,13 @@ func (m *monitorT) releaseLeadership() {
 			if pt.cord != nil {
 				pt.cordCanceller()
 			}
+			timeline.Record(timeline.KindLeadership, "released policy leadership", map[string]string{dl.FieldPolicyID: pt.id})
+			addCount(getMetrics().Released, 1)
 			// uses a background context, because the context for the
 			// monitor will be cancelled at this point in the code
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
-			err := dl.ReleasePolicyLeadership(ctx, m.bulker, pt.id, m.agentMetadata.ID, m.leaderInterval, dl.WithIndexName(m.leadersIndex))
+			err := m.elector.Release(ctx, pt.id, m.agentMetadata.ID, m.leaderInterval)
 			if err != nil {
 				l := zerolog.Ctx(ctx).With().Str("ctx", "policy leader manager").Str(dl.FieldPolicyID, pt.id).Logger()
 				l.Warn().Err(err).Msg("monitor.releaseLeadership: failed to release leadership")
@@ -352,6 +523,7 @@ func (m *monitorT) releaseLeadership() {
 		}(pt)
 	}
 	wg.Wait()
+	m.state.Store(&State{})
 }
 
 func (m *monitorT) calcMetadata(ctx context.Context) {
@@ -413,6 +585,7 @@ func runCoordinator(ctx context.Context, cord Coordinator, l zerolog.Logger, d t
 		err := cord.Run(ctx)
 		if !errors.Is(err, context.Canceled) {
 			l.Err(err).Msg("Policy coordinator failed and stopped")
+			timeline.Record(timeline.KindLeadership, "policy coordinator failed", map[string]string{"coordinator": cord.Name(), "error": fmt.Sprint(err)})
 			if errors.Is(sleep.WithContext(ctx, d), context.Canceled) {
 				break
 			}
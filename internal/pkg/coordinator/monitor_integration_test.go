@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
@@ -102,6 +103,9 @@ func TestMonitorLeadership(t *testing.T) {
 	ensureLeadership(ctx, t, bulker, cfg, leadersIndex, policy2Id)
 	ensurePolicy(ctx, t, bulker, policiesIndex, policy1Id, 1, 1)
 	ensurePolicy(ctx, t, bulker, policiesIndex, policy2Id, 1, 1)
+	state := pm.State()
+	assert.Equal(t, 2, state.Leading)
+	assert.False(t, state.Checked.IsZero())
 
 	// stop the monitors
 	cn()
@@ -109,6 +113,7 @@ func TestMonitorLeadership(t *testing.T) {
 	require.NoError(t, err)
 
 	// ensure leadership was released
+	assert.Zero(t, pm.State().Leading)
 	ensureLeadershipReleased(bulkCtx, t, bulker, cfg, leadersIndex, policy1Id)
 	ensureLeadershipReleased(bulkCtx, t, bulker, cfg, leadersIndex, policy2Id)
 }
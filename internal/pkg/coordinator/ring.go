@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+type ringPoint struct {
+	hash   uint32
+	member string
+}
+
+// hashRing is a consistent hash ring of the servers. A key is owned by the server of the first point that follows
+// the hash of the key, so a server that joins or leaves the ring only takes or gives up the keys of its own points.
+type hashRing struct {
+	points  []ringPoint
+	members map[string]bool
+}
+
+// newHashRing returns the ring of the members with virtualNodes points each. The ring does not depend on the order
+// of the members.
+func newHashRing(members []string, virtualNodes int) *hashRing {
+	r := &hashRing{
+		points:  make([]ringPoint, 0, len(members)*virtualNodes),
+		members: make(map[string]bool, len(members)),
+	}
+	for _, m := range members {
+		if r.members[m] {
+			continue
+		}
+		r.members[m] = true
+		for i := 0; i < virtualNodes; i++ {
+			r.points = append(r.points, ringPoint{hash: ringHash(m + "#" + strconv.Itoa(i)), member: m})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].member < r.points[j].member
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+// owner returns the member that owns the key, empty when the ring has no members.
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].member
+}
+
+// size returns the number of members of the ring.
+func (r *hashRing) size() int {
+	return len(r.members)
+}
+
+// ringHash hashes s with FNV-1a and the murmur3 finalizer, the finalizer mixes the bits so the points of the similar
+// names of the virtual nodes spread over the ring.
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum32()
+	x ^= x >> 16
+	x *= 0x85ebca6b
+	x ^= x >> 13
+	x *= 0xc2b2ae35
+	x ^= x >> 16
+	return x
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package coordinator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ringKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("policy-%d", i)
+	}
+	return keys
+}
+
+func TestHashRingOwner(t *testing.T) {
+	assert.Empty(t, newHashRing(nil, 64).owner("policy-1"), "an empty ring owns nothing")
+
+	r := newHashRing([]string{"server-1", "server-2", "server-3", "server-2"}, 64)
+	assert.Equal(t, 3, r.size())
+	reordered := newHashRing([]string{"server-3", "server-1", "server-2"}, 64)
+
+	counts := map[string]int{}
+	for _, k := range ringKeys(3000) {
+		owner := r.owner(k)
+		require.Equal(t, owner, reordered.owner(k), "the ring does not depend on the order of the members")
+		counts[owner]++
+	}
+	require.Len(t, counts, 3)
+	for member, n := range counts {
+		assert.Greater(t, n, 800, "%s owns %d of 3000 keys", member, n)
+	}
+}
+
+func TestHashRingRebalance(t *testing.T) {
+	keys := ringKeys(3000)
+	before := newHashRing([]string{"server-1", "server-2", "server-3"}, 64)
+
+	t.Run("member joins", func(t *testing.T) {
+		after := newHashRing([]string{"server-1", "server-2", "server-3", "server-4"}, 64)
+		moved := 0
+		for _, k := range keys {
+			if o := after.owner(k); o != before.owner(k) {
+				assert.Equal(t, "server-4", o, "only the keys taken by the new member move")
+				moved++
+			}
+		}
+		assert.Greater(t, moved, 0)
+		assert.Less(t, moved, 1500)
+	})
+	t.Run("member leaves", func(t *testing.T) {
+		after := newHashRing([]string{"server-1", "server-3"}, 64)
+		for _, k := range keys {
+			if o := before.owner(k); o != "server-2" {
+				assert.Equal(t, o, after.owner(k), "only the keys of the leaving member move")
+			}
+		}
+	})
+}
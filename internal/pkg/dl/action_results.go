@@ -13,14 +13,16 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/spool"
 	"github.com/rs/zerolog"
 )
 
-func CreateActionResult(ctx context.Context, bulker bulk.Bulk, acr model.ActionResult) error {
-	return createActionResult(ctx, bulker, FleetActionsResults, acr)
+// CreateActionResult creates the action result document, it is held in the spool when elasticsearch is unavailable.
+func CreateActionResult(ctx context.Context, bulker bulk.Bulk, sp *spool.Spool, acr model.ActionResult) error {
+	return createActionResult(ctx, bulker, sp, FleetActionsResults, acr)
 }
 
-func createActionResult(ctx context.Context, bulker bulk.Bulk, index string, acr model.ActionResult) error {
+func createActionResult(ctx context.Context, bulker bulk.Bulk, sp *spool.Spool, index string, acr model.ActionResult) error {
 	if acr.Timestamp == "" {
 		acr.Timestamp = time.Now().UTC().Format(time.RFC3339)
 	}
@@ -30,7 +32,11 @@ func createActionResult(ctx context.Context, bulker bulk.Bulk, index string, acr
 	}
 
 	id := acr.ActionID + ":" + acr.AgentID
-	_, err = bulker.Create(ctx, index, id, body, bulk.WithRefresh())
+	op := spool.Op{Action: spool.ActionCreate, Index: index, ID: id, Body: body, Refresh: true}
+	err = sp.Write([]spool.Op{op}, func() error {
+		_, err := bulker.Create(ctx, index, id, body, bulk.WithRefresh())
+		return err
+	})
 	// ignoring version conflict in case the same action result is tried to be created multiple times (unique id with actionID and agentID)
 	if errors.Is(err, es.ErrElasticVersionConflict) {
 		zerolog.Ctx(ctx).Debug().Err(err).Str("id", id).Msg("action result already exists, ignoring")
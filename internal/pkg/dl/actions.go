@@ -102,6 +102,21 @@ func createBaseActionsQuery() (tmpl *dsl.Tmpl, root, filter *dsl.Node) {
 	return //nolint:nakedret // simple function
 }
 
+// CreateAction writes the action to the actions index, the agents of the action are sent it by the action monitor.
+// The action ID is the ID of the document.
+func CreateAction(ctx context.Context, bulker bulk.Bulk, action model.Action, opt ...Option) error {
+	o := newOption(FleetActions, opt...)
+	if action.Timestamp == "" {
+		action.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	body, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	_, err = bulker.Create(ctx, o.indexName, action.ActionID, body, bulk.WithRefresh())
+	return err
+}
+
 func FindAction(ctx context.Context, bulker bulk.Bulk, id string, opts ...Option) ([]model.Action, error) {
 	o := newOption(FleetActions, opts...)
 	return findActions(ctx, bulker, QueryAction, o.indexName, map[string]interface{}{
@@ -118,12 +133,26 @@ func FindAgentActions(ctx context.Context, bulker bulk.Bulk, minSeqNo, maxSeqNo
 		FieldAgents:     []string{agentID},
 	}
 
-	res, err := findActionsHits(ctx, bulker, QueryAgentActions, index, params, maxSeqNo)
-	if err != nil || res == nil {
-		return nil, err
+	var actions []model.Action
+	for {
+		res, err := findActionsHits(ctx, bulker, QueryAgentActions, index, params, maxSeqNo)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			return actions, nil
+		}
+		page, err := hitsToActions(res.Hits)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, page...)
+		if len(res.Hits) < maxAgentActionsFetchSize {
+			return actions, nil
+		}
+		// the actions are sorted by _seq_no, the next page is searched after the last action of this one
+		params[FieldSeqNo] = res.Hits[len(res.Hits)-1].SeqNo
 	}
-
-	return hitsToActions(res.Hits)
 }
 
 func DeleteExpiredForIndex(ctx context.Context, index string, bulker bulk.Bulk, cleanupIntervalAfterExpired string) (int64, error) {
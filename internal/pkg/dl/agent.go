@@ -5,10 +5,15 @@
 package dl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
@@ -18,12 +23,23 @@ import (
 
 const (
 	FieldAccessAPIKeyID = "access_api_key_id"
+	FieldAgentID        = "agent.id"
+	FieldEnrolledAt     = "enrolled_at"
+	FieldTags           = "tags"
+
+	fieldDefaultAPIKey        = "default_api_key" //nolint:gosec // this is the name of the field
+	fieldDefaultAPIKeyHistory = "default_api_key_history"
+	fieldOutputs              = "outputs"
 )
 
 var (
 	QueryAgentByAssessAPIKeyID = prepareAgentFindByAccessAPIKeyID()
 	QueryAgentByID             = prepareAgentFindByID()
 	QueryAgentByEnrollmentID   = prepareAgentFindByEnrollmentID()
+
+	tmplCountAgentsPendingRevision = prepareCountAgentsPendingRevision()
+	tmplDeleteTombstonedAgents     = prepareDeleteTombstonedAgents()
+	tmplFindInactiveAgents         = prepareFindInactiveAgents()
 )
 
 func prepareAgentFindByID() *dsl.Tmpl {
@@ -34,8 +50,17 @@ func prepareAgentFindByAccessAPIKeyID() *dsl.Tmpl {
 	return prepareAgentFindByField(FieldAccessAPIKeyID)
 }
 
+// prepareAgentFindByEnrollmentID finds the agent enrolled with an enrollment ID, the tombstones of the agents it
+// replaced are ignored.
 func prepareAgentFindByEnrollmentID() *dsl.Tmpl {
-	return prepareAgentFindByField(FieldEnrollmentID)
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Param("version", true)
+	query := root.Query().Bool()
+	query.Filter().Term(FieldEnrollmentID, tmpl.Bind(FieldEnrollmentID), nil)
+	query.MustNot().Exists(FieldTombstonedAt)
+	tmpl.MustResolve(root)
+	return tmpl
 }
 
 func prepareAgentFindByField(field string) *dsl.Tmpl {
@@ -67,7 +92,7 @@ func GetAgent(ctx context.Context, bulker bulk.Bulk, agentID string, opt ...Opti
 
 func FindAgent(ctx context.Context, bulker bulk.Bulk, tmpl *dsl.Tmpl, name string, v interface{}, opt ...Option) (model.Agent, error) {
 	o := newOption(FleetAgents, opt...)
-	res, err := SearchWithOneParam(ctx, bulker, tmpl, o.indexName, name, v)
+	res, err := SearchWithOneParam(ctx, bulker, tmpl, o.indexName, name, v, o.searchOpts()...)
 	if err != nil {
 		return model.Agent{}, fmt.Errorf("failed searching for agent: %w", err)
 	}
@@ -83,3 +108,277 @@ func FindAgent(ctx context.Context, bulker bulk.Bulk, tmpl *dsl.Tmpl, name strin
 
 	return agent, nil
 }
+
+func prepareCountAgentsPendingRevision() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(0)
+	root.Param("track_total_hits", true)
+	filter := root.Query().Bool().Filter()
+	filter.Term(FieldActive, true, nil)
+	filter.Term(FieldPolicyID, tmpl.Bind(FieldPolicyID), nil)
+	filter.Range(FieldPolicyRevisionIdx, dsl.WithRangeLT(tmpl.Bind(FieldRevisionIdx)))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// CountAgentsPendingRevision returns the number of active agents of the policy that run a revision lower than the passed one.
+func CountAgentsPendingRevision(ctx context.Context, bulker bulk.Bulk, policyID string, revision int64, opt ...Option) (int, error) {
+	o := newOption(FleetAgents, opt...)
+	res, err := Search(ctx, bulker, tmplCountAgentsPendingRevision, o.indexName, map[string]interface{}{
+		FieldPolicyID:    policyID,
+		FieldRevisionIdx: revision,
+	}, o.searchOpts(bulk.WithIgnoreUnavailble())...)
+	if err != nil {
+		return 0, fmt.Errorf("failed counting agents pending revision: %w", err)
+	}
+	return int(res.Total.Value), nil
+}
+
+// AgentFilter selects the agents returned by SearchAgents, a filter without fields matches all the agents.
+type AgentFilter struct {
+	// Active matches the active or the inactive agents when set.
+	Active *bool
+	// IDs matches the agents with one of the IDs.
+	IDs []string
+	// PolicyID matches the agents of the policy.
+	PolicyID string
+	// Tags matches the agents that have all the tags.
+	Tags []string
+	// Statuses matches the agents whose last checkin status is one of the statuses.
+	Statuses []string
+	// LocalMetadata matches the agents whose local_metadata fields, keyed by their dotted path, have the values.
+	LocalMetadata map[string]string
+	// RuntimeFields matches the agents whose runtime fields, declared in AgentRuntimeFields, have the values.
+	RuntimeFields map[string]interface{}
+	// Namespaces matches the agents in one of the namespaces, the agents without namespaces are in the default one.
+	Namespaces []string
+}
+
+// agentSearchFields are the fields of the agents returned by SearchAgents, the API keys of the agents are never returned.
+var agentSearchFields = []string{
+	FieldActive,
+	FieldAgent,
+	FieldEnrolledAt,
+	FieldLastCheckin,
+	FieldLastCheckinStatus,
+	FieldLocalMetadata,
+	FieldPolicyID,
+	FieldPolicyRevisionIdx,
+	FieldTags,
+}
+
+func prepareSearchAgents(filter AgentFilter, size int, searchAfter []interface{}) ([]byte, error) {
+	root := dsl.NewRoot()
+	root.Size(uint64(size))
+	root.Source().Includes(agentSearchFields...)
+	order := root.Sort()
+	order.SortOrder(FieldEnrolledAt, dsl.SortAscend)
+	order.SortOrder(FieldAgentID, dsl.SortAscend)
+	if len(searchAfter) > 0 {
+		root.SearchAfter(searchAfter...)
+	}
+
+	query := root.Query().Bool().Filter()
+	if filter.Active != nil {
+		query.Term(FieldActive, *filter.Active, nil)
+	}
+	if len(filter.IDs) > 0 {
+		query.Terms(FieldID, filter.IDs, nil)
+	}
+	if filter.PolicyID != "" {
+		query.Term(FieldPolicyID, filter.PolicyID, nil)
+	}
+	for _, tag := range filter.Tags {
+		query.Term(FieldTags, tag, nil)
+	}
+	if len(filter.Statuses) > 0 {
+		query.Terms(FieldLastCheckinStatus, filter.Statuses, nil)
+	}
+	keys := make([]string, 0, len(filter.LocalMetadata))
+	for k := range filter.LocalMetadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		query.Term(FieldLocalMetadata+"."+k, filter.LocalMetadata[k], nil)
+	}
+	if len(filter.Namespaces) > 0 {
+		namespacesQuery(query, filter.Namespaces)
+	}
+	if err := withRuntimeFields(root, query, AgentRuntimeFields, filter.RuntimeFields); err != nil {
+		return nil, err
+	}
+	return root.MarshalJSON()
+}
+
+// SearchAgents returns a page of at most size agents matching the filter, ordered by enrollment date.
+// The page starts after the sort values of searchAfter, the sort values of the last agent are returned when the page is
+// full to request the next page.
+func SearchAgents(ctx context.Context, bulker bulk.Bulk, filter AgentFilter, size int, searchAfter []interface{}, opt ...Option) ([]model.Agent, []interface{}, error) {
+	o := newOption(FleetAgents, opt...)
+	query, err := prepareSearchAgents(filter, size, searchAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := bulker.Search(ctx, o.indexName, query, o.searchOpts(bulk.WithIgnoreUnavailble())...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed searching for agents: %w", err)
+	}
+
+	agents := make([]model.Agent, len(res.Hits))
+	for i := range res.Hits {
+		if err := res.Hits[i].Unmarshal(&agents[i]); err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshal ES document into model.Agent: %w", err)
+		}
+	}
+	var next []interface{}
+	if len(res.Hits) > 0 && len(res.Hits) == size {
+		next = res.Hits[len(res.Hits)-1].Sort
+	}
+	return agents, next, nil
+}
+
+// ReassignAgents assigns the agents to the policy with a bulk update. The policy revision and coordinator indexes of
+// the agents are reset so they are sent the policy on their next checkin. It returns the IDs of the agents that could
+// not be updated.
+func ReassignAgents(ctx context.Context, bulker bulk.Bulk, ids []string, policyID string, opt ...Option) ([]string, error) {
+	o := newOption(FleetAgents, opt...)
+	body, err := bulk.UpdateFields{
+		FieldPolicyID:             policyID,
+		FieldPolicyRevisionIdx:    0,
+		FieldPolicyCoordinatorIdx: 0,
+		FieldUpdatedAt:            time.Now().UTC().Format(time.RFC3339),
+	}.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("reassign marshal: %w", err)
+	}
+	ops := make([]bulk.MultiOp, len(ids))
+	for i, id := range ids {
+		ops[i] = bulk.MultiOp{ID: id, Index: o.indexName, Body: body}
+	}
+
+	// The error of an item is returned with the items, only the items tell which agents failed.
+	items, err := bulker.MUpdate(ctx, ops)
+	if items == nil && err != nil {
+		return nil, fmt.Errorf("failed reassigning agents: %w", err)
+	}
+	var failed []string
+	for i := range items {
+		if items[i].Status < 200 || items[i].Status >= 300 {
+			failed = append(failed, ids[i])
+		}
+	}
+	return failed, nil
+}
+
+// TombstoneAgent turns the document of the agent into a tombstone: the agent is inactive, its enrollment history is
+// kept and the secrets of its API keys are removed from the document. The API keys of the agent are not invalidated,
+// it is up to the caller. The reason is the unenrolled reason of the agent, the reason it already has is kept when
+// it is empty.
+func TombstoneAgent(ctx context.Context, bulker bulk.Bulk, agent *model.Agent, reason string, opt ...Option) error {
+	o := newOption(FleetAgents, opt...)
+	now := time.Now().UTC().Format(time.RFC3339)
+	unenrolledAt := agent.UnenrolledAt
+	if unenrolledAt == "" {
+		unenrolledAt = now
+	}
+	doc := bulk.UpdateFields{
+		FieldActive:               false,
+		FieldUnenrolledAt:         unenrolledAt,
+		FieldTombstonedAt:         now,
+		FieldUpdatedAt:            now,
+		fieldDefaultAPIKey:        nil,
+		fieldDefaultAPIKeyHistory: nil,
+		fieldOutputs:              nil,
+	}
+	if reason != "" {
+		doc[FieldUnenrolledReason] = reason
+	}
+	body, err := doc.Marshal()
+	if err != nil {
+		return fmt.Errorf("tombstone marshal: %w", err)
+	}
+	if err := bulker.Update(ctx, o.indexName, agent.Id, body, bulk.WithRefresh(), bulk.WithRetryOnConflict(3)); err != nil {
+		return fmt.Errorf("tombstone update: %w", err)
+	}
+	return nil
+}
+
+func prepareDeleteTombstonedAgents() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Query().Bool().Filter().Range(FieldTombstonedAt, dsl.WithRangeLT(tmpl.Bind(FieldTombstonedAt)))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// DeleteTombstonedAgentsBefore deletes the agents that became a tombstone before the passed time.
+// It returns the number of agents deleted.
+func DeleteTombstonedAgentsBefore(ctx context.Context, bulker bulk.Bulk, before time.Time, opt ...Option) (int64, error) {
+	o := newOption(FleetAgents, opt...)
+	query, err := tmplDeleteTombstonedAgents.Render(map[string]interface{}{
+		FieldTombstonedAt: before.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	client := bulker.Client()
+	res, err := client.API.DeleteByQuery([]string{o.indexName}, bytes.NewReader(query),
+		client.API.DeleteByQuery.WithContext(ctx),
+		client.API.DeleteByQuery.WithConflicts("proceed"))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	var esres es.DeleteByQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&esres); err != nil {
+		return 0, err
+	}
+	if res.IsError() {
+		err = es.TranslateError(res.StatusCode, esres.Error)
+		if errors.Is(err, es.ErrIndexNotFound) {
+			zerolog.Ctx(ctx).Debug().Str("index", o.indexName).Msg(es.ErrIndexNotFound.Error())
+			err = nil
+		}
+		return 0, err
+	}
+	return esres.Deleted, nil
+}
+
+func prepareFindInactiveAgents() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	filter := root.Query().Bool().Filter()
+	filter.Term(FieldActive, true, nil)
+	filter.Term(FieldPolicyID, tmpl.Bind(FieldPolicyID), nil)
+	filter.Range(FieldLastCheckin, dsl.WithRangeLT(tmpl.Bind(FieldLastCheckin)))
+	root.Sort().SortOrder(FieldLastCheckin, dsl.SortAscend)
+	root.WithSize(tmpl.Bind(FieldSize))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// FindInactiveAgents returns at most size active agents of the policy whose last checkin is before the passed time,
+// the agents that have been inactive for the longest time first. The agents that never checked in are not returned.
+func FindInactiveAgents(ctx context.Context, bulker bulk.Bulk, policyID string, before time.Time, size int, opt ...Option) ([]model.Agent, error) {
+	o := newOption(FleetAgents, opt...)
+	res, err := Search(ctx, bulker, tmplFindInactiveAgents, o.indexName, map[string]interface{}{
+		FieldPolicyID:    policyID,
+		FieldLastCheckin: before.UTC().Format(time.RFC3339),
+		FieldSize:        size,
+	}, o.searchOpts(bulk.WithIgnoreUnavailble())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed searching for inactive agents: %w", err)
+	}
+
+	agents := make([]model.Agent, len(res.Hits))
+	for i := range res.Hits {
+		if err := res.Hits[i].Unmarshal(&agents[i]); err != nil {
+			return nil, fmt.Errorf("could not unmarshal ES document into model.Agent: %w", err)
+		}
+	}
+	return agents, nil
+}
@@ -5,14 +5,188 @@
 package dl
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 )
 
 func TestPrepareAgentFindByEnrollmentID(t *testing.T) {
 
 	tmpl := prepareAgentFindByEnrollmentID()
 	query, _ := tmpl.RenderOne(FieldEnrollmentID, "1")
-	assert.Equal(t, `{"query":{"bool":{"filter":[{"term":{"enrollment_id":"1"}}]}},"version":true}`, string(query[:]))
+	assert.Equal(t, `{"query":{"bool":{"filter":[{"term":{"enrollment_id":"1"}}],"must_not":{"exists":{"field":"tombstoned_at"}}}},"version":true}`, string(query[:]))
+}
+
+func TestPrepareCountAgentsPendingRevision(t *testing.T) {
+	query, err := tmplCountAgentsPendingRevision.Render(map[string]interface{}{
+		FieldPolicyID:    "policy-id",
+		FieldRevisionIdx: 3,
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[{"term":{"active":true}},{"term":{"policy_id":"policy-id"}},{"range":{"policy_revision_idx":{"lt":3}}}]}},"size":0,"track_total_hits":true}`, string(query))
+}
+
+func TestPrepareSearchAgents(t *testing.T) {
+	active := true
+	query, err := prepareSearchAgents(AgentFilter{
+		Active:        &active,
+		PolicyID:      "policy-id",
+		Tags:          []string{"linux", "prod"},
+		Statuses:      []string{"online", "degraded"},
+		LocalMetadata: map[string]string{"os.family": "debian", "elastic.agent.version": "8.14.0"},
+	}, 50, []interface{}{1717996800000, "agent-id"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"query":{"bool":{"filter":[
+			{"term":{"active":true}},
+			{"term":{"policy_id":"policy-id"}},
+			{"term":{"tags":"linux"}},
+			{"term":{"tags":"prod"}},
+			{"terms":{"last_checkin_status":["online","degraded"]}},
+			{"term":{"local_metadata.elastic.agent.version":"8.14.0"}},
+			{"term":{"local_metadata.os.family":"debian"}}
+		]}},
+		"_source":{"includes":["active","agent","enrolled_at","last_checkin","last_checkin_status","local_metadata","policy_id","policy_revision_idx","tags"]},
+		"search_after":[1717996800000,"agent-id"],
+		"size":50,
+		"sort":["enrolled_at","agent.id"]
+	}`, string(query))
+
+	query, err = prepareSearchAgents(AgentFilter{}, 10, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[]}},"_source":{"includes":["active","agent","enrolled_at","last_checkin","last_checkin_status","local_metadata","policy_id","policy_revision_idx","tags"]},"size":10,"sort":["enrolled_at","agent.id"]}`, string(query))
+
+	query, err = prepareSearchAgents(AgentFilter{RuntimeFields: map[string]interface{}{RuntimeFieldOSPlatform: "linux", RuntimeFieldAgentVersionMajor: 8}}, 10, nil)
+	require.NoError(t, err)
+	var body struct {
+		RuntimeMappings map[string]struct {
+			Type   string `json:"type"`
+			Script struct {
+				Source string `json:"source"`
+			} `json:"script"`
+		} `json:"runtime_mappings"`
+		Query json.RawMessage `json:"query"`
+	}
+	require.NoError(t, json.Unmarshal(query, &body))
+	require.Len(t, body.RuntimeMappings, 2)
+	assert.Equal(t, "long", body.RuntimeMappings[RuntimeFieldAgentVersionMajor].Type)
+	assert.Equal(t, AgentRuntimeFields[RuntimeFieldAgentVersionMajor].Script, body.RuntimeMappings[RuntimeFieldAgentVersionMajor].Script.Source)
+	assert.Equal(t, "keyword", body.RuntimeMappings[RuntimeFieldOSPlatform].Type)
+	assert.JSONEq(t, `{"bool":{"filter":[{"term":{"agent_version_major":8}},{"term":{"os_platform":"linux"}}]}}`, string(body.Query))
+
+	_, err = prepareSearchAgents(AgentFilter{RuntimeFields: map[string]interface{}{"unknown": "value"}}, 10, nil)
+	assert.ErrorIs(t, err, ErrUnknownRuntimeField)
+
+	query, err = prepareSearchAgents(AgentFilter{Namespaces: []string{"space"}}, 10, nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(query, &body))
+	assert.JSONEq(t, `{"bool":{"filter":[{"bool":{"should":[{"terms":{"namespaces":["space"]}}],"minimum_should_match":1}}]}}`, string(body.Query))
+
+	query, err = prepareSearchAgents(AgentFilter{Namespaces: []string{"space", DefaultNamespace}}, 10, nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(query, &body))
+	assert.JSONEq(t, `{"bool":{"filter":[{"bool":{"should":[
+		{"terms":{"namespaces":["space","default"]}},
+		{"bool":{"must_not":{"exists":{"field":"namespaces"}}}}
+	],"minimum_should_match":1}}]}}`, string(body.Query), "the agents without namespaces are in the default namespace")
+}
+
+func TestSearchAgents(t *testing.T) {
+	hits := []es.HitT{
+		{ID: "agent-1", Source: []byte(`{"policy_id":"policy-id","tags":["linux"]}`), Sort: []interface{}{float64(1), "agent-1"}},
+		{ID: "agent-2", Source: []byte(`{"policy_id":"policy-id"}`), Sort: []interface{}{float64(2), "agent-2"}},
+	}
+	for _, tc := range []struct {
+		name string
+		size int
+		next []interface{}
+	}{
+		{"full page", 2, []interface{}{float64(2), "agent-2"}},
+		{"last page", 3, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Search", mock.Anything, FleetAgents, mock.Anything, mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: hits}}, nil).Once()
+
+			agents, next, err := SearchAgents(context.Background(), bulker, AgentFilter{PolicyID: "policy-id"}, tc.size, nil)
+			require.NoError(t, err)
+			require.Len(t, agents, 2)
+			assert.Equal(t, "agent-1", agents[0].Id)
+			assert.Equal(t, []string{"linux"}, agents[0].Tags)
+			assert.Equal(t, tc.next, next)
+			bulker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTombstoneAgent(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		agent  model.Agent
+		reason string
+		expect func(t *testing.T, doc map[string]interface{})
+	}{{
+		name:   "active agent",
+		agent:  model.Agent{ESDocument: model.ESDocument{Id: "agent-id"}, Active: true},
+		reason: "replaced",
+		expect: func(t *testing.T, doc map[string]interface{}) {
+			assert.Equal(t, "replaced", doc[FieldUnenrolledReason])
+			assert.Equal(t, doc[FieldTombstonedAt], doc[FieldUnenrolledAt])
+		},
+	}, {
+		name:  "unenrolled agent",
+		agent: model.Agent{ESDocument: model.ESDocument{Id: "agent-id"}, UnenrolledAt: "2024-06-10T00:00:00Z"},
+		expect: func(t *testing.T, doc map[string]interface{}) {
+			assert.NotContains(t, doc, FieldUnenrolledReason, "the reason of the unenrollment is kept")
+			assert.Equal(t, "2024-06-10T00:00:00Z", doc[FieldUnenrolledAt])
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Update", mock.Anything, FleetAgents, "agent-id", mock.MatchedBy(func(body []byte) bool {
+				var update struct {
+					Doc map[string]interface{} `json:"doc"`
+				}
+				require.NoError(t, json.Unmarshal(body, &update))
+				doc := update.Doc
+				assert.Equal(t, false, doc[FieldActive])
+				assert.NotEmpty(t, doc[FieldTombstonedAt])
+				for _, field := range []string{fieldDefaultAPIKey, fieldDefaultAPIKeyHistory, fieldOutputs} {
+					assert.Contains(t, doc, field)
+					assert.Nil(t, doc[field], "the secrets of %s are removed", field)
+				}
+				tc.expect(t, doc)
+				return true
+			}), mock.Anything).Return(nil).Once()
+
+			require.NoError(t, TombstoneAgent(context.Background(), bulker, &tc.agent, tc.reason))
+			bulker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPrepareDeleteTombstonedAgents(t *testing.T) {
+	query, err := tmplDeleteTombstonedAgents.Render(map[string]interface{}{
+		FieldTombstonedAt: "2024-06-10T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[{"range":{"tombstoned_at":{"lt":"2024-06-10T00:00:00Z"}}}]}}}`, string(query))
+}
+
+func TestPrepareFindInactiveAgents(t *testing.T) {
+	query, err := tmplFindInactiveAgents.Render(map[string]interface{}{
+		FieldPolicyID:    "policy-id",
+		FieldLastCheckin: "2024-06-10T00:00:00Z",
+		FieldSize:        100,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[{"term":{"active":true}},{"term":{"policy_id":"policy-id"}},{"range":{"last_checkin":{"lt":"2024-06-10T00:00:00Z"}}}]}},"size":100,"sort":["last_checkin"]}`, string(query))
 }
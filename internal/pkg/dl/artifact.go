@@ -7,6 +7,7 @@ package dl
 import (
 	"context"
 	"encoding/json"
+	"slices"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
@@ -14,8 +15,12 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// DefaultNamespace is the namespace of documents that do not specify any namespaces.
+const DefaultNamespace = "default"
+
 var (
-	QueryArtifactTmpl = prepareQueryArtifact()
+	QueryArtifactTmpl        = prepareQueryArtifact()
+	QueryRecentArtifactsTmpl = prepareQueryRecentArtifacts()
 )
 
 func prepareQueryArtifact() *dsl.Tmpl {
@@ -29,7 +34,35 @@ func prepareQueryArtifact() *dsl.Tmpl {
 	return tmpl
 }
 
-func FindArtifact(ctx context.Context, bulker bulk.Bulk, ident, sha2 string) (*model.Artifact, error) {
+func prepareQueryRecentArtifacts() *dsl.Tmpl {
+	root := dsl.NewRoot()
+	tmpl := dsl.NewTmpl()
+
+	root.Sort().SortOrder(FieldCreated, dsl.SortDescend)
+	root.WithSize(tmpl.Bind(FieldSize))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// FindRecentArtifacts returns the size most recently created artifacts.
+func FindRecentArtifacts(ctx context.Context, bulker bulk.Bulk, size int) ([]model.Artifact, error) {
+	res, err := SearchWithOneParam(ctx, bulker, QueryRecentArtifactsTmpl, FleetArtifacts, FieldSize, size)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]model.Artifact, len(res.Hits))
+	for i, hit := range res.Hits {
+		if err := hit.Unmarshal(&artifacts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return artifacts, nil
+}
+
+// FindArtifact returns the artifact matching ident and sha2 that is accessible from the passed namespaces.
+// Artifacts that belong to other namespaces are ignored, so ErrNotFound is returned if the artifact only exists elsewhere.
+func FindArtifact(ctx context.Context, bulker bulk.Bulk, namespaces []string, ident, sha2 string) (*model.Artifact, error) {
 
 	params := map[string]interface{}{
 		FieldDecodedSha256: sha2,
@@ -48,24 +81,67 @@ func FindArtifact(ctx context.Context, bulker bulk.Bulk, ident, sha2 string) (*m
 		return nil, err
 	}
 
-	if len(res.Hits) == 0 {
+	// deserialize, the same artifact may be present in multiple namespaces
+	var found *model.Artifact
+	var used string
+	cnt := 0
+	for _, hit := range res.Hits {
+		var artifact model.Artifact
+		if err = json.Unmarshal(hit.Source, &artifact); err != nil {
+			return nil, err
+		}
+		if !InNamespaces(artifact.Namespaces, namespaces) {
+			continue
+		}
+		cnt++
+		if found == nil {
+			found = &artifact
+			used = hit.ID
+		}
+	}
+
+	if found == nil {
 		return nil, ErrNotFound
 	}
 
-	if len(res.Hits) > 1 {
+	if cnt > 1 {
 		zerolog.Ctx(ctx).Warn().
 			Str("ident", ident).
 			Str("sha2", sha2).
-			Int("cnt", len(res.Hits)).
-			Str("used", res.Hits[0].ID).
+			Int("cnt", cnt).
+			Strs("namespaces", namespaces).
+			Str("used", used).
 			Msg("Multiple HITS on artifact query.  Using the first returned.")
 	}
 
-	// deserialize
-	var artifact model.Artifact
-	if err = json.Unmarshal(res.Hits[0].Source, &artifact); err != nil {
-		return nil, err
+	return found, nil
+}
+
+// InNamespaces returns true if a document assigned to docNamespaces can be accessed from namespaces.
+// A document or requester without namespaces is treated as belonging to the default namespace.
+func InNamespaces(docNamespaces, namespaces []string) bool {
+	if len(docNamespaces) == 0 {
+		docNamespaces = []string{DefaultNamespace}
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{DefaultNamespace}
 	}
+	for _, ns := range namespaces {
+		if slices.Contains(docNamespaces, ns) {
+			return true
+		}
+	}
+	return false
+}
 
-	return &artifact, nil
+// namespacesQuery adds to the clauses of query a query matching the documents in one of the namespaces, the documents
+// without namespaces match the default namespace like with InNamespaces.
+func namespacesQuery(query *dsl.Node, namespaces []string) {
+	nsQuery := query.AppendBool()
+	should := nsQuery.Should()
+	should.Terms(FieldNamespaces, namespaces, nil)
+	if slices.Contains(namespaces, DefaultNamespace) {
+		should.AppendBool().MustNot().Exists(FieldNamespaces)
+	}
+	nsQuery.Param("minimum_should_match", 1)
 }
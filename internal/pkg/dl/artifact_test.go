@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package dl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestInNamespaces(t *testing.T) {
+	tests := []struct {
+		name          string
+		docNamespaces []string
+		namespaces    []string
+		expected      bool
+	}{
+		{"both unset", nil, nil, true},
+		{"doc unset requester default", nil, []string{"default"}, true},
+		{"doc default requester unset", []string{"default"}, nil, true},
+		{"doc unset requester other", nil, []string{"space1"}, false},
+		{"doc other requester unset", []string{"space1"}, nil, false},
+		{"match", []string{"space1", "space2"}, []string{"space2"}, true},
+		{"mismatch", []string{"space1"}, []string{"space2"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, InNamespaces(tc.docNamespaces, tc.namespaces))
+		})
+	}
+}
+
+func TestFindArtifactNamespaces(t *testing.T) {
+	hits := &es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{
+		{ID: "1", Source: []byte(`{"identifier":"ident","decoded_sha256":"sha2","namespaces":["space1"],"body":"","created":""}`)},
+		{ID: "2", Source: []byte(`{"identifier":"ident","decoded_sha256":"sha2","body":"","created":""}`)},
+	}}}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		err        error
+		expected   []string
+	}{
+		{"default namespace", nil, nil, nil},
+		{"space1 namespace", []string{"space1"}, nil, []string{"space1"}},
+		{"unknown namespace", []string{"space2"}, ErrNotFound, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Search", mock.Anything, FleetArtifacts, mock.Anything, mock.Anything).Return(hits, nil).Once()
+
+			artifact, err := FindArtifact(context.Background(), bulker, tc.namespaces, "ident", "sha2")
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, artifact.Namespaces)
+			}
+			bulker.AssertExpectations(t)
+		})
+	}
+}
@@ -4,8 +4,16 @@
 
 package dl
 
+import (
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+)
+
 type queryOption struct {
-	indexName string
+	indexName  string
+	timeout    time.Duration
+	preference string
 }
 
 // Option for the operation being made
@@ -20,6 +28,22 @@ func WithIndexName(name string) Option {
 	}
 }
 
+// WithTimeout sets the timeout of the searches of the operation, they return es.ErrTimeout once it expires.
+// It bounds the time a request handler waits for a slow query, 0 waits until the context of the operation is done.
+func WithTimeout(d time.Duration) Option {
+	return func(opt *queryOption) {
+		opt.timeout = d
+	}
+}
+
+// WithPreference sets the preference of the searches of the operation, a custom string like an agent ID sends the
+// searches with the same string to the same shard copies. It overrides the search preference of the configuration.
+func WithPreference(p string) Option {
+	return func(opt *queryOption) {
+		opt.preference = p
+	}
+}
+
 func newOption(defaultIndex string, opts ...Option) queryOption {
 	o := queryOption{indexName: defaultIndex}
 	for _, opt := range opts {
@@ -27,3 +51,14 @@ func newOption(defaultIndex string, opts ...Option) queryOption {
 	}
 	return o
 }
+
+// searchOpts returns the bulk options of the searches of the operation.
+func (o queryOption) searchOpts(opts ...bulk.Opt) []bulk.Opt {
+	if o.timeout > 0 {
+		opts = append(opts, bulk.WithTimeout(o.timeout))
+	}
+	if o.preference != "" {
+		opts = append(opts, bulk.WithPreference(o.preference))
+	}
+	return opts
+}
@@ -16,6 +16,7 @@ const (
 	FleetPolicies          = ".fleet-policies"
 	FleetPoliciesLeader    = ".fleet-policies-leader"
 	FleetServers           = ".fleet-servers"
+	FleetMigrations        = ".fleet-migrations"
 	FleetOutputHealth      = "logs-fleet_server.output_health-default"
 )
 
@@ -40,6 +41,7 @@ const (
 	FieldPolicyCoordinatorIdx          = "policy_coordinator_idx"
 	FieldPolicyID                      = "policy_id"
 	FieldPolicyOutputAPIKey            = "api_key"
+	FieldPolicyOutputAPIKeyCreatedAt   = "api_key_created_at"
 	FieldPolicyOutputAPIKeyID          = "api_key_id"
 	FieldPolicyOutputPermissionsHash   = "permissions_hash"
 	FieldPolicyOutputToRetireAPIKeyIDs = "to_retire_api_key_ids" //nolint:gosec // false positive
@@ -50,8 +52,10 @@ const (
 	FieldUnhealthyReason               = "unhealthy_reason"
 
 	FieldActive           = "active"
+	FieldNamespaces       = "namespaces"
 	FieldUpdatedAt        = "updated_at"
 	FieldUnenrolledAt     = "unenrolled_at"
+	FieldTombstonedAt     = "tombstoned_at"
 	FieldUpgradedAt       = "upgraded_at"
 	FieldUpgradeStartedAt = "upgrade_started_at"
 	FieldUpgradeStatus    = "upgrade_status"
@@ -59,6 +63,7 @@ const (
 
 	FieldDecodedSha256 = "decoded_sha256"
 	FieldIdentifier    = "identifier"
+	FieldCreated       = "created"
 	FieldSharedID      = "shared_id"
 	FieldEnrollmentID  = "enrollment_id"
 )
@@ -21,6 +21,7 @@ const (
 var (
 	QueryEnrollmentAPIKeyByID       = prepareFindActiveEnrollmentAPIKeyByID()
 	QueryEnrollmentAPIKeyByPolicyID = prepareFindActiveEnrollmentAPIKeyByPolicyID()
+	QueryActiveEnrollmentAPIKeys    = prepareFindActiveEnrollmentAPIKeys()
 )
 
 func prepareFindActiveEnrollmentAPIKeyByID() *dsl.Tmpl {
@@ -47,6 +48,18 @@ func prepareFindActiveEnrollmentAPIKeyByPolicyID() *dsl.Tmpl {
 	return tmpl
 }
 
+func prepareFindActiveEnrollmentAPIKeys() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+
+	root := dsl.NewRoot()
+	filter := root.Query().Bool().Filter()
+	filter.Term(FieldActive, true, nil)
+	root.WithSize(tmpl.Bind(FieldSize))
+
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
 func FindEnrollmentAPIKey(ctx context.Context, bulker bulk.Bulk, tmpl *dsl.Tmpl, field string, id string) (rec model.EnrollmentAPIKey, err error) {
 	return findEnrollmentAPIKey(ctx, bulker, FleetEnrollmentAPIKeys, tmpl, field, id)
 }
@@ -59,6 +72,9 @@ func findEnrollmentAPIKey(ctx context.Context, bulker bulk.Bulk, index string, t
 	}
 
 	sz := len(res.Hits)
+	if sz == 0 {
+		return rec, ErrNotFound
+	}
 	if sz != 1 {
 		return rec, fmt.Errorf("hit count mismatch %v", sz)
 	}
@@ -86,6 +102,22 @@ func findEnrollmentAPIKeys(ctx context.Context, bulker bulk.Bulk, index string,
 	return recs, nil
 }
 
+// FindActiveEnrollmentAPIKeys returns up to size active enrollment API keys.
+func FindActiveEnrollmentAPIKeys(ctx context.Context, bulker bulk.Bulk, size int) ([]model.EnrollmentAPIKey, error) {
+	res, err := SearchWithOneParam(ctx, bulker, QueryActiveEnrollmentAPIKeys, FleetEnrollmentAPIKeys, FieldSize, size)
+	if err != nil {
+		return nil, err
+	}
+
+	recs := make([]model.EnrollmentAPIKey, len(res.Hits))
+	for i := 0; i < len(res.Hits); i++ {
+		if err := res.Hits[i].Unmarshal(&recs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return recs, nil
+}
+
 // CreateEnrollmentAPIKey creates a new enrollment API key
 func CreateEnrollmentAPIKey(ctx context.Context, bulker bulk.Bulk, key model.EnrollmentAPIKey, opt ...Option) (string, error) {
 	o := newOption(FleetEnrollmentAPIKeys, opt...)
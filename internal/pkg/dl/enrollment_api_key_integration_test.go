@@ -9,6 +9,7 @@ package dl
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -74,13 +75,8 @@ func TestSearchEnrollmentAPIKeyByID(t *testing.T) {
 	}
 
 	_, err = findEnrollmentAPIKey(ctx, bulker, index, QueryEnrollmentAPIKeyByID, FieldAPIKeyID, xid.New().String())
-	if err == nil {
-		t.Fatal("expected error")
-	} else {
-		diff := cmp.Diff(err.Error(), "hit count mismatch 0")
-		if diff != "" {
-			t.Fatal(diff)
-		}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found error, got: %v", err)
 	}
 }
 
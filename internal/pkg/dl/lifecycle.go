@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+)
+
+// FleetFileDataStreams are the data streams of the files uploaded by the agents and delivered to them.
+const FleetFileDataStreams = ".fleet-fileds-*"
+
+type dataLifecycleResponse struct {
+	Acknowledged bool            `json:"acknowledged"`
+	Error        json.RawMessage `json:"error"`
+}
+
+// PutDataStreamLifecycle sets the lifecycle of the data streams matching name so their data is deleted after
+// retention. Elasticsearch applies it to the backing indices that are not managed by an ILM policy.
+// It returns es.ErrIndexNotFound when no data stream matches name.
+func PutDataStreamLifecycle(ctx context.Context, bulker bulk.Bulk, name string, retention time.Duration) error {
+	client := bulker.Client()
+	body := fmt.Sprintf(`{"data_retention":"%ds"}`, int64(retention.Seconds()))
+	res, err := client.Indices.PutDataLifecycle([]string{name},
+		client.Indices.PutDataLifecycle.WithBody(strings.NewReader(body)),
+		client.Indices.PutDataLifecycle.WithExpandWildcards("all"),
+		client.Indices.PutDataLifecycle.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var esres dataLifecycleResponse
+	if err := json.NewDecoder(res.Body).Decode(&esres); err != nil {
+		return err
+	}
+	if res.IsError() {
+		return es.TranslateError(res.StatusCode, esres.Error)
+	}
+	return nil
+}
@@ -8,15 +8,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/gofrs/uuid"
 	"github.com/rs/zerolog"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 )
 
 type (
@@ -42,22 +46,219 @@ type (
 // timeNow is used to get the current time. It should be replaced for testing.
 var timeNow = time.Now
 
-// Migrate applies, in sequence, the migration functions. Currently, each migration
-// function is responsible to ensure it only applies the migration if needed,
-// being a no-op otherwise.
-func Migrate(ctx context.Context, bulker bulk.Bulk) error {
-	// WARNING: No new migrations should be added here. We need to implement
-	// a mechanism to perform migrations with standalone mode.
-	// See https://github.com/elastic/fleet-server/pull/2359.
-	for _, fn := range []migrationFn{migrateTov7_15, migrateToV8_5} {
-		if err := fn(ctx, bulker); err != nil {
+const (
+	migrationMetaID = "fleet-server-migrations"
+	migrationLockID = "fleet-server-migrations-lock"
+
+	// migrationLockTTL is how long the lock of a server that stopped while migrating prevents the other servers
+	// from migrating.
+	migrationLockTTL = 30 * time.Minute
+)
+
+// migrationWaitInterval is how often a server waiting for the migrations of another server checks whether they
+// are done. It should be replaced for testing.
+var migrationWaitInterval = 5 * time.Second
+
+// migrationStep is a versioned migration of the fleet indices. The steps are applied in order by the server
+// holding the migration lock and the version of the last step applied is recorded in the migration meta
+// document, so a step is applied once across the upgrades of the servers. A step must be safe to apply again
+// as a server may stop before recording it.
+type migrationStep struct {
+	version int
+	name    string
+	apply   migrationFn
+}
+
+// migrationSteps are the migrations of the fleet indices. A new step has the next version, it adds mappings
+// with mappingMigration or back-fills documents with backfillMigration.
+var migrationSteps = []migrationStep{
+	{version: 1, name: "v7.15.0", apply: backfillMigration(migrateAgentMetadata)},
+	{version: 2, name: "v8.5.0", apply: migrateToV8_5},
+}
+
+// migrationMeta is the meta document recording the migrations applied.
+type migrationMeta struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// migrationLock is the document of the server applying the migrations.
+type migrationLock struct {
+	ServerID  string `json:"server_id"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Migrate applies the migration steps that are not recorded in the migration meta document. One server applies
+// them, the others wait for it to be done; a server that is newer than the servers that applied the migrations
+// applies the steps they did not know.
+func Migrate(ctx context.Context, bulker bulk.Bulk, opt ...Option) error {
+	return runMigrations(ctx, bulker, migrationSteps, opt...)
+}
+
+func runMigrations(ctx context.Context, bulker bulk.Bulk, steps []migrationStep, opt ...Option) error {
+	o := newOption(FleetMigrations, opt...)
+	if len(steps) == 0 {
+		return nil
+	}
+	latest := steps[len(steps)-1].version
+	serverID := uuid.Must(uuid.NewV4()).String()
+	for {
+		meta, err := readMigrationMeta(ctx, bulker, o.indexName)
+		if err != nil {
 			return err
 		}
+		if meta.Version >= latest {
+			if meta.Version > latest {
+				zerolog.Ctx(ctx).Info().Int("fleet.migration.version", meta.Version).
+					Msg("fleet indices are migrated by a newer fleet-server")
+			}
+			return nil
+		}
+
+		locked, err := takeMigrationLock(ctx, bulker, o.indexName, serverID)
+		if err != nil {
+			return err
+		}
+		if locked {
+			err = applyMigrationSteps(ctx, bulker, o.indexName, steps)
+			if rerr := releaseMigrationLock(ctx, bulker, o.indexName); rerr != nil {
+				zerolog.Ctx(ctx).Warn().Err(rerr).Msg("unable to release the migration lock")
+			}
+			return err
+		}
+
+		zerolog.Ctx(ctx).Info().Int("fleet.migration.version", meta.Version).
+			Msg("waiting for the migrations of another fleet-server")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationWaitInterval):
+		}
 	}
+}
 
+// applyMigrationSteps applies the steps the meta document does not record, the meta document is read again as
+// another server may have applied them before the lock was taken.
+func applyMigrationSteps(ctx context.Context, bulker bulk.Bulk, index string, steps []migrationStep) error {
+	meta, err := readMigrationMeta(ctx, bulker, index)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if step.version <= meta.Version {
+			continue
+		}
+		zerolog.Ctx(ctx).Info().Int("fleet.migration.version", step.version).Msgf("applying migration %s", step.name)
+		if err := step.apply(ctx, bulker); err != nil {
+			return fmt.Errorf("migration %d %s failed: %w", step.version, step.name, err)
+		}
+		meta = migrationMeta{Version: step.version, Name: step.name, UpdatedAt: timeNow().UTC().Format(time.RFC3339)}
+		body, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		if _, err := bulker.Index(ctx, index, migrationMetaID, body, bulk.WithRefresh()); err != nil {
+			return fmt.Errorf("unable to record migration %d %s: %w", step.version, step.name, err)
+		}
+	}
 	return nil
 }
 
+func readMigrationMeta(ctx context.Context, bulker bulk.Bulk, index string) (migrationMeta, error) {
+	var meta migrationMeta
+	data, err := bulker.Read(ctx, index, migrationMetaID, bulk.WithRefresh())
+	if errors.Is(err, es.ErrElasticNotFound) || errors.Is(err, es.ErrIndexNotFound) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, fmt.Errorf("unable to read the migration meta document: %w", err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("unable to decode the migration meta document: %w", err)
+	}
+	return meta, nil
+}
+
+// takeMigrationLock creates the lock document of serverID, it returns false when another server holds the lock.
+// The lock of a server that did not release it before it expired is removed.
+func takeMigrationLock(ctx context.Context, bulker bulk.Bulk, index, serverID string) (bool, error) {
+	body, err := json.Marshal(migrationLock{ServerID: serverID, ExpiresAt: timeNow().UTC().Add(migrationLockTTL).Format(time.RFC3339)})
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < 2; i++ {
+		_, err = bulker.Create(ctx, index, migrationLockID, body, bulk.WithRefresh())
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, es.ErrElasticVersionConflict) {
+			return false, fmt.Errorf("unable to take the migration lock: %w", err)
+		}
+		data, err := bulker.Read(ctx, index, migrationLockID, bulk.WithRefresh())
+		if errors.Is(err, es.ErrElasticNotFound) {
+			// released since the conflict
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("unable to read the migration lock: %w", err)
+		}
+		var lock migrationLock
+		if err := json.Unmarshal(data, &lock); err != nil {
+			return false, fmt.Errorf("unable to decode the migration lock: %w", err)
+		}
+		expiresAt, err := time.Parse(time.RFC3339, lock.ExpiresAt)
+		if err == nil && timeNow().Before(expiresAt) {
+			return false, nil
+		}
+		zerolog.Ctx(ctx).Warn().Str("fleet.migration.lock.server_id", lock.ServerID).Msg("removing the expired migration lock")
+		if err := bulker.Delete(ctx, index, migrationLockID, bulk.WithRefresh()); err != nil && !errors.Is(err, es.ErrElasticNotFound) {
+			return false, fmt.Errorf("unable to remove the expired migration lock: %w", err)
+		}
+	}
+	return false, nil
+}
+
+func releaseMigrationLock(ctx context.Context, bulker bulk.Bulk, index string) error {
+	err := bulker.Delete(ctx, index, migrationLockID, bulk.WithRefresh())
+	if errors.Is(err, es.ErrElasticNotFound) {
+		return nil
+	}
+	return err
+}
+
+// mappingMigration returns a migration adding the properties, a JSON object of field mappings, to the mapping
+// of index. An index that does not exist yet is skipped, it is created with the current mappings.
+func mappingMigration(index, properties string) migrationFn {
+	return func(ctx context.Context, bulker bulk.Bulk) error {
+		client := bulker.Client()
+		body := `{"properties":` + properties + `}`
+		res, err := client.Indices.PutMapping([]string{index}, strings.NewReader(body),
+			client.Indices.PutMapping.WithContext(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		if res.IsError() {
+			return fmt.Errorf("put mapping of %s failed: %s", index, res.String())
+		}
+		zerolog.Ctx(ctx).Info().Str("fleet.migration.index", index).Msg("mapping migration done")
+		return nil
+	}
+}
+
+// backfillMigration returns a migration updating the documents matching the query of fn with its script.
+func backfillMigration(fn migrationBodyFn) migrationFn {
+	return func(ctx context.Context, bulker bulk.Bulk) error {
+		_, err := migrate(ctx, bulker, fn)
+		return err
+	}
+}
+
 func migrate(ctx context.Context, bulker bulk.Bulk, fn migrationBodyFn) (int, error) {
 	var updatedDocs int
 	for {
@@ -144,15 +345,6 @@ func applyMigration(ctx context.Context, name string, index string, bulker bulk.
 }
 
 // ============================== V7.15 migration ==============================
-func migrateTov7_15(ctx context.Context, bulker bulk.Bulk) error {
-	zerolog.Ctx(ctx).Debug().Msg("applying migration to v7.15")
-	_, err := migrate(ctx, bulker, migrateAgentMetadata)
-	if err != nil {
-		return fmt.Errorf("v7.15.0 data migration failed: %w", err)
-	}
-
-	return nil
-}
 
 // FleetServer 7.15 added a new *AgentMetadata field to the Agent record.
 // This field was populated in new enrollments in 7.15 and later; however, the
@@ -0,0 +1,177 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/esutil"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+// testMigrationSteps returns steps recording the versions they are applied with in applied.
+func testMigrationSteps(applied *[]int) []migrationStep {
+	step := func(version int) migrationStep {
+		return migrationStep{version: version, name: "test", apply: func(context.Context, bulk.Bulk) error {
+			*applied = append(*applied, version)
+			return nil
+		}}
+	}
+	return []migrationStep{step(1), step(2), step(3)}
+}
+
+func migrationMetaDoc(t *testing.T, version int) []byte {
+	t.Helper()
+	data, err := json.Marshal(migrationMeta{Version: version})
+	require.NoError(t, err)
+	return data
+}
+
+func migrationLockDoc(t *testing.T, expiresAt time.Time) []byte {
+	t.Helper()
+	data, err := json.Marshal(migrationLock{ServerID: "other", ExpiresAt: expiresAt.UTC().Format(time.RFC3339)})
+	require.NoError(t, err)
+	return data
+}
+
+func TestRunMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		applied []int
+	}{
+		{name: "new cluster", version: -1, applied: []int{1, 2, 3}},
+		{name: "upgrade", version: 1, applied: []int{2, 3}},
+		{name: "migrated", version: 3},
+		{name: "migrated by a newer server", version: 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := testlog.SetLogger(t).WithContext(context.Background())
+			bulker := ftesting.NewMockBulk()
+			if tc.version < 0 {
+				bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return([]byte(nil), es.ErrIndexNotFound)
+			} else {
+				bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return(migrationMetaDoc(t, tc.version), nil)
+			}
+			if len(tc.applied) > 0 {
+				bulker.On("Create", mock.Anything, FleetMigrations, migrationLockID, mock.Anything, mock.Anything).Return("", nil).Once()
+				bulker.On("Index", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything, mock.Anything).Return("", nil).Times(len(tc.applied))
+				bulker.On("Delete", mock.Anything, FleetMigrations, migrationLockID, mock.Anything).Return(nil).Once()
+			}
+
+			var applied []int
+			require.NoError(t, runMigrations(ctx, bulker, testMigrationSteps(&applied)))
+			assert.Equal(t, tc.applied, applied)
+			bulker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunMigrationsWaitsForTheLock(t *testing.T) {
+	defer func(d time.Duration) { migrationWaitInterval = d }(migrationWaitInterval)
+	migrationWaitInterval = time.Millisecond
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return(migrationMetaDoc(t, 1), nil).Once()
+	bulker.On("Create", mock.Anything, FleetMigrations, migrationLockID, mock.Anything, mock.Anything).Return("", es.ErrElasticVersionConflict).Once()
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationLockID, mock.Anything).Return(migrationLockDoc(t, time.Now().Add(time.Minute)), nil).Once()
+	// the other server applied the migrations
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return(migrationMetaDoc(t, 3), nil).Once()
+
+	var applied []int
+	require.NoError(t, runMigrations(ctx, bulker, testMigrationSteps(&applied)))
+	assert.Empty(t, applied)
+	bulker.AssertExpectations(t)
+}
+
+func TestRunMigrationsExpiredLock(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return(migrationMetaDoc(t, 2), nil)
+	bulker.On("Create", mock.Anything, FleetMigrations, migrationLockID, mock.Anything, mock.Anything).Return("", es.ErrElasticVersionConflict).Once()
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationLockID, mock.Anything).Return(migrationLockDoc(t, time.Now().Add(-time.Minute)), nil).Once()
+	bulker.On("Delete", mock.Anything, FleetMigrations, migrationLockID, mock.Anything).Return(nil).Twice()
+	bulker.On("Create", mock.Anything, FleetMigrations, migrationLockID, mock.Anything, mock.Anything).Return("", nil).Once()
+	bulker.On("Index", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything, mock.Anything).Return("", nil).Once()
+
+	var applied []int
+	require.NoError(t, runMigrations(ctx, bulker, testMigrationSteps(&applied)))
+	assert.Equal(t, []int{3}, applied)
+	bulker.AssertExpectations(t)
+}
+
+func TestRunMigrationsStepFailure(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Read", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything).Return([]byte(nil), es.ErrElasticNotFound)
+	bulker.On("Create", mock.Anything, FleetMigrations, migrationLockID, mock.Anything, mock.Anything).Return("", nil).Once()
+	bulker.On("Index", mock.Anything, FleetMigrations, migrationMetaID, mock.Anything, mock.Anything).Return("", nil).Once()
+	bulker.On("Delete", mock.Anything, FleetMigrations, migrationLockID, mock.Anything).Return(nil).Once()
+
+	steps := []migrationStep{
+		{version: 1, name: "first", apply: func(context.Context, bulk.Bulk) error { return nil }},
+		{version: 2, name: "second", apply: func(context.Context, bulk.Bulk) error { return es.ErrTimeout }},
+	}
+	err := runMigrations(ctx, bulker, steps)
+	require.ErrorIs(t, err, es.ErrTimeout)
+	assert.ErrorContains(t, err, "migration 2 second failed")
+	bulker.AssertExpectations(t)
+}
+
+func TestMappingMigration(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    string
+	}{
+		{name: "mapping added", status: http.StatusOK},
+		{name: "index not created yet", status: http.StatusNotFound},
+		{name: "conflicting mapping", status: http.StatusBadRequest, err: "put mapping of .fleet-agents failed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := testlog.SetLogger(t).WithContext(context.Background())
+			client, transport := esutil.MockESClient(t)
+			transport.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, http.MethodPut, req.Method)
+				assert.Equal(t, "/"+FleetAgents+"/_mapping", req.URL.Path)
+				body, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"properties":{"tags":{"type":"keyword"}}}`, string(body))
+				return &http.Response{
+					StatusCode: tc.status,
+					Body:       io.NopCloser(strings.NewReader(`{}`)),
+					Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+				}, nil
+			}
+			bulker := ftesting.NewMockBulk()
+			bulker.On("Client").Return(client)
+
+			err := mappingMigration(FleetAgents, `{"tags":{"type":"keyword"}}`)(ctx, bulker)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
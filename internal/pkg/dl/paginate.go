@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+)
+
+const (
+	// pitKeepAlive is how long a point in time is kept between two pages.
+	pitKeepAlive = "1m"
+
+	pitCloseTimeout = 5 * time.Second
+)
+
+// PageFunc is called with each page of hits, the paging stops when it returns false or an error.
+type PageFunc func(hits []es.HitT) (bool, error)
+
+type pitResponse struct {
+	ID    string          `json:"id"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+type pitSearchResponse struct {
+	es.Response
+	PitID string `json:"pit_id"`
+}
+
+// SearchPages calls fn with each page of size hits of the query on a point in time of index, until fn returns false
+// or all the hits are seen. The hits are sorted by the sort of the query and then by their order in the point in
+// time, the pages are searched after the last hit of the previous page, so they are not limited by the result
+// window of the index and do not skip or repeat hits when the index changes.
+func SearchPages(ctx context.Context, bulker bulk.Bulk, index string, query []byte, size int, fn PageFunc) error {
+	var body map[string]interface{}
+	if err := json.Unmarshal(query, &body); err != nil {
+		return fmt.Errorf("unable to decode the query: %w", err)
+	}
+	delete(body, "from")
+	body["size"] = size
+	body["sort"] = withShardDocSort(body["sort"])
+
+	pitID, err := openPointInTime(ctx, bulker, index)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closePointInTime(ctx, bulker, pitID)
+	}()
+
+	for {
+		body["pit"] = map[string]interface{}{"id": pitID, "keep_alive": pitKeepAlive}
+		page, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		res, err := searchPointInTime(ctx, bulker, page)
+		if err != nil {
+			return err
+		}
+		if res.PitID != "" {
+			pitID = res.PitID
+		}
+		hits := res.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		if more, err := fn(hits); err != nil || !more {
+			return err
+		}
+		if len(hits) < size {
+			return nil
+		}
+		body["search_after"] = hits[len(hits)-1].Sort
+	}
+}
+
+// SearchAll returns all the hits of the query on index. The query is searched once with bulker when its hits fit
+// in a page of size hits, the opts apply to that search; it is searched again with SearchPages when they do not.
+func SearchAll(ctx context.Context, bulker bulk.Bulk, index string, query []byte, size int, opts ...bulk.Opt) ([]es.HitT, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(query, &body); err != nil {
+		return nil, fmt.Errorf("unable to decode the query: %w", err)
+	}
+	body["size"] = size
+	first, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := bulker.Search(ctx, index, first, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Hits) < size {
+		return res.Hits, nil
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("index", index).Int("size", size).Msg("search results do not fit in a page, searching all the pages")
+	var hits []es.HitT
+	err = SearchPages(ctx, bulker, index, query, size, func(page []es.HitT) (bool, error) {
+		hits = append(hits, page...)
+		return true, nil
+	})
+	return hits, err
+}
+
+// withShardDocSort appends the _shard_doc tiebreaker to the sort of a query, so the hits with the same sort
+// values are not split between two pages.
+func withShardDocSort(sort interface{}) []interface{} {
+	var sorts []interface{}
+	switch s := sort.(type) {
+	case nil:
+	case []interface{}:
+		sorts = append(sorts, s...)
+	default:
+		sorts = append(sorts, s)
+	}
+	return append(sorts, map[string]interface{}{"_shard_doc": "asc"})
+}
+
+func openPointInTime(ctx context.Context, bulker bulk.Bulk, index string) (string, error) {
+	client := bulker.Client()
+	res, err := client.OpenPointInTime([]string{index}, pitKeepAlive,
+		client.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var pit pitResponse
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", err
+	}
+	if res.IsError() {
+		return "", es.TranslateError(res.StatusCode, pit.Error)
+	}
+	return pit.ID, nil
+}
+
+func searchPointInTime(ctx context.Context, bulker bulk.Bulk, body []byte) (*pitSearchResponse, error) {
+	client := bulker.Client()
+	res, err := client.Search(
+		client.Search.WithContext(ctx),
+		client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var esres pitSearchResponse
+	if err := json.NewDecoder(res.Body).Decode(&esres); err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, es.TranslateError(res.StatusCode, esres.Error)
+	}
+	return &esres, nil
+}
+
+// closePointInTime releases the point in time, it expires after its keep alive when it can not be closed.
+func closePointInTime(ctx context.Context, bulker bulk.Bulk, id string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pitCloseTimeout)
+	defer cancel()
+	body, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		return
+	}
+	client := bulker.Client()
+	res, err := client.ClosePointInTime(
+		client.ClosePointInTime.WithContext(ctx),
+		client.ClosePointInTime.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("unable to close the point in time")
+		return
+	}
+	res.Body.Close()
+}
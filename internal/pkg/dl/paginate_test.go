@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	"github.com/elastic/fleet-server/v7/internal/pkg/testing/esutil"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+// mockPointInTime serves the point in time searches of n hits, it returns the bodies of the searches and whether
+// the point in time was closed.
+func mockPointInTime(t *testing.T, bulker *ftesting.MockBulk, n int) (*[]map[string]interface{}, *bool) {
+	t.Helper()
+	client, transport := esutil.MockESClient(t)
+	var searches []map[string]interface{}
+	closed := false
+	transport.RoundTripFn = func(req *http.Request) (*http.Response, error) {
+		resp := `{}`
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/.fleet-agents/_pit":
+			assert.Equal(t, pitKeepAlive, req.URL.Query().Get("keep_alive"))
+			resp = `{"id":"pit-0"}`
+		case req.Method == http.MethodPost && req.URL.Path == "/_search":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+			searches = append(searches, body)
+			size := int(body["size"].(float64))
+			from := 0
+			if after, ok := body["search_after"].([]interface{}); ok {
+				from = int(after[0].(float64)) + 1
+			}
+			var hits []string
+			for i := from; i < n && i < from+size; i++ {
+				hits = append(hits, fmt.Sprintf(`{"_id":"%d","_source":{},"sort":[%d]}`, i, i))
+			}
+			resp = fmt.Sprintf(`{"pit_id":"pit-%d","hits":{"hits":[%s]}}`, len(searches), strings.Join(hits, ","))
+		case req.Method == http.MethodDelete && req.URL.Path == "/_pit":
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, fmt.Sprintf(`{"id":"pit-%d"}`, len(searches)), string(body))
+			closed = true
+		default:
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(resp)),
+			Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		}, nil
+	}
+	bulker.On("Client").Return(client)
+	return &searches, &closed
+}
+
+func TestSearchPages(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	bulker := ftesting.NewMockBulk()
+	searches, closed := mockPointInTime(t, bulker, 5)
+
+	var ids []string
+	err := SearchPages(ctx, bulker, FleetAgents, []byte(`{"query":{"match_all":{}},"sort":["enrolled_at"],"from":10}`), 2, func(hits []es.HitT) (bool, error) {
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, ids)
+	require.Len(t, *searches, 3)
+	first := (*searches)[0]
+	assert.Equal(t, []interface{}{"enrolled_at", map[string]interface{}{"_shard_doc": "asc"}}, first["sort"])
+	assert.Equal(t, map[string]interface{}{"id": "pit-0", "keep_alive": pitKeepAlive}, first["pit"])
+	assert.NotContains(t, first, "from")
+	assert.NotContains(t, first, "search_after")
+	// the following pages use the point in time returned by the previous page
+	assert.Equal(t, "pit-1", (*searches)[1]["pit"].(map[string]interface{})["id"])
+	assert.Equal(t, []interface{}{float64(3)}, (*searches)[2]["search_after"])
+	assert.True(t, *closed)
+}
+
+func TestSearchPagesStop(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	bulker := ftesting.NewMockBulk()
+	searches, closed := mockPointInTime(t, bulker, 5)
+
+	pages := 0
+	err := SearchPages(ctx, bulker, FleetAgents, []byte(`{}`), 2, func(hits []es.HitT) (bool, error) {
+		pages++
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pages)
+	assert.Len(t, *searches, 1)
+	assert.True(t, *closed)
+}
+
+func TestSearchAll(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	t.Run("one page", func(t *testing.T) {
+		bulker := ftesting.NewMockBulk()
+		bulker.On("Search", mock.Anything, FleetAgents, []byte(`{"query":{"match_all":{}},"size":3}`), mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{ID: "0"}, {ID: "1"}}}}, nil)
+
+		hits, err := SearchAll(ctx, bulker, FleetAgents, []byte(`{"query":{"match_all":{}}}`), 3)
+		require.NoError(t, err)
+		assert.Len(t, hits, 2)
+		bulker.AssertNotCalled(t, "Client")
+	})
+
+	t.Run("more than a page", func(t *testing.T) {
+		bulker := ftesting.NewMockBulk()
+		bulker.On("Search", mock.Anything, FleetAgents, mock.Anything, mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{ID: "0"}, {ID: "1"}, {ID: "2"}}}}, nil)
+		searches, closed := mockPointInTime(t, bulker, 7)
+
+		hits, err := SearchAll(ctx, bulker, FleetAgents, []byte(`{"query":{"match_all":{}}}`), 3)
+		require.NoError(t, err)
+		assert.Len(t, hits, 7)
+		assert.Len(t, *searches, 3)
+		assert.True(t, *closed)
+	})
+}
+
+func TestFindAgentActionsPages(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	page := func(from, n int) *es.ResultT {
+		hits := make([]es.HitT, 0, n)
+		for i := from; i < from+n; i++ {
+			hits = append(hits, es.HitT{ID: fmt.Sprint(i), SeqNo: int64(i), Source: []byte(fmt.Sprintf(`{"action_id":"action-%d"}`, i))})
+		}
+		return &es.ResultT{HitsT: es.HitsT{Hits: hits}}
+	}
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Search", mock.Anything, FleetActions, mock.Anything, mock.Anything).Return(page(1, maxAgentActionsFetchSize), nil).Once()
+	bulker.On("Search", mock.Anything, FleetActions, mock.MatchedBy(func(body []byte) bool {
+		return strings.Contains(string(body), fmt.Sprintf(`"gt":%d`, maxAgentActionsFetchSize))
+	}), mock.Anything).Return(page(maxAgentActionsFetchSize+1, 2), nil).Once()
+
+	actions, err := FindAgentActions(ctx, bulker, []int64{0}, []int64{1000}, "agent")
+	require.NoError(t, err)
+	require.Len(t, actions, maxAgentActionsFetchSize+2)
+	assert.Equal(t, "action-102", actions[len(actions)-1].ActionID)
+	bulker.AssertExpectations(t)
+}
@@ -5,6 +5,7 @@
 package dl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -22,13 +23,16 @@ var (
 	tmplQueryLatestPolicies = prepareQueryLatestPolicies()
 	ErrMissingAggregations  = errors.New("missing expected aggregation result")
 	tmplQueryPolicies       = prepareQueryPolicies()
+
+	// Queries for the policy revisions GC
+	tmplQueryLatestDeployedPolicy = prepareQueryLatestDeployedPolicy()
+	tmplDeletePolicyRevisions     = prepareDeletePolicyRevisions()
 )
 
 func prepareQueryLatestPolicies() []byte {
 	root := dsl.NewRoot()
 	root.Size(0)
-	policyID := root.Aggs().Agg(FieldPolicyID)
-	policyID.Terms("field", FieldPolicyID, nil).Size(10000)
+	policyID := root.Aggs().Agg(FieldPolicyID).TermsAgg(FieldPolicyID, dsl.WithAggSize(10000))
 	revisionIdx := policyID.Aggs().Agg(FieldRevisionIdx).TopHits()
 	revisionIdx.Size(1)
 	rSort := revisionIdx.Sort()
@@ -40,7 +44,7 @@ func prepareQueryLatestPolicies() []byte {
 // QueryLatestPolicies gets the latest revision for a policy
 func QueryLatestPolicies(ctx context.Context, bulker bulk.Bulk, opt ...Option) ([]model.Policy, error) {
 	o := newOption(FleetPolicies, opt...)
-	res, err := bulker.Search(ctx, o.indexName, tmplQueryLatestPolicies, bulk.WithIgnoreUnavailble())
+	res, err := bulker.Search(ctx, o.indexName, tmplQueryLatestPolicies, o.searchOpts(bulk.WithIgnoreUnavailble())...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +97,7 @@ func prepareQueryPolicies() *dsl.Tmpl {
 func QueryOutputFromPolicy(ctx context.Context, bulker bulk.Bulk, outputName string, opt ...Option) (*model.Policy, error) {
 	o := newOption(FleetPolicies, opt...)
 	params := map[string]interface{}{}
-	res, err := Search(ctx, bulker, tmplQueryPolicies, o.indexName, params)
+	res, err := Search(ctx, bulker, tmplQueryPolicies, o.indexName, params, o.searchOpts()...)
 	if err != nil {
 		if errors.Is(err, es.ErrIndexNotFound) {
 			zerolog.Ctx(ctx).Debug().Str("index", o.indexName).Msg(es.ErrIndexNotFound.Error())
@@ -114,3 +118,88 @@ func QueryOutputFromPolicy(ctx context.Context, bulker bulk.Bulk, outputName str
 	zerolog.Ctx(ctx).Debug().Str(logger.PolicyOutputName, outputName).Msg("policy with output not found")
 	return nil, nil
 }
+
+func prepareQueryLatestDeployedPolicy() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(1)
+	filter := root.Query().Bool().Filter()
+	filter.Term(FieldPolicyID, tmpl.Bind(FieldPolicyID), nil)
+	filter.Range(FieldCoordinatorIdx, dsl.WithRangeGT(0))
+	rSort := root.Sort()
+	rSort.SortOrder(FieldRevisionIdx, dsl.SortDescend)
+	rSort.SortOrder(FieldCoordinatorIdx, dsl.SortDescend)
+	root.Source().Includes(FieldPolicyID, FieldRevisionIdx, FieldCoordinatorIdx)
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// FindLatestDeployedPolicy returns the latest revision of the policy that was coordinated by a fleet-server,
+// and therefore may have been sent to agents. It returns nil if the policy was never coordinated.
+// The policy data is not included.
+func FindLatestDeployedPolicy(ctx context.Context, bulker bulk.Bulk, policyID string, opt ...Option) (*model.Policy, error) {
+	o := newOption(FleetPolicies, opt...)
+	res, err := Search(ctx, bulker, tmplQueryLatestDeployedPolicy, o.indexName, map[string]interface{}{
+		FieldPolicyID: policyID,
+	}, o.searchOpts()...)
+	if err != nil {
+		if errors.Is(err, es.ErrIndexNotFound) {
+			err = nil
+		}
+		return nil, err
+	}
+	if len(res.Hits) == 0 {
+		return nil, nil
+	}
+	var policy model.Policy
+	if err := res.Hits[0].Unmarshal(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func prepareDeletePolicyRevisions() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	filter := root.Query().Bool().Filter()
+	filter.Term(FieldPolicyID, tmpl.Bind(FieldPolicyID), nil)
+	filter.Range(FieldRevisionIdx, dsl.WithRangeLT(tmpl.Bind(FieldRevisionIdx)))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// DeletePolicyRevisionsBefore deletes the documents of the policy with a revision lower than the passed one.
+// It returns the number of documents deleted.
+func DeletePolicyRevisionsBefore(ctx context.Context, bulker bulk.Bulk, policyID string, revision int64, opt ...Option) (int64, error) {
+	o := newOption(FleetPolicies, opt...)
+	query, err := tmplDeletePolicyRevisions.Render(map[string]interface{}{
+		FieldPolicyID:    policyID,
+		FieldRevisionIdx: revision,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	client := bulker.Client()
+	res, err := client.API.DeleteByQuery([]string{o.indexName}, bytes.NewReader(query),
+		client.API.DeleteByQuery.WithContext(ctx),
+		client.API.DeleteByQuery.WithConflicts("proceed"))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	var esres es.DeleteByQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&esres); err != nil {
+		return 0, err
+	}
+	if res.IsError() {
+		err = es.TranslateError(res.StatusCode, esres.Error)
+		if errors.Is(err, es.ErrIndexNotFound) {
+			zerolog.Ctx(ctx).Debug().Str("index", o.indexName).Msg(es.ErrIndexNotFound.Error())
+			err = nil
+		}
+		return 0, err
+	}
+	return esres.Deleted, nil
+}
@@ -51,7 +51,7 @@ func SearchPolicyLeaders(ctx context.Context, bulker bulk.Bulk, ids []string, op
 	if err != nil {
 		return
 	}
-	res, err := bulker.Search(ctx, o.indexName, data)
+	res, err := bulker.Search(ctx, o.indexName, data, o.searchOpts()...)
 	if err != nil {
 		if errors.Is(err, es.ErrIndexNotFound) {
 			zerolog.Ctx(ctx).Debug().Str("index", o.indexName).Msg(es.ErrIndexNotFound.Error())
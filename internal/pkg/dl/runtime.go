@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
+)
+
+// RuntimeField is a field computed by Elasticsearch when the documents are searched. It is declared in the queries
+// that use it, it does not need a change of the mappings of the fleet indices, which are managed by Kibana.
+type RuntimeField struct {
+	// Type is the Elasticsearch type of the field, like keyword or long.
+	Type string
+	// Script is the painless script emitting the values of the field.
+	Script string
+}
+
+const (
+	// RuntimeFieldAgentVersionMajor is the major version of the agent, from agent.version.
+	RuntimeFieldAgentVersionMajor = "agent_version_major"
+	// RuntimeFieldOSPlatform is the lower case platform of the host of the agent, from local_metadata.os.platform.
+	RuntimeFieldOSPlatform = "os_platform"
+)
+
+// AgentRuntimeFields are the runtime fields of the agent documents.
+var AgentRuntimeFields = map[string]RuntimeField{
+	RuntimeFieldAgentVersionMajor: {
+		Type: "long",
+		Script: `if (doc.containsKey('agent.version') && doc['agent.version'].size() > 0) {
+  String v = doc['agent.version'].value;
+  int i = v.indexOf('.');
+  try { emit(Long.parseLong(i < 0 ? v : v.substring(0, i))); } catch (NumberFormatException e) {}
+}`,
+	},
+	RuntimeFieldOSPlatform: {
+		Type: "keyword",
+		Script: `if (doc.containsKey('local_metadata.os.platform') && doc['local_metadata.os.platform'].size() > 0) {
+  emit(doc['local_metadata.os.platform'].value.toLowerCase());
+}`,
+	},
+}
+
+var ErrUnknownRuntimeField = errors.New("unknown runtime field")
+
+// withRuntimeFields declares the runtime fields of values in the search of root and adds a term filter of each value
+// to query. The fields are ordered by name to render the same query for the same values.
+func withRuntimeFields(root, query *dsl.Node, fields map[string]RuntimeField, values map[string]interface{}) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownRuntimeField, name)
+		}
+		root.RuntimeField(name, field.Type, field.Script)
+		query.Term(name, values[name], nil)
+	}
+	return nil
+}
@@ -11,10 +11,49 @@ import (
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 )
 
+// maxServers bounds the number of servers returned by SearchServersSince.
+const maxServers = 1000
+
+var tmplSearchServersSince = prepareSearchServersSince()
+
+func prepareSearchServersSince() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(maxServers)
+	root.Query().Bool().Filter().Range("@timestamp", dsl.WithRangeGT(tmpl.Bind("@timestamp")))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// SearchServersSince returns the servers that ensured their document after since, a server ensures its document
+// each time it checks the leadership of the policies.
+func SearchServersSince(ctx context.Context, bulker bulk.Bulk, since time.Time, opt ...Option) ([]model.Server, error) {
+	o := newOption(FleetServers, opt...)
+	res, err := Search(ctx, bulker, tmplSearchServersSince, o.indexName, map[string]interface{}{
+		"@timestamp": since.UTC().Format(time.RFC3339Nano),
+	}, o.searchOpts()...)
+	if err != nil {
+		if errors.Is(err, es.ErrIndexNotFound) {
+			err = nil
+		}
+		return nil, err
+	}
+	servers := make([]model.Server, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		var server model.Server
+		if err := hit.Unmarshal(&server); err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
 // EnsureServer ensures that this server is written in the index.
 func EnsureServer(ctx context.Context, bulker bulk.Bulk, version string, agent model.AgentMetadata, host model.HostMetadata, opts ...Option) error {
 	var server model.Server
@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package drain is the drain mode of fleet-server. A draining server keeps serving the requests while the agents
+// move to the other servers of the tier, then it stops.
+package drain
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	ReasonSignal = "signal"
+	ReasonAPI    = "api"
+)
+
+type ctxKey struct{}
+
+// State is the drain state of a server, a nil State never drains.
+type State struct {
+	mut     sync.Mutex
+	started chan struct{}
+	since   time.Time
+	reason  string
+}
+
+// New returns the state of a server that is not draining.
+func New() *State {
+	return &State{started: make(chan struct{})}
+}
+
+// Start enters the drain mode, it returns false when the server is already draining.
+func (s *State) Start(reason string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if !s.since.IsZero() {
+		return false
+	}
+	s.since = time.Now().UTC()
+	s.reason = reason
+	close(s.started)
+	return true
+}
+
+// Draining returns true once the drain mode is entered.
+func (s *State) Draining() bool {
+	if s == nil {
+		return false
+	}
+	select {
+	case <-s.started:
+		return true
+	default:
+		return false
+	}
+}
+
+// Started returns a channel closed when the drain mode is entered, the channel of a nil State is never closed.
+func (s *State) Started() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	return s.started
+}
+
+// Since returns when and why the drain mode was entered, the zero time when the server is not draining.
+func (s *State) Since() (time.Time, string) {
+	if s == nil {
+		return time.Time{}, ""
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.since, s.reason
+}
+
+// WithContext returns a copy of ctx with the drain state, the requests of the api servers started with the
+// context get the state of their server.
+func WithContext(ctx context.Context, s *State) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext returns the drain state of ctx, nil when it has none.
+func FromContext(ctx context.Context) *State {
+	s, _ := ctx.Value(ctxKey{}).(*State)
+	return s
+}
@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package drain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	s := New()
+	assert.False(t, s.Draining())
+	since, _ := s.Since()
+	assert.True(t, since.IsZero())
+
+	assert.True(t, s.Start(ReasonAPI))
+	assert.False(t, s.Start(ReasonSignal), "the server is already draining")
+	assert.True(t, s.Draining())
+	since, reason := s.Since()
+	assert.False(t, since.IsZero())
+	assert.Equal(t, ReasonAPI, reason)
+	select {
+	case <-s.Started():
+	default:
+		t.Fatal("the started channel is not closed")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	s := FromContext(context.Background())
+	assert.Nil(t, s)
+	assert.False(t, s.Draining(), "a nil state never drains")
+	assert.Nil(t, s.Started())
+
+	s = New()
+	assert.Same(t, s, FromContext(WithContext(context.Background(), s)))
+}
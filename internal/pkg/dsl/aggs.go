@@ -15,3 +15,75 @@ func (n *Node) Agg(name string) *Node {
 func (n *Node) Max() *Node {
 	return n.findOrCreateChildByName(kKeywordMax)
 }
+
+// AggOpt sets an option of an aggregation, Elasticsearch rejects the options that do not apply to the aggregation.
+type AggOpt func(nodeMapT)
+
+// WithAggSize sets the number of buckets of a terms aggregation.
+func WithAggSize(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordSize] = &Node{leaf: v}
+	}
+}
+
+// WithAggMissing sets the bucket key of the documents without the field.
+func WithAggMissing(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordMissing] = &Node{leaf: v}
+	}
+}
+
+// WithAggMinDocCount sets the minimum number of documents of the returned buckets.
+func WithAggMinDocCount(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordMinDocCount] = &Node{leaf: v}
+	}
+}
+
+// WithAggCalendarInterval sets the calendar interval of a date histogram, like 1d or 1M.
+func WithAggCalendarInterval(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordCalendarInterval] = &Node{leaf: v}
+	}
+}
+
+// WithAggFixedInterval sets the fixed interval of a date histogram, like 30m.
+func WithAggFixedInterval(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordFixedInterval] = &Node{leaf: v}
+	}
+}
+
+// WithAggPrecisionThreshold sets the count below which a cardinality aggregation is expected to be exact.
+func WithAggPrecisionThreshold(v interface{}) AggOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordPrecisionThreshold] = &Node{leaf: v}
+	}
+}
+
+// TermsAgg makes n a terms aggregation of the field, the sub aggregations are added to the returned node.
+func (n *Node) TermsAgg(field string, opts ...AggOpt) *Node {
+	n.setAgg(kKeywordTerms, field, opts)
+	return n
+}
+
+// DateHistogram makes n a date histogram aggregation of the field, the sub aggregations are added to the returned
+// node. One of WithAggCalendarInterval and WithAggFixedInterval is required.
+func (n *Node) DateHistogram(field string, opts ...AggOpt) *Node {
+	n.setAgg(kKeywordDateHistogram, field, opts)
+	return n
+}
+
+// Cardinality makes n a cardinality aggregation of the field, the approximate count of its distinct values.
+func (n *Node) Cardinality(field string, opts ...AggOpt) *Node {
+	n.setAgg(kKeywordCardinality, field, opts)
+	return n
+}
+
+func (n *Node) setAgg(typ, field string, opts []AggOpt) {
+	childNode := n.findOrCreateChildByName(typ)
+	childNode.nodeMap = nodeMapT{kKeywordField: &Node{leaf: field}}
+	for _, o := range opts {
+		o(childNode.nodeMap)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dsl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggs(t *testing.T) {
+	tmpl := NewTmpl()
+	root := NewRoot()
+	root.Size(0)
+	status := root.Aggs().Agg("status").TermsAgg("last_checkin_status", WithAggSize(10), WithAggMissing("unknown"))
+	status.Aggs().Agg("agents").Cardinality("agent.id", WithAggPrecisionThreshold(1000))
+	root.Aggs().Agg("enrolled").DateHistogram("enrolled_at", WithAggCalendarInterval(tmpl.Bind("interval")), WithAggMinDocCount(1))
+	tmpl.MustResolve(root)
+
+	query, err := tmpl.Render(map[string]interface{}{"interval": "1d"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"size": 0,
+		"aggs": {
+			"status": {
+				"terms": {"field": "last_checkin_status", "size": 10, "missing": "unknown"},
+				"aggs": {"agents": {"cardinality": {"field": "agent.id", "precision_threshold": 1000}}}
+			},
+			"enrolled": {"date_histogram": {"field": "enrolled_at", "calendar_interval": "1d", "min_doc_count": 1}}
+		}
+	}`, string(query))
+}
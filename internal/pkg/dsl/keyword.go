@@ -5,29 +5,42 @@
 package dsl
 
 const (
-	kKeywordAggs        = "aggs"
-	kKeywordBool        = "bool"
-	kKeywordBoost       = "boost"
-	kKeywordExcludes    = "excludes"
-	kKeywordExists      = "exists"
-	kKeywordField       = "field"
-	kKeywordFilter      = "filter"
-	kKeywordGreaterThan = "gt"
-	kKeywordIncludes    = "includes"
-	kKeywordLessThanEq  = "lte"
-	kKeywordMatchAll    = "match_all"
-	kKeywordMatchNone   = "match_none"
-	kKeywordMax         = "max"
-	kKeywordMust        = "must"
-	kKeywordMustNot     = "must_not"
-	kKeywordNULL        = "null"
-	kKeywordParams      = "params"
-	kKeywordQuery       = "query"
-	kKeywordScript      = "script"
-	kKeywordSize        = "size"
-	kKeywordSort        = "sort"
-	kKeywordSource      = "_source"
-	kKeywordTerm        = "term"
-	kKeywordTerms       = "terms"
-	kKeywordTopHits     = "top_hits"
+	kKeywordAggs               = "aggs"
+	kKeywordBool               = "bool"
+	kKeywordBoost              = "boost"
+	kKeywordCalendarInterval   = "calendar_interval"
+	kKeywordCardinality        = "cardinality"
+	kKeywordDateHistogram      = "date_histogram"
+	kKeywordExcludes           = "excludes"
+	kKeywordExists             = "exists"
+	kKeywordField              = "field"
+	kKeywordFilter             = "filter"
+	kKeywordFixedInterval      = "fixed_interval"
+	kKeywordGreaterThan        = "gt"
+	kKeywordIncludes           = "includes"
+	kKeywordLessThan           = "lt"
+	kKeywordLessThanEq         = "lte"
+	kKeywordMatchAll           = "match_all"
+	kKeywordMatchNone          = "match_none"
+	kKeywordMax                = "max"
+	kKeywordMinDocCount        = "min_doc_count"
+	kKeywordMissing            = "missing"
+	kKeywordMust               = "must"
+	kKeywordMustNot            = "must_not"
+	kKeywordNULL               = "null"
+	kKeywordParams             = "params"
+	kKeywordPrecisionThreshold = "precision_threshold"
+	kKeywordQuery              = "query"
+	kKeywordRuntimeMappings    = "runtime_mappings"
+	kKeywordScript             = "script"
+	kKeywordScriptSource       = "source"
+	kKeywordSearchAfter        = "search_after"
+	kKeywordShould             = "should"
+	kKeywordSize               = "size"
+	kKeywordSort               = "sort"
+	kKeywordSource             = "_source"
+	kKeywordTerm               = "term"
+	kKeywordTerms              = "terms"
+	kKeywordTopHits            = "top_hits"
+	kKeywordType               = "type"
 )
@@ -27,3 +27,16 @@ func (n *Node) MustNot() *Node {
 	}
 	return childNode
 }
+
+func (n *Node) Should() *Node {
+	childNode := n.findOrCreateChildByName(kKeywordShould)
+	if childNode.nodeList == nil {
+		childNode.nodeList = nodeListT{}
+	}
+	return childNode
+}
+
+// AppendBool adds a bool query to the clauses of n, like the clauses of a filter or of a should.
+func (n *Node) AppendBool() *Node {
+	return n.appendOrSetChildNode(kKeywordBool)
+}
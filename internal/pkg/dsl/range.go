@@ -12,6 +12,12 @@ func WithRangeGT(v interface{}) RangeOpt {
 	}
 }
 
+func WithRangeLT(v interface{}) RangeOpt {
+	return func(nmap nodeMapT) {
+		nmap[kKeywordLessThan] = &Node{leaf: v}
+	}
+}
+
 func WithRangeLTE(v interface{}) RangeOpt {
 	return func(nmap nodeMapT) {
 		nmap[kKeywordLessThanEq] = &Node{leaf: v}
@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dsl
+
+// RuntimeField declares a field of the search computed from the documents by the painless script, it is used in the
+// query, aggregations and sort of the search like a mapped field of the type.
+func (n *Node) RuntimeField(name, typ string, script interface{}) *Node {
+	fieldNode := n.findOrCreateChildByName(kKeywordRuntimeMappings).findOrCreateChildByName(name)
+	fieldNode.Param(kKeywordType, typ)
+	fieldNode.Script().Param(kKeywordScriptSource, script)
+	return fieldNode
+}
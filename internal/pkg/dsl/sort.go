@@ -39,6 +39,11 @@ func (n *Node) SortOrder(field string, order SortOrderT) {
 	}
 }
 
+// SearchAfter sets the sort values of the hit the search continues after.
+func (n *Node) SearchAfter(vals ...interface{}) {
+	n.Param(kKeywordSearchAfter, vals)
+}
+
 /*
 func (q *QueryN) SortOpt(field string, order SortOrder, opts ...SortOpt) {
 	// TODO
@@ -29,6 +29,18 @@ func NewClient(ctx context.Context, cfg *config.Config, longPoll bool, opts ...C
 	addr := cfg.Output.Elasticsearch.Hosts
 	mcph := cfg.Output.Elasticsearch.MaxConnPerHost
 
+	// The in-memory backend serves the requests in place of Elasticsearch, the options still wrap its transport.
+	if cfg.Output.Elasticsearch.Memory.Enabled {
+		transport, address, err := memoryTransport(cfg.Output.Elasticsearch.Memory, escfg.ServiceToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the in-memory backend: %w", err)
+		}
+		escfg.Transport = transport
+		escfg.Addresses = []string{address}
+		escfg.CloudID = ""
+		addr = escfg.Addresses
+	}
+
 	// Apply configuration options
 	for _, opt := range opts {
 		opt(&escfg)
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
@@ -162,3 +163,29 @@ func TestClientCerts(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestTimeRoundTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		fmt.Fprintln(w, "{}")
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Output: config.Output{Elasticsearch: config.Elasticsearch{Hosts: []string{server.URL}}}}
+	client, err := NewClient(context.Background(), cfg, false, TimeRoundTripper())
+	require.NoError(t, err)
+
+	var timer Timer
+	ctx := WithTimer(context.Background(), &timer)
+	res, err := client.Info(client.Info.WithContext(ctx))
+	require.NoError(t, err)
+	res.Body.Close()
+	elapsed := timer.Elapsed()
+	require.GreaterOrEqual(t, elapsed, 10*time.Millisecond)
+
+	res, err = client.Info(client.Info.WithContext(context.Background()))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, elapsed, timer.Elapsed(), "the requests without a timer are not timed")
+}
@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !dev
+
+package es
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// memoryTransport returns the transport of the in-memory backend, authenticating the service token of the output, and
+// its address. The backend is only built with the dev build tag.
+func memoryTransport(config.Memory, string) (http.RoundTripper, string, error) {
+	return nil, "", errors.New("the in-memory backend is only available in the builds with the dev tag")
+}
@@ -0,0 +1,652 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+// Package memory is an in-memory backend used in place of Elasticsearch. It serves the subset of the Elasticsearch
+// REST API used by fleet-server to its Elasticsearch clients, so a fully functional fleet-server runs and enrolls real
+// agents without an Elasticsearch cluster. The documents and the API keys are kept in memory and saved to a state
+// file. Every request is authenticated with the service token of the output, a service token of the state file or an
+// API key. It is only built with the dev build tag.
+//
+// The searches support the match_all, match_none, bool, ids, exists, term, terms, match, match_phrase, prefix,
+// wildcard and range queries, the terms, date_histogram, filter, top_hits and metric aggregations and the runtime
+// fields of fleet-server. The painless scripts are not interpreted, the scripts of fleet-server are recognised by
+// their source and implemented in Go. The other queries, aggregations and scripts are refused with a 400 error.
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/version"
+)
+
+// Address is the address of the Elasticsearch clients of the in-memory backend.
+const Address = "http://memory"
+
+// defaultWaitTimeout is the timeout of the waits of the global checkpoints without timeout.
+const defaultWaitTimeout = 30 * time.Second
+
+// RoundTrip serves a request of an Elasticsearch client.
+func (s *Store) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, eerr := s.authenticate(r); eerr != nil {
+		return respond(r, nil, eerr)
+	}
+	if ct := r.Header.Get("Content-Type"); len(body) > 0 && ct != "" && !strings.Contains(ct, "json") {
+		return respond(r, nil, errBadRequest("illegal_argument_exception", "the memory backend does not support the content type [%s]", ct))
+	}
+	res, eerr := s.serve(r, body)
+	// like the HTTP transports, the requests fail once their context is done, the waits return early on it
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+	return respond(r, res, eerr)
+}
+
+// respond returns the response of a request, the response of the error when it is set.
+func respond(r *http.Request, res interface{}, eerr *esError) (*http.Response, error) {
+	status := http.StatusOK
+	if eerr != nil {
+		status = eerr.Status
+		res = map[string]interface{}{"error": eerr.body(), "status": eerr.Status}
+	} else if sr, ok := res.(statusResponse); ok {
+		status, res = sr.status, sr.body
+	}
+	var data []byte
+	if res != nil && r.Method != http.MethodHead {
+		var err error
+		if data, err = json.Marshal(res); err != nil {
+			return nil, err
+		}
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	// checked by the Elasticsearch clients
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       r,
+	}, nil
+}
+
+// statusResponse is a response with another status than 200.
+type statusResponse struct {
+	status int
+	body   interface{}
+}
+
+func queryBool(q url.Values, name string) bool {
+	v, ok := q[name]
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v[0])
+	return err != nil || b
+}
+
+func (s *Store) serve(r *http.Request, body []byte) (interface{}, *esError) {
+	path := strings.Trim(r.URL.Path, "/")
+	var parts []string
+	if path != "" {
+		for _, part := range strings.Split(path, "/") {
+			p, err := url.PathUnescape(part)
+			if err != nil {
+				return nil, errBadRequest("illegal_argument_exception", "invalid path [%s]", r.URL.Path)
+			}
+			parts = append(parts, p)
+		}
+	}
+	q := r.URL.Query()
+	method := r.Method
+	unsupported := errBadRequest("illegal_argument_exception", "the memory backend does not support [%s %s]", method, r.URL.Path)
+
+	if len(parts) == 0 {
+		return info(), nil
+	}
+
+	switch parts[0] {
+	case "_bulk":
+		return s.bulk("", body)
+	case "_mget":
+		return s.mget("", body)
+	case "_search":
+		return s.searchBody("", body, queryBool(q, "ignore_unavailable"))
+	case "_msearch":
+		return s.msearch("", body)
+	case "_pit":
+		if method == http.MethodDelete {
+			return map[string]interface{}{"succeeded": true, "num_freed": 1}, nil
+		}
+		return nil, unsupported
+	case "_fleet":
+		switch {
+		case len(parts) == 2 && parts[1] == "_fleet_msearch":
+			return s.msearch("", body)
+		case len(parts) == 3 && parts[1] == "secret":
+			return s.secret(parts[2])
+		}
+		return nil, unsupported
+	case "_security":
+		return s.serveSecurity(r, parts[1:], body, unsupported)
+	case "_data_stream":
+		// the lifecycle of the data streams, the documents are not deleted
+		if len(parts) == 3 && parts[2] == "_lifecycle" && method == http.MethodPut {
+			s.mu.Lock()
+			names, eerr := s.resolveLocked(parts[1], false)
+			s.mu.Unlock()
+			if eerr != nil {
+				return nil, eerr
+			}
+			if len(names) == 0 {
+				return nil, errIndexNotFound(parts[1])
+			}
+			return map[string]interface{}{"acknowledged": true}, nil
+		}
+		return nil, unsupported
+	}
+	if strings.HasPrefix(parts[0], "_") {
+		return nil, unsupported
+	}
+
+	name := parts[0]
+	if len(parts) == 1 {
+		switch method {
+		case http.MethodDelete:
+			return s.deleteIndices(name)
+		case http.MethodHead, http.MethodGet:
+			s.mu.Lock()
+			_, eerr := s.resolveLocked(name, false)
+			s.mu.Unlock()
+			return map[string]interface{}{}, eerr
+		case http.MethodPut:
+			s.mu.Lock()
+			_, eerr := s.writeIndexLocked(name)
+			s.mu.Unlock()
+			return map[string]interface{}{"acknowledged": true, "index": name}, eerr
+		}
+		return nil, unsupported
+	}
+
+	switch parts[1] {
+	case "_bulk":
+		return s.bulk(name, body)
+	case "_mget":
+		return s.mget(name, body)
+	case "_search":
+		return s.searchBody(name, body, queryBool(q, "ignore_unavailable"))
+	case "_msearch":
+		return s.msearch(name, body)
+	case "_refresh", "_mapping", "_mappings", "_settings":
+		return map[string]interface{}{"acknowledged": true}, nil
+	case "_pit":
+		s.mu.Lock()
+		_, eerr := s.resolveLocked(name, queryBool(q, "ignore_unavailable"))
+		s.mu.Unlock()
+		if eerr != nil {
+			return nil, eerr
+		}
+		// the point in time is the index, the searches see the later changes
+		return map[string]interface{}{"id": base64.URLEncoding.EncodeToString([]byte(name))}, nil
+	case "_fleet":
+		switch {
+		case len(parts) == 3 && parts[2] == "_fleet_search":
+			return s.searchBody(name, body, queryBool(q, "ignore_unavailable"))
+		case len(parts) == 3 && parts[2] == "_fleet_msearch":
+			return s.msearch(name, body)
+		case len(parts) == 3 && parts[2] == "global_checkpoints":
+			return s.globalCheckpoints(r, name)
+		}
+	case "_doc", "_create":
+		id := ""
+		if len(parts) == 3 {
+			id = parts[2]
+		}
+		switch {
+		case method == http.MethodGet || method == http.MethodHead:
+			return s.get(name, id)
+		case method == http.MethodDelete:
+			res, eerr := s.deleteDoc(name, id)
+			if eerr != nil {
+				return nil, eerr
+			}
+			return statusResponse{status: res.Status, body: writeResponse(res)}, nil
+		}
+		var source map[string]interface{}
+		if eerr := decodeJSON(body, &source); eerr != nil {
+			return nil, eerr
+		}
+		ifSeqNo := int64(-1)
+		if v := q.Get("if_seq_no"); v != "" {
+			ifSeqNo, _ = strconv.ParseInt(v, 10, 64)
+		}
+		res, eerr := s.indexDoc(name, id, source, parts[1] == "_create" || q.Get("op_type") == "create", ifSeqNo)
+		if eerr != nil {
+			return nil, eerr
+		}
+		return statusResponse{status: res.Status, body: writeResponse(res)}, nil
+	case "_update":
+		if len(parts) != 3 {
+			return nil, unsupported
+		}
+		var ub updateBody
+		if eerr := decodeJSON(body, &ub); eerr != nil {
+			return nil, eerr
+		}
+		res, eerr := s.updateDoc(name, parts[2], &ub)
+		if eerr != nil {
+			return nil, eerr
+		}
+		return writeResponse(res), nil
+	case "_update_by_query", "_delete_by_query":
+		return s.byQuery(name, body, parts[1] == "_delete_by_query", queryBool(q, "ignore_unavailable"))
+	}
+	return nil, unsupported
+}
+
+func info() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         "memory",
+		"cluster_name": "memory",
+		"cluster_uuid": "memory",
+		"version": map[string]interface{}{
+			"number":                              version.DefaultVersion,
+			"build_flavor":                        "default",
+			"build_type":                          "memory",
+			"minimum_wire_compatibility_version":  version.DefaultVersion,
+			"minimum_index_compatibility_version": version.DefaultVersion,
+		},
+		"tagline": "You Know, for Search",
+	}
+}
+
+func writeResponse(res writeResult) map[string]interface{} {
+	return map[string]interface{}{
+		"_index":        res.Index,
+		"_id":           res.ID,
+		"_version":      res.Version,
+		"result":        res.Result,
+		"_seq_no":       res.SeqNo,
+		"_primary_term": 1,
+		"status":        res.Status,
+		"_shards":       map[string]interface{}{"total": 1, "successful": 1, "failed": 0},
+	}
+}
+
+func (s *Store) serveSecurity(r *http.Request, parts []string, body []byte, unsupported *esError) (interface{}, *esError) {
+	switch strings.Join(parts, "/") {
+	case "_authenticate":
+		return s.authenticate(r)
+	case "api_key":
+		switch r.Method {
+		case http.MethodPost, http.MethodPut:
+			return s.createAPIKey(body)
+		case http.MethodGet:
+			return s.getAPIKeys(r)
+		case http.MethodDelete:
+			return s.invalidateAPIKeys(body)
+		}
+	case "api_key/_bulk_update":
+		return s.bulkUpdateAPIKeys(body)
+	case "user/_has_privileges":
+		return s.hasPrivileges(r)
+	}
+	return nil, unsupported
+}
+
+// bulk runs the actions of a bulk request, the index of the path is the one of the actions without index.
+func (s *Store) bulk(defaultIndex string, body []byte) (interface{}, *esError) {
+	items := []interface{}{}
+	hasErrors := false
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(make([]byte, 64*1024), len(body)+1)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var meta map[string]struct {
+			Index   string `json:"_index"`
+			ID      string `json:"_id"`
+			IfSeqNo *int64 `json:"if_seq_no"`
+		}
+		if err := json.Unmarshal(line, &meta); err != nil || len(meta) != 1 {
+			return nil, errBadRequest("illegal_argument_exception", "Malformed action/metadata line [%s]", line)
+		}
+		for action, m := range meta {
+			name := m.Index
+			if name == "" {
+				name = defaultIndex
+			}
+			var docBody []byte
+			if action != "delete" {
+				if !sc.Scan() {
+					return nil, errBadRequest("illegal_argument_exception", "The bulk request must be terminated by a newline [\\n]")
+				}
+				docBody = append([]byte(nil), sc.Bytes()...)
+			}
+
+			var (
+				res  writeResult
+				eerr *esError
+			)
+			switch action {
+			case "index", "create":
+				var source map[string]interface{}
+				if eerr = decodeJSON(docBody, &source); eerr == nil {
+					ifSeqNo := int64(-1)
+					if m.IfSeqNo != nil {
+						ifSeqNo = *m.IfSeqNo
+					}
+					res, eerr = s.indexDoc(name, m.ID, source, action == "create", ifSeqNo)
+				}
+			case "update":
+				var ub updateBody
+				if eerr = decodeJSON(docBody, &ub); eerr == nil {
+					res, eerr = s.updateDoc(name, m.ID, &ub)
+				}
+			case "delete":
+				res, eerr = s.deleteDoc(name, m.ID)
+			default:
+				return nil, errBadRequest("illegal_argument_exception", "Malformed action/metadata line, unknown action [%s]", action)
+			}
+
+			item := map[string]interface{}{"_index": name, "_id": m.ID}
+			if eerr != nil {
+				hasErrors = true
+				item["status"] = eerr.Status
+				item["error"] = eerr.body()
+			} else {
+				item = writeResponse(res)
+			}
+			items = append(items, map[string]interface{}{action: item})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errBadRequest("illegal_argument_exception", "%v", err)
+	}
+	return map[string]interface{}{"took": 0, "errors": hasErrors, "items": items}, nil
+}
+
+// mget returns the documents of a multi get request.
+func (s *Store) mget(defaultIndex string, body []byte) (interface{}, *esError) {
+	var req struct {
+		Docs []struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"docs"`
+		IDs []string `json:"ids"`
+	}
+	if eerr := decodeJSON(body, &req); eerr != nil {
+		return nil, eerr
+	}
+	for _, id := range req.IDs {
+		req.Docs = append(req.Docs, struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}{ID: id})
+	}
+	docs := make([]interface{}, 0, len(req.Docs))
+	for _, d := range req.Docs {
+		name := d.Index
+		if name == "" {
+			name = defaultIndex
+		}
+		docs = append(docs, getResponse(name, d.ID, s.getDoc(name, d.ID)))
+	}
+	return map[string]interface{}{"docs": docs}, nil
+}
+
+func getResponse(name, id string, doc *document) map[string]interface{} {
+	if doc == nil {
+		return map[string]interface{}{"_index": name, "_id": id, "found": false}
+	}
+	return map[string]interface{}{
+		"_index":        name,
+		"_id":           id,
+		"_version":      doc.Version,
+		"_seq_no":       doc.SeqNo,
+		"_primary_term": 1,
+		"found":         true,
+		"_source":       doc.Source,
+	}
+}
+
+func (s *Store) get(name, id string) (interface{}, *esError) {
+	doc := s.getDoc(name, id)
+	res := getResponse(name, id, doc)
+	if doc == nil {
+		return statusResponse{status: http.StatusNotFound, body: res}, nil
+	}
+	return res, nil
+}
+
+func (s *Store) secret(id string) (interface{}, *esError) {
+	doc := s.getDoc(".fleet-secrets", id)
+	if doc == nil {
+		return nil, &esError{Status: http.StatusNotFound, Type: "resource_not_found_exception", Reason: "No secret with id [" + id + "]"}
+	}
+	return map[string]interface{}{"id": id, "value": doc.Source["value"]}, nil
+}
+
+// pitIndices returns the indices of a point in time.
+func pitIndices(id string) (string, *esError) {
+	name, err := base64.URLEncoding.DecodeString(id)
+	if err != nil {
+		return "", errBadRequest("illegal_argument_exception", "invalid id for the point in time [%s]", id)
+	}
+	return string(name), nil
+}
+
+func (s *Store) searchBody(indices string, body []byte, ignoreUnavailable bool) (interface{}, *esError) {
+	var req searchRequest
+	if eerr := decodeJSON(body, &req); eerr != nil {
+		return nil, eerr
+	}
+	if req.PIT != nil {
+		var eerr *esError
+		if indices, eerr = pitIndices(req.PIT.ID); eerr != nil {
+			return nil, eerr
+		}
+	}
+	if indices == "" {
+		indices = "*"
+	}
+	return s.search(indices, &req, ignoreUnavailable)
+}
+
+// msearch runs the searches of a multi search, the header of each search is followed by its body.
+func (s *Store) msearch(defaultIndex string, body []byte) (interface{}, *esError) {
+	responses := []interface{}{}
+	lines := bytes.Split(body, []byte("\n"))
+	for i := 0; i < len(lines); i++ {
+		if len(bytes.TrimSpace(lines[i])) == 0 {
+			continue
+		}
+		var header struct {
+			Index             interface{} `json:"index"`
+			IgnoreUnavailable bool        `json:"ignore_unavailable"`
+		}
+		if eerr := decodeJSON(lines[i], &header); eerr != nil {
+			return nil, eerr
+		}
+		i++
+		if i >= len(lines) {
+			return nil, errBadRequest("illegal_argument_exception", "the search of the header [%s] is missing", lines[i-1])
+		}
+		indices := defaultIndex
+		switch idx := header.Index.(type) {
+		case string:
+			indices = idx
+		case []interface{}:
+			indices = strings.Join(toStrings(idx), ",")
+		}
+		res, eerr := s.searchBody(indices, lines[i], header.IgnoreUnavailable)
+		if eerr != nil {
+			responses = append(responses, map[string]interface{}{"error": eerr.body(), "status": eerr.Status})
+			continue
+		}
+		m := res.(map[string]interface{})
+		m["status"] = http.StatusOK
+		responses = append(responses, m)
+	}
+	return map[string]interface{}{"took": 0, "responses": responses}, nil
+}
+
+// globalCheckpoints returns the global checkpoint of the index, once it is after the checkpoint of the request when
+// the request waits for its advance.
+func (s *Store) globalCheckpoints(r *http.Request, name string) (interface{}, *esError) {
+	q := r.URL.Query()
+	if !queryBool(q, "wait_for_advance") {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		idx, ok := s.state.Indices[name]
+		if !ok {
+			return nil, errIndexNotFound(name)
+		}
+		return map[string]interface{}{"global_checkpoints": []int64{idx.SeqNo}, "timed_out": false}, nil
+	}
+
+	checkpoint := int64(-1)
+	if v := q.Get("checkpoints"); v != "" {
+		cps := strings.Split(v, ",")
+		n, err := strconv.ParseInt(strings.TrimSpace(cps[0]), 10, 64)
+		if err != nil {
+			return nil, errBadRequest("illegal_argument_exception", "invalid checkpoints [%s]", v)
+		}
+		checkpoint = n
+	}
+	timeout := defaultWaitTimeout
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errBadRequest("illegal_argument_exception", "invalid timeout [%s]", v)
+		}
+		timeout = d
+	}
+	current, advanced := s.waitCheckpoint(r.Context(), name, checkpoint, timeout)
+	return map[string]interface{}{"global_checkpoints": []int64{current}, "timed_out": !advanced}, nil
+}
+
+// byQuery updates or deletes the documents matching the query of the body, the updates run its script.
+func (s *Store) byQuery(indices string, body []byte, del, ignoreUnavailable bool) (interface{}, *esError) {
+	var req struct {
+		searchRequest
+		Script interface{} `json:"script"`
+	}
+	if eerr := decodeJSON(body, &req); eerr != nil {
+		return nil, eerr
+	}
+	// the script is checked before the search so an unknown script fails when no document matches too
+	if req.Script != nil && !del {
+		code, params, err := scriptOf(req.Script)
+		if err == nil {
+			_, err = updateScriptOf(code, params)
+		}
+		if err != nil {
+			return nil, errBadRequest("script_exception", "%v", err)
+		}
+	}
+	all := 1 << 30
+	req.Size = &all
+	req.Source = false
+	res, eerr := s.search(indices, &req.searchRequest, ignoreUnavailable)
+	if eerr != nil {
+		return nil, eerr
+	}
+
+	updated, deleted, noops := 0, 0, 0
+	failures := []interface{}{}
+	hits := res["hits"].(map[string]interface{})["hits"].([]interface{})
+	for _, h := range hits {
+		hit := h.(map[string]interface{})
+		name, id := hit["_index"].(string), hit["_id"].(string)
+		var (
+			wr   writeResult
+			werr *esError
+		)
+		switch {
+		case del:
+			wr, werr = s.deleteDoc(name, id)
+		case req.Script != nil:
+			wr, werr = s.updateDoc(name, id, &updateBody{Script: req.Script})
+		default:
+			// an update without script writes the documents again
+			wr, werr = s.updateDoc(name, id, &updateBody{Doc: map[string]interface{}{}, DetectNoop: new(bool)})
+		}
+		if werr != nil {
+			failures = append(failures, map[string]interface{}{"index": name, "id": id, "cause": werr.body(), "status": werr.Status})
+			continue
+		}
+		switch wr.Result {
+		case "deleted":
+			deleted++
+		case "noop":
+			noops++
+		case "updated":
+			updated++
+		}
+	}
+	return map[string]interface{}{
+		"took":              0,
+		"timed_out":         false,
+		"total":             len(hits),
+		"updated":           updated,
+		"deleted":           deleted,
+		"noops":             noops,
+		"batches":           1,
+		"version_conflicts": 0,
+		"failures":          failures,
+	}, nil
+}
+
+// deleteIndices deletes the indices of a comma separated list of names and patterns.
+func (s *Store) deleteIndices(expr string) (interface{}, *esError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, eerr := s.resolveLocked(expr, false)
+	if eerr != nil {
+		return nil, eerr
+	}
+	for _, name := range names {
+		delete(s.state.Indices, name)
+	}
+	if len(names) > 0 {
+		s.changedLocked()
+	}
+	return map[string]interface{}{"acknowledged": true}, nil
+}
+
+// String returns the address of the store, for the logs of the clients.
+func (s *Store) String() string {
+	if s.path == "" {
+		return Address
+	}
+	return fmt.Sprintf("%s (%s)", Address, s.path)
+}
@@ -0,0 +1,305 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev && !integration
+
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, path string) (*Store, *elasticsearch.Client) {
+	t.Helper()
+	store, err := newStore(path, "output-token")
+	require.NoError(t, err)
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses:    []string{Address},
+		Transport:    store,
+		ServiceToken: "output-token",
+	})
+	require.NoError(t, err)
+	return store, client
+}
+
+func do(t *testing.T, client *elasticsearch.Client, req esapi.Request) (int, map[string]interface{}) {
+	t.Helper()
+	res, err := req.Do(context.Background(), client)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	return res.StatusCode, body
+}
+
+func TestDocuments(t *testing.T) {
+	_, client := newTestClient(t, "")
+
+	status, body := do(t, client, esapi.IndexRequest{Index: ".fleet-agents", DocumentID: "a1", Body: strings.NewReader(`{"active":true,"policy_id":"p1"}`)})
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created", body["result"])
+	assert.EqualValues(t, 0, body["_seq_no"])
+
+	status, _ = do(t, client, esapi.CreateRequest{Index: ".fleet-agents", DocumentID: "a1", Body: strings.NewReader(`{}`)})
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, body = do(t, client, esapi.UpdateRequest{Index: ".fleet-agents", DocumentID: "a1", Body: strings.NewReader(`{"doc":{"last_checkin":"2024-01-01T00:00:00Z"}}`)})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "updated", body["result"])
+
+	status, body = do(t, client, esapi.GetRequest{Index: ".fleet-agents", DocumentID: "a1"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"active": true, "policy_id": "p1", "last_checkin": "2024-01-01T00:00:00Z"}, body["_source"])
+	assert.EqualValues(t, 1, body["_seq_no"])
+
+	status, body = do(t, client, esapi.UpdateRequest{Index: ".fleet-agents", DocumentID: "a2", Body: strings.NewReader(`{"doc":{}}`)})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "document_missing_exception", body["error"].(map[string]interface{})["type"])
+
+	status, _ = do(t, client, esapi.DeleteRequest{Index: ".fleet-agents", DocumentID: "a1"})
+	assert.Equal(t, http.StatusOK, status)
+	status, body = do(t, client, esapi.GetRequest{Index: ".fleet-agents", DocumentID: "a1"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, false, body["found"])
+}
+
+func TestBulk(t *testing.T) {
+	_, client := newTestClient(t, "")
+
+	bulk := `{"index":{"_index":".fleet-actions","_id":"1"}}
+{"action_id":"1","agents":["a1"]}
+{"create":{"_index":".fleet-actions","_id":"1"}}
+{"action_id":"1"}
+{"update":{"_index":".fleet-actions","_id":"1","retry_on_conflict":3}}
+{"doc":{"agents":["a1","a2"]}}
+{"delete":{"_index":".fleet-actions","_id":"2"}}
+`
+	status, body := do(t, client, esapi.BulkRequest{Body: strings.NewReader(bulk)})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, body["errors"])
+	items := body["items"].([]interface{})
+	require.Len(t, items, 4)
+	assert.EqualValues(t, http.StatusCreated, items[0].(map[string]interface{})["index"].(map[string]interface{})["status"])
+	assert.EqualValues(t, http.StatusConflict, items[1].(map[string]interface{})["create"].(map[string]interface{})["status"])
+	assert.EqualValues(t, http.StatusOK, items[2].(map[string]interface{})["update"].(map[string]interface{})["status"])
+	assert.EqualValues(t, http.StatusNotFound, items[3].(map[string]interface{})["delete"].(map[string]interface{})["status"])
+
+	status, body = do(t, client, esapi.MgetRequest{Index: ".fleet-actions", Body: strings.NewReader(`{"ids":["1","2"]}`)})
+	require.Equal(t, http.StatusOK, status)
+	docs := body["docs"].([]interface{})
+	require.Len(t, docs, 2)
+	assert.Equal(t, []interface{}{"a1", "a2"}, docs[0].(map[string]interface{})["_source"].(map[string]interface{})["agents"])
+	assert.Equal(t, false, docs[1].(map[string]interface{})["found"])
+}
+
+func TestSearch(t *testing.T) {
+	_, client := newTestClient(t, "")
+	bulk := `{"index":{"_index":".fleet-agents","_id":"a1"}}
+{"active":true,"agent":{"version":"8.14.0"},"local_metadata":{"os":{"platform":"Linux"}},"enrolled_at":"2024-01-01T00:00:00Z"}
+{"index":{"_index":".fleet-agents","_id":"a2"}}
+{"active":true,"agent":{"version":"7.17.1"},"enrolled_at":"2024-02-01T00:00:00Z"}
+{"index":{"_index":".fleet-agents","_id":"a3"}}
+{"active":false,"agent":{"version":"8.15.0"},"enrolled_at":"2024-03-01T00:00:00Z"}
+`
+	status, _ := do(t, client, esapi.BulkRequest{Body: strings.NewReader(bulk)})
+	require.Equal(t, http.StatusOK, status)
+
+	ids := func(body map[string]interface{}) []string {
+		var ids []string
+		for _, hit := range body["hits"].(map[string]interface{})["hits"].([]interface{}) {
+			ids = append(ids, hit.(map[string]interface{})["_id"].(string))
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"term", `{"query":{"term":{"active":true}},"sort":[{"enrolled_at":"desc"}]}`, []string{"a2", "a1"}},
+		{"range", `{"query":{"range":{"enrolled_at":{"gte":"2024-02-01T00:00:00Z"}}},"sort":["_doc"]}`, []string{"a2", "a3"}},
+		{"bool", `{"query":{"bool":{"filter":[{"term":{"active":true}}],"must_not":[{"exists":{"field":"local_metadata.os.platform"}}]}}}`, []string{"a2"}},
+		{"search after", `{"size":1,"sort":[{"_seq_no":"asc"}],"search_after":[0]}`, []string{"a2"}},
+		{"runtime field", `{"runtime_mappings":{"major":{"type":"long","script":{"source":"if (doc.containsKey('agent.version') && doc['agent.version'].size() > 0) {String v = doc['agent.version'].value; int i = v.indexOf('.'); try { emit(Long.parseLong(i < 0 ? v : v.substring(0, i))); } catch (NumberFormatException e) {}}"}}},"query":{"term":{"major":8}},"sort":["_doc"]}`, []string{"a1", "a3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := do(t, client, esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(tc.query)})
+			require.Equal(t, http.StatusOK, status, body)
+			assert.Equal(t, tc.want, ids(body))
+		})
+	}
+
+	status, body := do(t, client, esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"size":0,"aggs":{"active":{"terms":{"field":"active"}}}}`)})
+	require.Equal(t, http.StatusOK, status)
+	buckets := body["aggregations"].(map[string]interface{})["active"].(map[string]interface{})["buckets"].([]interface{})
+	require.Len(t, buckets, 2)
+	assert.EqualValues(t, 2, buckets[0].(map[string]interface{})["doc_count"])
+
+	msearch := `{"index":".fleet-agents"}
+{"query":{"ids":{"values":["a3"]}}}
+{"index":".fleet-missing"}
+{"query":{"match_all":{}}}
+`
+	status, body = do(t, client, esapi.MsearchRequest{Body: strings.NewReader(msearch)})
+	require.Equal(t, http.StatusOK, status)
+	responses := body["responses"].([]interface{})
+	require.Len(t, responses, 2)
+	assert.Equal(t, []string{"a3"}, ids(responses[0].(map[string]interface{})))
+	assert.EqualValues(t, http.StatusNotFound, responses[1].(map[string]interface{})["status"])
+}
+
+func TestGlobalCheckpoints(t *testing.T) {
+	_, client := newTestClient(t, "")
+
+	checkpoints := func(query string) (int, map[string]interface{}) {
+		r, err := http.NewRequest(http.MethodGet, "/.fleet-actions/_fleet/global_checkpoints?"+query, nil)
+		require.NoError(t, err)
+		res, err := client.Perform(r)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body
+	}
+
+	status, _ := checkpoints("")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	done := make(chan map[string]interface{})
+	go func() {
+		_, body := checkpoints("wait_for_advance=true&wait_for_index=true&checkpoints=-1&timeout=10s")
+		done <- body
+	}()
+	time.Sleep(10 * time.Millisecond)
+	status, _ = do(t, client, esapi.IndexRequest{Index: ".fleet-actions", Body: strings.NewReader(`{}`)})
+	require.Equal(t, http.StatusCreated, status)
+	body := <-done
+	assert.Equal(t, []interface{}{float64(0)}, body["global_checkpoints"])
+	assert.Equal(t, false, body["timed_out"])
+
+	_, body = checkpoints("wait_for_advance=true&checkpoints=0&timeout=10ms")
+	assert.Equal(t, true, body["timed_out"])
+}
+
+func TestAPIKeys(t *testing.T) {
+	_, client := newTestClient(t, "")
+
+	status, body := do(t, client, esapi.SecurityCreateAPIKeyRequest{Body: strings.NewReader(`{"name":"agent","metadata":{"agent_id":"a1"}}`)})
+	require.Equal(t, http.StatusOK, status)
+	id := body["id"].(string)
+	encoded := body["encoded"].(string)
+
+	auth := func(key string) (int, map[string]interface{}) {
+		header := http.Header{}
+		header.Set("Authorization", "ApiKey "+key)
+		return do(t, client, esapi.SecurityAuthenticateRequest{Header: header})
+	}
+	status, body = auth(encoded)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, id, body["api_key"].(map[string]interface{})["id"])
+
+	status, _ = auth(base64.StdEncoding.EncodeToString([]byte(id + ":wrong")))
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body = do(t, client, esapi.SecurityGetAPIKeyRequest{ID: id})
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, body["api_keys"], 1)
+
+	status, body = do(t, client, esapi.SecurityInvalidateAPIKeyRequest{Body: strings.NewReader(`{"ids":["` + id + `"]}`)})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{id}, body["invalidated_api_keys"])
+
+	status, _ = auth(encoded)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, client := newTestClient(t, path)
+
+	status, _ := do(t, client, esapi.IndexRequest{Index: ".fleet-policies", DocumentID: "p1", Body: strings.NewReader(`{"revision_idx":1}`)})
+	require.Equal(t, http.StatusCreated, status)
+	status, body := do(t, client, esapi.SecurityCreateAPIKeyRequest{Body: strings.NewReader(`{"name":"agent"}`)})
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, store.save())
+
+	_, client = newTestClient(t, path)
+	status, doc := do(t, client, esapi.GetRequest{Index: ".fleet-policies", DocumentID: "p1"})
+	require.Equal(t, http.StatusOK, status)
+	assert.EqualValues(t, 1, doc["_source"].(map[string]interface{})["revision_idx"])
+	assert.EqualValues(t, 0, doc["_seq_no"])
+
+	header := http.Header{}
+	header.Set("Authorization", "ApiKey "+body["encoded"].(string))
+	status, _ = do(t, client, esapi.SecurityAuthenticateRequest{Header: header})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestUnsupported(t *testing.T) {
+	_, client := newTestClient(t, "")
+	status, body := do(t, client, esapi.ClusterHealthRequest{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body["error"].(map[string]interface{})["reason"], "the memory backend does not support")
+
+	// the unknown queries and scripts fail on the empty indices too
+	status, _ = do(t, client, esapi.IndicesCreateRequest{Index: ".fleet-agents"})
+	require.Equal(t, http.StatusOK, status)
+	for name, req := range map[string]esapi.Request{
+		"query":        esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"query":{"bool":{"filter":[{"fuzzy":{"a":"b"}}]}}}`)},
+		"filter agg":   esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"aggs":{"f":{"filter":{"regexp":{"a":"b.*"}}}}}`)},
+		"aggregation":  esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"aggs":{"h":{"histogram":{"field":"a","interval":1}}}}`)},
+		"runtime":      esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"runtime_mappings":{"a":{"type":"long","script":{"source":"emit(1)"}}}}`)},
+		"script field": esapi.SearchRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"script_fields":{"a":{"script":{"source":"1"}}}}`)},
+		"update":       esapi.UpdateByQueryRequest{Index: []string{".fleet-agents"}, Body: strings.NewReader(`{"script":{"source":"ctx._source.a = 1"}}`)},
+	} {
+		status, body := do(t, client, req)
+		assert.Equal(t, http.StatusBadRequest, status, name)
+		assert.NotNil(t, body["error"], name)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	store, _ := newTestClient(t, "")
+	store.state.ServiceTokens = []serviceToken{{Name: "elastic/fleet-server/seeded", Token: "seeded-token"}}
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "output service token", token: "output-token", status: http.StatusOK},
+		{name: "seeded service token", token: "seeded-token", status: http.StatusOK},
+		{name: "unknown service token", token: "other", status: http.StatusUnauthorized},
+		{name: "no credentials", status: http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := elasticsearch.NewClient(elasticsearch.Config{
+				Addresses:    []string{Address},
+				Transport:    store,
+				ServiceToken: tc.token,
+			})
+			require.NoError(t, err)
+			status, _ := do(t, client, esapi.GetRequest{Index: ".fleet-agents", DocumentID: "a1", Header: http.Header{"X-Elastic-Product-Check": {"skip"}}})
+			if tc.status == http.StatusOK {
+				assert.Equal(t, http.StatusNotFound, status, "the authenticated request reaches the index")
+				return
+			}
+			assert.Equal(t, tc.status, status)
+		})
+	}
+}
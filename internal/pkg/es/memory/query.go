@@ -0,0 +1,1160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// docView is a document as seen by a search, with the runtime fields of the search.
+type docView struct {
+	index   string
+	id      string
+	doc     *document
+	runtime map[string]*runtimeField
+	cache   map[string][]interface{}
+}
+
+// runtimeField is a runtime field declared by a search, its values are emitted by its script.
+type runtimeField struct {
+	script runtimeScript
+}
+
+func (d *docView) values(field string) []interface{} {
+	switch field {
+	case "_id":
+		return []interface{}{d.id}
+	case "_index":
+		return []interface{}{d.index}
+	case "_seq_no":
+		return []interface{}{float64(d.doc.SeqNo)}
+	case "_version":
+		return []interface{}{float64(d.doc.Version)}
+	}
+	rf, ok := d.runtime[field]
+	if !ok {
+		return sourceValues(d.doc.Source, field)
+	}
+	if vals, ok := d.cache[field]; ok {
+		return vals
+	}
+	vals := rf.script(func(field string) []interface{} {
+		vals := sourceValues(d.doc.Source, field)
+		sort.Slice(vals, func(i, j int) bool {
+			c, _ := compareValues(vals[i], vals[j])
+			return c < 0
+		})
+		return vals
+	})
+	if d.cache == nil {
+		d.cache = map[string][]interface{}{}
+	}
+	d.cache[field] = vals
+	return vals
+}
+
+// sourceValues returns the values of a field of a source, the values of the arrays are flattened. The dots of the
+// field name are the objects of the source, or are part of the keys of the source.
+func sourceValues(source map[string]interface{}, field string) []interface{} {
+	var vals []interface{}
+	collectValues(source, strings.Split(field, "."), &vals)
+	return vals
+}
+
+func collectValues(v interface{}, parts []string, vals *[]interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v {
+			collectValues(item, parts, vals)
+		}
+	case map[string]interface{}:
+		if len(parts) == 0 {
+			*vals = append(*vals, v)
+			return
+		}
+		for i := 1; i <= len(parts); i++ {
+			if child, ok := v[strings.Join(parts[:i], ".")]; ok {
+				collectValues(child, parts[i:], vals)
+			}
+		}
+	default:
+		if len(parts) == 0 {
+			*vals = append(*vals, v)
+		}
+	}
+}
+
+// compareValues compares two values of the same kind, the numbers written as strings are numbers.
+func compareValues(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case float64:
+		switch b := b.(type) {
+		case float64:
+			return compareFloats(a, b), true
+		case string:
+			if f, err := strconv.ParseFloat(b, 64); err == nil {
+				return compareFloats(a, f), true
+			}
+		}
+	case string:
+		switch b := b.(type) {
+		case string:
+			return strings.Compare(a, b), true
+		case float64, bool:
+			c, ok := compareValues(b, a)
+			return -c, ok
+		}
+	case bool:
+		switch b := b.(type) {
+		case bool:
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			}
+			return 1, true
+		case string:
+			if bb, err := strconv.ParseBool(b); err == nil {
+				return compareValues(a, bb)
+			}
+		}
+	}
+	return 0, false
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// parseTime returns the time of a date value, a date string or a timestamp in milliseconds. The date math of the
+// range bounds is relative to now, like now-5m.
+func parseTime(v interface{}, now time.Time) (time.Time, bool) {
+	switch v := v.(type) {
+	case float64:
+		return time.UnixMilli(int64(v)), true
+	case string:
+		if strings.HasPrefix(v, "now") {
+			return dateMath(strings.TrimPrefix(v, "now"), now)
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateMath applies the date math expression, like -5m or +1d/d, to t.
+func dateMath(expr string, t time.Time) (time.Time, bool) {
+	for expr != "" {
+		op := expr[0]
+		expr = expr[1:]
+		if op == '/' {
+			// rounding
+			if expr == "" {
+				return t, false
+			}
+			t = t.Truncate(unitDuration(expr[0]))
+			expr = expr[1:]
+			continue
+		}
+		if op != '+' && op != '-' {
+			return t, false
+		}
+		i := 0
+		for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+			i++
+		}
+		n := 1
+		if i > 0 {
+			n, _ = strconv.Atoi(expr[:i])
+		}
+		if i >= len(expr) {
+			return t, false
+		}
+		d := unitDuration(expr[i])
+		if d == 0 {
+			return t, false
+		}
+		if op == '-' {
+			n = -n
+		}
+		t = t.Add(time.Duration(n) * d)
+		expr = expr[i+1:]
+	}
+	return t, true
+}
+
+func unitDuration(unit byte) time.Duration {
+	switch unit {
+	case 's':
+		return time.Second
+	case 'm':
+		return time.Minute
+	case 'h', 'H':
+		return time.Hour
+	case 'd':
+		return 24 * time.Hour
+	case 'w':
+		return 7 * 24 * time.Hour
+	case 'M':
+		return 30 * 24 * time.Hour
+	case 'y':
+		return 365 * 24 * time.Hour
+	}
+	return 0
+}
+
+// compareRange compares a value of a document to a bound of a range, the dates are compared as times.
+func compareRange(v, bound interface{}, now time.Time) (int, bool) {
+	if isDate(v) || isDate(bound) {
+		vt, vok := parseTime(v, now)
+		bt, bok := parseTime(bound, now)
+		if vok && bok {
+			return vt.Compare(bt), true
+		}
+	}
+	return compareValues(v, bound)
+}
+
+func isDate(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	_, ok = parseTime(s, time.Time{})
+	return ok
+}
+
+// clauses returns the clauses of a bool query, a single clause or an array.
+func clauses(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	return []interface{}{v}
+}
+
+// fieldQuery returns the field and the body of a term, terms, range, match, prefix or wildcard query.
+func fieldQuery(body interface{}) (string, interface{}, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("query is not an object")
+	}
+	for k, v := range m {
+		if k == "boost" || k == "_name" {
+			continue
+		}
+		return k, v, nil
+	}
+	return "", nil, fmt.Errorf("query has no field")
+}
+
+// valueOf returns the value of a term query, written as the value or as an object with it.
+func valueOf(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return v
+}
+
+// checkQuery returns an error when the query or one of the queries of its bool queries is not supported, the queries
+// are checked before the documents are matched so the unknown queries fail on the empty indices too.
+func checkQuery(q map[string]interface{}) error {
+	for typ, body := range q {
+		switch typ {
+		case "match_all", "match_none", "ids", "exists", "term", "terms", "match", "match_phrase", "prefix", "wildcard", "range":
+		case "bool":
+			m, ok := body.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("[bool] query is not an object")
+			}
+			for _, key := range []string{"must", "filter", "must_not", "should"} {
+				for _, c := range clauses(m[key]) {
+					cm, _ := c.(map[string]interface{})
+					if err := checkQuery(cm); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("unknown query [%s]", typ)
+		}
+	}
+	return nil
+}
+
+// matches returns true when the document matches the query.
+func (d *docView) matches(q map[string]interface{}, now time.Time) (bool, error) {
+	for typ, body := range q {
+		switch typ {
+		case "match_all":
+			return true, nil
+		case "match_none":
+			return false, nil
+		case "bool":
+			return d.matchesBool(body, now)
+		case "ids":
+			m, _ := body.(map[string]interface{})
+			for _, id := range clauses(m["values"]) {
+				if id == d.id {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "exists":
+			m, _ := body.(map[string]interface{})
+			field, _ := m["field"].(string)
+			return len(d.values(field)) > 0, nil
+		case "term", "terms", "match", "match_phrase", "prefix", "wildcard":
+			field, value, err := fieldQuery(body)
+			if err != nil {
+				return false, fmt.Errorf("[%s] %w", typ, err)
+			}
+			var match func(v interface{}) bool
+			switch typ {
+			case "term":
+				want := valueOf(value, "value")
+				match = func(v interface{}) bool { c, ok := compareValues(v, want); return ok && c == 0 }
+			case "terms":
+				wants := clauses(value)
+				match = func(v interface{}) bool {
+					for _, want := range wants {
+						if c, ok := compareValues(v, want); ok && c == 0 {
+							return true
+						}
+					}
+					return false
+				}
+			case "match", "match_phrase":
+				want := valueOf(value, "query")
+				match = func(v interface{}) bool { return matchText(v, want) }
+			case "prefix":
+				prefix := fmt.Sprint(valueOf(value, "value"))
+				match = func(v interface{}) bool { s, ok := v.(string); return ok && strings.HasPrefix(s, prefix) }
+			case "wildcard":
+				pattern := fmt.Sprint(valueOf(value, "value"))
+				match = func(v interface{}) bool {
+					s, ok := v.(string)
+					if !ok {
+						return false
+					}
+					matched, _ := filepath.Match(pattern, s)
+					return matched
+				}
+			}
+			for _, v := range d.values(field) {
+				if match(v) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "range":
+			field, value, err := fieldQuery(body)
+			if err != nil {
+				return false, fmt.Errorf("[range] %w", err)
+			}
+			bounds, _ := value.(map[string]interface{})
+			for _, v := range d.values(field) {
+				if inRange(v, bounds, now) {
+					return true, nil
+				}
+			}
+			return false, nil
+		default:
+			return false, fmt.Errorf("unknown query [%s]", typ)
+		}
+	}
+	// an empty query matches all the documents
+	return true, nil
+}
+
+func inRange(v interface{}, bounds map[string]interface{}, now time.Time) bool {
+	for op, bound := range bounds {
+		var ok bool
+		switch op {
+		case "gt", "gte", "lt", "lte", "from", "to":
+			var c int
+			if c, ok = compareRange(v, bound, now); !ok {
+				return false
+			}
+			switch op {
+			case "gt":
+				ok = c > 0
+			case "gte", "from":
+				ok = c >= 0
+			case "lt":
+				ok = c < 0
+			case "lte", "to":
+				ok = c <= 0
+			}
+		default:
+			// format, time_zone, boost
+			ok = true
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchText returns true when the value contains all the words of the query, ignoring the case.
+func matchText(v, query interface{}) bool {
+	if c, ok := compareValues(v, query); ok && c == 0 {
+		return true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	words := strings.Fields(strings.ToLower(s))
+	for _, want := range strings.Fields(strings.ToLower(fmt.Sprint(query))) {
+		found := false
+		for _, w := range words {
+			if w == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *docView) matchesBool(body interface{}, now time.Time) (bool, error) {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("[bool] query is not an object")
+	}
+	matchAll := func(key string, want bool) (bool, error) {
+		for _, c := range clauses(m[key]) {
+			cm, _ := c.(map[string]interface{})
+			ok, err := d.matches(cm, now)
+			if err != nil {
+				return false, err
+			}
+			if ok != want {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	for key, want := range map[string]bool{"must": true, "filter": true, "must_not": false} {
+		ok, err := matchAll(key, want)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	should := clauses(m["should"])
+	if len(should) == 0 {
+		return true, nil
+	}
+	minimum := 0
+	if len(clauses(m["must"]))+len(clauses(m["filter"])) == 0 {
+		minimum = 1
+	}
+	switch v := m["minimum_should_match"].(type) {
+	case float64:
+		minimum = int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			minimum = n
+		}
+	}
+	matched := 0
+	for _, c := range should {
+		cm, _ := c.(map[string]interface{})
+		ok, err := d.matches(cm, now)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+	return matched >= minimum, nil
+}
+
+// sortField is a field of the sort of a search.
+type sortField struct {
+	field string
+	desc  bool
+}
+
+// parseSort parses the sort of a search: a field, an object of the field and its order, or an array of them.
+func parseSort(v interface{}) ([]sortField, error) {
+	var fields []sortField
+	for _, item := range clauses(v) {
+		switch item := item.(type) {
+		case string:
+			fields = append(fields, sortField{field: item, desc: item == "_score"})
+		case map[string]interface{}:
+			for field, order := range item {
+				if m, ok := order.(map[string]interface{}); ok {
+					order = m["order"]
+				}
+				fields = append(fields, sortField{field: field, desc: order == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("invalid sort %v", item)
+		}
+	}
+	return fields, nil
+}
+
+// sortValues returns the values of the document for the sort, the lowest value of a field sorted in the ascending
+// order and the highest one in the descending order.
+func (d *docView) sortValues(fields []sortField) []interface{} {
+	vals := make([]interface{}, len(fields))
+	for i, f := range fields {
+		switch f.field {
+		case "_score":
+			vals[i] = float64(1)
+			continue
+		case "_shard_doc", "_doc":
+			vals[i] = float64(d.doc.SeqNo)
+			continue
+		}
+		for _, v := range d.values(f.field) {
+			if _, isMap := v.(map[string]interface{}); isMap {
+				continue
+			}
+			if vals[i] == nil {
+				vals[i] = v
+				continue
+			}
+			if c, ok := compareValues(v, vals[i]); ok && (c < 0) != f.desc && c != 0 {
+				vals[i] = v
+			}
+		}
+	}
+	return vals
+}
+
+// compareSort compares the sort values of two documents, the missing values are last.
+func compareSort(fields []sortField, a, b []interface{}) int {
+	for i, f := range fields {
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+		c, ok := compareValues(a[i], b[i])
+		if !ok {
+			// numbers before strings
+			_, aNum := a[i].(float64)
+			_, bNum := b[i].(float64)
+			c = map[bool]int{true: -1, false: 1}[aNum && !bNum]
+		}
+		if f.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortDocs sorts the documents, by index and by sequence number without sort.
+func sortDocs(docs []*docView, fields []sortField) [][]interface{} {
+	vals := make(map[*docView][]interface{}, len(docs))
+	for _, d := range docs {
+		vals[d] = d.sortValues(fields)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		if c := compareSort(fields, vals[docs[i]], vals[docs[j]]); c != 0 {
+			return c < 0
+		}
+		if docs[i].index != docs[j].index {
+			return docs[i].index < docs[j].index
+		}
+		return docs[i].doc.SeqNo < docs[j].doc.SeqNo
+	})
+	sorted := make([][]interface{}, len(docs))
+	for i, d := range docs {
+		sorted[i] = vals[d]
+	}
+	return sorted
+}
+
+// filterSource returns the fields of the source selected by the _source of a search: true, false, a pattern, an
+// array of patterns, or an object of includes and excludes.
+func filterSource(source map[string]interface{}, spec interface{}) (map[string]interface{}, bool) {
+	var includes, excludes []string
+	switch spec := spec.(type) {
+	case nil:
+		return source, true
+	case bool:
+		return source, spec
+	case string:
+		includes = []string{spec}
+	case []interface{}:
+		includes = toStrings(spec)
+	case map[string]interface{}:
+		includes = toStrings(clauses(spec["includes"]))
+		includes = append(includes, toStrings(clauses(spec["include"]))...)
+		excludes = toStrings(clauses(spec["excludes"]))
+		excludes = append(excludes, toStrings(clauses(spec["exclude"]))...)
+	}
+	return filterObject(source, "", includes, excludes), true
+}
+
+func toStrings(vals []interface{}) []string {
+	s := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if str, ok := v.(string); ok {
+			s = append(s, str)
+		}
+	}
+	return s
+}
+
+func filterObject(obj map[string]interface{}, prefix string, includes, excludes []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range obj {
+		p := prefix + k
+		if matchesAny(p, excludes) {
+			continue
+		}
+		included := len(includes) == 0 || matchesAny(p, includes)
+		child, isObject := v.(map[string]interface{})
+		switch {
+		case included && len(excludes) == 0:
+			out[k] = v
+		case isObject && (included || hasPrefixPattern(p, includes)):
+			var childIncludes []string
+			if !included {
+				childIncludes = includes
+			}
+			if filtered := filterObject(child, p+".", childIncludes, excludes); len(filtered) > 0 || included {
+				out[k] = filtered
+			}
+		case included:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func matchesAny(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, p); ok {
+			return true
+		}
+		// a pattern of an object selects its fields
+		if strings.HasPrefix(p, pattern+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixPattern returns true when a pattern may select a field of the object p.
+func hasPrefixPattern(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, p+".") || strings.HasPrefix(pattern, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate computes the aggregations of a search on the documents.
+func aggregate(aggs map[string]interface{}, docs []*docView, req *searchRequest) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(aggs))
+	for name, def := range aggs {
+		m, ok := def.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregation [%s] is not an object", name)
+		}
+		sub, _ := m["aggs"].(map[string]interface{})
+		if sub == nil {
+			sub, _ = m["aggregations"].(map[string]interface{})
+		}
+		for typ, body := range m {
+			if typ == "aggs" || typ == "aggregations" || typ == "meta" {
+				continue
+			}
+			params, _ := body.(map[string]interface{})
+			field, _ := params["field"].(string)
+			var (
+				res interface{}
+				err error
+			)
+			switch typ {
+			case "terms":
+				res, err = termsAgg(docs, field, params, sub, req)
+			case "date_histogram":
+				res, err = dateHistogramAgg(docs, field, params, sub, req)
+			case "filter":
+				if err := checkQuery(params); err != nil {
+					return nil, err
+				}
+				var filtered []*docView
+				for _, d := range docs {
+					ok, err := d.matches(params, req.now)
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						filtered = append(filtered, d)
+					}
+				}
+				res, err = bucket(map[string]interface{}{}, filtered, sub, req)
+			case "top_hits":
+				res, err = topHitsAgg(docs, params, req)
+			case "max", "min", "sum", "avg", "value_count", "cardinality":
+				res = metricAgg(typ, docs, field)
+			default:
+				return nil, fmt.Errorf("unknown aggregation type [%s]", typ)
+			}
+			if err != nil {
+				return nil, err
+			}
+			out[name] = res
+		}
+	}
+	return out, nil
+}
+
+// bucket returns a bucket of the documents with its sub aggregations.
+func bucket(b map[string]interface{}, docs []*docView, sub map[string]interface{}, req *searchRequest) (map[string]interface{}, error) {
+	b["doc_count"] = len(docs)
+	if len(sub) > 0 {
+		aggs, err := aggregate(sub, docs, req)
+		if err != nil {
+			return nil, err
+		}
+		for name, agg := range aggs {
+			b[name] = agg
+		}
+	}
+	return b, nil
+}
+
+func termsAgg(docs []*docView, field string, params, sub map[string]interface{}, req *searchRequest) (interface{}, error) {
+	type group struct {
+		key  interface{}
+		docs []*docView
+	}
+	groups := map[string]*group{}
+	add := func(key interface{}, d *docView) {
+		k := fmt.Sprintf("%T:%v", key, key)
+		g, ok := groups[k]
+		if !ok {
+			g = &group{key: key}
+			groups[k] = g
+		}
+		// a document is counted once per key
+		if n := len(g.docs); n == 0 || g.docs[n-1] != d {
+			g.docs = append(g.docs, d)
+		}
+	}
+	for _, d := range docs {
+		vals := d.values(field)
+		if len(vals) == 0 && params["missing"] != nil {
+			add(params["missing"], d)
+		}
+		for _, v := range vals {
+			if _, isMap := v.(map[string]interface{}); !isMap {
+				add(v, d)
+			}
+		}
+	}
+
+	sorted := make([]*group, 0, len(groups))
+	minDocCount := int(numberOr(params["min_doc_count"], 1))
+	for _, g := range groups {
+		if len(g.docs) >= minDocCount {
+			sorted = append(sorted, g)
+		}
+	}
+	byKey := false
+	desc := true
+	if order, ok := params["order"].(map[string]interface{}); ok {
+		if o, ok := order["_key"]; ok {
+			byKey, desc = true, o == "desc"
+		} else if o, ok := order["_count"]; ok {
+			desc = o == "desc"
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !byKey && len(a.docs) != len(b.docs) {
+			return (len(a.docs) > len(b.docs)) == desc
+		}
+		c, _ := compareValues(a.key, b.key)
+		if byKey && desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	size := int(numberOr(params["size"], 10))
+	other := 0
+	if len(sorted) > size {
+		for _, g := range sorted[size:] {
+			other += len(g.docs)
+		}
+		sorted = sorted[:size]
+	}
+	buckets := make([]interface{}, 0, len(sorted))
+	for _, g := range sorted {
+		b, err := bucket(map[string]interface{}{"key": g.key}, g.docs, sub, req)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return map[string]interface{}{
+		"doc_count_error_upper_bound": 0,
+		"sum_other_doc_count":         other,
+		"buckets":                     buckets,
+	}, nil
+}
+
+// intervalOf returns the interval of a date histogram, a fixed interval like 30s or a calendar unit like 1d or day.
+func intervalOf(params map[string]interface{}) (time.Duration, error) {
+	s, _ := params["fixed_interval"].(string)
+	if s == "" {
+		s, _ = params["calendar_interval"].(string)
+	}
+	switch s {
+	case "minute":
+		s = "1m"
+	case "hour":
+		s = "1h"
+	case "day":
+		s = "1d"
+	case "week":
+		s = "1w"
+	}
+	if s == "" {
+		return 0, fmt.Errorf("[date_histogram] interval is missing")
+	}
+	n, err := strconv.Atoi(strings.TrimRight(s, "smhdwMy"))
+	unit := unitDuration(s[len(s)-1])
+	if err != nil || n <= 0 || unit == 0 {
+		return 0, fmt.Errorf("[date_histogram] invalid interval [%s]", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func dateHistogramAgg(docs []*docView, field string, params, sub map[string]interface{}, req *searchRequest) (interface{}, error) {
+	interval, err := intervalOf(params)
+	if err != nil {
+		return nil, err
+	}
+	step := interval.Milliseconds()
+	groups := map[int64][]*docView{}
+	minKey, maxKey := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, d := range docs {
+		for _, v := range d.values(field) {
+			t, ok := parseTime(v, req.now)
+			if !ok {
+				continue
+			}
+			ms := t.UnixMilli()
+			key := ms - ((ms%step)+step)%step
+			groups[key] = append(groups[key], d)
+			if key < minKey {
+				minKey = key
+			}
+			if key > maxKey {
+				maxKey = key
+			}
+			break
+		}
+	}
+	minDocCount := int(numberOr(params["min_doc_count"], 0))
+	buckets := []interface{}{}
+	for key := minKey; len(groups) > 0 && key <= maxKey; key += step {
+		if len(groups[key]) < minDocCount {
+			continue
+		}
+		b, err := bucket(map[string]interface{}{
+			"key":           key,
+			"key_as_string": time.UnixMilli(key).UTC().Format("2006-01-02T15:04:05.000Z"),
+		}, groups[key], sub, req)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return map[string]interface{}{"buckets": buckets}, nil
+}
+
+func topHitsAgg(docs []*docView, params map[string]interface{}, req *searchRequest) (interface{}, error) {
+	fields, err := parseSort(params["sort"])
+	if err != nil {
+		return nil, err
+	}
+	docs = append([]*docView(nil), docs...)
+	sortVals := sortDocs(docs, fields)
+	size := int(numberOr(params["size"], 3))
+	if size > len(docs) {
+		size = len(docs)
+	}
+	hits := make([]interface{}, size)
+	for i := range hits {
+		hits[i] = renderHit(docs[i], sortVals[i], fields, &searchRequest{Source: params["_source"], now: req.now})
+	}
+	return map[string]interface{}{"hits": map[string]interface{}{
+		"total":     map[string]interface{}{"value": len(docs), "relation": "eq"},
+		"max_score": nil,
+		"hits":      hits,
+	}}, nil
+}
+
+func metricAgg(typ string, docs []*docView, field string) interface{} {
+	var (
+		count int
+		sum   float64
+		value interface{}
+	)
+	distinct := map[string]bool{}
+	for _, d := range docs {
+		for _, v := range d.values(field) {
+			count++
+			distinct[fmt.Sprintf("%T:%v", v, v)] = true
+			f, ok := v.(float64)
+			if !ok {
+				if t, isTime := parseTime(v, time.Time{}); isTime {
+					f, ok = float64(t.UnixMilli()), true
+				}
+			}
+			if !ok {
+				continue
+			}
+			sum += f
+			switch cur, _ := value.(float64); {
+			case value == nil,
+				typ == "max" && f > cur,
+				typ == "min" && f < cur:
+				value = f
+			}
+		}
+	}
+	switch typ {
+	case "sum":
+		value = sum
+	case "avg":
+		if count > 0 {
+			value = sum / float64(count)
+		}
+	case "value_count":
+		value = count
+	case "cardinality":
+		value = len(distinct)
+	}
+	return map[string]interface{}{"value": value}
+}
+
+// searchRequest is the body of a search.
+type searchRequest struct {
+	Query           map[string]interface{} `json:"query"`
+	Size            *int                   `json:"size"`
+	From            int                    `json:"from"`
+	Sort            interface{}            `json:"sort"`
+	SearchAfter     []interface{}          `json:"search_after"`
+	Source          interface{}            `json:"_source"`
+	Fields          []interface{}          `json:"fields"`
+	ScriptFields    map[string]interface{} `json:"script_fields"`
+	Aggs            map[string]interface{} `json:"aggs"`
+	Aggregations    map[string]interface{} `json:"aggregations"`
+	RuntimeMappings map[string]struct {
+		Type   string      `json:"type"`
+		Script interface{} `json:"script"`
+	} `json:"runtime_mappings"`
+	PIT *struct {
+		ID string `json:"id"`
+	} `json:"pit"`
+	SeqNoPrimaryTerm bool `json:"seq_no_primary_term"`
+	Version          bool `json:"version"`
+
+	now time.Time
+}
+
+// search runs a search on the indices.
+func (s *Store) search(indices string, req *searchRequest, ignoreUnavailable bool) (map[string]interface{}, *esError) {
+	req.now = time.Now().UTC()
+	runtime := map[string]*runtimeField{}
+	for name, rm := range req.RuntimeMappings {
+		code, _, err := scriptOf(rm.Script)
+		if err != nil {
+			return nil, errBadRequest("script_exception", "runtime field [%s]: %v", name, err)
+		}
+		scr, err := runtimeScriptOf(code)
+		if err != nil {
+			return nil, errBadRequest("script_exception", "runtime field [%s]: %v", name, err)
+		}
+		runtime[name] = &runtimeField{script: scr}
+	}
+	if len(req.ScriptFields) > 0 {
+		return nil, errBadRequest("illegal_argument_exception", "the memory backend does not support the script fields")
+	}
+	if err := checkQuery(req.Query); err != nil {
+		return nil, errBadRequest("parsing_exception", "%v", err)
+	}
+	fields, err := parseSort(req.Sort)
+	if err != nil {
+		return nil, errBadRequest("parsing_exception", "%v", err)
+	}
+
+	s.mu.Lock()
+	names, eerr := s.resolveLocked(indices, ignoreUnavailable)
+	if eerr != nil {
+		s.mu.Unlock()
+		return nil, eerr
+	}
+	var docs []*docView
+	for _, name := range names {
+		for id, doc := range s.state.Indices[name].Docs {
+			d := &docView{index: name, id: id, doc: doc, runtime: runtime}
+			ok, err := d.matches(req.Query, req.now)
+			if err != nil {
+				s.mu.Unlock()
+				return nil, errBadRequest("parsing_exception", "%v", err)
+			}
+			if ok {
+				docs = append(docs, d)
+			}
+		}
+	}
+	// the documents are replaced by the writes, the ones of the search are not changed once the lock is released
+	s.mu.Unlock()
+
+	sortVals := sortDocs(docs, fields)
+	res := map[string]interface{}{
+		"took":      0,
+		"timed_out": false,
+		"_shards":   map[string]interface{}{"total": len(names), "successful": len(names), "skipped": 0, "failed": 0},
+	}
+	aggs := req.Aggs
+	if aggs == nil {
+		aggs = req.Aggregations
+	}
+	if len(aggs) > 0 {
+		aggRes, err := aggregate(aggs, docs, req)
+		if err != nil {
+			return nil, errBadRequest("aggregation_execution_exception", "%v", err)
+		}
+		res["aggregations"] = aggRes
+	}
+
+	total := len(docs)
+	start := 0
+	if len(req.SearchAfter) > 0 {
+		after := req.SearchAfter
+		for start < len(docs) && compareSort(fields, sortVals[start], after) <= 0 {
+			start++
+		}
+	}
+	start += req.From
+	if start > len(docs) {
+		start = len(docs)
+	}
+	end := len(docs)
+	size := 10
+	if req.Size != nil {
+		size = *req.Size
+	}
+	if start+size < end {
+		end = start + size
+	}
+	hits := make([]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		hits = append(hits, renderHit(docs[i], sortVals[i], fields, req))
+	}
+	res["hits"] = map[string]interface{}{
+		"total":     map[string]interface{}{"value": total, "relation": "eq"},
+		"max_score": nil,
+		"hits":      hits,
+	}
+	if req.PIT != nil {
+		res["pit_id"] = req.PIT.ID
+	}
+	return res, nil
+}
+
+func renderHit(d *docView, sortVals []interface{}, fields []sortField, req *searchRequest) map[string]interface{} {
+	hit := map[string]interface{}{
+		"_index":        d.index,
+		"_id":           d.id,
+		"_score":        nil,
+		"_seq_no":       d.doc.SeqNo,
+		"_primary_term": 1,
+	}
+	if req.Version {
+		hit["_version"] = d.doc.Version
+	}
+	if src, ok := filterSource(d.doc.Source, req.Source); ok {
+		hit["_source"] = src
+	}
+	if len(fields) > 0 {
+		hit["sort"] = sortVals
+	}
+	if len(req.Fields) > 0 {
+		values := map[string]interface{}{}
+		for _, f := range req.Fields {
+			name, ok := f.(string)
+			if !ok {
+				m, _ := f.(map[string]interface{})
+				name, _ = m["field"].(string)
+			}
+			if vals := d.values(name); len(vals) > 0 {
+				values[name] = vals
+			}
+		}
+		hit["fields"] = values
+	}
+	return hit
+}
+
+func numberOr(v interface{}, def float64) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return def
+}
+
+// decodeJSON decodes a JSON body, an empty body is an empty object.
+func decodeJSON(body []byte, v interface{}) *esError {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return errBadRequest("parsing_exception", "%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The painless scripts are not interpreted: the scripts of fleet-server are recognised by their source, the comments
+// and the whitespace aside, and run by their implementation below. The other scripts fail like the scripts that do not
+// compile in Elasticsearch, a new script of fleet-server needs its implementation here.
+
+var errScript = errors.New("script error")
+
+func scriptErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errScript, fmt.Sprintf(format, args...))
+}
+
+// updateContext is the ctx of an update script.
+type updateContext struct {
+	source map[string]interface{}
+	id     string
+	// op is the operation of the update set by the script: index, noop or delete.
+	op string
+}
+
+type updateScript func(ctx *updateContext, params map[string]interface{}) error
+
+// updateScripts are the update scripts of fleet-server with a fixed source, by their compacted source.
+var updateScripts = map[string]updateScript{
+	// the policy acknowledged by an agent, api.makeUpdatePolicyBody
+	compactScript(`if (ctx._source.policy_id == params.id) {ctx._source.remove('default_api_key_history');ctx._source.policy_revision_idx = params.rev;ctx._source.policy_coordinator_idx= params.coord;ctx._source.updated_at = params.ts;} else {ctx.op = "noop";}`): ackPolicyScript,
+	// dl.migrateAgentMetadata
+	compactScript(`ctx._source.agent = [:]; ctx._source.agent.id = ctx._id;`): agentIDScript,
+	// dl.migratePolicyCoordinatorIdx
+	compactScript(`ctx._source.coordinator_idx++;`): coordinatorIdxScript,
+	// dl.migrateAgentOutputs
+	compactScript(agentOutputsSource): agentOutputsScript,
+}
+
+// updateScriptPatterns return the update scripts of fleet-server rendered with the names of the outputs and the fields
+// of params, nil when the source is not theirs.
+var updateScriptPatterns = []func(src string, params map[string]interface{}) updateScript{
+	removeOutputScript,
+	outputFieldsScript,
+}
+
+// updateScriptOf returns the update script of a source.
+func updateScriptOf(code string, params map[string]interface{}) (updateScript, error) {
+	src := compactScript(code)
+	if run, ok := updateScripts[src]; ok {
+		return run, nil
+	}
+	for _, pattern := range updateScriptPatterns {
+		if run := pattern(src, params); run != nil {
+			return run, nil
+		}
+	}
+	return nil, scriptErrorf("the memory backend does not support the script [%s]", code)
+}
+
+func ackPolicyScript(ctx *updateContext, params map[string]interface{}) error {
+	if !reflect.DeepEqual(ctx.source["policy_id"], params["id"]) {
+		ctx.op = "noop"
+		return nil
+	}
+	delete(ctx.source, "default_api_key_history")
+	ctx.source["policy_revision_idx"] = params["rev"]
+	ctx.source["policy_coordinator_idx"] = params["coord"]
+	ctx.source["updated_at"] = params["ts"]
+	return nil
+}
+
+func agentIDScript(ctx *updateContext, _ map[string]interface{}) error {
+	ctx.source["agent"] = map[string]interface{}{"id": ctx.id}
+	return nil
+}
+
+func coordinatorIdxScript(ctx *updateContext, _ map[string]interface{}) error {
+	idx, ok := ctx.source["coordinator_idx"].(float64)
+	if !ok {
+		return scriptErrorf("coordinator_idx is not a number")
+	}
+	ctx.source["coordinator_idx"] = idx + 1
+	return nil
+}
+
+const agentOutputsSource = `
+// set up the new fields
+ctx._source['outputs']=new HashMap();
+ctx._source['outputs']['default']=new HashMap();
+ctx._source['outputs']['default'].to_retire_api_key_ids=new ArrayList();
+
+// copy 'default_api_key_history' to new 'outputs' field
+ctx._source['outputs']['default'].type="elasticsearch";
+if (ctx._source.default_api_key_history != null && ctx._source.default_api_key_history.length > 0) {
+    ctx._source['outputs']['default'].to_retire_api_key_ids=ctx._source.default_api_key_history;
+}
+
+Map map = new HashMap();
+map.put("retired_at", params.retiredAt);
+map.put("id", ctx._source.default_api_key_id);
+
+// Make current API key empty, so fleet-server will generate a new one
+// Add current API jey to be retired
+if (ctx._source['outputs']['default'].to_retire_api_key_ids != null) {
+	ctx._source['outputs']['default'].to_retire_api_key_ids.add(map);
+}
+ctx._source['outputs']['default'].api_key="";
+ctx._source['outputs']['default'].api_key_id="";
+ctx._source['outputs']['default'].permissions_hash=ctx._source.policy_output_permissions_hash;
+
+// Erase deprecated fields
+ctx._source.default_api_key_history=null;
+ctx._source.default_api_key=null;
+ctx._source.default_api_key_id=null;
+ctx._source.policy_output_permissions_hash=null;
+`
+
+func agentOutputsScript(ctx *updateContext, params map[string]interface{}) error {
+	retire := []interface{}{}
+	if history, ok := ctx.source["default_api_key_history"].([]interface{}); ok && len(history) > 0 {
+		retire = history
+	}
+	retire = append(retire, map[string]interface{}{"retired_at": params["retiredAt"], "id": ctx.source["default_api_key_id"]})
+	ctx.source["outputs"] = map[string]interface{}{"default": map[string]interface{}{
+		"to_retire_api_key_ids": retire,
+		"type":                  "elasticsearch",
+		"api_key":               "",
+		"api_key_id":            "",
+		"permissions_hash":      ctx.source["policy_output_permissions_hash"],
+	}}
+	for _, field := range []string{"default_api_key_history", "default_api_key", "default_api_key_id", "policy_output_permissions_hash"} {
+		ctx.source[field] = nil
+	}
+	return nil
+}
+
+var removeOutputPattern = regexp.MustCompile(`^ctx\._source\['outputs'\]\.remove\("([^"]*)"\);?$`)
+
+// removeOutputScript removes an output of an agent, policy.Output.prepareElasticsearch.
+func removeOutputScript(src string, _ map[string]interface{}) updateScript {
+	m := removeOutputPattern.FindStringSubmatch(src)
+	if m == nil {
+		return nil
+	}
+	return func(ctx *updateContext, _ map[string]interface{}) error {
+		outputs, ok := ctx.source["outputs"].(map[string]interface{})
+		if !ok {
+			return scriptErrorf("outputs is not an object")
+		}
+		delete(outputs, m[1])
+		return nil
+	}
+}
+
+var outputFieldsPattern = regexp.MustCompile(`^if\(ctx\._source\['outputs'\]==null\)\{ctx\._source\['outputs'\]=newHashMap\(\);\}if\(ctx\._source\['outputs'\]\['([^']*)'\]==null\)\{ctx\._source\['outputs'\]\['([^']*)'\]=newHashMap\(\);\}`)
+
+// outputFieldsScript sets the fields of params on an output of an agent, the API keys to retire are appended,
+// policy.renderUpdatePainlessScript.
+func outputFieldsScript(src string, params map[string]interface{}) updateScript {
+	m := outputFieldsPattern.FindStringSubmatch(src)
+	if m == nil || m[1] != m[2] {
+		return nil
+	}
+	name, rest := m[1], src[len(m[0]):]
+	// the fields are rendered in the order of a map
+	var fields []string
+	for rest != "" {
+		found := false
+		for field := range params {
+			if part := compactScript(outputFieldSource(name, field)); strings.HasPrefix(rest, part) {
+				fields, rest, found = append(fields, field), rest[len(part):], true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return func(ctx *updateContext, params map[string]interface{}) error {
+		if ctx.source["outputs"] == nil {
+			ctx.source["outputs"] = map[string]interface{}{}
+		}
+		outputs, ok := ctx.source["outputs"].(map[string]interface{})
+		if !ok {
+			return scriptErrorf("outputs is not an object")
+		}
+		if outputs[name] == nil {
+			outputs[name] = map[string]interface{}{}
+		}
+		output, ok := outputs[name].(map[string]interface{})
+		if !ok {
+			return scriptErrorf("output [%s] is not an object", name)
+		}
+		for _, field := range fields {
+			if field != "to_retire_api_key_ids" {
+				output[field] = params[field]
+				continue
+			}
+			if output[field] == nil {
+				output[field] = []interface{}{}
+			}
+			keys, ok := output[field].([]interface{})
+			if !ok {
+				return scriptErrorf("%s is not a list", field)
+			}
+			if !containsValue(keys, params[field]) {
+				output[field] = append(keys, params[field])
+			}
+		}
+		return nil
+	}
+}
+
+// outputFieldSource is the source setting a field of an output, as rendered by policy.renderUpdatePainlessScript.
+func outputFieldSource(name, field string) string {
+	if field == "to_retire_api_key_ids" {
+		return fmt.Sprintf(`
+if (ctx._source['outputs']['%s'].%s==null)
+  {ctx._source['outputs']['%s'].%s=new ArrayList();}
+if (!ctx._source['outputs']['%s'].%s.contains(params.%s))
+  {ctx._source['outputs']['%s'].%s.add(params.%s);}
+`, name, field, name, field, name, field, field, name, field, field)
+	}
+	return fmt.Sprintf(`
+ctx._source['outputs']['%s'].%s=params.%s;`, name, field, field)
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, item := range values {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeScript emits the values of a runtime field of a document, doc returns the sorted values of its fields.
+type runtimeScript func(doc func(field string) []interface{}) []interface{}
+
+// runtimeScripts are the scripts of the runtime fields of fleet-server, dl.AgentRuntimeFields, by their compacted source.
+var runtimeScripts = map[string]runtimeScript{
+	compactScript(`if (doc.containsKey('agent.version') && doc['agent.version'].size() > 0) {
+  String v = doc['agent.version'].value;
+  int i = v.indexOf('.');
+  try { emit(Long.parseLong(i < 0 ? v : v.substring(0, i))); } catch (NumberFormatException e) {}
+}`): agentVersionMajorScript,
+	compactScript(`if (doc.containsKey('local_metadata.os.platform') && doc['local_metadata.os.platform'].size() > 0) {
+  emit(doc['local_metadata.os.platform'].value.toLowerCase());
+}`): osPlatformScript,
+}
+
+// runtimeScriptOf returns the runtime field script of a source.
+func runtimeScriptOf(code string) (runtimeScript, error) {
+	if run, ok := runtimeScripts[compactScript(code)]; ok {
+		return run, nil
+	}
+	return nil, scriptErrorf("the memory backend does not support the script [%s]", code)
+}
+
+func agentVersionMajorScript(doc func(field string) []interface{}) []interface{} {
+	vals := doc("agent.version")
+	if len(vals) == 0 {
+		return nil
+	}
+	v, ok := vals[0].(string)
+	if !ok {
+		return nil
+	}
+	if i := strings.Index(v, "."); i >= 0 {
+		v = v[:i]
+	}
+	major, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return []interface{}{float64(major)}
+}
+
+func osPlatformScript(doc func(field string) []interface{}) []interface{} {
+	vals := doc("local_metadata.os.platform")
+	if len(vals) == 0 {
+		return nil
+	}
+	v, ok := vals[0].(string)
+	if !ok {
+		return nil
+	}
+	return []interface{}{strings.ToLower(v)}
+}
+
+// compactScript removes the comments and the whitespace out of the strings of the source of a script.
+func compactScript(src string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				b.WriteByte(src[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			b.WriteByte(c)
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 3
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
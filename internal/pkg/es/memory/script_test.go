@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev && !integration
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The sources below are the ones rendered by fleet-server, they change with them.
+
+const (
+	testPolicyScript = `if (ctx._source.policy_id == params.id) {ctx._source.remove('default_api_key_history');ctx._source.policy_revision_idx = params.rev;ctx._source.policy_coordinator_idx= params.coord;ctx._source.updated_at = params.ts;} else {ctx.op = "noop";}`
+	testOutputScript = `
+if (ctx._source['outputs']==null)
+  {ctx._source['outputs']=new HashMap();}
+if (ctx._source['outputs']['default']==null)
+  {ctx._source['outputs']['default']=new HashMap();}
+
+if (ctx._source['outputs']['default'].to_retire_api_key_ids==null)
+  {ctx._source['outputs']['default'].to_retire_api_key_ids=new ArrayList();}
+if (!ctx._source['outputs']['default'].to_retire_api_key_ids.contains(params.to_retire_api_key_ids))
+  {ctx._source['outputs']['default'].to_retire_api_key_ids.add(params.to_retire_api_key_ids);}
+
+ctx._source['outputs']['default'].api_key_id=params.api_key_id;`
+	testAgentVersionScript = `if (doc.containsKey('agent.version') && doc['agent.version'].size() > 0) {
+  String v = doc['agent.version'].value;
+  int i = v.indexOf('.');
+  try { emit(Long.parseLong(i < 0 ? v : v.substring(0, i))); } catch (NumberFormatException e) {}
+}`
+	testOSPlatformScript = `if (doc.containsKey('local_metadata.os.platform') && doc['local_metadata.os.platform'].size() > 0) {
+  emit(doc['local_metadata.os.platform'].value.toLowerCase());
+}`
+)
+
+func TestRunUpdateScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script interface{}
+		source map[string]interface{}
+		want   map[string]interface{}
+		op     string
+	}{{
+		name: "policy update",
+		script: map[string]interface{}{
+			"lang":   "painless",
+			"source": testPolicyScript,
+			"params": map[string]interface{}{"id": "p1", "rev": float64(2), "coord": float64(1), "ts": "now"},
+		},
+		source: map[string]interface{}{"policy_id": "p1", "default_api_key_history": []interface{}{"a"}},
+		want:   map[string]interface{}{"policy_id": "p1", "policy_revision_idx": float64(2), "policy_coordinator_idx": float64(1), "updated_at": "now"},
+		op:     "index",
+	}, {
+		name: "policy update of another policy",
+		script: map[string]interface{}{
+			"source": testPolicyScript,
+			"params": map[string]interface{}{"id": "p2", "rev": float64(2), "coord": float64(1), "ts": "now"},
+		},
+		source: map[string]interface{}{"policy_id": "p1"},
+		want:   map[string]interface{}{"policy_id": "p1"},
+		op:     "noop",
+	}, {
+		name: "output fields",
+		script: map[string]interface{}{
+			"source": testOutputScript,
+			"params": map[string]interface{}{"to_retire_api_key_ids": map[string]interface{}{"id": "old"}, "api_key_id": "new"},
+		},
+		source: map[string]interface{}{},
+		want: map[string]interface{}{"outputs": map[string]interface{}{"default": map[string]interface{}{
+			"to_retire_api_key_ids": []interface{}{map[string]interface{}{"id": "old"}},
+			"api_key_id":            "new",
+		}}},
+		op: "index",
+	}, {
+		name: "output key already retired",
+		script: map[string]interface{}{
+			"source": testOutputScript,
+			"params": map[string]interface{}{"to_retire_api_key_ids": map[string]interface{}{"id": "old"}, "api_key_id": "new"},
+		},
+		source: map[string]interface{}{"outputs": map[string]interface{}{"default": map[string]interface{}{
+			"to_retire_api_key_ids": []interface{}{map[string]interface{}{"id": "old"}},
+		}}},
+		want: map[string]interface{}{"outputs": map[string]interface{}{"default": map[string]interface{}{
+			"to_retire_api_key_ids": []interface{}{map[string]interface{}{"id": "old"}},
+			"api_key_id":            "new",
+		}}},
+		op: "index",
+	}, {
+		name:   "coordinator index",
+		script: "ctx._source.coordinator_idx++;",
+		source: map[string]interface{}{"coordinator_idx": float64(1)},
+		want:   map[string]interface{}{"coordinator_idx": float64(2)},
+		op:     "index",
+	}, {
+		name:   "remove output",
+		script: map[string]interface{}{"lang": "painless", "source": `ctx._source['outputs'].remove("es")`},
+		source: map[string]interface{}{"outputs": map[string]interface{}{"es": map[string]interface{}{}, "other": map[string]interface{}{}}},
+		want:   map[string]interface{}{"outputs": map[string]interface{}{"other": map[string]interface{}{}}},
+		op:     "index",
+	}, {
+		name:   "agent id",
+		script: "ctx._source.agent = [:]; ctx._source.agent.id = ctx._id;",
+		source: map[string]interface{}{},
+		want:   map[string]interface{}{"agent": map[string]interface{}{"id": "doc"}},
+		op:     "index",
+	}, {
+		name: "agent outputs",
+		script: map[string]interface{}{
+			"source": agentOutputsSource,
+			"params": map[string]interface{}{"retiredAt": "now"},
+		},
+		source: map[string]interface{}{"default_api_key_history": []interface{}{map[string]interface{}{"id": "older"}}, "default_api_key_id": "old", "default_api_key": "old:key", "policy_output_permissions_hash": "hash"},
+		want: map[string]interface{}{
+			"outputs": map[string]interface{}{"default": map[string]interface{}{
+				"to_retire_api_key_ids": []interface{}{map[string]interface{}{"id": "older"}, map[string]interface{}{"id": "old", "retired_at": "now"}},
+				"type":                  "elasticsearch",
+				"api_key":               "",
+				"api_key_id":            "",
+				"permissions_hash":      "hash",
+			}},
+			"default_api_key_history": nil, "default_api_key": nil, "default_api_key_id": nil, "policy_output_permissions_hash": nil,
+		},
+		op: "index",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, op, err := runUpdateScript(tc.script, "doc", deepCopy(tc.source).(map[string]interface{}))
+			require.NoError(t, err)
+			assert.Equal(t, tc.op, op)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnknownScripts(t *testing.T) {
+	for _, src := range []string{
+		"ctx._source.a = 1;",
+		"while (true) {}",
+		"ctx._source.coordinator_idx += 2;",
+		`if (ctx._source.policy_id == params.id) {ctx._source.policy_revision_idx = params.rev;}`,
+		`ctx._source['outputs'].remove("es"); ctx._source.a = 1;`,
+		// the names of the output differ
+		`if (ctx._source['outputs']==null) {ctx._source['outputs']=new HashMap();}
+if (ctx._source['outputs']['a']==null) {ctx._source['outputs']['b']=new HashMap();}`,
+	} {
+		_, _, err := runUpdateScript(map[string]interface{}{"source": src, "params": map[string]interface{}{"api_key_id": "new"}}, "doc", map[string]interface{}{})
+		assert.ErrorIs(t, err, errScript, src)
+	}
+
+	// a field of the output that is not in the params
+	_, _, err := runUpdateScript(map[string]interface{}{"source": testOutputScript, "params": map[string]interface{}{"api_key_id": "new"}}, "doc", map[string]interface{}{})
+	assert.ErrorIs(t, err, errScript)
+
+	_, _, err = runUpdateScript(map[string]interface{}{"lang": "mustache", "source": ""}, "doc", map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = runtimeScriptOf("emit(doc['agent.version'].value)")
+	assert.ErrorIs(t, err, errScript)
+}
+
+func TestRuntimeFieldScripts(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		values map[string][]interface{}
+		want   []interface{}
+	}{
+		{"version", testAgentVersionScript, map[string][]interface{}{"agent.version": {"8.14.1"}}, []interface{}{float64(8)}},
+		{"major only", testAgentVersionScript, map[string][]interface{}{"agent.version": {"9"}}, []interface{}{float64(9)}},
+		{"invalid version", testAgentVersionScript, map[string][]interface{}{"agent.version": {"x.1"}}, nil},
+		{"missing version", testAgentVersionScript, map[string][]interface{}{}, nil},
+		{"platform", testOSPlatformScript, map[string][]interface{}{"local_metadata.os.platform": {"Linux"}}, []interface{}{"linux"}},
+		{"missing platform", testOSPlatformScript, map[string][]interface{}{}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			run, err := runtimeScriptOf(tc.script)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, run(func(field string) []interface{} { return tc.values[field] }))
+		})
+	}
+}
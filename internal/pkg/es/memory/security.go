@@ -0,0 +1,322 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+package memory
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultUsername is the owner of the API keys created by fleet-server.
+const defaultUsername = "elastic/fleet-server"
+
+// apiKey is an API key. The key is kept as is, the state file holds the credentials of the development setups.
+type apiKey struct {
+	ID              string          `json:"-"`
+	Name            string          `json:"name,omitempty"`
+	Key             string          `json:"api_key"`
+	Username        string          `json:"username,omitempty"`
+	Metadata        json.RawMessage `json:"metadata,omitempty"`
+	RoleDescriptors json.RawMessage `json:"role_descriptors,omitempty"`
+	Creation        int64           `json:"creation,omitempty"`
+	// Expiration is the time at which the key expires in milliseconds, 0 when it does not expire.
+	Expiration  int64 `json:"expiration,omitempty"`
+	Invalidated bool  `json:"invalidated,omitempty"`
+}
+
+// serviceToken is a service token, like the one of fleet-server, that can be authenticated.
+// outputServiceToken is the name of the service token of the output.
+const outputServiceToken = "elastic/fleet-server/output"
+
+type serviceToken struct {
+	// Name is the name of the token, like elastic/fleet-server/token-1.
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+func (k *apiKey) valid(now time.Time) bool {
+	return !k.Invalidated && (k.Expiration == 0 || now.UnixMilli() < k.Expiration)
+}
+
+func (k *apiKey) username() string {
+	if k.Username != "" {
+		return k.Username
+	}
+	return defaultUsername
+}
+
+func (k *apiKey) info() map[string]interface{} {
+	info := map[string]interface{}{
+		"id":          k.ID,
+		"name":        k.Name,
+		"creation":    k.Creation,
+		"invalidated": k.Invalidated,
+		"username":    k.username(),
+		"realm":       "_service_account",
+	}
+	if k.Expiration != 0 {
+		info["expiration"] = k.Expiration
+	}
+	info["metadata"] = rawOr(k.Metadata, "{}")
+	info["role_descriptors"] = rawOr(k.RoleDescriptors, "{}")
+	return info
+}
+
+func rawOr(raw json.RawMessage, def string) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage(def)
+	}
+	return raw
+}
+
+// authenticate returns the identity of the credentials of the request, an API key or a service token.
+func (s *Store) authenticate(r *http.Request) (map[string]interface{}, *esError) {
+	auth := r.Header.Get("Authorization")
+	unauthorized := &esError{Status: http.StatusUnauthorized, Type: "security_exception", Reason: "unable to authenticate"}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(auth, "ApiKey "):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(auth, "ApiKey ")))
+		if err != nil {
+			return nil, unauthorized
+		}
+		id, secret, _ := strings.Cut(string(decoded), ":")
+		key, ok := s.state.APIKeys[id]
+		if !ok || !key.valid(now) || subtle.ConstantTimeCompare([]byte(key.Key), []byte(secret)) != 1 {
+			return nil, unauthorized
+		}
+		return map[string]interface{}{
+			"username":             key.username(),
+			"roles":                []string{},
+			"full_name":            nil,
+			"email":                nil,
+			"metadata":             map[string]interface{}{},
+			"enabled":              true,
+			"authentication_realm": map[string]string{"name": "_es_api_key", "type": "_es_api_key"},
+			"lookup_realm":         map[string]string{"name": "_es_api_key", "type": "_es_api_key"},
+			"authentication_type":  "api_key",
+			"api_key":              map[string]string{"id": key.ID, "name": key.Name},
+		}, nil
+	case strings.HasPrefix(auth, "Bearer "):
+		token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		tokens := s.state.ServiceTokens
+		if s.serviceToken != "" {
+			tokens = append(tokens[:len(tokens):len(tokens)], serviceToken{Name: outputServiceToken, Token: s.serviceToken})
+		}
+		for _, st := range tokens {
+			if subtle.ConstantTimeCompare([]byte(st.Token), []byte(token)) != 1 {
+				continue
+			}
+			account, name := st.Name, st.Name
+			if i := strings.LastIndex(st.Name, "/"); i >= 0 {
+				account, name = st.Name[:i], st.Name[i+1:]
+			}
+			return map[string]interface{}{
+				"username":             account,
+				"roles":                []string{},
+				"full_name":            "Service account - " + account,
+				"email":                nil,
+				"metadata":             map[string]interface{}{"_elastic_service_account": true},
+				"enabled":              true,
+				"authentication_realm": map[string]string{"name": "_service_account", "type": "_service_account"},
+				"lookup_realm":         map[string]string{"name": "_service_account", "type": "_service_account"},
+				"authentication_type":  "token",
+				"token":                map[string]string{"name": name, "type": "_service_account_index"},
+			}, nil
+		}
+	}
+	return nil, unauthorized
+}
+
+// parseExpiration parses the expiration of an API key, like 30d or 1h.
+func parseExpiration(s string) (time.Duration, bool) {
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") {
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// createAPIKey creates an API key.
+func (s *Store) createAPIKey(body []byte) (map[string]interface{}, *esError) {
+	var req struct {
+		Name            string          `json:"name"`
+		Expiration      string          `json:"expiration"`
+		RoleDescriptors json.RawMessage `json:"role_descriptors"`
+		Metadata        json.RawMessage `json:"metadata"`
+	}
+	if err := decodeJSON(body, &req); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := &apiKey{
+		ID:              newID(),
+		Name:            req.Name,
+		Key:             newID(),
+		Metadata:        req.Metadata,
+		RoleDescriptors: req.RoleDescriptors,
+		Creation:        now.UnixMilli(),
+	}
+	if string(key.Metadata) == "null" {
+		key.Metadata = nil
+	}
+	if req.Expiration != "" {
+		d, ok := parseExpiration(req.Expiration)
+		if !ok {
+			return nil, errBadRequest("illegal_argument_exception", "failed to parse setting [expiration] with value [%s]", req.Expiration)
+		}
+		key.Expiration = now.Add(d).UnixMilli()
+	}
+
+	s.mu.Lock()
+	s.state.APIKeys[key.ID] = key
+	s.changedLocked()
+	s.mu.Unlock()
+
+	res := map[string]interface{}{
+		"id":      key.ID,
+		"name":    key.Name,
+		"api_key": key.Key,
+		"encoded": base64.StdEncoding.EncodeToString([]byte(key.ID + ":" + key.Key)),
+	}
+	if key.Expiration != 0 {
+		res["expiration"] = key.Expiration
+	}
+	return res, nil
+}
+
+// getAPIKeys returns the API keys of the id or the name of the query.
+func (s *Store) getAPIKeys(r *http.Request) (map[string]interface{}, *esError) {
+	id, name := r.URL.Query().Get("id"), r.URL.Query().Get("name")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []interface{}{}
+	ids := make([]string, 0, len(s.state.APIKeys))
+	for keyID := range s.state.APIKeys {
+		ids = append(ids, keyID)
+	}
+	sort.Strings(ids)
+	for _, keyID := range ids {
+		key := s.state.APIKeys[keyID]
+		if (id != "" && key.ID != id) || (name != "" && key.Name != name) {
+			continue
+		}
+		keys = append(keys, key.info())
+	}
+	if id != "" && len(keys) == 0 {
+		return nil, &esError{Status: http.StatusNotFound, Type: "resource_not_found_exception", Reason: "api key with id [" + id + "] not found"}
+	}
+	return map[string]interface{}{"api_keys": keys}, nil
+}
+
+// invalidateAPIKeys invalidates the API keys of the ids of the body.
+func (s *Store) invalidateAPIKeys(body []byte) (map[string]interface{}, *esError) {
+	var req struct {
+		ID   string   `json:"id"`
+		IDs  []string `json:"ids"`
+		Name string   `json:"name"`
+	}
+	if err := decodeJSON(body, &req); err != nil {
+		return nil, err
+	}
+	if req.ID != "" {
+		req.IDs = append(req.IDs, req.ID)
+	}
+	invalidated, previously := []string{}, []string{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.state.APIKeys {
+		if !slices.Contains(req.IDs, key.ID) && (req.Name == "" || key.Name != req.Name) {
+			continue
+		}
+		if key.Invalidated {
+			previously = append(previously, key.ID)
+			continue
+		}
+		key.Invalidated = true
+		invalidated = append(invalidated, key.ID)
+	}
+	if len(invalidated) > 0 {
+		s.changedLocked()
+	}
+	return map[string]interface{}{
+		"invalidated_api_keys":            invalidated,
+		"previously_invalidated_api_keys": previously,
+		"error_count":                     0,
+	}, nil
+}
+
+// bulkUpdateAPIKeys replaces the role descriptors and the metadata of the API keys.
+func (s *Store) bulkUpdateAPIKeys(body []byte) (map[string]interface{}, *esError) {
+	var req struct {
+		IDs             []string        `json:"ids"`
+		RoleDescriptors json.RawMessage `json:"role_descriptors"`
+		Metadata        json.RawMessage `json:"metadata"`
+	}
+	if err := decodeJSON(body, &req); err != nil {
+		return nil, err
+	}
+	updated, noops := []string{}, []string{}
+	errs := map[string]interface{}{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range req.IDs {
+		key, ok := s.state.APIKeys[id]
+		if !ok || key.Invalidated {
+			errs[id] = map[string]string{"type": "resource_not_found_exception", "reason": "no API key owned by requesting user found for ID [" + id + "]"}
+			continue
+		}
+		changed := false
+		if len(req.RoleDescriptors) > 0 && string(req.RoleDescriptors) != string(key.RoleDescriptors) {
+			key.RoleDescriptors, changed = req.RoleDescriptors, true
+		}
+		if len(req.Metadata) > 0 && string(req.Metadata) != string(key.Metadata) {
+			key.Metadata, changed = req.Metadata, true
+		}
+		if changed {
+			updated = append(updated, id)
+		} else {
+			noops = append(noops, id)
+		}
+	}
+	if len(updated) > 0 {
+		s.changedLocked()
+	}
+	res := map[string]interface{}{"updated": updated, "noops": noops}
+	if len(errs) > 0 {
+		res["errors"] = map[string]interface{}{"count": len(errs), "details": errs}
+	}
+	return res, nil
+}
+
+// hasPrivileges reports that the credentials of the request hold all the privileges, the privileges of the API keys
+// are not enforced.
+func (s *Store) hasPrivileges(r *http.Request) (map[string]interface{}, *esError) {
+	info, err := s.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"username":          info["username"],
+		"has_all_requested": true,
+		"cluster":           map[string]interface{}{},
+		"index":             map[string]interface{}{},
+		"application":       map[string]interface{}{},
+	}, nil
+}
@@ -0,0 +1,520 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// saveDelay is the delay of the save of the state file after a change, the changes of the delay are saved together.
+const saveDelay = time.Second
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*Store{}
+)
+
+// document is a document of an index.
+type document struct {
+	// SeqNo is the sequence number of the last write of the document.
+	SeqNo int64 `json:"seq_no"`
+	// Version is the number of writes of the document, the documents seeded in the state file have none.
+	Version int64                  `json:"version"`
+	Source  map[string]interface{} `json:"source"`
+}
+
+// index is an index, the sequence numbers of its writes follow each other from 0.
+type index struct {
+	// SeqNo is the sequence number of the last write of the index, it is the global checkpoint of the index.
+	SeqNo int64                `json:"seq_no"`
+	Docs  map[string]*document `json:"docs"`
+}
+
+// state is the state of the store, as written to the state file.
+type state struct {
+	Indices       map[string]*index  `json:"indices"`
+	APIKeys       map[string]*apiKey `json:"api_keys"`
+	ServiceTokens []serviceToken     `json:"service_tokens,omitempty"`
+}
+
+// Store is an in-memory Elasticsearch. It implements the subset of the Elasticsearch REST API used by fleet-server as
+// an http.RoundTripper, the Elasticsearch clients of fleet-server use it as their transport.
+type Store struct {
+	path string
+	// serviceToken is the service token of the output, accepted in addition to the service tokens of the state.
+	serviceToken string
+
+	mu    sync.Mutex
+	state state
+	// changed is closed and replaced at each write, to wake up the waits of the global checkpoints.
+	changed chan struct{}
+	saving  bool
+}
+
+// Open returns the store of the configuration, the service token of the output authenticates fleet-server. The clients
+// of a process share the store of a path, the state file is loaded by the first one.
+func Open(cfg config.Memory, serviceToken string) (*Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[cfg.Path]; ok {
+		return s, nil
+	}
+	s, err := newStore(cfg.Path, serviceToken)
+	if err != nil {
+		return nil, err
+	}
+	stores[cfg.Path] = s
+	return s, nil
+}
+
+func newStore(path, serviceToken string) (*Store, error) {
+	s := &Store{
+		path:         path,
+		serviceToken: serviceToken,
+		changed:      make(chan struct{}),
+		state: state{
+			Indices: map[string]*index{},
+			APIKeys: map[string]*apiKey{},
+		},
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read the memory state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("unable to parse the memory state file %s: %w", path, err)
+	}
+	s.init()
+	return s, nil
+}
+
+// init completes the state loaded from the state file, the seeded documents and API keys have only their content.
+func (s *Store) init() {
+	if s.state.Indices == nil {
+		s.state.Indices = map[string]*index{}
+	}
+	if s.state.APIKeys == nil {
+		s.state.APIKeys = map[string]*apiKey{}
+	}
+	for _, idx := range s.state.Indices {
+		if idx.Docs == nil {
+			idx.Docs = map[string]*document{}
+		}
+		ids := make([]string, 0, len(idx.Docs))
+		for id, doc := range idx.Docs {
+			if doc.Source == nil {
+				doc.Source = map[string]interface{}{}
+			}
+			if doc.Version == 0 {
+				ids = append(ids, id)
+			} else if doc.SeqNo > idx.SeqNo {
+				idx.SeqNo = doc.SeqNo
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			idx.SeqNo++
+			idx.Docs[id].SeqNo = idx.SeqNo
+			idx.Docs[id].Version = 1
+		}
+	}
+	for id, key := range s.state.APIKeys {
+		key.ID = id
+		if key.Creation == 0 {
+			key.Creation = time.Now().UnixMilli()
+		}
+	}
+}
+
+// changedLocked wakes up the waits of the changes and schedules the save of the state file, the lock is held.
+func (s *Store) changedLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+	if s.path == "" || s.saving {
+		return
+	}
+	s.saving = true
+	time.AfterFunc(saveDelay, func() {
+		if err := s.save(); err != nil {
+			zerolog.Ctx(context.TODO()).Warn().Err(err).Str("path", s.path).Msg("Unable to save the memory state file")
+		}
+	})
+}
+
+// save writes the state file, it is replaced once written.
+func (s *Store) save() error {
+	s.mu.Lock()
+	s.saving = false
+	data, err := json.Marshal(&s.state)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+	tmp := s.path + ".tmp"
+	// the state file holds the API keys
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// esError is an Elasticsearch error.
+type esError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *esError) Error() string {
+	return e.Type + ": " + e.Reason
+}
+
+func (e *esError) body() map[string]interface{} {
+	return map[string]interface{}{"type": e.Type, "reason": e.Reason}
+}
+
+func errIndexNotFound(name string) *esError {
+	return &esError{Status: http.StatusNotFound, Type: "index_not_found_exception", Reason: "no such index [" + name + "]"}
+}
+
+func errBadRequest(typ, format string, args ...interface{}) *esError {
+	return &esError{Status: http.StatusBadRequest, Type: typ, Reason: fmt.Sprintf(format, args...)}
+}
+
+// writeResult is the result of a write of a document.
+type writeResult struct {
+	Index   string
+	ID      string
+	SeqNo   int64
+	Version int64
+	// Result is created, updated, deleted, noop or not_found.
+	Result string
+	Status int
+}
+
+// updateBody is the body of an update.
+type updateBody struct {
+	Doc         map[string]interface{} `json:"doc"`
+	DocAsUpsert bool                   `json:"doc_as_upsert"`
+	Upsert      map[string]interface{} `json:"upsert"`
+	Script      interface{}            `json:"script"`
+	DetectNoop  *bool                  `json:"detect_noop"`
+}
+
+func newID() string {
+	b := make([]byte, 15)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// writeIndexLocked returns the index of a write, it is created when it does not exist.
+func (s *Store) writeIndexLocked(name string) (*index, *esError) {
+	if name == "" || strings.ContainsAny(name, "*,") {
+		return nil, errBadRequest("invalid_index_name_exception", "Invalid index name [%s]", name)
+	}
+	idx, ok := s.state.Indices[name]
+	if !ok {
+		idx = &index{SeqNo: -1, Docs: map[string]*document{}}
+		s.state.Indices[name] = idx
+	}
+	return idx, nil
+}
+
+// putLocked writes the source of a document, a nil source deletes it.
+func (s *Store) putLocked(name string, idx *index, id string, source map[string]interface{}) writeResult {
+	idx.SeqNo++
+	res := writeResult{Index: name, ID: id, SeqNo: idx.SeqNo}
+	doc, exists := idx.Docs[id]
+	switch {
+	case source == nil:
+		delete(idx.Docs, id)
+		res.Version = doc.Version + 1
+		res.Result, res.Status = "deleted", http.StatusOK
+	case exists:
+		idx.Docs[id] = &document{SeqNo: idx.SeqNo, Version: doc.Version + 1, Source: source}
+		res.Version = doc.Version + 1
+		res.Result, res.Status = "updated", http.StatusOK
+	default:
+		idx.Docs[id] = &document{SeqNo: idx.SeqNo, Version: 1, Source: source}
+		res.Version = 1
+		res.Result, res.Status = "created", http.StatusCreated
+	}
+	s.changedLocked()
+	return res
+}
+
+// indexDoc writes a document, create fails when the document exists. A document without id is given a new one.
+// ifSeqNo is the sequence number the document must have, -1 when it is not checked.
+func (s *Store) indexDoc(name, id string, source map[string]interface{}, create bool, ifSeqNo int64) (writeResult, *esError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.writeIndexLocked(name)
+	if err != nil {
+		return writeResult{}, err
+	}
+	if id == "" {
+		id = newID()
+	}
+	doc, exists := idx.Docs[id]
+	if create && exists {
+		return writeResult{}, errVersionConflict(id, "document already exists (current version [%d])", doc.Version)
+	}
+	if ifSeqNo >= 0 && (!exists || doc.SeqNo != ifSeqNo) {
+		return writeResult{}, errVersionConflict(id, "required seqNo [%d]", ifSeqNo)
+	}
+	if source == nil {
+		source = map[string]interface{}{}
+	}
+	return s.putLocked(name, idx, id, source), nil
+}
+
+func errVersionConflict(id, format string, args ...interface{}) *esError {
+	return &esError{Status: http.StatusConflict, Type: "version_conflict_engine_exception", Reason: "[" + id + "]: version conflict, " + fmt.Sprintf(format, args...)}
+}
+
+// deleteDoc deletes a document.
+func (s *Store) deleteDoc(name, id string) (writeResult, *esError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, ok := s.state.Indices[name]
+	if !ok {
+		return writeResult{}, errIndexNotFound(name)
+	}
+	if _, ok := idx.Docs[id]; !ok {
+		return writeResult{Index: name, ID: id, SeqNo: idx.SeqNo, Result: "not_found", Status: http.StatusNotFound}, nil
+	}
+	return s.putLocked(name, idx, id, nil), nil
+}
+
+// updateDoc updates a document with the partial document or the script of the update.
+func (s *Store) updateDoc(name, id string, body *updateBody) (writeResult, *esError) {
+	if body.Doc == nil && body.Script == nil {
+		return writeResult{}, errBadRequest("action_request_validation_exception", "Validation Failed: 1: script or doc is missing;")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var doc *document
+	if idx, ok := s.state.Indices[name]; ok {
+		doc = idx.Docs[id]
+	}
+	if doc == nil {
+		var upsert map[string]interface{}
+		switch {
+		case body.Upsert != nil:
+			upsert = body.Upsert
+		case body.DocAsUpsert && body.Doc != nil:
+			upsert = body.Doc
+		default:
+			return writeResult{}, &esError{Status: http.StatusNotFound, Type: "document_missing_exception", Reason: "[" + id + "]: document missing"}
+		}
+		idx, err := s.writeIndexLocked(name)
+		if err != nil {
+			return writeResult{}, err
+		}
+		return s.putLocked(name, idx, id, deepCopy(upsert).(map[string]interface{})), nil
+	}
+
+	idx := s.state.Indices[name]
+	noop := writeResult{Index: name, ID: id, SeqNo: doc.SeqNo, Version: doc.Version, Result: "noop", Status: http.StatusOK}
+	source := deepCopy(doc.Source).(map[string]interface{})
+	if body.Script != nil {
+		var (
+			op  string
+			err error
+		)
+		source, op, err = runUpdateScript(body.Script, id, source)
+		if err != nil {
+			return writeResult{}, errBadRequest("script_exception", "%v", err)
+		}
+		switch op {
+		case "noop", "none":
+			return noop, nil
+		case "delete":
+			return s.putLocked(name, idx, id, nil), nil
+		}
+	} else {
+		mergeSource(source, body.Doc)
+		if (body.DetectNoop == nil || *body.DetectNoop) && reflect.DeepEqual(source, doc.Source) {
+			return noop, nil
+		}
+	}
+	res := s.putLocked(name, idx, id, source)
+	res.Status = http.StatusOK
+	return res, nil
+}
+
+// runUpdateScript runs the script of an update on the source of a document, it returns the updated source and the
+// operation set by the script in ctx.op.
+func runUpdateScript(scr interface{}, id string, source map[string]interface{}) (map[string]interface{}, string, error) {
+	code, params, err := scriptOf(scr)
+	if err != nil {
+		return nil, "", err
+	}
+	run, err := updateScriptOf(code, params)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx := &updateContext{source: source, id: id, op: "index"}
+	if err := run(ctx, params); err != nil {
+		return nil, "", err
+	}
+	return ctx.source, ctx.op, nil
+}
+
+// scriptOf returns the source and the params of a script, written as a string or an object.
+func scriptOf(scr interface{}) (string, map[string]interface{}, error) {
+	switch scr := scr.(type) {
+	case string:
+		return scr, map[string]interface{}{}, nil
+	case map[string]interface{}:
+		code, _ := scr["source"].(string)
+		if code == "" {
+			code, _ = scr["inline"].(string)
+		}
+		if lang, ok := scr["lang"].(string); ok && lang != "painless" {
+			return "", nil, fmt.Errorf("unsupported script language [%s]", lang)
+		}
+		params, ok := scr["params"].(map[string]interface{})
+		if !ok {
+			params = map[string]interface{}{}
+		}
+		return code, deepCopy(params).(map[string]interface{}), nil
+	}
+	return "", nil, fmt.Errorf("invalid script")
+}
+
+// mergeSource merges the partial document of an update in source, the objects are merged and the other values are
+// replaced.
+func mergeSource(source, doc map[string]interface{}) {
+	for k, v := range doc {
+		if vm, ok := v.(map[string]interface{}); ok {
+			if sm, ok := source[k].(map[string]interface{}); ok {
+				mergeSource(sm, vm)
+				continue
+			}
+		}
+		source[k] = deepCopy(v)
+	}
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = deepCopy(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			l[i] = deepCopy(item)
+		}
+		return l
+	}
+	return v
+}
+
+// getDoc returns a document, it is nil when the document or its index does not exist.
+func (s *Store) getDoc(name, id string) *document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if idx, ok := s.state.Indices[name]; ok {
+		return idx.Docs[id]
+	}
+	return nil
+}
+
+// resolveLocked returns the indices of a comma separated list of names and wildcard patterns. A missing index is an
+// error when its name is not a pattern, unless ignoreUnavailable is set.
+func (s *Store) resolveLocked(expr string, ignoreUnavailable bool) ([]string, *esError) {
+	seen := map[string]bool{}
+	var names []string
+	for _, pattern := range strings.Split(expr, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if pattern == "_all" {
+			pattern = "*"
+		}
+		if !strings.Contains(pattern, "*") {
+			if _, ok := s.state.Indices[pattern]; !ok {
+				if ignoreUnavailable {
+					continue
+				}
+				return nil, errIndexNotFound(pattern)
+			}
+			if !seen[pattern] {
+				seen[pattern] = true
+				names = append(names, pattern)
+			}
+			continue
+		}
+		for name := range s.state.Indices {
+			if ok, _ := filepath.Match(pattern, name); ok && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// waitCheckpoint waits until the global checkpoint of the index is after checkpoint, it returns the checkpoint and
+// false when the timeout expires first. A missing index has the checkpoint -1.
+func (s *Store) waitCheckpoint(ctx context.Context, name string, checkpoint int64, timeout time.Duration) (int64, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		current := int64(-1)
+		if idx, ok := s.state.Indices[name]; ok {
+			current = idx.SeqNo
+		}
+		changed := s.changed
+		s.mu.Unlock()
+		if current > checkpoint {
+			return current, true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return current, false
+		case <-ctx.Done():
+			return current, false
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build dev
+
+package es
+
+import (
+	"net/http"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es/memory"
+)
+
+// memoryTransport returns the transport of the in-memory backend, authenticating the service token of the output, and
+// its address. The backend is only built with the dev build tag.
+func memoryTransport(cfg config.Memory, serviceToken string) (http.RoundTripper, string, error) {
+	store, err := memory.Open(cfg, serviceToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return store, memory.Address, nil
+}
@@ -6,6 +6,9 @@ package es
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 )
@@ -34,6 +37,7 @@ type HitT struct {
 	Source  json.RawMessage        `json:"_source"`
 	Score   *float64               `json:"_score"`
 	Fields  map[string]interface{} `json:"fields"`
+	Sort    []interface{}          `json:"sort,omitempty"`
 }
 
 func (hit *HitT) Unmarshal(v interface{}) error {
@@ -57,56 +61,85 @@ type HitsT struct {
 }
 
 type Bucket struct {
-	// any fields added here with json tags must also be added to the
-	// delete calls in the `UnmarshalJSON` function below
-	Key          string           `json:"key"`
-	DocCount     int64            `json:"doc_count"`
+	// Key is the key of the bucket, the numeric keys, like the timestamps of a date histogram, are formatted as strings.
+	Key string `json:"key"`
+	// KeyAsString is the formatted key of a date histogram bucket.
+	KeyAsString string `json:"key_as_string,omitempty"`
+	DocCount    int64  `json:"doc_count"`
+	// Aggregations are the top hits aggregations of the bucket.
 	Aggregations map[string]HitsT `json:"-"`
+	// SubAggregations are the other aggregations of the bucket, like nested terms and metrics.
+	SubAggregations map[string]Aggregation `json:"-"`
 }
 
-type _bucket Bucket
-
 func (b *Bucket) UnmarshalJSON(data []byte) error {
-	b2 := _bucket{}
-	err := json.Unmarshal(data, &b2)
-	if err != nil {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
 		return err
 	}
-	var aggs map[string]interface{}
-	err = json.Unmarshal(data, &aggs)
-	if err != nil {
-		return err
+	b2 := Bucket{
+		Aggregations:    make(map[string]HitsT),
+		SubAggregations: make(map[string]Aggregation),
 	}
-	// remove the json keys that already unmarshalled into the
-	// bucket. this needs to stay in sync with the json tags
-	// from `Bucket`.
-	delete(aggs, "key")
-	delete(aggs, "doc_count")
-	b2.Aggregations = make(map[string]HitsT)
-	for name, value := range aggs {
-		vMap, ok := value.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		hMap, ok := vMap["hits"]
-		if !ok {
-			continue
+	for name, value := range fields {
+		var err error
+		switch name {
+		case "key":
+			b2.Key, err = bucketKey(value)
+		case "key_as_string":
+			err = json.Unmarshal(value, &b2.KeyAsString)
+		case "doc_count":
+			err = json.Unmarshal(value, &b2.DocCount)
+		default:
+			if len(value) == 0 || value[0] != '{' {
+				continue
+			}
+			var agg struct {
+				Hits *HitsT `json:"hits"`
+				Aggregation
+			}
+			if err = json.Unmarshal(value, &agg); err != nil {
+				break
+			}
+			if agg.Hits != nil {
+				b2.Aggregations[name] = *agg.Hits
+			} else {
+				b2.SubAggregations[name] = agg.Aggregation
+			}
 		}
-		data, err := json.Marshal(hMap)
 		if err != nil {
-			return err
+			return fmt.Errorf("bucket %s: %w", name, err)
 		}
-		var hits HitsT
-		err = json.Unmarshal(data, &hits)
-		if err != nil {
-			return err
-		}
-		b2.Aggregations[name] = hits
 	}
-	*b = Bucket(b2)
+	*b = b2
 	return nil
 }
 
+// bucketKey returns the key of a bucket as a string, the numbers are kept as written by Elasticsearch.
+func bucketKey(value json.RawMessage) (string, error) {
+	if len(value) > 0 && value[0] == '"' {
+		var key string
+		err := json.Unmarshal(value, &key)
+		return key, err
+	}
+	return string(value), nil
+}
+
+// Time returns the time of a date histogram bucket, its key is a timestamp in milliseconds.
+func (b *Bucket) Time() (time.Time, error) {
+	ms, err := strconv.ParseInt(b.Key, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bucket key %q is not a timestamp: %w", b.Key, err)
+	}
+	return time.UnixMilli(ms).UTC(), nil
+}
+
+// Value returns the value of the metric sub aggregation of the bucket, like a cardinality.
+func (b *Bucket) Value(name string) (float64, bool) {
+	agg, ok := b.SubAggregations[name]
+	return agg.Value, ok
+}
+
 type Aggregation struct {
 	Value                   float64  `json:"value"`
 	DocCountErrorUpperBound int64    `json:"doc_count_error_upper_bound"`
@@ -114,6 +147,15 @@ type Aggregation struct {
 	Buckets                 []Bucket `json:"buckets,omitempty"`
 }
 
+// Counts returns the number of documents of each bucket of a terms or date histogram aggregation by key.
+func (a *Aggregation) Counts() map[string]int64 {
+	counts := make(map[string]int64, len(a.Buckets))
+	for _, b := range a.Buckets {
+		counts[b.Key] = b.DocCount
+	}
+	return counts
+}
+
 type Response struct {
 	Status   int    `json:"status"`
 	Took     uint64 `json:"took"`
@@ -9,6 +9,7 @@ package es
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/google/go-cmp/cmp"
@@ -73,3 +74,59 @@ func TestHitUnmarshal(t *testing.T) {
 	}
 
 }
+
+func TestAggregationUnmarshal(t *testing.T) {
+	body := []byte(`{
+		"took": 1,
+		"hits": {"hits": []},
+		"aggregations": {
+			"status": {
+				"doc_count_error_upper_bound": 0,
+				"sum_other_doc_count": 0,
+				"buckets": [
+					{"key": "online", "doc_count": 3, "agents": {"value": 2}},
+					{"key": "offline", "doc_count": 1, "agents": {"value": 1}}
+				]
+			},
+			"enrolled": {
+				"buckets": [
+					{"key_as_string": "2024-06-10T00:00:00.000Z", "key": 1717977600000, "doc_count": 4}
+				]
+			},
+			"latest": {
+				"buckets": [
+					{"key": "policy-id", "doc_count": 1, "revision_idx": {"hits": {"hits": [{"_id": "p1", "_source": {}}]}}}
+				]
+			}
+		}
+	}`)
+	var res Response
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	status := res.Aggregations["status"]
+	if diff := cmp.Diff(map[string]int64{"online": 3, "offline": 1}, status.Counts()); diff != "" {
+		t.Error(diff)
+	}
+	if v, ok := status.Buckets[0].Value("agents"); !ok || v != 2 {
+		t.Errorf("expected 2 agents online, got %v", v)
+	}
+
+	enrolled := res.Aggregations["enrolled"].Buckets[0]
+	if diff := cmp.Diff("2024-06-10T00:00:00.000Z", enrolled.KeyAsString); diff != "" {
+		t.Error(diff)
+	}
+	ts, err := enrolled.Time()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), ts); diff != "" {
+		t.Error(diff)
+	}
+
+	latest := res.Aggregations["latest"].Buckets[0]
+	if diff := cmp.Diff("p1", latest.Aggregations["revision_idx"].Hits[0].ID); diff != "" {
+		t.Error(diff)
+	}
+}
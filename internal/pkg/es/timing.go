@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package es
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Timer accumulates the time spent waiting on Elasticsearch on behalf of a request.
+// The time of concurrent calls is summed, it may exceed the duration of the request.
+type Timer struct {
+	ns atomic.Int64
+}
+
+type ctxTimerKey struct{}
+
+// WithTimer returns a context the time spent in the Elasticsearch calls made with is added to t.
+func WithTimer(ctx context.Context, t *Timer) context.Context {
+	return context.WithValue(ctx, ctxTimerKey{}, t)
+}
+
+// AddTime adds d to the timer of ctx, if any.
+func AddTime(ctx context.Context, d time.Duration) {
+	if t, ok := ctx.Value(ctxTimerKey{}).(*Timer); ok {
+		t.ns.Add(int64(d))
+	}
+}
+
+// Elapsed returns the accumulated time.
+func (t *Timer) Elapsed() time.Duration {
+	return time.Duration(t.ns.Load())
+}
+
+// TimeRoundTripper adds the time of the requests sent to Elasticsearch to the timer of their context.
+func TimeRoundTripper() ConfigOption {
+	return func(config *elasticsearch.Config) {
+		next := config.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		config.Transport = timingRoundTripper{next: next}
+	}
+}
+
+type timingRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (rt timingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	AddTime(req.Context(), time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+var ErrDecrypt = errors.New("unable to decrypt chunk contents")
+
+// Cipher seals chunk contents with AES-GCM.
+// Sealed contents are the random nonce followed by the ciphertext and authentication tag.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns the Cipher for the configuration, nil is returned if encryption is disabled.
+func New(cfg config.UploadEncryption) (*Cipher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	key, err := cfg.DecodeKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(key)
+}
+
+// NewCipher returns a Cipher using the AES key.
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Overhead is the number of bytes sealed contents are longer than the plaintext.
+func (c *Cipher) Overhead() int {
+	return c.aead.NonceSize() + c.aead.Overhead()
+}
+
+// Seal encrypts plaintext, id is authenticated with it so the contents can not be moved to another chunk.
+func (c *Cipher) Seal(plaintext []byte, id string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, []byte(id)), nil
+}
+
+// Open decrypts contents returned by Seal for the same id.
+func (c *Cipher) Open(sealed []byte, id string) ([]byte, error) {
+	if len(sealed) < c.Overhead() {
+		return nil, ErrDecrypt
+	}
+	n := c.aead.NonceSize()
+	plaintext, err := c.aead.Open(nil, sealed[:n], sealed[n:], []byte(id))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
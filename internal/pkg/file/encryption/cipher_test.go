@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package encryption
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(make([]byte, 32))
+	require.NoError(t, err)
+
+	sealed, err := c.Seal([]byte("chunkdata"), "file.0")
+	require.NoError(t, err)
+	assert.Len(t, sealed, len("chunkdata")+c.Overhead())
+	assert.NotContains(t, string(sealed), "chunkdata")
+
+	plain, err := c.Open(sealed, "file.0")
+	require.NoError(t, err)
+	assert.Equal(t, "chunkdata", string(plain))
+
+	// contents are bound to the chunk they were sealed for
+	_, err = c.Open(sealed, "file.1")
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = c.Open(sealed, "file.0")
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = c.Open([]byte("short"), "file.0")
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(config.UploadEncryption{})
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = New(config.UploadEncryption{Enabled: true, Key: base64.StdEncoding.EncodeToString([]byte("short"))})
+	assert.Error(t, err)
+
+	c, err = New(config.UploadEncryption{Enabled: true, Key: base64.StdEncoding.EncodeToString(make([]byte, 16))})
+	require.NoError(t, err)
+	assert.NotNil(t, c)
+}
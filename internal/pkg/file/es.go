@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"go.elastic.co/apm/v2"
@@ -23,6 +24,10 @@ const (
 	FieldLast     = "last"
 	FieldSHA2     = "sha2"
 	FieldUploadID = "upload_id"
+	FieldSize     = "size"
+
+	// chunkInfosPageSize is the number of chunk infos searched at once, the chunks of larger files are paged.
+	chunkInfosPageSize = 10000
 )
 
 var (
@@ -42,13 +47,13 @@ func prepareChunkInfo(size bool) *dsl.Tmpl {
 		root.Param("script_fields", map[string]interface{}{
 			"size": map[string]interface{}{
 				"script": map[string]interface{}{
-					"lang":   "painless",
-					"source": "params._source.data.length",
+					"lang": "painless",
+					// chunks kept by a storage backend have no data, only their size
+					"source": "params._source.containsKey('data') ? params._source.data.length : params._source.size",
 				},
 			},
 		})
 	}
-	root.Size(10000)
 	tmpl.MustResolve(root)
 	return tmpl
 }
@@ -104,16 +109,18 @@ func GetInfo(ctx context.Context, bulker bulk.Bulk, indexPattern string, uploadI
 	}
 
 	return Info{
-		ID:        fi.UploadID,
-		Source:    fi.Source,
-		AgentID:   fi.AgentID,
-		ActionID:  fi.ActionID,
-		DocID:     results[0].ID,
-		ChunkSize: fi.File.ChunkSize,
-		Total:     fi.File.Size,
-		Count:     int(cnt),
-		Start:     fi.Start,
-		Status:    Status(fi.File.Status),
+		ID:            fi.UploadID,
+		Source:        fi.Source,
+		AgentID:       fi.AgentID,
+		ActionID:      fi.ActionID,
+		DocID:         results[0].ID,
+		ChunkSize:     fi.File.ChunkSize,
+		Total:         fi.File.Size,
+		Count:         int(cnt),
+		Start:         fi.Start,
+		Status:        Status(fi.File.Status),
+		Encrypted:     fi.Encrypted,
+		FailureReason: fi.FailureReason,
 	}, nil
 }
 
@@ -152,13 +159,13 @@ func GetChunkInfos(ctx context.Context, bulker bulk.Bulk, indexPattern string, b
 	}
 
 	bSpan, bCtx := apm.StartSpan(ctx, "searchChunksInfo", "search")
-	res, err := bulker.Search(bCtx, fmt.Sprintf(indexPattern, "*"), query)
+	hits, err := dl.SearchAll(bCtx, bulker, fmt.Sprintf(indexPattern, "*"), query, chunkInfosPageSize)
 	bSpan.End()
 	if err != nil {
 		return nil, err
 	}
 
-	chunks := make([]ChunkInfo, len(res.HitsT.Hits))
+	chunks := make([]ChunkInfo, len(hits))
 
 	var (
 		bid  string
@@ -170,7 +177,7 @@ func GetChunkInfos(ctx context.Context, bulker bulk.Bulk, indexPattern string, b
 
 	vSpan, _ := apm.StartSpan(ctx, "validateChunksInfo", "validate")
 	defer vSpan.End()
-	for i, h := range res.HitsT.Hits {
+	for i, h := range hits {
 		if bid, ok = getResultsFieldString(h.Fields, FieldBaseID); !ok {
 			return nil, fmt.Errorf("unable to retrieve %s field from chunk document", FieldBaseID)
 		}
@@ -33,6 +33,7 @@ const (
 )
 
 type FileData struct {
+	Name      string `json:"name,omitempty"`
 	Size      int64  `json:"size"`
 	ChunkSize int64  `json:"ChunkSize"`
 	Status    string `json:"Status"`
@@ -52,6 +53,9 @@ type MetaDoc struct {
 	UploadID   string    `json:"upload_id"`
 	Start      time.Time `json:"upload_start"`
 	Namespaces []string  `json:"namespaces"`
+	Encrypted  bool      `json:"encrypted,omitempty"` // chunk contents are sealed with the server encryption key
+	// FailureReason describes why the upload failed, if it did
+	FailureReason string `json:"failure_reason,omitempty"`
 }
 
 // custom unmarshaller to make unix-epoch values work
@@ -94,6 +98,9 @@ type Info struct {
 	Count      int
 	Start      time.Time
 	Status     Status
+	Encrypted  bool
+	// FailureReason describes why an upload with StatusFail failed, it is empty for failures recorded before it was tracked
+	FailureReason string
 }
 
 // convenience functions for computing current "Status" based on the fields
@@ -0,0 +1,10 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+/*
+The storage package provides backends that hold the contents of uploaded
+file chunks outside of elasticsearch, such as a local filesystem or an
+S3 compatible object store
+*/
+package storage
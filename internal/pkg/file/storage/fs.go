@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// Filesystem stores chunks as files in a local directory.
+type Filesystem struct {
+	root string
+}
+
+// NewFilesystem returns a backend that stores chunks under root, the directory is created if needed.
+func NewFilesystem(root string) (*Filesystem, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create upload storage directory: %w", err)
+	}
+	return &Filesystem{root: root}, nil
+}
+
+func (f *Filesystem) Type() string {
+	return config.UploadStorageFilesystem
+}
+
+// path resolves key within the root directory, keys that would escape it are rejected.
+func (f *Filesystem) path(key string) (string, error) {
+	p := filepath.Join(f.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, f.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return p, nil
+}
+
+// Put writes to a temporary file that is renamed into place once complete, so a partial write is never visible.
+func (f *Filesystem) Put(_ context.Context, key string, r io.Reader, _ int64) (int64, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".chunk-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // removal fails once the file is renamed
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return n, err
+	}
+	if err := tmp.Close(); err != nil {
+		return n, err
+	}
+	return n, os.Rename(tmp.Name(), p)
+}
+
+func (f *Filesystem) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (f *Filesystem) Delete(_ context.Context, key string) error {
+	p, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
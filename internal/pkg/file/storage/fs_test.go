@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystem(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+
+	key := ChunkKey("endpoint", "action.agent", 0)
+	n, err := fs.Put(ctx, key, strings.NewReader("chunkdata"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), n)
+
+	r, err := fs.Get(ctx, key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "chunkdata", string(data))
+
+	require.NoError(t, fs.Delete(ctx, key))
+	_, err = fs.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// deleting a missing chunk is not an error
+	assert.NoError(t, fs.Delete(ctx, key))
+}
+
+func TestFilesystemRejectsKeysOutsideRoot(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = fs.Put(context.Background(), ChunkKey("..", "x", 0), strings.NewReader("data"), -1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = fs.Get(context.Background(), "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+var (
+	ErrNotFound   = errors.New("stored chunk not found")
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Backend stores the contents of file chunks.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Type returns the configured storage type.
+	Type() string
+	// Put stores the contents of r under key and returns the number of bytes written.
+	// size is the length of r if known, or -1.
+	Put(ctx context.Context, key string, r io.Reader, size int64) (int64, error)
+	// Get returns the contents stored under key, ErrNotFound is returned if it does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the contents stored under key, deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the backend for the configuration.
+// A nil Backend is returned for elasticsearch storage, chunks are then written in full to elasticsearch.
+func New(cfg config.UploadStorage) (Backend, error) {
+	switch cfg.Type {
+	case "", config.UploadStorageElasticsearch:
+		return nil, nil
+	case config.UploadStorageFilesystem:
+		return NewFilesystem(cfg.Filesystem.Path)
+	case config.UploadStorageS3:
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown upload storage type %q", cfg.Type)
+	}
+}
+
+// ChunkKey returns the storage key for a chunk of a file.
+func ChunkKey(source, fileID string, chunkNum int) string {
+	return fmt.Sprintf("%s/%s.%d", url.PathEscape(source), url.PathEscape(fileID), chunkNum)
+}
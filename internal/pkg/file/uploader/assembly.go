@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package uploader
+
+import (
+	"sync"
+)
+
+// assembly tracks the chunks of each upload that are being written by this instance.
+// Chunks may arrive in any order and in parallel, the chunk documents in elasticsearch
+// record which chunks have been received and are checked when the upload is completed.
+type assembly struct {
+	mu          sync.Mutex
+	maxParallel int // 0 allows any number of chunks of an upload to be written at once
+	inflight    map[string]map[int]struct{}
+}
+
+func newAssembly(maxParallel int) *assembly {
+	return &assembly{
+		maxParallel: maxParallel,
+		inflight:    make(map[string]map[int]struct{}),
+	}
+}
+
+// acquire marks the chunk of the upload as being written.
+// release must be called once the write has finished.
+func (a *assembly) acquire(uploadID string, pos int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	chunks, ok := a.inflight[uploadID]
+	if !ok {
+		chunks = make(map[int]struct{})
+		a.inflight[uploadID] = chunks
+	}
+	if _, ok := chunks[pos]; ok {
+		return ErrChunkInProgress
+	}
+	if a.maxParallel > 0 && len(chunks) >= a.maxParallel {
+		return ErrTooManyParallelChunks
+	}
+	chunks[pos] = struct{}{}
+	return nil
+}
+
+func (a *assembly) release(uploadID string, pos int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	chunks := a.inflight[uploadID]
+	delete(chunks, pos)
+	if len(chunks) == 0 {
+		delete(a.inflight, uploadID)
+	}
+}
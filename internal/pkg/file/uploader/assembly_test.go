@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package uploader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssembly(t *testing.T) {
+	a := newAssembly(2)
+
+	require.NoError(t, a.acquire("upload", 3))
+	assert.ErrorIs(t, a.acquire("upload", 3), ErrChunkInProgress)
+	require.NoError(t, a.acquire("upload", 0))
+	assert.ErrorIs(t, a.acquire("upload", 1), ErrTooManyParallelChunks)
+
+	// the limit applies per upload
+	require.NoError(t, a.acquire("other", 1))
+
+	a.release("upload", 3)
+	require.NoError(t, a.acquire("upload", 1))
+
+	a.release("upload", 0)
+	a.release("upload", 1)
+	a.release("other", 1)
+	assert.Empty(t, a.inflight)
+}
+
+func TestAssemblyUnlimited(t *testing.T) {
+	a := newAssembly(0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, a.acquire("upload", i))
+	}
+}
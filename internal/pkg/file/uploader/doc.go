@@ -4,6 +4,8 @@
 
 /*
 The uploader package provides for files to be delivered from agent or
-agent integration into elasticsearch for storage
+agent integration into elasticsearch for storage.
+Chunk contents may instead be kept by a storage backend, see the storage package,
+in which case only the file and chunk metadata are written to elasticsearch
 */
 package uploader
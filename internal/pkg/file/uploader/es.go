@@ -8,8 +8,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dsl"
@@ -32,8 +34,45 @@ var (
 	MatchChunkByBID      = prepareQueryChunkByBID()
 	MatchChunkByDocument = prepareQueryChunkByDoc()
 	UpdateMetaDocByID    = prepareUpdateMetaDoc()
+	QueryActiveUploads   = prepareQueryActiveUploads()
+	QueryUploadedBytes   = prepareQueryUploadedBytes()
+	QueryUploadsBefore   = prepareQueryUploadsBefore()
 )
 
+const (
+	fieldAgentID     = "agent_id"
+	fieldUploadStart = "upload_start"
+	fieldFileSize    = "file.size"
+	fieldFileStatus  = "file.Status"
+)
+
+// uploads of an agent that have not finished and were started after since
+func prepareQueryActiveUploads() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(0)
+	root.Param("track_total_hits", true)
+	must := root.Query().Bool().Must()
+	must.Term(fieldAgentID, tmpl.Bind(fieldAgentID), nil)
+	must.Terms(fieldFileStatus, []file.Status{file.StatusAwaiting, file.StatusProgress}, nil)
+	must.Range(fieldUploadStart, dsl.WithRangeGT(tmpl.Bind("since")))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// total size of the files an agent started uploading after since
+func prepareQueryUploadedBytes() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(0)
+	must := root.Query().Bool().Must()
+	must.Term(fieldAgentID, tmpl.Bind(fieldAgentID), nil)
+	must.Range(fieldUploadStart, dsl.WithRangeGT(tmpl.Bind("since")))
+	root.Aggs().Agg("bytes").Param("sum", map[string]string{"field": fieldFileSize})
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
 func prepareQueryChunkByBID() *dsl.Tmpl {
 	tmpl := dsl.NewTmpl()
 	root := dsl.NewRoot()
@@ -61,6 +100,19 @@ func prepareUpdateMetaDoc() *dsl.Tmpl {
 	prm := scr.Params()
 	prm.Param("status", tmpl.Bind("status"))
 	prm.Param("hash", tmpl.Bind("hash"))
+	prm.Param("reason", tmpl.Bind("reason"))
+	tmpl.MustResolve(root)
+	return tmpl
+}
+
+// uploads with one of the given statuses that were started at or before the given time
+func prepareQueryUploadsBefore() *dsl.Tmpl {
+	tmpl := dsl.NewTmpl()
+	root := dsl.NewRoot()
+	root.Size(100)
+	must := root.Query().Bool().Must()
+	must.Terms(fieldFileStatus, tmpl.Bind("statuses"), nil)
+	must.Range(fieldUploadStart, dsl.WithRangeLTE(tmpl.Bind("before")))
 	tmpl.MustResolve(root)
 	return tmpl
 }
@@ -75,7 +127,120 @@ func CreateFileDoc(ctx context.Context, bulker bulk.Bulk, doc []byte, source str
 	return bulker.Create(ctx, fmt.Sprintf(UploadHeaderIndexPattern, source), fileID, doc, bulk.WithRefresh())
 }
 
+// CountActiveUploads returns the number of unfinished uploads an agent started after since.
+func CountActiveUploads(ctx context.Context, bulker bulk.Bulk, agentID string, since time.Time) (int, error) {
+	span, ctx := apm.StartSpan(ctx, "countActiveUploads", "search")
+	defer span.End()
+	q, err := QueryActiveUploads.Render(map[string]interface{}{
+		fieldAgentID: agentID,
+		"since":      since.UnixMilli(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	res, err := bulker.Search(ctx, fmt.Sprintf(UploadHeaderIndexPattern, "*"), q)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.HitsT.Total.Value), nil
+}
+
+// SumUploadedBytes returns the total size of the files an agent started uploading after since.
+func SumUploadedBytes(ctx context.Context, bulker bulk.Bulk, agentID string, since time.Time) (int64, error) {
+	span, ctx := apm.StartSpan(ctx, "sumUploadedBytes", "search")
+	defer span.End()
+	q, err := QueryUploadedBytes.Render(map[string]interface{}{
+		fieldAgentID: agentID,
+		"since":      since.UnixMilli(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	res, err := bulker.Search(ctx, fmt.Sprintf(UploadHeaderIndexPattern, "*"), q)
+	if err != nil {
+		return 0, err
+	}
+	return int64(res.Aggregations["bytes"].Value), nil
+}
+
+// FindUploadsBefore returns up to 100 uploads with one of statuses that were started at or before the given time.
+func FindUploadsBefore(ctx context.Context, bulker bulk.Bulk, statuses []file.Status, before time.Time) ([]file.Info, error) {
+	span, ctx := apm.StartSpan(ctx, "findUploadsBefore", "search")
+	defer span.End()
+	q, err := QueryUploadsBefore.Render(map[string]interface{}{
+		"statuses": statuses,
+		"before":   before.UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := bulker.Search(ctx, fmt.Sprintf(UploadHeaderIndexPattern, "*"), q)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]file.Info, 0, len(res.HitsT.Hits))
+	for _, hit := range res.HitsT.Hits {
+		var fi file.MetaDoc
+		if err := json.Unmarshal(hit.Source, &fi); err != nil {
+			return nil, fmt.Errorf("file meta doc parsing error: %w", err)
+		}
+		infos = append(infos, file.Info{
+			ID:       fi.UploadID,
+			DocID:    hit.ID,
+			Source:   fi.Source,
+			AgentID:  fi.AgentID,
+			ActionID: fi.ActionID,
+			Start:    fi.Start,
+			Status:   file.Status(fi.File.Status),
+		})
+	}
+	return infos, nil
+}
+
+// DeleteFileDoc removes the metadata document of an upload.
+// The index is refreshed so that the next search of the uploads to clean up does not find it again.
+func DeleteFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID string) error {
+	span, ctx := apm.StartSpan(ctx, "deleteFileInfo", "delete_by_query")
+	defer span.End()
+	q, err := MatchChunkByDocument.Render(map[string]interface{}{
+		"_id": fileID,
+	})
+	if err != nil {
+		return err
+	}
+	client := bulker.Client()
+	resp, err := client.DeleteByQuery([]string{fmt.Sprintf(UploadHeaderIndexPattern, source)}, bytes.NewReader(q),
+		client.DeleteByQuery.WithContext(ctx),
+		client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return fmt.Errorf("unable to delete file metadata %s: %s", fileID, resp.String())
+	}
+	return nil
+}
+
 func UpdateFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID string, status file.Status, hash string) error {
+	return updateFileDoc(ctx, bulker, source, fileID, status, hash, "", "ctx._source.file.Status = params.status; if(params.hash != ''){ ctx._source.transithash = ['sha256':params.hash]; }")
+}
+
+// FailFileDoc marks an upload as failed and records why.
+func FailFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID string, reason string) error {
+	return updateFileDoc(ctx, bulker, source, fileID, file.StatusFail, "", reason, "ctx._source.file.Status = params.status; ctx._source.failure_reason = params.reason;")
+}
+
+// MarkUploading moves an upload that is awaiting chunks to the uploading status.
+// Uploads in any other status are left untouched so that a failure recorded by a parallel chunk request is not overwritten.
+func MarkUploading(ctx context.Context, bulker bulk.Bulk, info file.Info) error {
+	return updateFileDoc(ctx, bulker, info.Source, info.DocID, file.StatusProgress, "", "",
+		fmt.Sprintf("if (ctx._source.file.Status == '%s') { ctx._source.file.Status = params.status } else { ctx.op = 'noop' }", file.StatusAwaiting))
+}
+
+func updateFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID string, status file.Status, hash string, reason string, script string) error {
 	span, ctx := apm.StartSpan(ctx, "updateFileInfo", "update_by_query")
 	defer span.End()
 	client := bulker.Client()
@@ -84,7 +249,8 @@ func UpdateFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID
 		"_id":    fileID,
 		"status": string(status),
 		"hash":   hash,
-		"source": "ctx._source.file.Status = params.status; if(params.hash != ''){ ctx._source.transithash = ['sha256':params.hash]; }",
+		"reason": reason,
+		"source": script,
 	})
 	if err != nil {
 		return err
@@ -93,6 +259,7 @@ func UpdateFileDoc(ctx context.Context, bulker bulk.Bulk, source string, fileID
 	resp, err := client.UpdateByQuery([]string{fmt.Sprintf(UploadHeaderIndexPattern, source)}, client.UpdateByQuery.WithContext(ctx),
 		func(req *esapi.UpdateByQueryRequest) {
 			req.Body = bytes.NewReader(q)
+			req.Conflicts = "proceed"
 		})
 	if err != nil {
 		return err
@@ -142,12 +309,55 @@ func IndexChunk(ctx context.Context, client *elasticsearch.Client, body *cbor.Ch
 	}
 	zerolog.Ctx(ctx).Trace().Int("status_code", resp.StatusCode).Interface("chunk-response", response).Msg("uploaded chunk")
 
+	if response.Error.Type == "version_conflict_engine_exception" {
+		return ErrChunkExists
+	}
 	if response.Error.Type != "" {
 		return fmt.Errorf("%s: %s caused by %s: %s", response.Error.Type, response.Error.Reason, response.Error.Cause.Type, response.Error.Cause.Reason)
 	}
 	return nil
 }
 
+// IndexChunkInfo writes a chunk document without data, for chunks whose contents are kept by a storage backend.
+// The size field is stored in place of the data so that finalization can verify chunk sizes.
+func IndexChunkInfo(ctx context.Context, bulker bulk.Bulk, source string, fileID string, chunkNum int, last bool, chunkHash string, size int64) error {
+	span, ctx := apm.StartSpan(ctx, "createChunkInfo", "create")
+	defer span.End()
+	doc, err := json.Marshal(map[string]interface{}{
+		"@timestamp":     time.Now().UnixMilli(),
+		file.FieldLast:   last,
+		file.FieldBaseID: fileID,
+		file.FieldSHA2:   chunkHash,
+		file.FieldSize:   size,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = bulker.Create(ctx, fmt.Sprintf(UploadDataIndexPattern, source), fmt.Sprintf("%s.%d", fileID, chunkNum), doc, bulk.WithRefresh())
+	if errors.Is(err, es.ErrElasticVersionConflict) {
+		return ErrChunkExists
+	}
+	return err
+}
+
+// ChunkExists reports whether a chunk document has been written.
+// It is meant for chunk documents written by IndexChunkInfo, the matching document is returned in full.
+func ChunkExists(ctx context.Context, bulker bulk.Bulk, source string, fileID string, chunkNum int) (bool, error) {
+	span, ctx := apm.StartSpan(ctx, "chunkExists", "search")
+	defer span.End()
+	q, err := MatchChunkByDocument.Render(map[string]interface{}{
+		"_id": fmt.Sprintf("%s.%d", fileID, chunkNum),
+	})
+	if err != nil {
+		return false, err
+	}
+	res, err := bulker.Search(ctx, fmt.Sprintf(UploadDataIndexPattern, source), q)
+	if err != nil {
+		return false, err
+	}
+	return len(res.HitsT.Hits) > 0, nil
+}
+
 type ChunkUploadResponse struct {
 	Index   string `json:"_index"`
 	ID      string `json:"_id"`
@@ -161,6 +371,8 @@ type ChunkUploadResponse struct {
 	Error es.ErrorT `json:"error"`
 }
 
+// DeleteChunk removes a single chunk.
+// The index is refreshed so that the chunk may be uploaded again right away.
 func DeleteChunk(ctx context.Context, bulker bulk.Bulk, source string, fileID string, chunkNum int) error {
 	span, ctx := apm.StartSpan(ctx, "deleteChunk", "delete_by_query")
 	defer span.End()
@@ -171,8 +383,29 @@ func DeleteChunk(ctx context.Context, bulker bulk.Bulk, source string, fileID st
 		return err
 	}
 	client := bulker.Client()
-	_, err = client.DeleteByQuery([]string{fmt.Sprintf(UploadDataIndexPattern, source)}, bytes.NewReader(q), client.DeleteByQuery.WithContext(ctx))
-	return err
+	resp, err := client.DeleteByQuery([]string{fmt.Sprintf(UploadDataIndexPattern, source)}, bytes.NewReader(q),
+		client.DeleteByQuery.WithContext(ctx),
+		client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Error    es.ErrorT `json:"error"`
+		Failures []any     `json:"failures"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return err
+	}
+	if response.Error.Type != "" {
+		return fmt.Errorf("%s: %s caused by %s: %s", response.Error.Type, response.Error.Reason, response.Error.Cause.Type, response.Error.Cause.Reason)
+	}
+	if len(response.Failures) > 0 {
+		return fmt.Errorf("unable to delete chunk %d of %s: %d failures", chunkNum, fileID, len(response.Failures))
+	}
+	return nil
 }
 
 func DeleteAllChunksForFile(ctx context.Context, bulker bulk.Bulk, source string, baseID string) error {
@@ -9,6 +9,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 
@@ -50,11 +51,11 @@ func (u *Uploader) Complete(ctx context.Context, id string, transitHash string)
 		vSpan.End()
 		return info, ErrMissingChunks
 	}
-	if !u.verifyChunkInfo(info, chunks, transitHash) {
-		if err := SetStatus(ctx, u.bulker, info, file.StatusFail); err != nil {
+	if verr := u.verifyChunkInfo(info, chunks, transitHash); verr != nil {
+		if err := SetFailed(ctx, u.bulker, info, verr.Error()); err != nil {
 			zerolog.Ctx(ctx).Warn().Err(err).Str("fileID", info.DocID).Str("uploadID", info.ID).Msg("file upload failed chunk validation, but encountered an error setting the upload status to failure")
 		}
-		if err := DeleteAllChunksForFile(ctx, u.bulker, info.Source, info.DocID); err != nil {
+		if err := u.deleteAllChunks(ctx, info, chunks); err != nil {
 			zerolog.Ctx(ctx).Warn().Err(err).Str("fileID", info.DocID).Str("uploadID", info.ID).Msg("file upload failed chunk validation, but encountered an error deleting left-behind chunk data")
 		}
 		vSpan.End()
@@ -96,7 +97,8 @@ func (u *Uploader) allChunksPresent(info file.Info, chunks []file.ChunkInfo) boo
 	return true
 }
 
-func (u *Uploader) verifyChunkInfo(info file.Info, chunks []file.ChunkInfo, transitHash string) bool {
+// verifyChunkInfo returns an error describing why the chunks do not make up a valid file.
+func (u *Uploader) verifyChunkInfo(info file.Info, chunks []file.ChunkInfo, transitHash string) error {
 	log := zerolog.Ctx(context.TODO())
 	// verify all chunks except last are info.ChunkSize size
 	// verify last: false (or field excluded) for all except final chunk
@@ -105,32 +107,40 @@ func (u *Uploader) verifyChunkInfo(info file.Info, chunks []file.ChunkInfo, tran
 
 	hasher := sha256.New()
 
+	// sealed contents of encrypted uploads are longer than the chunk contents that were sent
+	overhead, ok := u.chunkOverhead(info)
+	if !ok {
+		log.Warn().Msg("upload is encrypted but no encryption key is configured")
+		return ErrEncryptionKeyMissing
+	}
+
 	for i, chunk := range chunks {
+		chunk.Size -= overhead
 		if i < info.Count-1 {
 			// all chunks except last must have last:false
 			// and be PRECISELY info.ChunkSize bytes long
 			if chunk.Last {
 				log.Debug().Int("chunkID", i).Msg("non-final chunk was incorrectly marked last")
-				return false
+				return fmt.Errorf("non-final chunk %d was marked last", i)
 			}
 			if chunk.Size != int(info.ChunkSize) {
 				log.Debug().Int64("requiredSize", info.ChunkSize).Int("chunkID", i).Int("gotSize", chunk.Size).Msg("chunk was undersized")
-				return false
+				return fmt.Errorf("chunk %d is %d bytes, expected %d", i, chunk.Size, info.ChunkSize)
 			}
 		} else {
 			// last chunk must be marked last:true
 			// and can be any valid size (0,ChunkSize]
 			if !chunk.Last {
 				log.Debug().Int("chunkID", i).Msg("final chunk was not marked as final")
-				return false
+				return fmt.Errorf("final chunk %d was not marked last", i)
 			}
-			if chunk.Size == 0 {
+			if chunk.Size <= 0 {
 				log.Debug().Int("chunkID", i).Msg("final chunk was 0 size")
-				return false
+				return fmt.Errorf("final chunk %d is empty", i)
 			}
 			if chunk.Size > int(info.ChunkSize) {
 				log.Debug().Int("chunk-size", chunk.Size).Int("maxsize", int(info.ChunkSize)).Msg("final chunk was oversized")
-				return false
+				return fmt.Errorf("final chunk %d is %d bytes, larger than the chunk size %d", i, chunk.Size, info.ChunkSize)
 			}
 		}
 
@@ -139,22 +149,34 @@ func (u *Uploader) verifyChunkInfo(info file.Info, chunks []file.ChunkInfo, tran
 		rawHash, err := hex.DecodeString(chunk.SHA2)
 		if err != nil {
 			log.Warn().Err(err).Msg("error decoding chunk hash")
-			return false
+			return fmt.Errorf("chunk %d has an invalid hash", i)
 		}
 		if n, err := hasher.Write(rawHash); err != nil {
 			log.Error().Err(err).Msg("error computing transitHash from component chunk hashes")
-			return false
+			return fmt.Errorf("unable to compute transithash: %w", err)
 		} else if n != len(rawHash) {
 			log.Error().Int("wrote", n).Int("expected", len(rawHash)).Msg("transitHash calculation failure, could not write to hasher")
-			return false
+			return errors.New("unable to compute transithash")
 		}
 	}
 
 	calcHash := hex.EncodeToString(hasher.Sum(nil))
 	if !strings.EqualFold(transitHash, calcHash) {
 		log.Warn().Str("provided-hash", transitHash).Str("calc-hash", calcHash).Msg("file upload streaming hash does not match")
-		return false
+		return errors.New("transithash does not match the chunk hashes")
 	}
 
-	return true
+	return nil
+}
+
+// chunkOverhead returns how many bytes longer the stored chunks of an upload are than the contents sent.
+// false is returned if the upload is encrypted but no key is configured.
+func (u *Uploader) chunkOverhead(info file.Info) (int, bool) {
+	if !info.Encrypted {
+		return 0, true
+	}
+	if u.cipher == nil {
+		return 0, false
+	}
+	return u.cipher.Overhead(), true
 }
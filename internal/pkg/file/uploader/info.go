@@ -15,6 +15,11 @@ func SetStatus(ctx context.Context, bulker bulk.Bulk, info file.Info, status fil
 	return UpdateFileDoc(ctx, bulker, info.Source, info.DocID, status, "")
 }
 
+// SetFailed marks the upload as failed, reason is reported by the upload status endpoint.
+func SetFailed(ctx context.Context, bulker bulk.Bulk, info file.Info, reason string) error {
+	return FailFileDoc(ctx, bulker, info.Source, info.DocID, reason)
+}
+
 func MarkComplete(ctx context.Context, bulker bulk.Bulk, info file.Info, hash string) error {
 	return UpdateFileDoc(ctx, bulker, info.Source, info.DocID, file.StatusDone, hash)
 }
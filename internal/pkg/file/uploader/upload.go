@@ -5,18 +5,28 @@
 package uploader
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/cbor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/encryption"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog"
 	"go.elastic.co/apm/v2"
 )
 
@@ -28,13 +38,27 @@ var (
 	ErrUploadExpired    = errors.New("upload has expired")
 	ErrUploadStopped    = errors.New("upload has stopped")
 	ErrInvalidChunkNum  = errors.New("invalid chunk number")
+	ErrInvalidChunkHash = errors.New("chunk hash must be a hex encoded SHA256")
 
 	ErrPayloadRequired  = errors.New("upload start payload required")
 	ErrFileSizeRequired = errors.New("file.size is required")
 	ErrInvalidFileSize  = errors.New("invalid filesize")
 	ErrFieldRequired    = errors.New("field required")
+
+	ErrQuotaConcurrentUploads = errors.New("too many uploads in progress for this agent")
+	ErrQuotaUploadBytes       = errors.New("this upload exceeds the daily upload quota of the agent")
+
+	ErrEncryptionKeyMissing = errors.New("upload is encrypted but no encryption key is configured")
+	ErrUploadNotDone        = errors.New("upload has not been verified")
+
+	ErrChunkExists           = errors.New("chunk has already been received")
+	ErrChunkInProgress       = errors.New("chunk is already being uploaded")
+	ErrTooManyParallelChunks = errors.New("too many chunks of this upload are being uploaded at once")
 )
 
+// quotaWindow is the period MaxBytesPerDay applies to
+const quotaWindow = 24 * time.Hour
+
 type Uploader struct {
 	cache     cache.Cache // cache of file metadata doc info
 	sizeLimit int64
@@ -42,16 +66,72 @@ type Uploader struct {
 
 	chunkClient *elasticsearch.Client
 	bulker      bulk.Bulk
+	store       storage.Backend // holds chunk contents, chunks are written in full to elasticsearch if nil
+	quotas      config.UploadQuotas
+	cipher      *encryption.Cipher // seals chunk contents of new uploads if set
+	assembly    *assembly
+	chunkSize   int64 // the size of the chunks of new uploads
+}
+
+// Option configures optional behaviour of an Uploader.
+type Option func(*Uploader)
+
+// WithStorage keeps chunk contents in store instead of elasticsearch.
+func WithStorage(store storage.Backend) Option {
+	return func(u *Uploader) {
+		u.store = store
+	}
+}
+
+// WithQuotas limits the uploads each agent may start.
+func WithQuotas(quotas config.UploadQuotas) Option {
+	return func(u *Uploader) {
+		u.quotas = quotas
+	}
+}
+
+// WithMaxParallelChunks limits the chunks of an upload that may be written at once by this instance, 0 disables the limit.
+func WithMaxParallelChunks(n int) Option {
+	return func(u *Uploader) {
+		u.assembly.maxParallel = n
+	}
+}
+
+// WithChunkSize sets the size of the chunks the agents split new uploads into, file.MaxChunkSize when 0.
+func WithChunkSize(n int64) Option {
+	return func(u *Uploader) {
+		if n > 0 {
+			u.chunkSize = n
+		}
+	}
+}
+
+// WithEncryption encrypts the chunk contents of new uploads with c.
+func WithEncryption(c *encryption.Cipher) Option {
+	return func(u *Uploader) {
+		u.cipher = c
+	}
 }
 
-func New(chunkClient *elasticsearch.Client, bulker bulk.Bulk, cache cache.Cache, sizeLimit int64, timeLimit time.Duration) *Uploader {
-	return &Uploader{
+func New(chunkClient *elasticsearch.Client, bulker bulk.Bulk, cache cache.Cache, sizeLimit int64, timeLimit time.Duration, opts ...Option) *Uploader {
+	u := &Uploader{
 		chunkClient: chunkClient,
 		bulker:      bulker,
 		sizeLimit:   sizeLimit,
 		timeLimit:   timeLimit,
 		cache:       cache,
+		assembly:    newAssembly(0),
+		chunkSize:   file.MaxChunkSize,
+	}
+	for _, opt := range opts {
+		opt(u)
 	}
+	return u
+}
+
+// MaxParallelChunks returns the number of chunks of an upload that may be sent at once, 0 if there is no limit.
+func (u *Uploader) MaxParallelChunks() int {
+	return u.assembly.maxParallel
 }
 
 // Start an upload operation
@@ -78,17 +158,25 @@ func (u *Uploader) Begin(ctx context.Context, namespaces []string, data JSDict)
 		return file.Info{}, ErrFileSizeTooLarge
 	}
 
-	uid, err := uuid.NewV4()
+	// grab required fields that were checked already in validation step
+	agentID, _ := data.Str("agent_id")
+	actionID, _ := data.Str("action_id")
+	source, _ := data.Str("src")
+
+	if limit := u.quotas.MaxFileSize[source]; limit > 0 && size > limit {
+		vSpan.End()
+		return file.Info{}, ErrFileSizeTooLarge
+	}
 	vSpan.End()
+	if err := u.checkQuotas(ctx, agentID, size); err != nil {
+		return file.Info{}, err
+	}
+
+	uid, err := uuid.NewV4()
 	if err != nil {
 		return file.Info{}, fmt.Errorf("unable to generate upload operation ID: %w", err)
 	}
 	id := uid.String()
-
-	// grab required fields that were checked already in validation step
-	agentID, _ := data.Str("agent_id")
-	actionID, _ := data.Str("action_id")
-	source, _ := data.Str("src")
 	docID := fmt.Sprintf("%s.%s", actionID, agentID)
 
 	info := file.Info{
@@ -97,11 +185,12 @@ func (u *Uploader) Begin(ctx context.Context, namespaces []string, data JSDict)
 		AgentID:    agentID,
 		ActionID:   actionID,
 		Namespaces: namespaces,
-		ChunkSize:  file.MaxChunkSize,
+		ChunkSize:  u.chunkSize,
 		Source:     source,
 		Total:      size,
 		Status:     file.StatusAwaiting,
 		Start:      time.Now(),
+		Encrypted:  u.cipher != nil,
 	}
 	chunkCount := info.Total / info.ChunkSize
 	if info.Total%info.ChunkSize > 0 {
@@ -131,6 +220,11 @@ func (u *Uploader) Begin(ctx context.Context, namespaces []string, data JSDict)
 	if err := data.Put(info.Namespaces, "namespaces"); err != nil {
 		return file.Info{}, err
 	}
+	if info.Encrypted {
+		if err := data.Put(true, "encrypted"); err != nil {
+			return file.Info{}, err
+		}
+	}
 
 	/*
 		Write to storage
@@ -147,6 +241,32 @@ func (u *Uploader) Begin(ctx context.Context, namespaces []string, data JSDict)
 	return info, nil
 }
 
+// checkQuotas ensures an agent may start an upload of size bytes.
+func (u *Uploader) checkQuotas(ctx context.Context, agentID string, size int64) error {
+	span, ctx := apm.StartSpan(ctx, "checkQuotas", "validate")
+	defer span.End()
+	now := time.Now()
+	if u.quotas.MaxConcurrent > 0 {
+		active, err := CountActiveUploads(ctx, u.bulker, agentID, now.Add(-u.timeLimit))
+		if err != nil {
+			return fmt.Errorf("unable to check concurrent upload quota: %w", err)
+		}
+		if active >= u.quotas.MaxConcurrent {
+			return ErrQuotaConcurrentUploads
+		}
+	}
+	if u.quotas.MaxBytesPerDay > 0 {
+		uploaded, err := SumUploadedBytes(ctx, u.bulker, agentID, now.Add(-quotaWindow))
+		if err != nil {
+			return fmt.Errorf("unable to check daily upload quota: %w", err)
+		}
+		if uploaded+size > u.quotas.MaxBytesPerDay {
+			return ErrQuotaUploadBytes
+		}
+	}
+	return nil
+}
+
 func (u *Uploader) Chunk(ctx context.Context, uplID string, chunkNum int, chunkHash string) (file.Info, file.ChunkInfo, error) {
 	// find the upload, details, and status associated with the file upload
 	info, err := u.GetUploadInfo(ctx, uplID)
@@ -167,6 +287,9 @@ func (u *Uploader) Chunk(ctx context.Context, uplID string, chunkNum int, chunkH
 	if chunkNum < 0 || chunkNum >= info.Count {
 		return file.Info{}, file.ChunkInfo{}, ErrInvalidChunkNum
 	}
+	if !validChunkHash(chunkHash) {
+		return file.Info{}, file.ChunkInfo{}, ErrInvalidChunkHash
+	}
 
 	return info, file.ChunkInfo{
 		Pos:  chunkNum,
@@ -177,6 +300,274 @@ func (u *Uploader) Chunk(ctx context.Context, uplID string, chunkNum int, chunkH
 	}, nil
 }
 
+// WriteChunk stores the contents of a chunk read from r.
+// Chunks may be written in any order and in parallel, a chunk that was already received is rejected with ErrChunkExists.
+// With a storage backend the contents are written to the backend and only the chunk metadata is written to elasticsearch.
+// Contents of encrypted uploads are read in full and sealed before they are written.
+func (u *Uploader) WriteChunk(ctx context.Context, info file.Info, chunk file.ChunkInfo, r io.Reader) error {
+	if err := u.assembly.acquire(info.ID, chunk.Pos); err != nil {
+		return err
+	}
+	defer u.assembly.release(info.ID, chunk.Pos)
+
+	if err := u.writeChunk(ctx, info, chunk, r); err != nil {
+		return err
+	}
+
+	if info.Status == file.StatusAwaiting {
+		if err := MarkUploading(ctx, u.bulker, info); err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Str("fileID", info.DocID).Str("uploadID", info.ID).Msg("unable to mark upload as in progress")
+			return nil
+		}
+		info.Status = file.StatusProgress
+		u.cache.SetUpload(info.ID, info)
+	}
+	return nil
+}
+
+func (u *Uploader) writeChunk(ctx context.Context, info file.Info, chunk file.ChunkInfo, r io.Reader) error {
+	chunkSize := info.ChunkSize
+	if info.Encrypted {
+		sealed, err := u.sealChunk(ctx, info, chunk, r)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(sealed)
+		chunkSize += int64(u.cipher.Overhead())
+	}
+
+	if u.store == nil {
+		ce := cbor.NewChunkWriter(r, chunk.Last, chunk.BID, chunk.SHA2, chunkSize)
+		return IndexChunk(ctx, u.chunkClient, ce, info.Source, chunk.BID, chunk.Pos)
+	}
+
+	// the stored contents would be replaced before the chunk document is found to exist
+	exists, err := ChunkExists(ctx, u.bulker, info.Source, chunk.BID, chunk.Pos)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrChunkExists
+	}
+
+	span, sCtx := apm.StartSpan(ctx, "storeChunk", "write")
+	span.Context.SetLabel("storage", u.store.Type())
+	n, err := u.store.Put(sCtx, storage.ChunkKey(info.Source, chunk.BID, chunk.Pos), r, -1)
+	span.End()
+	if err != nil {
+		return err
+	}
+	return IndexChunkInfo(ctx, u.bulker, info.Source, chunk.BID, chunk.Pos, chunk.Last, chunk.SHA2, n)
+}
+
+func (u *Uploader) sealChunk(ctx context.Context, info file.Info, chunk file.ChunkInfo, r io.Reader) ([]byte, error) {
+	if u.cipher == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	span, _ := apm.StartSpan(ctx, "encryptChunk", "process")
+	defer span.End()
+	data, err := io.ReadAll(io.LimitReader(r, info.ChunkSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return u.cipher.Seal(data, chunkID(chunk.BID, chunk.Pos))
+}
+
+// ReadChunk returns the contents of a chunk written by WriteChunk, contents of encrypted uploads are decrypted.
+func (u *Uploader) ReadChunk(ctx context.Context, info file.Info, chunk file.ChunkInfo) ([]byte, error) {
+	span, ctx := apm.StartSpan(ctx, "readChunk", "process")
+	defer span.End()
+	var data []byte
+	if u.store == nil {
+		c, err := file.GetChunk(ctx, u.bulker, UploadDataIndexPattern, info.Source, chunk.BID, chunk.Pos)
+		if err != nil {
+			return nil, err
+		}
+		data = c.Data
+	} else {
+		rc, err := u.store.Get(ctx, storage.ChunkKey(info.Source, chunk.BID, chunk.Pos))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !info.Encrypted {
+		return data, nil
+	}
+	if u.cipher == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	return u.cipher.Open(data, chunkID(chunk.BID, chunk.Pos))
+}
+
+// Contents returns the info and the chunks, sorted by position, of a verified upload.
+// The info is always fetched from elasticsearch so the contents of an upload are only read once it is verified.
+// Encrypted uploads are refused when no key is configured, before anything is written by WriteContents.
+func (u *Uploader) Contents(ctx context.Context, uplID string) (file.Info, []file.ChunkInfo, error) {
+	span, ctx := apm.StartSpan(ctx, "uploadContents", "process")
+	defer span.End()
+	info, err := file.GetInfo(ctx, u.bulker, UploadHeaderIndexPattern, uplID)
+	if err != nil {
+		return file.Info{}, nil, err
+	}
+	if info.Status != file.StatusDone {
+		return info, nil, ErrUploadNotDone
+	}
+	if info.Encrypted && u.cipher == nil {
+		return info, nil, ErrEncryptionKeyMissing
+	}
+	chunks, err := file.GetChunkInfos(ctx, u.bulker, UploadDataIndexPattern, info.DocID, file.GetChunkInfoOpt{})
+	if err != nil {
+		return info, nil, err
+	}
+	if !u.allChunksPresent(info, chunks) {
+		return info, nil, ErrMissingChunks
+	}
+	return info, chunks, nil
+}
+
+// WriteContents writes the contents of the chunks of an upload to w in order, each chunk is read from the storage
+// it was written to.
+func (u *Uploader) WriteContents(ctx context.Context, w io.Writer, info file.Info, chunks []file.ChunkInfo) error {
+	for _, chunk := range chunks {
+		data, err := u.ReadChunk(ctx, info, chunk)
+		if err != nil {
+			return fmt.Errorf("unable to read chunk %d: %w", chunk.Pos, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkID is the document ID of a chunk, sealed contents are bound to it.
+func chunkID(baseID string, chunkNum int) string {
+	return fmt.Sprintf("%s.%d", baseID, chunkNum)
+}
+
+// DeleteChunk removes a chunk written by WriteChunk.
+func (u *Uploader) DeleteChunk(ctx context.Context, info file.Info, chunk file.ChunkInfo) error {
+	if err := DeleteChunk(ctx, u.bulker, info.Source, chunk.BID, chunk.Pos); err != nil {
+		return err
+	}
+	if u.store == nil {
+		return nil
+	}
+	return u.store.Delete(ctx, storage.ChunkKey(info.Source, chunk.BID, chunk.Pos))
+}
+
+// deleteAllChunks removes the metadata and contents of all chunks of a file.
+func (u *Uploader) deleteAllChunks(ctx context.Context, info file.Info, chunks []file.ChunkInfo) error {
+	return deleteAllChunks(ctx, u.bulker, u.store, info, chunks)
+}
+
+// The stored contents are removed first, the chunk documents are needed to find them again if that fails.
+func deleteAllChunks(ctx context.Context, bulker bulk.Bulk, store storage.Backend, info file.Info, chunks []file.ChunkInfo) error {
+	if store != nil {
+		var errs []error
+		for _, chunk := range chunks {
+			errs = append(errs, store.Delete(ctx, storage.ChunkKey(info.Source, chunk.BID, chunk.Pos)))
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	return DeleteAllChunksForFile(ctx, bulker, info.Source, info.DocID)
+}
+
+// Purge removes an upload entirely, its chunks, their contents in store and the upload metadata.
+// The metadata is removed last so a failed purge is retried on the next attempt.
+func Purge(ctx context.Context, bulker bulk.Bulk, store storage.Backend, info file.Info) error {
+	span, ctx := apm.StartSpan(ctx, "purgeUpload", "process")
+	defer span.End()
+	var chunks []file.ChunkInfo
+	if store != nil {
+		var err error
+		chunks, err = file.GetChunkInfos(ctx, bulker, UploadDataIndexPattern, info.DocID, file.GetChunkInfoOpt{})
+		if err != nil {
+			return err
+		}
+	}
+	if err := deleteAllChunks(ctx, bulker, store, info, chunks); err != nil {
+		return err
+	}
+	return DeleteFileDoc(ctx, bulker, info.Source, info.DocID)
+}
+
+// Progress returns the upload info along with the chunks received so far, sorted by position.
+// The info is always fetched from elasticsearch so the reported status is current.
+// Chunk sizes are those of the contents that were sent, before any encryption.
+func (u *Uploader) Progress(ctx context.Context, uplID string) (file.Info, []file.ChunkInfo, error) {
+	span, ctx := apm.StartSpan(ctx, "uploadProgress", "process")
+	defer span.End()
+	info, err := file.GetInfo(ctx, u.bulker, UploadHeaderIndexPattern, uplID)
+	if err != nil {
+		return file.Info{}, nil, err
+	}
+
+	chunks, err := file.GetChunkInfos(ctx, u.bulker, UploadDataIndexPattern, info.DocID, file.GetChunkInfoOpt{IncludeSize: true})
+	if err != nil {
+		return file.Info{}, nil, err
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Pos < chunks[j].Pos
+	})
+	if overhead, ok := u.chunkOverhead(info); ok && overhead > 0 {
+		for i := range chunks {
+			chunks[i].Size -= overhead
+		}
+	}
+	return info, chunks, nil
+}
+
+// ReceivedBytes returns the total size of the chunks.
+func ReceivedBytes(chunks []file.ChunkInfo) int64 {
+	var n int64
+	for _, c := range chunks {
+		n += int64(c.Size)
+	}
+	return n
+}
+
+// OutstandingChunks returns the positions of the chunks of the upload that have not been received.
+// chunks must be sorted by position.
+func OutstandingChunks(info file.Info, chunks []file.ChunkInfo) []int {
+	missing := []int{}
+	next := 0
+	for _, c := range chunks {
+		for ; next < c.Pos && next < info.Count; next++ {
+			missing = append(missing, next)
+		}
+		next = c.Pos + 1
+	}
+	for ; next < info.Count; next++ {
+		missing = append(missing, next)
+	}
+	return missing
+}
+
+// NextChunk returns the position of the first chunk that has not been received.
+// chunks must be sorted by position, the number of chunks is returned if none are missing.
+func NextChunk(chunks []file.ChunkInfo) int {
+	for i, c := range chunks {
+		if c.Pos != i {
+			return i
+		}
+	}
+	return len(chunks)
+}
+
+// validChunkHash returns true if hash is a hex encoded SHA256 digest.
+func validChunkHash(hash string) bool {
+	b, err := hex.DecodeString(hash)
+	return err == nil && len(b) == sha256.Size
+}
+
 func validateUploadPayload(info JSDict) error {
 
 	required := [][]string{
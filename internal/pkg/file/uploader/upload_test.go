@@ -6,8 +6,13 @@
 package uploader
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -16,6 +21,8 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
 	"github.com/elastic/fleet-server/v7/internal/pkg/file"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/encryption"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
 	itesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 
 	"github.com/stretchr/testify/assert"
@@ -57,6 +64,26 @@ func makeUploadRequestDict(input map[string]interface{}) JSDict {
 	return d
 }
 
+func TestUploadBeginChunkSize(t *testing.T) {
+	src := "mysource"
+	fakeBulk := itesting.NewMockBulk()
+	fakeBulk.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+
+	size := 20 * 1024 * 1024
+	u := New(nil, fakeBulk, c, int64(size), time.Hour, WithChunkSize(8*1024*1024))
+	info, err := u.Begin(context.Background(), []string{}, makeUploadRequestDict(map[string]interface{}{
+		"action_id": "abc",
+		"agent_id":  "XYZ",
+		"src":       src,
+		"file.size": size,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8*1024*1024), info.ChunkSize)
+	assert.Equal(t, 3, info.Count)
+}
+
 // Happy-path case, where everything expected is provided
 // tests to make sure the returned struct is correctly populated
 func TestUploadBeginReturnsCorrectInfo(t *testing.T) {
@@ -287,6 +314,7 @@ func mockUploadInfoResult(bulker *itesting.MockBulk, info file.Info) {
 		},
 		"upload_id":    info.ID,
 		"upload_start": info.Start.UnixMilli(),
+		"encrypted":    info.Encrypted,
 	})
 
 	bulker.On("Search",
@@ -306,6 +334,322 @@ func mockUploadInfoResult(bulker *itesting.MockBulk, info file.Info) {
 	}, nil).Once()
 }
 
+func TestUploadBeginQuotas(t *testing.T) {
+	isActiveQuery := mock.MatchedBy(func(q []byte) bool { return bytes.Contains(q, []byte("track_total_hits")) })
+	isBytesQuery := mock.MatchedBy(func(q []byte) bool { return bytes.Contains(q, []byte(`"sum"`)) })
+
+	tests := []struct {
+		Name     string
+		Quotas   config.UploadQuotas
+		Active   uint64
+		Uploaded float64
+		Err      error
+	}{
+		{"no quotas", config.UploadQuotas{}, 10, 1 << 30, nil},
+		{"under concurrent quota", config.UploadQuotas{MaxConcurrent: 2}, 1, 0, nil},
+		{"at concurrent quota", config.UploadQuotas{MaxConcurrent: 2}, 2, 0, ErrQuotaConcurrentUploads},
+		{"under daily quota", config.UploadQuotas{MaxBytesPerDay: 2048}, 0, 1024, nil},
+		{"over daily quota", config.UploadQuotas{MaxBytesPerDay: 2048}, 0, 1025, ErrQuotaUploadBytes},
+		{"under integration file size", config.UploadQuotas{MaxFileSize: map[string]int64{"agent": 1024}}, 0, 0, nil},
+		{"over integration file size", config.UploadQuotas{MaxFileSize: map[string]int64{"agent": 1023}}, 0, 0, ErrFileSizeTooLarge},
+		{"other integration file size", config.UploadQuotas{MaxFileSize: map[string]int64{"endpoint": 1}}, 0, 0, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			fakeBulk := itesting.NewMockBulk()
+			fakeBulk.On("Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			active := &es.ResultT{}
+			active.Total.Value = tc.Active
+			fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-meta-*", isActiveQuery, mock.Anything).Return(active, nil)
+			fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-meta-*", isBytesQuery, mock.Anything).Return(&es.ResultT{
+				Aggregations: map[string]es.Aggregation{"bytes": {Value: tc.Uploaded}},
+			}, nil)
+
+			c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+			require.NoError(t, err)
+			u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithQuotas(tc.Quotas))
+
+			_, err = u.Begin(context.Background(), []string{}, makeUploadRequestDict(nil))
+			if tc.Err != nil {
+				assert.ErrorIs(t, err, tc.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.Quotas.MaxConcurrent == 0 {
+				fakeBulk.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, isActiveQuery, mock.Anything)
+			}
+			if tc.Quotas.MaxBytesPerDay == 0 {
+				fakeBulk.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, isBytesQuery, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestWriteChunkToStorage(t *testing.T) {
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+
+	fakeBulk := itesting.NewMockBulk()
+	fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-endpoint", mock.Anything, mock.Anything).Return(&es.ResultT{}, nil).Once()
+	fakeBulk.On("Create",
+		mock.Anything,
+		".fleet-fileds-fromhost-data-endpoint",
+		"action.agent.0",
+		mock.MatchedBy(func(doc []byte) bool {
+			var chunk map[string]interface{}
+			if err := json.Unmarshal(doc, &chunk); err != nil {
+				return false
+			}
+			_, hasData := chunk["data"]
+			return !hasData && chunk[file.FieldSize] == float64(9) && chunk[file.FieldSHA2] == chunkHash && chunk[file.FieldLast] == true
+		}),
+		mock.Anything,
+	).Return("", nil).Once()
+
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store))
+
+	info := file.Info{Source: "endpoint", DocID: "action.agent", ChunkSize: file.MaxChunkSize}
+	chunk := file.ChunkInfo{Pos: 0, BID: "action.agent", Last: true, SHA2: chunkHash}
+	require.NoError(t, u.WriteChunk(context.Background(), info, chunk, strings.NewReader("chunkdata")))
+	fakeBulk.AssertExpectations(t)
+
+	r, err := store.Get(context.Background(), storage.ChunkKey("endpoint", "action.agent", 0))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "chunkdata", string(data))
+}
+
+func TestWriteChunkEncrypted(t *testing.T) {
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	require.NoError(t, err)
+
+	fakeBulk := itesting.NewMockBulk()
+	fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-endpoint", mock.Anything, mock.Anything).Return(&es.ResultT{}, nil).Once()
+	fakeBulk.On("Create",
+		mock.Anything,
+		".fleet-fileds-fromhost-data-endpoint",
+		"action.agent.0",
+		mock.MatchedBy(func(doc []byte) bool {
+			var chunk map[string]interface{}
+			if err := json.Unmarshal(doc, &chunk); err != nil {
+				return false
+			}
+			return chunk[file.FieldSize] == float64(9+cipher.Overhead())
+		}),
+		mock.Anything,
+	).Return("", nil).Once()
+
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store), WithEncryption(cipher))
+
+	info := file.Info{Source: "endpoint", DocID: "action.agent", ChunkSize: file.MaxChunkSize, Encrypted: true}
+	chunk := file.ChunkInfo{Pos: 0, BID: "action.agent", Last: true, SHA2: chunkHash}
+	require.NoError(t, u.WriteChunk(context.Background(), info, chunk, strings.NewReader("chunkdata")))
+	fakeBulk.AssertExpectations(t)
+
+	r, err := store.Get(context.Background(), storage.ChunkKey("endpoint", "action.agent", 0))
+	require.NoError(t, err)
+	stored, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.NotContains(t, string(stored), "chunkdata")
+
+	data, err := u.ReadChunk(context.Background(), info, chunk)
+	require.NoError(t, err)
+	assert.Equal(t, "chunkdata", string(data))
+
+	// sealed chunk sizes are corrected for when the upload is verified
+	chunk.Size = len(stored)
+	info.Count = 1
+	rawHash, err := hex.DecodeString(chunkHash)
+	require.NoError(t, err)
+	transitHash := sha256.Sum256(rawHash)
+	assert.NoError(t, u.verifyChunkInfo(info, []file.ChunkInfo{chunk}, hex.EncodeToString(transitHash[:])))
+
+	// without the key the upload can not be read back
+	u = New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store))
+	_, err = u.ReadChunk(context.Background(), info, chunk)
+	assert.ErrorIs(t, err, ErrEncryptionKeyMissing)
+}
+
+func TestUploadContentsFromStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	for i, data := range []string{"chunk0", "chunk1"} {
+		_, err := store.Put(ctx, storage.ChunkKey("endpoint", "action.agent", i), strings.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+	}
+	chunkHit := func(pos int) es.HitT {
+		return es.HitT{
+			ID: fmt.Sprintf("action.agent.%d", pos),
+			Fields: map[string]interface{}{
+				file.FieldBaseID: []interface{}{"action.agent"},
+				file.FieldSHA2:   []interface{}{chunkHash},
+				file.FieldLast:   []interface{}{pos == 1},
+			},
+		}
+	}
+
+	info := file.Info{ID: "upload", DocID: "action.agent", Source: "endpoint", ChunkSize: 6, Total: 12, Status: file.StatusDone}
+	fakeBulk := itesting.NewMockBulk()
+	mockUploadInfoResult(fakeBulk, info)
+	fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-*", mock.Anything, mock.Anything).Return(&es.ResultT{
+		HitsT: es.HitsT{Hits: []es.HitT{chunkHit(1), chunkHit(0)}},
+	}, nil).Once()
+
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store))
+
+	info, chunks, err := u.Contents(ctx, "upload")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, u.WriteContents(ctx, &buf, info, chunks))
+	assert.Equal(t, "chunk0chunk1", buf.String(), "the chunks are read from the storage in order")
+
+	info.Status = file.StatusProgress
+	mockUploadInfoResult(fakeBulk, info)
+	_, _, err = u.Contents(ctx, "upload")
+	assert.ErrorIs(t, err, ErrUploadNotDone)
+	fakeBulk.AssertExpectations(t)
+}
+
+func TestUploadContentsEncrypted(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	cipher, err := encryption.NewCipher(make([]byte, 32))
+	require.NoError(t, err)
+	for i, data := range []string{"chunk0", "chunk1"} {
+		sealed, err := cipher.Seal([]byte(data), chunkID("action.agent", i))
+		require.NoError(t, err)
+		_, err = store.Put(ctx, storage.ChunkKey("endpoint", "action.agent", i), bytes.NewReader(sealed), int64(len(sealed)))
+		require.NoError(t, err)
+	}
+	hits := make([]es.HitT, 0, 2)
+	for i := 0; i < 2; i++ {
+		hits = append(hits, es.HitT{
+			ID: fmt.Sprintf("action.agent.%d", i),
+			Fields: map[string]interface{}{
+				file.FieldBaseID: []interface{}{"action.agent"},
+				file.FieldSHA2:   []interface{}{chunkHash},
+				file.FieldLast:   []interface{}{i == 1},
+			},
+		})
+	}
+
+	info := file.Info{ID: "upload", DocID: "action.agent", Source: "endpoint", ChunkSize: 6, Total: 12, Status: file.StatusDone, Encrypted: true}
+	fakeBulk := itesting.NewMockBulk()
+	mockUploadInfoResult(fakeBulk, info)
+	fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-*", mock.Anything, mock.Anything).Return(&es.ResultT{
+		HitsT: es.HitsT{Hits: hits},
+	}, nil).Once()
+
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store), WithEncryption(cipher))
+
+	info, chunks, err := u.Contents(ctx, "upload")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, u.WriteContents(ctx, &buf, info, chunks))
+	assert.Equal(t, "chunk0chunk1", buf.String(), "the contents are decrypted when read back")
+
+	// without the key the contents are not read
+	u = New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store))
+	mockUploadInfoResult(fakeBulk, info)
+	_, _, err = u.Contents(ctx, "upload")
+	assert.ErrorIs(t, err, ErrEncryptionKeyMissing)
+	buf.Reset()
+	assert.ErrorIs(t, u.WriteContents(ctx, &buf, info, chunks), ErrEncryptionKeyMissing)
+	assert.Zero(t, buf.Len())
+	fakeBulk.AssertExpectations(t)
+}
+
+func TestWriteChunkRejectsReceivedChunk(t *testing.T) {
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+
+	fakeBulk := itesting.NewMockBulk()
+	fakeBulk.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-endpoint", mock.Anything, mock.Anything).Return(&es.ResultT{
+		HitsT: es.HitsT{Hits: []es.HitT{{ID: "action.agent.0"}}},
+	}, nil).Once()
+
+	c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+	require.NoError(t, err)
+	u := New(nil, fakeBulk, c, 8388608000, time.Hour, WithStorage(store), WithMaxParallelChunks(1))
+
+	info := file.Info{ID: "upload", Source: "endpoint", DocID: "action.agent", ChunkSize: file.MaxChunkSize}
+	chunk := file.ChunkInfo{Pos: 0, BID: "action.agent", SHA2: chunkHash}
+	err = u.WriteChunk(context.Background(), info, chunk, strings.NewReader("chunkdata"))
+	assert.ErrorIs(t, err, ErrChunkExists)
+	fakeBulk.AssertExpectations(t)
+
+	// nothing is written to the store for a chunk that was already received
+	_, err = store.Get(context.Background(), storage.ChunkKey("endpoint", "action.agent", 0))
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// chunks being written by another request are rejected before anything is written
+	require.NoError(t, u.assembly.acquire("upload", 1))
+	err = u.WriteChunk(context.Background(), info, file.ChunkInfo{Pos: 1, BID: "action.agent", SHA2: chunkHash}, strings.NewReader("chunkdata"))
+	assert.ErrorIs(t, err, ErrChunkInProgress)
+	err = u.WriteChunk(context.Background(), info, file.ChunkInfo{Pos: 2, BID: "action.agent", SHA2: chunkHash}, strings.NewReader("chunkdata"))
+	assert.ErrorIs(t, err, ErrTooManyParallelChunks)
+}
+
+func TestChunkRejectsInvalidHash(t *testing.T) {
+	tests := []struct {
+		Name string
+		Hash string
+		Err  error
+	}{
+		{"empty hash", "", ErrInvalidChunkHash},
+		{"short hash", "abc", ErrInvalidChunkHash},
+		{"non-hex hash", strings.Repeat("z", 64), ErrInvalidChunkHash},
+		{"long hash", chunkHash + "00", ErrInvalidChunkHash},
+		{"valid hash", chunkHash, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			fakeBulk := itesting.NewMockBulk()
+			c, err := cache.New(config.Cache{NumCounters: 100, MaxCost: 100000})
+			require.NoError(t, err)
+
+			info := file.Info{
+				ID:        "abc",
+				DocID:     "abc",
+				ChunkSize: file.MaxChunkSize,
+				Total:     10,
+				Count:     1,
+				Start:     time.Now(),
+				Status:    file.StatusAwaiting,
+			}
+			mockUploadInfoResult(fakeBulk, info)
+			u := New(nil, fakeBulk, c, 8388608000, time.Hour)
+
+			_, _, err = u.Chunk(context.Background(), info.ID, 0, tc.Hash)
+			if tc.Err != nil {
+				assert.ErrorIs(t, err, tc.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// chunkHash is a well-formed SHA256 hex digest used where chunk contents are not inspected
+const chunkHash = "0c4a81b85a6b7ff00bde6c32e1e8be33b4b793b3b7b5cb03db93f77f7c9374d1"
+
 func TestChunkMarksFinal(t *testing.T) {
 	tests := []struct {
 		FileSize   int64
@@ -349,7 +693,7 @@ func TestChunkMarksFinal(t *testing.T) {
 			// for anything larger than 1-chunk, check for off-by-ones
 			if tc.FinalChunk > 0 {
 				mockUploadInfoResult(fakeBulk, info)
-				_, prev, err := u.Chunk(context.Background(), info.ID, tc.FinalChunk-1, "")
+				_, prev, err := u.Chunk(context.Background(), info.ID, tc.FinalChunk-1, chunkHash)
 				assert.NoError(t, err)
 				assert.Falsef(t, prev.Last, "penultimate chunk number (%d) should not be marked final", tc.FinalChunk-1)
 			}
@@ -357,7 +701,7 @@ func TestChunkMarksFinal(t *testing.T) {
 			mockUploadInfoResult(fakeBulk, info)
 
 			// make sure the final chunk is marked as such
-			_, chunk, err := u.Chunk(context.Background(), info.ID, tc.FinalChunk, "")
+			_, chunk, err := u.Chunk(context.Background(), info.ID, tc.FinalChunk, chunkHash)
 			assert.NoError(t, err)
 			assert.Truef(t, chunk.Last, "chunk number %d should be marked as Last", tc.FinalChunk)
 		})
@@ -0,0 +1,242 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package fips restricts the cryptography of fleet-server to the algorithms approved by FIPS 140. The FIPS mode is
+// enabled with server.fips.enabled, the builds with the requirefips tag always run in the FIPS mode.
+//
+// The hashes and the ciphers fleet-server computes itself are approved in any mode: the checksums of the uploads and
+// the hashes of the audit log are HMAC-SHA-256 and the uploads are encrypted with AES-GCM.
+// The FIPS mode restricts the TLS connections of the server and of its clients and the encryption of the keys.
+package fips
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// ErrNotApproved is returned for the settings that are not approved in the FIPS mode.
+var ErrNotApproved = errors.New("not approved in FIPS mode")
+
+const (
+	// minSaltSize and minIterations are the minimums of SP 800-132 for the PBKDF2 of the keys.
+	minSaltSize   = 16
+	minIterations = 1000
+)
+
+var (
+	// cipherSuites are the approved cipher suites of TLS 1.2, the ones of Go with an ECDHE or an RSA key exchange
+	// and AES-GCM.
+	cipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	}
+	// curves are the approved curves of the key exchanges, X25519 is not approved.
+	curves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	// approvedPRFs are HMAC-SHA-224, HMAC-SHA-256, HMAC-SHA-384 and HMAC-SHA-512.
+	approvedPRFs = []asn1.ObjectIdentifier{
+		{1, 2, 840, 113549, 2, 8},
+		{1, 2, 840, 113549, 2, 9},
+		{1, 2, 840, 113549, 2, 10},
+		{1, 2, 840, 113549, 2, 11},
+	}
+	// approvedCiphers are AES-128-CBC, AES-192-CBC and AES-256-CBC.
+	approvedCiphers = []asn1.ObjectIdentifier{
+		{2, 16, 840, 1, 101, 3, 4, 1, 2},
+		{2, 16, 840, 1, 101, 3, 4, 1, 22},
+		{2, 16, 840, 1, 101, 3, 4, 1, 42},
+	}
+)
+
+var enabled atomic.Bool
+
+// Enabled returns true when the FIPS mode is enabled by the configuration or required by the build.
+func Enabled() bool {
+	return Required || enabled.Load()
+}
+
+// SetEnabled enables or disables the FIPS mode, it can not be disabled in the builds that require it.
+func SetEnabled(b bool) {
+	enabled.Store(b)
+}
+
+// Restrict restricts c to TLS 1.2 and to the approved cipher suites and curves when the FIPS mode is enabled, a new
+// configuration is returned when c is nil. TLS 1.3 is not negotiated as its cipher suites can not be restricted.
+// c is returned as is when the FIPS mode is disabled.
+func Restrict(c *tls.Config) *tls.Config {
+	return RestrictMode(c, Enabled())
+}
+
+// RestrictMode restricts c like Restrict when fipsMode is true or the FIPS mode is required by the build. It is used by
+// the clients built while the configuration is read, before the FIPS mode of the configuration is set.
+func RestrictMode(c *tls.Config, fipsMode bool) *tls.Config {
+	if !fipsMode && !Required {
+		return c
+	}
+	if c == nil {
+		c = &tls.Config{} //nolint:gosec // the versions are set below
+	}
+	c.MinVersion = tls.VersionTLS12
+	c.MaxVersion = tls.VersionTLS12
+	c.CipherSuites = filter(c.CipherSuites, cipherSuites)
+	c.CurvePreferences = filter(c.CurvePreferences, curves)
+	return c
+}
+
+// filter returns the approved values of values, all the approved values when values is empty.
+func filter[T comparable](values, approved []T) []T {
+	if len(values) == 0 {
+		return append([]T(nil), approved...)
+	}
+	kept := make([]T, 0, len(values))
+	for _, v := range values {
+		if contains(approved, v) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, a := range values {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVersion returns an error when the TLS version is not approved in the FIPS mode.
+func CheckVersion(v uint16) error {
+	if v != tls.VersionTLS12 {
+		return fmt.Errorf("%w: %s, only TLS 1.2 is supported", ErrNotApproved, tls.VersionName(v))
+	}
+	return nil
+}
+
+// CheckCipherSuite returns an error when the cipher suite is not approved in the FIPS mode.
+func CheckCipherSuite(id uint16) error {
+	if !contains(cipherSuites, id) {
+		return fmt.Errorf("%w: cipher suite %s", ErrNotApproved, tls.CipherSuiteName(id))
+	}
+	return nil
+}
+
+// CheckCurve returns an error when the curve is not approved in the FIPS mode.
+func CheckCurve(id tls.CurveID) error {
+	if !contains(curves, id) {
+		return fmt.Errorf("%w: curve %s", ErrNotApproved, id)
+	}
+	return nil
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// CheckKey returns an error when the private key, a PEM string or the path of a PEM file, is encrypted with an
+// algorithm that is not approved in the FIPS mode. The legacy PEM encryption derives its key with MD5 and is
+// rejected, the encrypted PKCS #8 keys must use PBES2 with PBKDF2 and HMAC-SHA-2 and AES-CBC.
+func CheckKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	r, err := tlscommon.NewPEMReader(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			return nil
+		}
+		if x509.IsEncryptedPEMBlock(block) { //nolint:staticcheck // only detects the legacy encryption
+			return fmt.Errorf("%w: the legacy PEM encryption of the key, use an encrypted PKCS #8 key", ErrNotApproved)
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			if err := checkPKCS8(block.Bytes); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkPKCS8 checks the encryption of an encrypted PKCS #8 key.
+func checkPKCS8(der []byte) error {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return fmt.Errorf("unable to parse the encrypted PKCS #8 key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return fmt.Errorf("%w: the encryption %s of the PKCS #8 key, use PBES2", ErrNotApproved, info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return fmt.Errorf("unable to parse the PBES2 parameters of the PKCS #8 key: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return fmt.Errorf("%w: the key derivation %s of the PKCS #8 key, use PBKDF2", ErrNotApproved, params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return fmt.Errorf("unable to parse the PBKDF2 parameters of the PKCS #8 key: %w", err)
+	}
+	if !containsOID(approvedPRFs, kdf.PRF.Algorithm) {
+		prf := kdf.PRF.Algorithm.String()
+		if len(kdf.PRF.Algorithm) == 0 {
+			prf = "HMAC-SHA-1" // the default PRF
+		}
+		return fmt.Errorf("%w: the PRF %s of the PBKDF2 of the PKCS #8 key, use HMAC-SHA-256", ErrNotApproved, prf)
+	}
+	if len(kdf.Salt) < minSaltSize || kdf.IterationCount < minIterations {
+		return fmt.Errorf("%w: the PBKDF2 of the PKCS #8 key needs a salt of %d bytes and %d iterations", ErrNotApproved, minSaltSize, minIterations)
+	}
+	if !containsOID(approvedCiphers, params.EncryptionScheme.Algorithm) {
+		return fmt.Errorf("%w: the cipher %s of the PKCS #8 key, use AES-CBC", ErrNotApproved, params.EncryptionScheme.Algorithm)
+	}
+	return nil
+}
+
+func containsOID(oids []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) bool {
+	for _, o := range oids {
+		if o.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration && !requirefips
+
+package fips
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+func TestRestrict(t *testing.T) {
+	t.Cleanup(func() {
+		SetEnabled(false)
+	})
+
+	c := &tls.Config{} //nolint:gosec // test config
+	assert.Same(t, c, Restrict(c))
+	assert.Nil(t, Restrict(nil), "nothing is restricted when the FIPS mode is disabled")
+	assert.Empty(t, c.CipherSuites)
+
+	SetEnabled(true)
+	assert.True(t, Enabled())
+	c = Restrict(nil)
+	require.NotNil(t, c)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.MaxVersion)
+	assert.Equal(t, cipherSuites, c.CipherSuites)
+	assert.Equal(t, curves, c.CurvePreferences)
+
+	c = Restrict(&tls.Config{ //nolint:gosec // test config
+		MinVersion:       tls.VersionTLS10,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP384},
+	})
+	assert.Equal(t, uint16(tls.VersionTLS12), c.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, c.CipherSuites)
+	assert.Equal(t, []tls.CurveID{tls.CurveP384}, c.CurvePreferences)
+
+	// the mode of the configuration is used before it is set
+	SetEnabled(false)
+	c = RestrictMode(nil, true)
+	require.NotNil(t, c)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.MaxVersion)
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, CheckVersion(tls.VersionTLS12))
+	assert.ErrorIs(t, CheckVersion(tls.VersionTLS11), ErrNotApproved)
+	assert.ErrorIs(t, CheckVersion(tls.VersionTLS13), ErrNotApproved)
+
+	assert.NoError(t, CheckCipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256))
+	assert.ErrorIs(t, CheckCipherSuite(tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256), ErrNotApproved)
+	assert.ErrorIs(t, CheckCipherSuite(tls.TLS_RSA_WITH_AES_128_CBC_SHA), ErrNotApproved)
+
+	assert.NoError(t, CheckCurve(tls.CurveP256))
+	assert.ErrorIs(t, CheckCurve(tls.X25519), ErrNotApproved)
+}
+
+// encryptedPKCS8 returns the PEM of an encrypted PKCS #8 key with the parameters, the data is not a key as only the
+// parameters are checked.
+func encryptedPKCS8(t *testing.T, prf asn1.ObjectIdentifier, saltSize, iterations int, cipher asn1.ObjectIdentifier) string {
+	t.Helper()
+	marshal := func(v interface{}) asn1.RawValue {
+		b, err := asn1.Marshal(v)
+		require.NoError(t, err)
+		return asn1.RawValue{FullBytes: b}
+	}
+	kdf := pbkdf2Params{Salt: make([]byte, saltSize), IterationCount: iterations}
+	if prf != nil {
+		kdf.PRF = pkix.AlgorithmIdentifier{Algorithm: prf, Parameters: asn1.NullRawValue}
+	}
+	iv := make([]byte, 16)
+	_, err := rand.Read(iv)
+	require.NoError(t, err)
+	info := encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm: oidPBES2,
+			Parameters: marshal(pbes2Params{
+				KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: marshal(kdf)},
+				EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: cipher, Parameters: marshal(iv)},
+			}),
+		},
+		EncryptedData: []byte("encrypted"),
+	}
+	der, err := asn1.Marshal(info)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}))
+}
+
+func TestCheckKey(t *testing.T) {
+	assert.NoError(t, CheckKey(""))
+	assert.NoError(t, CheckKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))))
+	assert.NoError(t, CheckKey(encryptedPKCS8(t, oidHMACSHA256, 16, 10000, oidAES256CBC)))
+
+	//nolint:staticcheck // the legacy encryption is rejected
+	legacy, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", []byte("key"), []byte("passphrase"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	assert.ErrorIs(t, CheckKey(string(pem.EncodeToMemory(legacy))), ErrNotApproved)
+
+	tests := map[string]string{
+		"default PRF":    encryptedPKCS8(t, nil, 16, 10000, oidAES256CBC),
+		"SHA-1 PRF":      encryptedPKCS8(t, oidHMACSHA1, 16, 10000, oidAES256CBC),
+		"short salt":     encryptedPKCS8(t, oidHMACSHA256, 8, 10000, oidAES256CBC),
+		"few iterations": encryptedPKCS8(t, oidHMACSHA256, 16, 100, oidAES256CBC),
+		"3DES":           encryptedPKCS8(t, oidHMACSHA256, 16, 10000, oidDESEDE3CBC),
+	}
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, CheckKey(key), ErrNotApproved)
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		require.NoError(t, os.WriteFile(path, []byte(tests["3DES"]), 0o600))
+		assert.ErrorIs(t, CheckKey(path), ErrNotApproved)
+	})
+}
@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build requirefips
+
+package fips
+
+// fipsonly restricts crypto/tls to the approved settings in the whole process, it requires a build with
+// GOEXPERIMENT=boringcrypto so the approved algorithms come from the validated module.
+import _ "crypto/tls/fipsonly"
+
+// Required is true in the builds that require the FIPS mode.
+const Required = true
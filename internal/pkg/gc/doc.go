@@ -2,5 +2,5 @@
 // or more contributor license agreements. Licensed under the Elastic License;
 // you may not use this file except in compliance with the Elastic License.
 
-// Package gc provides utilities to cleanup expired (elastic-agent) actions.
+// Package gc provides utilities to cleanup expired (elastic-agent) actions and stale file uploads.
 package gc
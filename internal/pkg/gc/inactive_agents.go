@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+)
+
+// unenrolledReasonTimeout is the unenrolled reason of the agents that did not check in before the unenroll timeout
+// of their policy.
+const unenrolledReasonTimeout = "timeout"
+
+func getInactiveAgentsGCFunc(bulker bulk.Bulk, cfg config.InactiveAgentsGC) scheduler.WorkFunc {
+	return func(ctx context.Context) error {
+		if !cfg.Enabled {
+			return nil
+		}
+		return unenrollInactiveAgents(ctx, bulker, cfg.BatchSize, time.Now())
+	}
+}
+
+// unenrollInactiveAgents unenrolls the agents of the policies with an unenroll timeout that did not check in
+// for longer than the timeout. The agents become tombstones once their API keys are invalidated.
+func unenrollInactiveAgents(ctx context.Context, bulker bulk.Bulk, batchSize int, now time.Time) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "inactive agents cleanup").Logger()
+
+	policies, err := dl.QueryLatestPolicies(ctx, bulker)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to query the policies")
+		return err
+	}
+
+	var errs []error
+	for _, policy := range policies {
+		if policy.UnenrollTimeout <= 0 {
+			continue
+		}
+		before := now.Add(-time.Duration(policy.UnenrollTimeout) * time.Second)
+		if err := unenrollInactivePolicyAgents(ctx, log, bulker, policy.PolicyID, before, batchSize); err != nil {
+			errs = append(errs, fmt.Errorf("policy %s: %w", policy.PolicyID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unenrollInactivePolicyAgents unenrolls the agents of the policy that did not check in since the passed time,
+// batchSize agents at a time. It stops at the first batch that can not be fully unenrolled to not search the same
+// agents again.
+func unenrollInactivePolicyAgents(ctx context.Context, log zerolog.Logger, bulker bulk.Bulk, policyID string, before time.Time, batchSize int) error {
+	log = log.With().Str(dl.FieldPolicyID, policyID).Time("before", before).Logger()
+	metrics := getInactiveAgentsMetrics()
+
+	for {
+		agents, err := dl.FindInactiveAgents(ctx, bulker, policyID, before, batchSize)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed to find inactive agents")
+			return err
+		}
+		if len(agents) == 0 {
+			return nil
+		}
+
+		unenrolled, err := unenrollAgents(ctx, log, bulker, agents)
+		addCount(metrics.Unenrolled, unenrolled)
+		addCount(metrics.Failed, len(agents)-unenrolled)
+		log.Debug().Int("count", unenrolled).Msg("unenrolled inactive agents")
+		if err != nil {
+			return err
+		}
+		if len(agents) < batchSize {
+			return nil
+		}
+	}
+}
+
+// unenrollAgents invalidates the API keys of the agents with a call per output and makes a tombstone of each agent
+// whose keys are all invalidated. It returns the number of agents unenrolled.
+func unenrollAgents(ctx context.Context, log zerolog.Logger, bulker bulk.Bulk, agents []model.Agent) (int, error) {
+	byOutput := make(map[string][]string)
+	outputsByAgent := make(map[string][]string, len(agents))
+	for i := range agents {
+		for _, key := range agents[i].APIKeyIDs() {
+			byOutput[key.Output] = append(byOutput[key.Output], key.ID)
+			outputsByAgent[agents[i].Id] = append(outputsByAgent[agents[i].Id], key.Output)
+		}
+	}
+
+	var errs []error
+	failedOutputs := make(map[string]bool)
+	for output, ids := range byOutput {
+		err := invalidator.InvalidateOutput(ctx, log, bulker, output, ids)
+		audit.APIKey(log, audit.APIKeyEvent{
+			Action: audit.ActionAPIKeyInvalidate,
+			Reason: audit.ReasonAgentInactive,
+			Output: output,
+			IDs:    ids,
+		}, err)
+		if err != nil {
+			failedOutputs[output] = true
+			errs = append(errs, fmt.Errorf("failed to invalidate the API keys of output %q: %w", output, err))
+		}
+	}
+
+	unenrolled := 0
+agents:
+	for i := range agents {
+		for _, output := range outputsByAgent[agents[i].Id] {
+			if failedOutputs[output] {
+				continue agents
+			}
+		}
+		if err := dl.TombstoneAgent(ctx, bulker, &agents[i], unenrolledReasonTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unenroll agent %s: %w", agents[i].Id, err))
+			continue
+		}
+		unenrolled++
+	}
+	return unenrolled, errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+type testCounter uint64
+
+func (c *testCounter) Add(delta uint64) {
+	*c += testCounter(delta)
+}
+
+func TestInactiveAgentsGC(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+
+	var unenrolled, failed testCounter
+	SetInactiveAgentsMetrics(InactiveAgentsMetrics{Unenrolled: &unenrolled, Failed: &failed})
+	t.Cleanup(func() { SetInactiveAgentsMetrics(InactiveAgentsMetrics{}) })
+
+	bulker := ftesting.NewMockBulk()
+	policyBucket := func(source string) es.Bucket {
+		return es.Bucket{Aggregations: map[string]es.HitsT{dl.FieldRevisionIdx: {Hits: []es.HitT{{Source: []byte(source)}}}}}
+	}
+	bulker.On("Search", mock.Anything, dl.FleetPolicies, mock.Anything, mock.Anything).Return(&es.ResultT{Aggregations: map[string]es.Aggregation{
+		dl.FieldPolicyID: {Buckets: []es.Bucket{
+			policyBucket(`{"policy_id":"policy-timeout","revision_idx":1,"unenroll_timeout":3600}`),
+			policyBucket(`{"policy_id":"policy-no-timeout","revision_idx":1}`),
+		}},
+	}}, nil).Once()
+	var query struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Size int `json:"size"`
+	}
+	bulker.On("Search", mock.Anything, dl.FleetAgents, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal(args.Get(2).([]byte), &query))
+	}).Return(&es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{
+		{ID: "agent-1", Source: []byte(`{"active":true,"agent":{"id":"agent-1"},"access_api_key_id":"ak1","outputs":{"default":{"api_key_id":"k1"},"remote":{"api_key_id":"rk1"}}}`)},
+		{ID: "agent-2", Source: []byte(`{"active":true,"agent":{"id":"agent-2"},"access_api_key_id":"ak2"}`)},
+	}}}, nil).Once()
+
+	remote := ftesting.NewMockBulk()
+	bulker.On("GetBulker", "remote").Return(remote)
+	remote.On("APIKeyInvalidate", mock.Anything, []string{"rk1"}).Return(errors.New("remote unavailable")).Once()
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"ak1", "k1", "ak2"}).Return(nil).Once()
+	var doc struct {
+		Doc map[string]interface{} `json:"doc"`
+	}
+	bulker.On("Update", mock.Anything, dl.FleetAgents, "agent-2", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal(args.Get(3).([]byte), &doc))
+	}).Return(nil).Once()
+
+	err := unenrollInactiveAgents(ctx, bulker, 2, now)
+	require.Error(t, err, "the agent with a key that can not be invalidated is not unenrolled")
+	bulker.AssertExpectations(t)
+	remote.AssertExpectations(t)
+
+	require.Len(t, query.Query.Bool.Filter, 3)
+	assert.Equal(t, "policy-timeout", query.Query.Bool.Filter[1]["term"]["policy_id"])
+	assert.Equal(t, map[string]interface{}{"lt": "2024-06-10T11:00:00Z"}, query.Query.Bool.Filter[2]["range"]["last_checkin"])
+	assert.Equal(t, 2, query.Size)
+	assert.Equal(t, false, doc.Doc[dl.FieldActive])
+	assert.Equal(t, "timeout", doc.Doc[dl.FieldUnenrolledReason])
+	assert.EqualValues(t, 1, unenrolled)
+	assert.EqualValues(t, 1, failed)
+}
+
+func TestInactiveAgentsGCDisabled(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	cfg := config.InactiveAgentsGC{}
+	cfg.InitDefaults()
+	require.NoError(t, getInactiveAgentsGCFunc(bulker, cfg)(context.Background()))
+	bulker.AssertNotCalled(t, "Search")
+}
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+)
+
+type dataStreamRetention struct {
+	name      string
+	retention time.Duration
+}
+
+func getLifecycleGCFunc(bulker bulk.Bulk, cfg config.LifecycleGC) scheduler.WorkFunc {
+	return func(ctx context.Context) error {
+		if !cfg.Enabled {
+			return nil
+		}
+		return putLifecycles(ctx, bulker, []dataStreamRetention{
+			{name: dl.FleetActionsResults, retention: cfg.ActionResultsRetention},
+			{name: dl.FleetFileDataStreams, retention: cfg.FilesRetention},
+			{name: dl.FleetOutputHealth, retention: cfg.OutputHealthRetention},
+		})
+	}
+}
+
+// putLifecycles sets the retention of the data streams, it runs on each schedule so the data streams created
+// since the last run get their lifecycle. A data stream that is not created yet is skipped.
+func putLifecycles(ctx context.Context, bulker bulk.Bulk, streams []dataStreamRetention) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "data stream lifecycles").Logger()
+
+	var errs []error
+	for _, s := range streams {
+		if s.retention <= 0 {
+			continue
+		}
+		err := dl.PutDataStreamLifecycle(ctx, bulker, s.name, s.retention)
+		if errors.Is(err, es.ErrIndexNotFound) {
+			log.Debug().Str("index", s.name).Msg("data stream not created yet")
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("index", s.name).Msg("failed to set the data stream lifecycle")
+			errs = append(errs, err)
+			continue
+		}
+		log.Debug().Str("index", s.name).Dur("retention", s.retention).Msg("data stream lifecycle set")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package gc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestLifecycleGC(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	bodies := map[string]string{}
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, http.MethodPut, r.Method)
+			name, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/_data_stream/"), "/_lifecycle"))
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			bodies[name] = string(body)
+			status, resp := http.StatusOK, `{"acknowledged":true}`
+			if name == ".fleet-fileds-*" {
+				status, resp = http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index"},"status":404}`
+			}
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(resp)),
+			}, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(client)
+
+	cfg := config.LifecycleGC{Enabled: true}
+	cfg.InitDefaults()
+	cfg.OutputHealthRetention = 0
+	require.NoError(t, getLifecycleGCFunc(bulker, cfg)(ctx))
+	assert.Equal(t, map[string]string{
+		".fleet-actions-results": `{"data_retention":"2592000s"}`,
+		".fleet-fileds-*":        `{"data_retention":"2592000s"}`,
+	}, bodies)
+}
+
+func TestLifecycleGCDisabled(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	cfg := config.LifecycleGC{}
+	cfg.InitDefaults()
+	require.NoError(t, getLifecycleGCFunc(bulker, cfg)(context.Background()))
+	bulker.AssertNotCalled(t, "Client")
+}
+
+func TestLifecycleGCFailure(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusForbidden,
+				Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(`{"error":{"type":"security_exception","reason":"action is unauthorized"},"status":403}`)),
+			}, nil
+		}),
+	})
+	require.NoError(t, err)
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(client)
+
+	err = putLifecycles(ctx, bulker, []dataStreamRetention{{name: ".fleet-actions-results", retention: time.Hour}})
+	assert.ErrorContains(t, err, "security_exception")
+}
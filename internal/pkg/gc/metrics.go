@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import "sync"
+
+// Counter is incremented by the GC jobs to report their work.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// InactiveAgentsMetrics are the counters the inactive agents cleanup reports to, nil counters are ignored.
+// The invalidations of the API keys of the agents are reported by the API key audit events.
+type InactiveAgentsMetrics struct {
+	Unenrolled Counter
+	Failed     Counter
+}
+
+var (
+	metricsMut            sync.RWMutex
+	inactiveAgentsMetrics InactiveAgentsMetrics
+)
+
+// SetInactiveAgentsMetrics sets the counters the inactive agents cleanup reports to.
+func SetInactiveAgentsMetrics(m InactiveAgentsMetrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	inactiveAgentsMetrics = m
+}
+
+func getInactiveAgentsMetrics() InactiveAgentsMetrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return inactiveAgentsMetrics
+}
+
+func addCount(c Counter, n int) {
+	if c != nil && n > 0 {
+		c.Add(uint64(n))
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import (
+	"context"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+
+	"github.com/rs/zerolog"
+)
+
+func getPoliciesGCFunc(bulker bulk.Bulk, cfg config.PoliciesGC) scheduler.WorkFunc {
+	return func(ctx context.Context) error {
+		if cfg.KeepRevisions <= 0 {
+			return nil
+		}
+		return cleanupPolicies(ctx, bulker, int64(cfg.KeepRevisions))
+	}
+}
+
+// cleanupPolicies deletes the documents of each policy that are more than keep revisions older than its latest revision.
+// Revisions from the latest one coordinated by a fleet-server are kept, so a policy that was saved but not yet
+// coordinated can still be sent to agents.
+func cleanupPolicies(ctx context.Context, bulker bulk.Bulk, keep int64) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "policy revisions cleanup").Int64("keep", keep).Logger()
+
+	log.Debug().Msg("delete old policy revisions")
+
+	policies, err := dl.QueryLatestPolicies(ctx, bulker)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to find policies")
+		return err
+	}
+
+	var deleted int64
+	for _, p := range policies {
+		before := p.RevisionIdx - keep + 1
+		if p.CoordinatorIdx == 0 {
+			deployed, err := dl.FindLatestDeployedPolicy(ctx, bulker, p.PolicyID)
+			if err != nil {
+				log.Debug().Err(err).Str(logger.PolicyID, p.PolicyID).Msg("failed to find deployed policy revision")
+				return err
+			}
+			if deployed == nil {
+				// never coordinated, there is nothing safe to delete
+				continue
+			}
+			before = min(before, deployed.RevisionIdx)
+		}
+		if before <= 1 {
+			continue
+		}
+		n, err := dl.DeletePolicyRevisionsBefore(ctx, bulker, p.PolicyID, before)
+		if err != nil {
+			log.Debug().Err(err).Str(logger.PolicyID, p.PolicyID).Msg("failed to delete policy revisions")
+			return err
+		}
+		deleted += n
+	}
+	log.Debug().Int64("count", deleted).Msg("deleted old policy revisions")
+	return nil
+}
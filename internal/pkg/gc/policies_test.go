@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package gc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func latestPolicyBucket(id string, rev, coord int64) es.Bucket {
+	return es.Bucket{
+		Key: id,
+		Aggregations: map[string]es.HitsT{
+			dl.FieldRevisionIdx: {Hits: []es.HitT{{
+				Source: []byte(fmt.Sprintf(`{"policy_id":%q,"revision_idx":%d,"coordinator_idx":%d}`, id, rev, coord)),
+			}}},
+		},
+	}
+}
+
+func TestPoliciesGC(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	var deletes []string
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/.fleet-policies/_delete_by_query", r.URL.Path)
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			deletes = append(deletes, string(body))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(`{"deleted":2}`)),
+			}, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	isAggQuery := func(body []byte) bool { return bytes.Contains(body, []byte(`"aggs"`)) }
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(client)
+	bulker.On("Search", mock.Anything, dl.FleetPolicies, mock.MatchedBy(isAggQuery), mock.Anything).Return(&es.ResultT{
+		Aggregations: map[string]es.Aggregation{
+			dl.FieldPolicyID: {Buckets: []es.Bucket{
+				// deployed, revisions older than 8 are removed
+				latestPolicyBucket("deployed", 10, 1),
+				// not coordinated yet, revisions older than the deployed revision 4 are removed
+				latestPolicyBucket("pending", 12, 0),
+				// not enough revisions
+				latestPolicyBucket("new", 2, 1),
+				// never coordinated
+				latestPolicyBucket("never", 5, 0),
+			}},
+		},
+	}, nil).Once()
+	bulker.On("Search", mock.Anything, dl.FleetPolicies, mock.MatchedBy(func(body []byte) bool {
+		return !isAggQuery(body) && bytes.Contains(body, []byte(`"pending"`))
+	}), mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{
+		Source: []byte(`{"policy_id":"pending","revision_idx":4,"coordinator_idx":1}`),
+	}}}}, nil).Once()
+	bulker.On("Search", mock.Anything, dl.FleetPolicies, mock.MatchedBy(func(body []byte) bool {
+		return !isAggQuery(body) && bytes.Contains(body, []byte(`"never"`))
+	}), mock.Anything).Return(&es.ResultT{}, nil).Once()
+
+	err = getPoliciesGCFunc(bulker, config.PoliciesGC{KeepRevisions: 3})(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, deletes, 2)
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[{"term":{"policy_id":"deployed"}},{"range":{"revision_idx":{"lt":8}}}]}}}`, deletes[0])
+	assert.JSONEq(t, `{"query":{"bool":{"filter":[{"term":{"policy_id":"pending"}},{"range":{"revision_idx":{"lt":4}}}]}}}`, deletes[1])
+	bulker.AssertExpectations(t)
+}
+
+func TestPoliciesGCDisabled(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	err := getPoliciesGCFunc(bulker, config.PoliciesGC{})(context.Background())
+	require.NoError(t, err)
+	bulker.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
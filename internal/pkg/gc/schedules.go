@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
 	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
 )
 
@@ -17,10 +19,13 @@ const (
 )
 
 // Schedules returns the GC schedules
-func Schedules(bulker bulk.Bulk, scheduleInterval time.Duration, cleanupIntervalAfterExpired string) []scheduler.Schedule {
+// store holds the contents of uploaded chunks, it is nil if they are kept in elasticsearch.
+func Schedules(bulker bulk.Bulk, store storage.Backend, cfg config.GC) []scheduler.Schedule {
+	scheduleInterval := cfg.ScheduleInterval
 	if scheduleInterval == 0 {
 		scheduleInterval = defaultScheduleInterval
 	}
+	cleanupIntervalAfterExpired := cfg.CleanupAfterExpiredInterval
 	if cleanupIntervalAfterExpired == "" {
 		cleanupIntervalAfterExpired = defaultCleanupIntervalAfterExpired
 	}
@@ -31,5 +36,30 @@ func Schedules(bulker bulk.Bulk, scheduleInterval time.Duration, cleanupInterval
 			Interval: scheduleInterval,
 			WorkFn:   getActionsGCFunc(bulker, cleanupIntervalAfterExpired),
 		},
+		{
+			Name:     "file uploads cleanup",
+			Interval: scheduleInterval,
+			WorkFn:   getUploadsGCFunc(bulker, store, cfg.Uploads),
+		},
+		{
+			Name:     "policy revisions cleanup",
+			Interval: scheduleInterval,
+			WorkFn:   getPoliciesGCFunc(bulker, cfg.Policies),
+		},
+		{
+			Name:     "data stream lifecycles",
+			Interval: scheduleInterval,
+			WorkFn:   getLifecycleGCFunc(bulker, cfg.Lifecycle),
+		},
+		{
+			Name:     "agent tombstones cleanup",
+			Interval: scheduleInterval,
+			WorkFn:   getTombstonesGCFunc(bulker, cfg.Tombstones),
+		},
+		{
+			Name:     "inactive agents cleanup",
+			Interval: scheduleInterval,
+			WorkFn:   getInactiveAgentsGCFunc(bulker, cfg.InactiveAgents),
+		},
 	}
 }
@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+)
+
+func getTombstonesGCFunc(bulker bulk.Bulk, cfg config.TombstonesGC) scheduler.WorkFunc {
+	return func(ctx context.Context) error {
+		if cfg.Retention <= 0 {
+			return nil
+		}
+		return cleanupTombstones(ctx, bulker, time.Now().Add(-cfg.Retention))
+	}
+}
+
+// cleanupTombstones deletes the agents that became a tombstone before the passed time, their API keys were
+// invalidated when they became a tombstone.
+func cleanupTombstones(ctx context.Context, bulker bulk.Bulk, before time.Time) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "agent tombstones cleanup").Time("before", before).Logger()
+
+	log.Debug().Msg("delete agent tombstones")
+
+	deleted, err := dl.DeleteTombstonedAgentsBefore(ctx, bulker, before)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to delete agent tombstones")
+		return err
+	}
+	log.Debug().Int64("count", deleted).Msg("deleted agent tombstones")
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestTombstonesGC(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	var before time.Time
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			assert.Equal(t, "/.fleet-agents/_delete_by_query", r.URL.Path)
+			var query struct {
+				Query struct {
+					Bool struct {
+						Filter []struct {
+							Range struct {
+								TombstonedAt struct {
+									LT time.Time `json:"lt"`
+								} `json:"tombstoned_at"`
+							} `json:"range"`
+						} `json:"filter"`
+					} `json:"bool"`
+				} `json:"query"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			require.Len(t, query.Query.Bool.Filter, 1)
+			before = query.Query.Bool.Filter[0].Range.TombstonedAt.LT
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(`{"deleted":3}`)),
+			}, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(client)
+
+	cfg := config.TombstonesGC{}
+	cfg.InitDefaults()
+	require.NoError(t, getTombstonesGCFunc(bulker, cfg)(ctx))
+	assert.WithinDuration(t, time.Now().Add(-cfg.Retention), before, time.Minute)
+}
+
+func TestTombstonesGCDisabled(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	require.NoError(t, getTombstonesGCFunc(bulker, config.TombstonesGC{})(context.Background()))
+	bulker.AssertNotCalled(t, "Client")
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/uploader"
+	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+
+	"github.com/rs/zerolog"
+)
+
+// maxUploadsCleanupBatches bounds the number of uploads removed in a single run, the rest are removed in later runs.
+const maxUploadsCleanupBatches = 10
+
+var (
+	staleUploadStatuses     = []file.Status{file.StatusAwaiting, file.StatusProgress, file.StatusFail, file.StatusDel}
+	completedUploadStatuses = []file.Status{file.StatusDone}
+)
+
+func getUploadsGCFunc(bulker bulk.Bulk, store storage.Backend, cfg config.UploadsGC) scheduler.WorkFunc {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		if cfg.StaleAfter > 0 {
+			if err := cleanupUploads(ctx, bulker, store, "stale", staleUploadStatuses, now.Add(-cfg.StaleAfter)); err != nil {
+				return err
+			}
+		}
+		if cfg.Retention > 0 {
+			if err := cleanupUploads(ctx, bulker, store, "completed", completedUploadStatuses, now.Add(-cfg.Retention)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func cleanupUploads(ctx context.Context, bulker bulk.Bulk, store storage.Backend, kind string, statuses []file.Status, before time.Time) error {
+	log := zerolog.Ctx(ctx).With().Str("ctx", "file uploads cleanup").Str("kind", kind).Time("before", before).Logger()
+
+	log.Debug().Msg("delete uploads")
+
+	var deleted int
+	for i := 0; i < maxUploadsCleanupBatches; i++ {
+		infos, err := uploader.FindUploadsBefore(ctx, bulker, statuses, before)
+		if err != nil {
+			log.Debug().Err(err).Msg("failed to find uploads")
+			return err
+		}
+		if len(infos) == 0 {
+			break
+		}
+		for _, info := range infos {
+			if err := uploader.Purge(ctx, bulker, store, info); err != nil {
+				log.Debug().Err(err).Str("fileID", info.DocID).Msg("failed to delete upload")
+				return err
+			}
+			deleted++
+		}
+	}
+	log.Debug().Int("count", deleted).Msg("deleted uploads")
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package gc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestUploadsGC(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+
+	store, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	key := storage.ChunkKey("endpoint", "action.agent", 0)
+	_, err = store.Put(ctx, key, strings.NewReader("data"), -1)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var deleted, refresh []string
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			refresh = append(refresh, r.URL.Query().Get("refresh"))
+			mu.Unlock()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}},
+				Body:       io.NopCloser(strings.NewReader(`{"deleted":1}`)),
+			}, nil
+		}),
+	})
+	require.NoError(t, err)
+
+	bulker := ftesting.NewMockBulk()
+	bulker.On("Client").Return(client)
+	stale := &es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{
+		ID:     "action.agent",
+		Source: []byte(`{"upload_id":"upl","agent_id":"agent","action_id":"action","src":"endpoint","upload_start":1,"file":{"Status":"UPLOADING","size":4,"ChunkSize":4}}`),
+	}}}}
+	bulker.On("Search", mock.Anything, ".fleet-fileds-fromhost-meta-*", mock.Anything, mock.Anything).Return(stale, nil).Once()
+	bulker.On("Search", mock.Anything, ".fleet-fileds-fromhost-meta-*", mock.Anything, mock.Anything).Return(&es.ResultT{}, nil)
+	bulker.On("Search", mock.Anything, ".fleet-fileds-fromhost-data-*", mock.Anything, mock.Anything).Return(&es.ResultT{HitsT: es.HitsT{Hits: []es.HitT{{
+		ID: "action.agent.0",
+		Fields: map[string]interface{}{
+			file.FieldBaseID: []interface{}{"action.agent"},
+		},
+	}}}}, nil)
+
+	err = getUploadsGCFunc(bulker, store, config.UploadsGC{StaleAfter: time.Hour})(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/.fleet-fileds-fromhost-data-endpoint/_delete_by_query",
+		"/.fleet-fileds-fromhost-meta-endpoint/_delete_by_query",
+	}, deleted)
+	assert.Equal(t, "true", refresh[1], "the deleted upload is not found by the next batch")
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	bulker.AssertExpectations(t)
+}
+
+func TestUploadsGCDisabled(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	err := getUploadsGCFunc(bulker, nil, config.UploadsGC{})(context.Background())
+	require.NoError(t, err)
+	bulker.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,314 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package invalidator invalidates retired API keys in the background.
+package invalidator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultMaxRetryDelay = 5 * time.Minute
+)
+
+// ErrOutputNotFound is returned when the output of API keys is not known to the fleet-server, its keys can not be invalidated.
+var ErrOutputNotFound = errors.New("output not found")
+
+type retryT struct {
+	delay time.Duration
+	next  time.Time
+}
+
+// stateT is the content of the state file.
+type stateT struct {
+	// Pending are the IDs of the API keys to invalidate by output name, the fleet-server output has an empty name.
+	Pending map[string][]string `json:"pending"`
+}
+
+// Invalidator collects the API keys to invalidate and invalidates them in batches at a set interval.
+//
+// The invalidations of an output that fail are retried with a growing delay, the pending invalidations
+// are written to a file so they are not lost when the fleet-server restarts.
+type Invalidator struct {
+	bulker        bulk.Bulk
+	path          string
+	batchSize     int
+	flushInterval time.Duration
+	maxRetryDelay time.Duration
+
+	mut     sync.Mutex
+	pending map[string]map[string]struct{}
+	retry   map[string]retryT
+	dirty   chan struct{}
+
+	log zerolog.Logger
+}
+
+// New creates an Invalidator, the pending invalidations are only sent once Run is called.
+func New(bulker bulk.Bulk, cfg config.APIKeyInvalidation) *Invalidator {
+	inv := &Invalidator{
+		bulker:        bulker,
+		path:          cfg.StatePath(),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		maxRetryDelay: cfg.MaxRetryDelay,
+		pending:       make(map[string]map[string]struct{}),
+		retry:         make(map[string]retryT),
+		dirty:         make(chan struct{}, 1),
+		log:           zerolog.Nop(),
+	}
+	if inv.batchSize <= 0 {
+		inv.batchSize = defaultBatchSize
+	}
+	if inv.flushInterval <= 0 {
+		inv.flushInterval = defaultFlushInterval
+	}
+	if inv.maxRetryDelay <= 0 {
+		inv.maxRetryDelay = defaultMaxRetryDelay
+	}
+	return inv
+}
+
+// Invalidate adds the API keys to the pending invalidations.
+func (inv *Invalidator) Invalidate(keys ...model.ToRetireAPIKeyIdsItems) {
+	added := false
+	inv.mut.Lock()
+	for _, k := range keys {
+		if k.ID == "" {
+			continue
+		}
+		ids, ok := inv.pending[k.Output]
+		if !ok {
+			ids = make(map[string]struct{})
+			inv.pending[k.Output] = ids
+		}
+		ids[k.ID] = struct{}{}
+		added = true
+	}
+	inv.mut.Unlock()
+
+	if added {
+		select {
+		case inv.dirty <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Pending returns the number of API keys waiting to be invalidated.
+func (inv *Invalidator) Pending() int {
+	inv.mut.Lock()
+	defer inv.mut.Unlock()
+	n := 0
+	for _, ids := range inv.pending {
+		n += len(ids)
+	}
+	return n
+}
+
+// Run loads the invalidations left by a previous run and sends the pending invalidations until the context is cancelled.
+func (inv *Invalidator) Run(ctx context.Context) error {
+	inv.log = zerolog.Ctx(ctx).With().Str("ctx", "api key invalidator").Logger()
+	if err := inv.load(); err != nil {
+		inv.log.Warn().Err(err).Str("path", inv.path).Msg("Failed to load the pending API key invalidations")
+	}
+
+	tick := time.NewTicker(inv.flushInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			inv.save()
+			return nil
+		case <-inv.dirty:
+			inv.save()
+		case <-tick.C:
+			if inv.flush(ctx) {
+				inv.save()
+			}
+		}
+	}
+}
+
+// flush sends a batch of the pending invalidations of each output that is not waiting for a retry.
+// It returns true if the pending invalidations changed.
+func (inv *Invalidator) flush(ctx context.Context) bool {
+	now := time.Now()
+	batches := make(map[string][]string)
+	inv.mut.Lock()
+	for output, pending := range inv.pending {
+		if r, ok := inv.retry[output]; ok && now.Before(r.next) {
+			continue
+		}
+		ids := make([]string, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		batches[output] = ids[:min(len(ids), inv.batchSize)]
+	}
+	inv.mut.Unlock()
+
+	changed := false
+	for output, ids := range batches {
+		err := InvalidateOutput(ctx, inv.log, inv.bulker, output, ids)
+		if ctx.Err() != nil {
+			return changed
+		}
+		audit.APIKey(inv.log, audit.APIKeyEvent{Action: audit.ActionAPIKeyInvalidate, Output: output, IDs: ids}, err)
+		zlog := inv.log.With().Str(logger.PolicyOutputName, output).Strs("ids", ids).Logger()
+
+		inv.mut.Lock()
+		switch {
+		case errors.Is(err, ErrOutputNotFound):
+			zlog.Warn().Err(err).Msg("Output not found, API keys will be orphaned")
+			inv.remove(output, ids)
+			changed = true
+		case err != nil:
+			r := inv.retry[output]
+			r.delay = min(max(2*r.delay, inv.flushInterval), inv.maxRetryDelay)
+			r.next = now.Add(r.delay)
+			inv.retry[output] = r
+			zlog.Warn().Err(err).Dur("retry_delay", r.delay).Msg("Failed to invalidate API keys, retrying")
+		default:
+			zlog.Debug().Msg("Invalidated API keys")
+			delete(inv.retry, output)
+			inv.remove(output, ids)
+			changed = true
+		}
+		inv.mut.Unlock()
+	}
+	return changed
+}
+
+// remove deletes ids from the pending invalidations of output.
+// WARNING: Expects mutex locked.
+func (inv *Invalidator) remove(output string, ids []string) {
+	pending := inv.pending[output]
+	for _, id := range ids {
+		delete(pending, id)
+	}
+	if len(pending) == 0 {
+		delete(inv.pending, output)
+	}
+}
+
+func (inv *Invalidator) load() error {
+	b, err := os.ReadFile(inv.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var state stateT
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("failed to decode state file: %w", err)
+	}
+
+	keys := make([]model.ToRetireAPIKeyIdsItems, 0)
+	for output, ids := range state.Pending {
+		for _, id := range ids {
+			keys = append(keys, model.ToRetireAPIKeyIdsItems{ID: id, Output: output})
+		}
+	}
+	inv.Invalidate(keys...)
+	inv.log.Info().Int("count", len(keys)).Msg("Loaded pending API key invalidations")
+	return nil
+}
+
+// save writes the pending invalidations to the state file, the file is removed when there are none.
+func (inv *Invalidator) save() {
+	if err := inv.write(); err != nil {
+		inv.log.Error().Err(err).Str("path", inv.path).Msg("Failed to save the pending API key invalidations")
+	}
+}
+
+func (inv *Invalidator) write() error {
+	state := stateT{Pending: make(map[string][]string)}
+	inv.mut.Lock()
+	for output, pending := range inv.pending {
+		ids := make([]string, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		state.Pending[output] = ids
+	}
+	inv.mut.Unlock()
+
+	if len(state.Pending) == 0 {
+		if err := os.Remove(inv.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file that is renamed into place, so a partial write never replaces the state.
+	tmp, err := os.CreateTemp(filepath.Dir(inv.path), ".api-key-invalidations-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // removal fails once the file is renamed
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), inv.path)
+}
+
+// InvalidateOutput invalidates API keys of an output with a single security API call.
+// The keys of the fleet-server output, with an empty name, are invalidated with bulker.
+// The keys of remote outputs are invalidated with the bulker of the output, which is created from
+// its policy definition if needed. ErrOutputNotFound is returned if no policy defines the output.
+func InvalidateOutput(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, output string, ids []string) error {
+	if output == "" {
+		return bulker.APIKeyInvalidate(ctx, ids...)
+	}
+
+	outputBulk := bulker.GetBulker(output)
+	if outputBulk == nil {
+		// read output config from .fleet-policies, not filtering by policy id as agent could be reassigned
+		policy, err := dl.QueryOutputFromPolicy(ctx, bulker, output)
+		if err != nil {
+			return fmt.Errorf("failed to read output policy: %w", err)
+		}
+		if policy == nil {
+			return ErrOutputNotFound
+		}
+		outputBulk, _, err = bulker.CreateAndGetBulker(ctx, zlog, output, policy.Data.Outputs)
+		if err != nil {
+			return fmt.Errorf("failed to recreate output bulker: %w", err)
+		}
+	}
+	return outputBulk.APIKeyInvalidate(ctx, ids...)
+}
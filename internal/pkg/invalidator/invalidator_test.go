@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package invalidator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestInvalidatorFlushBatches(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	remote := ftesting.NewMockBulk()
+	bulker.On("GetBulker", "remote").Return(remote)
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"a", "b"}).Return(nil).Once()
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"c"}).Return(nil).Once()
+	remote.On("APIKeyInvalidate", mock.Anything, []string{"r"}).Return(nil).Once()
+
+	inv := New(bulker, config.APIKeyInvalidation{Path: filepath.Join(t.TempDir(), "state.json"), BatchSize: 2})
+	inv.Invalidate(
+		model.ToRetireAPIKeyIdsItems{ID: "c"},
+		model.ToRetireAPIKeyIdsItems{ID: "a"},
+		model.ToRetireAPIKeyIdsItems{ID: "b"},
+		model.ToRetireAPIKeyIdsItems{ID: "a"},
+		model.ToRetireAPIKeyIdsItems{ID: ""},
+		model.ToRetireAPIKeyIdsItems{ID: "r", Output: "remote"},
+	)
+	assert.Equal(t, 4, inv.Pending())
+
+	ctx := context.Background()
+	assert.True(t, inv.flush(ctx))
+	assert.Equal(t, 1, inv.Pending())
+	assert.True(t, inv.flush(ctx))
+	assert.Equal(t, 0, inv.Pending())
+	assert.False(t, inv.flush(ctx))
+
+	bulker.AssertExpectations(t)
+	remote.AssertExpectations(t)
+}
+
+func TestInvalidatorFlushRetry(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"a"}).Return(errors.New("unavailable")).Twice()
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"a"}).Return(nil).Once()
+
+	inv := New(bulker, config.APIKeyInvalidation{
+		Path:          filepath.Join(t.TempDir(), "state.json"),
+		FlushInterval: time.Second,
+		MaxRetryDelay: 3 * time.Second,
+	})
+	inv.Invalidate(model.ToRetireAPIKeyIdsItems{ID: "a"})
+
+	ctx := context.Background()
+	assert.False(t, inv.flush(ctx))
+	assert.Equal(t, time.Second, inv.retry[""].delay)
+
+	// the output is skipped until its retry time
+	assert.False(t, inv.flush(ctx))
+	bulker.AssertNumberOfCalls(t, "APIKeyInvalidate", 1)
+
+	inv.retry[""] = retryT{delay: inv.retry[""].delay, next: time.Now()}
+	assert.False(t, inv.flush(ctx))
+	assert.Equal(t, 2*time.Second, inv.retry[""].delay)
+
+	inv.retry[""] = retryT{delay: inv.retry[""].delay, next: time.Now()}
+	assert.True(t, inv.flush(ctx))
+	assert.Equal(t, 0, inv.Pending())
+	assert.Empty(t, inv.retry)
+
+	bulker.AssertExpectations(t)
+}
+
+func TestInvalidatorFlushOutputNotFound(t *testing.T) {
+	bulker := ftesting.NewMockBulk()
+	bulker.On("GetBulker", "remote").Return(nil)
+	bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&es.ResultT{}, nil)
+
+	inv := New(bulker, config.APIKeyInvalidation{Path: filepath.Join(t.TempDir(), "state.json")})
+	inv.Invalidate(model.ToRetireAPIKeyIdsItems{ID: "r", Output: "remote"})
+
+	assert.True(t, inv.flush(context.Background()))
+	assert.Equal(t, 0, inv.Pending(), "the keys of an unknown output are dropped")
+	bulker.AssertExpectations(t)
+}
+
+func TestInvalidatorState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := config.APIKeyInvalidation{Path: path}
+
+	inv := New(ftesting.NewMockBulk(), cfg)
+	inv.Invalidate(
+		model.ToRetireAPIKeyIdsItems{ID: "a"},
+		model.ToRetireAPIKeyIdsItems{ID: "r", Output: "remote"},
+	)
+	require.NoError(t, inv.write())
+
+	restarted := New(ftesting.NewMockBulk(), cfg)
+	require.NoError(t, restarted.load())
+	assert.Equal(t, inv.pending, restarted.pending)
+
+	restarted.pending = make(map[string]map[string]struct{})
+	require.NoError(t, restarted.write())
+	_, err := os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the state file is removed when nothing is pending")
+}
+
+func TestInvalidatorRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"pending":{"":["a"]}}`), 0o600))
+
+	done := make(chan struct{})
+	bulker := ftesting.NewMockBulk()
+	bulker.On("APIKeyInvalidate", mock.Anything, []string{"a"}).Return(nil).Once().Run(func(mock.Arguments) {
+		close(done)
+	})
+
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	defer cancel()
+
+	inv := New(bulker, config.APIKeyInvalidation{Path: path, FlushInterval: 10 * time.Millisecond})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- inv.Run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending invalidation was not loaded and sent")
+	}
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return errors.Is(err, os.ErrNotExist)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	bulker.On("GetBulker", "remote").Return(nil).Maybe()
+	bulker.On("Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&es.ResultT{}, errors.New("unavailable")).Maybe()
+	inv.Invalidate(model.ToRetireAPIKeyIdsItems{ID: "b", Output: "remote"})
+	cancel()
+	require.NoError(t, <-errCh)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"pending":{"remote":["b"]}}`, string(b), "pending invalidations are saved on shutdown")
+}
@@ -0,0 +1,181 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package limit
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const (
+	// scaleDecrease is the factor of the scale on each measure under pressure, the scale drops fast to shed the load.
+	scaleDecrease = 0.75
+	// scaleIncrease is added to the scale on each measure without pressure, the scale recovers slowly.
+	scaleIncrease = 0.1
+)
+
+// scaleBits are the bits of the scale of the rate and the burst of the limiters, 0 is a scale of 1.
+var scaleBits atomic.Uint64
+
+// Scale returns the scale of the rate and the burst of the limiters set by the adaptive limits.
+func Scale() float64 {
+	b := scaleBits.Load()
+	if b == 0 {
+		return 1
+	}
+	return math.Float64frombits(b)
+}
+
+func setScale(scale float64) {
+	scaleBits.Store(math.Float64bits(scale))
+}
+
+// Gauge is set by the adaptive limits to report the scale.
+type Gauge interface {
+	Set(float64)
+}
+
+// Metrics are the values the adaptive limits report to, nil values are ignored.
+type Metrics struct {
+	Scale Gauge
+}
+
+var (
+	metricsMut sync.RWMutex
+	metricsV   Metrics
+)
+
+// SetMetrics sets the values the adaptive limits report to.
+func SetMetrics(m Metrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metricsV = m
+}
+
+func getMetrics() Metrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return metricsV
+}
+
+// load is a measure of the load of the server, the CPU and the memory are shares of their limit.
+type load struct {
+	cpu    float64
+	memory float64
+	queue  int
+}
+
+// Adaptive scales the rate and the burst of the limiters with the load of the server.
+type Adaptive struct {
+	cfg    config.AdaptiveLimits
+	queue  func() int
+	sample func() (load, error)
+	proc   *process.Process
+}
+
+// NewAdaptive returns the adaptive limits of cfg, queue returns the number of requests queued by the bulker.
+func NewAdaptive(cfg config.AdaptiveLimits, queue func() int) (*Adaptive, error) {
+	proc, err := process.NewProcess(int32(os.Getpid())) //nolint:gosec // pids fit in an int32
+	if err != nil {
+		return nil, err
+	}
+	a := &Adaptive{cfg: cfg, queue: queue, proc: proc}
+	a.sample = a.measure
+	return a, nil
+}
+
+// Run measures the load of the server at each interval and adjusts the scale until ctx is done. The scale is reset
+// when it returns.
+func (a *Adaptive) Run(ctx context.Context) error {
+	defer a.set(1)
+	// the first measure of the CPU is the usage since the start of the process
+	_, _ = a.proc.PercentWithContext(ctx, 0)
+
+	t := time.NewTicker(a.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		l, err := a.sample()
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("unable to measure the load of the server, the limits are not adjusted")
+			continue
+		}
+		a.adjust(ctx, l)
+	}
+}
+
+// adjust decreases the scale when the server is under pressure and increases it otherwise, within the floor and
+// the ceiling.
+func (a *Adaptive) adjust(ctx context.Context, l load) float64 {
+	cur := Scale()
+	pressure := l.cpu > a.cfg.CPUThreshold || l.memory > a.cfg.MemoryThreshold ||
+		(a.cfg.QueueThreshold > 0 && l.queue > a.cfg.QueueThreshold)
+
+	next := math.Min(cur+scaleIncrease, a.cfg.Ceiling)
+	if pressure {
+		next = math.Max(cur*scaleDecrease, a.cfg.Floor)
+	}
+	if next != cur {
+		zerolog.Ctx(ctx).Info().
+			Float64("cpu", l.cpu).
+			Float64("memory", l.memory).
+			Int("queue", l.queue).
+			Float64("old", cur).
+			Float64("new", next).
+			Msg("adjusting the rate limits to the load of the server")
+	}
+	a.set(next)
+	return next
+}
+
+func (a *Adaptive) set(scale float64) {
+	setScale(scale)
+	if g := getMetrics().Scale; g != nil {
+		g.Set(scale)
+	}
+}
+
+func (a *Adaptive) measure() (load, error) {
+	var l load
+	cpu, err := a.proc.Percent(0)
+	if err != nil {
+		return l, err
+	}
+	l.cpu = cpu / 100 / float64(runtime.GOMAXPROCS(0))
+
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+		metrics.Read(sample)
+		l.memory = float64(sample[0].Value.Uint64()) / float64(limit)
+	} else {
+		vm, err := mem.VirtualMemory()
+		if err != nil {
+			return l, err
+		}
+		l.memory = vm.UsedPercent / 100
+	}
+
+	if a.queue != nil {
+		l.queue = a.queue()
+	}
+	return l, nil
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package limit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+type testGauge struct {
+	v float64
+}
+
+func (g *testGauge) Set(v float64) {
+	g.v = v
+}
+
+func TestAdaptiveAdjust(t *testing.T) {
+	t.Cleanup(func() {
+		setScale(1)
+		SetMetrics(Metrics{})
+	})
+	g := &testGauge{}
+	SetMetrics(Metrics{Scale: g})
+
+	var cfg config.AdaptiveLimits
+	cfg.InitDefaults()
+	cfg.QueueThreshold = 100
+	a := &Adaptive{cfg: cfg}
+	ctx := context.Background()
+
+	assert.Equal(t, 0.75, a.adjust(ctx, load{cpu: 0.9}), "the scale decreases under CPU pressure")
+	assert.Equal(t, 0.5625, a.adjust(ctx, load{memory: 0.9}), "the scale decreases under memory pressure")
+	assert.Equal(t, 0.5625, g.v)
+	for i := 0; i < 10; i++ {
+		a.adjust(ctx, load{queue: 101})
+	}
+	assert.Equal(t, cfg.Floor, Scale(), "the scale does not drop below the floor")
+
+	assert.InDelta(t, 0.35, a.adjust(ctx, load{cpu: 0.5, memory: 0.5, queue: 100}), 1e-9, "the scale recovers without pressure")
+	for i := 0; i < 30; i++ {
+		a.adjust(ctx, load{})
+	}
+	assert.Equal(t, cfg.Ceiling, Scale(), "the scale does not exceed the ceiling")
+	assert.Equal(t, cfg.Ceiling, g.v)
+}
+
+func TestAdaptiveQueueThresholdDisabled(t *testing.T) {
+	t.Cleanup(func() { setScale(1) })
+	var cfg config.AdaptiveLimits
+	cfg.InitDefaults()
+	a := &Adaptive{cfg: cfg}
+
+	assert.InDelta(t, 1.1, a.adjust(context.Background(), load{queue: 1_000_000}), 1e-9, "the queue is ignored without a threshold")
+}
@@ -7,7 +7,10 @@ package limit
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -17,9 +20,29 @@ var (
 	ErrMaxLimit  = errors.New("max limit")
 )
 
+// RetryAfterError is a limit error with the delay after which the request is expected to be accepted.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterSeconds returns the value of the Retry-After header of the delay, rounded up to the second and at least 1.
+func RetryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
+
 // writeError recreates the behaviour of api/error.go.
-// It is defined separately here to stop a circular import
-func writeError(log *zerolog.Logger, w http.ResponseWriter, err error) error {
+// It is defined separately here to stop a circular import.
+// The Retry-After header is retryAfter rounded up to the second, at least 1.
+func writeError(log *zerolog.Logger, w http.ResponseWriter, err error, retryAfter time.Duration) error {
 	resp := struct {
 		Status  int    `json:"statusCode"`
 		Error   string `json:"error"`
@@ -45,6 +68,7 @@ func writeError(log *zerolog.Logger, w http.ResponseWriter, err error) error {
 	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Retry-After", RetryAfterSeconds(retryAfter))
 	w.WriteHeader(http.StatusTooManyRequests)
 	_, wErr = w.Write(p)
 	return wErr
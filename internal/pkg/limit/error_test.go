@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
 
@@ -18,32 +19,40 @@ import (
 
 func TestWriteError(t *testing.T) {
 	tests := []struct {
-		name string
-		err  error
-		want string
+		name       string
+		err        error
+		retryAfter time.Duration
+		want       string
+		wantRetry  string
 	}{{
-		name: "unknown",
-		err:  errors.New("unknown"),
-		want: "UnknownLimiterError",
+		name:      "unknown",
+		err:       errors.New("unknown"),
+		want:      "UnknownLimiterError",
+		wantRetry: "1",
 	}, {
-		name: "rate limit",
-		err:  ErrRateLimit,
-		want: "RateLimit",
+		name:       "rate limit",
+		err:        ErrRateLimit,
+		retryAfter: 2100 * time.Millisecond,
+		want:       "RateLimit",
+		wantRetry:  "3",
 	}, {
-		name: "max limit",
-		err:  ErrMaxLimit,
-		want: "MaxLimit",
+		name:       "max limit",
+		err:        ErrMaxLimit,
+		retryAfter: time.Second,
+		want:       "MaxLimit",
+		wantRetry:  "1",
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			log := testlog.SetLogger(t)
-			err := writeError(&log, w, tt.err)
+			err := writeError(&log, w, tt.err, tt.retryAfter)
 			require.NoError(t, err)
 			resp := w.Result()
 			defer resp.Body.Close()
 			require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+			require.Equal(t, tt.wantRetry, resp.Header.Get("Retry-After"))
 
 			var body struct {
 				Status int    `json:"statusCode"`
@@ -6,7 +6,11 @@
 package limit
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
@@ -17,6 +21,14 @@ import (
 	"golang.org/x/time/rate"
 )
 
+const (
+	// minRetryAfter is the Retry-After of the responses of the max limit, the requests in flight end at an unknown
+	// time, and the least Retry-After of the rate limit.
+	minRetryAfter = time.Second
+	// maxRetryAfter bounds the Retry-After of the rate limit, the burst may be too low to ever allow a request.
+	maxRetryAfter = time.Minute
+)
+
 type releaseFunc func()
 
 // StatIncer is the interface used to count statistics associated with an endpoint.
@@ -27,7 +39,21 @@ type StatIncer interface {
 
 type Limiter struct {
 	rateLimit *rate.Limiter
+	interval  time.Duration
+	burst     int
+	scale     atomic.Uint64 // the bits of the scale of the rate limit, 0 before it is first scaled
 	maxLimit  *semaphore.Weighted
+	max       int64
+	maxBody   int64
+	inFlight  atomic.Int64
+}
+
+type ctxLimiterKey struct{}
+
+// FromContext returns the limiter of the route of the request of ctx.
+func FromContext(ctx context.Context) (*Limiter, bool) {
+	l, ok := ctx.Value(ctxLimiterKey{}).(*Limiter)
+	return l, ok
 }
 
 func NewLimiter(cfg *config.Limit) *Limiter {
@@ -39,11 +65,15 @@ func NewLimiter(cfg *config.Limit) *Limiter {
 
 	if cfg.Interval != time.Duration(0) {
 		l.rateLimit = rate.NewLimiter(rate.Every(cfg.Interval), cfg.Burst)
+		l.interval = cfg.Interval
+		l.burst = cfg.Burst
 	}
 
 	if cfg.Max != 0 {
 		l.maxLimit = semaphore.NewWeighted(cfg.Max)
+		l.max = cfg.Max
 	}
+	l.maxBody = cfg.MaxBody
 
 	return l
 }
@@ -51,6 +81,7 @@ func NewLimiter(cfg *config.Limit) *Limiter {
 func (l *Limiter) acquire() (releaseFunc, error) {
 	releaseFunc := noop
 
+	l.rescale()
 	if l.rateLimit != nil && !l.rateLimit.Allow() {
 		return nil, ErrRateLimit
 	}
@@ -59,18 +90,85 @@ func (l *Limiter) acquire() (releaseFunc, error) {
 		if !l.maxLimit.TryAcquire(1) {
 			return nil, ErrMaxLimit
 		}
+		l.inFlight.Add(1)
 		releaseFunc = l.release
 	}
 
 	return releaseFunc, nil
 }
 
+// rescale applies the scale of the adaptive limits to the rate and the burst of the rate limit when it changed.
+func (l *Limiter) rescale() {
+	if l.rateLimit == nil || l.interval == 0 {
+		return
+	}
+	scale := Scale()
+	bits := math.Float64bits(scale)
+	if old := l.scale.Swap(bits); old == bits || (old == 0 && scale == 1) {
+		return
+	}
+	l.rateLimit.SetLimit(rate.Every(l.interval) * rate.Limit(scale))
+	burst := int(float64(l.burst) * scale)
+	if burst < 1 && l.burst > 0 {
+		burst = 1
+	}
+	l.rateLimit.SetBurst(burst)
+}
+
 func (l *Limiter) release() {
 	if l.maxLimit != nil {
+		l.inFlight.Add(-1)
 		l.maxLimit.Release(1)
 	}
 }
 
+// Usage returns the share of the max limit in use, 0 without a max limit.
+func (l *Limiter) Usage() float64 {
+	if l.max == 0 {
+		return 0
+	}
+	return float64(l.inFlight.Load()) / float64(l.max)
+}
+
+// RateLimited returns true if the next request would exceed the rate limit.
+func (l *Limiter) RateLimited() bool {
+	return l.rateLimit != nil && l.rateLimit.Tokens() < 1
+}
+
+// retryAfter returns the delay after which a request rejected with err is expected to be accepted, the time the rate
+// limit takes to refill a token.
+func (l *Limiter) retryAfter(err error, now time.Time) time.Duration {
+	if !errors.Is(err, ErrRateLimit) || l.rateLimit == nil {
+		return minRetryAfter
+	}
+	limit := l.rateLimit.Limit()
+	if limit == rate.Inf || limit <= 0 {
+		return maxRetryAfter
+	}
+	wait := time.Duration((1 - l.rateLimit.TokensAt(now)) / float64(limit) * float64(time.Second))
+	switch {
+	case wait < minRetryAfter:
+		return minRetryAfter
+	case wait > maxRetryAfter:
+		return maxRetryAfter
+	}
+	return wait
+}
+
+// Acquire takes the limits for a request in a handler, like the limits of the policy of an agent once it is
+// authenticated, and limits the size of the body of r. The returned func releases the limits, the error is a
+// *RetryAfterError when a limit is reached.
+func (l *Limiter) Acquire(w http.ResponseWriter, r *http.Request) (func(), error) {
+	lf, err := l.acquire()
+	if err != nil {
+		return nil, &RetryAfterError{Err: err, After: l.retryAfter(err, time.Now())}
+	}
+	if l.maxBody > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, l.maxBody)
+	}
+	return lf, nil
+}
+
 func (l *Limiter) Wrap(name string, si StatIncer, ll zerolog.Level) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,7 +180,7 @@ func (l *Limiter) Wrap(name string, si StatIncer, ll zerolog.Level) func(http.Ha
 			lf, err := l.acquire()
 			if err != nil {
 				hlog.FromRequest(r).WithLevel(ll).Str("route", name).Err(err).Msg("limit reached")
-				if wErr := writeError(hlog.FromRequest(r), w, err); wErr != nil {
+				if wErr := writeError(hlog.FromRequest(r), w, err, l.retryAfter(err, time.Now())); wErr != nil {
 					hlog.FromRequest(r).Error().Err(wErr).Msg("fail writing error response")
 				}
 				if si != nil {
@@ -91,7 +189,11 @@ func (l *Limiter) Wrap(name string, si StatIncer, ll zerolog.Level) func(http.Ha
 				return
 			}
 			defer lf()
-			next.ServeHTTP(w, r)
+			// Limit the size of the body to prevent malicious agent from exhausting RAM in server
+			if l.maxBody > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, l.maxBody)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxLimiterKey{}, l)))
 		})
 	}
 }
@@ -5,15 +5,20 @@
 package limit
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/sync/semaphore"
 	"golang.org/x/time/rate"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 )
 
 type mockIncer struct {
@@ -86,7 +91,82 @@ func Test_Limiter_Wrap(t *testing.T) {
 			resp := w.Result()
 			resp.Body.Close()
 			assert.Equal(t, tt.status, resp.StatusCode)
+			if tt.status == http.StatusTooManyRequests {
+				assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+			}
 			mi.AssertExpectations(t)
 		})
 	}
 }
+
+func Test_Limiter_MaxBody(t *testing.T) {
+	l := NewLimiter(&config.Limit{MaxBody: 4})
+	var readErr error
+	h := l.Wrap("test", nil, zerolog.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+	assert.NoError(t, readErr)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	var mbErr *http.MaxBytesError
+	assert.ErrorAs(t, readErr, &mbErr)
+}
+
+func Test_Limiter_Usage(t *testing.T) {
+	l := NewLimiter(&config.Limit{Interval: time.Hour, Burst: 2, Max: 4})
+	var usage float64
+	var rateLimited bool
+	h := l.Wrap("test", nil, zerolog.DebugLevel)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromCtx, ok := FromContext(r.Context())
+		assert.True(t, ok)
+		usage, rateLimited = fromCtx.Usage(), fromCtx.RateLimited()
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 0.25, usage, "the request is in flight")
+	assert.False(t, rateLimited)
+	assert.Zero(t, l.Usage(), "the request is done")
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, rateLimited, "the burst is used")
+}
+
+func Test_Limiter_Scale(t *testing.T) {
+	t.Cleanup(func() { setScale(1) })
+	l := NewLimiter(&config.Limit{Interval: time.Second, Burst: 4})
+
+	l.rescale()
+	assert.Equal(t, rate.Limit(1), l.rateLimit.Limit())
+	assert.Equal(t, 4, l.rateLimit.Burst())
+
+	setScale(0.5)
+	l.rescale()
+	assert.Equal(t, rate.Limit(0.5), l.rateLimit.Limit())
+	assert.Equal(t, 2, l.rateLimit.Burst())
+
+	setScale(0.1)
+	l.rescale()
+	assert.Equal(t, 1, l.rateLimit.Burst(), "the burst is at least 1")
+
+	setScale(2)
+	l.rescale()
+	assert.Equal(t, rate.Limit(2), l.rateLimit.Limit())
+	assert.Equal(t, 8, l.rateLimit.Burst())
+}
+
+func Test_Limiter_RetryAfter(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(&config.Limit{Interval: 10 * time.Second, Burst: 1, Max: 1})
+	assert.True(t, l.rateLimit.AllowN(now, 1))
+
+	assert.Equal(t, 10*time.Second, l.retryAfter(ErrRateLimit, now), "a token is refilled after the interval")
+	assert.InDelta(t, 4*time.Second, l.retryAfter(ErrRateLimit, now.Add(6*time.Second)), float64(time.Millisecond))
+	assert.Equal(t, minRetryAfter, l.retryAfter(ErrRateLimit, now.Add(9900*time.Millisecond)))
+	assert.Equal(t, minRetryAfter, l.retryAfter(ErrMaxLimit, now), "the end of the requests in flight is unknown")
+
+	l = NewLimiter(&config.Limit{Interval: time.Hour, Burst: 1})
+	assert.True(t, l.rateLimit.AllowN(now, 1))
+	assert.Equal(t, maxRetryAfter, l.retryAfter(ErrRateLimit, now))
+}
@@ -0,0 +1,454 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package loadtest simulates enrolled agents against a fleet-server. Each simulated agent enrolls, then checks in
+// with the long polls of an agent, acknowledges the actions it receives and downloads the artifacts of its policy,
+// the latencies and the errors of the requests are reported by operation.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/api"
+	"github.com/elastic/fleet-server/v7/internal/pkg/sleep"
+)
+
+const (
+	opEnroll   = "enroll"
+	opCheckin  = "checkin"
+	opAck      = "ack"
+	opArtifact = "artifact"
+
+	defaultRequestTimeout = 30 * time.Second
+	// serverPollTimeout is the default long poll of the checkins of fleet-server, and serverMinPoll the minimum long
+	// poll of the checkins setting their poll timeout.
+	serverPollTimeout = 5 * time.Minute
+	serverMinPoll     = time.Minute
+
+	retryMinDelay = time.Second
+	retryMaxDelay = 30 * time.Second
+)
+
+// Config is the configuration of a load test.
+type Config struct {
+	// URL is the URL of the fleet-server, like https://fleet-server:8220.
+	URL string
+	// EnrollmentToken is the enrollment API key the agents enroll with.
+	EnrollmentToken string
+	// Agents is the number of simulated agents.
+	Agents int
+	// Duration is the duration of the test, from the start of the first agent.
+	Duration time.Duration
+	// RampUp is the period the agents are started over, evenly.
+	RampUp time.Duration
+	// PollTimeout is the poll timeout of the checkins, the latencies of the checkins without actions include the long
+	// poll. The server holds the checkins for the poll timeout minus 2 minutes, 1 minute at least, or for its own
+	// long poll when it is not set.
+	PollTimeout time.Duration
+	// RequestTimeout is the timeout of the requests other than the checkins, 30s by default.
+	RequestTimeout time.Duration
+	// AgentVersion is the version of the agents.
+	AgentVersion string
+	// Tags are the tags of the agents.
+	Tags []string
+	// Artifacts downloads the artifacts of the policies of the agents, like the endpoint exception lists.
+	Artifacts bool
+	TLS       *tls.Config
+
+	// Progress receives a summary of the test at each ProgressInterval when it is set.
+	Progress         io.Writer
+	ProgressInterval time.Duration
+}
+
+// loadTest is a running load test.
+type loadTest struct {
+	cfg    Config
+	client *http.Client
+	stats  map[string]*opStats
+	start  time.Time
+
+	mu       sync.Mutex
+	enrolled int
+}
+
+// Run runs the load test until its duration expires or ctx is canceled.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("the URL of the fleet-server is required")
+	}
+	if cfg.EnrollmentToken == "" {
+		return nil, errors.New("an enrollment token is required")
+	}
+	if cfg.Agents <= 0 {
+		return nil, fmt.Errorf("the number of agents must be positive, got %d", cfg.Agents)
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+
+	lt := &loadTest{
+		cfg: cfg,
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     cfg.TLS,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: cfg.Agents,
+			IdleConnTimeout:     90 * time.Second,
+		}},
+		stats: map[string]*opStats{
+			opEnroll:   {},
+			opCheckin:  {},
+			opAck:      {},
+			opArtifact: {},
+		},
+		start: time.Now(),
+	}
+	defer lt.client.CloseIdleConnections()
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Agents; i++ {
+		delay := time.Duration(0)
+		if cfg.RampUp > 0 {
+			delay = cfg.RampUp * time.Duration(i) / time.Duration(cfg.Agents)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if sleep.WithContext(ctx, delay) != nil {
+				return
+			}
+			a := &agent{lt: lt, hostname: fmt.Sprintf("loadtest-%d", i), artifacts: map[string]bool{}}
+			a.run(ctx)
+		}(i)
+	}
+
+	done := make(chan struct{})
+	if cfg.Progress != nil && cfg.ProgressInterval > 0 {
+		go lt.progress(done)
+	}
+	wg.Wait()
+	close(done)
+	return lt.report(), nil
+}
+
+// progress writes a summary of the test at each interval until done is closed.
+func (lt *loadTest) progress(done <-chan struct{}) {
+	tick := time.NewTicker(lt.cfg.ProgressInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-tick.C:
+			_, _ = fmt.Fprintln(lt.cfg.Progress, lt.report().summary())
+		}
+	}
+}
+
+func (lt *loadTest) addEnrolled() {
+	lt.mu.Lock()
+	lt.enrolled++
+	lt.mu.Unlock()
+}
+
+// httpError is the error response of a request.
+type httpError struct {
+	status     int
+	name       string
+	retryAfter time.Duration
+}
+
+func (e *httpError) Error() string {
+	if e.name == "" {
+		return fmt.Sprintf("%d %s", e.status, http.StatusText(e.status))
+	}
+	return fmt.Sprintf("%d %s", e.status, e.name)
+}
+
+// do sends a request of the operation with its timeout and decodes its response in out when it is set, the latency
+// and the error of the request are recorded unless the test ends while the request is sent.
+func (lt *loadTest) do(ctx context.Context, timeout time.Duration, op, method, path, apiKey string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, method, lt.cfg.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+	req.Header.Set("User-Agent", "Elastic Agent v"+lt.cfg.AgentVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	err = lt.send(req, out)
+	if err != nil && ctx.Err() != nil {
+		// the request was interrupted by the end of the test
+		return err
+	}
+	lt.stats[op].record(time.Since(start), err)
+	return err
+}
+
+func (lt *loadTest) send(req *http.Request, out interface{}) error {
+	res, err := lt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		herr := &httpError{status: res.StatusCode}
+		var resp api.HTTPErrResp
+		if json.NewDecoder(res.Body).Decode(&resp) == nil {
+			herr.name = resp.Error
+		}
+		if d, err := time.ParseDuration(res.Header.Get("Retry-After") + "s"); err == nil {
+			herr.retryAfter = d
+		}
+		return herr
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// agent is a simulated agent.
+type agent struct {
+	lt       *loadTest
+	hostname string
+
+	id       string
+	apiKey   string
+	ackToken string
+	// artifacts are the URLs of the artifacts already downloaded.
+	artifacts map[string]bool
+}
+
+// run enrolls the agent and checks in until the end of the test, the failed requests are retried with a backoff.
+func (a *agent) run(ctx context.Context) {
+	retry := newBackoff(retryMinDelay, retryMaxDelay)
+	for {
+		err := a.enroll(ctx)
+		if err == nil {
+			break
+		}
+		if !a.wait(ctx, retry, err) {
+			return
+		}
+	}
+	a.lt.addEnrolled()
+	retry.Reset()
+
+	for ctx.Err() == nil {
+		resp, err := a.checkin(ctx)
+		if err != nil {
+			if !a.wait(ctx, retry, err) {
+				return
+			}
+			continue
+		}
+		retry.Reset()
+		if resp.AckToken != nil {
+			a.ackToken = *resp.AckToken
+		}
+		if resp.Actions != nil && len(*resp.Actions) > 0 {
+			a.handleActions(ctx, *resp.Actions)
+		}
+		if resp.Backoff != nil {
+			// like the agents, the checkins slow down while fleet-server is under pressure
+			if d, err := time.ParseDuration(resp.Backoff.PollInterval); err == nil && sleep.WithContext(ctx, d) != nil {
+				return
+			}
+		}
+	}
+}
+
+// wait waits before retrying a failed request, for the Retry-After of the response when it is set, and returns
+// false once the test ends.
+func (a *agent) wait(ctx context.Context, retry *backoff, err error) bool {
+	delay := retry.Next()
+	var herr *httpError
+	if errors.As(err, &herr) && herr.retryAfter > 0 {
+		delay = herr.retryAfter
+	}
+	return sleep.WithContext(ctx, delay) == nil
+}
+
+func (a *agent) enroll(ctx context.Context) error {
+	local, err := json.Marshal(map[string]interface{}{
+		"elastic": map[string]interface{}{
+			"agent": map[string]interface{}{
+				"version":     a.lt.cfg.AgentVersion,
+				"snapshot":    false,
+				"upgradeable": false,
+			},
+		},
+		"host": map[string]interface{}{
+			"hostname":     a.hostname,
+			"name":         a.hostname,
+			"architecture": "x86_64",
+		},
+		"os": map[string]interface{}{
+			"platform": "linux",
+			"family":   "debian",
+			"name":     "Linux",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	tags := a.lt.cfg.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	req := api.EnrollRequest{
+		Type: api.PERMANENT,
+		Metadata: api.EnrollMetadata{
+			Local:        local,
+			Tags:         tags,
+			UserProvided: json.RawMessage("{}"),
+		},
+	}
+	var resp api.EnrollResponse
+	if err := a.lt.do(ctx, a.lt.cfg.RequestTimeout, opEnroll, http.MethodPost, "/api/fleet/agents/enroll", a.lt.cfg.EnrollmentToken, req, &resp); err != nil {
+		return err
+	}
+	a.id, a.apiKey = resp.Item.Id, resp.Item.AccessApiKey
+	return nil
+}
+
+func (a *agent) checkin(ctx context.Context) (*api.CheckinResponse, error) {
+	req := api.CheckinRequest{
+		Status:  api.CheckinRequestStatusOnline,
+		Message: "Running",
+	}
+	if a.lt.cfg.PollTimeout > 0 {
+		pollTimeout := a.lt.cfg.PollTimeout.String()
+		req.PollTimeout = &pollTimeout
+	}
+	if a.ackToken != "" {
+		req.AckToken = &a.ackToken
+	}
+	timeout := serverPollTimeout
+	if a.lt.cfg.PollTimeout > 0 {
+		timeout = max(a.lt.cfg.PollTimeout, serverMinPoll)
+	}
+	var resp api.CheckinResponse
+	// the server answers before the poll timeout, the timeout of the request leaves it a margin
+	if err := a.lt.do(ctx, timeout+time.Minute, opCheckin, http.MethodPost, "/api/fleet/agents/"+a.id+"/checkin", a.apiKey, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// handleActions acknowledges the actions and downloads the new artifacts of the policy changes.
+func (a *agent) handleActions(ctx context.Context, actions []api.Action) {
+	events := make([]api.AckRequest_Events_Item, 0, len(actions))
+	for _, action := range actions {
+		var event api.AckRequest_Events_Item
+		if err := event.FromGenericEvent(api.GenericEvent{
+			ActionId:  action.Id,
+			AgentId:   a.id,
+			Message:   fmt.Sprintf("Action '%s' of type '%s' acknowledged.", action.Id, action.Type),
+			Subtype:   api.ACKNOWLEDGED,
+			Type:      api.ACTIONRESULT,
+			Timestamp: time.Now().UTC(),
+		}); err != nil {
+			continue
+		}
+		events = append(events, event)
+
+		if action.Type == api.POLICYCHANGE && a.lt.cfg.Artifacts {
+			if change, err := action.Data.AsActionPolicyChange(); err == nil && change.Policy != nil {
+				a.downloadArtifacts(ctx, change.Policy)
+			}
+		}
+	}
+
+	_ = a.lt.do(ctx, a.lt.cfg.RequestTimeout, opAck, http.MethodPost, "/api/fleet/agents/"+a.id+"/acks", a.apiKey, api.AckRequest{Events: events}, nil)
+}
+
+// downloadArtifacts downloads the artifacts of the manifests of the inputs of the policy, like Elastic Defend does,
+// the artifacts already downloaded are skipped.
+func (a *agent) downloadArtifacts(ctx context.Context, policy *api.PolicyData) {
+	for _, url := range artifactURLs(policy) {
+		if a.artifacts[url] {
+			continue
+		}
+		if err := a.lt.do(ctx, a.lt.cfg.RequestTimeout, opArtifact, http.MethodGet, url, a.apiKey, nil, nil); err == nil {
+			a.artifacts[url] = true
+		}
+	}
+}
+
+// artifactURLs returns the relative URLs of the artifacts of the manifests of the inputs of the policy.
+func artifactURLs(policy *api.PolicyData) []string {
+	if policy.Inputs == nil {
+		return nil
+	}
+	var urls []string
+	for _, input := range *policy.Inputs {
+		manifest, _ := input["artifact_manifest"].(map[string]interface{})
+		artifacts, _ := manifest["artifacts"].(map[string]interface{})
+		for _, artifact := range artifacts {
+			if a, ok := artifact.(map[string]interface{}); ok {
+				if url, ok := a["relative_url"].(string); ok && url != "" {
+					urls = append(urls, url)
+				}
+			}
+		}
+	}
+	return urls
+}
+
+// backoff is the exponential delay between the retries of the failed requests of an agent.
+type backoff struct {
+	min, max, cur time.Duration
+}
+
+func newBackoff(minDelay, maxDelay time.Duration) *backoff {
+	return &backoff{min: minDelay, max: maxDelay}
+}
+
+// Next returns the delay before the next attempt.
+func (b *backoff) Next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.min
+	} else {
+		b.cur = min(2*b.cur, b.max)
+	}
+	return b.cur
+}
+
+// Reset is called once a request succeeds.
+func (b *backoff) Reset() {
+	b.cur = 0
+}
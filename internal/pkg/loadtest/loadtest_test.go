@@ -0,0 +1,178 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/api"
+)
+
+const testArtifactURL = "/api/fleet/artifacts/endpoint-exceptionlist-linux-v1/abc"
+
+// fakeFleetServer answers the requests of the agents, the first checkin of an agent receives a policy change with
+// an artifact and the enrollments fail while failEnroll is positive.
+type fakeFleetServer struct {
+	mu         sync.Mutex
+	failEnroll int
+	checkins   map[string]int
+	acks       int
+	artifacts  int
+}
+
+func (f *fakeFleetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("User-Agent"), "Elastic Agent v8.15.0") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch {
+	case r.URL.Path == "/api/fleet/agents/enroll":
+		if r.Header.Get("Authorization") != "ApiKey token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if f.failEnroll > 0 {
+			f.failEnroll--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(api.HTTPErrResp{StatusCode: http.StatusTooManyRequests, Error: "RateLimit"})
+			return
+		}
+		var req api.EnrollRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Type != api.PERMANENT {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id := "agent-" + string(rune('a'+len(f.checkins)))
+		f.checkins[id] = 0
+		_ = json.NewEncoder(w).Encode(api.EnrollResponse{
+			Action: "created",
+			Item:   api.EnrollResponseItem{Id: id, AccessApiKey: "key-" + id, Tags: req.Metadata.Tags},
+		})
+	case strings.HasSuffix(r.URL.Path, "/checkin"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/fleet/agents/"), "/checkin")
+		if r.Header.Get("Authorization") != "ApiKey key-"+id {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.checkins[id]++
+		resp := api.CheckinResponse{AckToken: ptr("token-" + id)}
+		if f.checkins[id] == 1 {
+			var data api.Action_Data
+			inputs := []map[string]interface{}{{
+				"type": "endpoint",
+				"artifact_manifest": map[string]interface{}{
+					"artifacts": map[string]interface{}{
+						"endpoint-exceptionlist-linux-v1": map[string]interface{}{"relative_url": testArtifactURL},
+					},
+				},
+			}}
+			if err := data.FromActionPolicyChange(api.ActionPolicyChange{Policy: &api.PolicyData{Id: ptr("policy"), Inputs: &inputs}}); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			resp.Actions = &[]api.Action{{Id: "action-" + id, Type: api.POLICYCHANGE, AgentId: id, Data: data}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case strings.HasSuffix(r.URL.Path, "/acks"):
+		var req api.AckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.acks += len(req.Events)
+		_ = json.NewEncoder(w).Encode(api.AckResponse{Action: "acks"})
+	case r.URL.Path == testArtifactURL:
+		f.artifacts++
+		_, _ = w.Write([]byte("artifact"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestRun(t *testing.T) {
+	fake := &fakeFleetServer{failEnroll: 1, checkins: map[string]int{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	var progress bytes.Buffer
+	report, err := Run(context.Background(), Config{
+		URL:              srv.URL + "/",
+		EnrollmentToken:  "token",
+		Agents:           3,
+		Duration:         2500 * time.Millisecond,
+		PollTimeout:      time.Minute,
+		AgentVersion:     "8.15.0",
+		Tags:             []string{"loadtest"},
+		Artifacts:        true,
+		Progress:         &progress,
+		ProgressInterval: time.Second,
+	})
+	require.NoError(t, err)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Equal(t, 3, report.Agents)
+	assert.Equal(t, 3, report.Enrolled)
+	assert.Len(t, fake.checkins, 3)
+	assert.Equal(t, 3, fake.acks)
+	assert.Equal(t, 3, fake.artifacts, "each agent downloads the artifact once")
+
+	enroll := report.Operations[opEnroll]
+	assert.Equal(t, 4, enroll.Requests)
+	assert.Equal(t, 1, enroll.Errors)
+	assert.Equal(t, map[string]int{"429 RateLimit": 1}, enroll.ErrorsByType)
+	assert.Equal(t, 3, report.Operations[opAck].Requests)
+	assert.Equal(t, 3, report.Operations[opArtifact].Requests)
+	checkin := report.Operations[opCheckin]
+	assert.Greater(t, checkin.Requests, 3)
+	assert.Zero(t, checkin.Errors)
+	assert.LessOrEqual(t, checkin.Latency.P50, checkin.Latency.Max)
+	assert.Contains(t, progress.String(), "/3 enroll=")
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "Agents enrolled: 3/3")
+	assert.Contains(t, text.String(), "enroll errors 429 RateLimit: 1")
+}
+
+func TestRunConfig(t *testing.T) {
+	_, err := Run(context.Background(), Config{EnrollmentToken: "token", Agents: 1})
+	assert.EqualError(t, err, "the URL of the fleet-server is required")
+	_, err = Run(context.Background(), Config{URL: "http://localhost:8220", Agents: 1})
+	assert.EqualError(t, err, "an enrollment token is required")
+	_, err = Run(context.Background(), Config{URL: "http://localhost:8220", EnrollmentToken: "token"})
+	assert.EqualError(t, err, "the number of agents must be positive, got 0")
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 50.0, percentile(latencies, 50))
+	assert.Equal(t, 99.0, percentile(latencies, 99))
+	assert.Equal(t, 5.0, percentile(latencies[:5], 90))
+	assert.Equal(t, 1.0, percentile(latencies[:1], 50))
+}
@@ -0,0 +1,174 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// operations are the operations in the order of the reports.
+var operations = []string{opEnroll, opCheckin, opAck, opArtifact}
+
+// opStats are the latencies and the errors of the requests of an operation.
+type opStats struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	// errors counts the errors by status and name, like "429 RateLimit", the errors without response are counted as
+	// "transport".
+	errors map[string]int
+}
+
+func (s *opStats) record(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, d)
+	if err == nil {
+		return
+	}
+	if s.errors == nil {
+		s.errors = make(map[string]int)
+	}
+	key := "transport"
+	var herr *httpError
+	if errors.As(err, &herr) {
+		key = herr.Error()
+	}
+	s.errors[key]++
+}
+
+func (s *opStats) report(elapsed time.Duration) OperationReport {
+	s.mu.Lock()
+	latencies := make([]time.Duration, len(s.latencies))
+	copy(latencies, s.latencies)
+	r := OperationReport{Requests: len(latencies)}
+	for key, n := range s.errors {
+		if r.ErrorsByType == nil {
+			r.ErrorsByType = make(map[string]int, len(s.errors))
+		}
+		r.ErrorsByType[key] = n
+		r.Errors += n
+	}
+	s.mu.Unlock()
+
+	if elapsed > 0 {
+		r.RatePerSecond = float64(r.Requests) / elapsed.Seconds()
+	}
+	if len(latencies) == 0 {
+		return r
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	r.Latency = LatencyReport{
+		P50: percentile(latencies, 50),
+		P90: percentile(latencies, 90),
+		P99: percentile(latencies, 99),
+		Max: milliseconds(latencies[len(latencies)-1]),
+	}
+	return r
+}
+
+// percentile returns the nearest-rank percentile in milliseconds of the sorted latencies.
+func percentile(sorted []time.Duration, p int) float64 {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return milliseconds(sorted[i])
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Report is the result of a load test.
+type Report struct {
+	Agents   int `json:"agents"`
+	Enrolled int `json:"enrolled"`
+	// DurationSeconds is the time elapsed since the start of the test.
+	DurationSeconds float64                    `json:"duration_seconds"`
+	Operations      map[string]OperationReport `json:"operations"`
+}
+
+// OperationReport are the statistics of the requests of an operation, the failed requests are included in the
+// latencies.
+type OperationReport struct {
+	Requests      int            `json:"requests"`
+	Errors        int            `json:"errors"`
+	ErrorsByType  map[string]int `json:"errors_by_type,omitempty"`
+	RatePerSecond float64        `json:"rate_per_second"`
+	Latency       LatencyReport  `json:"latency_ms"`
+}
+
+// LatencyReport are the percentiles of the latencies of an operation in milliseconds.
+type LatencyReport struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func (lt *loadTest) report() *Report {
+	elapsed := time.Since(lt.start)
+	lt.mu.Lock()
+	enrolled := lt.enrolled
+	lt.mu.Unlock()
+	r := &Report{
+		Agents:          lt.cfg.Agents,
+		Enrolled:        enrolled,
+		DurationSeconds: elapsed.Seconds(),
+		Operations:      make(map[string]OperationReport, len(lt.stats)),
+	}
+	for op, s := range lt.stats {
+		r.Operations[op] = s.report(elapsed)
+	}
+	return r
+}
+
+// summary is the one line summary of the report written while the test runs.
+func (r *Report) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s enrolled=%d/%d", time.Duration(r.DurationSeconds*float64(time.Second)).Round(time.Second), r.Enrolled, r.Agents)
+	for _, op := range operations {
+		o := r.Operations[op]
+		fmt.Fprintf(&b, " %s=%d", op, o.Requests)
+		if o.Errors > 0 {
+			fmt.Fprintf(&b, "(%d errors)", o.Errors)
+		}
+	}
+	return b.String()
+}
+
+// WriteText writes the report as a table of the operations followed by their errors.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Agents enrolled: %d/%d in %.0fs\n\n", r.Enrolled, r.Agents, r.DurationSeconds)
+	fmt.Fprintln(tw, "OPERATION\tREQUESTS\tERRORS\tRATE/S\tP50 MS\tP90 MS\tP99 MS\tMAX MS")
+	for _, op := range operations {
+		o := r.Operations[op]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.1f\t%.1f\t%.1f\t%.1f\n", op, o.Requests, o.Errors, o.RatePerSecond,
+			o.Latency.P50, o.Latency.P90, o.Latency.P99, o.Latency.Max)
+	}
+	for _, op := range operations {
+		o := r.Operations[op]
+		keys := make([]string, 0, len(o.ErrorsByType))
+		for key := range o.ErrorsByType {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "\n%s errors %s: %d", op, key, o.ErrorsByType[key])
+		}
+	}
+	if _, err := fmt.Fprintln(tw); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
@@ -27,6 +27,7 @@ const (
 	ECSURLFull   = "url.full"
 	ECSURLDomain = "url.domain"
 	ECSURLPort   = "url.port"
+	ECSURLPath   = "url.path"
 
 	// Client
 	ECSClientAddress = "client.address"
@@ -51,6 +52,8 @@ const (
 
 	// Event
 	ECSEventDuration = "event.duration"
+	ECSEventAction   = "event.action"
+	ECSEventOutcome  = "event.outcome"
 
 	// Service
 	ECSServiceName = "service.name"
@@ -65,10 +68,14 @@ const (
 	AgentID               = "fleet.agent.id"
 	EnrollAPIKeyID        = "fleet.enroll.apikey.id"
 	AccessAPIKeyID        = "fleet.access.apikey.id"
+	AccessServiceToken    = "fleet.access.service_token"
 	DefaultOutputAPIKeyID = "fleet.default.apikey.id"
 	ActionID              = "fleet.action.id"
 	ActionType            = "fleet.action.type"
 	PolicyOutputName      = "fleet.policy.output.name"
 	RevisionIdx           = "fleet.revision_idx"
 	CoordinatorIdx        = "fleet.coordinator_idx"
+	Route                 = "fleet.route"
+	ESDuration            = "fleet.elasticsearch.duration"
+	HandlerDuration       = "fleet.handler.duration"
 )
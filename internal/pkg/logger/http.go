@@ -89,6 +89,11 @@ func (rc *ResponseCounter) Count() uint64 {
 	return atomic.LoadUint64(&rc.count)
 }
 
+// StatusCode returns the status code of the response, 0 if the response is not written yet.
+func (rc *ResponseCounter) StatusCode() int {
+	return rc.statusCode
+}
+
 type ctxTSKey struct{}
 
 // CtxStartTime returns the start time associated with a context
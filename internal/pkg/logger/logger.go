@@ -148,6 +148,10 @@ func getOutput(cfg *config.Config) (out io.Writer, wr WriterSync, err error) {
 		out = io.Discard
 		wr = &nopSync{}
 	}
+	if err == nil {
+		// the recent entries are kept for the diagnostics
+		out = io.MultiWriter(out, gRecent)
+	}
 
 	return //nolint:nakedret // short function
 }
@@ -120,3 +120,17 @@ func Test_Logger_Reload(t *testing.T) {
 		assert.NotEmpty(t, b, "expected something to be written")
 	})
 }
+
+func TestRecentLogs(t *testing.T) {
+	r := newRecentLogs(2)
+	assert.Empty(t, r.get())
+
+	_, _ = r.Write([]byte("first"))
+	assert.Equal(t, [][]byte{[]byte("first")}, r.get())
+
+	buf := []byte("second")
+	_, _ = r.Write(buf)
+	copy(buf, "reused")
+	_, _ = r.Write([]byte("third"))
+	assert.Equal(t, [][]byte{[]byte("second"), []byte("third")}, r.get(), "only the last entries are kept")
+}
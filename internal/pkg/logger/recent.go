@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package logger
+
+import (
+	"sync"
+)
+
+// kRecentLogs is the number of log entries kept in memory for the diagnostics.
+const kRecentLogs = 1000
+
+var gRecent = newRecentLogs(kRecentLogs)
+
+// recentLogs is a writer keeping the last entries written by the logger, whatever its output.
+type recentLogs struct {
+	mut     sync.Mutex
+	entries [][]byte
+	next    int
+}
+
+func newRecentLogs(size int) *recentLogs {
+	return &recentLogs{entries: make([][]byte, 0, size)}
+}
+
+// Write stores a log entry, zerolog writes an entry per call.
+func (r *recentLogs) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, entry)
+	} else {
+		r.entries[r.next] = entry
+		r.next = (r.next + 1) % len(r.entries)
+	}
+	return len(p), nil
+}
+
+func (r *recentLogs) get() [][]byte {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	entries := make([][]byte, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	return append(entries, r.entries[:r.next]...)
+}
+
+// RecentLogs returns the last entries logged at the log level in effect, the oldest first.
+func RecentLogs() [][]byte {
+	return gRecent.get()
+}
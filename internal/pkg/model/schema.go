@@ -122,6 +122,9 @@ type Agent struct {
 	// ID of the API key the Elastic Agent must used to contact Fleet Server
 	AccessAPIKeyID string `json:"access_api_key_id,omitempty"`
 
+	// Identity of the Elasticsearch service token the Elastic Agent must use to contact Fleet Server, in the service-account/token-name format
+	AccessServiceToken string `json:"access_service_token,omitempty"`
+
 	// The last acknowledged action sequence number for the Elastic Agent
 	ActionSeqNo []int64 `json:"action_seq_no,omitempty"`
 
@@ -129,6 +132,9 @@ type Agent struct {
 	Active bool           `json:"active"`
 	Agent  *AgentMetadata `json:"agent,omitempty"`
 
+	// Identity of the client certificate the Elastic Agent must use to contact Fleet Server, the subject or the SHA-256 of the public key of the certificate it enrolled with, prefixed by its kind
+	ClientCertificate string `json:"client_certificate,omitempty"`
+
 	// Elastic Agent components detailed status information
 	Components []ComponentsItems `json:"components,omitempty"`
 
@@ -162,6 +168,9 @@ type Agent struct {
 	// Local metadata information for the Elastic Agent
 	LocalMetadata json.RawMessage `json:"local_metadata,omitempty"`
 
+	// The data stream namespace that replaces the namespace of the policy inputs for the Elastic Agent
+	NamespaceOverride string `json:"namespace_override,omitempty"`
+
 	// Namespaces
 	Namespaces []string `json:"namespaces,omitempty"`
 
@@ -189,6 +198,9 @@ type Agent struct {
 	// User provided tags for the Elastic Agent
 	Tags []string `json:"tags,omitempty"`
 
+	// Date/time the Elastic Agent document became a tombstone, its API keys are invalidated and it is deleted after the tombstone retention
+	TombstonedAt string `json:"tombstoned_at,omitempty"`
+
 	// Type
 	Type string `json:"type"`
 
@@ -264,6 +276,9 @@ type Artifact struct {
 	// Human readable artifact identifier
 	Identifier string `json:"identifier"`
 
+	// Namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+
 	// Name of the package that owns this artifact
 	PackageName string `json:"package_name,omitempty"`
 }
@@ -462,6 +477,9 @@ type PolicyOutput struct {
 	// API key the Elastic Agent uses to authenticate with elasticsearch
 	APIKey string `json:"api_key"`
 
+	// Date/time the API key was created, used to rotate the API key once it reaches its maximum age
+	APIKeyCreatedAt string `json:"api_key_created_at,omitempty"`
+
 	// ID of the API key the Elastic Agent uses to authenticate with elasticsearch
 	APIKeyID string `json:"api_key_id"`
 
@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitor
+
+import "time"
+
+// backoff is the delay between consecutive failed requests to elasticsearch.
+// It doubles on every failure, up to max, and goes back to min once a request succeeds.
+type backoff struct {
+	min time.Duration
+	max time.Duration
+	cur time.Duration
+}
+
+func newBackoff(minDelay, maxDelay time.Duration) *backoff {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return &backoff{min: minDelay, max: maxDelay}
+}
+
+// Next returns the delay before the next attempt.
+func (b *backoff) Next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.min
+	} else {
+		b.cur = min(2*b.cur, b.max)
+	}
+	return b.cur
+}
+
+// Reset is called once a request succeeds.
+func (b *backoff) Reset() {
+	b.cur = 0
+}
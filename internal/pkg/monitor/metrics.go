@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitor
+
+import "sync"
+
+// Gauge is set to the last value measured by a monitor.
+type Gauge interface {
+	Set(v float64)
+}
+
+// Metrics are the metrics the index monitors report to, nil entries are ignored.
+type Metrics struct {
+	// Lag is the checkpoint lag by index: the number of sequence numbers between the global checkpoint of the index
+	// and the checkpoint of the documents the monitor processed. It grows when the documents are not fetched or
+	// their subscribers do not keep up.
+	Lag map[string]Gauge
+}
+
+var (
+	metricsMut sync.RWMutex
+	metrics    Metrics
+)
+
+// SetMetrics sets the metrics the index monitors report to.
+func SetMetrics(m Metrics) {
+	metricsMut.Lock()
+	defer metricsMut.Unlock()
+	metrics = m
+}
+
+func getMetrics() Metrics {
+	metricsMut.RLock()
+	defer metricsMut.RUnlock()
+	return metrics
+}
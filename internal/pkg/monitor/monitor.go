@@ -20,6 +20,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/gcheckpt"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sleep"
 	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/rs/zerolog"
@@ -39,12 +40,15 @@ const (
 	// One action can be split up into multiple documents up to the 1000 agents per action if needed.
 	defaultFetchSize = 1000
 
-	// Retry delay on error waiting on the global checkpoint update.
+	// Retry delays on error waiting on the global checkpoint update or fetching the documents.
 	// This is the wait time between requests to elastisearch in case if:
 	// 1. Index is not found (index is created only on the first document save)
 	// 2. Any other error waiting on global checkpoint, except timeouts.
+	// 3. Any error fetching the documents up to the new checkpoint.
+	// The delay starts at retryMinDelay and doubles on each consecutive failure up to retryMaxDelay.
 	// For the long poll timeout, start a new request as soon as possible.
-	retryDelay = 10 * time.Second
+	retryMinDelay = time.Second
+	retryMaxDelay = 30 * time.Second
 )
 
 const (
@@ -80,7 +84,6 @@ type simpleMonitorT struct {
 	esCli     *elasticsearch.Client
 	monCli    *elasticsearch.Client
 	tracer    *apm.Tracer
-	tmplCheck *dsl.Tmpl
 	tmplQuery *dsl.Tmpl
 
 	index          string
@@ -88,8 +91,11 @@ type simpleMonitorT struct {
 	withExpiration bool
 	fetchSize      int
 	debounceTime   time.Duration
+	retryMinDelay  time.Duration
+	retryMaxDelay  time.Duration
 
 	checkpoint sqn.SeqNo    // index global checkpoint
+	global     sqn.SeqNo    // last global checkpoint of the index, the lag is the distance to checkpoint
 	mx         sync.RWMutex // checkpoint mutex
 
 	log zerolog.Logger
@@ -113,6 +119,8 @@ func NewSimple(index string, esCli, monCli *elasticsearch.Client, opts ...Option
 		withExpiration: defaultWithExpiration,
 		fetchSize:      defaultFetchSize,
 		debounceTime:   0,
+		retryMinDelay:  retryMinDelay,
+		retryMaxDelay:  retryMaxDelay,
 		checkpoint:     sqn.DefaultSeqNo,
 		outCh:          make(chan []es.HitT, 1),
 	}
@@ -121,12 +129,6 @@ func NewSimple(index string, esCli, monCli *elasticsearch.Client, opts ...Option
 		opt(m)
 	}
 
-	tmplCheck, err := m.prepareCheckQuery()
-	if err != nil {
-		return nil, err
-	}
-	m.tmplCheck = tmplCheck
-
 	tmplQuery, err := m.prepareQuery()
 	if err != nil {
 		return nil, err
@@ -178,6 +180,16 @@ func WithDebounceTime(dur time.Duration) Option {
 	}
 }
 
+// WithRetryDelay sets the bounds of the delay between requests to elasticsearch after a failure.
+func WithRetryDelay(minDelay, maxDelay time.Duration) Option {
+	return func(m SimpleMonitor) {
+		if minDelay > 0 {
+			m.(*simpleMonitorT).retryMinDelay = minDelay
+			m.(*simpleMonitorT).retryMaxDelay = maxDelay
+		}
+	}
+}
+
 // Output returns the output channel for the monitor.
 func (m *simpleMonitorT) Output() <-chan []es.HitT {
 	return m.outCh
@@ -193,6 +205,28 @@ func (m *simpleMonitorT) storeCheckpoint(val sqn.SeqNo) {
 	m.mx.Lock()
 	defer m.mx.Unlock()
 	m.checkpoint = val.Clone()
+	m.reportLag()
+}
+
+// storeGlobal records the global checkpoint of the index returned by Elasticsearch.
+func (m *simpleMonitorT) storeGlobal(val sqn.SeqNo) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.global = val.Clone()
+	m.reportLag()
+}
+
+// reportLag reports the distance between the global checkpoint and the checkpoint, m.mx must be held.
+func (m *simpleMonitorT) reportLag() {
+	g := getMetrics().Lag[m.index]
+	if g == nil || !m.global.IsSet() {
+		return
+	}
+	lag := m.global.Value() - m.checkpoint.Value()
+	if lag < 0 {
+		lag = 0
+	}
+	g.Set(float64(lag))
 }
 
 func (m *simpleMonitorT) loadCheckpoint() sqn.SeqNo {
@@ -205,19 +239,41 @@ func (m *simpleMonitorT) loadCheckpoint() sqn.SeqNo {
 func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 	m.log = zerolog.Ctx(ctx).With().Str("index", m.index).Str("ctx", "index monitor").Logger()
 	m.log.Info().Msg("starting index monitor")
+	timeline.Record(timeline.KindMonitor, "index monitor started", map[string]string{"index": m.index})
 	defer func() {
 		if errors.Is(err, context.Canceled) {
 			err = nil
 		}
 		m.log.Info().Err(err).Msg("index monitor exited")
+		fields := map[string]string{"index": m.index}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		timeline.Record(timeline.KindMonitor, "index monitor exited", fields)
 	}()
 
+	// unreachable is set while the monitor fails to reach Elasticsearch, so only the transitions are recorded
+	unreachable := false
+	setReachable := func(reachable bool, err error) {
+		if reachable == !unreachable {
+			return
+		}
+		unreachable = !reachable
+		if reachable {
+			timeline.Record(timeline.KindElasticsearch, "index monitor reached elasticsearch again", map[string]string{"index": m.index})
+		} else {
+			timeline.Record(timeline.KindElasticsearch, "index monitor failed to reach elasticsearch", map[string]string{"index": m.index, "error": err.Error()})
+		}
+	}
+
 	defer func() {
 		if m.readyCh != nil {
 			m.readyCh <- err
 		}
 	}()
 
+	retry := newBackoff(m.retryMinDelay, m.retryMaxDelay)
+
 	// Get initial global checkpoint
 	var trans *apm.Transaction
 	for {
@@ -229,8 +285,10 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 		checkpoint, err := gcheckpt.Query(sCtx, m.monCli, m.index)
 		span.End()
 		if err != nil {
-			m.log.Warn().Err(err).Msg("failed to initialize the global checkpoints, will retry")
-			err = sleep.WithContext(ctx, retryDelay)
+			delay := retry.Next()
+			m.log.Warn().Err(err).Dur("retry_delay", delay).Msg("failed to initialize the global checkpoints, will retry")
+			setReachable(false, err)
+			err = sleep.WithContext(ctx, delay)
 			if err != nil {
 				if m.tracer != nil {
 					trans.End()
@@ -243,6 +301,9 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 			continue
 		}
 
+		retry.Reset()
+		setReachable(true, nil)
+		m.storeGlobal(checkpoint)
 		m.storeCheckpoint(checkpoint)
 		m.log.Debug().Ints64("checkpoint", checkpoint).Msg("initial checkpoint")
 
@@ -272,11 +333,14 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 		newCheckpoint, err := gcheckpt.WaitAdvance(gCtx, m.monCli, m.index, checkpoint, m.pollTimeout)
 		span.End()
 		if err != nil {
+			delay := retry.Next()
 			if errors.Is(err, es.ErrIndexNotFound) {
 				// Wait until created
-				m.log.Debug().Msgf("index not found, poll again in %v", retryDelay)
+				m.log.Debug().Msgf("index not found, poll again in %v", delay)
 			} else if errors.Is(err, es.ErrTimeout) {
 				// Timed out, wait again
+				retry.Reset()
+				setReachable(true, nil)
 				m.log.Debug().Msg("timeout on global checkpoints advance, poll again")
 				// Loop back to the checkpoint "wait advance" without delay
 				if m.tracer != nil {
@@ -292,11 +356,12 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 				return err
 			} else {
 				// Log the error and keep trying
-				m.log.Info().Err(err).Msg("failed on waiting for global checkpoints advance")
+				m.log.Info().Err(err).Dur("retry_delay", delay).Msg("failed on waiting for global checkpoints advance")
+				setReachable(false, err)
 			}
 
 			// Delay next attempt
-			err = sleep.WithContext(ctx, retryDelay)
+			err = sleep.WithContext(ctx, delay)
 			if err != nil {
 				if m.tracer != nil {
 					trans.End()
@@ -310,6 +375,9 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 			continue
 		}
 
+		setReachable(true, nil)
+		m.storeGlobal(newCheckpoint)
+
 		// This is an example of steps for fetching the documents without "holes" (not-yet-indexed documents in between)
 		// as recommended by Elasticsearch team on August 25th, 2021
 		// 1. Call Global checkpoints = 5
@@ -321,23 +389,26 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 		// Fetch up to the new checkpoint.
 		//
 		// The fetch happens at least once.
-		// The fetch repeats until there is no more documents to fetch.
+		// The documents are fetched sorted by _seq_no, each fetch continues after the _seq_no of the last fetched document
+		// until there is no more documents to fetch.
 
 		// Set count to max fetch size (m.fetchSize) initially, so the fetch happens at least once.
+		var (
+			total    int
+			fetchErr error
+		)
 		count := m.fetchSize
 		for count == m.fetchSize {
 			// Fetch the documents between the last known checkpoint and the new checkpoint value received from "wait advance".
-			hits, err := m.fetch(ctx, checkpoint, newCheckpoint)
-			if err != nil {
-				m.log.Error().Err(err).Msg("failed checking new documents")
-				if m.tracer != nil {
-					trans.End()
-				}
+			var hits []es.HitT
+			hits, fetchErr = m.fetch(ctx, checkpoint, newCheckpoint)
+			if fetchErr != nil {
 				break
 			}
 
 			// Notify call updates m.checkpoint as max(_seq_no) from the fetched hits
 			count = m.notify(ctx, hits)
+			total += count
 			m.log.Debug().Int("count", count).Msg("hits found after notify")
 
 			// If the number of fetched documents is the same as the max fetch size, then it's possible there are more documents to fetch.
@@ -360,7 +431,18 @@ func (m *simpleMonitorT) Run(ctx context.Context) (err error) {
 		if m.tracer != nil {
 			trans.End()
 		}
-		if m.debounceTime > 0 {
+		if fetchErr != nil {
+			// The checkpoint was not advanced past the documents that failed to be fetched,
+			// so "wait advance" returns right away; delay the next attempt.
+			delay := retry.Next()
+			m.log.Error().Err(fetchErr).Dur("retry_delay", delay).Msg("failed checking new documents")
+			if err := sleep.WithContext(ctx, delay); err != nil {
+				return err
+			}
+			continue
+		}
+		retry.Reset()
+		if m.debounceTime > 0 && total > 0 {
 			m.log.Debug().Dur("debounce_time", m.debounceTime).Msg("monitor debounce start")
 			// Introduce a debounce time before wait advance (the signal for new docs in the index)
 			// This is specifically done so we can introduce a delay in for cases like rapid policy changes
@@ -390,7 +472,7 @@ func (m *simpleMonitorT) notify(ctx context.Context, hits []es.HitT) int {
 func (m *simpleMonitorT) fetch(ctx context.Context, checkpoint, maxCheckpoint sqn.SeqNo) ([]es.HitT, error) {
 	now := time.Now().UTC().Format(time.RFC3339)
 
-	// Run check query that detects that there are new documents available
+	// Fetch the documents after the checkpoint, up to the max checkpoint
 	params := map[string]interface{}{
 		dl.FieldSeqNo:    checkpoint.Value(),
 		dl.FieldMaxSeqNo: maxCheckpoint.Value(),
@@ -446,45 +528,26 @@ func (m *simpleMonitorT) search(ctx context.Context, tmpl *dsl.Tmpl, params map[
 	return esres.Hits.Hits, nil
 }
 
-// Prepares minimal query to do the quick check without reading all matches full documents
-func (m *simpleMonitorT) prepareCheckQuery() (*dsl.Tmpl, error) {
-	tmpl, root := m.prepareCommon(false)
-
-	root.Source().Includes(dl.FieldSeqNo)
-	root.Size(1)
-
-	if err := tmpl.Resolve(root); err != nil {
-		return nil, err
-	}
-	return tmpl, nil
-}
-
-// Prepares full documents query
+// Prepares full documents query.
+// The documents are sorted by _seq_no and paged with search_after, so each fetch resumes right after the last
+// document seen by the monitor.
 func (m *simpleMonitorT) prepareQuery() (*dsl.Tmpl, error) {
-	tmpl, root := m.prepareCommon(true)
-	root.Size(uint64(m.fetchSize))
-	root.Sort().SortOrder(fieldSeqNo, dsl.SortAscend)
-
-	if err := tmpl.Resolve(root); err != nil {
-		return nil, err
-	}
-	return tmpl, nil
-}
-
-func (m *simpleMonitorT) prepareCommon(limitMax bool) (*dsl.Tmpl, *dsl.Node) {
 	tmpl := dsl.NewTmpl()
 
 	root := dsl.NewRoot()
 	root.Param(seqNoPrimaryTerm, true)
+	root.Size(uint64(m.fetchSize))
+	root.Sort().SortOrder(fieldSeqNo, dsl.SortAscend)
+	root.SearchAfter(tmpl.Bind(fieldSeqNo))
 
 	filter := root.Query().Bool().Filter()
-	filter.Range(fieldSeqNo, dsl.WithRangeGT(tmpl.Bind(fieldSeqNo)))
-	if limitMax {
-		filter.Range(fieldSeqNo, dsl.WithRangeLTE(tmpl.Bind(fieldMaxSeqNo)))
-	}
+	filter.Range(fieldSeqNo, dsl.WithRangeLTE(tmpl.Bind(fieldMaxSeqNo)))
 	if m.withExpiration {
 		filter.Range(fieldExpiration, dsl.WithRangeGT(tmpl.Bind(fieldExpiration)))
 	}
 
-	return tmpl, root
+	if err := tmpl.Resolve(root); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
 }
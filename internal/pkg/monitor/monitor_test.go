@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareQuery(t *testing.T) {
+	tests := []struct {
+		name           string
+		withExpiration bool
+		params         map[string]interface{}
+		expect         string
+	}{{
+		name:   "search after checkpoint",
+		params: map[string]interface{}{fieldSeqNo: 5, fieldMaxSeqNo: 10},
+		expect: `{"seq_no_primary_term":true,"size":1000,"sort":["_seq_no"],"search_after":[5],
+			"query":{"bool":{"filter":[{"range":{"_seq_no":{"lte":10}}}]}}}`,
+	}, {
+		name:           "with expiration",
+		withExpiration: true,
+		params:         map[string]interface{}{fieldSeqNo: -1, fieldMaxSeqNo: 3, fieldExpiration: "2024-01-01T00:00:00Z"},
+		expect: `{"seq_no_primary_term":true,"size":1000,"sort":["_seq_no"],"search_after":[-1],
+			"query":{"bool":{"filter":[{"range":{"_seq_no":{"lte":3}}},{"range":{"expiration":{"gt":"2024-01-01T00:00:00Z"}}}]}}}`,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewSimple("test", nil, nil, WithExpiration(tc.withExpiration))
+			require.NoError(t, err)
+
+			query, err := m.(*simpleMonitorT).tmplQuery.Render(tc.params)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(query))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 5*time.Second)
+	assert.Equal(t, time.Second, b.Next())
+	assert.Equal(t, 2*time.Second, b.Next())
+	assert.Equal(t, 4*time.Second, b.Next())
+	assert.Equal(t, 5*time.Second, b.Next())
+	assert.Equal(t, 5*time.Second, b.Next())
+
+	b.Reset()
+	assert.Equal(t, time.Second, b.Next())
+}
+
+type testGauge struct {
+	v float64
+}
+
+func (g *testGauge) Set(v float64) {
+	g.v = v
+}
+
+func TestCheckpointLag(t *testing.T) {
+	g := &testGauge{v: -1}
+	SetMetrics(Metrics{Lag: map[string]Gauge{"index": g}})
+	t.Cleanup(func() { SetMetrics(Metrics{}) })
+
+	m := &simpleMonitorT{index: "index", checkpoint: []int64{-1}}
+	m.storeCheckpoint([]int64{3})
+	assert.Equal(t, float64(-1), g.v, "the lag is not reported before the global checkpoint is known")
+
+	m.storeGlobal([]int64{10})
+	assert.Equal(t, float64(7), g.v)
+	m.storeCheckpoint([]int64{10})
+	assert.Equal(t, float64(0), g.v)
+	m.storeCheckpoint([]int64{12})
+	assert.Equal(t, float64(0), g.v, "the checkpoint can be ahead of the last global checkpoint")
+}
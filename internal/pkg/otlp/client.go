@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package otlp exports the traces and the metrics of fleet-server with the OpenTelemetry protocol over HTTP,
+// using the JSON encoding of the protocol.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const (
+	envEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envHeaders  = "OTEL_EXPORTER_OTLP_HEADERS"
+
+	defaultEndpoint = "http://localhost:4318"
+	defaultTimeout  = 10 * time.Second
+
+	scopeName = "github.com/elastic/fleet-server"
+)
+
+// client sends the export requests to an OTLP/HTTP receiver.
+type client struct {
+	endpoint string
+	headers  map[string]string
+	http     *http.Client
+}
+
+func newClient(cfg config.OTLP) (*client, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(envEndpoint)
+	}
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid otlp endpoint: %w", err)
+	}
+
+	headers, err := parseHeaders(os.Getenv(envHeaders))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+
+	tlsConfig, err := cfg.TLS.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck // the default transport is an *http.Transport
+	transport.TLSClientConfig = tlsConfig
+
+	return &client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+		http:     &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// parseHeaders parses the headers of OTEL_EXPORTER_OTLP_HEADERS, a comma separated list of key=value pairs.
+func parseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s header %q", envHeaders, pair)
+		}
+		v, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header %q: %w", envHeaders, pair, err)
+		}
+		headers[strings.TrimSpace(k)] = v
+	}
+	return headers, nil
+}
+
+// post sends the export request body to the signal path, like /v1/traces.
+func (c *client) post(ctx context.Context, path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export to %s failed with status %d: %s", path, resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// The JSON encoding of the messages of the protocol, only the fields fleet-server uses are defined.
+// The 64-bit integers are encoded as strings.
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func attr(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: value}}
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+// newResource returns the resource of the service, the empty attributes are left out.
+func newResource(name, version, environment string) resource {
+	r := resource{Attributes: []keyValue{attr("service.name", name)}}
+	if version != "" {
+		r.Attributes = append(r.Attributes, attr("service.version", version))
+	}
+	if environment != "" {
+		r.Attributes = append(r.Attributes, attr("deployment.environment", environment))
+	}
+	return r
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
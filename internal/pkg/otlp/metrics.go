@@ -0,0 +1,240 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package otlp
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const (
+	metricsPath = "/v1/metrics"
+
+	defaultMetricsInterval = 60 * time.Second
+
+	// aggregationTemporalityCumulative is the temporality of the prometheus counters and histograms.
+	aggregationTemporalityCumulative = 2
+)
+
+// MetricsExporter periodically exports the metrics of a prometheus registry, like the registry of the
+// /metrics endpoint, to an OTLP receiver.
+type MetricsExporter struct {
+	client   *client
+	gatherer prometheus.Gatherer
+	interval time.Duration
+	resource resource
+	version  string
+	start    time.Time
+}
+
+// NewMetricsExporter returns an exporter of the metrics of gatherer to the OTLP receiver of cfg.
+func NewMetricsExporter(cfg config.OTLP, gatherer prometheus.Gatherer, version, environment string) (*MetricsExporter, error) {
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.MetricsInterval
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+	return &MetricsExporter{
+		client:   c,
+		gatherer: gatherer,
+		interval: interval,
+		resource: newResource("fleet-server", version, environment),
+		version:  version,
+		start:    time.Now(),
+	}, nil
+}
+
+// Run exports the metrics at the configured interval until ctx is done, the metrics are exported a last time then.
+// The failed exports are only logged.
+func (e *MetricsExporter) Run(ctx context.Context) error {
+	log := zerolog.Ctx(ctx)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), e.client.http.Timeout)
+			defer cancel()
+			if err := e.export(flushCtx); err != nil {
+				log.Warn().Err(err).Msg("Failed to export the last metrics")
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.export(ctx); err != nil {
+				log.Warn().Err(err).Msg("Failed to export metrics")
+			}
+		}
+	}
+}
+
+type exportMetricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Sum         *sum       `json:"sum,omitempty"`
+	Gauge       *gauge     `json:"gauge,omitempty"`
+	Histogram   *histogram `json:"histogram,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type histogram struct {
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	Count             uint64     `json:"count,string"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
+
+func (e *MetricsExporter) export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	req := exportMetricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: e.resource,
+		ScopeMetrics: []scopeMetrics{{
+			Scope:   scope{Name: scopeName, Version: e.version},
+			Metrics: convertFamilies(families, e.start, time.Now()),
+		}},
+	}}}
+	return e.client.post(ctx, metricsPath, req)
+}
+
+// convertFamilies converts the prometheus metric families, the summaries and the values that are not finite are left out.
+func convertFamilies(families []*dto.MetricFamily, start, now time.Time) []metric {
+	startNano := uint64(start.UnixNano())
+	nowNano := uint64(now.UnixNano())
+	metrics := make([]metric, 0, len(families))
+	for _, f := range families {
+		m := metric{Name: f.GetName(), Description: f.GetHelp()}
+		switch f.GetType() {
+		case dto.MetricType_COUNTER:
+			m.Sum = &sum{AggregationTemporality: aggregationTemporalityCumulative, IsMonotonic: true}
+			for _, pm := range f.GetMetric() {
+				if v := pm.GetCounter().GetValue(); finite(v) {
+					m.Sum.DataPoints = append(m.Sum.DataPoints, numberDataPoint{
+						Attributes:        labels(pm),
+						StartTimeUnixNano: startNano,
+						TimeUnixNano:      nowNano,
+						AsDouble:          v,
+					})
+				}
+			}
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			m.Gauge = &gauge{}
+			for _, pm := range f.GetMetric() {
+				v := pm.GetGauge().GetValue()
+				if f.GetType() == dto.MetricType_UNTYPED {
+					v = pm.GetUntyped().GetValue()
+				}
+				if finite(v) {
+					m.Gauge.DataPoints = append(m.Gauge.DataPoints, numberDataPoint{
+						Attributes:   labels(pm),
+						TimeUnixNano: nowNano,
+						AsDouble:     v,
+					})
+				}
+			}
+		case dto.MetricType_HISTOGRAM:
+			m.Histogram = &histogram{AggregationTemporality: aggregationTemporalityCumulative}
+			for _, pm := range f.GetMetric() {
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramPoint(pm, startNano, nowNano))
+			}
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// histogramPoint converts the cumulative prometheus buckets to the counts of each bucket, the last
+// bucket counts the observations over the last bound.
+func histogramPoint(pm *dto.Metric, startNano, nowNano uint64) histogramDataPoint {
+	h := pm.GetHistogram()
+	p := histogramDataPoint{
+		Attributes:        labels(pm),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      nowNano,
+		Count:             h.GetSampleCount(),
+		Sum:               h.GetSampleSum(),
+	}
+	var prev uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		p.ExplicitBounds = append(p.ExplicitBounds, b.GetUpperBound())
+		p.BucketCounts = append(p.BucketCounts, strconv.FormatUint(b.GetCumulativeCount()-prev, 10))
+		prev = b.GetCumulativeCount()
+	}
+	p.BucketCounts = append(p.BucketCounts, strconv.FormatUint(p.Count-prev, 10))
+	if !finite(p.Sum) {
+		p.Sum = 0
+	}
+	return p
+}
+
+func labels(pm *dto.Metric) []keyValue {
+	if len(pm.GetLabel()) == 0 {
+		return nil
+	}
+	attrs := make([]keyValue, 0, len(pm.GetLabel()))
+	for _, l := range pm.GetLabel() {
+		attrs = append(attrs, attr(l.GetName(), l.GetValue()))
+	}
+	return attrs
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
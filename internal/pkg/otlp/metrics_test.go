@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestMetricsExporter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "http_server_routes_checkin_total"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "bulker_active"})
+	hist := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "bulker_read_latency_seconds", Buckets: []float64{0.1, 1}})
+	info := prometheus.NewCounter(prometheus.CounterOpts{Name: "service_info", ConstLabels: prometheus.Labels{"version": "8.15.0"}})
+	reg.MustRegister(counter, gauge, hist, info)
+	counter.Add(3)
+	gauge.Set(2)
+	hist.Observe(0.05)
+	hist.Observe(0.5)
+	hist.Observe(5)
+	info.Inc()
+
+	recv := newReceiver(t)
+	exporter, err := NewMetricsExporter(config.OTLP{Endpoint: recv.URL}, reg, "8.15.0", "")
+	require.NoError(t, err)
+	require.NoError(t, exporter.export(context.Background()))
+
+	reqs := recv.received(metricsPath)
+	require.Len(t, reqs, 1)
+	var req exportMetricsRequest
+	require.NoError(t, json.Unmarshal(reqs[0], &req))
+	require.Len(t, req.ResourceMetrics, 1)
+	assert.Equal(t, newResource("fleet-server", "8.15.0", ""), req.ResourceMetrics[0].Resource)
+
+	metrics := map[string]metric{}
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+	require.Len(t, metrics, 4)
+
+	c := metrics["http_server_routes_checkin_total"]
+	require.NotNil(t, c.Sum)
+	assert.True(t, c.Sum.IsMonotonic)
+	assert.Equal(t, aggregationTemporalityCumulative, c.Sum.AggregationTemporality)
+	assert.Equal(t, 3.0, c.Sum.DataPoints[0].AsDouble)
+
+	g := metrics["bulker_active"]
+	require.NotNil(t, g.Gauge)
+	assert.Equal(t, 2.0, g.Gauge.DataPoints[0].AsDouble)
+
+	h := metrics["bulker_read_latency_seconds"]
+	require.NotNil(t, h.Histogram)
+	p := h.Histogram.DataPoints[0]
+	assert.Equal(t, uint64(3), p.Count)
+	assert.Equal(t, []float64{0.1, 1}, p.ExplicitBounds)
+	assert.Equal(t, []string{"1", "1", "1"}, p.BucketCounts, "the buckets are not cumulative")
+
+	i := metrics["service_info"]
+	require.NotNil(t, i.Sum)
+	assert.Equal(t, []keyValue{attr("version", "8.15.0")}, i.Sum.DataPoints[0].Attributes)
+}
+
+func TestMetricsExporterRun(t *testing.T) {
+	recv := newReceiver(t)
+	exporter, err := NewMetricsExporter(config.OTLP{Endpoint: recv.URL, MetricsInterval: 10 * time.Millisecond}, prometheus.NewRegistry(), "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	errCh := make(chan error)
+	go func() { errCh <- exporter.Run(ctx) }()
+	require.Eventually(t, func() bool { return len(recv.received(metricsPath)) >= 2 }, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+}
@@ -0,0 +1,253 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package otlp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	apmtransport "go.elastic.co/apm/v2/transport"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+const tracesPath = "/v1/traces"
+
+// Span kinds and status codes of the protocol.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+
+	statusCodeError = 2
+)
+
+// Transport is an APM tracer transport that exports the transactions and the spans of the tracer as OTLP
+// spans. The metrics of the tracer and the errors are not exported, the metrics are exported by MetricsExporter.
+type Transport struct {
+	client *client
+}
+
+// NewTransport returns a tracer transport exporting to the OTLP receiver of cfg.
+func NewTransport(cfg config.OTLP) (*Transport, error) {
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Transport{client: c}, nil
+}
+
+// apmMetadata, apmTransaction and apmSpan are the fields of the events of the APM intake stream that are exported.
+type apmMetadata struct {
+	Service struct {
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Environment string `json:"environment"`
+	} `json:"service"`
+}
+
+type apmTransaction struct {
+	ID        string  `json:"id"`
+	TraceID   string  `json:"trace_id"`
+	ParentID  string  `json:"parent_id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Timestamp int64   `json:"timestamp"` // microseconds since the epoch
+	Duration  float64 `json:"duration"`  // milliseconds
+	Result    string  `json:"result"`
+	Outcome   string  `json:"outcome"`
+	Sampled   *bool   `json:"sampled"`
+}
+
+type apmSpan struct {
+	ID        string  `json:"id"`
+	TraceID   string  `json:"trace_id"`
+	ParentID  string  `json:"parent_id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Subtype   string  `json:"subtype"`
+	Action    string  `json:"action"`
+	Timestamp int64   `json:"timestamp"`
+	Duration  float64 `json:"duration"`
+	Outcome   string  `json:"outcome"`
+	Context   *struct {
+		Destination json.RawMessage `json:"destination"`
+	} `json:"context"`
+}
+
+type apmEvent struct {
+	Metadata    *apmMetadata    `json:"metadata"`
+	Transaction *apmTransaction `json:"transaction"`
+	Span        *apmSpan        `json:"span"`
+}
+
+type exportTraceRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64     `json:"endTimeUnixNano,string"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	Code int `json:"code"`
+}
+
+// SendStream exports the transactions and the spans of the zlib compressed ndjson stream of the tracer.
+func (t *Transport) SendStream(ctx context.Context, r io.Reader) error {
+	req, err := decodeStream(r)
+	if err != nil {
+		return err
+	}
+	if len(req.ResourceSpans) == 0 {
+		return nil
+	}
+	return t.client.post(ctx, tracesPath, req)
+}
+
+func decodeStream(r io.Reader) (*exportTraceRequest, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("otlp transport: %w", err)
+	}
+	defer zr.Close()
+
+	var (
+		meta  apmMetadata
+		spans []span
+	)
+	br := bufio.NewReader(zr)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var ev apmEvent
+			if err := json.Unmarshal(line, &ev); err != nil {
+				return nil, fmt.Errorf("otlp transport: %w", err)
+			}
+			switch {
+			case ev.Metadata != nil:
+				meta = *ev.Metadata
+			case ev.Transaction != nil:
+				if s, ok := transactionSpan(ev.Transaction); ok {
+					spans = append(spans, s)
+				}
+			case ev.Span != nil:
+				spans = append(spans, spanSpan(ev.Span))
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("otlp transport: %w", err)
+		}
+	}
+
+	req := &exportTraceRequest{}
+	if len(spans) > 0 {
+		req.ResourceSpans = []resourceSpans{{
+			Resource: newResource(meta.Service.Name, meta.Service.Version, meta.Service.Environment),
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName, Version: meta.Service.Version},
+				Spans: spans,
+			}},
+		}}
+	}
+	return req, nil
+}
+
+// transactionSpan returns the span of a sampled transaction, a request transaction is a server span.
+func transactionSpan(tx *apmTransaction) (span, bool) {
+	if tx.Sampled != nil && !*tx.Sampled {
+		return span{}, false
+	}
+	s := newSpan(tx.TraceID, tx.ID, tx.ParentID, tx.Name, tx.Timestamp, tx.Duration, tx.Outcome)
+	s.Kind = spanKindInternal
+	if tx.Type == "request" {
+		s.Kind = spanKindServer
+	}
+	s.Attributes = appendAttr(s.Attributes, "transaction.type", tx.Type)
+	s.Attributes = appendAttr(s.Attributes, "transaction.result", tx.Result)
+	return s, true
+}
+
+// spanSpan returns the span of an APM span, a span with a destination, like a request to Elasticsearch, is a client span.
+func spanSpan(sp *apmSpan) span {
+	s := newSpan(sp.TraceID, sp.ID, sp.ParentID, sp.Name, sp.Timestamp, sp.Duration, sp.Outcome)
+	s.Kind = spanKindInternal
+	if sp.Context != nil && len(sp.Context.Destination) > 0 {
+		s.Kind = spanKindClient
+	}
+	s.Attributes = appendAttr(s.Attributes, "span.type", sp.Type)
+	s.Attributes = appendAttr(s.Attributes, "span.subtype", sp.Subtype)
+	s.Attributes = appendAttr(s.Attributes, "span.action", sp.Action)
+	return s
+}
+
+func newSpan(traceID, id, parentID, name string, timestamp int64, duration float64, outcome string) span {
+	start := uint64(max(timestamp, 0)) * 1000
+	s := span{
+		TraceID:           traceID,
+		SpanID:            id,
+		ParentSpanID:      parentID,
+		Name:              name,
+		StartTimeUnixNano: start,
+		EndTimeUnixNano:   start + uint64(max(duration, 0)*1e6),
+	}
+	if outcome == "failure" {
+		s.Status = &status{Code: statusCodeError}
+	}
+	return s
+}
+
+func appendAttr(attrs []keyValue, key, value string) []keyValue {
+	if value == "" {
+		return attrs
+	}
+	return append(attrs, attr(key, value))
+}
+
+// Tee returns a tracer transport sending the streams to all the transports, like the APM server and an OTLP receiver.
+func Tee(transports ...apmtransport.Transport) apmtransport.Transport {
+	return teeTransport(transports)
+}
+
+type teeTransport []apmtransport.Transport
+
+func (t teeTransport) SendStream(ctx context.Context, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0, len(t))
+	for _, tr := range t {
+		errs = append(errs, tr.SendStream(ctx, bytes.NewReader(b)))
+	}
+	return errors.Join(errs...)
+}
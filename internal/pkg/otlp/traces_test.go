@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// receiver is an OTLP/HTTP receiver recording the export requests.
+type receiver struct {
+	*httptest.Server
+	mut      sync.Mutex
+	requests map[string][][]byte
+	headers  http.Header
+}
+
+func newReceiver(t *testing.T) *receiver {
+	t.Helper()
+	r := &receiver{requests: make(map[string][][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil || req.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.mut.Lock()
+		r.requests[req.URL.Path] = append(r.requests[req.URL.Path], b)
+		r.headers = req.Header.Clone()
+		r.mut.Unlock()
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received(path string) [][]byte {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.requests[path]
+}
+
+func TestTransport(t *testing.T) {
+	recv := newReceiver(t)
+	transport, err := NewTransport(config.OTLP{Endpoint: recv.URL, Headers: map[string]string{"Authorization": "ApiKey secret"}})
+	require.NoError(t, err)
+
+	tracer, err := apm.NewTracerOptions(apm.TracerOptions{
+		ServiceName:        "fleet-server",
+		ServiceVersion:     "8.15.0",
+		ServiceEnvironment: "test",
+		Transport:          transport,
+	})
+	require.NoError(t, err)
+	defer tracer.Close()
+
+	tx := tracer.StartTransaction("POST /api/fleet/agents/:id/checkin", "request")
+	tx.Result = "HTTP 2xx"
+	ctx := apm.ContextWithTransaction(context.Background(), tx)
+	esSpan, _ := apm.StartSpan(ctx, "search", "db.elasticsearch")
+	esSpan.Context.SetDestinationService(apm.DestinationServiceSpanContext{Resource: "elasticsearch"})
+	esSpan.Outcome = "failure"
+	esSpan.End()
+	tx.End()
+	tracer.Flush(nil)
+
+	reqs := recv.received(tracesPath)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "ApiKey secret", recv.headers.Get("Authorization"))
+
+	var req exportTraceRequest
+	require.NoError(t, json.Unmarshal(reqs[0], &req))
+	require.Len(t, req.ResourceSpans, 1)
+	assert.Contains(t, req.ResourceSpans[0].Resource.Attributes, attr("service.name", "fleet-server"))
+	assert.Contains(t, req.ResourceSpans[0].Resource.Attributes, attr("deployment.environment", "test"))
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	byName := map[string]span{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	txSpan := byName["POST /api/fleet/agents/:id/checkin"]
+	assert.Equal(t, spanKindServer, txSpan.Kind)
+	assert.Equal(t, tx.TraceContext().Trace.String(), txSpan.TraceID)
+	assert.Contains(t, txSpan.Attributes, attr("transaction.result", "HTTP 2xx"))
+	assert.Nil(t, txSpan.Status)
+
+	search := byName["search"]
+	assert.Equal(t, spanKindClient, search.Kind)
+	assert.Equal(t, txSpan.TraceID, search.TraceID)
+	assert.Equal(t, txSpan.SpanID, search.ParentSpanID)
+	assert.Equal(t, &status{Code: statusCodeError}, search.Status)
+	assert.Contains(t, search.Attributes, attr("span.subtype", "elasticsearch"))
+	assert.GreaterOrEqual(t, search.EndTimeUnixNano, search.StartTimeUnixNano)
+}
+
+type recordTransport struct {
+	streams [][]byte
+	err     error
+}
+
+func (t *recordTransport) SendStream(_ context.Context, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	t.streams = append(t.streams, b)
+	return t.err
+}
+
+func TestTee(t *testing.T) {
+	first := &recordTransport{err: errors.New("unavailable")}
+	second := &recordTransport{}
+	err := Tee(first, second).SendStream(context.Background(), strings.NewReader("stream"))
+	assert.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, [][]byte{[]byte("stream")}, first.streams)
+	assert.Equal(t, [][]byte{[]byte("stream")}, second.streams, "the stream is sent to every transport")
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders("Authorization=Bearer%20token, x-tenant = acme,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer token", "x-tenant": "acme"}, headers)
+
+	_, err = parseHeaders("invalid")
+	assert.Error(t, err)
+
+	t.Setenv(envHeaders, "x-tenant=env,x-other=env")
+	c, err := newClient(config.OTLP{Headers: map[string]string{"x-tenant": "config"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"x-tenant": "config", "x-other": "env"}, c.headers, "the configured headers take precedence")
+	assert.Equal(t, defaultEndpoint, c.endpoint)
+}
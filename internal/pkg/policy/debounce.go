@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+)
+
+// debounceT tracks a policy change that has not been dispatched yet.
+type debounceT struct {
+	// first is when the oldest undispatched revision was received.
+	first time.Time
+	// last is when the latest revision was received.
+	last time.Time
+	// coalesced is the number of revisions received since the last dispatch.
+	coalesced int
+}
+
+// WithDispatchDebounce delays the dispatch of a changed policy until no new revision was received for window,
+// so agents only receive the latest of several revisions saved in quick succession.
+// A policy that keeps changing is dispatched at the latest maxWait after its first undispatched revision.
+func WithDispatchDebounce(window, maxWait time.Duration) MonitorOption {
+	return func(m *monitorT) {
+		m.debounceWindow = window
+		m.debounceMaxWait = maxWait
+	}
+}
+
+// debounced records that a new revision of the policy was received; it returns true if its dispatch must be delayed.
+// It must be called before the revision replaces the one stored in p, the first revision of a policy is never delayed.
+func (m *monitorT) debounced(p *policyT, now time.Time) bool {
+	if m.debounceWindow <= 0 || p.pp.Policy.CoordinatorIdx <= 0 || p.pp.Policy.PolicyID == cloudPolicyID {
+		return false
+	}
+	if p.debounce == nil {
+		p.debounce = &debounceT{first: now}
+	}
+	p.debounce.last = now
+	p.debounce.coalesced++
+
+	d := m.debounceDeadline(p.debounce).Sub(now)
+	time.AfterFunc(d, m.kickFlush)
+	return true
+}
+
+// debounceDeadline returns when a debounced policy change should be dispatched.
+func (m *monitorT) debounceDeadline(d *debounceT) time.Time {
+	deadline := d.last.Add(m.debounceWindow)
+	if m.debounceMaxWait > 0 {
+		if maxDeadline := d.first.Add(m.debounceMaxWait); maxDeadline.Before(deadline) {
+			deadline = maxDeadline
+		}
+	}
+	return deadline
+}
+
+func (m *monitorT) kickFlush() {
+	select {
+	case m.flushCh <- struct{}{}:
+	default:
+	}
+}
+
+// flushDebounced queues the subscriptions of the debounced policy changes whose deadline passed.
+// It returns true if subscriptions were queued for delivery.
+func (m *monitorT) flushDebounced(now time.Time) bool {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	nQueued := 0
+	for policyID, p := range m.policies {
+		if p.debounce == nil || now.Before(m.debounceDeadline(p.debounce)) {
+			continue
+		}
+		coalesced := p.debounce.coalesced
+		p.debounce = nil
+		m.policies[policyID] = p
+
+		n := m.queueUpdates(p)
+		m.log.Info().
+			Str(logger.PolicyID, policyID).
+			Int64(logger.RevisionIdx, p.pp.Policy.RevisionIdx).
+			Int64(logger.CoordinatorIdx, p.pp.Policy.CoordinatorIdx).
+			Int("coalesced_revisions", coalesced).
+			Int("nSubs", n).
+			Msg("Debounced policy revision added to the queue")
+		nQueued += n
+	}
+	return nQueued > 0
+}
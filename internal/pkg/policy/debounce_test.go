@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+func TestDispatchDebounce(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithDispatchDebounce(time.Minute, 5*time.Minute)).(*monitorT)
+
+	// the first revision of a policy is not delayed
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	assert.Nil(t, m.policies["policy"].debounce)
+	_, err := m.Subscribe("agent-1", "policy", 1, 1)
+	require.NoError(t, err)
+
+	m.updatePolicy(ctx, rolloutPolicy(2))
+	m.updatePolicy(ctx, rolloutPolicy(3))
+	assert.True(t, m.pendingQ.isEmpty())
+	require.NotNil(t, m.policies["policy"].debounce)
+	assert.Equal(t, 2, m.policies["policy"].debounce.coalesced)
+
+	// agents subscribing while the change is debounced wait for it as well
+	_, err = m.Subscribe("agent-2", "policy", 1, 1)
+	require.NoError(t, err)
+	assert.True(t, m.pendingQ.isEmpty())
+
+	assert.False(t, m.flushDebounced(time.Now()))
+	assert.True(t, m.flushDebounced(time.Now().Add(2*time.Minute)))
+	assert.Nil(t, m.policies["policy"].debounce)
+	assert.Equal(t, []string{"agent-1", "agent-2"}, pendingAgents(m))
+	assert.Equal(t, int64(3), m.policies["policy"].pp.Policy.RevisionIdx)
+}
+
+func TestDispatchDebounceMaxWait(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithDispatchDebounce(time.Minute, 5*time.Minute)).(*monitorT)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	_, err := m.Subscribe("agent-1", "policy", 1, 1)
+	require.NoError(t, err)
+	m.updatePolicy(ctx, rolloutPolicy(2))
+
+	// keep changing the policy within the window
+	d := m.policies["policy"].debounce
+	d.first = time.Now().Add(-5 * time.Minute)
+	m.updatePolicy(ctx, rolloutPolicy(3))
+
+	assert.True(t, m.flushDebounced(time.Now()))
+	assert.Equal(t, []string{"agent-1"}, pendingAgents(m))
+}
+
+func TestDispatchDebounceKicksFlush(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithDispatchDebounce(time.Millisecond, 0)).(*monitorT)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	m.updatePolicy(ctx, rolloutPolicy(2))
+
+	select {
+	case <-m.flushCh:
+	case <-time.After(time.Second):
+		t.Fatal("debounced policy change was never flushed")
+	}
+}
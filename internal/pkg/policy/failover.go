@@ -0,0 +1,122 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+const (
+	// FieldOutputFailover is the output attribute that holds its failover settings.
+	FieldOutputFailover = "failover"
+	// FieldOutputFallback names the output agents may fail over to, it is set in the policy.
+	FieldOutputFallback = "fallback"
+	// FieldOutputPreferred names the output agents should prefer, it is set by fleet-server when the policy is sent to an agent.
+	FieldOutputPreferred = "preferred"
+)
+
+var ErrInvalidOutputFallback = errors.New("invalid output fallback")
+
+// outputFallback returns the name of the fallback output declared by the output, or an empty string.
+func outputFallback(output map[string]interface{}) (string, error) {
+	v, ok := output[FieldOutputFailover]
+	if !ok || v == nil {
+		return "", nil
+	}
+	failover, ok := v.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be an object", ErrInvalidOutputFallback, FieldOutputFailover)
+	}
+	fallback, ok := failover[FieldOutputFallback].(string)
+	if !ok || fallback == "" {
+		return "", fmt.Errorf("%w: %s.%s must be an output name", ErrInvalidOutputFallback, FieldOutputFailover, FieldOutputFallback)
+	}
+	return fallback, nil
+}
+
+// validateFallbacks checks that the fallback of each output is another output of the policy,
+// and that fallback outputs do not declare a fallback of their own.
+func validateFallbacks(outputs map[string]Output) error {
+	for name, o := range outputs {
+		if o.Fallback == "" {
+			continue
+		}
+		fallback, ok := outputs[o.Fallback]
+		switch {
+		case o.Fallback == name:
+			return fmt.Errorf("%w: output %q is its own fallback", ErrInvalidOutputFallback, name)
+		case !ok:
+			return fmt.Errorf("%w: fallback %q of output %q not found", ErrInvalidOutputFallback, o.Fallback, name)
+		case fallback.Fallback != "":
+			return fmt.Errorf("%w: fallback %q of output %q has a fallback", ErrInvalidOutputFallback, o.Fallback, name)
+		}
+	}
+	return nil
+}
+
+// PrepareOutputs prepares all the outputs of a policy to be sent to the agent.
+//
+// The preparation of an output that has a fallback, or that is a fallback, may fail as long as the other output
+// of the pair could be prepared; the failover attribute of the primary output is then updated in outputMap to name
+// the output the agent should prefer. Any other preparation failure is returned.
+func PrepareOutputs(ctx context.Context, zlog zerolog.Logger, bulker bulk.Bulk, agent *model.Agent, outputs map[string]Output, outputMap map[string]map[string]interface{}) error {
+	isFallback := make(map[string]bool)
+	for _, o := range outputs {
+		if o.Fallback != "" {
+			isFallback[o.Fallback] = true
+		}
+	}
+
+	failed := make(map[string]error)
+	for name, o := range outputs {
+		err := o.Prepare(ctx, zlog, bulker, agent, outputMap)
+		if err == nil {
+			continue
+		}
+		if o.Fallback == "" && !isFallback[name] {
+			return fmt.Errorf("failed to prepare output %q: %w", name, err)
+		}
+		failed[name] = err
+	}
+
+	for name, o := range outputs {
+		if o.Fallback == "" {
+			continue
+		}
+		primaryErr, fallbackErr := failed[name], failed[o.Fallback]
+		preferred := name
+		switch {
+		case primaryErr != nil && fallbackErr != nil:
+			return fmt.Errorf("failed to prepare output %q and its fallback %q: %w", name, o.Fallback, errors.Join(primaryErr, fallbackErr))
+		case primaryErr != nil:
+			preferred = o.Fallback
+			zlog.Warn().Err(primaryErr).
+				Str(logger.PolicyOutputName, name).
+				Str("fallback", o.Fallback).
+				Msg("failed to prepare output, agent will prefer its fallback")
+		case fallbackErr != nil:
+			zlog.Warn().Err(fallbackErr).
+				Str(logger.PolicyOutputName, name).
+				Str("fallback", o.Fallback).
+				Msg("failed to prepare fallback output, agent will not be able to fail over")
+		}
+		if _, ok := outputMap[name]; ok {
+			// replace the attribute rather than mutating it, it may be shared with the policy held by the monitor
+			outputMap[name][FieldOutputFailover] = map[string]interface{}{
+				FieldOutputFallback:  o.Fallback,
+				FieldOutputPreferred: preferred,
+			}
+		}
+	}
+	return nil
+}
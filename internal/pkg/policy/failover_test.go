@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestConstructPolicyOutputsFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		outputs  map[string]map[string]interface{}
+		fallback string
+		err      bool
+	}{{
+		name: "fallback",
+		outputs: map[string]map[string]interface{}{
+			"primary":   {"type": OutputTypeElasticsearch, "failover": map[string]interface{}{"fallback": "secondary"}},
+			"secondary": {"type": OutputTypeRemoteElasticsearch},
+		},
+		fallback: "secondary",
+	}, {
+		name: "no fallback",
+		outputs: map[string]map[string]interface{}{
+			"primary": {"type": OutputTypeElasticsearch},
+		},
+	}, {
+		name: "unknown fallback",
+		outputs: map[string]map[string]interface{}{
+			"primary": {"type": OutputTypeElasticsearch, "failover": map[string]interface{}{"fallback": "secondary"}},
+		},
+		err: true,
+	}, {
+		name: "own fallback",
+		outputs: map[string]map[string]interface{}{
+			"primary": {"type": OutputTypeElasticsearch, "failover": map[string]interface{}{"fallback": "primary"}},
+		},
+		err: true,
+	}, {
+		name: "chained fallback",
+		outputs: map[string]map[string]interface{}{
+			"primary":   {"type": OutputTypeElasticsearch, "failover": map[string]interface{}{"fallback": "secondary"}},
+			"secondary": {"type": OutputTypeElasticsearch, "failover": map[string]interface{}{"fallback": "primary"}},
+		},
+		err: true,
+	}, {
+		name: "malformed failover",
+		outputs: map[string]map[string]interface{}{
+			"primary": {"type": OutputTypeElasticsearch, "failover": "secondary"},
+		},
+		err: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			outputs, err := constructPolicyOutputs(tc.outputs, nil)
+			if tc.err {
+				assert.ErrorIs(t, err, ErrInvalidOutputFallback)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.fallback, outputs["primary"].Fallback)
+		})
+	}
+}
+
+func TestPrepareOutputsFailover(t *testing.T) {
+	role := &RoleT{Sha2: "fake sha", Raw: TestPayload}
+	tests := []struct {
+		name      string
+		outputs   map[string]Output
+		preferred string
+		err       bool
+	}{{
+		name: "unprepared primary prefers the fallback",
+		outputs: map[string]Output{
+			"primary":   {Name: "primary", Type: OutputTypeElasticsearch, Fallback: "secondary"},
+			"secondary": {Name: "secondary", Type: OutputTypeLogstash, Role: role},
+		},
+		preferred: "secondary",
+	}, {
+		name: "unprepared fallback keeps the primary",
+		outputs: map[string]Output{
+			"primary":   {Name: "primary", Type: OutputTypeLogstash, Role: role, Fallback: "secondary"},
+			"secondary": {Name: "secondary", Type: OutputTypeElasticsearch},
+		},
+		preferred: "primary",
+	}, {
+		name: "both unprepared",
+		outputs: map[string]Output{
+			"primary":   {Name: "primary", Type: OutputTypeElasticsearch, Fallback: "secondary"},
+			"secondary": {Name: "secondary", Type: OutputTypeElasticsearch},
+		},
+		err: true,
+	}, {
+		name: "unprepared output without fallback",
+		outputs: map[string]Output{
+			"primary":   {Name: "primary", Type: OutputTypeLogstash, Role: role, Fallback: "secondary"},
+			"secondary": {Name: "secondary", Type: OutputTypeLogstash},
+			"other":     {Name: "other", Type: OutputTypeElasticsearch},
+		},
+		err: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			outputMap := make(map[string]map[string]interface{})
+			for name := range tc.outputs {
+				outputMap[name] = map[string]interface{}{}
+			}
+
+			err := PrepareOutputs(context.Background(), testlog.SetLogger(t), ftesting.NewMockBulk(), &model.Agent{}, tc.outputs, outputMap)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{
+				FieldOutputFallback:  "secondary",
+				FieldOutputPreferred: tc.preferred,
+			}, outputMap["primary"][FieldOutputFailover])
+			assert.NotContains(t, outputMap["secondary"], FieldOutputFailover)
+		})
+	}
+}
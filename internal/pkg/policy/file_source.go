@@ -0,0 +1,350 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/sqn"
+)
+
+const defaultFileWatchInterval = 10 * time.Second
+
+// fileT is the last successfully loaded content of a policy file.
+type fileT struct {
+	sum      [sha256.Size]byte
+	policyID string
+}
+
+type fileSubT struct {
+	c chan []es.HitT
+}
+
+// Output returns the subscription channel.
+func (s *fileSubT) Output() <-chan []es.HitT {
+	return s.c
+}
+
+// FileSource serves policies read from local files instead of the policies index.
+//
+// It implements monitor.Monitor so it can replace the policies index monitor: every new or changed policy is sent
+// to the subscriptions as if a new revision of the policy had been written to the index. Each file holds the data of
+// one policy in YAML or JSON. As no coordinator runs on the policies, they are served with a coordinator index of 1.
+type FileSource struct {
+	log      zerolog.Logger
+	path     string
+	interval time.Duration
+
+	mut      sync.RWMutex
+	files    map[string]fileT        // by file path
+	policies map[string]model.Policy // by policy ID
+	seqNo    int64
+	err      error // errors of the last load
+
+	subMut sync.RWMutex
+	subs   map[*fileSubT]struct{}
+}
+
+// NewFileSource creates the policy source for the policy files described by cfg.
+func NewFileSource(cfg config.PolicyFiles) *FileSource {
+	interval := cfg.WatchInterval
+	if interval <= 0 {
+		interval = defaultFileWatchInterval
+	}
+	return &FileSource{
+		log:      zerolog.Nop(),
+		path:     cfg.Path,
+		interval: interval,
+		files:    make(map[string]fileT),
+		policies: make(map[string]model.Policy),
+		seqNo:    sqn.UndefinedSeqNo,
+		subs:     make(map[*fileSubT]struct{}),
+	}
+}
+
+// Run loads the policy files, then checks them for changes every watch interval.
+func (s *FileSource) Run(ctx context.Context) error {
+	s.log = zerolog.Ctx(ctx).With().Str("ctx", "policy files").Str("path", s.path).Logger()
+	s.log.Info().Dur("watch_interval", s.interval).Msg("serving policies from files")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.notify(ctx, s.reload())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetCheckpoint implements the monitor.GlobalCheckpointProvider interface.
+func (s *FileSource) GetCheckpoint() sqn.SeqNo {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return sqn.SeqNo{s.seqNo}
+}
+
+// Subscribe returns a Subscription that is notified of new and changed policies.
+func (s *FileSource) Subscribe() monitor.Subscription {
+	sub := &fileSubT{c: make(chan []es.HitT, 1)}
+	s.subMut.Lock()
+	s.subs[sub] = struct{}{}
+	s.subMut.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscription, its channel is not closed.
+func (s *FileSource) Unsubscribe(sub monitor.Subscription) {
+	fs, ok := sub.(*fileSubT)
+	if !ok {
+		return
+	}
+	s.subMut.Lock()
+	delete(s.subs, fs)
+	s.subMut.Unlock()
+}
+
+// QueryLatestPolicies returns the policies loaded from the files.
+// It has the signature of dl.QueryLatestPolicies so it can be used in its place.
+func (s *FileSource) QueryLatestPolicies(_ context.Context, _ bulk.Bulk, _ ...dl.Option) ([]model.Policy, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	policies := make([]model.Policy, 0, len(s.policies))
+	for _, p := range s.policies {
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// Err returns the errors of the last load of the policy files.
+func (s *FileSource) Err() error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.err
+}
+
+func (s *FileSource) notify(ctx context.Context, policies []model.Policy) {
+	if len(policies) == 0 {
+		return
+	}
+	hits := make([]es.HitT, 0, len(policies))
+	for _, p := range policies {
+		src, err := json.Marshal(p)
+		if err != nil {
+			s.log.Error().Err(err).Str(logger.PolicyID, p.PolicyID).Msg("failed to encode policy")
+			continue
+		}
+		hits = append(hits, es.HitT{ID: p.Id, SeqNo: p.SeqNo, Source: src})
+	}
+
+	s.subMut.RLock()
+	defer s.subMut.RUnlock()
+	for sub := range s.subs {
+		select {
+		case sub.c <- hits:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload reads the policy files and returns the policies that are new or changed since the last load.
+// A file that fails to load keeps serving its previously loaded policy; a policy whose file was removed is
+// no longer served.
+func (s *FileSource) reload() []model.Policy {
+	paths, err := s.policyFiles()
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to list policy files")
+		s.mut.Lock()
+		s.err = err
+		s.mut.Unlock()
+		return nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var (
+		errs    []error
+		changed []model.Policy
+	)
+	files := make(map[string]fileT, len(paths))
+	seen := make(map[string]string, len(paths)) // policy ID to file path
+	keep := func(path string, err error) {
+		s.log.Error().Err(err).Str("file", path).Msg("failed to load policy file")
+		errs = append(errs, err)
+		if prev, ok := s.files[path]; ok {
+			if _, dup := seen[prev.policyID]; !dup {
+				files[path] = prev
+				seen[prev.policyID] = path
+			}
+		}
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			keep(path, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if prev, ok := s.files[path]; ok && prev.sum == sum {
+			if _, dup := seen[prev.policyID]; !dup {
+				files[path] = prev
+				seen[prev.policyID] = path
+				continue
+			}
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			keep(path, err)
+			continue
+		}
+		p, err := readPolicyFile(path, content, info.ModTime())
+		if err != nil {
+			keep(path, fmt.Errorf("policy file %s: %w", path, err))
+			continue
+		}
+		if other, dup := seen[p.PolicyID]; dup {
+			keep(path, fmt.Errorf("policy file %s: policy %q is already defined in %s", path, p.PolicyID, other))
+			continue
+		}
+
+		// a changed policy must have a higher revision to be sent to the agents running it
+		if curr, ok := s.policies[p.PolicyID]; ok && p.RevisionIdx <= curr.RevisionIdx {
+			p.RevisionIdx = curr.RevisionIdx + 1
+			p.Data.Revision = p.RevisionIdx
+		}
+		s.seqNo++
+		p.SeqNo = s.seqNo
+
+		files[path] = fileT{sum: sum, policyID: p.PolicyID}
+		seen[p.PolicyID] = path
+		s.policies[p.PolicyID] = p
+		changed = append(changed, p)
+		s.log.Info().
+			Str("file", path).
+			Str(logger.PolicyID, p.PolicyID).
+			Int64(logger.RevisionIdx, p.RevisionIdx).
+			Msg("policy file loaded")
+	}
+
+	for id := range s.policies {
+		if _, ok := seen[id]; !ok {
+			s.log.Info().Str(logger.PolicyID, id).Msg("policy file removed, policy is no longer served")
+			delete(s.policies, id)
+		}
+	}
+	s.files = files
+	s.err = errors.Join(errs...)
+	return changed
+}
+
+// policyFiles returns the configured path if it is a file, or the YAML and JSON files of the configured directory.
+func (s *FileSource) policyFiles() ([]string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{s.path}, nil
+	}
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		switch filepath.Ext(e.Name()) {
+		case ".yml", ".yaml", ".json":
+			paths = append(paths, filepath.Join(s.path, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// readPolicyFile decodes the policy data held by a file.
+//
+// The policy ID is the id attribute of the data, or the file name without its extension. The revision is the
+// revision attribute of the data, or the modification time of the file so it increases across restarts.
+func readPolicyFile(path string, content []byte, modTime time.Time) (model.Policy, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return model.Policy{}, err
+	}
+	if raw == nil {
+		return model.Policy{}, errors.New("file is empty")
+	}
+	// Round trip through JSON so the data is decoded like a policy document of the policies index.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return model.Policy{}, err
+	}
+	var data model.PolicyData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return model.Policy{}, err
+	}
+
+	if data.ID == "" {
+		data.ID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if data.Revision <= 0 {
+		data.Revision = modTime.Unix()
+	}
+	if err := checkPolicyData(&data); err != nil {
+		return model.Policy{}, err
+	}
+
+	return model.Policy{
+		ESDocument:         model.ESDocument{Id: data.ID},
+		PolicyID:           data.ID,
+		RevisionIdx:        data.Revision,
+		CoordinatorIdx:     1,
+		Data:               &data,
+		DefaultFleetServer: HasFleetServerInput(data.Inputs),
+		Timestamp:          modTime.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// checkPolicyData runs the checks of newParsedPolicy that do not need elasticsearch,
+// so a malformed file is rejected on load rather than failing the policy monitor.
+func checkPolicyData(data *model.PolicyData) error {
+	roles, err := parsePerms(data.OutputPermissions)
+	if err != nil {
+		return err
+	}
+	if _, err := constructPolicyOutputs(data.Outputs, roles); err != nil {
+		return err
+	}
+	_, err = findDefaultOutputName(data.Outputs)
+	return err
+}
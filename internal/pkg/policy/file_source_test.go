@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+const (
+	testPolicyFileYAML = `
+revision: 3
+outputs:
+  default:
+    type: logstash
+inputs:
+  - type: fleet-server
+`
+	testPolicyFileJSON = `{"id":"other","outputs":{"default":{"type":"logstash"}},"inputs":[{"type":"logfile"}]}`
+)
+
+func writePolicyFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func policyIDs(policies []model.Policy) []string {
+	ids := make([]string, 0, len(policies))
+	for _, p := range policies {
+		ids = append(ids, p.PolicyID)
+	}
+	return ids
+}
+
+func TestFileSourceReload(t *testing.T) {
+	dir := t.TempDir()
+	writePolicyFile(t, filepath.Join(dir, "fleet-server.yml"), testPolicyFileYAML)
+	writePolicyFile(t, filepath.Join(dir, "other.json"), testPolicyFileJSON)
+	writePolicyFile(t, filepath.Join(dir, "README.md"), "not a policy")
+
+	src := NewFileSource(config.PolicyFiles{Path: dir})
+
+	changed := src.reload()
+	require.NoError(t, src.Err())
+	assert.ElementsMatch(t, []string{"fleet-server", "other"}, policyIDs(changed))
+
+	policies, err := src.QueryLatestPolicies(context.Background(), nil)
+	require.NoError(t, err)
+	latest := groupByLatest(policies)
+	require.Len(t, latest, 2)
+
+	p := latest["fleet-server"]
+	assert.Equal(t, int64(3), p.RevisionIdx)
+	assert.Equal(t, int64(3), p.Data.Revision)
+	assert.Equal(t, "fleet-server", p.Data.ID)
+	assert.Equal(t, int64(1), p.CoordinatorIdx)
+	assert.True(t, p.DefaultFleetServer)
+	assert.Greater(t, latest["other"].RevisionIdx, int64(0), "revision defaults to the file modification time")
+
+	t.Run("unchanged files", func(t *testing.T) {
+		assert.Empty(t, src.reload())
+	})
+
+	t.Run("changed file without a new revision", func(t *testing.T) {
+		writePolicyFile(t, filepath.Join(dir, "fleet-server.yml"), testPolicyFileYAML+"  - type: logfile\n")
+		changed := src.reload()
+		require.Len(t, changed, 1)
+		assert.Equal(t, int64(4), changed[0].RevisionIdx)
+		assert.Len(t, changed[0].Data.Inputs, 2)
+	})
+
+	t.Run("invalid file keeps the previous policy", func(t *testing.T) {
+		writePolicyFile(t, filepath.Join(dir, "other.json"), `{"id":"other","outputs":{}}`)
+		assert.Empty(t, src.reload())
+		assert.Error(t, src.Err())
+
+		policies, err := src.QueryLatestPolicies(context.Background(), nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"fleet-server", "other"}, policyIDs(policies))
+	})
+
+	t.Run("duplicate policy ID", func(t *testing.T) {
+		writePolicyFile(t, filepath.Join(dir, "other.json"), testPolicyFileJSON)
+		writePolicyFile(t, filepath.Join(dir, "copy.json"), testPolicyFileJSON)
+		src.reload()
+		assert.ErrorContains(t, src.Err(), `policy "other" is already defined`)
+		require.NoError(t, os.Remove(filepath.Join(dir, "copy.json")))
+	})
+
+	t.Run("removed file", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, "other.json")))
+		assert.Empty(t, src.reload())
+		require.NoError(t, src.Err())
+
+		policies, err := src.QueryLatestPolicies(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"fleet-server"}, policyIDs(policies))
+	})
+}
+
+func TestFileSourceRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writePolicyFile(t, path, testPolicyFileYAML)
+
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	defer cancel()
+
+	src := NewFileSource(config.PolicyFiles{Path: path, WatchInterval: 10 * time.Millisecond})
+	sub := src.Subscribe()
+	defer src.Unsubscribe(sub)
+	go src.Run(ctx) //nolint:errcheck // test
+
+	select {
+	case hits := <-sub.Output():
+		require.Len(t, hits, 1)
+		var p model.Policy
+		require.NoError(t, hits[0].Unmarshal(&p))
+		assert.Equal(t, "policy", p.PolicyID)
+		assert.Equal(t, int64(3), p.RevisionIdx)
+		assert.Equal(t, src.GetCheckpoint().Value(), p.SeqNo)
+	case <-time.After(5 * time.Second):
+		t.Fatal("policy file not loaded")
+	}
+
+	writePolicyFile(t, path, testPolicyFileYAML+"agent: {}\n")
+	select {
+	case hits := <-sub.Output():
+		require.Len(t, hits, 1)
+		var p model.Policy
+		require.NoError(t, hits[0].Unmarshal(&p))
+		assert.Equal(t, int64(4), p.RevisionIdx)
+	case <-time.After(5 * time.Second):
+		t.Fatal("policy file change not detected")
+	}
+}
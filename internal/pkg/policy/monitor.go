@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -21,9 +22,14 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
 )
 
-const cloudPolicyID = "policy-elastic-agent-on-cloud"
+const (
+	cloudPolicyID = "policy-elastic-agent-on-cloud"
+
+	defaultRolloutInterval = 10 * time.Second
+)
 
 /*
 Design should have the following properties
@@ -62,6 +68,47 @@ type Monitor interface {
 
 	// Unsubscribe removes the current subscription.
 	Unsubscribe(sub Subscription) error
+
+	// ReportHealth records the checkin status of an agent running the passed policy revision.
+	// It is used to decide if a staged rollout of the revision may continue.
+	ReportHealth(agentID string, policyID string, revisionIdx int64, healthy bool)
+
+	// RolledBackRevision returns the revision of the policy that is redelivered to agents after a staged rollout was rolled back.
+	RolledBackRevision(policyID string) (int64, bool)
+
+	// Preload loads the latest revision of every policy, so the first subscriptions do not wait for a load.
+	// It waits for the monitor to run.
+	Preload(ctx context.Context) error
+
+	// DispatchLag returns for how long policy changes have been waiting for dispatch, 0 when none are pending.
+	DispatchLag() time.Duration
+}
+
+// MonitorOption configures optional behaviour of the policy monitor.
+type MonitorOption func(*monitorT)
+
+// WithSecretCache resolves the secret references of all policies through the passed cache.
+func WithSecretCache(secrets *SecretCache) MonitorOption {
+	return func(m *monitorT) {
+		m.secretCache = secrets
+	}
+}
+
+// WithRollout releases new policy revisions in stages as described by cfg.
+func WithRollout(cfg config.PolicyRollout) MonitorOption {
+	return func(m *monitorT) {
+		if cfg.Enabled && len(cfg.Stages) > 0 {
+			m.rollout = &cfg
+		}
+	}
+}
+
+// WithFileSource serves the policies loaded by src instead of those of the policies index.
+func WithFileSource(src *FileSource) MonitorOption {
+	return func(m *monitorT) {
+		m.monitor = src
+		m.policyF = src.QueryLatestPolicies
+	}
 }
 
 type policyFetcher func(ctx context.Context, bulker bulk.Bulk, opt ...dl.Option) ([]model.Policy, error)
@@ -69,6 +116,10 @@ type policyFetcher func(ctx context.Context, bulker bulk.Bulk, opt ...dl.Option)
 type policyT struct {
 	pp   ParsedPolicy
 	head *subT
+	// rollout is set while the revision in pp is being released in stages.
+	rollout *rolloutT
+	// debounce is set while the dispatch of the revision in pp is delayed.
+	debounce *debounceT
 }
 
 type monitorT struct {
@@ -78,21 +129,32 @@ type monitorT struct {
 	bulker  bulk.Bulk
 	monitor monitor.Monitor
 
-	kickCh   chan struct{}
-	deployCh chan struct{}
+	kickCh    chan struct{}
+	deployCh  chan struct{}
+	flushCh   chan struct{}
+	preloadCh chan chan error
 
-	policies map[string]policyT
-	pendingQ *subT
+	policies     map[string]policyT
+	pendingQ     *subT
+	pendingSince atomic.Int64 // unix nanoseconds at which pendingQ became non-empty, 0 if empty
 
 	policyF       policyFetcher
 	policiesIndex string
 	limit         *rate.Limiter
 
+	rollout         *config.PolicyRollout
+	rolloutInterval time.Duration
+
+	debounceWindow  time.Duration
+	debounceMaxWait time.Duration
+
+	secretCache *SecretCache
+
 	startCh chan struct{}
 }
 
 // NewMonitor creates the policy monitor for subscribing agents.
-func NewMonitor(bulker bulk.Bulk, monitor monitor.Monitor, cfg config.ServerLimits) Monitor {
+func NewMonitor(bulker bulk.Bulk, monitor monitor.Monitor, cfg config.ServerLimits, opts ...MonitorOption) Monitor {
 	burst := cfg.PolicyLimit.Burst
 	interval := rate.Every(cfg.PolicyLimit.Interval)
 	if cfg.PolicyLimit.Burst <= 0 {
@@ -105,18 +167,25 @@ func NewMonitor(bulker bulk.Bulk, monitor monitor.Monitor, cfg config.ServerLimi
 			interval = rate.Every(time.Nanosecond) // set minimal spin rate
 		}
 	}
-	return &monitorT{
-		bulker:        bulker,
-		monitor:       monitor,
-		kickCh:        make(chan struct{}, 1),
-		deployCh:      make(chan struct{}, 1),
-		policies:      make(map[string]policyT),
-		pendingQ:      makeHead(),
-		limit:         rate.NewLimiter(interval, burst),
-		policyF:       dl.QueryLatestPolicies,
-		policiesIndex: dl.FleetPolicies,
-		startCh:       make(chan struct{}),
+	m := &monitorT{
+		bulker:          bulker,
+		monitor:         monitor,
+		kickCh:          make(chan struct{}, 1),
+		deployCh:        make(chan struct{}, 1),
+		flushCh:         make(chan struct{}, 1),
+		preloadCh:       make(chan chan error),
+		policies:        make(map[string]policyT),
+		pendingQ:        makeHead(),
+		limit:           rate.NewLimiter(interval, burst),
+		policyF:         dl.QueryLatestPolicies,
+		policiesIndex:   dl.FleetPolicies,
+		rolloutInterval: defaultRolloutInterval,
+		startCh:         make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // endTrans is a convenience function to end the passed transaction if it's not nil
@@ -127,16 +196,36 @@ func endTrans(t *apm.Transaction) {
 }
 
 // Run runs the monitor.
-func (m *monitorT) Run(ctx context.Context) error {
+func (m *monitorT) Run(ctx context.Context) (err error) {
 	m.log = zerolog.Ctx(ctx).With().Str("ctx", "policy agent monitor").Logger()
 	m.log.Info().
 		Int("burst", m.limit.Burst()).
 		Any("event_rate", m.limit.Limit()). // Limit() returns an alias type for float64
 		Msg("run policy monitor")
+	timeline.Record(timeline.KindMonitor, "policy monitor started", nil)
+	defer func() {
+		var fields map[string]string
+		if err != nil && !errors.Is(err, context.Canceled) {
+			fields = map[string]string{"error": err.Error()}
+		}
+		timeline.Record(timeline.KindMonitor, "policy monitor exited", fields)
+	}()
 
 	s := m.monitor.Subscribe()
 	defer m.monitor.Unsubscribe(s)
 
+	// rolloutC stays nil, and never fires, if staged rollouts are disabled.
+	var rolloutC <-chan time.Time
+	if m.rollout != nil {
+		m.log.Info().
+			Ints("stages", m.rollout.Stages).
+			Dur("stage_duration", m.rollout.StageDuration).
+			Msg("staged policy rollout enabled")
+		tick := time.NewTicker(m.rolloutInterval)
+		defer tick.Stop()
+		rolloutC = tick.C
+	}
+
 	close(m.startCh)
 
 	var iCtx context.Context
@@ -181,6 +270,26 @@ LOOP:
 			}
 			m.dispatchPending(iCtx)
 			endTrans(trans)
+		case done := <-m.preloadCh:
+			m.log.Trace().Msg("policy monitor preload")
+			if m.bulker.HasTracer() {
+				trans = m.bulker.StartTransaction("preload policies", "policy_monitor")
+				iCtx = apm.ContextWithTransaction(ctx, trans)
+			}
+
+			err := m.loadPolicies(iCtx)
+			m.dispatchPending(iCtx)
+			endTrans(trans)
+			done <- err
+		case <-m.flushCh:
+			m.log.Trace().Msg("policy monitor flush debounced")
+			if m.flushDebounced(time.Now()) {
+				m.dispatchPending(ctx)
+			}
+		case <-rolloutC:
+			if m.evaluateRollouts(time.Now()) {
+				m.dispatchPending(ctx)
+			}
 		case <-ctx.Done():
 			break LOOP
 		}
@@ -214,8 +323,26 @@ func (m *monitorT) processHits(ctx context.Context, hits []es.HitT) error {
 	return m.processPolicies(ctx, policies)
 }
 
+// Preload loads the latest revision of every policy through the Run loop.
+func (m *monitorT) Preload(ctx context.Context) error {
+	if err := m.waitStart(ctx); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	select {
+	case m.preloadCh <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // waitStart returns once Run has started
-// It's used in tests.
 func (m *monitorT) waitStart(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -238,6 +365,7 @@ func (m *monitorT) dispatchPending(ctx context.Context) {
 
 	s := m.pendingQ.popFront()
 	if s == nil {
+		m.pendingSince.Store(0)
 		return
 	}
 
@@ -259,11 +387,13 @@ func (m *monitorT) dispatchPending(ctx context.Context) {
 			return
 		}
 
+		pp := m.target(policy, s.agentID)
+
 		select {
 		case <-ctx.Done():
 			m.log.Debug().Err(ctx.Err()).Msg("context termination detected in policy dispatch")
 			return
-		case s.ch <- &policy.pp:
+		case s.ch <- pp:
 			m.log.Debug().
 				Str(logger.AgentID, s.agentID).
 				Str(logger.PolicyID, s.policyID).
@@ -285,11 +415,36 @@ func (m *monitorT) dispatchPending(ctx context.Context) {
 		nQueued += 1
 	}
 
+	m.pendingSince.Store(0)
+
 	dur := time.Since(ts)
 	m.log.Debug().Dur("event.duration", dur).Int("nSubs", nQueued).
 		Msg("policy monitor dispatch complete")
 }
 
+// pushPending queues the subscription for dispatch, at the front for an immediate delivery.
+// m.mut must be held.
+func (m *monitorT) pushPending(sub *subT, front bool) {
+	if m.pendingQ.isEmpty() {
+		m.pendingSince.Store(time.Now().UnixNano())
+	}
+	if front {
+		m.pendingQ.pushFront(sub)
+	} else {
+		m.pendingQ.pushBack(sub)
+	}
+}
+
+// DispatchLag returns for how long the oldest pending policy change has been waiting for dispatch.
+// It does not wait for a dispatch in progress.
+func (m *monitorT) DispatchLag() time.Duration {
+	since := m.pendingSince.Load()
+	if since == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, since))
+}
+
 func (m *monitorT) loadPolicies(ctx context.Context) error {
 	span, ctx := apm.StartSpan(ctx, "Load policies", "load")
 	defer span.End()
@@ -334,7 +489,7 @@ func (m *monitorT) processPolicies(ctx context.Context, policies []model.Policy)
 
 	latest := m.groupByLatest(policies)
 	for _, policy := range latest {
-		pp, err := NewParsedPolicy(ctx, m.bulker, policy)
+		pp, err := newParsedPolicy(ctx, m.bulker, m.secretCache, policy)
 		if err != nil {
 			return err
 		}
@@ -403,18 +558,41 @@ func (m *monitorT) updatePolicy(ctx context.Context, pp *ParsedPolicy) bool {
 	// Cache the old stored policy for logging
 	oldPolicy := p.pp.Policy
 
+	if m.stagedRollout(p, &newPolicy) {
+		// Keep releasing the last fully released revision to the agents outside of the rollout.
+		if p.rollout == nil {
+			p.rollout = newRollout(p.pp, time.Now())
+		} else {
+			p.rollout = newRollout(p.rollout.prev, time.Now())
+		}
+		zlog.Info().
+			Int("stage_percent", m.rollout.Stages[0]).
+			Int64("released_revision_idx", p.rollout.prev.Policy.RevisionIdx).
+			Msg("Start staged rollout of policy revision")
+	}
+
 	// Update the policy in our data structure
+	debounced := m.debounced(&p, time.Now())
 	p.pp = *pp
 	m.policies[newPolicy.PolicyID] = p
 	zlog.Debug().Str(logger.PolicyID, newPolicy.PolicyID).Msg("Update policy revision")
 
+	if debounced {
+		zlog.Info().
+			Int64("old_revision_idx", oldPolicy.RevisionIdx).
+			Int64("old_coordinator_idx", oldPolicy.CoordinatorIdx).
+			Dur("debounce_window", m.debounceWindow).
+			Msg("New revision of policy received, dispatch debounced")
+		return true
+	}
+
 	// Iterate through the subscriptions on this policy;
 	// schedule any subscription for delivery that requires an update.
 	nQueued := 0
 
 	iter := NewIterator(p.head)
 	for sub := iter.Next(); sub != nil; sub = iter.Next() {
-		if sub.isUpdate(&newPolicy) {
+		if m.needsUpdate(p, sub) {
 
 			// Unlink the target node from the list
 			iter.Unlink()
@@ -422,11 +600,7 @@ func (m *monitorT) updatePolicy(ctx context.Context, pp *ParsedPolicy) bool {
 			// Push the node onto the pendingQ
 			// HACK: if update is for cloud agent, put on front of queue
 			// not at the end for immediate delivery.
-			if newPolicy.PolicyID == cloudPolicyID {
-				m.pendingQ.pushFront(sub)
-			} else {
-				m.pendingQ.pushBack(sub)
-			}
+			m.pushPending(sub, newPolicy.PolicyID == cloudPolicyID)
 
 			zlog.Debug().
 				Str(logger.AgentID, sub.agentID).
@@ -501,12 +675,12 @@ func (m *monitorT) Subscribe(agentID string, policyID string, revisionIdx int64,
 		p.head.pushBack(s)
 		m.policies[policyID] = p
 		m.kickLoad()
-	case s.isUpdate(&p.pp.Policy):
+	case p.debounce == nil && m.needsUpdate(p, s):
 		empty := m.pendingQ.isEmpty()
-		m.pendingQ.pushBack(s)
+		m.pushPending(s, false)
 		m.log.Debug().
 			Str(logger.AgentID, s.agentID).
-			Int64(logger.RevisionIdx, (&p.pp.Policy).RevisionIdx).
+			Int64(logger.RevisionIdx, m.target(p, s.agentID).Policy.RevisionIdx).
 			Msg("deploy pending on subscribe")
 		if empty {
 			m.kickDeploy()
@@ -154,6 +154,51 @@ func TestMonitor_NewPolicy(t *testing.T) {
 	mm.AssertExpectations(t)
 }
 
+func TestMonitor_Preload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = testlog.SetLogger(t).WithContext(ctx)
+
+	chHitT := make(chan []es.HitT)
+	ms := mmock.NewMockSubscription()
+	ms.On("Output").Return((<-chan []es.HitT)(chHitT))
+	mm := mmock.NewMockMonitor()
+	mm.On("Subscribe").Return(ms).Once()
+	mm.On("Unsubscribe", mock.Anything).Return().Once()
+	bulker := ftesting.NewMockBulk()
+
+	monitor := NewMonitor(bulker, mm, config.ServerLimits{})
+	pm := monitor.(*monitorT)
+	policyID := uuid.Must(uuid.NewV4()).String()
+	pm.policyF = func(ctx context.Context, bulker bulk.Bulk, opt ...dl.Option) ([]model.Policy, error) {
+		return []model.Policy{{
+			ESDocument:     model.ESDocument{Id: xid.New().String()},
+			PolicyID:       policyID,
+			CoordinatorIdx: 1,
+			Data:           policyDataDefault,
+			RevisionIdx:    2,
+		}}, nil
+	}
+
+	var mwg sync.WaitGroup
+	mwg.Add(1)
+	go func() {
+		defer mwg.Done()
+		_ = monitor.Run(ctx)
+	}()
+
+	require.NoError(t, monitor.Preload(ctx))
+	pm.mut.Lock()
+	p, ok := pm.policies[policyID]
+	pm.mut.Unlock()
+	require.True(t, ok, "the policy is loaded before any subscription")
+	assert.Equal(t, int64(2), p.pp.Policy.RevisionIdx)
+
+	cancel()
+	mwg.Wait()
+	assert.ErrorIs(t, monitor.Preload(ctx), context.Canceled)
+}
+
 func TestMonitor_SamePolicy(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -528,3 +573,21 @@ LOOP:
 	ms.AssertExpectations(t)
 	mm.AssertExpectations(t)
 }
+
+func TestMonitor_DispatchLag(t *testing.T) {
+	ctx := testlog.SetLogger(t).WithContext(context.Background())
+	m := NewMonitor(nil, nil, config.ServerLimits{}).(*monitorT)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	_, err := m.Subscribe("agent-1", "policy", 1, 1)
+	require.NoError(t, err)
+	assert.Zero(t, m.DispatchLag(), "no policy change is pending")
+
+	m.updatePolicy(ctx, rolloutPolicy(2))
+	require.False(t, m.pendingQ.isEmpty())
+	time.Sleep(time.Millisecond)
+	assert.Greater(t, m.DispatchLag(), time.Duration(0))
+
+	m.dispatchPending(ctx)
+	assert.Zero(t, m.DispatchLag())
+}
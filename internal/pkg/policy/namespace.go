@@ -0,0 +1,147 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/smap"
+)
+
+const (
+	fieldDataStream = "data_stream"
+	fieldNamespace  = "namespace"
+	fieldStreams    = "streams"
+	fieldIndices    = "indices"
+	fieldNames      = "names"
+
+	maxNamespaceLength = 100
+)
+
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+// validateNamespace checks a namespace against the data stream naming restrictions of elasticsearch.
+func validateNamespace(namespace string) error {
+	switch {
+	case namespace == "":
+		return fmt.Errorf("%w: namespace is empty", ErrInvalidNamespace)
+	case len(namespace) > maxNamespaceLength:
+		return fmt.Errorf("%w %q: longer than %d bytes", ErrInvalidNamespace, namespace, maxNamespaceLength)
+	case namespace != strings.ToLower(namespace):
+		return fmt.Errorf("%w %q: must be lowercase", ErrInvalidNamespace, namespace)
+	case strings.ContainsAny(namespace, `\/*?"<>|,#:- `):
+		return fmt.Errorf("%w %q: contains an invalid character", ErrInvalidNamespace, namespace)
+	}
+	return nil
+}
+
+// OverrideNamespace returns copies of the inputs and outputs of a policy where the data stream namespace of the inputs,
+// and of their streams, is replaced by namespace.
+//
+// The index privileges of the output roles on the data streams of the replaced namespaces are granted on the data
+// streams of namespace instead, so the output API keys of the agent can write where its inputs send data.
+// The passed inputs and outputs are not modified.
+func OverrideNamespace(namespace string, inputs []map[string]interface{}, outputs map[string]Output) ([]map[string]interface{}, map[string]Output, error) {
+	if err := validateNamespace(namespace); err != nil {
+		return nil, nil, err
+	}
+
+	replaced := make(map[string]struct{})
+	resInputs := make([]map[string]interface{}, 0, len(inputs))
+	for _, input := range inputs {
+		input = maps.Clone(input)
+		overrideDataStreamNamespace(input, namespace, replaced)
+		if streams, ok := input[fieldStreams].([]interface{}); ok {
+			resStreams := make([]interface{}, len(streams))
+			for i, s := range streams {
+				if stream, ok := s.(map[string]interface{}); ok {
+					stream = maps.Clone(stream)
+					overrideDataStreamNamespace(stream, namespace, replaced)
+					s = stream
+				}
+				resStreams[i] = s
+			}
+			input[fieldStreams] = resStreams
+		}
+		resInputs = append(resInputs, input)
+	}
+
+	resOutputs := make(map[string]Output, len(outputs))
+	for name, o := range outputs {
+		if o.Role != nil && len(replaced) > 0 {
+			role, err := roles.namespaced(o.Role, namespace, replaced)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to override namespace of output %q permissions: %w", name, err)
+			}
+			o.Role = role
+		}
+		resOutputs[name] = o
+	}
+	return resInputs, resOutputs, nil
+}
+
+// overrideDataStreamNamespace replaces the data_stream.namespace attribute of m, if it is set, and records the replaced value.
+func overrideDataStreamNamespace(m map[string]interface{}, namespace string, replaced map[string]struct{}) {
+	ds, ok := m[fieldDataStream].(map[string]interface{})
+	if !ok {
+		return
+	}
+	ns, ok := ds[fieldNamespace].(string)
+	if !ok {
+		return
+	}
+	replaced[ns] = struct{}{}
+	ds = maps.Clone(ds)
+	ds[fieldNamespace] = namespace
+	m[fieldDataStream] = ds
+}
+
+// overrideRoleNamespace returns a copy of role where the index names ending with one of the replaced namespaces
+// end with namespace instead.
+func overrideRoleNamespace(role *RoleT, namespace string, replaced map[string]struct{}) (*RoleT, error) {
+	descriptors, err := smap.Parse(role.Raw)
+	if err != nil {
+		return nil, err
+	}
+	for name := range descriptors {
+		indices, ok := descriptors.GetMap(name)[fieldIndices].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range indices {
+			index, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			names, ok := index[fieldNames].([]interface{})
+			if !ok {
+				continue
+			}
+			for i, n := range names {
+				s, ok := n.(string)
+				if !ok {
+					continue
+				}
+				if pos := strings.LastIndexByte(s, '-'); pos >= 0 {
+					if _, ok := replaced[s[pos+1:]]; ok {
+						names[i] = s[:pos+1] + namespace
+					}
+				}
+			}
+		}
+	}
+
+	var r RoleT
+	if r.Sha2, err = descriptors.Hash(); err != nil {
+		return nil, err
+	}
+	if r.Raw, err = descriptors.Marshal(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
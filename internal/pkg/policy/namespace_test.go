@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideNamespace(t *testing.T) {
+	var inputs []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"type":"logfile","data_stream":{"namespace":"default"},"streams":[
+			{"id":"auth","data_stream":{"dataset":"system.auth","type":"logs"}},
+			{"id":"audit","data_stream":{"dataset":"system.audit","type":"logs","namespace":"audit"}}
+		]},
+		{"type":"fleet-server"}
+	]`), &inputs))
+
+	roles, err := parsePerms([]byte(`{"default":{
+		"_fallback":{"cluster":["monitor"],"indices":[{"names":["logs-*","metrics-*"],"privileges":["create_doc"]}]},
+		"system":{"indices":[{"names":["logs-system.auth-default","logs-system.audit-audit","logs-other-prod"],"privileges":["create_doc"]}]}
+	}}`))
+	require.NoError(t, err)
+	role := roles["default"]
+	outputs := map[string]Output{
+		"default":  {Name: "default", Type: OutputTypeElasticsearch, Role: &role},
+		"logstash": {Name: "logstash", Type: OutputTypeLogstash},
+	}
+
+	resInputs, resOutputs, err := OverrideNamespace("tenant", inputs, outputs)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(resInputs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type":"logfile","data_stream":{"namespace":"tenant"},"streams":[
+			{"id":"auth","data_stream":{"dataset":"system.auth","type":"logs"}},
+			{"id":"audit","data_stream":{"dataset":"system.audit","type":"logs","namespace":"tenant"}}
+		]},
+		{"type":"fleet-server"}
+	]`, string(b))
+
+	assert.JSONEq(t, `{
+		"_fallback":{"cluster":["monitor"],"indices":[{"names":["logs-*","metrics-*"],"privileges":["create_doc"]}]},
+		"system":{"indices":[{"names":["logs-system.auth-tenant","logs-system.audit-tenant","logs-other-prod"],"privileges":["create_doc"]}]}
+	}`, string(resOutputs["default"].Role.Raw))
+	assert.NotEqual(t, role.Sha2, resOutputs["default"].Role.Sha2)
+	assert.Nil(t, resOutputs["logstash"].Role)
+
+	// the policy is shared by all agents and must not be modified
+	assert.Equal(t, "default", inputs[0][fieldDataStream].(map[string]interface{})[fieldNamespace])
+	assert.Contains(t, string(outputs["default"].Role.Raw), "logs-system.auth-default")
+}
+
+func TestOverrideNamespaceInvalid(t *testing.T) {
+	for _, ns := range []string{"Tenant", "my-tenant", "a b", "a*"} {
+		t.Run(ns, func(t *testing.T) {
+			_, _, err := OverrideNamespace(ns, nil, nil)
+			assert.ErrorIs(t, err, ErrInvalidNamespace)
+		})
+	}
+}
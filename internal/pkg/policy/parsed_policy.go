@@ -53,6 +53,11 @@ type ParsedPolicy struct {
 }
 
 func NewParsedPolicy(ctx context.Context, bulker bulk.Bulk, p model.Policy) (*ParsedPolicy, error) {
+	return newParsedPolicy(ctx, bulker, nil, p)
+}
+
+// newParsedPolicy parses the policy, resolving its secret references through the passed cache.
+func newParsedPolicy(ctx context.Context, bulker bulk.Bulk, secretCache *SecretCache, p model.Policy) (*ParsedPolicy, error) {
 	var err error
 	// Interpret the output permissions if available
 	var roles map[string]RoleT
@@ -65,7 +70,7 @@ func NewParsedPolicy(ctx context.Context, bulker bulk.Bulk, p model.Policy) (*Pa
 		return nil, err
 	}
 	for _, policyOutput := range p.Data.Outputs {
-		err := ProcessOutputSecret(ctx, policyOutput, bulker)
+		err := processOutputSecret(ctx, policyOutput, bulker, secretCache, p.PolicyID, p.RevisionIdx)
 		if err != nil {
 			return nil, err
 		}
@@ -74,7 +79,7 @@ func NewParsedPolicy(ctx context.Context, bulker bulk.Bulk, p model.Policy) (*Pa
 	if err != nil {
 		return nil, err
 	}
-	policyInputs, err := getPolicyInputsWithSecrets(ctx, p.Data, bulker)
+	policyInputs, err := getPolicyInputsWithSecrets(ctx, p.Data, bulker, secretCache, p.PolicyID, p.RevisionIdx)
 	if err != nil {
 		return nil, err
 	}
@@ -109,9 +114,14 @@ func constructPolicyOutputs(outputs map[string]map[string]interface{}, roles map
 		if !ok {
 			return nil, fmt.Errorf("missing or invalid output type: %+v", v)
 		}
+		fallback, err := outputFallback(v)
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %w", k, err)
+		}
 		p := Output{
-			Name: k,
-			Type: typeStr,
+			Name:     k,
+			Type:     typeStr,
+			Fallback: fallback,
 		}
 
 		if role, ok := roles[k]; ok {
@@ -120,6 +130,9 @@ func constructPolicyOutputs(outputs map[string]map[string]interface{}, roles map
 
 		result[k] = p
 	}
+	if err := validateFallbacks(result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -17,6 +17,7 @@ import (
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/fleet-server/v7/internal/pkg/apikey"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
@@ -41,6 +42,10 @@ type Output struct {
 	Type         string
 	ServiceToken string
 	Role         *RoleT
+	// Fallback is the name of the output agents may fail over to.
+	Fallback string
+	// RotateAfter is the age after which the API key of an agent for an Elasticsearch output is replaced, 0 disables the rotation.
+	RotateAfter time.Duration
 }
 
 // Prepare prepares the output p to be sent to the elastic-agent
@@ -179,13 +184,23 @@ func (p *Output) prepareElasticsearch(
 	// Currently, we assume all ES outputs are the same ES fleet-server is connected to.
 	needNewKey := false
 	needUpdateKey := false
+	reason := ""
 	switch {
 	case output.APIKey == "":
 		zlog.Debug().Msg("must generate api key as default API key is not present")
 		needNewKey = true
+		reason = audit.ReasonOutputKey
 	case hasConfigChanged:
 		zlog.Debug().Msg("must generate api key as remote output config changed")
 		needNewKey = true
+		reason = audit.ReasonOutputChanged
+	case p.RotateAfter > 0 && apiKeyRotationDue(output, p.RotateAfter, time.Now()):
+		zlog.Info().
+			Str(logger.APIKeyID, output.APIKeyID).
+			Str("fleet.policy.output.api_key_created_at", output.APIKeyCreatedAt).
+			Msg("must generate api key as the output API key reached its maximum age")
+		needNewKey = true
+		reason = audit.ReasonRotation
 	case p.Role.Sha2 != output.PermissionsHash:
 		// the is actually the OutputPermissionsHash for the default hash. The Agent
 		// document on ES does not have OutputPermissionsHash for any other output
@@ -213,21 +228,35 @@ func (p *Output) prepareElasticsearch(
 			return err
 		}
 
-		// merge roles with p.Role
-		newRoles, err := mergeRoles(zlog, currentRoles, p.Role)
-		if err != nil {
-			zlog.Error().
-				Str("apiKeyID", output.APIKeyID).
-				Err(err).Msg("fail merging roles for key")
-			return err
-		}
+		if currentRoles.Sha2 == p.Role.Sha2 {
+			// The key already holds the policy permissions, only the agent record is out of date.
+			zlog.Debug().Str("apiKeyID", output.APIKeyID).Msg("api key roles already match the policy output permissions")
+		} else {
+			// merge roles with p.Role, agents with the same current roles share the merged roles
+			newRoles, err := roles.merged(currentRoles, p.Role, func() (*RoleT, error) {
+				return mergeRoles(zlog, currentRoles, p.Role)
+			})
+			if err != nil {
+				zlog.Error().
+					Str("apiKeyID", output.APIKeyID).
+					Err(err).Msg("fail merging roles for key")
+				return err
+			}
 
-		// hash provided is only for merging request together and not persisted
-		err = outputBulker.APIKeyUpdate(ctx, output.APIKeyID, newRoles.Sha2, newRoles.Raw)
-		if err != nil {
-			zlog.Error().Err(err).Msg("fail generate output key")
-			zlog.Debug().RawJSON("roles", newRoles.Raw).Str("sha", newRoles.Sha2).Err(err).Msg("roles not updated")
-			return err
+			// hash provided is only for merging request together and not persisted
+			err = outputBulker.APIKeyUpdate(ctx, output.APIKeyID, newRoles.Sha2, newRoles.Raw)
+			audit.APIKey(zlog, audit.APIKeyEvent{
+				Action:  audit.ActionAPIKeyUpdate,
+				Reason:  audit.ReasonPermissionsChange,
+				AgentID: agent.Id,
+				Output:  p.Name,
+				IDs:     []string{output.APIKeyID},
+			}, err)
+			if err != nil {
+				zlog.Error().Err(err).Msg("fail generate output key")
+				zlog.Debug().RawJSON("roles", newRoles.Raw).Str("sha", newRoles.Sha2).Err(err).Msg("roles not updated")
+				return err
+			}
 		}
 
 		output.PermissionsHash = p.Role.Sha2 // for the sake of consistency
@@ -261,6 +290,11 @@ func (p *Output) prepareElasticsearch(
 		ctx := zlog.WithContext(ctx)
 		outputAPIKey, err :=
 			generateOutputAPIKey(ctx, outputBulker, agent.Id, p.Name, p.Role.Raw)
+		keyEvent := audit.APIKeyEvent{Action: audit.ActionAPIKeyCreate, Reason: reason, AgentID: agent.Id, Output: p.Name}
+		if outputAPIKey != nil {
+			keyEvent.IDs = []string{outputAPIKey.ID}
+		}
+		audit.APIKey(zlog, keyEvent, err)
 
 		// reporting output health and not returning the error to keep fleet-server running
 		if outputAPIKey == nil && p.Type == OutputTypeRemoteElasticsearch {
@@ -304,8 +338,10 @@ func (p *Output) prepareElasticsearch(
 			Str(logger.DefaultOutputAPIKeyID, outputAPIKey.ID).
 			Msg("Updating agent record to pick up default output key.")
 
+		createdAt := time.Now().UTC().Format(time.RFC3339)
 		fields := map[string]interface{}{
 			dl.FieldPolicyOutputAPIKey:          outputAPIKey.Agent(),
+			dl.FieldPolicyOutputAPIKeyCreatedAt: createdAt,
 			dl.FieldPolicyOutputAPIKeyID:        outputAPIKey.ID,
 			dl.FieldPolicyOutputPermissionsHash: p.Role.Sha2,
 		}
@@ -338,6 +374,7 @@ func (p *Output) prepareElasticsearch(
 		// this method returns.
 		output.Type = OutputTypeElasticsearch
 		output.APIKey = outputAPIKey.Agent()
+		output.APIKeyCreatedAt = createdAt
 		output.APIKeyID = outputAPIKey.ID
 		output.PermissionsHash = p.Role.Sha2 // for the sake of consistency
 	}
@@ -7,10 +7,12 @@
 package policy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +22,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/smap"
 	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
 )
@@ -249,6 +252,51 @@ func TestPolicyOutputESPrepare(t *testing.T) {
 		bulker.AssertExpectations(t)
 	})
 
+	t.Run("API key roles already match the permissions no need to update the key", func(t *testing.T) {
+		logger := testlog.SetLogger(t)
+		bulker := ftesting.NewMockBulk()
+
+		roles := []byte(`{"_elastic_agent_checks":{"cluster":["monitor"]}}`)
+		descriptors, err := smap.Parse(roles)
+		require.NoError(t, err)
+		hash, err := descriptors.Hash()
+		require.NoError(t, err)
+
+		bulker.
+			On("APIKeyRead", mock.Anything, "test_id", mock.Anything).
+			Return(&bulk.APIKeyMetadata{ID: "test_id", RoleDescriptors: roles}, nil).
+			Once()
+		bulker.On("Update",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil).Once()
+
+		output := Output{
+			Type: OutputTypeElasticsearch,
+			Name: "test output",
+			Role: &RoleT{Sha2: hash, Raw: roles},
+		}
+		policyMap := map[string]map[string]interface{}{
+			"test output": map[string]interface{}{},
+		}
+		testAgent := &model.Agent{
+			Outputs: map[string]*model.PolicyOutput{
+				output.Name: {
+					APIKey:          "test_id:EXISTING-KEY",
+					APIKeyID:        "test_id",
+					PermissionsHash: "old-HASH",
+					Type:            OutputTypeElasticsearch,
+				},
+			},
+		}
+
+		err = output.Prepare(context.Background(), logger, bulker, testAgent, policyMap)
+		require.NoError(t, err, "expected prepare to pass")
+
+		assert.Equal(t, hash, testAgent.Outputs[output.Name].PermissionsHash)
+		bulker.AssertNotCalled(t, "APIKeyUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		bulker.AssertExpectations(t)
+	})
+
 	t.Run("Generate API Key on new Agent", func(t *testing.T) {
 		logger := testlog.SetLogger(t)
 		bulker := ftesting.NewMockBulk()
@@ -300,6 +348,72 @@ func TestPolicyOutputESPrepare(t *testing.T) {
 	})
 }
 
+func TestPolicyOutputESPrepareRotation(t *testing.T) {
+	oldAPIKey := bulk.APIKey{ID: "test_id_existing", Key: "existing-key"}
+	output := Output{
+		Type: OutputTypeElasticsearch,
+		Name: "test output",
+		Role: &RoleT{
+			Sha2: "hash",
+			Raw:  TestPayload,
+		},
+		RotateAfter: time.Hour,
+	}
+	newAgent := func(createdAt time.Time) *model.Agent {
+		return &model.Agent{
+			Outputs: map[string]*model.PolicyOutput{
+				output.Name: {
+					APIKey:          oldAPIKey.Agent(),
+					APIKeyCreatedAt: createdAt.UTC().Format(time.RFC3339),
+					APIKeyID:        oldAPIKey.ID,
+					PermissionsHash: "hash",
+					Type:            OutputTypeElasticsearch,
+				},
+			},
+		}
+	}
+
+	t.Run("API key younger than the maximum age is kept", func(t *testing.T) {
+		logger := testlog.SetLogger(t)
+		bulker := ftesting.NewMockBulk()
+		policyMap := map[string]map[string]interface{}{output.Name: {}}
+		testAgent := newAgent(time.Now().Add(-time.Minute))
+
+		err := output.Prepare(context.Background(), logger, bulker, testAgent, policyMap)
+		require.NoError(t, err)
+
+		assert.Equal(t, oldAPIKey.Agent(), policyMap[output.Name]["api_key"])
+		bulker.AssertNotCalled(t, "APIKeyCreate",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		bulker.AssertNotCalled(t, "Update",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("API key older than the maximum age is rotated", func(t *testing.T) {
+		logger := testlog.SetLogger(t)
+		bulker := ftesting.NewMockBulk()
+		apiKey := bulk.APIKey{ID: "new_id", Key: "new-key"}
+		bulker.On("APIKeyCreate",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(&apiKey, nil).Once()
+		bulker.On("Update", mock.Anything, dl.FleetAgents, mock.Anything, mock.MatchedBy(func(body []byte) bool {
+			return bytes.Contains(body, []byte(`"`+oldAPIKey.ID+`"`)) && bytes.Contains(body, []byte(dl.FieldPolicyOutputAPIKeyCreatedAt))
+		}), mock.Anything).Return(nil).Once()
+
+		policyMap := map[string]map[string]interface{}{output.Name: {}}
+		testAgent := newAgent(time.Now().Add(-2 * time.Hour))
+
+		err := output.Prepare(context.Background(), logger, bulker, testAgent, policyMap)
+		require.NoError(t, err)
+
+		gotOutput := testAgent.Outputs[output.Name]
+		assert.Equal(t, apiKey.Agent(), policyMap[output.Name]["api_key"])
+		assert.Equal(t, apiKey.ID, gotOutput.APIKeyID)
+		assert.False(t, apiKeyRotationDue(gotOutput, output.RotateAfter, time.Now()), "the new key has a fresh creation date")
+		bulker.AssertExpectations(t)
+	})
+}
+
 func TestPolicyRemoteESOutputPrepareNoRole(t *testing.T) {
 	logger := testlog.SetLogger(t)
 	bulker := ftesting.NewMockBulk()
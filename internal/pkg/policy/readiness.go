@@ -0,0 +1,133 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+type agentsPendingFetcher func(ctx context.Context, bulker bulk.Bulk, policyID string, revision int64, opt ...dl.Option) (int, error)
+
+// PolicyReadiness is the readiness of a policy with a Fleet Server input.
+type PolicyReadiness struct {
+	PolicyID string
+	// Revision is the latest revision of the policy.
+	Revision int64
+	// OutputsPrepared is set when the permissions needed to create the output API keys of the agents are defined
+	// for all the Elasticsearch outputs of the policy.
+	OutputsPrepared bool
+	// AgentsPending is the number of active agents of the policy that have not received the latest revision yet.
+	AgentsPending int
+	// Ready is set when the outputs are prepared and all the agents run the latest revision.
+	Ready bool
+	// Message describes why the policy is not ready.
+	Message string
+}
+
+// Readiness returns the readiness of the policies with a Fleet Server input, sorted by policy ID.
+//
+// The readiness is computed at most once per check interval.
+func (m *selfMonitorT) Readiness(ctx context.Context) []PolicyReadiness {
+	m.mut.Lock()
+	if m.readiness != nil && time.Since(m.readinessTime) < m.checkTime {
+		readiness := m.readiness
+		m.mut.Unlock()
+		return readiness
+	}
+	policies := make([]model.Policy, 0, len(m.serverPolicies))
+	for _, p := range m.serverPolicies {
+		policies = append(policies, p)
+	}
+	m.mut.Unlock()
+
+	readiness := make([]PolicyReadiness, 0, len(policies))
+	for _, p := range policies {
+		readiness = append(readiness, m.policyReadiness(ctx, p))
+	}
+	sort.Slice(readiness, func(i, j int) bool {
+		return readiness[i].PolicyID < readiness[j].PolicyID
+	})
+
+	m.mut.Lock()
+	m.readiness = readiness
+	m.readinessTime = time.Now()
+	m.mut.Unlock()
+	return readiness
+}
+
+// trackServerPolicies keeps the latest revision of the policies with a Fleet Server input.
+func (m *selfMonitorT) trackServerPolicies(latest map[string]model.Policy) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for id, p := range latest {
+		if p.Data == nil || !HasFleetServerInput(p.Data.Inputs) {
+			delete(m.serverPolicies, id)
+			continue
+		}
+		if prev, ok := m.serverPolicies[id]; ok && prev.RevisionIdx > p.RevisionIdx {
+			continue
+		}
+		m.serverPolicies[id] = p
+	}
+}
+
+func (m *selfMonitorT) policyReadiness(ctx context.Context, p model.Policy) PolicyReadiness {
+	r := PolicyReadiness{
+		PolicyID: p.PolicyID,
+		Revision: p.RevisionIdx,
+	}
+	if err := checkOutputsPrepared(p.Data); err != nil {
+		r.Message = err.Error()
+	} else {
+		r.OutputsPrepared = true
+	}
+
+	pending, err := m.agentsPendingF(ctx, m.bulker, p.PolicyID, p.RevisionIdx)
+	if err != nil {
+		m.log.Warn().Err(err).Str(logger.PolicyID, p.PolicyID).Msg("unable to get the agents pending the policy revision")
+		if r.Message == "" {
+			r.Message = err.Error()
+		}
+		return r
+	}
+	r.AgentsPending = pending
+	r.Ready = r.OutputsPrepared && pending == 0
+	if r.Message == "" && pending > 0 {
+		r.Message = fmt.Sprintf("%d agents have not received revision %d", pending, p.RevisionIdx)
+	}
+	return r
+}
+
+// checkOutputsPrepared checks that the permissions of the Elasticsearch outputs of the policy are defined.
+func checkOutputsPrepared(data *model.PolicyData) error {
+	roles, err := parsePerms(data.OutputPermissions)
+	if err != nil {
+		return fmt.Errorf("invalid output permissions: %w", err)
+	}
+	outputs, err := constructPolicyOutputs(data.Outputs, roles)
+	if err != nil {
+		return fmt.Errorf("invalid outputs: %w", err)
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o := outputs[name]
+		if (o.Type == OutputTypeElasticsearch || o.Type == OutputTypeRemoteElasticsearch) && o.Role == nil {
+			return fmt.Errorf("output %q has no permissions", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestSelfMonitorReadiness(t *testing.T) {
+	fleetServerInputs := []map[string]interface{}{{"type": "fleet-server"}}
+	policies := []model.Policy{{
+		PolicyID:    "ready",
+		RevisionIdx: 2,
+		Data: &model.PolicyData{
+			Outputs:           map[string]map[string]interface{}{"default": {"type": "elasticsearch"}},
+			OutputPermissions: []byte(`{"default":{"_elastic_agent_checks":{"cluster":["monitor"]}}}`),
+			Inputs:            fleetServerInputs,
+		},
+	}, {
+		PolicyID:    "pending",
+		RevisionIdx: 5,
+		Data: &model.PolicyData{
+			Outputs: map[string]map[string]interface{}{"default": {"type": "logstash"}},
+			Inputs:  fleetServerInputs,
+		},
+	}, {
+		PolicyID:    "no-permissions",
+		RevisionIdx: 1,
+		Data: &model.PolicyData{
+			Outputs: map[string]map[string]interface{}{"default": {"type": "elasticsearch"}},
+			Inputs:  fleetServerInputs,
+		},
+	}, {
+		PolicyID:    "count-error",
+		RevisionIdx: 1,
+		Data: &model.PolicyData{
+			Outputs: map[string]map[string]interface{}{"default": {"type": "logstash"}},
+			Inputs:  fleetServerInputs,
+		},
+	}, {
+		PolicyID:    "agents",
+		RevisionIdx: 1,
+		Data: &model.PolicyData{
+			Outputs: map[string]map[string]interface{}{"default": {"type": "logstash"}},
+			Inputs:  []map[string]interface{}{{"type": "logfile"}},
+		},
+	}}
+
+	m := NewSelfMonitor(config.Fleet{}, ftesting.NewMockBulk(), nil, "", nil).(*selfMonitorT)
+	calls := 0
+	m.agentsPendingF = func(_ context.Context, _ bulk.Bulk, policyID string, _ int64, _ ...dl.Option) (int, error) {
+		calls++
+		switch policyID {
+		case "pending":
+			return 3, nil
+		case "count-error":
+			return 0, errors.New("count failed")
+		}
+		return 0, nil
+	}
+	m.trackServerPolicies(m.groupByLatest(policies))
+
+	readiness := m.Readiness(context.Background())
+	require.Len(t, readiness, 4, "only the policies with a fleet-server input are reported")
+	assert.Equal(t, PolicyReadiness{PolicyID: "count-error", Revision: 1, OutputsPrepared: true, Message: "count failed"}, readiness[0])
+	assert.Equal(t, PolicyReadiness{PolicyID: "no-permissions", Revision: 1, Message: `output "default" has no permissions`}, readiness[1])
+	assert.Equal(t, PolicyReadiness{PolicyID: "pending", Revision: 5, OutputsPrepared: true, AgentsPending: 3, Message: "3 agents have not received revision 5"}, readiness[2])
+	assert.Equal(t, PolicyReadiness{PolicyID: "ready", Revision: 2, OutputsPrepared: true, Ready: true}, readiness[3])
+	assert.Equal(t, 4, calls)
+
+	t.Run("readiness is cached for the check interval", func(t *testing.T) {
+		assert.Equal(t, readiness, m.Readiness(context.Background()))
+		assert.Equal(t, 4, calls)
+	})
+}
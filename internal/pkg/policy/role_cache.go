@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultRoleCacheSize = 1024
+
+// roles is the role cache shared by the outputs of all policies.
+var roles = newRoleCache(defaultRoleCacheSize)
+
+type roleOp string
+
+const (
+	roleOpMerge     roleOp = "merge"
+	roleOpNamespace roleOp = "namespace"
+)
+
+// roleKey identifies a computed role by the operation and the hashes, or values, of its inputs.
+type roleKey struct {
+	op roleOp
+	a  string
+	b  string
+}
+
+// roleCache deduplicates the role descriptors computed for the output API keys of agents.
+//
+// The agents of a policy mostly hold API keys with the same role descriptors, so a role computed from
+// the same inputs is computed once and the same *RoleT is referenced by all agents. As the merged roles
+// share their hash, the bulker sends the API key updates of all those agents in a single request.
+// The cache is cleared once it holds maxSize roles.
+type roleCache struct {
+	mut     sync.Mutex
+	maxSize int
+	entries map[roleKey]*RoleT
+}
+
+func newRoleCache(maxSize int) *roleCache {
+	return &roleCache{
+		maxSize: maxSize,
+		entries: make(map[roleKey]*RoleT),
+	}
+}
+
+// get returns the role of key, it is computed with compute if it is not cached.
+// Errors are not cached.
+func (c *roleCache) get(key roleKey, compute func() (*RoleT, error)) (*RoleT, error) {
+	c.mut.Lock()
+	r, ok := c.entries[key]
+	c.mut.Unlock()
+	if ok {
+		return r, nil
+	}
+
+	r, err := compute()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if len(c.entries) >= c.maxSize {
+		clear(c.entries)
+	}
+	c.entries[key] = r
+	return r, nil
+}
+
+// merged returns the merge of the current roles of an API key and the new roles, see mergeRoles.
+func (c *roleCache) merged(current, new *RoleT, merge func() (*RoleT, error)) (*RoleT, error) {
+	if current == nil || new == nil {
+		return merge()
+	}
+	return c.get(roleKey{op: roleOpMerge, a: current.Sha2, b: new.Sha2}, merge)
+}
+
+// namespaced returns role with the replaced namespaces overridden by namespace, see overrideRoleNamespace.
+func (c *roleCache) namespaced(role *RoleT, namespace string, replaced map[string]struct{}) (*RoleT, error) {
+	names := make([]string, 0, len(replaced))
+	for ns := range replaced {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	key := roleKey{op: roleOpNamespace, a: role.Sha2, b: namespace + ":" + strings.Join(names, ",")}
+	return c.get(key, func() (*RoleT, error) {
+		return overrideRoleNamespace(role, namespace, replaced)
+	})
+}
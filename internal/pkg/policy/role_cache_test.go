@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+func TestRoleCacheGet(t *testing.T) {
+	c := newRoleCache(2)
+	calls := 0
+	compute := func() (*RoleT, error) {
+		calls++
+		return &RoleT{Sha2: "computed"}, nil
+	}
+
+	a, err := c.get(roleKey{op: roleOpMerge, a: "a", b: "b"}, compute)
+	require.NoError(t, err)
+	b, err := c.get(roleKey{op: roleOpMerge, a: "a", b: "b"}, compute)
+	require.NoError(t, err)
+	assert.Same(t, a, b, "the computed role is shared")
+	assert.Equal(t, 1, calls)
+
+	_, err = c.get(roleKey{op: roleOpNamespace, a: "a", b: "b"}, compute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "the operation is part of the key")
+
+	_, err = c.get(roleKey{op: roleOpMerge, a: "a", b: "c"}, func() (*RoleT, error) {
+		return nil, errors.New("failed")
+	})
+	require.Error(t, err)
+	assert.Len(t, c.entries, 2, "errors are not cached")
+
+	_, err = c.get(roleKey{op: roleOpMerge, a: "a", b: "d"}, compute)
+	require.NoError(t, err)
+	assert.Len(t, c.entries, 1, "the cache is cleared once full")
+}
+
+func TestRoleCacheMerged(t *testing.T) {
+	log := testlog.SetLogger(t)
+	c := newRoleCache(defaultRoleCacheSize)
+	current := &RoleT{Sha2: "current", Raw: []byte(`{"monitor":{"cluster":["monitor"]}}`)}
+	updated := &RoleT{Sha2: "new", Raw: []byte(`{"read":{"cluster":["read"]}}`)}
+
+	calls := 0
+	merge := func() (*RoleT, error) {
+		calls++
+		return mergeRoles(log, current, updated)
+	}
+	first, err := c.merged(current, updated, merge)
+	require.NoError(t, err)
+	second, err := c.merged(&RoleT{Sha2: "current"}, updated, merge)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "keys with the same current roles share the merged roles")
+	assert.Equal(t, 1, calls)
+	assert.JSONEq(t, `{"read":{"cluster":["read"]},"monitor-0-rdstale":{"cluster":["monitor"]}}`, string(first.Raw))
+}
+
+func TestRoleCacheNamespaced(t *testing.T) {
+	c := newRoleCache(defaultRoleCacheSize)
+	role := &RoleT{Sha2: "hash", Raw: []byte(`{"write":{"indices":[{"names":["logs-*-default"],"privileges":["create_doc"]}]}}`)}
+	replaced := map[string]struct{}{"default": {}}
+
+	first, err := c.namespaced(role, "team", replaced)
+	require.NoError(t, err)
+	second, err := c.namespaced(role, "team", map[string]struct{}{"default": {}})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.JSONEq(t, `{"write":{"indices":[{"names":["logs-*-team"],"privileges":["create_doc"]}]}}`, string(first.Raw))
+
+	other, err := c.namespaced(role, "other", replaced)
+	require.NoError(t, err)
+	assert.NotSame(t, first, other)
+}
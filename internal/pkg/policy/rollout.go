@@ -0,0 +1,224 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/logger"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+/*
+Staged rollout
+
+When rollouts are enabled a new revision of a policy that is already known to the monitor
+is not released to all subscribers at once. The agents are split into 100 stable cohorts by
+hashing their ID; each stage of the rollout releases the revision to the cohorts below the
+stage percentage, the other agents keep receiving the revision that was fully released before.
+
+The agents that run the new revision report their health on checkin. Once a stage has lasted
+the configured duration without exceeding the unhealthy threshold the next stage starts. If the
+threshold is exceeded the rollout is held at its current stage, and optionally rolled back so
+the agents of the rollout are sent the previous revision again. A held rollout only resumes with
+a new revision of the policy.
+
+The rollout state is kept in memory, a restarted fleet-server releases the latest revision to
+all agents.
+*/
+
+type rolloutState int
+
+const (
+	rolloutProgressing rolloutState = iota
+	rolloutHeld
+	rolloutRolledBack
+)
+
+func (s rolloutState) String() string {
+	switch s {
+	case rolloutProgressing:
+		return "progressing"
+	case rolloutHeld:
+		return "held"
+	case rolloutRolledBack:
+		return "rolled_back"
+	default:
+		return "unknown"
+	}
+}
+
+// rolloutT tracks the staged release of a policy revision.
+type rolloutT struct {
+	// prev is the last revision that was released to all agents.
+	prev       ParsedPolicy
+	stage      int
+	stageStart time.Time
+	state      rolloutState
+	// health is the last status reported by each agent that runs the new revision.
+	health map[string]bool
+}
+
+func newRollout(prev ParsedPolicy, now time.Time) *rolloutT {
+	return &rolloutT{
+		prev:       prev,
+		stageStart: now,
+		health:     make(map[string]bool),
+	}
+}
+
+// agentCohort returns the stable cohort, between 0 and 99, of an agent.
+func agentCohort(agentID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(agentID))
+	return int(h.Sum32() % 100)
+}
+
+// target returns the revision that should be delivered to the agent.
+func (r *rolloutT) target(cfg *config.PolicyRollout, latest *ParsedPolicy, agentID string) *ParsedPolicy {
+	if r.state == rolloutRolledBack {
+		return &r.prev
+	}
+	if agentCohort(agentID) < cfg.Stages[r.stage] {
+		return latest
+	}
+	return &r.prev
+}
+
+// counts returns the number of agents on the new revision that reported health, and how many of them are unhealthy.
+func (r *rolloutT) counts() (int, int) {
+	unhealthy := 0
+	for _, healthy := range r.health {
+		if !healthy {
+			unhealthy++
+		}
+	}
+	return len(r.health), unhealthy
+}
+
+// degraded returns true if enough agents reported health and too many of them are unhealthy.
+func (r *rolloutT) degraded(cfg *config.PolicyRollout) bool {
+	total, unhealthy := r.counts()
+	if total == 0 || total < cfg.MinAgents {
+		return false
+	}
+	return float64(unhealthy)/float64(total) > cfg.MaxUnhealthy
+}
+
+// stagedRollout returns true if the new policy revision should be released in stages.
+// A policy seen for the first time, or a change that only bumps the coordinator, is released at once.
+func (m *monitorT) stagedRollout(p policyT, newPolicy *model.Policy) bool {
+	return m.rollout != nil &&
+		newPolicy.PolicyID != cloudPolicyID &&
+		p.pp.Policy.CoordinatorIdx > 0 &&
+		newPolicy.RevisionIdx > p.pp.Policy.RevisionIdx
+}
+
+// target returns the policy revision that should be delivered to the agent.
+func (m *monitorT) target(p policyT, agentID string) *ParsedPolicy {
+	if p.rollout == nil {
+		return &p.pp
+	}
+	return p.rollout.target(m.rollout, &p.pp, agentID)
+}
+
+// needsUpdate returns true if the subscription should be sent its target revision.
+func (m *monitorT) needsUpdate(p policyT, s *subT) bool {
+	pp := m.target(p, s.agentID)
+	if s.isUpdate(&pp.Policy) {
+		return true
+	}
+	// Agents that received the revision of a rolled back rollout are sent the previous revision again.
+	return p.rollout != nil && p.rollout.state == rolloutRolledBack && s.revIdx > pp.Policy.RevisionIdx
+}
+
+// ReportHealth records the checkin status of an agent running the passed policy revision.
+func (m *monitorT) ReportHealth(agentID string, policyID string, revisionIdx int64, healthy bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	p, ok := m.policies[policyID]
+	if !ok || p.rollout == nil || p.pp.Policy.RevisionIdx != revisionIdx {
+		return
+	}
+	p.rollout.health[agentID] = healthy
+}
+
+// RolledBackRevision returns the revision that is redelivered to the agents of a rolled back rollout.
+func (m *monitorT) RolledBackRevision(policyID string) (int64, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	p, ok := m.policies[policyID]
+	if !ok || p.rollout == nil || p.rollout.state != rolloutRolledBack {
+		return 0, false
+	}
+	return p.rollout.prev.Policy.RevisionIdx, true
+}
+
+// evaluateRollouts holds degraded rollouts and moves healthy ones to their next stage.
+// It returns true if subscriptions were queued for delivery.
+func (m *monitorT) evaluateRollouts(now time.Time) bool {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	nQueued := 0
+	for policyID, p := range m.policies {
+		r := p.rollout
+		// Debounced changes are queued once they are flushed.
+		if r == nil || r.state != rolloutProgressing || p.debounce != nil {
+			continue
+		}
+		total, unhealthy := r.counts()
+		zlog := m.log.With().
+			Str(logger.PolicyID, policyID).
+			Int64(logger.RevisionIdx, p.pp.Policy.RevisionIdx).
+			Int("stage_percent", m.rollout.Stages[r.stage]).
+			Int("agents_reported", total).
+			Int("agents_unhealthy", unhealthy).
+			Logger()
+
+		switch {
+		case r.degraded(m.rollout):
+			r.state = rolloutHeld
+			if m.rollout.Rollback {
+				r.state = rolloutRolledBack
+			}
+			zlog.Warn().
+				Str("rollout_state", r.state.String()).
+				Int64("released_revision_idx", r.prev.Policy.RevisionIdx).
+				Msg("Staged rollout of policy revision stopped, too many agents are unhealthy")
+		case now.Sub(r.stageStart) >= m.rollout.StageDuration:
+			r.stage++
+			r.stageStart = now
+			if r.stage >= len(m.rollout.Stages) || m.rollout.Stages[r.stage] >= 100 {
+				p.rollout = nil
+				m.policies[policyID] = p
+				zlog.Info().Msg("Staged rollout of policy revision complete")
+			} else {
+				zlog.Info().Int("next_stage_percent", m.rollout.Stages[r.stage]).Msg("Staged rollout of policy revision moved to next stage")
+			}
+		default:
+			continue
+		}
+
+		nQueued += m.queueUpdates(p)
+	}
+	return nQueued > 0
+}
+
+// queueUpdates moves the subscriptions of the policy that need an update to the pending queue.
+func (m *monitorT) queueUpdates(p policyT) int {
+	nQueued := 0
+	iter := NewIterator(p.head)
+	for sub := iter.Next(); sub != nil; sub = iter.Next() {
+		if m.needsUpdate(p, sub) {
+			iter.Unlink()
+			m.pushPending(sub, false)
+			nQueued++
+		}
+	}
+	return nQueued
+}
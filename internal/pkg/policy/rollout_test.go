@@ -0,0 +1,187 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+func testRolloutConfig() config.PolicyRollout {
+	return config.PolicyRollout{
+		Enabled:       true,
+		Stages:        []int{50, 100},
+		StageDuration: time.Minute,
+		MinAgents:     2,
+		MaxUnhealthy:  0.2,
+		Rollback:      true,
+	}
+}
+
+// rolloutAgents returns an agent ID inside and one outside of the cohort of the passed percentage.
+func rolloutAgents(t *testing.T, pct int) (string, string) {
+	t.Helper()
+	var in, out string
+	for i := 0; in == "" || out == ""; i++ {
+		id := fmt.Sprintf("agent-%d", i)
+		if agentCohort(id) < pct {
+			in = id
+		} else {
+			out = id
+		}
+	}
+	return in, out
+}
+
+func rolloutPolicy(rev int64) *ParsedPolicy {
+	return &ParsedPolicy{Policy: model.Policy{
+		PolicyID:       "policy",
+		RevisionIdx:    rev,
+		CoordinatorIdx: 1,
+		Data:           policyDataDefault,
+	}}
+}
+
+func pendingAgents(m *monitorT) []string {
+	var agents []string
+	for s := m.pendingQ.popFront(); s != nil; s = m.pendingQ.popFront() {
+		agents = append(agents, s.agentID)
+	}
+	return agents
+}
+
+func TestAgentCohort(t *testing.T) {
+	counts := make([]int, 2)
+	for i := 0; i < 1000; i++ {
+		c := agentCohort(fmt.Sprintf("agent-%d", i))
+		require.GreaterOrEqual(t, c, 0)
+		require.Less(t, c, 100)
+		counts[c/50]++
+	}
+	assert.Equal(t, agentCohort("agent-1"), agentCohort("agent-1"))
+	assert.InDelta(t, 500, counts[0], 100)
+}
+
+func TestRolloutStages(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithRollout(testRolloutConfig())).(*monitorT)
+	canary, other := rolloutAgents(t, 50)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	_, err := m.Subscribe(canary, "policy", 1, 1)
+	require.NoError(t, err)
+	_, err = m.Subscribe(other, "policy", 1, 1)
+	require.NoError(t, err)
+	require.True(t, m.pendingQ.isEmpty())
+
+	m.updatePolicy(ctx, rolloutPolicy(2))
+	p := m.policies["policy"]
+	require.NotNil(t, p.rollout)
+	assert.Equal(t, int64(2), m.target(p, canary).Policy.RevisionIdx)
+	assert.Equal(t, int64(1), m.target(p, other).Policy.RevisionIdx)
+	assert.Equal(t, []string{canary}, pendingAgents(m))
+
+	// the stage has not lasted long enough
+	assert.False(t, m.evaluateRollouts(time.Now()))
+
+	assert.True(t, m.evaluateRollouts(time.Now().Add(2*time.Minute)))
+	assert.Equal(t, []string{other}, pendingAgents(m))
+	p = m.policies["policy"]
+	assert.Nil(t, p.rollout)
+	assert.Equal(t, int64(2), m.target(p, other).Policy.RevisionIdx)
+}
+
+func TestRolloutRollback(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithRollout(testRolloutConfig())).(*monitorT)
+	canary, other := rolloutAgents(t, 50)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	m.updatePolicy(ctx, rolloutPolicy(2))
+
+	// the canary runs the new revision, the other agent is not part of the first stage
+	_, err := m.Subscribe(canary, "policy", 2, 1)
+	require.NoError(t, err)
+	_, err = m.Subscribe(other, "policy", 1, 1)
+	require.NoError(t, err)
+	require.True(t, m.pendingQ.isEmpty())
+
+	// a single report is not enough to stop the rollout
+	m.ReportHealth(canary, "policy", 2, false)
+	assert.False(t, m.evaluateRollouts(time.Now()))
+
+	// reports for other revisions are ignored
+	m.ReportHealth("agent-old", "policy", 1, false)
+	assert.False(t, m.evaluateRollouts(time.Now()))
+
+	m.ReportHealth("agent-x", "policy", 2, true)
+	assert.True(t, m.evaluateRollouts(time.Now()))
+	assert.Equal(t, []string{canary}, pendingAgents(m))
+
+	p := m.policies["policy"]
+	assert.Equal(t, rolloutRolledBack, p.rollout.state)
+	assert.Equal(t, int64(1), m.target(p, canary).Policy.RevisionIdx)
+	rev, ok := m.RolledBackRevision("policy")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), rev)
+
+	// a rolled back rollout does not advance
+	assert.False(t, m.evaluateRollouts(time.Now().Add(time.Hour)))
+
+	// a new revision starts a new rollout from the first stage
+	m.updatePolicy(ctx, rolloutPolicy(3))
+	p = m.policies["policy"]
+	assert.Equal(t, rolloutProgressing, p.rollout.state)
+	assert.Equal(t, int64(1), p.rollout.prev.Policy.RevisionIdx)
+	assert.Equal(t, int64(3), m.target(p, canary).Policy.RevisionIdx)
+	_, ok = m.RolledBackRevision("policy")
+	assert.False(t, ok)
+}
+
+func TestRolloutHold(t *testing.T) {
+	cfg := testRolloutConfig()
+	cfg.Rollback = false
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}, WithRollout(cfg)).(*monitorT)
+	canary, other := rolloutAgents(t, 50)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	m.updatePolicy(ctx, rolloutPolicy(2))
+	_, err := m.Subscribe(other, "policy", 1, 1)
+	require.NoError(t, err)
+
+	m.ReportHealth(canary, "policy", 2, false)
+	m.ReportHealth("agent-x", "policy", 2, false)
+	assert.False(t, m.evaluateRollouts(time.Now().Add(time.Hour)))
+
+	p := m.policies["policy"]
+	assert.Equal(t, rolloutHeld, p.rollout.state)
+	assert.Equal(t, int64(2), m.target(p, canary).Policy.RevisionIdx)
+	assert.Equal(t, int64(1), m.target(p, other).Policy.RevisionIdx)
+	assert.True(t, m.pendingQ.isEmpty())
+}
+
+func TestRolloutDisabled(t *testing.T) {
+	ctx := context.Background()
+	m := NewMonitor(nil, nil, config.ServerLimits{}).(*monitorT)
+	_, other := rolloutAgents(t, 50)
+
+	m.updatePolicy(ctx, rolloutPolicy(1))
+	_, err := m.Subscribe(other, "policy", 1, 1)
+	require.NoError(t, err)
+	m.updatePolicy(ctx, rolloutPolicy(2))
+	assert.Nil(t, m.policies["policy"].rollout)
+	assert.Equal(t, []string{other}, pendingAgents(m))
+}
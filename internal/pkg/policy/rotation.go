@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+// WithAPIKeyRotation returns a copy of outputs where the agent API keys of the Elasticsearch outputs are
+// replaced once they are older than maxAge.
+func WithAPIKeyRotation(outputs map[string]Output, maxAge time.Duration) map[string]Output {
+	res := make(map[string]Output, len(outputs))
+	for name, o := range outputs {
+		if o.Type == OutputTypeElasticsearch || o.Type == OutputTypeRemoteElasticsearch {
+			o.RotateAfter = maxAge
+		}
+		res[name] = o
+	}
+	return res
+}
+
+// APIKeyRotationDue returns true if one of the output API keys of the agent is older than maxAge.
+func APIKeyRotationDue(agent *model.Agent, maxAge time.Duration, now time.Time) bool {
+	for _, output := range agent.Outputs {
+		if output != nil && output.APIKeyID != "" && apiKeyRotationDue(output, maxAge, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyRotationDue returns true if the API key of output is older than maxAge.
+// Keys without a valid creation date, created by older fleet-server versions, are always due.
+func apiKeyRotationDue(output *model.PolicyOutput, maxAge time.Duration, now time.Time) bool {
+	createdAt, err := time.Parse(time.RFC3339, output.APIKeyCreatedAt)
+	if err != nil {
+		return true
+	}
+	return now.Sub(createdAt) >= maxAge
+}
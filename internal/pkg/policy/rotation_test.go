@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+)
+
+func TestWithAPIKeyRotation(t *testing.T) {
+	outputs := map[string]Output{
+		"es":     {Name: "es", Type: OutputTypeElasticsearch},
+		"remote": {Name: "remote", Type: OutputTypeRemoteElasticsearch},
+		"ls":     {Name: "ls", Type: OutputTypeLogstash},
+	}
+
+	res := WithAPIKeyRotation(outputs, time.Hour)
+	assert.Equal(t, time.Hour, res["es"].RotateAfter)
+	assert.Equal(t, time.Hour, res["remote"].RotateAfter)
+	assert.Zero(t, res["ls"].RotateAfter)
+	assert.Zero(t, outputs["es"].RotateAfter, "the passed outputs are not changed")
+}
+
+func TestAPIKeyRotationDue(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	agent := func(createdAt string) *model.Agent {
+		return &model.Agent{Outputs: map[string]*model.PolicyOutput{
+			"default": {APIKeyID: "id", APIKeyCreatedAt: createdAt},
+		}}
+	}
+
+	tests := []struct {
+		name  string
+		agent *model.Agent
+		due   bool
+	}{{
+		name:  "recent key",
+		agent: agent(now.Add(-time.Minute).Format(time.RFC3339)),
+	}, {
+		name:  "old key",
+		agent: agent(now.Add(-2 * time.Hour).Format(time.RFC3339)),
+		due:   true,
+	}, {
+		name:  "key without creation date",
+		agent: agent(""),
+		due:   true,
+	}, {
+		name:  "invalid creation date",
+		agent: agent("yesterday"),
+		due:   true,
+	}, {
+		name:  "output without key",
+		agent: &model.Agent{Outputs: map[string]*model.PolicyOutput{"default": {}}},
+	}, {
+		name:  "no outputs",
+		agent: &model.Agent{},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.due, APIKeyRotationDue(tc.agent, time.Hour, now))
+		})
+	}
+}
@@ -19,7 +19,8 @@ var (
 )
 
 // read secret values that belong to the agent policy's secret references, returns secrets as id:value map
-func getSecretValues(ctx context.Context, secretRefs []model.SecretReferencesItems, bulker bulk.Bulk) (map[string]string, error) {
+// values are served from the secrets cache for the passed policy revision when possible
+func getSecretValues(ctx context.Context, secretRefs []model.SecretReferencesItems, bulker bulk.Bulk, secretCache *SecretCache, policyID string, revision int64) (map[string]string, error) {
 	if len(secretRefs) == 0 {
 		return nil, nil
 	}
@@ -29,7 +30,7 @@ func getSecretValues(ctx context.Context, secretRefs []model.SecretReferencesIte
 		ids = append(ids, ref.ID)
 	}
 
-	results, err := bulker.ReadSecrets(ctx, ids)
+	results, err := secretCache.Read(ctx, bulker, policyID, revision, ids)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +40,7 @@ func getSecretValues(ctx context.Context, secretRefs []model.SecretReferencesIte
 
 // read inputs and secret_references from agent policy
 // replace values of secret refs in inputs and input streams properties
-func getPolicyInputsWithSecrets(ctx context.Context, data *model.PolicyData, bulker bulk.Bulk) ([]map[string]interface{}, error) {
+func getPolicyInputsWithSecrets(ctx context.Context, data *model.PolicyData, bulker bulk.Bulk, secretCache *SecretCache, policyID string, revision int64) ([]map[string]interface{}, error) {
 	if len(data.Inputs) == 0 {
 		return nil, nil
 	}
@@ -48,7 +49,7 @@ func getPolicyInputsWithSecrets(ctx context.Context, data *model.PolicyData, bul
 		return data.Inputs, nil
 	}
 
-	secretValues, err := getSecretValues(ctx, data.SecretReferences, bulker)
+	secretValues, err := getSecretValues(ctx, data.SecretReferences, bulker, secretCache, policyID, revision)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +147,10 @@ func setSecretPath(output smap.Map, secretValue string, secretPaths []string) er
 
 // Read secret from output and mutate output with secret value
 func ProcessOutputSecret(ctx context.Context, output smap.Map, bulker bulk.Bulk) error {
+	return processOutputSecret(ctx, output, bulker, nil, "", 0)
+}
+
+func processOutputSecret(ctx context.Context, output smap.Map, bulker bulk.Bulk, secretCache *SecretCache, policyID string, revision int64) error {
 	secrets := output.GetMap(FieldOutputSecrets)
 
 	delete(output, FieldOutputSecrets)
@@ -163,7 +168,7 @@ func ProcessOutputSecret(ctx context.Context, output smap.Map, bulker bulk.Bulk)
 	if len(secretReferences) == 0 {
 		return nil
 	}
-	secretValues, err := getSecretValues(ctx, secretReferences, bulker)
+	secretValues, err := getSecretValues(ctx, secretReferences, bulker, secretCache, policyID, revision)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,132 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+)
+
+// Counter is incremented by the secret cache to report its activity.
+type Counter interface {
+	Add(delta uint64)
+}
+
+// SecretCacheMetrics are the counters a SecretCache reports to, nil counters are ignored.
+type SecretCacheMetrics struct {
+	Hits      Counter
+	Misses    Counter
+	Evictions Counter
+}
+
+type secretKey struct {
+	policyID string
+	revision int64
+	id       string
+}
+
+type secretEntry struct {
+	value   string
+	expires time.Time
+}
+
+// SecretCache caches the resolved values of policy secret references.
+//
+// Entries are keyed by the secret ID and the ID and revision of the policy that references it, so a new
+// revision of a policy always reads its secrets from Elasticsearch again. Once secrets are read for
+// a revision of a policy, the entries of the older revisions of the policy are dropped, the entries of
+// the other policies are kept. Entries also expire after a TTL.
+// A single cache is shared by all policies of the monitor; a nil *SecretCache reads every secret.
+type SecretCache struct {
+	mut     sync.Mutex
+	ttl     time.Duration
+	entries map[secretKey]secretEntry
+	metrics SecretCacheMetrics
+
+	now func() time.Time
+}
+
+// NewSecretCache creates a secret cache, it returns nil if ttl is not positive.
+func NewSecretCache(ttl time.Duration, metrics SecretCacheMetrics) *SecretCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &SecretCache{
+		ttl:     ttl,
+		entries: make(map[secretKey]secretEntry),
+		metrics: metrics,
+		now:     time.Now,
+	}
+}
+
+// Read returns the values of the secrets referenced by the passed policy revision as an id:value map.
+// Secrets that are not cached, or expired, are read with the bulker.
+func (c *SecretCache) Read(ctx context.Context, bulker bulk.Bulk, policyID string, revision int64, ids []string) (map[string]string, error) {
+	if c == nil {
+		return bulker.ReadSecrets(ctx, ids)
+	}
+
+	result := make(map[string]string, len(ids))
+	missing := make([]string, 0, len(ids))
+
+	c.mut.Lock()
+	now := c.now()
+	for _, id := range ids {
+		e, ok := c.entries[secretKey{policyID: policyID, revision: revision, id: id}]
+		if ok && now.Before(e.expires) {
+			result[id] = e.value
+			continue
+		}
+		missing = append(missing, id)
+	}
+	c.mut.Unlock()
+
+	addCount(c.metrics.Hits, len(result))
+	if len(missing) == 0 {
+		return result, nil
+	}
+	addCount(c.metrics.Misses, len(missing))
+
+	values, err := bulker.ReadSecrets(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	now = c.now()
+	evicted := c.evict(now, policyID, revision)
+	for id, value := range values {
+		c.entries[secretKey{policyID: policyID, revision: revision, id: id}] = secretEntry{
+			value:   value,
+			expires: now.Add(c.ttl),
+		}
+		result[id] = value
+	}
+	addCount(c.metrics.Evictions, evicted)
+	return result, nil
+}
+
+// evict removes expired entries, and the entries of the older revisions of the policy.
+// The caller must hold the lock.
+func (c *SecretCache) evict(now time.Time, policyID string, revision int64) int {
+	evicted := 0
+	for k, e := range c.entries {
+		if !now.Before(e.expires) || (k.policyID == policyID && k.revision < revision) {
+			delete(c.entries, k)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+func addCount(c Counter, n int) {
+	if c != nil && n > 0 {
+		c.Add(uint64(n))
+	}
+}
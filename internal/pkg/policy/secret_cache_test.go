@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+// countingBulk records the secrets read from Elasticsearch.
+type countingBulk struct {
+	*ftesting.MockBulk
+	reads []string
+}
+
+func (b *countingBulk) ReadSecrets(ctx context.Context, secretIds []string) (map[string]string, error) {
+	b.reads = append(b.reads, secretIds...)
+	return b.MockBulk.ReadSecrets(ctx, secretIds)
+}
+
+type testCounter struct {
+	n uint64
+}
+
+func (c *testCounter) Add(delta uint64) {
+	c.n += delta
+}
+
+func TestSecretCache(t *testing.T) {
+	ctx := context.Background()
+	bulker := &countingBulk{MockBulk: ftesting.NewMockBulk()}
+	hits, misses, evictions := &testCounter{}, &testCounter{}, &testCounter{}
+	c := NewSecretCache(time.Minute, SecretCacheMetrics{Hits: hits, Misses: misses, Evictions: evictions})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	values, err := c.Read(ctx, bulker, "p1", 1, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "a_value", "b": "b_value"}, values)
+	assert.Equal(t, []string{"a", "b"}, bulker.reads)
+
+	// the same revision is served from the cache
+	values, err = c.Read(ctx, bulker, "p1", 1, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "a_value", "b": "b_value"}, values)
+	assert.Len(t, bulker.reads, 2)
+
+	// another policy with a more recent revision does not evict the entries of the policy
+	_, err = c.Read(ctx, bulker, "p2", 5, []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "a"}, bulker.reads)
+	assert.Contains(t, c.entries, secretKey{policyID: "p1", revision: 1, id: "a"})
+	_, err = c.Read(ctx, bulker, "p1", 1, []string{"a"})
+	require.NoError(t, err)
+	assert.Len(t, bulker.reads, 3)
+
+	// a new revision reads the secrets again and drops the entries of the old revisions of the policy
+	_, err = c.Read(ctx, bulker, "p1", 2, []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "a", "a"}, bulker.reads)
+	assert.NotContains(t, c.entries, secretKey{policyID: "p1", revision: 1, id: "a"})
+	assert.NotContains(t, c.entries, secretKey{policyID: "p1", revision: 1, id: "b"})
+	assert.Contains(t, c.entries, secretKey{policyID: "p2", revision: 5, id: "a"})
+
+	// expired entries are read again
+	now = now.Add(2 * time.Minute)
+	_, err = c.Read(ctx, bulker, "p1", 2, []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "a", "a", "a"}, bulker.reads)
+	assert.Len(t, c.entries, 1)
+
+	assert.Equal(t, uint64(3), hits.n)
+	assert.Equal(t, uint64(5), misses.n)
+	assert.Equal(t, uint64(4), evictions.n)
+}
+
+func TestSecretCacheDisabled(t *testing.T) {
+	bulker := &countingBulk{MockBulk: ftesting.NewMockBulk()}
+	c := NewSecretCache(0, SecretCacheMetrics{})
+	require.Nil(t, c)
+
+	for i := 0; i < 2; i++ {
+		values, err := c.Read(context.Background(), bulker, "p1", 1, []string{"a"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "a_value"}, values)
+	}
+	assert.Equal(t, []string{"a", "a"}, bulker.reads)
+}
@@ -79,7 +79,7 @@ func TestGetSecretValues(t *testing.T) {
 	refs := []model.SecretReferencesItems{{ID: "ref1"}, {ID: "ref2"}}
 	bulker := ftesting.NewMockBulk()
 
-	secretRefs, _ := getSecretValues(context.TODO(), refs, bulker)
+	secretRefs, _ := getSecretValues(context.TODO(), refs, bulker, nil, "policy", 1)
 
 	expectedRefs := map[string]string{
 		"ref1": "ref1_value",
@@ -119,7 +119,7 @@ func TestGetPolicyInputsWithSecretsAndStreams(t *testing.T) {
 		{"id": "input2", "streams": []interface{}{expectedStream}},
 	}
 
-	result, _ := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker)
+	result, _ := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker, nil, "policy", 1)
 
 	assert.Equal(t, expectedResult, result)
 	assert.Nil(t, pData.SecretReferences)
@@ -165,7 +165,7 @@ func TestPolicyInputSteamsEmbedded(t *testing.T) {
 		}},
 	}
 
-	result, err := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker)
+	result, err := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker, nil, "policy", 1)
 	require.NoError(t, err)
 
 	assert.Equal(t, expected, result)
@@ -193,7 +193,7 @@ func TestGetPolicyInputsNoopWhenNoSecrets(t *testing.T) {
 		{"id": "input2", "streams": []interface{}{expectedStream}},
 	}
 
-	result, _ := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker)
+	result, _ := getPolicyInputsWithSecrets(context.TODO(), &pData, bulker, nil, "policy", 1)
 
 	assert.Equal(t, expectedResult, result)
 }
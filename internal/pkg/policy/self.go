@@ -39,6 +39,8 @@ type SelfMonitor interface {
 	Run(ctx context.Context) error
 	// State gets current state of monitor.
 	State() client.UnitState
+	// Readiness gets the readiness of the policies with a Fleet Server input.
+	Readiness(ctx context.Context) []PolicyReadiness
 }
 
 type selfMonitorT struct {
@@ -58,8 +60,13 @@ type selfMonitorT struct {
 	policyF          policyFetcher
 	policiesIndex    string
 	enrollmentTokenF enrollmentTokenFetcher
+	agentsPendingF   agentsPendingFetcher
 	checkTime        time.Duration
 
+	serverPolicies map[string]model.Policy
+	readiness      []PolicyReadiness
+	readinessTime  time.Time
+
 	startCh chan struct{}
 }
 
@@ -78,7 +85,9 @@ func NewSelfMonitor(fleet config.Fleet, bulker bulk.Bulk, monitor monitor.Monito
 		policyF:          dl.QueryLatestPolicies,
 		policiesIndex:    dl.FleetPolicies,
 		enrollmentTokenF: findEnrollmentAPIKeys,
+		agentsPendingF:   dl.CountAgentsPendingRevision,
 		checkTime:        DefaultCheckTime,
+		serverPolicies:   make(map[string]model.Policy),
 		startCh:          make(chan struct{}),
 	}
 }
@@ -170,6 +179,7 @@ func (m *selfMonitorT) processPolicies(ctx context.Context, policies []model.Pol
 		return client.UnitStateStarting, nil
 	}
 	latest := m.groupByLatest(policies)
+	m.trackServerPolicies(latest)
 	for i := range latest {
 		policy := latest[i]
 		if m.policyID != "" && policy.PolicyID == m.policyID {
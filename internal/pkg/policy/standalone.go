@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
 	"github.com/elastic/fleet-server/v7/internal/pkg/state"
 	"github.com/rs/zerolog"
 	"go.elastic.co/apm/v2"
@@ -50,6 +51,20 @@ func NewStandAloneSelfMonitor(bulker bulk.Bulk, reporter state.Reporter) *standA
 	}
 }
 
+// NewFileSelfMonitor creates the self policy monitor for a stand-alone Fleet Server serving policies from files.
+//
+// Checks that the policy files could be loaded.
+func NewFileSelfMonitor(bulker bulk.Bulk, reporter state.Reporter, src *FileSource) *standAloneSelfMonitorT {
+	m := NewStandAloneSelfMonitor(bulker, reporter)
+	m.policyF = func(ctx context.Context, bulker bulk.Bulk, opt ...dl.Option) ([]model.Policy, error) {
+		if err := src.Err(); err != nil {
+			return nil, err
+		}
+		return src.QueryLatestPolicies(ctx, bulker, opt...)
+	}
+	return m
+}
+
 // Run runs the monitor.
 func (m *standAloneSelfMonitorT) Run(ctx context.Context) error {
 	m.log = zerolog.Ctx(ctx).With().Str("ctx", "policy self monitor").Logger()
@@ -81,6 +96,11 @@ func (m *standAloneSelfMonitorT) State() client.UnitState {
 	return m.state
 }
 
+// Readiness returns nil, the stand-alone monitor does not track the policies.
+func (m *standAloneSelfMonitorT) Readiness(_ context.Context) []PolicyReadiness {
+	return nil
+}
+
 func (m *standAloneSelfMonitorT) check(ctx context.Context) {
 	ctx, cancel := context.WithTimeout(ctx, m.checkTimeout)
 	defer cancel()
@@ -0,0 +1,164 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	"github.com/elastic/fleet-server/v7/internal/pkg/smap"
+)
+
+// ValidationIssue is a problem found while validating a policy.
+type ValidationIssue struct {
+	// Path is the dotted path of the policy attribute the issue relates to.
+	Path    string
+	Message string
+}
+
+// ValidationResult holds the outcome of validating a policy.
+// Errors would prevent the policy from being dispatched to agents, warnings would not.
+type ValidationResult struct {
+	Errors   []ValidationIssue
+	Warnings []ValidationIssue
+}
+
+// Valid returns true if no errors were found.
+func (r *ValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *ValidationResult) addError(path string, err error) {
+	r.Errors = append(r.Errors, ValidationIssue{Path: path, Message: err.Error()})
+}
+
+func (r *ValidationResult) addWarning(path, msg string) {
+	r.Warnings = append(r.Warnings, ValidationIssue{Path: path, Message: msg})
+}
+
+// Validate runs the policy data through the parsing steps the monitor applies to a new policy revision,
+// and through the checks done when outputs are prepared for an agent.
+//
+// Unlike newParsedPolicy, it does not stop on the first error so all issues are reported at once.
+// Secret references are resolved with the bulker, but no output API keys are created.
+// The passed data is mutated.
+func Validate(ctx context.Context, bulker bulk.Bulk, data *model.PolicyData) *ValidationResult {
+	res := &ValidationResult{}
+
+	roles, err := parsePerms(data.OutputPermissions)
+	if err != nil {
+		res.addError(FieldOutputPermissions, fmt.Errorf("%w: %w", ErrInvalidPermissionsFormat, err))
+	}
+
+	if len(data.Outputs) == 0 {
+		res.addError(FieldOutputs, ErrOutputsNotFound)
+		return res
+	}
+
+	names := make([]string, 0, len(data.Outputs))
+	for name := range data.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs, err := constructPolicyOutputs(data.Outputs, roles)
+	if err != nil {
+		res.addError(FieldOutputs, err)
+	}
+	for _, name := range names {
+		path := FieldOutputs + "." + name
+		if err := processOutputSecret(ctx, data.Outputs[name], bulker, nil, "", 0); err != nil {
+			res.addError(path+"."+FieldOutputSecrets, err)
+		}
+		if outputs == nil {
+			continue
+		}
+		validateOutput(res, outputs[name], data.Outputs[name], roles == nil)
+	}
+
+	permNames := make([]string, 0, len(roles))
+	for name := range roles {
+		permNames = append(permNames, name)
+	}
+	sort.Strings(permNames)
+	for _, name := range permNames {
+		if _, ok := data.Outputs[name]; !ok {
+			res.addWarning(FieldOutputPermissions+"."+name, "permissions are defined for an output that is not in the policy")
+		}
+	}
+
+	if _, err := findDefaultOutputName(data.Outputs); err != nil {
+		res.addError(FieldOutputs, err)
+	}
+
+	if len(data.Inputs) == 0 {
+		res.addWarning("inputs", "policy does not contain any inputs")
+		return res
+	}
+	inputs, err := getPolicyInputsWithSecrets(ctx, data, bulker, nil, "", 0)
+	if err != nil {
+		res.addError("secret_references", err)
+		return res
+	}
+	for i, input := range inputs {
+		for _, ref := range unresolvedSecretRefs(input) {
+			res.addWarning(fmt.Sprintf("inputs.%d", i), fmt.Sprintf("secret reference %q could not be resolved", ref))
+		}
+	}
+	return res
+}
+
+// validateOutput checks the output as Output.Prepare would, without preparing it.
+// badPerms is set when the output permissions could not be parsed, and is used to avoid reporting missing permissions twice.
+func validateOutput(res *ValidationResult, p Output, outputMap smap.Map, badPerms bool) {
+	path := FieldOutputs + "." + p.Name
+	switch p.Type {
+	case OutputTypeElasticsearch, OutputTypeRemoteElasticsearch:
+		if p.Role == nil && !badPerms {
+			res.addError(FieldOutputPermissions+"."+p.Name, ErrNoOutputPerms)
+		}
+		if p.Type != OutputTypeRemoteElasticsearch {
+			return
+		}
+		if hosts, _ := outputMap["hosts"].([]interface{}); len(hosts) == 0 {
+			res.addError(path+".hosts", errors.New("remote elasticsearch output has no hosts"))
+		}
+		if outputMap.GetString(FieldOutputServiceToken) == "" {
+			res.addError(path+"."+FieldOutputServiceToken, errors.New("remote elasticsearch output has no service token"))
+		}
+	case OutputTypeLogstash, OutputTypeKafka:
+	default:
+		res.addError(path+"."+FieldOutputType, fmt.Errorf("unknown output type: %s", p.Type))
+	}
+}
+
+// unresolvedSecretRefs returns the secret references left in the passed value after the secrets were replaced.
+func unresolvedSecretRefs(v any) []string {
+	var refs []string
+	switch val := v.(type) {
+	case string:
+		for _, m := range secretRegex.FindAllStringSubmatch(val, -1) {
+			refs = append(refs, m[1])
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			refs = append(refs, unresolvedSecretRefs(val[k])...)
+		}
+	case []any:
+		for _, e := range val {
+			refs = append(refs, unresolvedSecretRefs(e)...)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/model"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		errors   []ValidationIssue
+		warnings []ValidationIssue
+	}{{
+		name: "valid policy",
+		data: testPolicy,
+	}, {
+		name:   "no outputs",
+		data:   `{"id":"policy","inputs":[{"type":"logfile"}]}`,
+		errors: []ValidationIssue{{Path: "outputs", Message: ErrOutputsNotFound.Error()}},
+	}, {
+		name: "missing permissions and unknown output type",
+		data: `{"id":"policy","outputs":{"default":{"type":"elasticsearch"},"other":{"type":"unknown"}},"output_permissions":{"gone":{"_fallback":{}}},"inputs":[{"type":"logfile"}]}`,
+		errors: []ValidationIssue{
+			{Path: "output_permissions.default", Message: ErrNoOutputPerms.Error()},
+			{Path: "outputs.other.type", Message: "unknown output type: unknown"},
+		},
+		warnings: []ValidationIssue{
+			{Path: "output_permissions.gone", Message: "permissions are defined for an output that is not in the policy"},
+		},
+	}, {
+		name: "incomplete remote output",
+		data: `{"id":"policy","outputs":{"default":{"type":"logstash"},"remote":{"type":"remote_elasticsearch"}},"output_permissions":{"remote":{"_fallback":{}}},"inputs":[{"type":"logfile"}]}`,
+		errors: []ValidationIssue{
+			{Path: "outputs.remote.hosts", Message: "remote elasticsearch output has no hosts"},
+			{Path: "outputs.remote.service_token", Message: "remote elasticsearch output has no service token"},
+		},
+	}, {
+		name: "unresolved secret reference",
+		data: `{"id":"policy","outputs":{"default":{"type":"logstash"}},"secret_references":[{"id":"known"}],"inputs":[{"type":"logfile","password":"$co.elastic.secret{known}","token":"$co.elastic.secret{missing}"}]}`,
+		warnings: []ValidationIssue{
+			{Path: "inputs.0", Message: `secret reference "missing" could not be resolved`},
+		},
+	}, {
+		name:     "no inputs",
+		data:     `{"id":"policy","outputs":{"default":{"type":"logstash"}}}`,
+		warnings: []ValidationIssue{{Path: "inputs", Message: "policy does not contain any inputs"}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var data model.PolicyData
+			require.NoError(t, json.Unmarshal([]byte(tc.data), &data))
+
+			res := Validate(context.Background(), ftesting.NewMockBulk(), &data)
+			assert.Equal(t, tc.errors, res.Errors)
+			assert.Equal(t, tc.warnings, res.Warnings)
+			assert.Equal(t, len(tc.errors) == 0, res.Valid())
+		})
+	}
+}
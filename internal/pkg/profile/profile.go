@@ -17,6 +17,17 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// newMux registers the pprof handlers under /debug/pprof/.
+func newMux() *http.ServeMux {
+	r := http.NewServeMux()
+	r.HandleFunc("/debug/pprof/", pprof.Index)
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return r
+}
+
 // RunProfiler exposes /debug/pprof on the passed address by staring a server.
 func RunProfiler(ctx context.Context, addr string) error {
 	if addr == "" {
@@ -26,13 +37,7 @@ func RunProfiler(ctx context.Context, addr string) error {
 
 	bctx := func(net.Listener) context.Context { return ctx }
 
-	// Register pprof handlers
-	r := http.NewServeMux()
-	r.HandleFunc("/debug/pprof/", pprof.Index)
-	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	r := newMux()
 
 	cfg := &config.ServerTimeouts{}
 	cfg.InitDefaults()
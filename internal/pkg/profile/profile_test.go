@@ -8,6 +8,8 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"runtime"
 	"testing"
 	"time"
 
@@ -54,3 +56,20 @@ func TestRunProfiler(t *testing.T) {
 	default:
 	}
 }
+
+func TestHandler(t *testing.T) {
+	t.Cleanup(func() { Apply(Settings{}) })
+	h := Handler("/api/fleet")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/debug/pprof/cmdline", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code, "the endpoints are disabled by default")
+
+	s := Update(func(s *Settings) { s.Enabled = true; s.MutexFraction = 5 })
+	require.Equal(t, Settings{Enabled: true, MutexFraction: 5}, s)
+	require.Equal(t, 5, runtime.SetMutexProfileFraction(-1))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/fleet/debug/pprof/cmdline", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package profile
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// Settings are the profiling settings that can be changed while the server runs.
+type Settings struct {
+	// Enabled serves the pprof endpoints of Handler.
+	Enabled bool
+	// BlockRate is the rate of the block profile, see runtime.SetBlockProfileRate.
+	BlockRate int
+	// MutexFraction is the fraction of the mutex profile, see runtime.SetMutexProfileFraction.
+	MutexFraction int
+}
+
+// SettingsFromConfig returns the runtime settings of the profiler configuration.
+func SettingsFromConfig(cfg config.ServerProfiler) Settings {
+	return Settings{
+		Enabled:       cfg.Enabled,
+		BlockRate:     cfg.BlockRate,
+		MutexFraction: cfg.MutexFraction,
+	}
+}
+
+var (
+	mu      sync.Mutex
+	current Settings
+)
+
+// Apply sets the block and mutex profile rates of the runtime and enables or disables the endpoints of Handler.
+// The rates are process wide so the last applied settings win.
+func Apply(s Settings) {
+	Update(func(c *Settings) { *c = s })
+}
+
+// Update changes the settings in effect with fn and applies them, it returns the new settings.
+func Update(fn func(*Settings)) Settings {
+	mu.Lock()
+	defer mu.Unlock()
+	s := current
+	fn(&s)
+	runtime.SetBlockProfileRate(s.BlockRate)
+	runtime.SetMutexProfileFraction(s.MutexFraction)
+	current = s
+	return s
+}
+
+// Current returns the settings in effect.
+func Current() Settings {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Handler returns the pprof endpoints under prefix + /debug/pprof/, so they can be served by an existing listener.
+// The endpoints respond with 404 unless the settings in effect enable them.
+func Handler(prefix string) http.Handler {
+	h := http.StripPrefix(prefix, newMux())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Current().Enabled {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -184,6 +184,15 @@ func (a *Agent) Run(ctx context.Context) error {
 	return nil
 }
 
+// Drain enters the drain mode of the running fleet-server, it returns false when none is running.
+func (a *Agent) Drain(reason string) bool {
+	srv := a.srv
+	if srv == nil {
+		return false
+	}
+	return srv.Drain(reason)
+}
+
 // UpdateState updates the state of the message and payload.
 func (a *Agent) UpdateState(state client.UnitState, message string, payload map[string]interface{}) error {
 	if a.inputUnit != nil {
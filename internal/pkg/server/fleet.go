@@ -20,6 +20,7 @@ import (
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/action"
 	"github.com/elastic/fleet-server/v7/internal/pkg/api"
+	"github.com/elastic/fleet-server/v7/internal/pkg/audit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/build"
 	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
 	"github.com/elastic/fleet-server/v7/internal/pkg/cache"
@@ -27,13 +28,22 @@ import (
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
 	"github.com/elastic/fleet-server/v7/internal/pkg/coordinator"
 	"github.com/elastic/fleet-server/v7/internal/pkg/dl"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
 	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	"github.com/elastic/fleet-server/v7/internal/pkg/file/storage"
+	"github.com/elastic/fleet-server/v7/internal/pkg/fips"
 	"github.com/elastic/fleet-server/v7/internal/pkg/gc"
+	"github.com/elastic/fleet-server/v7/internal/pkg/invalidator"
+	"github.com/elastic/fleet-server/v7/internal/pkg/limit"
 	"github.com/elastic/fleet-server/v7/internal/pkg/monitor"
+	"github.com/elastic/fleet-server/v7/internal/pkg/otlp"
 	"github.com/elastic/fleet-server/v7/internal/pkg/policy"
 	"github.com/elastic/fleet-server/v7/internal/pkg/profile"
 	"github.com/elastic/fleet-server/v7/internal/pkg/scheduler"
+	"github.com/elastic/fleet-server/v7/internal/pkg/spool"
+	"github.com/elastic/fleet-server/v7/internal/pkg/standby"
 	"github.com/elastic/fleet-server/v7/internal/pkg/state"
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
 	"github.com/elastic/fleet-server/v7/internal/pkg/ver"
 
 	"github.com/hashicorp/go-version"
@@ -56,6 +66,26 @@ type Fleet struct {
 	// Used for diagnostics reporting
 	l   sync.RWMutex
 	cfg *config.Config
+
+	// the api servers of the running server, their listener settings are reloaded without a restart
+	srvMut     sync.Mutex
+	apiServers []apiServerT
+
+	drain   *drain.State
+	standby *standby.State
+}
+
+// apiServerT is an api server of the server, listener is the index of its additional listener or -1 and http3 is
+// true for the HTTP/3 listener.
+type apiServerT struct {
+	srv      listenerReloader
+	listener int
+	http3    bool
+}
+
+// listenerReloader is an api server whose listener settings can be changed while it is running.
+type listenerReloader interface {
+	Reload(cfg *config.Server) error
 }
 
 // NewFleet creates the actual fleet server service.
@@ -70,7 +100,8 @@ func NewFleet(bi build.Info, reporter state.Reporter, standAlone bool) (*Fleet,
 		bi:         bi,
 		verCon:     verCon,
 		cfgCh:      make(chan *config.Config, 1),
-		reporter:   reporter,
+		reporter:   state.NewChained(state.NewTimeline(), reporter),
+		drain:      drain.New(),
 	}, nil
 }
 
@@ -78,12 +109,34 @@ type runFunc func(context.Context) error
 
 type runFuncCfg func(context.Context, *config.Config) error
 
+// runPromoted returns a runFunc that runs fn once the server is out of standby.
+func runPromoted(fn runFunc) runFunc {
+	return func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-standby.FromContext(ctx).Promoted():
+		}
+		return fn(ctx)
+	}
+}
+
 func (f *Fleet) GetConfig() *config.Config {
 	f.l.RLock()
 	defer f.l.RUnlock()
 	return f.cfg
 }
 
+// Drain enters the drain mode when the server drains on signals, it returns false when it does not or when it is
+// already draining.
+func (f *Fleet) Drain(reason string) bool {
+	cfg := f.GetConfig()
+	if cfg == nil || !cfg.Inputs[0].Server.Drain.OnSignal {
+		return false
+	}
+	return f.drain.Start(reason)
+}
+
 // Run runs the fleet server
 func (f *Fleet) Run(ctx context.Context, initCfg *config.Config) error {
 	log := zerolog.Ctx(ctx)
@@ -106,7 +159,34 @@ func (f *Fleet) Run(ctx context.Context, initCfg *config.Config) error {
 	// in order to automatically cancel all the go routines
 	// that were started in the scope of this function on function exit
 	ctx, cn := context.WithCancel(ctx)
-	defer cn()
+
+	// The requests of the api servers get the drain state from the context, the server stops at the end of the
+	// drain period like on a signal.
+	ctx = drain.WithContext(ctx, f.drain)
+	ctx, stopDrained := context.WithCancel(ctx)
+	defer stopDrained()
+	go f.stopAfterDrain(ctx, stopDrained)
+
+	// The standby state is also read from the context, a server that starts in standby is promoted by a
+	// configuration without standby or through the API.
+	f.standby = standby.New(initCfg.Inputs[0].Server.Standby.Enabled)
+	ctx = standby.WithContext(ctx, f.standby)
+	if f.standby.Standby() {
+		log.Info().Msg("starting in standby")
+		go f.logPromotion(ctx)
+	}
+
+	// Write the state of the API key cache, when enabled, until fleet-server stops.
+	var cacheEg errgroup.Group
+	cacheEg.Go(loggedRunFunc(ctx, "API key cache state", cache.RunAPIKeyState))
+	// Read the secrets of the configuration again, the server is reconfigured when one of them changed.
+	cacheEg.Go(loggedRunFunc(ctx, "Secrets refresh", func(ctx context.Context) error {
+		return f.refreshSecrets(ctx, initCfg.Secrets.RefreshInterval)
+	}))
+	defer func() {
+		cn()
+		_ = cacheEg.Wait()
+	}()
 
 	stop := func(cn context.CancelFunc, g *errgroup.Group) {
 		if cn != nil {
@@ -167,6 +247,11 @@ LOOP:
 			}
 		}
 
+		// Apply the runtime profiling settings, this resets the changes made through the API
+		if curCfg == nil || curCfg.Inputs[0].Server.Profiler != newCfg.Inputs[0].Server.Profiler {
+			profile.Apply(profile.SettingsFromConfig(newCfg.Inputs[0].Server.Profiler))
+		}
+
 		// Start or restart profiler
 		if configChangedProfiler(curCfg, newCfg) {
 			if proCancel != nil {
@@ -182,8 +267,21 @@ LOOP:
 			}
 		}
 
+		// Leaving the standby mode promotes the server, a server that is active can not go back to standby.
+		if curCfg != nil && curCfg.Inputs[0].Server.Standby.Enabled != newCfg.Inputs[0].Server.Standby.Enabled {
+			if newCfg.Inputs[0].Server.Standby.Enabled {
+				log.Warn().Msg("standby can not be enabled on a running server, restart it to enter standby")
+			} else {
+				f.standby.Promote(standby.ReasonConfig)
+			}
+		}
+
+		// Reload the listener settings of the running server when they are the only change, so the rotation
+		// of the certificates or a change of the timeouts does not drop the connections of the agents.
+		reloaded := srvCancel != nil && configChangedListener(curCfg, newCfg) && f.reloadListeners(*log, &newCfg.Inputs[0].Server)
+
 		// Start or restart server
-		if configChangedServer(*log, curCfg, newCfg) {
+		if !reloaded && configChangedServer(*log, curCfg, newCfg) {
 			if srvCancel != nil {
 				log.Info().Msg("stopping server on configuration change")
 				stop(srvCancel, srvEg)
@@ -195,6 +293,7 @@ LOOP:
 				}
 			}
 			log.Info().Msg("starting server on configuration change")
+			timeline.Record(timeline.KindServer, "server started on configuration change", nil)
 			srvEg, srvCancel = start(ctx, func(ctx context.Context, cfg *config.Config) error {
 				return f.runServer(ctx, cfg)
 			}, newCfg, ech)
@@ -226,11 +325,54 @@ LOOP:
 	if errors.Is(err, context.Canceled) {
 		err = nil
 	}
+	if f.drain.Draining() {
+		f.reporter.UpdateState(client.UnitStateStopped, "Drained", nil) //nolint:errcheck // unclear on what should we do if updating the status fails?
+	}
 
 	log.Info().Err(err).Msg("Fleet Server exited")
 	return err
 }
 
+// logPromotion records the promotion of a server that started in standby.
+func (f *Fleet) logPromotion(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-f.standby.Promoted():
+	}
+	_, reason := f.standby.PromotedAt()
+	zerolog.Ctx(ctx).Info().Str("reason", reason).Msg("server promoted out of standby")
+	timeline.Record(timeline.KindServer, "server promoted", map[string]string{"reason": reason})
+}
+
+// stopAfterDrain reports the server as stopping when it enters the drain mode and stops it at the end of the
+// drain period.
+func (f *Fleet) stopAfterDrain(ctx context.Context, stop context.CancelFunc) {
+	select {
+	case <-f.drain.Started():
+	case <-ctx.Done():
+		return
+	}
+	_, reason := f.drain.Since()
+	f.reporter.UpdateState(client.UnitStateStopping, "Draining", nil) //nolint:errcheck // unclear on what should we do if updating the status fails?
+
+	var period time.Duration
+	if cfg := f.GetConfig(); cfg != nil {
+		period = cfg.Inputs[0].Server.Drain.Period
+	}
+	zerolog.Ctx(ctx).Info().Str("reason", reason).Dur("period", period).Msg("Fleet Server draining")
+	timeline.Record(timeline.KindServer, "server draining", map[string]string{"reason": reason})
+
+	t := time.NewTimer(period)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		zerolog.Ctx(ctx).Info().Msg("Fleet Server drained, stopping")
+		stop()
+	case <-ctx.Done():
+	}
+}
+
 func configChangedProfiler(curCfg, newCfg *config.Config) bool {
 	changed := true
 
@@ -278,6 +420,63 @@ func configChangedServer(log zerolog.Logger, curCfg, newCfg *config.Config) bool
 	return changed
 }
 
+// configChangedListener returns true when the only changes of the server configuration are the listener settings,
+// the TLS configuration, the connection timeouts, the max header size and the standby flag, which are applied without
+// a restart.
+func configChangedListener(curCfg, newCfg *config.Config) bool {
+	if curCfg == nil ||
+		!reflect.DeepEqual(curCfg.Fleet.CopyNoLogging(), newCfg.Fleet.CopyNoLogging()) ||
+		!reflect.DeepEqual(curCfg.Output, newCfg.Output) {
+		return false
+	}
+	cur, next := curCfg.Inputs[0].Server, newCfg.Inputs[0].Server
+	if reflect.DeepEqual(cur, next) {
+		return false
+	}
+	cur.TLS = next.TLS
+	cur.Timeouts.Read = next.Timeouts.Read
+	cur.Timeouts.ReadHeader = next.Timeouts.ReadHeader
+	cur.Timeouts.Write = next.Timeouts.Write
+	cur.Timeouts.Idle = next.Timeouts.Idle
+	cur.Limits.MaxHeaderByteSize = next.Limits.MaxHeaderByteSize
+	cur.Standby.Enabled = next.Standby.Enabled
+	return reflect.DeepEqual(cur, next)
+}
+
+// reloadListeners applies cfg to the listeners of the running api servers, it returns false when the servers
+// have to be restarted instead.
+func (f *Fleet) reloadListeners(log zerolog.Logger, cfg *config.Server) bool {
+	f.srvMut.Lock()
+	defer f.srvMut.Unlock()
+	if len(f.apiServers) == 0 {
+		return false
+	}
+	listeners := cfg.ListenerServers()
+	for _, as := range f.apiServers {
+		srvCfg := cfg
+		switch {
+		case as.http3:
+			h3Cfg, _ := cfg.HTTP3Server()
+			srvCfg = &h3Cfg
+		case as.listener >= 0:
+			srvCfg = &listeners[as.listener]
+		}
+		if err := as.srv.Reload(srvCfg); err != nil {
+			log.Warn().Err(err).Msg("unable to reload the listener settings, restarting the server")
+			return false
+		}
+	}
+	log.Info().Msg("listener settings reloaded on configuration change")
+	timeline.Record(timeline.KindServer, "listener settings reloaded on configuration change", nil)
+	return true
+}
+
+func (f *Fleet) setAPIServers(servers []apiServerT) {
+	f.srvMut.Lock()
+	defer f.srvMut.Unlock()
+	f.apiServers = servers
+}
+
 func safeWait(g *errgroup.Group, to time.Duration) error {
 	var err error
 	waitCh := make(chan error)
@@ -335,12 +534,19 @@ func initRuntime(cfg *config.Config) {
 			Int64("new", memoryLimit).
 			Msg("SetMemoryLimit")
 	}
-
+	fipsMode := cfg.Inputs[0].Server.FIPS.Enabled
+	if fipsMode != fips.Enabled() {
+		zerolog.Ctx(context.TODO()).Info().
+			Bool("enabled", fipsMode).
+			Bool("required", fips.Required).
+			Msg("FIPS mode")
+	}
+	fips.SetEnabled(fipsMode)
 }
 
 func (f *Fleet) initBulker(ctx context.Context, tracer *apm.Tracer, cfg *config.Config) (*bulk.Bulker, error) {
 	es, err := es.NewClient(ctx, cfg, false, elasticsearchOptions(
-		cfg.Inputs[0].Server.Instrumentation.Enabled, f.bi,
+		cfg.Inputs[0].Server.Instrumentation.Enabled || cfg.Inputs[0].Server.Instrumentation.OTLP.Enabled, f.bi,
 	)...)
 	if err != nil {
 		return nil, err
@@ -354,6 +560,7 @@ func (f *Fleet) initBulker(ctx context.Context, tracer *apm.Tracer, cfg *config.
 
 func (f *Fleet) runServer(ctx context.Context, cfg *config.Config) (err error) {
 	initRuntime(cfg)
+	defer f.setAPIServers(nil)
 
 	// Create the APM tracer.
 	tracer, err := f.initTracer(ctx, cfg.Inputs[0].Server.Instrumentation)
@@ -372,6 +579,18 @@ func (f *Fleet) runServer(ctx context.Context, cfg *config.Config) (err error) {
 		}()
 	}
 
+	if auditCfg := cfg.Inputs[0].Server.Audit; auditCfg.Enabled {
+		auditLog, err := audit.Open(ctx, auditCfg)
+		if err != nil {
+			return fmt.Errorf("unable to open the audit log: %w", err)
+		}
+		audit.SetLog(auditLog)
+		defer func() {
+			audit.SetLog(nil)
+			_ = auditLog.Close()
+		}()
+	}
+
 	// Bulker is started in its own context and managed in the scope of this function. This is done so
 	// when the `ctx` is cancelled, the bulker will remain executing until this function exits.
 	// This allows the child subsystems to continue to write to the data store while tearing down.
@@ -436,6 +655,14 @@ func (f *Fleet) runServer(ctx context.Context, cfg *config.Config) (err error) {
 func (f *Fleet) runSubsystems(ctx context.Context, cfg *config.Config, g *errgroup.Group, bulker bulk.Bulk, tracer *apm.Tracer) (err error) {
 	esCli := bulker.Client()
 
+	if instCfg := cfg.Inputs[0].Server.Instrumentation; instCfg.OTLP.Enabled {
+		exporter, err := otlp.NewMetricsExporter(instCfg.OTLP, api.MetricsGatherer(), f.bi.Version, instCfg.Environment)
+		if err != nil {
+			return err
+		}
+		g.Go(loggedRunFunc(ctx, "OTLP metrics exporter", exporter.Run))
+	}
+
 	// Version check is not performed in standalone mode because it is expected that
 	// standalone Fleet Server may be running with older versions of Elasticsearch.
 	if !f.standAlone {
@@ -463,45 +690,91 @@ func (f *Fleet) runSubsystems(ctx context.Context, cfg *config.Config, g *errgro
 	}
 
 	// Run scheduler for periodic GC/cleanup
-	gcCfg := cfg.Inputs[0].Server.GC
-	sched, err := scheduler.New(gc.Schedules(bulker, gcCfg.ScheduleInterval, gcCfg.CleanupAfterExpiredInterval))
+	uploadStore, err := storage.New(cfg.Inputs[0].Server.Uploads.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to create upload storage: %w", err)
+	}
+	sched, err := scheduler.New(gc.Schedules(bulker, uploadStore, cfg.Inputs[0].Server.GC))
 	if err != nil {
 		return fmt.Errorf("failed to create elasticsearch GC: %w", err)
 	}
 	g.Go(loggedRunFunc(ctx, "Elasticsearch GC", sched.Run))
 
+	if adaptiveCfg := cfg.Inputs[0].Server.Limits.Adaptive; adaptiveCfg.Enabled {
+		adaptive, err := limit.NewAdaptive(adaptiveCfg, bulker.QueueDepth)
+		if err != nil {
+			return fmt.Errorf("failed to create adaptive limits: %w", err)
+		}
+		g.Go(loggedRunFunc(ctx, "Adaptive limits", adaptive.Run))
+	}
+
 	// Monitoring es client, longer timeout, no retries
 	monCli, err := es.NewClient(ctx, cfg, true, elasticsearchOptions(
-		cfg.Inputs[0].Server.Instrumentation.Enabled, f.bi,
+		cfg.Inputs[0].Server.Instrumentation.Enabled || cfg.Inputs[0].Server.Instrumentation.OTLP.Enabled, f.bi,
 	)...)
 	if err != nil {
 		return err
 	}
 
-	// Coordinator policy monitor
-	pim, err := monitor.New(dl.FleetPolicies, esCli, monCli,
-		monitor.WithFetchSize(cfg.Inputs[0].Monitor.FetchSize),
-		monitor.WithPollTimeout(cfg.Inputs[0].Monitor.PollTimeout),
-		monitor.WithAPMTracer(tracer),
-		monitor.WithDebounceTime(cfg.Inputs[0].Monitor.PolicyDebounceTime),
-	)
+	pmOpts := []policy.MonitorOption{
+		policy.WithRollout(cfg.Inputs[0].Server.PolicyRollout),
+		policy.WithDispatchDebounce(cfg.Inputs[0].Monitor.PolicyDispatchDebounce, cfg.Inputs[0].Monitor.PolicyDispatchMaxWait),
+		policy.WithSecretCache(policy.NewSecretCache(cfg.Inputs[0].Cache.SecretTTL, api.SecretCacheMetrics())),
+	}
+
+	elector, err := coordinator.NewElector(cfg.Inputs[0].Server.Coordinator, bulker)
 	if err != nil {
 		return err
 	}
 
-	g.Go(loggedRunFunc(ctx, "Policy index monitor", pim.Run))
-	cord := coordinator.NewMonitor(cfg.Fleet, f.bi.Version, bulker, pim, coordinator.NewCoordinatorZero)
-	g.Go(loggedRunFunc(ctx, "Coordinator policy monitor", cord.Run))
+	var (
+		pim          monitor.Monitor
+		cord         coordinator.Monitor
+		policyFiles  *policy.FileSource
+		policiesPath = cfg.Inputs[0].Policy.Files.Path
+	)
+	if policiesPath != "" {
+		// Policies are served from local files, the policies index is neither monitored nor coordinated.
+		if !f.standAlone {
+			return errors.New("policy.files is only supported by a stand-alone fleet-server")
+		}
+		policyFiles = policy.NewFileSource(cfg.Inputs[0].Policy.Files)
+		pim = policyFiles
+		pmOpts = append(pmOpts, policy.WithFileSource(policyFiles))
+		g.Go(loggedRunFunc(ctx, "Policy files monitor", pim.Run))
+	} else {
+		// Coordinator policy monitor
+		pim, err = monitor.New(dl.FleetPolicies, esCli, monCli,
+			monitor.WithFetchSize(cfg.Inputs[0].Monitor.FetchSize),
+			monitor.WithPollTimeout(cfg.Inputs[0].Monitor.PollTimeout),
+			monitor.WithAPMTracer(tracer),
+			monitor.WithDebounceTime(cfg.Inputs[0].Monitor.PolicyDebounceTime),
+		)
+		if err != nil {
+			return err
+		}
+
+		g.Go(loggedRunFunc(ctx, "Policy index monitor", pim.Run))
+		cord = coordinator.NewMonitor(cfg.Fleet, f.bi.Version, bulker, pim, coordinator.NewCoordinatorZero,
+			coordinator.WithSharding(cfg.Inputs[0].Server.Coordinator.Sharding),
+			coordinator.WithElector(elector),
+		)
+		// A server in standby does not take the leadership of the policies until it is promoted.
+		g.Go(loggedRunFunc(ctx, "Coordinator policy monitor", runPromoted(cord.Run)))
+	}
 
 	// Policy monitor
-	pm := policy.NewMonitor(bulker, pim, cfg.Inputs[0].Server.Limits)
+	pm := policy.NewMonitor(bulker, pim, cfg.Inputs[0].Server.Limits, pmOpts...)
 	g.Go(loggedRunFunc(ctx, "Policy monitor", pm.Run))
 
 	// Policy self monitor
 	var sm policy.SelfMonitor
-	if f.standAlone {
+	switch {
+	case policyFiles != nil:
+		sm = policy.NewFileSelfMonitor(bulker, f.reporter, policyFiles)
+	case f.standAlone:
 		sm = policy.NewStandAloneSelfMonitor(bulker, f.reporter)
-	} else {
+	default:
 		sm = policy.NewSelfMonitor(cfg.Fleet, bulker, pim, cfg.Inputs[0].Policy.ID, f.reporter)
 	}
 	g.Go(loggedRunFunc(ctx, "Policy self monitor", sm.Run))
@@ -529,28 +802,81 @@ func (f *Fleet) runSubsystems(ctx context.Context, cfg *config.Config, g *errgro
 		return err
 	}
 
-	bc := checkin.NewBulk(bulker)
+	sp, err := spool.Open(ctx, cfg.Inputs[0].Server.Spool, bulker)
+	if err != nil {
+		return fmt.Errorf("failed to open the spool: %w", err)
+	}
+	if sp != nil {
+		g.Go(loggedRunFunc(ctx, "Spool", sp.Run))
+	}
+
+	bc := checkin.NewBulk(bulker, checkin.WithSpool(sp))
 	g.Go(loggedRunFunc(ctx, "Bulk checkin", bc.Run))
 
-	ct := api.NewCheckinT(f.verCon, &cfg.Inputs[0].Server, f.cache, bc, pm, am, ad, tr, bulker)
-	et, err := api.NewEnrollerT(f.verCon, &cfg.Inputs[0].Server, bulker, f.cache)
+	inv := invalidator.New(bulker, cfg.Inputs[0].Server.APIKeyInvalidation)
+	g.Go(loggedRunFunc(ctx, "API key invalidator", inv.Run))
+
+	ct := api.NewCheckinT(f.verCon, &cfg.Inputs[0].Server, f.cache, bc, pm, am, ad, tr, bulker, inv)
+	et, err := api.NewEnrollerT(f.verCon, &cfg.Inputs[0].Server, bulker, f.cache, policyFiles)
 	if err != nil {
 		return err
 	}
 
 	at := api.NewArtifactT(&cfg.Inputs[0].Server, bulker, f.cache)
-	ack := api.NewAckT(&cfg.Inputs[0].Server, bulker, f.cache)
-	st := api.NewStatusT(&cfg.Inputs[0].Server, bulker, f.cache)
-	ut := api.NewUploadT(&cfg.Inputs[0].Server, bulker, monCli, f.cache) // uses no-retry client for bufferless chunk upload
+	ack := api.NewAckT(&cfg.Inputs[0].Server, bulker, f.cache, pm, inv, sp)
+	// cord is nil if the policies are not coordinated
+	st := api.NewStatusT(&cfg.Inputs[0].Server, bulker, f.cache, api.WithPolicyMonitor(pm), api.WithCoordinator(cord))
+	ut, err := api.NewUploadT(&cfg.Inputs[0].Server, bulker, monCli, f.cache) // uses no-retry client for bufferless chunk upload
+	if err != nil {
+		return err
+	}
 	ft := api.NewFileDeliveryT(&cfg.Inputs[0].Server, bulker, monCli, f.cache)
 	pt := api.NewPGPRetrieverT(&cfg.Inputs[0].Server, bulker, f.cache)
+	pv := api.NewPolicyValidatorT(bulker, f.cache)
+	rt := api.NewRevokerT(&cfg.Inputs[0].Server, bulker, f.cache, inv)
+	prof := api.NewProfilerT(&cfg.Inputs[0].Server, bulker, f.cache)
+	diag := api.NewDiagnosticsT(cfg, bulker, f.cache, map[string]monitor.GlobalCheckpointProvider{
+		"policies": pim,
+		"actions":  am,
+	})
+	dt := api.NewDrainT(&cfg.Inputs[0].Server, bulker, f.cache)
+	ast := api.NewAgentSearchT(&cfg.Inputs[0].Server, bulker, f.cache)
+	art := api.NewAgentReassignT(&cfg.Inputs[0].Server, bulker, f.cache)
+	pl := api.NewPolicyLeadersT(&cfg.Inputs[0].Server, bulker, f.cache, pim, elector)
+	pr := api.NewPromoteT(&cfg.Inputs[0].Server, bulker, f.cache)
+
+	if cfg.Inputs[0].Cache.Warmup.Enabled {
+		api.WarmCaches(ctx, cfg.Inputs[0].Cache.Warmup, bulker, f.cache, pm)
+	}
 
+	var servers []apiServerT
 	for _, endpoint := range (&cfg.Inputs[0].Server).BindEndpoints() {
-		apiServer := api.NewServer(endpoint, &cfg.Inputs[0].Server, ct, et, at, ack, st, sm, f.bi, ut, ft, pt, bulker, tracer)
+		apiServer := api.NewServer(endpoint, &cfg.Inputs[0].Server, ct, et, at, ack, st, sm, f.bi, ut, ft, pt, pv, rt, prof, diag, dt, ast, art, pl, pr, bulker, tracer)
 		g.Go(loggedRunFunc(ctx, "Http server", func(ctx context.Context) error {
 			return apiServer.Run(ctx)
 		}))
+		servers = append(servers, apiServerT{srv: apiServer, listener: -1})
 	}
+	if h3Cfg, ok := cfg.Inputs[0].Server.HTTP3Server(); ok {
+		apiServer := api.NewServer(h3Cfg.HTTP3Endpoint(), &h3Cfg, ct, et, at, ack, st, sm, f.bi, ut, ft, pt, pv, rt, prof, diag, dt, ast, art, pl, pr, bulker, tracer)
+		g.Go(loggedRunFunc(ctx, "HTTP/3 server", func(ctx context.Context) error {
+			return apiServer.Run(ctx)
+		}))
+		servers = append(servers, apiServerT{srv: apiServer, listener: -1, http3: true})
+	}
+	// the additional listeners have their own address, TLS configuration, limits and routes
+	listeners := cfg.Inputs[0].Server.ListenerServers()
+	for i := range listeners {
+		srvCfg := &listeners[i]
+		for _, endpoint := range srvCfg.ListenerEndpoints() {
+			apiServer := api.NewServer(endpoint, srvCfg, ct, et, at, ack, st, sm, f.bi, ut, ft, pt, pv, rt, prof, diag, dt, ast, art, pl, pr, bulker, tracer)
+			g.Go(loggedRunFunc(ctx, "Http server "+endpoint, func(ctx context.Context) error {
+				return apiServer.Run(ctx)
+			}))
+			servers = append(servers, apiServerT{srv: apiServer, listener: i})
+		}
+	}
+	f.setAPIServers(servers)
 
 	return err
 }
@@ -564,10 +890,47 @@ func (f *Fleet) Reload(ctx context.Context, cfg *config.Config) error {
 	return nil
 }
 
+// refreshSecrets reads the secrets of the current configuration every interval until ctx is done.
+func (f *Fleet) refreshSecrets(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+	log := zerolog.Ctx(ctx)
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+
+		cfg := f.GetConfig()
+		if cfg == nil {
+			continue
+		}
+		next, changed, err := cfg.RefreshSecrets(ctx)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to refresh the secrets, the previous secrets are kept")
+			continue
+		}
+		// the configuration may have been replaced while the secrets were read
+		if !changed || f.GetConfig() != cfg {
+			continue
+		}
+		log.Info().Msg("Secrets changed, reconfiguring")
+		// the secrets keep being refreshed when the reload fails, the configuration is unchanged so it is retried on the next tick
+		if err := f.Reload(ctx, next); err != nil {
+			log.Error().Err(err).Msg("Failed to reconfigure with the new secrets")
+		}
+	}
+}
+
 const envAPMActive = "ELASTIC_APM_ACTIVE"
 
 func (f *Fleet) initTracer(ctx context.Context, cfg config.Instrumentation) (*apm.Tracer, error) {
-	if !cfg.Enabled && os.Getenv(envAPMActive) != "true" {
+	apmEnabled := cfg.Enabled || os.Getenv(envAPMActive) == "true"
+	if !apmEnabled && !cfg.OTLP.Enabled {
 		return nil, nil
 	}
 
@@ -587,14 +950,28 @@ func (f *Fleet) initTracer(ctx context.Context, cfg config.Instrumentation) (*ap
 		defer os.Unsetenv(envTransactionSampleRate)
 	}
 
-	options, err := cfg.APMHTTPTransportOptions()
-	if err != nil {
-		return nil, err
+	var transport apmtransport.Transport
+	if apmEnabled {
+		options, err := cfg.APMHTTPTransportOptions()
+		if err != nil {
+			return nil, err
+		}
+		transport, err = apmtransport.NewHTTPTransport(options)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	transport, err := apmtransport.NewHTTPTransport(options)
-	if err != nil {
-		return nil, err
+	if cfg.OTLP.Enabled {
+		// The traces are exported to the OTLP receiver alone or alongside the APM server.
+		otlpTransport, err := otlp.NewTransport(cfg.OTLP)
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			transport = otlp.Tee(transport, otlpTransport)
+		} else {
+			transport = otlpTransport
+		}
 	}
 
 	return apm.NewTracerOptions(apm.TracerOptions{
@@ -606,7 +983,7 @@ func (f *Fleet) initTracer(ctx context.Context, cfg config.Instrumentation) (*ap
 }
 
 func elasticsearchOptions(instumented bool, bi build.Info) []es.ConfigOption {
-	options := []es.ConfigOption{es.WithUserAgent(kUAFleetServer, bi)}
+	options := []es.ConfigOption{es.WithUserAgent(kUAFleetServer, bi), es.TimeRoundTripper()}
 	if instumented {
 		options = append(options, es.InstrumentRoundTripper())
 	}
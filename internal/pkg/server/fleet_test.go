@@ -6,11 +6,20 @@ package server
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/go-ucfg/yaml"
 
 	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/drain"
+	"github.com/elastic/fleet-server/v7/internal/pkg/state"
 	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_configChangedServer(t *testing.T) {
@@ -117,6 +126,59 @@ func Test_configChangedServer(t *testing.T) {
 	}
 }
 
+func Test_configChangedListener(t *testing.T) {
+	newCfg := func(modify func(*config.Server)) *config.Config {
+		cfg := &config.Config{Inputs: []config.Input{{}}}
+		cfg.Inputs[0].Server.InitDefaults()
+		modify(&cfg.Inputs[0].Server)
+		return cfg
+	}
+	cfg := newCfg(func(*config.Server) {})
+
+	testcases := []struct {
+		name    string
+		cur     *config.Config
+		cfg     *config.Config
+		changed bool
+	}{{
+		name: "initial configuration",
+		cfg:  cfg,
+	}, {
+		name: "no changes",
+		cur:  cfg,
+		cfg:  newCfg(func(*config.Server) {}),
+	}, {
+		name:    "timeouts",
+		cur:     cfg,
+		cfg:     newCfg(func(s *config.Server) { s.Timeouts.Read = time.Hour; s.Timeouts.Idle = time.Hour }),
+		changed: true,
+	}, {
+		name:    "tls",
+		cur:     cfg,
+		cfg:     newCfg(func(s *config.Server) { s.TLS = &tlscommon.ServerConfig{CAs: []string{"ca.pem"}} }),
+		changed: true,
+	}, {
+		name:    "standby",
+		cur:     newCfg(func(s *config.Server) { s.Standby.Enabled = true }),
+		cfg:     cfg,
+		changed: true,
+	}, {
+		name: "checkin timeouts",
+		cur:  cfg,
+		cfg:  newCfg(func(s *config.Server) { s.Timeouts.CheckinLongPoll = time.Hour }),
+	}, {
+		name: "timeouts and port",
+		cur:  cfg,
+		cfg:  newCfg(func(s *config.Server) { s.Timeouts.Read = time.Hour; s.Port = 8000 }),
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.changed, configChangedListener(tc.cur, tc.cfg))
+		})
+	}
+}
+
 func Test_initTracer(t *testing.T) {
 	testcases := []struct {
 		name                 string
@@ -135,6 +197,13 @@ func Test_initTracer(t *testing.T) {
 		cfg: config.Instrumentation{
 			Enabled: true,
 		},
+	}, {
+		name:                 "enabled with otlp export",
+		apmActiveEnvVariable: "",
+		expectTracer:         true,
+		cfg: config.Instrumentation{
+			OTLP: config.OTLP{Enabled: true, Endpoint: "http://localhost:4318"},
+		},
 	}, {
 		name:                 "not enabled",
 		apmActiveEnvVariable: "",
@@ -159,3 +228,48 @@ func Test_initTracer(t *testing.T) {
 		})
 	}
 }
+
+func Test_refreshSecrets(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "service_token")
+	require.NoError(t, os.WriteFile(path, []byte("token-1"), 0o600))
+	c, err := yaml.NewConfig([]byte("output.elasticsearch.service_token_file: "+path), config.DefaultOptions...)
+	require.NoError(t, err)
+	cfg, err := config.FromConfig(c)
+	require.NoError(t, err)
+
+	f := &Fleet{cfg: cfg, cfgCh: make(chan *config.Config, 1)}
+	go f.refreshSecrets(ctx, 10*time.Millisecond) //nolint:errcheck // the error is the context error
+
+	require.NoError(t, os.WriteFile(path, []byte("token-2"), 0o600))
+	select {
+	case next := <-f.cfgCh:
+		assert.Equal(t, "token-2", next.Output.Elasticsearch.ServiceToken)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the configuration with the new secret was not reloaded")
+	}
+}
+
+func Test_stopAfterDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(testlog.SetLogger(t).WithContext(context.Background()))
+	defer cancel()
+	cfg := &config.Config{Inputs: []config.Input{{}}}
+	cfg.Inputs[0].Server.InitDefaults()
+	cfg.Inputs[0].Server.Drain.Period = 10 * time.Millisecond
+
+	f := &Fleet{cfg: cfg, drain: drain.New(), reporter: state.NewLog()}
+	assert.False(t, f.Drain(drain.ReasonSignal), "the server does not drain on signals by default")
+	assert.False(t, f.drain.Draining())
+
+	stopped := make(chan struct{})
+	go f.stopAfterDrain(ctx, func() { close(stopped) })
+	cfg.Inputs[0].Server.Drain.OnSignal = true
+	require.True(t, f.Drain(drain.ReasonSignal))
+	assert.False(t, f.Drain(drain.ReasonSignal), "the server is already draining")
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server was not stopped at the end of the drain period")
+	}
+}
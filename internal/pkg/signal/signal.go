@@ -12,10 +12,14 @@ import (
 	"syscall"
 
 	"github.com/rs/zerolog"
+
+	fleetdrain "github.com/elastic/fleet-server/v7/internal/pkg/drain"
 )
 
 // HandleInterrupt will wrap and return a context that is cancelled when the process receives a SIGINT or SIGTERM.
-func HandleInterrupt(ctx context.Context) context.Context {
+// When drain is not nil it is called on the first signal, the context is only cancelled on the next signal when
+// drain returns true.
+func HandleInterrupt(ctx context.Context, drain func(reason string) bool) context.Context {
 	ctx, cfunc := context.WithCancel(ctx)
 	log := zerolog.Ctx(ctx)
 
@@ -24,12 +28,21 @@ func HandleInterrupt(ctx context.Context) context.Context {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		select {
-		case sig := <-sigs:
-			log.Info().Str("sig", sig.String()).Msg("On signal")
-			cfunc()
-		case <-ctx.Done():
-			log.Debug().Msg("Shutdown context done")
+	LOOP:
+		for draining := false; ; {
+			select {
+			case sig := <-sigs:
+				log.Info().Str("sig", sig.String()).Msg("On signal")
+				if !draining && drain != nil && drain(fleetdrain.ReasonSignal) {
+					log.Info().Msg("Draining, the next signal stops the server")
+					draining = true
+					continue
+				}
+				cfunc()
+			case <-ctx.Done():
+				log.Debug().Msg("Shutdown context done")
+			}
+			break LOOP
 		}
 
 		signal.Stop(sigs)
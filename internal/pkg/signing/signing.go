@@ -0,0 +1,176 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package signing verifies the HMAC signatures of the requests of the agents. The signing key of an agent is derived
+// from the secret of the configuration and the agent ID at enrollment, the agent signs the method, the path and query,
+// the timestamp, the nonce and the SHA-256 of the body of each request with it.
+// The signatures protect the integrity of the requests and, with the timestamps and the nonces, reject their replays.
+// The nonces are only known by the fleet-server that received them, a request can be replayed to another fleet-server
+// within the allowed skew.
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+// Headers of the signed requests.
+const (
+	HeaderSignature = "X-Fleet-Signature"
+	HeaderTimestamp = "X-Fleet-Signature-Timestamp"
+	HeaderNonce     = "X-Fleet-Signature-Nonce"
+)
+
+const (
+	// keyContext separates the signing keys from any other use of the secret.
+	keyContext = "fleet-server request signing\x00"
+
+	minNonceSize = 16
+	maxNonceSize = 128
+)
+
+var (
+	ErrSignatureMissing = errors.New("request signature missing")
+	ErrSignatureInvalid = errors.New("request signature invalid")
+	ErrSignatureExpired = errors.New("request signature timestamp outside of the allowed skew")
+	ErrSignatureReplay  = errors.New("request signature nonce already used")
+)
+
+// Request is the signed part of a request.
+type Request struct {
+	Method    string
+	URI       string
+	Timestamp string
+	Nonce     string
+	Signature string
+	BodyHash  []byte
+}
+
+// ReadRequest returns the signed part of r, the body of r is read and replaced with a copy.
+// It returns nil when r is not signed. The body must be limited by the caller.
+func ReadRequest(r *http.Request) (*Request, error) {
+	sig := r.Header.Get(HeaderSignature)
+	if sig == "" {
+		return nil, nil
+	}
+	h := sha256.New()
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		h.Write(body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return &Request{
+		Method:    r.Method,
+		URI:       r.URL.RequestURI(),
+		Timestamp: r.Header.Get(HeaderTimestamp),
+		Nonce:     r.Header.Get(HeaderNonce),
+		Signature: sig,
+		BodyHash:  h.Sum(nil),
+	}, nil
+}
+
+// Key returns the signing key of the agent.
+func Key(secret []byte, agentID string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(keyContext))
+	mac.Write([]byte(agentID))
+	return mac.Sum(nil)
+}
+
+// Sign returns the hex encoded signature of the request with the key.
+func Sign(key []byte, req *Request) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(req.Method + "\n" + req.URI + "\n" + req.Timestamp + "\n" + req.Nonce + "\n"))
+	mac.Write([]byte(hex.EncodeToString(req.BodyHash)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier verifies the signatures of the requests and remembers their nonces in memory.
+type Verifier struct {
+	secret        []byte
+	maxSkew       time.Duration
+	allowUnsigned bool
+	now           func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextPurge time.Time
+}
+
+// NewVerifier returns the verifier of the configuration, nil when the signatures are not verified.
+func NewVerifier(cfg *config.RequestSigning) (*Verifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	secret, err := cfg.DecodeSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		secret:        secret,
+		maxSkew:       cfg.MaxSkew,
+		allowUnsigned: cfg.AllowUnsigned,
+		now:           time.Now,
+		nonces:        make(map[string]time.Time),
+	}, nil
+}
+
+// Verify verifies the signature of the request of the agent, req is nil for an unsigned request.
+func (v *Verifier) Verify(agentID string, req *Request) error {
+	if req == nil {
+		if v.allowUnsigned {
+			return nil
+		}
+		return ErrSignatureMissing
+	}
+	if len(req.Nonce) < minNonceSize || len(req.Nonce) > maxNonceSize {
+		return ErrSignatureInvalid
+	}
+	sec, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if !hmac.Equal([]byte(Sign(Key(v.secret, agentID), req)), []byte(req.Signature)) {
+		return ErrSignatureInvalid
+	}
+	now := v.now()
+	if skew := now.Sub(time.Unix(sec, 0)); skew > v.maxSkew || skew < -v.maxSkew {
+		return ErrSignatureExpired
+	}
+	return v.useNonce(agentID+"/"+req.Nonce, now)
+}
+
+// useNonce returns an error when the nonce was already used. The nonces are remembered for twice the skew, the
+// longest a timestamp is accepted for.
+func (v *Verifier) useNonce(nonce string, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.After(v.nextPurge) {
+		for n, exp := range v.nonces {
+			if now.After(exp) {
+				delete(v.nonces, n)
+			}
+		}
+		v.nextPurge = now.Add(v.maxSkew)
+	}
+	if exp, ok := v.nonces[nonce]; ok && !now.After(exp) {
+		return ErrSignatureReplay
+	}
+	v.nonces[nonce] = now.Add(2 * v.maxSkew)
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package signing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+)
+
+var testSecret = bytes.Repeat([]byte("s"), 32)
+
+func testVerifier(t *testing.T, allowUnsigned bool, now time.Time) *Verifier {
+	t.Helper()
+	cfg := config.RequestSigning{Enabled: true, Secret: base64.StdEncoding.EncodeToString(testSecret), AllowUnsigned: allowUnsigned}
+	cfg.InitDefaults()
+	v, err := NewVerifier(&cfg)
+	require.NoError(t, err)
+	v.now = func() time.Time { return now }
+	return v
+}
+
+// signedRequest returns a request signed by the agent at ts.
+func signedRequest(t *testing.T, agentID string, ts time.Time, nonce, body string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/fleet/agents/"+agentID+"/checkin?wait=1", bytes.NewBufferString(body))
+	req, err := ReadRequest(r)
+	require.NoError(t, err)
+	require.Nil(t, req, "the request is not signed yet")
+
+	req = &Request{Method: r.Method, URI: r.URL.RequestURI(), Timestamp: strconv.FormatInt(ts.Unix(), 10), Nonce: nonce}
+	req.BodyHash = hashOf(body)
+	r.Header.Set(HeaderTimestamp, req.Timestamp)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, Sign(Key(testSecret, agentID), req))
+	return r
+}
+
+func hashOf(body string) []byte {
+	h := sha256.Sum256([]byte(body))
+	return h[:]
+}
+
+func TestNewVerifier(t *testing.T) {
+	v, err := NewVerifier(&config.RequestSigning{})
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = NewVerifier(&config.RequestSigning{Enabled: true, Secret: "c2hvcnQ="})
+	assert.Error(t, err)
+}
+
+func TestKey(t *testing.T) {
+	assert.Len(t, Key(testSecret, "agent"), 32)
+	assert.Equal(t, Key(testSecret, "agent"), Key(testSecret, "agent"))
+	assert.NotEqual(t, Key(testSecret, "agent"), Key(testSecret, "other"))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	nonce := "0123456789abcdef"
+
+	t.Run("valid", func(t *testing.T) {
+		r := signedRequest(t, "agent", now, nonce, `{"status":"online"}`)
+		req, err := ReadRequest(r)
+		require.NoError(t, err)
+		assert.NoError(t, testVerifier(t, false, now).Verify("agent", req))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"status":"online"}`, string(body), "the body is restored")
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		assert.ErrorIs(t, testVerifier(t, false, now).Verify("agent", nil), ErrSignatureMissing)
+		assert.NoError(t, testVerifier(t, true, now).Verify("agent", nil))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		v := testVerifier(t, true, now)
+		tests := map[string]func(r *http.Request){
+			"other agent": func(r *http.Request) { r.URL.Path = "/api/fleet/agents/other/checkin" },
+			"body":        func(r *http.Request) { r.Body = io.NopCloser(bytes.NewBufferString(`{"status":"error"}`)) },
+			"method":      func(r *http.Request) { r.Method = http.MethodPut },
+			"timestamp":   func(r *http.Request) { r.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix()+1, 10)) },
+			"short nonce": func(r *http.Request) { r.Header.Set(HeaderNonce, "nonce") },
+		}
+		for name, tamper := range tests {
+			t.Run(name, func(t *testing.T) {
+				r := signedRequest(t, "agent", now, nonce, `{"status":"online"}`)
+				tamper(r)
+				req, err := ReadRequest(r)
+				require.NoError(t, err)
+				assert.ErrorIs(t, v.Verify("agent", req), ErrSignatureInvalid)
+			})
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		v := testVerifier(t, false, now)
+		for _, ts := range []time.Time{now.Add(-6 * time.Minute), now.Add(6 * time.Minute)} {
+			req, err := ReadRequest(signedRequest(t, "agent", ts, nonce, ""))
+			require.NoError(t, err)
+			assert.ErrorIs(t, v.Verify("agent", req), ErrSignatureExpired)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		v := testVerifier(t, false, now)
+		verify := func(agentID string) error {
+			req, err := ReadRequest(signedRequest(t, agentID, now, nonce, ""))
+			require.NoError(t, err)
+			return v.Verify(agentID, req)
+		}
+		require.NoError(t, verify("agent"))
+		assert.ErrorIs(t, verify("agent"), ErrSignatureReplay)
+		assert.NoError(t, verify("other"), "the nonces are per agent")
+
+		require.NoError(t, v.useNonce("other/nonce", now.Add(11*time.Minute)))
+		assert.Len(t, v.nonces, 1, "the expired nonces are purged")
+	})
+}
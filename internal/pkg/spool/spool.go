@@ -0,0 +1,363 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package spool keeps the writes to Elasticsearch that fail while it is unreachable in segment files on the local disk
+// and replays them in order through the bulker once it recovers. The writes that follow a spooled write are spooled
+// too until the spool is empty, so an older write is never replayed over a newer one.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+)
+
+const (
+	segmentPrefix = "spool-"
+	segmentExt    = ".ndjson"
+	// maxSegmentSize is the size at which a new segment is started, a segment is removed once it is replayed.
+	maxSegmentSize = 4 * 1024 * 1024
+	// replayBatchSize is the largest number of operations sent in a single bulk request by the replay.
+	replayBatchSize = 500
+)
+
+// ErrFull is returned when a write does not fit in the spool.
+var ErrFull = errors.New("spool full")
+
+// Action is the bulk action of a spooled operation.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+)
+
+// Op is a spooled write, the body is the one of the bulk action.
+type Op struct {
+	Action  Action          `json:"action"`
+	Index   string          `json:"index"`
+	ID      string          `json:"id"`
+	Body    json.RawMessage `json:"body"`
+	Refresh bool            `json:"refresh,omitempty"`
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// Spool is the write-ahead spool of the writes to Elasticsearch. A nil *Spool writes directly.
+type Spool struct {
+	dir      string
+	maxSize  int64
+	interval time.Duration
+	bulker   bulk.Bulk
+
+	mu sync.Mutex
+	// segments are the segment files, the oldest first. The writes are appended to the last one when file is open.
+	segments []segment
+	size     int64
+	file     *os.File
+	nextSeq  uint64
+
+	log zerolog.Logger
+}
+
+// Open opens the spool of the configuration with the segments left by a previous run, it returns nil when the spool
+// is disabled. The spool logs with the logger of the context.
+func Open(ctx context.Context, cfg config.Spool, bulker bulk.Bulk) (*Spool, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	s := &Spool{
+		dir:      cfg.Dir(),
+		maxSize:  cfg.MaxSize,
+		interval: cfg.RetryInterval,
+		bulker:   bulker,
+		log:      zerolog.Ctx(ctx).With().Str("ctx", "spool").Logger(),
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create the spool directory: %w", err)
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the spool directory: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, segment{seq: seq, size: info.Size()})
+		s.size += info.Size()
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	return s, nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", segmentPrefix, seq, segmentExt))
+}
+
+// Pending returns true when writes are waiting to be replayed.
+func (s *Spool) Pending() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) > 0
+}
+
+// Write calls write for the operations, they are spooled instead when the spool is not empty or when write fails
+// because Elasticsearch is unavailable. The error of write is returned when the operations can not be spooled, the
+// operations are never written ahead of the spooled ones: the error of the spool is returned when it is not empty.
+func (s *Spool) Write(ops []Op, write func() error) error {
+	if s == nil {
+		return write()
+	}
+	if s.Pending() {
+		err := s.append(ops)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Unable to spool the writes")
+		}
+		return err
+	}
+	err := write()
+	if err == nil || !Unavailable(err) {
+		return err
+	}
+	if aerr := s.append(ops); aerr != nil {
+		s.log.Warn().Err(aerr).Msg("Unable to spool the writes")
+		return err
+	}
+	s.log.Debug().Err(err).Int("count", len(ops)).Msg("Elasticsearch unavailable, writes spooled")
+	return nil
+}
+
+// append writes the operations at the end of the last segment.
+func (s *Spool) append(ops []Op) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range ops {
+		if err := enc.Encode(&ops[i]); err != nil {
+			return err
+		}
+	}
+	n := int64(buf.Len())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+n > s.maxSize {
+		return ErrFull
+	}
+	if s.file == nil || s.segments[len(s.segments)-1].size >= maxSegmentSize {
+		if s.file != nil {
+			_ = s.file.Close()
+		}
+		f, err := os.OpenFile(s.path(s.nextSeq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			s.file = nil
+			return err
+		}
+		s.file = f
+		s.segments = append(s.segments, segment{seq: s.nextSeq})
+		s.nextSeq++
+	}
+	last := &s.segments[len(s.segments)-1]
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		_ = s.file.Truncate(last.size)
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	last.size += n
+	s.size += n
+	return nil
+}
+
+// Run replays the spool at each retry interval until the context is cancelled.
+func (s *Spool) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.segments) > 0 {
+		s.log.Info().Int("segments", len(s.segments)).Int64("size", s.size).Msg("Spool has writes to replay")
+	}
+	s.mu.Unlock()
+
+	tick := time.NewTicker(s.interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			if s.file != nil {
+				_ = s.file.Close()
+				s.file = nil
+			}
+			s.mu.Unlock()
+			return nil
+		case <-tick.C:
+			if err := s.replay(ctx); err != nil {
+				s.log.Warn().Err(err).Msg("Spool replay failed, retrying later")
+			}
+		}
+	}
+}
+
+// replay sends the segments in order and removes each one once it is sent. A segment that fails is sent again from
+// its start, the updates are idempotent and the conflicts of the creates are ignored.
+func (s *Spool) replay(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if len(s.segments) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		seg := s.segments[0]
+		if len(s.segments) == 1 && s.file != nil {
+			// the new writes go to a new segment while this one is replayed
+			_ = s.file.Close()
+			s.file = nil
+		}
+		s.mu.Unlock()
+
+		if err := s.replaySegment(ctx, s.path(seg.seq)); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		s.segments = s.segments[1:]
+		s.size -= seg.size
+		s.mu.Unlock()
+		if err := os.Remove(s.path(seg.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.log.Warn().Err(err).Msg("Unable to remove the replayed spool segment")
+		}
+		s.log.Info().Uint64("segment", seg.seq).Int64("size", seg.size).Msg("Spool segment replayed")
+	}
+}
+
+func (s *Spool) replaySegment(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	batch := make([]Op, 0, replayBatchSize)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var op Op
+			if jerr := json.Unmarshal(line, &op); jerr != nil {
+				// a write torn by a crash
+				s.log.Warn().Err(jerr).Str("path", path).Msg("Skipping an invalid spooled write")
+			} else {
+				batch = append(batch, op)
+			}
+		}
+		if len(batch) == replayBatchSize || (err != nil && len(batch) > 0) {
+			if serr := s.send(ctx, batch); serr != nil {
+				return serr
+			}
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// send sends the operations in order, grouping the consecutive operations of the same action in a bulk request.
+// The failures of single operations, like the updates of removed agents, are dropped.
+func (s *Spool) send(ctx context.Context, ops []Op) error {
+	for len(ops) > 0 {
+		n := 1
+		refresh := ops[0].Refresh
+		for n < len(ops) && ops[n].Action == ops[0].Action {
+			refresh = refresh || ops[n].Refresh
+			n++
+		}
+		mops := make([]bulk.MultiOp, n)
+		for i, op := range ops[:n] {
+			mops[i] = bulk.MultiOp{ID: op.ID, Index: op.Index, Body: op.Body}
+		}
+		var opts []bulk.Opt
+		if refresh {
+			opts = append(opts, bulk.WithRefresh())
+		}
+
+		var err error
+		switch ops[0].Action {
+		case ActionCreate:
+			_, err = s.bulker.MCreate(ctx, mops, opts...)
+		case ActionUpdate:
+			_, err = s.bulker.MUpdate(ctx, mops, opts...)
+		default:
+			err = fmt.Errorf("unknown spooled action %q", ops[0].Action)
+		}
+		if err != nil && (Unavailable(err) || errors.Is(err, context.Canceled)) {
+			return err
+		} else if err != nil && !errors.Is(err, es.ErrElasticVersionConflict) {
+			s.log.Warn().Err(err).Str("action", string(ops[0].Action)).Int("count", n).Msg("Dropping spooled writes that failed")
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// Unavailable returns true when err is returned because Elasticsearch is unreachable or overloaded, the write may
+// succeed later, and not because of the write itself.
+func Unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, es.ErrTimeout) {
+		return true
+	}
+	var esErr *es.ErrElastic
+	if errors.As(err, &esErr) {
+		switch esErr.Status {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
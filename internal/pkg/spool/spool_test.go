@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/bulk"
+	"github.com/elastic/fleet-server/v7/internal/pkg/config"
+	"github.com/elastic/fleet-server/v7/internal/pkg/es"
+	ftesting "github.com/elastic/fleet-server/v7/internal/pkg/testing"
+	testlog "github.com/elastic/fleet-server/v7/internal/pkg/testing/log"
+)
+
+var errUnreachable = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func testSpool(t *testing.T, dir string, bulker bulk.Bulk) *Spool {
+	t.Helper()
+	cfg := config.Spool{Enabled: true, Path: dir}
+	cfg.InitDefaults()
+	s, err := Open(testlog.SetLogger(t).WithContext(context.Background()), cfg, bulker)
+	require.NoError(t, err)
+	return s
+}
+
+func updateOp(id string) Op {
+	return Op{Action: ActionUpdate, Index: ".fleet-agents", ID: id, Body: []byte(`{"doc":{"last_checkin_status":"online"}}`)}
+}
+
+func TestOpenDisabled(t *testing.T) {
+	s, err := Open(context.Background(), config.Spool{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	called := false
+	assert.NoError(t, s.Write([]Op{updateOp("agent")}, func() error {
+		called = true
+		return nil
+	}))
+	assert.True(t, called, "a nil spool writes directly")
+	assert.False(t, s.Pending())
+}
+
+func TestWrite(t *testing.T) {
+	s := testSpool(t, t.TempDir(), nil)
+
+	writeErr := errors.New("mapper_parsing_exception")
+	assert.ErrorIs(t, s.Write([]Op{updateOp("agent1")}, func() error { return writeErr }), writeErr, "the errors of the writes are not spooled")
+	assert.False(t, s.Pending())
+
+	require.NoError(t, s.Write([]Op{updateOp("agent1")}, func() error { return errUnreachable }))
+	assert.True(t, s.Pending())
+
+	require.NoError(t, s.Write([]Op{updateOp("agent2")}, func() error {
+		t.Fatal("the writes are spooled while the spool is not empty")
+		return nil
+	}))
+
+	s.maxSize = s.size
+	err := s.Write([]Op{updateOp("agent3")}, func() error {
+		t.Fatal("the writes are not sent ahead of the spooled ones when the spool is full")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrFull)
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	s := testSpool(t, dir, nil)
+	require.NoError(t, s.Write([]Op{updateOp("agent1"), updateOp("agent2")}, func() error { return errUnreachable }))
+	require.NoError(t, s.Write([]Op{{Action: ActionCreate, Index: ".fleet-actions-results", ID: "action:agent1", Body: []byte(`{}`), Refresh: true}}, nil))
+	require.NoError(t, s.Write([]Op{updateOp("agent3")}, nil))
+
+	bulker := ftesting.NewMockBulk()
+	s = testSpool(t, dir, bulker)
+	require.True(t, s.Pending(), "the spool is kept across restarts")
+
+	ids := func(ops []bulk.MultiOp) []string {
+		var ids []string
+		for _, op := range ops {
+			ids = append(ids, op.ID)
+		}
+		return ids
+	}
+	var sent [][]string
+	bulker.On("MUpdate", mock.Anything, mock.Anything, mock.Anything).Return([]bulk.BulkIndexerResponseItem{}, errUnreachable).Once()
+	require.ErrorIs(t, s.replay(context.Background()), errUnreachable)
+	assert.True(t, s.Pending())
+
+	bulker.On("MUpdate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, ids(args.Get(1).([]bulk.MultiOp)))
+	}).Return([]bulk.BulkIndexerResponseItem{}, nil)
+	bulker.On("MCreate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, ids(args.Get(1).([]bulk.MultiOp)))
+		assert.Len(t, args.Get(2), 1, "the refresh of the action result is kept")
+	}).Return([]bulk.BulkIndexerResponseItem{}, fmt.Errorf("create: %w", es.ErrElasticVersionConflict))
+	require.NoError(t, s.replay(context.Background()))
+
+	assert.Equal(t, [][]string{{"agent1", "agent2"}, {"action:agent1"}, {"agent3"}}, sent, "the writes are replayed in order")
+	assert.False(t, s.Pending())
+	assert.Zero(t, s.size)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the replayed segments are removed")
+}
+
+func TestUnavailable(t *testing.T) {
+	assert.False(t, Unavailable(nil))
+	assert.False(t, Unavailable(errors.New("invalid body")))
+	assert.False(t, Unavailable(&es.ErrElastic{Status: http.StatusBadRequest}))
+	assert.False(t, Unavailable(context.Canceled))
+
+	assert.True(t, Unavailable(fmt.Errorf("bulk: %w", errUnreachable)))
+	assert.True(t, Unavailable(context.DeadlineExceeded))
+	assert.True(t, Unavailable(&es.ErrElastic{Status: http.StatusServiceUnavailable}))
+	assert.True(t, Unavailable(&es.ErrElastic{Status: http.StatusTooManyRequests}))
+}
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package standby is the standby mode of fleet-server. A standby server runs its monitors and keeps its caches warm
+// but does not serve the agents until it is promoted, for the passive site of an active/passive deployment.
+package standby
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	ReasonConfig = "config"
+	ReasonAPI    = "api"
+)
+
+type ctxKey struct{}
+
+// closed is the promoted channel of a nil State.
+var closed = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// State is the standby state of a server, a nil State is never in standby.
+type State struct {
+	mut        sync.Mutex
+	promoted   chan struct{}
+	promotedAt time.Time
+	reason     string
+}
+
+// New returns the state of a server that starts in standby when standby is true.
+func New(standby bool) *State {
+	s := &State{promoted: make(chan struct{})}
+	if !standby {
+		close(s.promoted)
+	}
+	return s
+}
+
+// Promote leaves the standby mode, it returns false when the server is not in standby.
+func (s *State) Promote(reason string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	select {
+	case <-s.promoted:
+		return false
+	default:
+	}
+	s.promotedAt = time.Now().UTC()
+	s.reason = reason
+	close(s.promoted)
+	return true
+}
+
+// Standby returns true until the server is promoted.
+func (s *State) Standby() bool {
+	if s == nil {
+		return false
+	}
+	select {
+	case <-s.promoted:
+		return false
+	default:
+		return true
+	}
+}
+
+// Promoted returns a channel closed when the server is promoted, the channel of a server that did not start in
+// standby or of a nil State is closed.
+func (s *State) Promoted() <-chan struct{} {
+	if s == nil {
+		return closed
+	}
+	return s.promoted
+}
+
+// PromotedAt returns when and why the server was promoted, the zero time when it is in standby or did not start in
+// standby.
+func (s *State) PromotedAt() (time.Time, string) {
+	if s == nil {
+		return time.Time{}, ""
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.promotedAt, s.reason
+}
+
+// WithContext returns a copy of ctx with the standby state, the requests of the api servers started with the
+// context get the state of their server.
+func WithContext(ctx context.Context, s *State) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromContext returns the standby state of ctx, nil when it has none.
+func FromContext(ctx context.Context) *State {
+	s, _ := ctx.Value(ctxKey{}).(*State)
+	return s
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package standby
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	s := New(true)
+	assert.True(t, s.Standby())
+	select {
+	case <-s.Promoted():
+		t.Fatal("the promoted channel is closed in standby")
+	default:
+	}
+
+	assert.True(t, s.Promote(ReasonAPI))
+	assert.False(t, s.Promote(ReasonConfig), "the server is already promoted")
+	assert.False(t, s.Standby())
+	at, reason := s.PromotedAt()
+	assert.False(t, at.IsZero())
+	assert.Equal(t, ReasonAPI, reason)
+	<-s.Promoted()
+}
+
+func TestStateActive(t *testing.T) {
+	s := New(false)
+	assert.False(t, s.Standby())
+	assert.False(t, s.Promote(ReasonAPI), "a server that did not start in standby is not promoted")
+	at, _ := s.PromotedAt()
+	assert.True(t, at.IsZero())
+	<-s.Promoted()
+}
+
+func TestFromContext(t *testing.T) {
+	s := FromContext(context.Background())
+	assert.Nil(t, s)
+	assert.False(t, s.Standby(), "a nil state is never in standby")
+	<-s.Promoted()
+
+	s = New(true)
+	assert.Same(t, s, FromContext(WithContext(context.Background(), s)))
+}
@@ -7,9 +7,12 @@ package state
 
 import (
 	"context"
+	"sync"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/rs/zerolog"
+
+	"github.com/elastic/fleet-server/v7/internal/pkg/timeline"
 )
 
 // Reporter is interface that reports updated state on.
@@ -51,3 +54,27 @@ func (l *Chained) UpdateState(state client.UnitState, message string, payload ma
 	}
 	return nil
 }
+
+// Timeline records the changes of state in the timeline of the server.
+type Timeline struct {
+	mut  sync.Mutex
+	last client.UnitState
+	set  bool
+}
+
+// NewTimeline creates a Timeline.
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// UpdateState records the state when it differs from the last state reported.
+func (t *Timeline) UpdateState(state client.UnitState, message string, _ map[string]interface{}) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.set && t.last == state {
+		return nil
+	}
+	t.last, t.set = state, true
+	timeline.Record(timeline.KindServer, "state changed", map[string]string{"state": state.String(), "message": message})
+	return nil
+}
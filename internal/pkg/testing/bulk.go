@@ -85,6 +85,10 @@ func (m *MockBulk) Client() *elasticsearch.Client {
 	return args.Get(0).(*elasticsearch.Client)
 }
 
+func (m *MockBulk) QueueDepth() int {
+	return 0
+}
+
 func (m *MockBulk) GetBulker(outputName string) bulk.Bulk {
 	args := m.Called(outputName)
 	if args.Get(0) == nil {
@@ -131,6 +135,11 @@ func (m *MockBulk) APIKeyAuth(ctx context.Context, key bulk.APIKey) (*bulk.Secur
 	return args.Get(0).(*bulk.SecurityInfo), args.Error(1)
 }
 
+func (m *MockBulk) ServiceTokenAuth(ctx context.Context, token string) (*bulk.SecurityInfo, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*bulk.SecurityInfo), args.Error(1)
+}
+
 func (m *MockBulk) APIKeyInvalidate(ctx context.Context, ids ...string) error {
 	args := m.Called(ctx, ids)
 	return args.Error(0)
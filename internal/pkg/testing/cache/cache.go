@@ -43,6 +43,15 @@ func (m *MockCache) ValidAPIKey(key corecache.APIKey) bool {
 	return args.Bool(0)
 }
 
+func (m *MockCache) SetServiceToken(token, identity string) {
+	m.Called(token, identity)
+}
+
+func (m *MockCache) GetServiceToken(token string) (string, bool) {
+	args := m.Called(token)
+	return args.String(0), args.Bool(1)
+}
+
 func (m *MockCache) SetEnrollmentAPIKey(id string, key model.EnrollmentAPIKey, cost int64) {
 	m.Called(id, key, cost)
 }
@@ -52,12 +61,12 @@ func (m *MockCache) GetEnrollmentAPIKey(id string) (model.EnrollmentAPIKey, bool
 	return args.Get(0).(model.EnrollmentAPIKey), args.Bool(1)
 }
 
-func (m *MockCache) SetArtifact(artifact model.Artifact) {
-	m.Called(artifact)
+func (m *MockCache) SetArtifact(namespace string, artifact model.Artifact) {
+	m.Called(namespace, artifact)
 }
 
-func (m *MockCache) GetArtifact(ident, sha2 string) (model.Artifact, bool) {
-	args := m.Called(ident, sha2)
+func (m *MockCache) GetArtifact(namespace, ident, sha2 string) (model.Artifact, bool) {
+	args := m.Called(namespace, ident, sha2)
 	return args.Get(0).(model.Artifact), args.Bool(1)
 }
 
@@ -78,3 +87,16 @@ func (m *MockCache) GetPGPKey(id string) ([]byte, bool) {
 	args := m.Called(id)
 	return args.Get(0).([]byte), args.Bool(1)
 }
+
+func (m *MockCache) SetNotFound(kind, id string) {
+	m.Called(kind, id)
+}
+
+func (m *MockCache) NotFound(kind, id string) bool {
+	args := m.Called(kind, id)
+	return args.Bool(0)
+}
+
+func (m *MockCache) ClearNotFound(kind, id string) {
+	m.Called(kind, id)
+}
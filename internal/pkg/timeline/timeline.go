@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package timeline keeps the last significant transitions of the server, like leadership changes or the loss of
+// the connection to Elasticsearch, so the diagnostics give a timeline of the server when investigating an incident.
+package timeline
+
+import (
+	"sync"
+	"time"
+)
+
+// kEvents is the number of events kept in memory.
+const kEvents = 500
+
+// Kind is the subsystem an event is about.
+type Kind string
+
+const (
+	// KindElasticsearch is the connectivity to Elasticsearch.
+	KindElasticsearch Kind = "elasticsearch"
+	// KindLeadership is the leadership of the policies.
+	KindLeadership Kind = "leadership"
+	// KindMonitor is the lifecycle of the index and policy monitors.
+	KindMonitor Kind = "monitor"
+	// KindServer is the state of the server reported to the agent.
+	KindServer Kind = "server"
+)
+
+// Event is a transition of the server.
+type Event struct {
+	Time    time.Time         `json:"@timestamp"`
+	Kind    Kind              `json:"kind"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+var gTimeline = newTimeline(kEvents)
+
+// timeline is a ring of the last events.
+type timeline struct {
+	mut    sync.Mutex
+	events []Event
+	next   int
+}
+
+func newTimeline(size int) *timeline {
+	return &timeline{events: make([]Event, 0, size)}
+}
+
+func (t *timeline) record(e Event) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if len(t.events) < cap(t.events) {
+		t.events = append(t.events, e)
+	} else {
+		t.events[t.next] = e
+		t.next = (t.next + 1) % len(t.events)
+	}
+}
+
+func (t *timeline) get() []Event {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	events := make([]Event, 0, len(t.events))
+	events = append(events, t.events[t.next:]...)
+	return append(events, t.events[:t.next]...)
+}
+
+// Record records an event of kind with the fields that identify what it is about, fields may be nil.
+func Record(kind Kind, message string, fields map[string]string) {
+	gTimeline.record(Event{Time: time.Now().UTC(), Kind: kind, Message: message, Fields: fields})
+}
+
+// Events returns the last events, the oldest first.
+func Events() []Event {
+	return gTimeline.get()
+}
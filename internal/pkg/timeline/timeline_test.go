@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !integration
+
+package timeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline(t *testing.T) {
+	tl := newTimeline(2)
+	assert.Empty(t, tl.get())
+
+	tl.record(Event{Kind: KindLeadership, Message: "first"})
+	tl.record(Event{Kind: KindMonitor, Message: "second"})
+	tl.record(Event{Kind: KindElasticsearch, Message: "third"})
+
+	events := tl.get()
+	assert.Len(t, events, 2, "only the last events are kept")
+	assert.Equal(t, "second", events[0].Message)
+	assert.Equal(t, "third", events[1].Message)
+}
+
+func TestRecord(t *testing.T) {
+	Record(KindServer, "state changed", map[string]string{"state": "HEALTHY"})
+	events := Events()
+	last := events[len(events)-1]
+	assert.Equal(t, KindServer, last.Kind)
+	assert.Equal(t, "state changed", last.Message)
+	assert.Equal(t, "HEALTHY", last.Fields["state"])
+	assert.False(t, last.Time.IsZero())
+}
@@ -101,6 +101,16 @@ type ClientInterface interface {
 
 	AgentEnroll(ctx context.Context, params *AgentEnrollParams, body AgentEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// AgentReassignWithBody request with any body
+	AgentReassignWithBody(ctx context.Context, params *AgentReassignParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	AgentReassign(ctx context.Context, params *AgentReassignParams, body AgentReassignJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// AgentSearchWithBody request with any body
+	AgentSearchWithBody(ctx context.Context, params *AgentSearchParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	AgentSearch(ctx context.Context, params *AgentSearchParams, body AgentSearchJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// AgentAcksWithBody request with any body
 	AgentAcksWithBody(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
@@ -111,22 +121,53 @@ type ClientInterface interface {
 
 	AgentCheckin(ctx context.Context, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// AgentRevoke request
+	AgentRevoke(ctx context.Context, id string, params *AgentRevokeParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// Artifact request
 	Artifact(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// GetDiagnostics request
+	GetDiagnostics(ctx context.Context, params *GetDiagnosticsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// Drain request
+	Drain(ctx context.Context, params *DrainParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// GetFile request
 	GetFile(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// GetPolicyLeaders request
+	GetPolicyLeaders(ctx context.Context, params *GetPolicyLeadersParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PolicyValidateWithBody request with any body
+	PolicyValidateWithBody(ctx context.Context, params *PolicyValidateParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PolicyValidate(ctx context.Context, params *PolicyValidateParams, body PolicyValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// UpdateProfilerWithBody request with any body
+	UpdateProfilerWithBody(ctx context.Context, params *UpdateProfilerParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	UpdateProfiler(ctx context.Context, params *UpdateProfilerParams, body UpdateProfilerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// Promote request
+	Promote(ctx context.Context, params *PromoteParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// UploadBeginWithBody request with any body
 	UploadBeginWithBody(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	UploadBegin(ctx context.Context, params *UploadBeginParams, body UploadBeginJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// UploadStatus request
+	UploadStatus(ctx context.Context, id string, params *UploadStatusParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// UploadCompleteWithBody request with any body
 	UploadCompleteWithBody(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	UploadComplete(ctx context.Context, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// UploadContents request
+	UploadContents(ctx context.Context, id string, params *UploadContentsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// UploadChunkWithBody request with any body
 	UploadChunkWithBody(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
@@ -170,6 +211,54 @@ func (c *Client) AgentEnroll(ctx context.Context, params *AgentEnrollParams, bod
 	return c.Client.Do(req)
 }
 
+func (c *Client) AgentReassignWithBody(ctx context.Context, params *AgentReassignParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAgentReassignRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) AgentReassign(ctx context.Context, params *AgentReassignParams, body AgentReassignJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAgentReassignRequest(c.Server, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) AgentSearchWithBody(ctx context.Context, params *AgentSearchParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAgentSearchRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) AgentSearch(ctx context.Context, params *AgentSearchParams, body AgentSearchJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAgentSearchRequest(c.Server, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) AgentAcksWithBody(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewAgentAcksRequestWithBody(c.Server, id, params, contentType, body)
 	if err != nil {
@@ -218,6 +307,18 @@ func (c *Client) AgentCheckin(ctx context.Context, id string, params *AgentCheck
 	return c.Client.Do(req)
 }
 
+func (c *Client) AgentRevoke(ctx context.Context, id string, params *AgentRevokeParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAgentRevokeRequest(c.Server, id, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) Artifact(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewArtifactRequest(c.Server, id, sha2, params)
 	if err != nil {
@@ -230,6 +331,30 @@ func (c *Client) Artifact(ctx context.Context, id string, sha2 string, params *A
 	return c.Client.Do(req)
 }
 
+func (c *Client) GetDiagnostics(ctx context.Context, params *GetDiagnosticsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetDiagnosticsRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) Drain(ctx context.Context, params *DrainParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDrainRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) GetFile(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewGetFileRequest(c.Server, id, params)
 	if err != nil {
@@ -242,6 +367,78 @@ func (c *Client) GetFile(ctx context.Context, id string, params *GetFileParams,
 	return c.Client.Do(req)
 }
 
+func (c *Client) GetPolicyLeaders(ctx context.Context, params *GetPolicyLeadersParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetPolicyLeadersRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PolicyValidateWithBody(ctx context.Context, params *PolicyValidateParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPolicyValidateRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PolicyValidate(ctx context.Context, params *PolicyValidateParams, body PolicyValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPolicyValidateRequest(c.Server, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) UpdateProfilerWithBody(ctx context.Context, params *UpdateProfilerParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewUpdateProfilerRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) UpdateProfiler(ctx context.Context, params *UpdateProfilerParams, body UpdateProfilerJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewUpdateProfilerRequest(c.Server, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) Promote(ctx context.Context, params *PromoteParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPromoteRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) UploadBeginWithBody(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewUploadBeginRequestWithBody(c.Server, params, contentType, body)
 	if err != nil {
@@ -266,6 +463,18 @@ func (c *Client) UploadBegin(ctx context.Context, params *UploadBeginParams, bod
 	return c.Client.Do(req)
 }
 
+func (c *Client) UploadStatus(ctx context.Context, id string, params *UploadStatusParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewUploadStatusRequest(c.Server, id, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) UploadCompleteWithBody(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewUploadCompleteRequestWithBody(c.Server, id, params, contentType, body)
 	if err != nil {
@@ -290,6 +499,18 @@ func (c *Client) UploadComplete(ctx context.Context, id string, params *UploadCo
 	return c.Client.Do(req)
 }
 
+func (c *Client) UploadContents(ctx context.Context, id string, params *UploadContentsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewUploadContentsRequest(c.Server, id, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
 func (c *Client) UploadChunkWithBody(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
 	req, err := NewUploadChunkRequestWithBody(c.Server, id, chunkNum, params, contentType, body)
 	if err != nil {
@@ -463,34 +684,27 @@ func NewAgentEnrollRequestWithBody(server string, params *AgentEnrollParams, con
 	return req, nil
 }
 
-// NewAgentAcksRequest calls the generic AgentAcks builder with application/json body
-func NewAgentAcksRequest(server string, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody) (*http.Request, error) {
+// NewAgentReassignRequest calls the generic AgentReassign builder with application/json body
+func NewAgentReassignRequest(server string, params *AgentReassignParams, body AgentReassignJSONRequestBody) (*http.Request, error) {
 	var bodyReader io.Reader
 	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 	bodyReader = bytes.NewReader(buf)
-	return NewAgentAcksRequestWithBody(server, id, params, "application/json", bodyReader)
+	return NewAgentReassignRequestWithBody(server, params, "application/json", bodyReader)
 }
 
-// NewAgentAcksRequestWithBody generates requests for AgentAcks with any type of body
-func NewAgentAcksRequestWithBody(server string, id string, params *AgentAcksParams, contentType string, body io.Reader) (*http.Request, error) {
+// NewAgentReassignRequestWithBody generates requests for AgentReassign with any type of body
+func NewAgentReassignRequestWithBody(server string, params *AgentReassignParams, contentType string, body io.Reader) (*http.Request, error) {
 	var err error
 
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
-	if err != nil {
-		return nil, err
-	}
-
 	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/agents/%s/acks", pathParam0)
+	operationPath := fmt.Sprintf("/api/fleet/agents/reassign")
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -536,34 +750,27 @@ func NewAgentAcksRequestWithBody(server string, id string, params *AgentAcksPara
 	return req, nil
 }
 
-// NewAgentCheckinRequest calls the generic AgentCheckin builder with application/json body
-func NewAgentCheckinRequest(server string, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody) (*http.Request, error) {
+// NewAgentSearchRequest calls the generic AgentSearch builder with application/json body
+func NewAgentSearchRequest(server string, params *AgentSearchParams, body AgentSearchJSONRequestBody) (*http.Request, error) {
 	var bodyReader io.Reader
 	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 	bodyReader = bytes.NewReader(buf)
-	return NewAgentCheckinRequestWithBody(server, id, params, "application/json", bodyReader)
+	return NewAgentSearchRequestWithBody(server, params, "application/json", bodyReader)
 }
 
-// NewAgentCheckinRequestWithBody generates requests for AgentCheckin with any type of body
-func NewAgentCheckinRequestWithBody(server string, id string, params *AgentCheckinParams, contentType string, body io.Reader) (*http.Request, error) {
+// NewAgentSearchRequestWithBody generates requests for AgentSearch with any type of body
+func NewAgentSearchRequestWithBody(server string, params *AgentSearchParams, contentType string, body io.Reader) (*http.Request, error) {
 	var err error
 
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
-	if err != nil {
-		return nil, err
-	}
-
 	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/agents/%s/checkin", pathParam0)
+	operationPath := fmt.Sprintf("/api/fleet/agents/search")
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -582,46 +789,26 @@ func NewAgentCheckinRequestWithBody(server string, id string, params *AgentCheck
 
 	if params != nil {
 
-		if params.AcceptEncoding != nil {
-			var headerParam0 string
-
-			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "Accept-Encoding", runtime.ParamLocationHeader, *params.AcceptEncoding)
-			if err != nil {
-				return nil, err
-			}
-
-			req.Header.Set("Accept-Encoding", headerParam0)
-		}
-
-		var headerParam1 string
-
-		headerParam1, err = runtime.StyleParamWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, params.UserAgent)
-		if err != nil {
-			return nil, err
-		}
-
-		req.Header.Set("User-Agent", headerParam1)
-
 		if params.XRequestId != nil {
-			var headerParam2 string
+			var headerParam0 string
 
-			headerParam2, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("X-Request-Id", headerParam2)
+			req.Header.Set("X-Request-Id", headerParam0)
 		}
 
 		if params.ElasticApiVersion != nil {
-			var headerParam3 string
+			var headerParam1 string
 
-			headerParam3, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("elastic-api-version", headerParam3)
+			req.Header.Set("elastic-api-version", headerParam1)
 		}
 
 	}
@@ -629,20 +816,24 @@ func NewAgentCheckinRequestWithBody(server string, id string, params *AgentCheck
 	return req, nil
 }
 
-// NewArtifactRequest generates requests for Artifact
-func NewArtifactRequest(server string, id string, sha2 string, params *ArtifactParams) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+// NewAgentAcksRequest calls the generic AgentAcks builder with application/json body
+func NewAgentAcksRequest(server string, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
+	bodyReader = bytes.NewReader(buf)
+	return NewAgentAcksRequestWithBody(server, id, params, "application/json", bodyReader)
+}
 
-	var pathParam1 string
+// NewAgentAcksRequestWithBody generates requests for AgentAcks with any type of body
+func NewAgentAcksRequestWithBody(server string, id string, params *AgentAcksParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
 
-	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "sha2", runtime.ParamLocationPath, sha2)
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
 	if err != nil {
 		return nil, err
 	}
@@ -652,7 +843,7 @@ func NewArtifactRequest(server string, id string, sha2 string, params *ArtifactP
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/artifacts/%s/%s", pathParam0, pathParam1)
+	operationPath := fmt.Sprintf("/api/fleet/agents/%s/acks", pathParam0)
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -662,11 +853,13 @@ func NewArtifactRequest(server string, id string, sha2 string, params *ArtifactP
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	req, err := http.NewRequest("POST", queryURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Header.Add("Content-Type", contentType)
+
 	if params != nil {
 
 		if params.XRequestId != nil {
@@ -696,11 +889,22 @@ func NewArtifactRequest(server string, id string, sha2 string, params *ArtifactP
 	return req, nil
 }
 
-// NewGetFileRequest generates requests for GetFile
-func NewGetFileRequest(server string, id string, params *GetFileParams) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
+// NewAgentCheckinRequest calls the generic AgentCheckin builder with application/json body
+func NewAgentCheckinRequest(server string, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewAgentCheckinRequestWithBody(server, id, params, "application/json", bodyReader)
+}
+
+// NewAgentCheckinRequestWithBody generates requests for AgentCheckin with any type of body
+func NewAgentCheckinRequestWithBody(server string, id string, params *AgentCheckinParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
 
 	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
 	if err != nil {
@@ -712,7 +916,7 @@ func NewGetFileRequest(server string, id string, params *GetFileParams) (*http.R
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/file/%s", pathParam0)
+	operationPath := fmt.Sprintf("/api/fleet/agents/%s/checkin", pathParam0)
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -722,33 +926,55 @@ func NewGetFileRequest(server string, id string, params *GetFileParams) (*http.R
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	req, err := http.NewRequest("POST", queryURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Header.Add("Content-Type", contentType)
+
 	if params != nil {
 
-		if params.ElasticApiVersion != nil {
+		if params.AcceptEncoding != nil {
 			var headerParam0 string
 
-			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "Accept-Encoding", runtime.ParamLocationHeader, *params.AcceptEncoding)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("elastic-api-version", headerParam0)
+			req.Header.Set("Accept-Encoding", headerParam0)
+		}
+
+		var headerParam1 string
+
+		headerParam1, err = runtime.StyleParamWithLocation("simple", false, "User-Agent", runtime.ParamLocationHeader, params.UserAgent)
+		if err != nil {
+			return nil, err
 		}
 
+		req.Header.Set("User-Agent", headerParam1)
+
 		if params.XRequestId != nil {
-			var headerParam1 string
+			var headerParam2 string
 
-			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			headerParam2, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("X-Request-Id", headerParam1)
+			req.Header.Set("X-Request-Id", headerParam2)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam3 string
+
+			headerParam3, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam3)
 		}
 
 	}
@@ -756,27 +982,23 @@ func NewGetFileRequest(server string, id string, params *GetFileParams) (*http.R
 	return req, nil
 }
 
-// NewUploadBeginRequest calls the generic UploadBegin builder with application/json body
-func NewUploadBeginRequest(server string, params *UploadBeginParams, body UploadBeginJSONRequestBody) (*http.Request, error) {
-	var bodyReader io.Reader
-	buf, err := json.Marshal(body)
+// NewAgentRevokeRequest generates requests for AgentRevoke
+func NewAgentRevokeRequest(server string, id string, params *AgentRevokeParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
 	if err != nil {
 		return nil, err
 	}
-	bodyReader = bytes.NewReader(buf)
-	return NewUploadBeginRequestWithBody(server, params, "application/json", bodyReader)
-}
-
-// NewUploadBeginRequestWithBody generates requests for UploadBegin with any type of body
-func NewUploadBeginRequestWithBody(server string, params *UploadBeginParams, contentType string, body io.Reader) (*http.Request, error) {
-	var err error
 
 	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/uploads")
+	operationPath := fmt.Sprintf("/api/fleet/agents/%s/revoke", pathParam0)
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -786,13 +1008,11 @@ func NewUploadBeginRequestWithBody(server string, params *UploadBeginParams, con
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", queryURL.String(), body)
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", contentType)
-
 	if params != nil {
 
 		if params.XRequestId != nil {
@@ -822,19 +1042,8 @@ func NewUploadBeginRequestWithBody(server string, params *UploadBeginParams, con
 	return req, nil
 }
 
-// NewUploadCompleteRequest calls the generic UploadComplete builder with application/json body
-func NewUploadCompleteRequest(server string, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody) (*http.Request, error) {
-	var bodyReader io.Reader
-	buf, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
-	}
-	bodyReader = bytes.NewReader(buf)
-	return NewUploadCompleteRequestWithBody(server, id, params, "application/json", bodyReader)
-}
-
-// NewUploadCompleteRequestWithBody generates requests for UploadComplete with any type of body
-func NewUploadCompleteRequestWithBody(server string, id string, params *UploadCompleteParams, contentType string, body io.Reader) (*http.Request, error) {
+// NewArtifactRequest generates requests for Artifact
+func NewArtifactRequest(server string, id string, sha2 string, params *ArtifactParams) (*http.Request, error) {
 	var err error
 
 	var pathParam0 string
@@ -844,12 +1053,19 @@ func NewUploadCompleteRequestWithBody(server string, id string, params *UploadCo
 		return nil, err
 	}
 
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "sha2", runtime.ParamLocationPath, sha2)
+	if err != nil {
+		return nil, err
+	}
+
 	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/uploads/%s", pathParam0)
+	operationPath := fmt.Sprintf("/api/fleet/artifacts/%s/%s", pathParam0, pathParam1)
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -859,13 +1075,11 @@ func NewUploadCompleteRequestWithBody(server string, id string, params *UploadCo
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", queryURL.String(), body)
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", contentType)
-
 	if params != nil {
 
 		if params.XRequestId != nil {
@@ -895,30 +1109,16 @@ func NewUploadCompleteRequestWithBody(server string, id string, params *UploadCo
 	return req, nil
 }
 
-// NewUploadChunkRequestWithBody generates requests for UploadChunk with any type of body
-func NewUploadChunkRequestWithBody(server string, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader) (*http.Request, error) {
+// NewGetDiagnosticsRequest generates requests for GetDiagnostics
+func NewGetDiagnosticsRequest(server string, params *GetDiagnosticsParams) (*http.Request, error) {
 	var err error
 
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
-	if err != nil {
-		return nil, err
-	}
-
-	var pathParam1 string
-
-	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "chunkNum", runtime.ParamLocationPath, chunkNum)
-	if err != nil {
-		return nil, err
-	}
-
 	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/fleet/uploads/%s/%s", pathParam0, pathParam1)
+	operationPath := fmt.Sprintf("/api/fleet/diagnostics")
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -928,44 +1128,33 @@ func NewUploadChunkRequestWithBody(server string, id string, chunkNum int, param
 		return nil, err
 	}
 
-	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", contentType)
-
 	if params != nil {
 
-		var headerParam0 string
-
-		headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Chunk-SHA2", runtime.ParamLocationHeader, params.XChunkSHA2)
-		if err != nil {
-			return nil, err
-		}
-
-		req.Header.Set("X-Chunk-SHA2", headerParam0)
-
 		if params.XRequestId != nil {
-			var headerParam1 string
+			var headerParam0 string
 
-			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("X-Request-Id", headerParam1)
+			req.Header.Set("X-Request-Id", headerParam0)
 		}
 
 		if params.ElasticApiVersion != nil {
-			var headerParam2 string
+			var headerParam1 string
 
-			headerParam2, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
 			if err != nil {
 				return nil, err
 			}
 
-			req.Header.Set("elastic-api-version", headerParam2)
+			req.Header.Set("elastic-api-version", headerParam1)
 		}
 
 	}
@@ -973,8 +1162,8 @@ func NewUploadChunkRequestWithBody(server string, id string, chunkNum int, param
 	return req, nil
 }
 
-// NewStatusRequest generates requests for Status
-func NewStatusRequest(server string, params *StatusParams) (*http.Request, error) {
+// NewDrainRequest generates requests for Drain
+func NewDrainRequest(server string, params *DrainParams) (*http.Request, error) {
 	var err error
 
 	serverURL, err := url.Parse(server)
@@ -982,7 +1171,7 @@ func NewStatusRequest(server string, params *StatusParams) (*http.Request, error
 		return nil, err
 	}
 
-	operationPath := fmt.Sprintf("/api/status")
+	operationPath := fmt.Sprintf("/api/fleet/drain")
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
@@ -992,7 +1181,7 @@ func NewStatusRequest(server string, params *StatusParams) (*http.Request, error
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1026,500 +1215,2315 @@ func NewStatusRequest(server string, params *StatusParams) (*http.Request, error
 	return req, nil
 }
 
-func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
-	for _, r := range c.RequestEditors {
-		if err := r(ctx, req); err != nil {
-			return err
-		}
+// NewGetFileRequest generates requests for GetFile
+func NewGetFileRequest(server string, id string, params *GetFileParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
 	}
-	for _, r := range additionalEditors {
-		if err := r(ctx, req); err != nil {
-			return err
-		}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
 
-// ClientWithResponses builds on ClientInterface to offer response payloads
-type ClientWithResponses struct {
-	ClientInterface
-}
+	operationPath := fmt.Sprintf("/api/fleet/file/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
 
-// NewClientWithResponses creates a new ClientWithResponses, which wraps
-// Client with return type handling
-func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
-	client, err := NewClient(server, opts...)
+	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
 		return nil, err
 	}
-	return &ClientWithResponses{client}, nil
-}
 
-// WithBaseURL overrides the baseURL.
-func WithBaseURL(baseURL string) ClientOption {
-	return func(c *Client) error {
-		newBaseURL, err := url.Parse(baseURL)
-		if err != nil {
-			return err
-		}
-		c.Server = newBaseURL.String()
-		return nil
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// ClientWithResponsesInterface is the interface specification for the client with responses above.
-type ClientWithResponsesInterface interface {
-	// GetPGPKeyWithResponse request
-	GetPGPKeyWithResponse(ctx context.Context, major int, minor int, patch int, params *GetPGPKeyParams, reqEditors ...RequestEditorFn) (*GetPGPKeyResponse, error)
+	if params != nil {
 
-	// AgentEnrollWithBodyWithResponse request with any body
-	AgentEnrollWithBodyWithResponse(ctx context.Context, params *AgentEnrollParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error)
+		if params.ElasticApiVersion != nil {
+			var headerParam0 string
 
-	AgentEnrollWithResponse(ctx context.Context, params *AgentEnrollParams, body AgentEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error)
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
 
-	// AgentAcksWithBodyWithResponse request with any body
-	AgentAcksWithBodyWithResponse(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error)
+			req.Header.Set("elastic-api-version", headerParam0)
+		}
 
-	AgentAcksWithResponse(ctx context.Context, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error)
+		if params.XRequestId != nil {
+			var headerParam1 string
 
-	// AgentCheckinWithBodyWithResponse request with any body
-	AgentCheckinWithBodyWithResponse(ctx context.Context, id string, params *AgentCheckinParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error)
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
 
-	AgentCheckinWithResponse(ctx context.Context, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error)
+			req.Header.Set("X-Request-Id", headerParam1)
+		}
 
-	// ArtifactWithResponse request
-	ArtifactWithResponse(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*ArtifactResponse, error)
+	}
 
-	// GetFileWithResponse request
-	GetFileWithResponse(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*GetFileResponse, error)
+	return req, nil
+}
 
-	// UploadBeginWithBodyWithResponse request with any body
-	UploadBeginWithBodyWithResponse(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error)
+// NewGetPolicyLeadersRequest generates requests for GetPolicyLeaders
+func NewGetPolicyLeadersRequest(server string, params *GetPolicyLeadersParams) (*http.Request, error) {
+	var err error
 
-	UploadBeginWithResponse(ctx context.Context, params *UploadBeginParams, body UploadBeginJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error)
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
 
-	// UploadCompleteWithBodyWithResponse request with any body
-	UploadCompleteWithBodyWithResponse(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error)
+	operationPath := fmt.Sprintf("/api/fleet/policies/leaders")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
 
-	UploadCompleteWithResponse(ctx context.Context, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error)
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
 
-	// UploadChunkWithBodyWithResponse request with any body
-	UploadChunkWithBodyWithResponse(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadChunkResponse, error)
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// StatusWithResponse request
-	StatusWithResponse(ctx context.Context, params *StatusParams, reqEditors ...RequestEditorFn) (*StatusResponse, error)
-}
+	if params != nil {
 
-type GetPGPKeyResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-}
+		if params.XRequestId != nil {
+			var headerParam0 string
 
-// Status returns HTTPResponse.Status
-func (r GetPGPKeyResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
-	}
-	return http.StatusText(0)
-}
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetPGPKeyResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
-	}
-	return 0
-}
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
 
-type AgentEnrollResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *EnrollResponse
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON408      *Deadline
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
 
-// Status returns HTTPResponse.Status
-func (r AgentEnrollResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
-	}
-	return http.StatusText(0)
-}
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r AgentEnrollResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
 	}
-	return 0
-}
 
-type AgentAcksResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *AckResponse
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON403      *Forbidden
-	JSON404      *AgentNotFound
-	JSON408      *Deadline
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
+	return req, nil
 }
 
-// Status returns HTTPResponse.Status
-func (r AgentAcksResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+// NewPolicyValidateRequest calls the generic PolicyValidate builder with application/json body
+func NewPolicyValidateRequest(server string, params *PolicyValidateParams, body PolicyValidateJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
+	bodyReader = bytes.NewReader(buf)
+	return NewPolicyValidateRequestWithBody(server, params, "application/json", bodyReader)
 }
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r AgentAcksResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+// NewPolicyValidateRequestWithBody generates requests for PolicyValidate with any type of body
+func NewPolicyValidateRequestWithBody(server string, params *PolicyValidateParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
 	}
-	return 0
-}
 
-type AgentCheckinResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *CheckinResponse
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON403      *Forbidden
-	JSON404      *AgentNotFound
-	JSON408      *Deadline
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
+	operationPath := fmt.Sprintf("/api/fleet/policies/validate")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
 
-// Status returns HTTPResponse.Status
-func (r AgentCheckinResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r AgentCheckinResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
 	}
-	return 0
-}
 
-type ArtifactResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON404      *AgentNotFound
-	JSON408      *Deadline
-	JSON428      *Throttle
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
+	req.Header.Add("Content-Type", contentType)
 
-// Status returns HTTPResponse.Status
-func (r ArtifactResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
-	}
-	return http.StatusText(0)
-}
+	if params != nil {
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r ArtifactResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
-	}
-	return 0
-}
+		if params.XRequestId != nil {
+			var headerParam0 string
 
-type GetFileResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON403      *Error
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
 
-// Status returns HTTPResponse.Status
-func (r GetFileResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
-	}
-	return http.StatusText(0)
-}
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetFileResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
-	}
-	return 0
-}
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
 
-type UploadBeginResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *UploadBeginAPIResponse
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON403      *Forbidden
-	JSON408      *Deadline
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
 
-// Status returns HTTPResponse.Status
-func (r UploadBeginResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
 	}
-	return http.StatusText(0)
+
+	return req, nil
 }
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r UploadBeginResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+// NewUpdateProfilerRequest calls the generic UpdateProfiler builder with application/json body
+func NewUpdateProfilerRequest(server string, params *UpdateProfilerParams, body UpdateProfilerJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
 	}
-	return 0
+	bodyReader = bytes.NewReader(buf)
+	return NewUpdateProfilerRequestWithBody(server, params, "application/json", bodyReader)
 }
 
-type UploadCompleteResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *struct {
-		Status *string `json:"status,omitempty"`
+// NewUpdateProfilerRequestWithBody generates requests for UpdateProfiler with any type of body
+func NewUpdateProfilerRequestWithBody(server string, params *UpdateProfilerParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
 	}
-	JSON400 *BadRequest
-	JSON401 *KeyNotEnabled
-	JSON403 *Forbidden
-	JSON408 *Deadline
-	JSON500 *InternalServerError
-	JSON503 *Unavailable
-}
 
-// Status returns HTTPResponse.Status
-func (r UploadCompleteResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	operationPath := fmt.Sprintf("/api/fleet/profiler")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r UploadCompleteResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
 	}
-	return 0
-}
-
-type UploadChunkResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON400      *BadRequest
-	JSON401      *KeyNotEnabled
-	JSON403      *Forbidden
-	JSON408      *Deadline
-	JSON500      *InternalServerError
-	JSON503      *Unavailable
-}
 
-// Status returns HTTPResponse.Status
-func (r UploadChunkResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r UploadChunkResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	req.Header.Add("Content-Type", contentType)
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
 	}
-	return 0
-}
 
-type StatusResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *StatusAPIResponse
-	JSON400      *BadRequest
-	JSON503      *StatusAPIResponse
+	return req, nil
 }
 
-// Status returns HTTPResponse.Status
-func (r StatusResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+// NewPromoteRequest generates requests for Promote
+func NewPromoteRequest(server string, params *PromoteParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r StatusResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	operationPath := fmt.Sprintf("/api/fleet/promote")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
 	}
-	return 0
-}
 
-// GetPGPKeyWithResponse request returning *GetPGPKeyResponse
-func (c *ClientWithResponses) GetPGPKeyWithResponse(ctx context.Context, major int, minor int, patch int, params *GetPGPKeyParams, reqEditors ...RequestEditorFn) (*GetPGPKeyResponse, error) {
-	rsp, err := c.GetPGPKey(ctx, major, minor, patch, params, reqEditors...)
+	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
 		return nil, err
 	}
-	return ParseGetPGPKeyResponse(rsp)
-}
 
-// AgentEnrollWithBodyWithResponse request with arbitrary body returning *AgentEnrollResponse
-func (c *ClientWithResponses) AgentEnrollWithBodyWithResponse(ctx context.Context, params *AgentEnrollParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error) {
-	rsp, err := c.AgentEnrollWithBody(ctx, params, contentType, body, reqEditors...)
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentEnrollResponse(rsp)
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
 }
 
-func (c *ClientWithResponses) AgentEnrollWithResponse(ctx context.Context, params *AgentEnrollParams, body AgentEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error) {
-	rsp, err := c.AgentEnroll(ctx, params, body, reqEditors...)
+// NewUploadBeginRequest calls the generic UploadBegin builder with application/json body
+func NewUploadBeginRequest(server string, params *UploadBeginParams, body UploadBeginJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentEnrollResponse(rsp)
+	bodyReader = bytes.NewReader(buf)
+	return NewUploadBeginRequestWithBody(server, params, "application/json", bodyReader)
 }
 
-// AgentAcksWithBodyWithResponse request with arbitrary body returning *AgentAcksResponse
-func (c *ClientWithResponses) AgentAcksWithBodyWithResponse(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error) {
-	rsp, err := c.AgentAcksWithBody(ctx, id, params, contentType, body, reqEditors...)
+// NewUploadBeginRequestWithBody generates requests for UploadBegin with any type of body
+func NewUploadBeginRequestWithBody(server string, params *UploadBeginParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentAcksResponse(rsp)
-}
 
-func (c *ClientWithResponses) AgentAcksWithResponse(ctx context.Context, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error) {
-	rsp, err := c.AgentAcks(ctx, id, params, body, reqEditors...)
+	operationPath := fmt.Sprintf("/api/fleet/uploads")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentAcksResponse(rsp)
-}
 
-// AgentCheckinWithBodyWithResponse request with arbitrary body returning *AgentCheckinResponse
-func (c *ClientWithResponses) AgentCheckinWithBodyWithResponse(ctx context.Context, id string, params *AgentCheckinParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error) {
-	rsp, err := c.AgentCheckinWithBody(ctx, id, params, contentType, body, reqEditors...)
+	req, err := http.NewRequest("POST", queryURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentCheckinResponse(rsp)
+
+	req.Header.Add("Content-Type", contentType)
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
 }
 
-func (c *ClientWithResponses) AgentCheckinWithResponse(ctx context.Context, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error) {
-	rsp, err := c.AgentCheckin(ctx, id, params, body, reqEditors...)
+// NewUploadStatusRequest generates requests for UploadStatus
+func NewUploadStatusRequest(server string, id string, params *UploadStatusParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
 	if err != nil {
 		return nil, err
 	}
-	return ParseAgentCheckinResponse(rsp)
-}
 
-// ArtifactWithResponse request returning *ArtifactResponse
-func (c *ClientWithResponses) ArtifactWithResponse(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*ArtifactResponse, error) {
-	rsp, err := c.Artifact(ctx, id, sha2, params, reqEditors...)
+	serverURL, err := url.Parse(server)
 	if err != nil {
 		return nil, err
 	}
-	return ParseArtifactResponse(rsp)
-}
 
-// GetFileWithResponse request returning *GetFileResponse
-func (c *ClientWithResponses) GetFileWithResponse(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*GetFileResponse, error) {
-	rsp, err := c.GetFile(ctx, id, params, reqEditors...)
+	operationPath := fmt.Sprintf("/api/fleet/uploads/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
 	if err != nil {
 		return nil, err
 	}
-	return ParseGetFileResponse(rsp)
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
 }
 
-// UploadBeginWithBodyWithResponse request with arbitrary body returning *UploadBeginResponse
-func (c *ClientWithResponses) UploadBeginWithBodyWithResponse(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error) {
-	rsp, err := c.UploadBeginWithBody(ctx, params, contentType, body, reqEditors...)
+// NewUploadCompleteRequest calls the generic UploadComplete builder with application/json body
+func NewUploadCompleteRequest(server string, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	return ParseUploadBeginResponse(rsp)
+	bodyReader = bytes.NewReader(buf)
+	return NewUploadCompleteRequestWithBody(server, id, params, "application/json", bodyReader)
 }
 
-func (c *ClientWithResponses) UploadBeginWithResponse(ctx context.Context, params *UploadBeginParams, body UploadBeginJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error) {
-	rsp, err := c.UploadBegin(ctx, params, body, reqEditors...)
-	if err != nil {
-		return nil, err
-	}
-	return ParseUploadBeginResponse(rsp)
-}
+// NewUploadCompleteRequestWithBody generates requests for UploadComplete with any type of body
+func NewUploadCompleteRequestWithBody(server string, id string, params *UploadCompleteParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/api/fleet/uploads/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
+}
+
+// NewUploadContentsRequest generates requests for UploadContents
+func NewUploadContentsRequest(server string, id string, params *UploadContentsParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/api/fleet/uploads/%s/contents", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
+}
+
+// NewUploadChunkRequestWithBody generates requests for UploadChunk with any type of body
+func NewUploadChunkRequestWithBody(server string, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "chunkNum", runtime.ParamLocationPath, chunkNum)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/api/fleet/uploads/%s/%s", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	if params != nil {
+
+		var headerParam0 string
+
+		headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Chunk-SHA2", runtime.ParamLocationHeader, params.XChunkSHA2)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("X-Chunk-SHA2", headerParam0)
+
+		if params.XRequestId != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam1)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam2 string
+
+			headerParam2, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam2)
+		}
+
+	}
+
+	return req, nil
+}
+
+// NewStatusRequest generates requests for Status
+func NewStatusRequest(server string, params *StatusParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/api/status")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Verbose != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "verbose", runtime.ParamLocationQuery, *params.Verbose); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+
+		if params.XRequestId != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "X-Request-Id", runtime.ParamLocationHeader, *params.XRequestId)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("X-Request-Id", headerParam0)
+		}
+
+		if params.ElasticApiVersion != nil {
+			var headerParam1 string
+
+			headerParam1, err = runtime.StyleParamWithLocation("simple", false, "elastic-api-version", runtime.ParamLocationHeader, *params.ElasticApiVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("elastic-api-version", headerParam1)
+		}
+
+	}
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// GetPGPKeyWithResponse request
+	GetPGPKeyWithResponse(ctx context.Context, major int, minor int, patch int, params *GetPGPKeyParams, reqEditors ...RequestEditorFn) (*GetPGPKeyResponse, error)
+
+	// AgentEnrollWithBodyWithResponse request with any body
+	AgentEnrollWithBodyWithResponse(ctx context.Context, params *AgentEnrollParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error)
+
+	AgentEnrollWithResponse(ctx context.Context, params *AgentEnrollParams, body AgentEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error)
+
+	// AgentReassignWithBodyWithResponse request with any body
+	AgentReassignWithBodyWithResponse(ctx context.Context, params *AgentReassignParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentReassignResponse, error)
+
+	AgentReassignWithResponse(ctx context.Context, params *AgentReassignParams, body AgentReassignJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentReassignResponse, error)
+
+	// AgentSearchWithBodyWithResponse request with any body
+	AgentSearchWithBodyWithResponse(ctx context.Context, params *AgentSearchParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentSearchResponse, error)
+
+	AgentSearchWithResponse(ctx context.Context, params *AgentSearchParams, body AgentSearchJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentSearchResponse, error)
+
+	// AgentAcksWithBodyWithResponse request with any body
+	AgentAcksWithBodyWithResponse(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error)
+
+	AgentAcksWithResponse(ctx context.Context, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error)
+
+	// AgentCheckinWithBodyWithResponse request with any body
+	AgentCheckinWithBodyWithResponse(ctx context.Context, id string, params *AgentCheckinParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error)
+
+	AgentCheckinWithResponse(ctx context.Context, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error)
+
+	// AgentRevokeWithResponse request
+	AgentRevokeWithResponse(ctx context.Context, id string, params *AgentRevokeParams, reqEditors ...RequestEditorFn) (*AgentRevokeResponse, error)
+
+	// ArtifactWithResponse request
+	ArtifactWithResponse(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*ArtifactResponse, error)
+
+	// GetDiagnosticsWithResponse request
+	GetDiagnosticsWithResponse(ctx context.Context, params *GetDiagnosticsParams, reqEditors ...RequestEditorFn) (*GetDiagnosticsResponse, error)
+
+	// DrainWithResponse request
+	DrainWithResponse(ctx context.Context, params *DrainParams, reqEditors ...RequestEditorFn) (*DrainResponse, error)
+
+	// GetFileWithResponse request
+	GetFileWithResponse(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*GetFileResponse, error)
+
+	// GetPolicyLeadersWithResponse request
+	GetPolicyLeadersWithResponse(ctx context.Context, params *GetPolicyLeadersParams, reqEditors ...RequestEditorFn) (*GetPolicyLeadersResponse, error)
+
+	// PolicyValidateWithBodyWithResponse request with any body
+	PolicyValidateWithBodyWithResponse(ctx context.Context, params *PolicyValidateParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PolicyValidateResponse, error)
+
+	PolicyValidateWithResponse(ctx context.Context, params *PolicyValidateParams, body PolicyValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*PolicyValidateResponse, error)
+
+	// UpdateProfilerWithBodyWithResponse request with any body
+	UpdateProfilerWithBodyWithResponse(ctx context.Context, params *UpdateProfilerParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UpdateProfilerResponse, error)
+
+	UpdateProfilerWithResponse(ctx context.Context, params *UpdateProfilerParams, body UpdateProfilerJSONRequestBody, reqEditors ...RequestEditorFn) (*UpdateProfilerResponse, error)
+
+	// PromoteWithResponse request
+	PromoteWithResponse(ctx context.Context, params *PromoteParams, reqEditors ...RequestEditorFn) (*PromoteResponse, error)
+
+	// UploadBeginWithBodyWithResponse request with any body
+	UploadBeginWithBodyWithResponse(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error)
+
+	UploadBeginWithResponse(ctx context.Context, params *UploadBeginParams, body UploadBeginJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error)
+
+	// UploadStatusWithResponse request
+	UploadStatusWithResponse(ctx context.Context, id string, params *UploadStatusParams, reqEditors ...RequestEditorFn) (*UploadStatusResponse, error)
+
+	// UploadCompleteWithBodyWithResponse request with any body
+	UploadCompleteWithBodyWithResponse(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error)
+
+	UploadCompleteWithResponse(ctx context.Context, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error)
+
+	// UploadContentsWithResponse request
+	UploadContentsWithResponse(ctx context.Context, id string, params *UploadContentsParams, reqEditors ...RequestEditorFn) (*UploadContentsResponse, error)
+
+	// UploadChunkWithBodyWithResponse request with any body
+	UploadChunkWithBodyWithResponse(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadChunkResponse, error)
+
+	// StatusWithResponse request
+	StatusWithResponse(ctx context.Context, params *StatusParams, reqEditors ...RequestEditorFn) (*StatusResponse, error)
+}
+
+type GetPGPKeyResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+}
+
+// Status returns HTTPResponse.Status
+func (r GetPGPKeyResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetPGPKeyResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentEnrollResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *EnrollResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentEnrollResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentEnrollResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentReassignResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *AgentReassignAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentReassignResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentReassignResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentSearchResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *AgentSearchAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentSearchResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentSearchResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentAcksResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *AckResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON404      *AgentNotFound
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentAcksResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentAcksResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentCheckinResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *CheckinResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON404      *AgentNotFound
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentCheckinResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentCheckinResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type AgentRevokeResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *AgentRevokeAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON404      *AgentNotFound
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r AgentRevokeResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AgentRevokeResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ArtifactResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON404      *AgentNotFound
+	JSON408      *Deadline
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r ArtifactResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ArtifactResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetDiagnosticsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r GetDiagnosticsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetDiagnosticsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DrainResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON202      *DrainAPIResponse
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r DrainResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DrainResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetFileResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Error
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r GetFileResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetFileResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetPolicyLeadersResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *PolicyLeadersAPIResponse
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r GetPolicyLeadersResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetPolicyLeadersResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PolicyValidateResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *PolicyValidateAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r PolicyValidateResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PolicyValidateResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UpdateProfilerResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ProfilerAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UpdateProfilerResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UpdateProfilerResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PromoteResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *PromoteAPIResponse
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON428      *Throttle
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r PromoteResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PromoteResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UploadBeginResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *UploadBeginAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UploadBeginResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UploadBeginResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UploadStatusResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *UploadStatusAPIResponse
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UploadStatusResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UploadStatusResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UploadCompleteResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *struct {
+		Status *string `json:"status,omitempty"`
+	}
+	JSON400 *BadRequest
+	JSON401 *KeyNotEnabled
+	JSON403 *Forbidden
+	JSON408 *Deadline
+	JSON500 *InternalServerError
+	JSON503 *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UploadCompleteResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UploadCompleteResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UploadContentsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON408      *Deadline
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UploadContentsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UploadContentsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type UploadChunkResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *BadRequest
+	JSON401      *KeyNotEnabled
+	JSON403      *Forbidden
+	JSON408      *Deadline
+	JSON409      *Error
+	JSON429      *Error
+	JSON500      *InternalServerError
+	JSON503      *Unavailable
+}
+
+// Status returns HTTPResponse.Status
+func (r UploadChunkResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r UploadChunkResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type StatusResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *StatusAPIResponse
+	JSON400      *BadRequest
+	JSON503      *StatusAPIResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r StatusResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r StatusResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// GetPGPKeyWithResponse request returning *GetPGPKeyResponse
+func (c *ClientWithResponses) GetPGPKeyWithResponse(ctx context.Context, major int, minor int, patch int, params *GetPGPKeyParams, reqEditors ...RequestEditorFn) (*GetPGPKeyResponse, error) {
+	rsp, err := c.GetPGPKey(ctx, major, minor, patch, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetPGPKeyResponse(rsp)
+}
+
+// AgentEnrollWithBodyWithResponse request with arbitrary body returning *AgentEnrollResponse
+func (c *ClientWithResponses) AgentEnrollWithBodyWithResponse(ctx context.Context, params *AgentEnrollParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error) {
+	rsp, err := c.AgentEnrollWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentEnrollResponse(rsp)
+}
+
+func (c *ClientWithResponses) AgentEnrollWithResponse(ctx context.Context, params *AgentEnrollParams, body AgentEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentEnrollResponse, error) {
+	rsp, err := c.AgentEnroll(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentEnrollResponse(rsp)
+}
+
+// AgentReassignWithBodyWithResponse request with arbitrary body returning *AgentReassignResponse
+func (c *ClientWithResponses) AgentReassignWithBodyWithResponse(ctx context.Context, params *AgentReassignParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentReassignResponse, error) {
+	rsp, err := c.AgentReassignWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentReassignResponse(rsp)
+}
+
+func (c *ClientWithResponses) AgentReassignWithResponse(ctx context.Context, params *AgentReassignParams, body AgentReassignJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentReassignResponse, error) {
+	rsp, err := c.AgentReassign(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentReassignResponse(rsp)
+}
+
+// AgentSearchWithBodyWithResponse request with arbitrary body returning *AgentSearchResponse
+func (c *ClientWithResponses) AgentSearchWithBodyWithResponse(ctx context.Context, params *AgentSearchParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentSearchResponse, error) {
+	rsp, err := c.AgentSearchWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentSearchResponse(rsp)
+}
+
+func (c *ClientWithResponses) AgentSearchWithResponse(ctx context.Context, params *AgentSearchParams, body AgentSearchJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentSearchResponse, error) {
+	rsp, err := c.AgentSearch(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentSearchResponse(rsp)
+}
+
+// AgentAcksWithBodyWithResponse request with arbitrary body returning *AgentAcksResponse
+func (c *ClientWithResponses) AgentAcksWithBodyWithResponse(ctx context.Context, id string, params *AgentAcksParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error) {
+	rsp, err := c.AgentAcksWithBody(ctx, id, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentAcksResponse(rsp)
+}
+
+func (c *ClientWithResponses) AgentAcksWithResponse(ctx context.Context, id string, params *AgentAcksParams, body AgentAcksJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentAcksResponse, error) {
+	rsp, err := c.AgentAcks(ctx, id, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentAcksResponse(rsp)
+}
+
+// AgentCheckinWithBodyWithResponse request with arbitrary body returning *AgentCheckinResponse
+func (c *ClientWithResponses) AgentCheckinWithBodyWithResponse(ctx context.Context, id string, params *AgentCheckinParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error) {
+	rsp, err := c.AgentCheckinWithBody(ctx, id, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentCheckinResponse(rsp)
+}
+
+func (c *ClientWithResponses) AgentCheckinWithResponse(ctx context.Context, id string, params *AgentCheckinParams, body AgentCheckinJSONRequestBody, reqEditors ...RequestEditorFn) (*AgentCheckinResponse, error) {
+	rsp, err := c.AgentCheckin(ctx, id, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentCheckinResponse(rsp)
+}
+
+// AgentRevokeWithResponse request returning *AgentRevokeResponse
+func (c *ClientWithResponses) AgentRevokeWithResponse(ctx context.Context, id string, params *AgentRevokeParams, reqEditors ...RequestEditorFn) (*AgentRevokeResponse, error) {
+	rsp, err := c.AgentRevoke(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAgentRevokeResponse(rsp)
+}
+
+// ArtifactWithResponse request returning *ArtifactResponse
+func (c *ClientWithResponses) ArtifactWithResponse(ctx context.Context, id string, sha2 string, params *ArtifactParams, reqEditors ...RequestEditorFn) (*ArtifactResponse, error) {
+	rsp, err := c.Artifact(ctx, id, sha2, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseArtifactResponse(rsp)
+}
+
+// GetDiagnosticsWithResponse request returning *GetDiagnosticsResponse
+func (c *ClientWithResponses) GetDiagnosticsWithResponse(ctx context.Context, params *GetDiagnosticsParams, reqEditors ...RequestEditorFn) (*GetDiagnosticsResponse, error) {
+	rsp, err := c.GetDiagnostics(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetDiagnosticsResponse(rsp)
+}
+
+// DrainWithResponse request returning *DrainResponse
+func (c *ClientWithResponses) DrainWithResponse(ctx context.Context, params *DrainParams, reqEditors ...RequestEditorFn) (*DrainResponse, error) {
+	rsp, err := c.Drain(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDrainResponse(rsp)
+}
+
+// GetFileWithResponse request returning *GetFileResponse
+func (c *ClientWithResponses) GetFileWithResponse(ctx context.Context, id string, params *GetFileParams, reqEditors ...RequestEditorFn) (*GetFileResponse, error) {
+	rsp, err := c.GetFile(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetFileResponse(rsp)
+}
+
+// GetPolicyLeadersWithResponse request returning *GetPolicyLeadersResponse
+func (c *ClientWithResponses) GetPolicyLeadersWithResponse(ctx context.Context, params *GetPolicyLeadersParams, reqEditors ...RequestEditorFn) (*GetPolicyLeadersResponse, error) {
+	rsp, err := c.GetPolicyLeaders(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetPolicyLeadersResponse(rsp)
+}
+
+// PolicyValidateWithBodyWithResponse request with arbitrary body returning *PolicyValidateResponse
+func (c *ClientWithResponses) PolicyValidateWithBodyWithResponse(ctx context.Context, params *PolicyValidateParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PolicyValidateResponse, error) {
+	rsp, err := c.PolicyValidateWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicyValidateResponse(rsp)
+}
+
+func (c *ClientWithResponses) PolicyValidateWithResponse(ctx context.Context, params *PolicyValidateParams, body PolicyValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*PolicyValidateResponse, error) {
+	rsp, err := c.PolicyValidate(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicyValidateResponse(rsp)
+}
+
+// UpdateProfilerWithBodyWithResponse request with arbitrary body returning *UpdateProfilerResponse
+func (c *ClientWithResponses) UpdateProfilerWithBodyWithResponse(ctx context.Context, params *UpdateProfilerParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UpdateProfilerResponse, error) {
+	rsp, err := c.UpdateProfilerWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUpdateProfilerResponse(rsp)
+}
+
+func (c *ClientWithResponses) UpdateProfilerWithResponse(ctx context.Context, params *UpdateProfilerParams, body UpdateProfilerJSONRequestBody, reqEditors ...RequestEditorFn) (*UpdateProfilerResponse, error) {
+	rsp, err := c.UpdateProfiler(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUpdateProfilerResponse(rsp)
+}
+
+// PromoteWithResponse request returning *PromoteResponse
+func (c *ClientWithResponses) PromoteWithResponse(ctx context.Context, params *PromoteParams, reqEditors ...RequestEditorFn) (*PromoteResponse, error) {
+	rsp, err := c.Promote(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePromoteResponse(rsp)
+}
+
+// UploadBeginWithBodyWithResponse request with arbitrary body returning *UploadBeginResponse
+func (c *ClientWithResponses) UploadBeginWithBodyWithResponse(ctx context.Context, params *UploadBeginParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error) {
+	rsp, err := c.UploadBeginWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadBeginResponse(rsp)
+}
+
+func (c *ClientWithResponses) UploadBeginWithResponse(ctx context.Context, params *UploadBeginParams, body UploadBeginJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadBeginResponse, error) {
+	rsp, err := c.UploadBegin(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadBeginResponse(rsp)
+}
+
+// UploadStatusWithResponse request returning *UploadStatusResponse
+func (c *ClientWithResponses) UploadStatusWithResponse(ctx context.Context, id string, params *UploadStatusParams, reqEditors ...RequestEditorFn) (*UploadStatusResponse, error) {
+	rsp, err := c.UploadStatus(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadStatusResponse(rsp)
+}
+
+// UploadCompleteWithBodyWithResponse request with arbitrary body returning *UploadCompleteResponse
+func (c *ClientWithResponses) UploadCompleteWithBodyWithResponse(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error) {
+	rsp, err := c.UploadCompleteWithBody(ctx, id, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadCompleteResponse(rsp)
+}
+
+func (c *ClientWithResponses) UploadCompleteWithResponse(ctx context.Context, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error) {
+	rsp, err := c.UploadComplete(ctx, id, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadCompleteResponse(rsp)
+}
+
+// UploadContentsWithResponse request returning *UploadContentsResponse
+func (c *ClientWithResponses) UploadContentsWithResponse(ctx context.Context, id string, params *UploadContentsParams, reqEditors ...RequestEditorFn) (*UploadContentsResponse, error) {
+	rsp, err := c.UploadContents(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadContentsResponse(rsp)
+}
+
+// UploadChunkWithBodyWithResponse request with arbitrary body returning *UploadChunkResponse
+func (c *ClientWithResponses) UploadChunkWithBodyWithResponse(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadChunkResponse, error) {
+	rsp, err := c.UploadChunkWithBody(ctx, id, chunkNum, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseUploadChunkResponse(rsp)
+}
+
+// StatusWithResponse request returning *StatusResponse
+func (c *ClientWithResponses) StatusWithResponse(ctx context.Context, params *StatusParams, reqEditors ...RequestEditorFn) (*StatusResponse, error) {
+	rsp, err := c.Status(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatusResponse(rsp)
+}
+
+// ParseGetPGPKeyResponse parses an HTTP response from a GetPGPKeyWithResponse call
+func ParseGetPGPKeyResponse(rsp *http.Response) (*GetPGPKeyResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetPGPKeyResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentEnrollResponse parses an HTTP response from a AgentEnrollWithResponse call
+func ParseAgentEnrollResponse(rsp *http.Response) (*AgentEnrollResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentEnrollResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest EnrollResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentReassignResponse parses an HTTP response from a AgentReassignWithResponse call
+func ParseAgentReassignResponse(rsp *http.Response) (*AgentReassignResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentReassignResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest AgentReassignAPIResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON428 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentSearchResponse parses an HTTP response from a AgentSearchWithResponse call
+func ParseAgentSearchResponse(rsp *http.Response) (*AgentSearchResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentSearchResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest AgentSearchAPIResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON428 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentAcksResponse parses an HTTP response from a AgentAcksWithResponse call
+func ParseAgentAcksResponse(rsp *http.Response) (*AgentAcksResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentAcksResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest AckResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest AgentNotFound
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentCheckinResponse parses an HTTP response from a AgentCheckinWithResponse call
+func ParseAgentCheckinResponse(rsp *http.Response) (*AgentCheckinResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentCheckinResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest CheckinResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest AgentNotFound
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseAgentRevokeResponse parses an HTTP response from a AgentRevokeWithResponse call
+func ParseAgentRevokeResponse(rsp *http.Response) (*AgentRevokeResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AgentRevokeResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest AgentRevokeAPIResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest AgentNotFound
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseArtifactResponse parses an HTTP response from a ArtifactWithResponse call
+func ParseArtifactResponse(rsp *http.Response) (*ArtifactResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ArtifactResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest AgentNotFound
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON428 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetDiagnosticsResponse parses an HTTP response from a GetDiagnosticsWithResponse call
+func ParseGetDiagnosticsResponse(rsp *http.Response) (*GetDiagnosticsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetDiagnosticsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON428 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDrainResponse parses an HTTP response from a DrainWithResponse call
+func ParseDrainResponse(rsp *http.Response) (*DrainResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DrainResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest DrainAPIResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON428 = &dest
 
-// UploadCompleteWithBodyWithResponse request with arbitrary body returning *UploadCompleteResponse
-func (c *ClientWithResponses) UploadCompleteWithBodyWithResponse(ctx context.Context, id string, params *UploadCompleteParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error) {
-	rsp, err := c.UploadCompleteWithBody(ctx, id, params, contentType, body, reqEditors...)
-	if err != nil {
-		return nil, err
-	}
-	return ParseUploadCompleteResponse(rsp)
-}
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
 
-func (c *ClientWithResponses) UploadCompleteWithResponse(ctx context.Context, id string, params *UploadCompleteParams, body UploadCompleteJSONRequestBody, reqEditors ...RequestEditorFn) (*UploadCompleteResponse, error) {
-	rsp, err := c.UploadComplete(ctx, id, params, body, reqEditors...)
-	if err != nil {
-		return nil, err
-	}
-	return ParseUploadCompleteResponse(rsp)
-}
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
 
-// UploadChunkWithBodyWithResponse request with arbitrary body returning *UploadChunkResponse
-func (c *ClientWithResponses) UploadChunkWithBodyWithResponse(ctx context.Context, id string, chunkNum int, params *UploadChunkParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*UploadChunkResponse, error) {
-	rsp, err := c.UploadChunkWithBody(ctx, id, chunkNum, params, contentType, body, reqEditors...)
-	if err != nil {
-		return nil, err
 	}
-	return ParseUploadChunkResponse(rsp)
-}
 
-// StatusWithResponse request returning *StatusResponse
-func (c *ClientWithResponses) StatusWithResponse(ctx context.Context, params *StatusParams, reqEditors ...RequestEditorFn) (*StatusResponse, error) {
-	rsp, err := c.Status(ctx, params, reqEditors...)
-	if err != nil {
-		return nil, err
-	}
-	return ParseStatusResponse(rsp)
+	return response, nil
 }
 
-// ParseGetPGPKeyResponse parses an HTTP response from a GetPGPKeyWithResponse call
-func ParseGetPGPKeyResponse(rsp *http.Response) (*GetPGPKeyResponse, error) {
+// ParseGetFileResponse parses an HTTP response from a GetFileWithResponse call
+func ParseGetFileResponse(rsp *http.Response) (*GetFileResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetPGPKeyResponse{
+	response := &GetFileResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
@@ -1539,52 +3543,73 @@ func ParseGetPGPKeyResponse(rsp *http.Response) (*GetPGPKeyResponse, error) {
 		}
 		response.JSON401 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
 	}
 
 	return response, nil
 }
 
-// ParseAgentEnrollResponse parses an HTTP response from a AgentEnrollWithResponse call
-func ParseAgentEnrollResponse(rsp *http.Response) (*AgentEnrollResponse, error) {
+// ParseGetPolicyLeadersResponse parses an HTTP response from a GetPolicyLeadersWithResponse call
+func ParseGetPolicyLeadersResponse(rsp *http.Response) (*GetPolicyLeadersResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &AgentEnrollResponse{
+	response := &GetPolicyLeadersResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest EnrollResponse
+		var dest PolicyLeadersAPIResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
 		response.JSON200 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
-		var dest BadRequest
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
-		response.JSON400 = &dest
+		response.JSON401 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
-		var dest KeyNotEnabled
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
-		response.JSON401 = &dest
+		response.JSON403 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
-		var dest Deadline
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
+		var dest Throttle
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
-		response.JSON408 = &dest
+		response.JSON428 = &dest
 
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest InternalServerError
@@ -1605,22 +3630,22 @@ func ParseAgentEnrollResponse(rsp *http.Response) (*AgentEnrollResponse, error)
 	return response, nil
 }
 
-// ParseAgentAcksResponse parses an HTTP response from a AgentAcksWithResponse call
-func ParseAgentAcksResponse(rsp *http.Response) (*AgentAcksResponse, error) {
+// ParsePolicyValidateResponse parses an HTTP response from a PolicyValidateWithResponse call
+func ParsePolicyValidateResponse(rsp *http.Response) (*PolicyValidateResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &AgentAcksResponse{
+	response := &PolicyValidateResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest AckResponse
+		var dest PolicyValidateAPIResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -1647,20 +3672,6 @@ func ParseAgentAcksResponse(rsp *http.Response) (*AgentAcksResponse, error) {
 		}
 		response.JSON403 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
-		var dest AgentNotFound
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON404 = &dest
-
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
-		var dest Deadline
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON408 = &dest
-
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest InternalServerError
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
@@ -1680,22 +3691,22 @@ func ParseAgentAcksResponse(rsp *http.Response) (*AgentAcksResponse, error) {
 	return response, nil
 }
 
-// ParseAgentCheckinResponse parses an HTTP response from a AgentCheckinWithResponse call
-func ParseAgentCheckinResponse(rsp *http.Response) (*AgentCheckinResponse, error) {
+// ParseUpdateProfilerResponse parses an HTTP response from a UpdateProfilerWithResponse call
+func ParseUpdateProfilerResponse(rsp *http.Response) (*UpdateProfilerResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &AgentCheckinResponse{
+	response := &UpdateProfilerResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest CheckinResponse
+		var dest ProfilerAPIResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -1722,20 +3733,6 @@ func ParseAgentCheckinResponse(rsp *http.Response) (*AgentCheckinResponse, error
 		}
 		response.JSON403 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
-		var dest AgentNotFound
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON404 = &dest
-
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
-		var dest Deadline
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON408 = &dest
-
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest InternalServerError
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
@@ -1755,26 +3752,26 @@ func ParseAgentCheckinResponse(rsp *http.Response) (*AgentCheckinResponse, error
 	return response, nil
 }
 
-// ParseArtifactResponse parses an HTTP response from a ArtifactWithResponse call
-func ParseArtifactResponse(rsp *http.Response) (*ArtifactResponse, error) {
+// ParsePromoteResponse parses an HTTP response from a PromoteWithResponse call
+func ParsePromoteResponse(rsp *http.Response) (*PromoteResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &ArtifactResponse{
+	response := &PromoteResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
-		var dest BadRequest
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest PromoteAPIResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
-		response.JSON400 = &dest
+		response.JSON200 = &dest
 
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
 		var dest KeyNotEnabled
@@ -1783,19 +3780,12 @@ func ParseArtifactResponse(rsp *http.Response) (*ArtifactResponse, error) {
 		}
 		response.JSON401 = &dest
 
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
-		var dest AgentNotFound
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON404 = &dest
-
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
-		var dest Deadline
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
-		response.JSON408 = &dest
+		response.JSON403 = &dest
 
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 428:
 		var dest Throttle
@@ -1823,20 +3813,27 @@ func ParseArtifactResponse(rsp *http.Response) (*ArtifactResponse, error) {
 	return response, nil
 }
 
-// ParseGetFileResponse parses an HTTP response from a GetFileWithResponse call
-func ParseGetFileResponse(rsp *http.Response) (*GetFileResponse, error) {
+// ParseUploadBeginResponse parses an HTTP response from a UploadBeginWithResponse call
+func ParseUploadBeginResponse(rsp *http.Response) (*UploadBeginResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetFileResponse{
+	response := &UploadBeginResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest UploadBeginAPIResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
 		var dest BadRequest
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
@@ -1852,12 +3849,19 @@ func ParseGetFileResponse(rsp *http.Response) (*GetFileResponse, error) {
 		response.JSON401 = &dest
 
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
-		var dest Error
+		var dest Forbidden
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
 		response.JSON403 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest InternalServerError
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
@@ -1877,22 +3881,22 @@ func ParseGetFileResponse(rsp *http.Response) (*GetFileResponse, error) {
 	return response, nil
 }
 
-// ParseUploadBeginResponse parses an HTTP response from a UploadBeginWithResponse call
-func ParseUploadBeginResponse(rsp *http.Response) (*UploadBeginResponse, error) {
+// ParseUploadStatusResponse parses an HTTP response from a UploadStatusWithResponse call
+func ParseUploadStatusResponse(rsp *http.Response) (*UploadStatusResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &UploadBeginResponse{
+	response := &UploadStatusResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest UploadBeginAPIResponse
+		var dest UploadStatusAPIResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -2015,6 +4019,67 @@ func ParseUploadCompleteResponse(rsp *http.Response) (*UploadCompleteResponse, e
 	return response, nil
 }
 
+// ParseUploadContentsResponse parses an HTTP response from a UploadContentsWithResponse call
+func ParseUploadContentsResponse(rsp *http.Response) (*UploadContentsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &UploadContentsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest BadRequest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest KeyNotEnabled
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 403:
+		var dest Forbidden
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON403 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 408:
+		var dest Deadline
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON408 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest InternalServerError
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Unavailable
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
 // ParseUploadChunkResponse parses an HTTP response from a UploadChunkWithResponse call
 func ParseUploadChunkResponse(rsp *http.Response) (*UploadChunkResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
@@ -2057,6 +4122,20 @@ func ParseUploadChunkResponse(rsp *http.Response) (*UploadChunkResponse, error)
 		}
 		response.JSON408 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 409:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON409 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 429:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON429 = &dest
+
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest InternalServerError
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
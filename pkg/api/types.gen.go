@@ -46,6 +46,13 @@ const (
 	ActionSettingsLogLevelWarning ActionSettingsLogLevel = "warning"
 )
 
+// Defines values for CheckinBackoffReason.
+const (
+	Draining      CheckinBackoffReason = "draining"
+	Elasticsearch CheckinBackoffReason = "elasticsearch"
+	Limits        CheckinBackoffReason = "limits"
+)
+
 // Defines values for CheckinRequestStatus.
 const (
 	CheckinRequestStatusDegraded CheckinRequestStatus = "degraded"
@@ -81,6 +88,13 @@ const (
 	STATE        EventType = "STATE"
 )
 
+// Defines values for PolicyLeadersItemState.
+const (
+	Expired PolicyLeadersItemState = "expired"
+	Held    PolicyLeadersItemState = "held"
+	Vacant  PolicyLeadersItemState = "vacant"
+)
+
 // Defines values for StatusResponseStatus.
 const (
 	Configuring StatusResponseStatus = "configuring"
@@ -123,6 +137,13 @@ const (
 	Endpoint UploadBeginRequestSrc = "endpoint"
 )
 
+// Defines values for UploadStatusResponseVerification.
+const (
+	UploadStatusResponseVerificationFailed   UploadStatusResponseVerification = "failed"
+	UploadStatusResponseVerificationPending  UploadStatusResponseVerification = "pending"
+	UploadStatusResponseVerificationVerified UploadStatusResponseVerification = "verified"
+)
+
 // AckRequest The request an elastic-agent sends to fleet-serve to acknowledge the execution of one or more actions.
 type AckRequest struct {
 	Events []AckRequest_Events_Item `json:"events"`
@@ -209,9 +230,13 @@ type ActionCancel struct {
 type ActionInputAction = map[string]interface{}
 
 // ActionPolicyChange The POLICY_CHANGE action data.
+// Exactly one of `policy` or `policy_gzip` is set, `policy_gzip` is only used for agents that list `gzip` in the `policy_encodings` of their checkin request.
 type ActionPolicyChange struct {
 	// Policy The full policy that an agent should run after combining with local configuration/env vars.
-	Policy PolicyData `json:"policy"`
+	Policy *PolicyData `json:"policy,omitempty"`
+
+	// PolicyGzip The base64 encoded, gzip compressed JSON serialized policy data.
+	PolicyGzip *[]byte `json:"policy_gzip,omitempty"`
 }
 
 // ActionPolicyReassign The POLICY_REASSIGN action data.
@@ -257,6 +282,146 @@ type ActionUpgrade struct {
 	Version string `json:"version"`
 }
 
+// AgentReassignQuery The filters of the agents to reassign, the active agents matching all the filters that are set are reassigned.
+type AgentReassignQuery struct {
+	// LocalMetadata Match the agents whose local_metadata fields have the values, the fields are keyed by their dotted path such as os.family.
+	LocalMetadata *map[string]string `json:"local_metadata,omitempty"`
+
+	// PolicyId Match the agents of the policy.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// Status Match the agents whose last checkin status is one of the statuses.
+	Status *[]string `json:"status,omitempty"`
+
+	// Tags Match the agents that have all the tags.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// AgentReassignRequest The agents to reassign to a policy, listed by ID or matched by a query.
+type AgentReassignRequest struct {
+	// Agents The IDs of the agents, it can not be set with query.
+	Agents *[]string `json:"agents,omitempty"`
+
+	// PolicyId The policy the agents are assigned to.
+	PolicyId string `json:"policy_id"`
+
+	// Query The filters of the agents to reassign, the active agents matching all the filters that are set are reassigned.
+	Query *AgentReassignQuery `json:"query,omitempty"`
+}
+
+// AgentReassignAPIResponse The result of the reassignment of agents to a policy.
+type AgentReassignAPIResponse struct {
+	// ActionIds The IDs of the POLICY_REASSIGN actions sent to the reassigned agents.
+	ActionIds []string `json:"action_ids"`
+
+	// Failed The IDs of the agents that could not be reassigned.
+	Failed []string `json:"failed"`
+
+	// PolicyId The policy the agents are assigned to.
+	PolicyId string `json:"policy_id"`
+
+	// Reassigned The number of agents assigned to the policy, the agents already assigned to it are not counted.
+	Reassigned int `json:"reassigned"`
+}
+
+// AgentSearchItem An agent matched by a search.
+type AgentSearchItem struct {
+	// Active True if the agent is enrolled.
+	Active bool `json:"active"`
+
+	// EnrolledAt When the agent enrolled.
+	EnrolledAt string `json:"enrolled_at"`
+
+	// Id The agent ID.
+	Id string `json:"id"`
+
+	// LastCheckin When the agent last checked in.
+	LastCheckin *string `json:"last_checkin,omitempty"`
+
+	// LocalMetadata The local metadata of the agent.
+	LocalMetadata *map[string]interface{} `json:"local_metadata,omitempty"`
+
+	// PolicyId The policy of the agent.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// PolicyRevisionIdx The revision of the policy the agent runs.
+	PolicyRevisionIdx *int64 `json:"policy_revision_idx,omitempty"`
+
+	// Status The last checkin status of the agent.
+	Status *string `json:"status,omitempty"`
+
+	// Tags The tags of the agent.
+	Tags *[]string `json:"tags,omitempty"`
+
+	// Version The version of the agent.
+	Version *string `json:"version,omitempty"`
+}
+
+// AgentSearchRequest The filters of an agent search, the agents match all the filters that are set.
+type AgentSearchRequest struct {
+	// Active Match the active or the inactive agents, the active agents are matched when it is not set.
+	Active *bool `json:"active,omitempty"`
+
+	// LocalMetadata Match the agents whose local_metadata fields have the values, the fields are keyed by their dotted path such as os.family.
+	LocalMetadata *map[string]string `json:"local_metadata,omitempty"`
+
+	// PolicyId Match the agents of the policy.
+	PolicyId *string `json:"policy_id,omitempty"`
+
+	// SearchAfter The search_after of the previous response, to request the next page.
+	SearchAfter *[]interface{} `json:"search_after,omitempty"`
+
+	// Size The maximum number of agents in the response, 100 when it is not set.
+	Size *int `json:"size,omitempty"`
+
+	// Status Match the agents whose last checkin status is one of the statuses.
+	Status *[]string `json:"status,omitempty"`
+
+	// Tags Match the agents that have all the tags.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// AgentSearchAPIResponse A page of the agents matched by a search, ordered by enrollment date.
+type AgentSearchAPIResponse struct {
+	Items []AgentSearchItem `json:"items"`
+
+	// SearchAfter Set when the page is full, the search_after of the request for the next page.
+	SearchAfter *[]interface{} `json:"search_after,omitempty"`
+}
+
+// AgentRevokeAPIResponse The result of revoking the credentials of an agent.
+type AgentRevokeAPIResponse struct {
+	// Id The agent ID
+	Id string `json:"id"`
+
+	// InvalidatedApiKeyIds The IDs of the access and output API keys of the agent that were invalidated.
+	InvalidatedApiKeyIds []string `json:"invalidated_api_key_ids"`
+
+	// PendingApiKeyIds The IDs of the API keys that could not be invalidated.
+	// Their invalidation is retried in the background.
+	PendingApiKeyIds []string `json:"pending_api_key_ids"`
+}
+
+// CheckinBackoff A hint to check in less often, sent while fleet-server is under pressure so the agents do not add to the load.
+type CheckinBackoff struct {
+	// Hosts The fleet-server hosts the agent is steered to while fleet-server is draining, it checks in with one of them instead of this fleet-server.
+	Hosts *[]string `json:"hosts,omitempty"`
+
+	// PollInterval The suggested minimum interval between two checkins while fleet-server is under pressure. A duration string such as "5m".
+	PollInterval string `json:"poll_interval"`
+
+	// Reason The pressure fleet-server is under.
+	// limits when the concurrent checkins or their rate are close to the checkin limits, elasticsearch when the writes to Elasticsearch are backed up. draining when fleet-server is draining before it stops.
+	Reason CheckinBackoffReason `json:"reason"`
+
+	// RetryAfter The suggested delay in seconds before the next checkin, also sent as the Retry-After header of the response.
+	RetryAfter int `json:"retry_after"`
+}
+
+// CheckinBackoffReason The pressure fleet-server is under.
+// limits when the concurrent checkins or their rate are close to the checkin limits, elasticsearch when the writes to Elasticsearch are backed up. draining when fleet-server is draining before it stops.
+type CheckinBackoffReason string
+
 // CheckinRequest defines model for checkinRequest.
 type CheckinRequest struct {
 	// AckToken The ack_token form a previous response if the agent has checked in before.
@@ -277,6 +442,11 @@ type CheckinRequest struct {
 	// Message State message, may be overridden or use the error message of a failing component.
 	Message string `json:"message"`
 
+	// PolicyEncodings An optional list of the encodings the agent can decode the policy of a POLICY_CHANGE action from.
+	// If it contains `gzip` and the rendered policy is larger than the server's compression threshold, fleet-server sends the
+	// gzip compressed policy in the `policy_gzip` attribute of the action data instead of the `policy` attribute.
+	PolicyEncodings *[]string `json:"policy_encodings,omitempty"`
+
 	// PollTimeout An optional timeout value that informs fleet-server of when a client will time out on it's checkin request.
 	// If not specified fleet-server will use the timeout values specified in the config (defaults to 5m polling and a 10m write timeout).
 	// The value, if specified is expected to be a string that is parsable by [time.ParseDuration](https://pkg.go.dev/time#ParseDuration).
@@ -303,6 +473,27 @@ type CheckinResponse struct {
 
 	// Actions A list of actions that the agent must execute.
 	Actions *[]Action `json:"actions,omitempty"`
+
+	// Backoff A hint to check in less often, sent while fleet-server is under pressure so the agents do not add to the load.
+	Backoff *CheckinBackoff `json:"backoff,omitempty"`
+}
+
+// DrainAPIResponse The drain mode of the server.
+type DrainAPIResponse struct {
+	// StartedAt When the server entered the drain mode.
+	StartedAt time.Time `json:"started_at"`
+
+	// StopsAt When the server stops, at the end of the drain period.
+	StopsAt time.Time `json:"stops_at"`
+}
+
+// PromoteAPIResponse The standby mode of the server.
+type PromoteAPIResponse struct {
+	// PromotedAt When the server was promoted, absent when the server did not start in standby.
+	PromotedAt *time.Time `json:"promoted_at,omitempty"`
+
+	// Reason What promoted the server, api or config.
+	Reason *string `json:"reason,omitempty"`
 }
 
 // DiagnosticsEvent defines model for diagnosticsEvent.
@@ -386,6 +577,11 @@ type EnrollRequest struct {
 	// Deprecated:
 	SharedId *string `json:"shared_id,omitempty"`
 
+	// ServiceToken An Elasticsearch service token the agent authenticates with instead of an access API key.
+	// Requires server.service_token_auth to be enabled, the agent is bound to the service account and token name of the token.
+	// No access API key is generated and the response has an empty access_api_key.
+	ServiceToken *string `json:"service_token,omitempty"`
+
 	// Type The enrollment type of the agent.
 	// The agent only supports the PERMANENT value.
 	// In the future the enrollment type may be used to indicate agents that use fleet for reporting and monitoring, but do not use policies.
@@ -442,6 +638,10 @@ type EnrollResponseItem struct {
 	// PolicyId The policy ID that the agent is enrolled with. Decoded from the API key used in the request.
 	PolicyId string `json:"policy_id"`
 
+	// SigningKey The base64 encoded key the agent signs its requests with, only set when fleet-server verifies the request signatures.
+	// The signature is the hex encoded HMAC-SHA256 of the method, the path and query, the X-Fleet-Signature-Timestamp and X-Fleet-Signature-Nonce headers and the hex encoded SHA-256 of the body, each followed by a newline except the last, and is sent in the X-Fleet-Signature header.
+	SigningKey *string `json:"signing_key,omitempty"`
+
 	// Status Agent status from fleet-server.
 	// fleet-ui may differ.
 	//
@@ -601,7 +801,7 @@ type PolicyData struct {
 	// OutputPermissions Elasticsearch permissions that the agent requires in order to run the policy.
 	OutputPermissions *map[string]interface{} `json:"output_permissions,omitempty"`
 
-	// Outputs A map of all outputs that the agent running the policy can use to send data to.
+	// Outputs A map of all outputs that the agent running the policy can use to send data to. An output may name a fallback output in its failover.fallback attribute, fleet-server then sets failover.preferred to the output the agent should prefer.
 	Outputs *map[string]interface{} `json:"outputs,omitempty"`
 
 	// Revision The revision number of the policy. Should match revision_idx.
@@ -611,11 +811,109 @@ type PolicyData struct {
 	Signed *ActionSignature `json:"signed,omitempty" yaml:"signed"`
 }
 
+// PolicyValidateRequest A policy document to validate before it is saved
+type PolicyValidateRequest struct {
+	// Data The policy data, as it is stored in the data attribute of a .fleet-policies document
+	Data map[string]interface{} `json:"data"`
+
+	// PolicyId The ID of the policy the document belongs to, used for logging
+	PolicyId *string `json:"policy_id,omitempty"`
+}
+
+// PolicyValidateAPIResponse The result of the validation of a policy document. The policy can be dispatched to agents if there are no errors.
+type PolicyValidateAPIResponse struct {
+	// Errors Issues that would prevent the policy from being dispatched to agents
+	Errors []PolicyValidationIssue `json:"errors"`
+
+	// Valid True if no errors were found
+	Valid bool `json:"valid"`
+
+	// Warnings Issues that would not prevent the policy from being dispatched, but are likely mistakes
+	Warnings []PolicyValidationIssue `json:"warnings"`
+}
+
+// PolicyValidationIssue A problem found in a policy document
+type PolicyValidationIssue struct {
+	// Message A description of the issue
+	Message string `json:"message"`
+
+	// Path The dotted path of the policy attribute the issue relates to
+	Path string `json:"path"`
+}
+
+// PolicyLeadersItem The leader election state of a policy.
+type PolicyLeadersItem struct {
+	// CoordinatorIdx The coordinator index of the latest revision, 0 until the leader of the policy coordinates the revision and it can be rolled out.
+	CoordinatorIdx int64 `json:"coordinator_idx"`
+
+	// HeldAt When the leader last took or renewed the leadership of the policy.
+	HeldAt *time.Time `json:"held_at,omitempty"`
+
+	// LeaseExpiresAt When the leadership expires if the leader does not renew it, another fleet-server can then take it.
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+
+	// PolicyId The policy ID.
+	PolicyId string `json:"policy_id"`
+
+	// RevisionIdx The latest revision of the policy.
+	RevisionIdx int64 `json:"revision_idx"`
+
+	// ServerId The ID of the fleet-server that led the policy last.
+	ServerId *string `json:"server_id,omitempty"`
+
+	// ServerVersion The version of the fleet-server that led the policy last.
+	ServerVersion *string `json:"server_version,omitempty"`
+
+	// State held when a fleet-server holds the leadership of the policy, expired when its lease expired without being renewed and vacant when no fleet-server ever led the policy.
+	State PolicyLeadersItemState `json:"state"`
+}
+
+// PolicyLeadersItemState held when a fleet-server holds the leadership of the policy, expired when its lease expired without being renewed and vacant when no fleet-server ever led the policy.
+type PolicyLeadersItemState string
+
+// PolicyLeadersAPIResponse The leader election state of the policies.
+type PolicyLeadersAPIResponse struct {
+	Items []PolicyLeadersItem `json:"items"`
+
+	// PoliciesCheckpoint The global checkpoint of .fleet-policies processed by the fleet-server that answers, the policy changes after it are not seen by the server yet.
+	PoliciesCheckpoint *int64 `json:"policies_checkpoint,omitempty"`
+}
+
+// ProfilerRequest The runtime profiling settings to change, the settings that are not set are left unchanged.
+type ProfilerRequest struct {
+	// BlockProfileRate The rate of the block profile in nanoseconds, as set by runtime.SetBlockProfileRate. 0 turns the block profile off.
+	BlockProfileRate *int `json:"block_profile_rate,omitempty"`
+
+	// Enabled Serve the pprof endpoints under /api/fleet/debug/pprof/ on the API listener.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MutexProfileFraction On average 1/n of the mutex contention events are reported, as set by runtime.SetMutexProfileFraction. 0 turns the mutex profile off.
+	MutexProfileFraction *int `json:"mutex_profile_fraction,omitempty"`
+}
+
+// ProfilerAPIResponse The runtime profiling settings in effect.
+type ProfilerAPIResponse struct {
+	// BlockProfileRate The rate of the block profile in nanoseconds.
+	BlockProfileRate int `json:"block_profile_rate"`
+
+	// Enabled True if the pprof endpoints are served on the API listener.
+	Enabled bool `json:"enabled"`
+
+	// MutexProfileFraction The fraction of the mutex contention events that are reported.
+	MutexProfileFraction int `json:"mutex_profile_fraction"`
+}
+
 // StatusAPIResponse Status response information.
 type StatusAPIResponse struct {
+	// Dependencies Health of the dependencies of fleet-server, included in the response to an authorized verbose status request.
+	Dependencies *StatusResponseDependencies `json:"dependencies,omitempty"`
+
 	// Name Service name.
 	Name string `json:"name"`
 
+	// Policies The readiness of the policies with a Fleet Server integration, included in the response to an authorized status request.
+	Policies *[]StatusResponsePolicy `json:"policies,omitempty"`
+
 	// Status A Unit state that fleet-server may report.
 	// Unit state is defined in the elastic-agent-client specification.
 	Status StatusResponseStatus `json:"status"`
@@ -624,6 +922,96 @@ type StatusAPIResponse struct {
 	Version *StatusResponseVersion `json:"version,omitempty"`
 }
 
+// StatusResponseBulker State of the bulker, the batching of the Elasticsearch operations.
+type StatusResponseBulker struct {
+	// QueueDepth The number of operations queued and not flushed to Elasticsearch yet.
+	QueueDepth int `json:"queue_depth"`
+}
+
+// StatusResponseCoordinator State of the leader election of the policies.
+type StatusResponseCoordinator struct {
+	// Checked The date-time leadership was last ensured.
+	Checked *string `json:"checked,omitempty"`
+
+	// Leading The number of policies the fleet-server leads.
+	Leading int `json:"leading"`
+
+	// Members The number of fleet-servers the policies are sharded across, absent when sharding is disabled.
+	Members *int `json:"members,omitempty"`
+}
+
+// StatusResponseDependencies Health of the dependencies of fleet-server, included in the response to an authorized verbose status request.
+type StatusResponseDependencies struct {
+	// Bulker State of the bulker, the batching of the Elasticsearch operations.
+	Bulker *StatusResponseBulker `json:"bulker,omitempty"`
+
+	// Coordinator State of the leader election of the policies.
+	Coordinator *StatusResponseCoordinator `json:"coordinator,omitempty"`
+
+	// Elasticsearch Health of the Elasticsearch cluster, measured with a ping.
+	Elasticsearch *StatusResponseElasticsearch `json:"elasticsearch,omitempty"`
+
+	// Limits The limits of concurrent requests and connections that are configured.
+	Limits *[]StatusResponseLimit `json:"limits,omitempty"`
+
+	// PolicyMonitor State of the dispatch of the policy changes to the agents.
+	PolicyMonitor *StatusResponsePolicyMonitor `json:"policy_monitor,omitempty"`
+}
+
+// StatusResponseElasticsearch Health of the Elasticsearch cluster, measured with a ping.
+type StatusResponseElasticsearch struct {
+	// Available True when the ping succeeded.
+	Available bool `json:"available"`
+
+	// Error Why the ping failed.
+	Error *string `json:"error,omitempty"`
+
+	// LatencyMs The round trip of the ping in milliseconds.
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// StatusResponseLimit Saturation of the limit of concurrent requests of a route, or of the connections.
+type StatusResponseLimit struct {
+	// Active The number of requests or connections in progress.
+	Active int `json:"active"`
+
+	// Max The configured limit.
+	Max int `json:"max"`
+
+	// Name The route, like checkin, or connections for the connection limit.
+	Name string `json:"name"`
+
+	// Saturation The ratio of active to max.
+	Saturation float64 `json:"saturation"`
+}
+
+// StatusResponsePolicy Readiness of a policy with a Fleet Server integration, included in the response to an authorized status request.
+type StatusResponsePolicy struct {
+	// AgentsPending The number of active agents of the policy that have not received the latest revision yet.
+	AgentsPending int `json:"agents_pending"`
+
+	// Id The policy ID.
+	Id string `json:"id"`
+
+	// Message Why the policy is not ready.
+	Message *string `json:"message,omitempty"`
+
+	// OutputsPrepared True when the permissions needed to create the output API keys are defined for all the Elasticsearch outputs of the policy.
+	OutputsPrepared bool `json:"outputs_prepared"`
+
+	// Ready True when the outputs of the policy are prepared and all its active agents run the latest revision.
+	Ready bool `json:"ready"`
+
+	// Revision The latest revision of the policy.
+	Revision int64 `json:"revision"`
+}
+
+// StatusResponsePolicyMonitor State of the dispatch of the policy changes to the agents.
+type StatusResponsePolicyMonitor struct {
+	// DispatchLagMs For how long the oldest pending policy change has been waiting for dispatch, in milliseconds.
+	DispatchLagMs int64 `json:"dispatch_lag_ms"`
+}
+
 // StatusResponseStatus A Unit state that fleet-server may report.
 // Unit state is defined in the elastic-agent-client specification.
 type StatusResponseStatus string
@@ -789,6 +1177,9 @@ type UploadBeginAPIResponse struct {
 	// ChunkSize The required size (in bytes) that the file must be segmented into for each chunk
 	ChunkSize int64 `json:"chunk_size"`
 
+	// MaxParallelChunks The number of chunks that may be uploaded at once. Chunks may be uploaded in any order. Omitted if there is no limit.
+	MaxParallelChunks *int `json:"max_parallel_chunks,omitempty"`
+
 	// UploadId A unique identifier for the ensuing upload operation
 	UploadId string `json:"upload_id"`
 }
@@ -802,6 +1193,45 @@ type UploadCompleteRequest struct {
 	} `json:"transithash"`
 }
 
+// UploadStatusAPIResponse The state of an upload operation, used by agents to resume an interrupted upload and by operators to debug uploads that do not complete
+type UploadStatusAPIResponse struct {
+	// BytesReceived The number of bytes of file contents received so far
+	BytesReceived int64 `json:"bytes_received"`
+
+	// ChunkCount The number of chunks the file is made of
+	ChunkCount int `json:"chunk_count"`
+
+	// ChunkSize The required size (in bytes) that the file must be segmented into for each chunk
+	ChunkSize int64 `json:"chunk_size"`
+
+	// Chunks The positions of the chunks that have been received, in ascending order
+	Chunks []int `json:"chunks"`
+
+	// FailureReason Why the upload failed, only present for failed uploads
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	// FileSize The size of the file in bytes
+	FileSize int64 `json:"file_size"`
+
+	// NextChunk The position of the first chunk that has not been received. Equal to the number of chunks in the file if all chunks have been received.
+	NextChunk int `json:"next_chunk"`
+
+	// Outstanding The positions of the chunks that have not been received, in ascending order
+	Outstanding []int `json:"outstanding"`
+
+	// Status The status of the upload operation
+	Status string `json:"status"`
+
+	// UploadId The upload operation identifier
+	UploadId string `json:"upload_id"`
+
+	// Verification Whether the file contents have been verified against the transithash sent to complete the upload. pending until the upload is completed, verified once it is done, failed if the upload failed.
+	Verification UploadStatusResponseVerification `json:"verification"`
+}
+
+// UploadStatusResponseVerification Whether the file contents have been verified against the transithash sent to complete the upload. pending until the upload is completed, verified once it is done, failed if the upload failed.
+type UploadStatusResponseVerification string
+
 // ApiVersion defines model for apiVersion.
 type ApiVersion = string
 
@@ -858,6 +1288,24 @@ type AgentEnrollParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// AgentReassignParams defines parameters for AgentReassign.
+type AgentReassignParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// AgentSearchParams defines parameters for AgentSearch.
+type AgentSearchParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // AgentAcksParams defines parameters for AgentAcks.
 type AgentAcksParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -886,6 +1334,15 @@ type AgentCheckinParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// AgentRevokeParams defines parameters for AgentRevoke.
+type AgentRevokeParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // ArtifactParams defines parameters for Artifact.
 type ArtifactParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -895,6 +1352,24 @@ type ArtifactParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// GetDiagnosticsParams defines parameters for GetDiagnostics.
+type GetDiagnosticsParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// DrainParams defines parameters for Drain.
+type DrainParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // GetFileParams defines parameters for GetFile.
 type GetFileParams struct {
 	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
@@ -904,6 +1379,42 @@ type GetFileParams struct {
 	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
 }
 
+// GetPolicyLeadersParams defines parameters for GetPolicyLeaders.
+type GetPolicyLeadersParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// PolicyValidateParams defines parameters for PolicyValidate.
+type PolicyValidateParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// UpdateProfilerParams defines parameters for UpdateProfiler.
+type UpdateProfilerParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
+// PromoteParams defines parameters for Promote.
+type PromoteParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadBeginParams defines parameters for UploadBegin.
 type UploadBeginParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -913,6 +1424,15 @@ type UploadBeginParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// UploadStatusParams defines parameters for UploadStatus.
+type UploadStatusParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadCompleteParams defines parameters for UploadComplete.
 type UploadCompleteParams struct {
 	// XRequestId The request tracking ID for APM.
@@ -922,9 +1442,19 @@ type UploadCompleteParams struct {
 	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
 }
 
+// UploadContentsParams defines parameters for UploadContents.
+type UploadContentsParams struct {
+	// XRequestId The request tracking ID for APM.
+	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
+
+	// ElasticApiVersion The API version to use, format should be "YYYY-MM-DD"
+	ElasticApiVersion *ApiVersion `json:"elastic-api-version,omitempty"`
+}
+
 // UploadChunkParams defines parameters for UploadChunk.
 type UploadChunkParams struct {
-	// XChunkSHA2 the SHA256 hash of the body contents for this request
+	// XChunkSHA2 The hex encoded SHA256 hash of the body contents for this request.
+	// A chunk whose contents do not match this hash is rejected with a 400 `ErrHashMismatch` and may be uploaded again.
 	XChunkSHA2 string `json:"X-Chunk-SHA2"`
 
 	// XRequestId The request tracking ID for APM.
@@ -936,6 +1466,9 @@ type UploadChunkParams struct {
 
 // StatusParams defines parameters for Status.
 type StatusParams struct {
+	// Verbose Include the health of the dependencies of fleet-server in the response of an authenticated request.
+	Verbose *bool `form:"verbose,omitempty" json:"verbose,omitempty"`
+
 	// XRequestId The request tracking ID for APM.
 	XRequestId *RequestId `json:"X-Request-Id,omitempty"`
 
@@ -946,12 +1479,24 @@ type StatusParams struct {
 // AgentEnrollJSONRequestBody defines body for AgentEnroll for application/json ContentType.
 type AgentEnrollJSONRequestBody = EnrollRequest
 
+// AgentReassignJSONRequestBody defines body for AgentReassign for application/json ContentType.
+type AgentReassignJSONRequestBody = AgentReassignRequest
+
+// AgentSearchJSONRequestBody defines body for AgentSearch for application/json ContentType.
+type AgentSearchJSONRequestBody = AgentSearchRequest
+
 // AgentAcksJSONRequestBody defines body for AgentAcks for application/json ContentType.
 type AgentAcksJSONRequestBody = AckRequest
 
 // AgentCheckinJSONRequestBody defines body for AgentCheckin for application/json ContentType.
 type AgentCheckinJSONRequestBody = CheckinRequest
 
+// PolicyValidateJSONRequestBody defines body for PolicyValidate for application/json ContentType.
+type PolicyValidateJSONRequestBody = PolicyValidateRequest
+
+// UpdateProfilerJSONRequestBody defines body for UpdateProfiler for application/json ContentType.
+type UpdateProfilerJSONRequestBody = ProfilerRequest
+
 // UploadBeginJSONRequestBody defines body for UploadBegin for application/json ContentType.
 type UploadBeginJSONRequestBody = UploadBeginRequest
 